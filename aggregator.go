@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/armosec/host-sensor/k8s"
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+)
+
+const (
+	peerRequestTimeout = 30 * time.Second
+
+	// maximal number of peers queried concurrently
+	peerConcurrency = 10
+)
+
+var (
+	errAggregatorDisabled = &sensor.SenseError{
+		Massage: "aggregator mode is disabled",
+		Kind:    "AggregatorDisabled",
+		Code:    http.StatusServiceUnavailable,
+	}
+
+	// aggregator merges the reports of the peer sensors, nil if aggregator mode is disabled
+	aggregator *peerAggregator
+)
+
+// ClusterReport holds the scan reports of all the sensors in the cluster
+type ClusterReport struct {
+	// Time the report was aggregated
+	Time time.Time `json:"time"`
+
+	// Scan reports, keyed by node name
+	Nodes map[string]json.RawMessage `json:"nodes"`
+
+	// Errors of the peers which couldn't be queried, keyed by node name
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+// peer is a sensor pod discovered through the Kubernetes API
+type peer struct {
+	nodeName string
+	address  string
+}
+
+// peerAggregator discovers the peer sensors (the pods matching the selector in its namespace),
+// and merges their scan reports
+type peerAggregator struct {
+	client     *k8s.Client
+	selector   string
+	port       int
+	scheme     string
+	httpClient *http.Client
+}
+
+// newPeerAggregator returns an aggregator querying the peers over TLS with `tlsConfig`, or over plain HTTP if nil
+func newPeerAggregator(client *k8s.Client, selector string, port int, tlsConfig *tls.Config) *peerAggregator {
+	a := &peerAggregator{
+		client:     client,
+		selector:   selector,
+		port:       port,
+		scheme:     "http",
+		httpClient: &http.Client{Timeout: peerRequestTimeout},
+	}
+	if tlsConfig != nil {
+		a.scheme = "https"
+		a.httpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+	return a
+}
+
+// discoverPeers returns the running sensor pods
+func (a *peerAggregator) discoverPeers(ctx context.Context) ([]peer, error) {
+	pods := k8s.PodList{}
+	if err := a.client.Get(ctx, k8s.PodsPath(a.client.Namespace, a.selector), &pods); err != nil {
+		return nil, fmt.Errorf("failed to list peers: %w", err)
+	}
+
+	peers := []peer{}
+	for _, pod := range pods.Items {
+		if pod.Status.Phase != k8s.PodPhaseRunning || pod.Status.PodIP == "" || pod.Spec.NodeName == "" {
+			continue
+		}
+		peers = append(peers, peer{
+			nodeName: pod.Spec.NodeName,
+			address:  net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(a.port)),
+		})
+	}
+	return peers, nil
+}
+
+// aggregate queries the scan reports of all the peers
+func (a *peerAggregator) aggregate(ctx context.Context) (*ClusterReport, error) {
+	peers, err := a.discoverPeers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ClusterReport{
+		Time:   time.Now().UTC(),
+		Nodes:  map[string]json.RawMessage{},
+		Errors: map[string]string{},
+	}
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	semaphore := make(chan struct{}, peerConcurrency)
+	for _, p := range peers {
+		wg.Add(1)
+		go func(p peer) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			nodeReport, err := a.getScanReport(ctx, p)
+
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				sensor.Logger(ctx).Warn("failed to get peer scan report", zap.String("peerNodeName", p.nodeName), zap.Error(err))
+				report.Errors[p.nodeName] = err.Error()
+				return
+			}
+			report.Nodes[p.nodeName] = nodeReport
+		}(p)
+	}
+	wg.Wait()
+
+	return report, nil
+}
+
+// getScanReport queries the scan report of a peer. Over TLS, it authenticates with the service account token, which
+// is never sent in cleartext.
+func (a *peerAggregator) getScanReport(ctx context.Context, p peer) (json.RawMessage, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, a.scheme+"://"+p.address+"/scanReport", nil)
+	if err != nil {
+		return nil, err
+	}
+	if a.scheme == "https" {
+		token, err := a.client.Token()
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	// the peer logs are stamped with the ID of the cluster report request
+	if id := sensor.RequestID(ctx); id != "" {
+		req.Header.Set(requestIDHeader, id)
+	}
+
+	resp, err := a.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("peer responded with status %d: %s", resp.StatusCode, body)
+	}
+	if !json.Valid(body) {
+		return nil, fmt.Errorf("peer responded with invalid JSON")
+	}
+	return body, nil
+}
+
+// clusterReportHandler responds with the merged scan reports of all the peer sensors
+func clusterReportHandler(rw http.ResponseWriter, r *http.Request) {
+	if aggregator == nil {
+		writeSenseError(rw, errAggregatorDisabled, "clusterReport")
+		return
+	}
+	report, err := aggregator.aggregate(r.Context())
+	if err == nil && reportAnonymizer != nil {
+		report = anonymizeNodeNames(report, reportAnonymizer)
+	}
+	GenericSensorHandler(rw, r, report, err, "clusterReport")
+}
+
+// anonymizeNodeNames returns the cluster report keyed by the pseudonyms of the node names, which the anonymization of
+// the values doesn't reach. Stripped node names are numbered, so the nodes are kept apart.
+func anonymizeNodeNames(report *ClusterReport, a *anonymizer) *ClusterReport {
+	names := map[string]bool{}
+	for nodeName := range report.Nodes {
+		names[nodeName] = true
+	}
+	for nodeName := range report.Errors {
+		names[nodeName] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for nodeName := range names {
+		sorted = append(sorted, nodeName)
+	}
+	sort.Strings(sorted)
+	pseudonyms := map[string]string{}
+	for i, nodeName := range sorted {
+		pseudonyms[nodeName] = a.pseudonym("host", nodeName)
+		if a.mode == anonymizeModeStrip {
+			pseudonyms[nodeName] = fmt.Sprintf("%s-%d", redacted, i+1)
+		}
+	}
+
+	ret := &ClusterReport{
+		Time:   report.Time,
+		Nodes:  make(map[string]json.RawMessage, len(report.Nodes)),
+		Errors: make(map[string]string, len(report.Errors)),
+	}
+	for nodeName, nodeReport := range report.Nodes {
+		ret.Nodes[pseudonyms[nodeName]] = nodeReport
+	}
+	for nodeName, nodeErr := range report.Errors {
+		ret.Errors[pseudonyms[nodeName]] = nodeErr
+	}
+	return ret
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/armosec/host-sensor/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerAggregator(t *testing.T) {
+	peerSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/scanReport", r.URL.Path)
+		// the token is never sent in cleartext
+		assert.Empty(t, r.Header.Get("Authorization"))
+		w.Write([]byte(`{"results":{}}`))
+	}))
+	defer peerSrv.Close()
+	client, port := newTestPeerCluster(t, peerSrv)
+
+	report, err := newPeerAggregator(client, "name=host-sensor", port, nil).aggregate(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]json.RawMessage{"node-a": json.RawMessage(`{"results":{}}`)}, report.Nodes)
+	assert.Empty(t, report.Errors)
+}
+
+func TestPeerAggregatorTLS(t *testing.T) {
+	dir := t.TempDir()
+	caFile, certFile, keyFile := path.Join(dir, "ca.crt"), path.Join(dir, "tls.crt"), path.Join(dir, "tls.key")
+	writeTestCertificates(t, caFile, certFile, keyFile)
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	require.NoError(t, err)
+	serverConf := &Config{TLSCertFile: certFile, TLSKeyFile: keyFile, TLSClientCAFile: caFile}
+	serverTLSConfig, err := newServerTLSConfig(serverConf)
+	require.NoError(t, err)
+	serverTLSConfig.Certificates = []tls.Certificate{cert}
+
+	peerSrv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NotNil(t, r.TLS)
+		assert.NotEmpty(t, r.TLS.PeerCertificates)
+		assert.Equal(t, "Bearer sensor-token", r.Header.Get("Authorization"))
+		w.Write([]byte(`{"results":{}}`))
+	}))
+	peerSrv.TLS = serverTLSConfig
+	peerSrv.StartTLS()
+	defer peerSrv.Close()
+	client, port := newTestPeerCluster(t, peerSrv)
+
+	peerTLSConfig, err := newPeerTLSConfig(&Config{TLSCertFile: certFile, TLSKeyFile: keyFile, AggregatorCAFile: caFile})
+	require.NoError(t, err)
+	report, err := newPeerAggregator(client, "name=host-sensor", port, peerTLSConfig).aggregate(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]json.RawMessage{"node-a": json.RawMessage(`{"results":{}}`)}, report.Nodes)
+	assert.Empty(t, report.Errors)
+
+	// a peer with a certificate of another CA isn't trusted
+	otherCAFile := path.Join(dir, "other-ca.crt")
+	writeTestCertificates(t, otherCAFile, path.Join(dir, "other.crt"), path.Join(dir, "other.key"))
+	peerTLSConfig, err = newPeerTLSConfig(&Config{TLSCertFile: certFile, TLSKeyFile: keyFile, AggregatorCAFile: otherCAFile})
+	require.NoError(t, err)
+	report, err = newPeerAggregator(client, "name=host-sensor", port, peerTLSConfig).aggregate(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, report.Nodes)
+	assert.Contains(t, report.Errors, "node-a")
+}
+
+// newTestPeerCluster returns a client of an API server listing a running sensor pod served by `peerSrv` (on node-a),
+// and the port of the peer
+func newTestPeerCluster(t *testing.T, peerSrv *httptest.Server) (*k8s.Client, int) {
+	peerURL, _ := url.Parse(peerSrv.URL)
+	peerHost, peerPort, _ := net.SplitHostPort(peerURL.Host)
+	port, _ := strconv.Atoi(peerPort)
+
+	apiSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/namespaces/default/pods", r.URL.Path)
+		assert.Equal(t, "name=host-sensor", r.URL.Query().Get("labelSelector"))
+		pods := k8s.PodList{Items: []k8s.Pod{
+			{Spec: k8s.PodSpec{NodeName: "node-a"}, Status: k8s.PodStatus{Phase: k8s.PodPhaseRunning, PodIP: peerHost}},
+			{Spec: k8s.PodSpec{NodeName: "node-b"}, Status: k8s.PodStatus{Phase: "Pending"}},
+		}}
+		json.NewEncoder(w).Encode(pods)
+	}))
+	t.Cleanup(apiSrv.Close)
+
+	tokenFile := path.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("sensor-token"), 0600))
+	client := k8s.NewClient(apiSrv.URL, tokenFile, apiSrv.Client(), "default")
+
+	return client, port
+}
+
+// writeTestCertificates writes a new CA, and a certificate of 127.0.0.1 signed by it for servers and clients
+func writeTestCertificates(t *testing.T, caFile, certFile, keyFile string) {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	caCert, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "host-sensor"},
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}), 0644))
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+}
+
+func TestAnonymizeNodeNames(t *testing.T) {
+	report := &ClusterReport{
+		Nodes:  map[string]json.RawMessage{"worker-1": json.RawMessage(`{}`), "worker-2": json.RawMessage(`{}`)},
+		Errors: map[string]string{"worker-3": "peer responded with status 500"},
+	}
+
+	a, err := newAnonymizer(anonymizeModeHash, "", nodeIdentifiers{})
+	require.NoError(t, err)
+	anonymized := anonymizeNodeNames(report, a)
+	assert.Contains(t, anonymized.Nodes, a.pseudonym("host", "worker-1"))
+	assert.Contains(t, anonymized.Nodes, a.pseudonym("host", "worker-2"))
+	assert.Equal(t, map[string]string{a.pseudonym("host", "worker-3"): "peer responded with status 500"}, anonymized.Errors)
+
+	a, err = newAnonymizer(anonymizeModeStrip, "", nodeIdentifiers{})
+	require.NoError(t, err)
+	anonymized = anonymizeNodeNames(report, a)
+	assert.Len(t, anonymized.Nodes, 2)
+	assert.Contains(t, anonymized.Nodes, "REDACTED-1")
+	assert.Contains(t, anonymized.Nodes, "REDACTED-2")
+	assert.Contains(t, anonymized.Errors, "REDACTED-3")
+}
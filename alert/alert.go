@@ -0,0 +1,221 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/armosec/host-sensor/evaluation"
+)
+
+// This package fires webhooks for new findings above a severity threshold, with rate limiting.
+
+// Webhook payload formats
+const (
+	// FormatGeneric POSTs the alert as JSON, or the rendered template if set
+	FormatGeneric = "generic"
+
+	// FormatSlack POSTs a Slack incoming webhook message, whose text is the rendered template
+	FormatSlack = "slack"
+)
+
+// defaultSlackTemplate is the text of Slack messages if no template is set
+const defaultSlackTemplate = `*host-sensor* found {{len .Findings}} new finding(s) on node *{{.Node}}*:
+{{range .Findings}}• [{{.Severity}}] {{.RuleID}}: {{.Message}}
+{{end}}`
+
+// Alert is the data of a webhook, also used as the template data
+type Alert struct {
+	Time     time.Time            `json:"time"`
+	Node     string               `json:"node"`
+	Findings []evaluation.Finding `json:"findings"`
+}
+
+// Options of a Webhook
+type Options struct {
+	URL string
+
+	// One of Format*. Defaults to FormatGeneric
+	Format string
+
+	// Go text/template of the payload (generic) or message text (Slack), rendered with an `Alert`.
+	// The `json` function encodes a value as JSON.
+	Template string
+
+	// Findings below this severity are ignored. Defaults to High
+	MinSeverity evaluation.Severity
+
+	// Maximal number of webhooks fired in RatePeriod. Defaults to 10 in an hour
+	RateLimit  int
+	RatePeriod time.Duration
+
+	HTTPClient *http.Client
+}
+
+func (o *Options) setDefaults() {
+	if o.Format == "" {
+		o.Format = FormatGeneric
+	}
+	if o.MinSeverity == "" {
+		o.MinSeverity = evaluation.SeverityHigh
+	}
+	if o.RateLimit <= 0 {
+		o.RateLimit = 10
+	}
+	if o.RatePeriod <= 0 {
+		o.RatePeriod = time.Hour
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+}
+
+// Webhook fires alerts for new findings
+type Webhook struct {
+	opts     Options
+	template *template.Template
+
+	lock sync.Mutex
+
+	// times of the webhooks fired in the current rate period
+	fired []time.Time
+
+	// keys of the alerted findings of the previous scan
+	previous map[string]bool
+}
+
+// New creates a webhook
+func New(opts Options) (*Webhook, error) {
+	opts.setDefaults()
+	if opts.URL == "" {
+		return nil, fmt.Errorf("webhook URL is required")
+	}
+	if opts.Format != FormatGeneric && opts.Format != FormatSlack {
+		return nil, fmt.Errorf("invalid webhook format %q", opts.Format)
+	}
+	if !evaluation.IsValidSeverity(opts.MinSeverity) {
+		return nil, fmt.Errorf("invalid webhook severity %q", opts.MinSeverity)
+	}
+
+	w := &Webhook{opts: opts}
+	text := opts.Template
+	if text == "" && opts.Format == FormatSlack {
+		text = defaultSlackTemplate
+	}
+	if text != "" {
+		tmpl, err := template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse webhook template: %w", err)
+		}
+		w.template = tmpl
+	}
+	return w, nil
+}
+
+// Notify fires the webhook for the findings above the severity threshold which were not present in the previous call.
+// Alerts dropped by the rate limit are not retried.
+func (w *Webhook) Notify(ctx context.Context, node string, findings []evaluation.Finding) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	current := map[string]bool{}
+	alert := Alert{Time: time.Now().UTC(), Node: node, Findings: []evaluation.Finding{}}
+	for _, finding := range findings {
+		if !finding.Severity.AtLeast(w.opts.MinSeverity) {
+			continue
+		}
+		current[finding.Key()] = true
+		if !w.previous[finding.Key()] {
+			alert.Findings = append(alert.Findings, finding)
+		}
+	}
+
+	if len(alert.Findings) == 0 {
+		w.previous = current
+		return nil
+	}
+
+	if !w.allow(alert.Time) {
+		w.previous = current
+		return fmt.Errorf("rate limit exceeded, dropped alert of %d findings", len(alert.Findings))
+	}
+
+	if err := w.send(ctx, &alert); err != nil {
+		// keep the findings as new, so they will be alerted next time
+		for _, finding := range alert.Findings {
+			delete(current, finding.Key())
+		}
+		w.previous = current
+		return err
+	}
+	w.previous = current
+	return nil
+}
+
+// allow returns true if a webhook can be fired now, and records it
+func (w *Webhook) allow(now time.Time) bool {
+	fired := w.fired[:0]
+	for _, t := range w.fired {
+		if now.Sub(t) < w.opts.RatePeriod {
+			fired = append(fired, t)
+		}
+	}
+	w.fired = fired
+	if len(w.fired) >= w.opts.RateLimit {
+		return false
+	}
+	w.fired = append(w.fired, now)
+	return true
+}
+
+func (w *Webhook) send(ctx context.Context, alert *Alert) error {
+	body, err := w.payload(alert)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.opts.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.opts.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// payload renders the request body of the alert
+func (w *Webhook) payload(alert *Alert) ([]byte, error) {
+	if w.template == nil {
+		return json.Marshal(alert)
+	}
+
+	rendered := bytes.Buffer{}
+	if err := w.template.Execute(&rendered, alert); err != nil {
+		return nil, fmt.Errorf("failed to render webhook template: %w", err)
+	}
+	if w.opts.Format == FormatSlack {
+		return json.Marshal(map[string]string{"text": rendered.String()})
+	}
+	return rendered.Bytes(), nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	content, err := json.Marshal(v)
+	return string(content), err
+}
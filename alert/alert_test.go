@@ -0,0 +1,81 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/armosec/host-sensor/evaluation"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	criticalFinding = evaluation.Finding{RuleID: "kubelet-anonymous-auth", Severity: evaluation.SeverityCritical, Message: "anonymous auth"}
+	lowFinding      = evaluation.Finding{RuleID: "low", Severity: evaluation.SeverityLow, Message: "low"}
+)
+
+func newTestServer(t *testing.T, bodies *[]string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		*bodies = append(*bodies, string(body))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestNotify(t *testing.T) {
+	bodies := []string{}
+	srv := newTestServer(t, &bodies)
+	w, err := New(Options{URL: srv.URL})
+	require.NoError(t, err)
+
+	require.NoError(t, w.Notify(context.Background(), "node-a", []evaluation.Finding{criticalFinding, lowFinding}))
+	require.Len(t, bodies, 1)
+	alert := Alert{}
+	require.NoError(t, json.Unmarshal([]byte(bodies[0]), &alert))
+	assert.Equal(t, "node-a", alert.Node)
+	assert.Equal(t, []evaluation.Finding{criticalFinding}, alert.Findings)
+
+	// already alerted
+	require.NoError(t, w.Notify(context.Background(), "node-a", []evaluation.Finding{criticalFinding}))
+	assert.Len(t, bodies, 1)
+
+	// alerted again after it was resolved
+	require.NoError(t, w.Notify(context.Background(), "node-a", nil))
+	require.NoError(t, w.Notify(context.Background(), "node-a", []evaluation.Finding{criticalFinding}))
+	assert.Len(t, bodies, 2)
+}
+
+func TestNotifyTemplateAndRateLimit(t *testing.T) {
+	bodies := []string{}
+	srv := newTestServer(t, &bodies)
+	w, err := New(Options{
+		URL:        srv.URL,
+		Format:     FormatSlack,
+		Template:   `{{range .Findings}}{{.RuleID}} on {{$.Node}}{{end}}`,
+		RateLimit:  1,
+		RatePeriod: time.Hour,
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, w.Notify(context.Background(), "node-a", []evaluation.Finding{criticalFinding}))
+	assert.Equal(t, []string{`{"text":"kubelet-anonymous-auth on node-a"}`}, bodies)
+
+	require.NoError(t, w.Notify(context.Background(), "node-a", nil))
+	assert.Error(t, w.Notify(context.Background(), "node-a", []evaluation.Finding{criticalFinding}))
+	assert.Len(t, bodies, 1)
+}
+
+func TestNewInvalid(t *testing.T) {
+	_, err := New(Options{URL: "http://localhost", Format: "teams"})
+	assert.Error(t, err)
+	_, err = New(Options{URL: "http://localhost", MinSeverity: "Severe"})
+	assert.Error(t, err)
+	_, err = New(Options{URL: "http://localhost", Template: "{{"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/armosec/host-sensor/alert"
+	"go.uber.org/zap"
+)
+
+// newWebhook creates the webhook of the configuration
+func newWebhook(conf *WebhookConfig) (*alert.Webhook, error) {
+	opts := alert.Options{
+		URL:         conf.URL,
+		Format:      conf.Format,
+		MinSeverity: conf.MinSeverity,
+		RateLimit:   conf.RateLimit,
+	}
+	if conf.TemplateFile != "" {
+		content, err := os.ReadFile(conf.TemplateFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read webhook template: %w", err)
+		}
+		opts.Template = string(content)
+	}
+	return alert.New(opts)
+}
+
+// newWebhookScanHandler returns a scan handler which fires the webhook for new findings, with the (anonymized) node
+// name of the report
+func newWebhookScanHandler(webhook *alert.Webhook, format string) scanHandler {
+	return func(ctx context.Context, report *ScanReport) {
+		if err := webhook.Notify(ctx, report.Identity.NodeName, report.Findings); err != nil {
+			zap.L().Error("failed to fire webhook", zap.String("format", format), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+)
+
+// This file contains the anonymization of the reports, which strips or hashes the hostnames, IP addresses, usernames
+// and file contents, for sharing the reports outside of the organization. The verdicts are evaluated before.
+
+const (
+	// Replace the identifying values with REDACTED
+	anonymizeModeStrip = "strip"
+
+	// Replace the identifying values with keyed hashes, so equal values have equal pseudonyms across reports
+	anonymizeModeHash = "hash"
+
+	// The key of the error of a failed anonymization, in the errors of the scan report
+	anonymizationErrorKey = "anonymization"
+
+	// Hostnames shorter than this are only replaced as whole values, since they're likely words of other values
+	minAnonymizedSubstring = 5
+)
+
+var (
+	// The keys of the values holding hostnames and host identifiers
+	anonymizedHostKeys = map[string]bool{
+		"hostname":           true,
+		"nodeName":           true,
+		"registeredNodeName": true,
+		"domainName":         true,
+		"proxyServerHost":    true,
+		"machineID":          true,
+		"bootID":             true,
+		"productUUID":        true,
+		"providerID":         true,
+	}
+
+	// The keys of the values holding usernames
+	anonymizedUserKeys = map[string]bool{
+		"username": true,
+		"user":     true,
+		"users":    true,
+	}
+
+	// The values which don't identify the node
+	anonymizeKeptValues = map[string]bool{"": true, "root": true, "localhost": true, "(none)": true}
+
+	// The candidates of IP addresses in strings, validated by parsing
+	ipCandidatePattern = regexp.MustCompile(`[0-9A-Fa-f:.]*[:.][0-9A-Fa-f:.]*`)
+
+	// The pseudonyms, which aren't anonymized again
+	pseudonymPattern = regexp.MustCompile(`^(host|user|ip)-[0-9a-f]{12}$`)
+
+	errRawContentAnonymized = &sensor.SenseError{
+		Massage: "raw file contents aren't served when anonymization is enabled",
+		Kind:    "Anonymized",
+		Code:    http.StatusForbidden,
+	}
+
+	errAnonymizationFailed = &sensor.SenseError{
+		Massage: "failed to anonymize the scan report",
+		Kind:    "AnonymizationFailed",
+		Code:    http.StatusInternalServerError,
+	}
+
+	// reportAnonymizer anonymizes the reports and the sensor responses, nil if disabled
+	reportAnonymizer *anonymizer
+)
+
+// anonymizer strips or hashes the identifying values of JSON documents
+type anonymizer struct {
+	mode string
+	key  []byte
+
+	// the identifiers of the node, see `gatherNodeIdentifiers`
+	node nodeIdentifiers
+}
+
+// nodeIdentifiers are the identifiers of the node, anonymized in every document, including the values whose key
+// doesn't tell they're identifiers (e.g. command lines and kubeconfig server URLs)
+type nodeIdentifiers struct {
+	hosts map[string]bool
+	ips   map[string]bool
+}
+
+// newAnonymizer returns an anonymizer of the mode, replacing the identifiers of the node besides the ones of the
+// documents. The pseudonyms are keyed by the content of `keyFile`, or by a random key if it's empty, and then are
+// consistent until the sensor restarts.
+func newAnonymizer(mode, keyFile string, node nodeIdentifiers) (*anonymizer, error) {
+	key := make([]byte, 32)
+	if keyFile != "" {
+		content, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read anonymization key: %w", err)
+		}
+		key = bytes.TrimSpace(content)
+	} else if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate anonymization key: %w", err)
+	}
+	return &anonymizer{mode: mode, key: key, node: node}, nil
+}
+
+// gatherNodeIdentifiers returns the identifiers of the node: its hostname and node names (including the kubelet
+// --hostname-override), its machine identifiers, the server names of its kubeconfigs and its IP addresses
+func gatherNodeIdentifiers(ctx context.Context, nodeName string) nodeIdentifiers {
+	ret := nodeIdentifiers{hosts: map[string]bool{}, ips: map[string]bool{}}
+	addHost := func(host string) {
+		if host = strings.TrimSpace(host); anonymizeKeptValues[host] {
+			return
+		}
+		if ip := net.ParseIP(host); ip != nil {
+			ret.ips[ip.String()] = true
+			return
+		}
+		ret.hosts[host] = true
+	}
+
+	addHost(nodeName)
+	if hostname, err := os.Hostname(); err == nil {
+		addHost(hostname)
+	}
+	if identity, err := sensor.SenseNodeIdentity(ctx); err == nil {
+		addHost(identity.Hostname)
+		addHost(identity.NodeName)
+	}
+	if proc, err := sensor.LocateKubeletProcess(ctx); err == nil {
+		if override, ok := proc.GetArg("--hostname-override"); ok {
+			addHost(override)
+		}
+	}
+	if info, err := sensor.SenseHostInfo(ctx); err == nil {
+		for _, id := range []string{info.Uname.NodeName, info.Uname.DomainName, info.MachineID, info.BootID, info.ProductUUID} {
+			addHost(id)
+		}
+	}
+	if kubeconfigs, err := sensor.SenseKubeconfigs(ctx); err == nil {
+		for i := range kubeconfigs {
+			for _, cluster := range kubeconfigs[i].Clusters {
+				if server, err := url.Parse(cluster.Server); err == nil {
+					addHost(server.Hostname())
+				}
+			}
+		}
+	}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && !ipNet.IP.IsUnspecified() {
+				ret.ips[ipNet.IP.String()] = true
+			}
+		}
+	} else {
+		zap.L().Debug("failed to list the IP addresses of the node", zap.Error(err))
+	}
+	return ret
+}
+
+// pseudonym returns the replacement of a value of the kind, "host", "user" or "ip"
+func (a *anonymizer) pseudonym(kind, value string) string {
+	if a.mode == anonymizeModeStrip {
+		return redacted
+	}
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(value))
+	return kind + "-" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// anonymizeInto anonymizes the JSON encoding of `value` into `out`
+func (a *anonymizer) anonymizeInto(value, out interface{}) error {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if content, err = a.anonymize(content); err != nil {
+		return err
+	}
+	return json.Unmarshal(content, out)
+}
+
+// anonymize returns a JSON document without its hostnames, IP addresses (other than the loopback and unspecified
+// addresses), usernames and file contents
+func (a *anonymizer) anonymize(content []byte) ([]byte, error) {
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+
+	hosts, users := map[string]bool{}, map[string]bool{}
+	for host := range a.node.hosts {
+		hosts[host] = true
+	}
+	collectIdentifiers(doc, "", hosts, users)
+	replacer := a.newValueReplacer(hosts, users)
+	return json.Marshal(a.rewrite(doc, "", replacer))
+}
+
+// collectIdentifiers collects the hostnames and the usernames of a decoded JSON value
+func collectIdentifiers(value interface{}, key string, hosts, users map[string]bool) {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		for k, item := range typed {
+			collectIdentifiers(item, k, hosts, users)
+		}
+	case []interface{}:
+		for _, item := range typed {
+			collectIdentifiers(item, key, hosts, users)
+		}
+	case string:
+		if anonymizeKeptValues[typed] || pseudonymPattern.MatchString(typed) || net.ParseIP(typed) != nil {
+			return
+		}
+		if anonymizedHostKeys[key] {
+			hosts[typed] = true
+		} else if anonymizedUserKeys[key] {
+			users[typed] = true
+		}
+	}
+}
+
+// valueReplacer replaces the identifiers in strings
+type valueReplacer struct {
+	// The pseudonyms of the whole values
+	values map[string]string
+
+	// The patterns of the identifiers in longer strings, and their pseudonyms
+	patterns     []*regexp.Regexp
+	replacements []string
+}
+
+// newValueReplacer returns the replacer of the hostnames, the usernames and the IP addresses of the node. Hostnames
+// are replaced in every string, usernames in the writers (e.g. "user:alice") and the home directories. The other IP
+// addresses are replaced by `rewriteString`.
+func (a *anonymizer) newValueReplacer(hosts, users map[string]bool) *valueReplacer {
+	ret := &valueReplacer{values: map[string]string{}}
+	add := func(pattern, replacement string) {
+		ret.patterns = append(ret.patterns, regexp.MustCompile(pattern))
+		ret.replacements = append(ret.replacements, replacement)
+	}
+	// the longest first, so a hostname isn't replaced within its FQDN
+	for _, host := range sortedByLength(hosts) {
+		pseudonym := a.pseudonym("host", host)
+		ret.values[host] = pseudonym
+		if len(host) >= minAnonymizedSubstring {
+			add(`(^|[^A-Za-z0-9-])`+regexp.QuoteMeta(host)+`($|[^A-Za-z0-9-])`, "${1}"+pseudonym+"${2}")
+		}
+	}
+	for _, user := range sortedByLength(users) {
+		pseudonym := a.pseudonym("user", user)
+		ret.values[user] = pseudonym
+		add(`(user:|/home/)`+regexp.QuoteMeta(user)+`($|[^A-Za-z0-9_.-])`, "${1}"+pseudonym+"${2}")
+	}
+	// e.g. the IPv6 addresses in brackets, or the IPv4 addresses of hostnames as "ip-10-0-0-1"
+	for _, ip := range sortedByLength(a.node.ips) {
+		pseudonym := a.pseudonym("ip", ip)
+		ret.values[ip] = pseudonym
+		add(`(^|[^0-9A-Fa-f:.])`+regexp.QuoteMeta(ip)+`($|[^0-9A-Fa-f:])`, "${1}"+pseudonym+"${2}")
+		if dashed := strings.ReplaceAll(ip, ".", "-"); dashed != ip {
+			add(`(^|[^0-9])`+regexp.QuoteMeta(dashed)+`($|[^0-9])`, "${1}"+pseudonym+"${2}")
+		}
+	}
+	return ret
+}
+
+// sortedByLength returns the strings of a set, the longest first
+func sortedByLength(set map[string]bool) []string {
+	ret := []string{}
+	for str := range set {
+		ret = append(ret, str)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if len(ret[i]) != len(ret[j]) {
+			return len(ret[i]) > len(ret[j])
+		}
+		return ret[i] < ret[j]
+	})
+	return ret
+}
+
+// rewrite returns a decoded JSON value with its identifiers replaced, and without file contents
+func (a *anonymizer) rewrite(value interface{}, key string, replacer *valueReplacer) interface{} {
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		// every content is dropped, e.g. the Corefile of the DNS servers; the file contents are base64 encoded
+		// strings next to a path, and are replaced by their hash
+		if content, ok := typed["content"]; ok {
+			delete(typed, "content")
+			_, isFile := typed["path"]
+			if encoded, ok := content.(string); ok && isFile && a.mode == anonymizeModeHash && typed["sha256"] == nil {
+				if decoded, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+					sum := sha256.Sum256(decoded)
+					typed["sha256"] = hex.EncodeToString(sum[:])
+				}
+			}
+		}
+		for k, item := range typed {
+			typed[k] = a.rewrite(item, k, replacer)
+		}
+		return typed
+	case []interface{}:
+		for i, item := range typed {
+			typed[i] = a.rewrite(item, key, replacer)
+		}
+		return typed
+	case string:
+		return a.rewriteString(typed, replacer)
+	}
+	return value
+}
+
+// rewriteString returns a string with its identifiers replaced
+func (a *anonymizer) rewriteString(str string, replacer *valueReplacer) string {
+	if pseudonym, ok := replacer.values[str]; ok {
+		return pseudonym
+	}
+	for i, pattern := range replacer.patterns {
+		str = pattern.ReplaceAllString(str, replacer.replacements[i])
+	}
+	return ipCandidatePattern.ReplaceAllStringFunc(str, func(candidate string) string {
+		// e.g. "10.0.0.1" of "10.0.0.1:6443", or of "10.0.0.1." at the end of a sentence
+		trimmed := strings.TrimRight(candidate, ":.")
+		if net.ParseIP(trimmed) == nil {
+			if host, _, err := net.SplitHostPort(trimmed); err == nil {
+				trimmed = host
+			}
+		}
+		ip := net.ParseIP(trimmed)
+		if ip == nil || ip.IsLoopback() || ip.IsUnspecified() || !strings.HasPrefix(candidate, trimmed) {
+			return candidate
+		}
+		return a.pseudonym("ip", trimmed) + candidate[len(trimmed):]
+	})
+}
+
+// anonymizeResult returns the anonymized JSON encoding of a result, or the result if anonymization is disabled
+func anonymizeResult(result interface{}) (interface{}, error) {
+	if reportAnonymizer == nil {
+		return result, nil
+	}
+	anonymized := json.RawMessage{}
+	if err := reportAnonymizer.anonymizeInto(result, &anonymized); err != nil {
+		return nil, err
+	}
+	return anonymized, nil
+}
+
+// anonymizeText returns an anonymized plain text response of a sensor, e.g. the kubelet command line, or the text if
+// anonymization is disabled
+func anonymizeText(text []byte) ([]byte, error) {
+	if reportAnonymizer == nil {
+		return text, nil
+	}
+	anonymized := ""
+	if err := reportAnonymizer.anonymizeInto(string(text), &anonymized); err != nil {
+		return nil, err
+	}
+	return []byte(anonymized), nil
+}
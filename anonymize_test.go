@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/armosec/host-sensor/evaluation"
+	"github.com/armosec/host-sensor/sensor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnonymize(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "key")
+	require.NoError(t, os.WriteFile(keyFile, []byte("secret\n"), 0o600))
+	a, err := newAnonymizer(anonymizeModeHash, keyFile, nodeIdentifiers{})
+	require.NoError(t, err)
+
+	nodeIdentity, err := json.Marshal(sensor.NodeIdentity{Hostname: "worker-1.corp.example.com", NodeName: "worker-1", Role: sensor.NodeRoleWorker})
+	require.NoError(t, err)
+	kubeletInfo, err := json.Marshal(sensor.KubeletInfo{
+		ConfigFile: &sensor.FileInfo{
+			Path:      "/var/lib/kubelet/config.yaml",
+			Content:   []byte("kind: KubeletConfiguration\n"),
+			Ownership: &sensor.FileOwnership{Username: "alice", Groupname: "root"},
+		},
+		CmdLine: "/usr/bin/kubelet --node-ip=10.0.0.7 --hostname-override=worker-1 --healthz-bind-address=127.0.0.1 --root-dir=/home/alice/kubelet",
+	})
+	require.NoError(t, err)
+	report := &ScanReport{
+		Identity: Identity{NodeName: "worker-1", PodName: "host-sensor-x7k2p"},
+		Results:  map[string]json.RawMessage{"nodeIdentity": nodeIdentity, "kubeletInfo": kubeletInfo},
+		Findings: []evaluation.Finding{{RuleID: "kubelet-config-writable", Severity: evaluation.SeverityHigh, Message: "the kubelet config is writable by user:alice on worker-1 (10.0.0.7)"}},
+	}
+
+	anonymized := &ScanReport{}
+	require.NoError(t, a.anonymizeInto(report, anonymized))
+	host, user, ip := a.pseudonym("host", "worker-1"), a.pseudonym("user", "alice"), a.pseudonym("ip", "10.0.0.7")
+	assert.Regexp(t, `^host-[0-9a-f]{12}$`, host)
+
+	assert.Equal(t, host, anonymized.Identity.NodeName)
+	assert.Equal(t, "host-sensor-x7k2p", anonymized.Identity.PodName)
+	// the verdicts are kept
+	require.Len(t, anonymized.Findings, 1)
+	assert.Equal(t, "kubelet-config-writable", anonymized.Findings[0].RuleID)
+	assert.Equal(t, "the kubelet config is writable by user:"+user+" on "+host+" ("+ip+")", anonymized.Findings[0].Message)
+
+	identity := sensor.NodeIdentity{}
+	require.NoError(t, json.Unmarshal(anonymized.Results["nodeIdentity"], &identity))
+	assert.Equal(t, a.pseudonym("host", "worker-1.corp.example.com"), identity.Hostname)
+	assert.Equal(t, host, identity.NodeName)
+	assert.Equal(t, sensor.NodeRoleWorker, identity.Role)
+
+	kubelet := sensor.KubeletInfo{}
+	require.NoError(t, json.Unmarshal(anonymized.Results["kubeletInfo"], &kubelet))
+	require.NotNil(t, kubelet.ConfigFile)
+	assert.Nil(t, kubelet.ConfigFile.Content)
+	assert.Len(t, kubelet.ConfigFile.SHA256, 64)
+	assert.Equal(t, user, kubelet.ConfigFile.Ownership.Username)
+	assert.Equal(t, "root", kubelet.ConfigFile.Ownership.Groupname)
+	assert.Equal(t, "/usr/bin/kubelet --node-ip="+ip+" --hostname-override="+host+" --healthz-bind-address=127.0.0.1 --root-dir=/home/"+user+"/kubelet", kubelet.CmdLine)
+
+	// anonymizing twice changes nothing, and the pseudonyms are stable
+	again := &ScanReport{}
+	require.NoError(t, a.anonymizeInto(anonymized, again))
+	assert.Equal(t, anonymized.Findings, again.Findings)
+	assert.JSONEq(t, string(anonymized.Results["kubeletInfo"]), string(again.Results["kubeletInfo"]))
+}
+
+func TestAnonymizeStrip(t *testing.T) {
+	a, err := newAnonymizer(anonymizeModeStrip, "", nodeIdentifiers{})
+	require.NoError(t, err)
+
+	content, err := a.anonymize([]byte(`{"hostname": "worker-1", "server": "https://[fd00::1]:6443", "users": ["bob"], "file": {"path": "/etc/hosts", "content": "MTAuMC4wLjEgd29ya2VyLTE="}}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"hostname": "REDACTED", "server": "https://[REDACTED]:6443", "users": ["REDACTED"], "file": {"path": "/etc/hosts"}}`, string(content))
+	assert.False(t, strings.Contains(string(content), "sha256"))
+}
+
+func TestAnonymizeNodeIdentifiers(t *testing.T) {
+	node := nodeIdentifiers{
+		hosts: map[string]bool{"api.prod.example.com": true, "worker-7": true},
+		ips:   map[string]bool{"10.0.0.9": true},
+	}
+	a, err := newAnonymizer(anonymizeModeHash, "", node)
+	require.NoError(t, err)
+	host, server, ip := a.pseudonym("host", "worker-7"), a.pseudonym("host", "api.prod.example.com"), a.pseudonym("ip", "10.0.0.9")
+
+	// the identifiers of the node are replaced without a key telling they're identifiers
+	content, err := a.anonymize([]byte(`{
+		"cmdLine": "/usr/bin/kubelet --hostname-override=worker-7",
+		"clusters": [{"server": "https://api.prod.example.com:6443"}],
+		"awsName": "ip-10-0-0-9.ec2.internal",
+		"corefile": {"content": "LiA6NTMgeyBmb3J3YXJkIC4gMTAuMC4wLjkgfQ=="},
+		"config": {"content": {"nested": true}}
+	}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"cmdLine": "/usr/bin/kubelet --hostname-override=`+host+`",
+		"clusters": [{"server": "https://`+server+`:6443"}],
+		"awsName": "ip-`+ip+`.ec2.internal",
+		"corefile": {},
+		"config": {}
+	}`, string(content))
+}
+
+func TestAnonymizeReportFailed(t *testing.T) {
+	a, err := newAnonymizer(anonymizeModeHash, "", nodeIdentifiers{})
+	require.NoError(t, err)
+	report := &ScanReport{
+		Identity:  Identity{NodeName: "worker-1"},
+		RequestID: "scan-1",
+		Results:   map[string]json.RawMessage{"kubeletInfo": json.RawMessage(`{"cmdLine": `)},
+		Findings:  []evaluation.Finding{{RuleID: "kubelet-config-writable", Message: "writable on worker-1"}},
+	}
+
+	// nothing of the report is kept, and the scan is failed
+	anonymized := anonymizeReport(context.Background(), a, report)
+	assert.True(t, anonymized.Failed)
+	assert.Equal(t, "scan-1", anonymized.RequestID)
+	assert.Empty(t, anonymized.Identity.NodeName)
+	assert.Empty(t, anonymized.Results)
+	assert.Empty(t, anonymized.Findings)
+	require.Contains(t, anonymized.Errors, anonymizationErrorKey)
+	assert.Equal(t, "AnonymizationFailed", anonymized.Errors[anonymizationErrorKey].Kind)
+}
+
+func TestAnonymizeKubeletConfigurations(t *testing.T) {
+	defer func(orig *anonymizer) { reportAnonymizer = orig }(reportAnonymizer)
+	a, err := newAnonymizer(anonymizeModeHash, "", nodeIdentifiers{})
+	require.NoError(t, err)
+	reportAnonymizer = a
+
+	// the kubelet config is all file content, so it's refused rather than served
+	rw := httptest.NewRecorder()
+	kubeletConfigurationsHandler(rw, httptest.NewRequest(http.MethodGet, "/kubeletConfigurations", nil))
+	assert.Equal(t, http.StatusForbidden, rw.Code)
+	senseErr := sensor.SenseError{}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &senseErr))
+	assert.Equal(t, "Anonymized", senseErr.Kind)
+}
+
+func TestAnonymizeKubeletCommandLine(t *testing.T) {
+	root := t.TempDir()
+	cmdLine := "/usr/bin/kubelet\x00--node-ip=10.0.0.7\x00--hostname-override=worker-7\x00--healthz-bind-address=127.0.0.1"
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "proc", "42"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "proc", "42", "cmdline"), []byte(cmdLine), 0o644))
+	require.NoError(t, sensor.UseFixtureRoot(root))
+	defer sensor.ResetFixtureRoot()
+
+	defer func(orig *anonymizer) { reportAnonymizer = orig }(reportAnonymizer)
+	node := nodeIdentifiers{hosts: map[string]bool{"worker-7": true}, ips: map[string]bool{"10.0.0.7": true}}
+	a, err := newAnonymizer(anonymizeModeHash, "", node)
+	require.NoError(t, err)
+	reportAnonymizer = a
+
+	rw := httptest.NewRecorder()
+	kubeletCommandLineHandler(rw, httptest.NewRequest(http.MethodGet, "/kubeletCommandLine", nil))
+	require.Equal(t, http.StatusOK, rw.Code, rw.Body.String())
+	host, ip := a.pseudonym("host", "worker-7"), a.pseudonym("ip", "10.0.0.7")
+	assert.Equal(t, "/usr/bin/kubelet --node-ip="+ip+" --hostname-override="+host+" --healthz-bind-address=127.0.0.1", rw.Body.String())
+}
+
+func TestGatherNodeIdentifiers(t *testing.T) {
+	hfs := sensor.NewHostFS(t.TempDir())
+	for filePath, content := range map[string]string{
+		"/etc/machine-id":            "0123456789abcdef0123456789abcdef\n",
+		"/etc/kubernetes/admin.conf": "apiVersion: v1\nkind: Config\nclusters:\n- name: prod\n  cluster:\n    server: https://api.prod.example.com:6443\n",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(hfs.Path(filePath)), 0o755))
+		require.NoError(t, os.WriteFile(hfs.Path(filePath), []byte(content), 0o644))
+	}
+
+	node := gatherNodeIdentifiers(sensor.WithHostFS(context.Background(), hfs), "worker-7")
+	assert.True(t, node.hosts["worker-7"])
+	assert.True(t, node.hosts["0123456789abcdef0123456789abcdef"])
+	assert.True(t, node.hosts["api.prod.example.com"])
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	assert.True(t, node.hosts[hostname])
+	for ip := range node.ips {
+		assert.False(t, net.ParseIP(ip).IsLoopback(), ip)
+	}
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+)
+
+// maximal number of audit records kept in memory, the oldest are dropped first
+const maxAuditRecords = 1000
+
+var (
+	errAuditDisabled = &sensor.SenseError{
+		Massage: "audit log is disabled",
+		Kind:    "AuditDisabled",
+		Code:    http.StatusServiceUnavailable,
+	}
+
+	// auditedPaths are the endpoints serving file contents and credentials (e.g. private keys and kubeconfigs).
+	// Paths ending with `/` match their sub-paths.
+	auditedPaths = []string{
+		"/kubeletConfigurations",
+		"/kubeletInfo",
+		"/kubeProxyInfo",
+		"/controlPlaneInfo",
+		"/kubeconfigs",
+		"/privateKeys",
+		"/tokens",
+		"/registryCredentials",
+		"/certificates",
+		"/etcdEncryption",
+		"/kubeadmArtifacts",
+		"/staticPods",
+		"/scanReport",
+		"/scan",
+		"/jobs/",
+		"/clusterReport",
+		"/debug/bundle",
+	}
+
+	// auditTrail keeps the audit records, nil if the audit log is disabled
+	auditTrail *auditLog
+)
+
+// AuditRecord is a request to an endpoint serving sensitive content
+type AuditRecord struct {
+	Time time.Time `json:"time"`
+
+	// The authenticated user (the token subject), empty if authorization is disabled
+	User string `json:"user,omitempty"`
+
+	// Common name of the client certificate, empty if there is none
+	ClientCN string `json:"clientCN,omitempty"`
+
+	SourceIP string `json:"sourceIP"`
+
+	// ID of the request, see `identifyRequest`
+	RequestID string `json:"requestID,omitempty"`
+
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	Status int    `json:"status"`
+}
+
+// auditLog logs the records, and keeps the latest in memory
+type auditLog struct {
+	lock    sync.Mutex
+	records []AuditRecord
+}
+
+func newAuditLog() *auditLog {
+	return &auditLog{}
+}
+
+func (a *auditLog) add(record AuditRecord) {
+	zap.L().Info("audit",
+		zap.String("user", record.User),
+		zap.String("clientCN", record.ClientCN),
+		zap.String("sourceIP", record.SourceIP),
+		zap.String("requestID", record.RequestID),
+		zap.String("method", record.Method),
+		zap.String("path", record.Path),
+		zap.Int("status", record.Status))
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if len(a.records) >= maxAuditRecords {
+		a.records = a.records[len(a.records)-maxAuditRecords+1:]
+	}
+	a.records = append(a.records, record)
+}
+
+// list returns the kept records, oldest first
+func (a *auditLog) list() []AuditRecord {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	return append([]AuditRecord{}, a.records...)
+}
+
+func isAuditedPath(path string) bool {
+	for _, audited := range auditedPaths {
+		if path == audited || (strings.HasSuffix(audited, "/") && strings.HasPrefix(path, audited)) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditRequest is a middleware recording the requests to the audited paths (if the audit log is enabled)
+func auditRequest(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if auditTrail == nil || !isAuditedPath(r.URL.Path) {
+		next(rw, r)
+		return
+	}
+
+	record := AuditRecord{
+		Time:      time.Now().UTC(),
+		User:      requestUser(r.Context()),
+		SourceIP:  r.RemoteAddr,
+		RequestID: sensor.RequestID(r.Context()),
+		Method:    r.Method,
+		Path:      r.URL.Path,
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		record.SourceIP = host
+	}
+	if r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		record.ClientCN = r.TLS.PeerCertificates[0].Subject.CommonName
+	}
+
+	next(rw, r)
+
+	record.Status = http.StatusOK
+	if statusWriter, ok := rw.(interface{ Status() int }); ok && statusWriter.Status() != 0 {
+		record.Status = statusWriter.Status()
+	}
+	auditTrail.add(record)
+}
+
+// auditHandler responds with the kept audit records, oldest first
+func auditHandler(rw http.ResponseWriter, r *http.Request) {
+	if auditTrail == nil {
+		writeSenseError(rw, errAuditDisabled, "audit")
+		return
+	}
+	GenericSensorHandler(rw, r, auditTrail.list(), nil, "audit")
+}
+
+type userContextKey struct{}
+
+// withRequestUser returns a context holding the authenticated user of the request
+func withRequestUser(ctx context.Context, user string) context.Context {
+	return context.WithValue(ctx, userContextKey{}, user)
+}
+
+// requestUser returns the authenticated user of the request, empty if not authenticated
+func requestUser(ctx context.Context) string {
+	user, _ := ctx.Value(userContextKey{}).(string)
+	return user
+}
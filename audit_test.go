@@ -0,0 +1,60 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/codegangsta/negroni"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditRequest(t *testing.T) {
+	defer func() { auditTrail = nil }()
+	auditTrail = newAuditLog()
+
+	forbidden := func(rw http.ResponseWriter, r *http.Request) { rw.WriteHeader(http.StatusForbidden) }
+	ok := func(rw http.ResponseWriter, r *http.Request) { rw.WriteHeader(http.StatusOK) }
+
+	r := httptest.NewRequest(http.MethodGet, "/privateKeys", nil)
+	r.RemoteAddr = "10.0.0.1:5555"
+	r = r.WithContext(withRequestUser(r.Context(), "system:serviceaccount:kubescape:kubescape"))
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{Subject: pkix.Name{CommonName: "collector"}}}}
+	auditRequest(negroni.NewResponseWriter(httptest.NewRecorder()), r, forbidden)
+
+	// not audited
+	auditRequest(negroni.NewResponseWriter(httptest.NewRecorder()), httptest.NewRequest(http.MethodGet, "/osRelease", nil), ok)
+
+	auditRequest(negroni.NewResponseWriter(httptest.NewRecorder()), httptest.NewRequest(http.MethodGet, "/jobs/1234", nil), ok)
+
+	records := auditTrail.list()
+	require.Len(t, records, 2)
+	assert.Equal(t, "system:serviceaccount:kubescape:kubescape", records[0].User)
+	assert.Equal(t, "collector", records[0].ClientCN)
+	assert.Equal(t, "10.0.0.1", records[0].SourceIP)
+	assert.Equal(t, "/privateKeys", records[0].Path)
+	assert.Equal(t, http.StatusForbidden, records[0].Status)
+	assert.Equal(t, "/jobs/1234", records[1].Path)
+	assert.Equal(t, http.StatusOK, records[1].Status)
+
+	rw := httptest.NewRecorder()
+	auditHandler(rw, httptest.NewRequest(http.MethodGet, "/audit", nil))
+	served := []AuditRecord{}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &served))
+	assert.Len(t, served, 2)
+}
+
+func TestAuditLogLimit(t *testing.T) {
+	log := newAuditLog()
+	for i := 0; i < maxAuditRecords+10; i++ {
+		log.add(AuditRecord{Status: i})
+	}
+	records := log.list()
+	assert.Len(t, records, maxAuditRecords)
+	assert.Equal(t, 10, records[0].Status)
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armosec/host-sensor/k8s"
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+)
+
+// This file contains the authentication and authorization of API consumers.
+
+const (
+	authModeNone        = ""
+	authModeTokenReview = "tokenreview"
+
+	authCacheTTL = time.Minute
+
+	// the rejected tokens are cached shortly, so they don't call the API server on every request, while new tokens
+	// are accepted soon
+	authNegativeCacheTTL = 10 * time.Second
+)
+
+var (
+	errUnauthenticated = &sensor.SenseError{
+		Massage: "unauthenticated",
+		Kind:    "Unauthenticated",
+		Code:    http.StatusUnauthorized,
+	}
+	errForbidden = &sensor.SenseError{
+		Massage: "forbidden",
+		Kind:    "Forbidden",
+		Code:    http.StatusForbidden,
+	}
+
+	// Endpoints which are accessible without authentication
+	unauthenticatedPaths = map[string]bool{
+		"/version": true,
+		"/healthz": true,
+	}
+
+	// authorizer authorizes the API requests, nil if authorization is disabled
+	authorizer *tokenReviewAuthorizer
+)
+
+// authDecision is the cached result of authorizing a token for a path
+type authDecision struct {
+	user            string
+	allowed         bool
+	unauthenticated bool
+	expires         time.Time
+}
+
+// tokenReviewAuthorizer authenticates service account tokens with TokenReview,
+// and authorizes the requested path with SubjectAccessReview (as a non resource URL).
+type tokenReviewAuthorizer struct {
+	client *k8s.Client
+
+	cache     map[string]authDecision
+	cacheLock sync.Mutex
+}
+
+func newTokenReviewAuthorizer(client *k8s.Client) *tokenReviewAuthorizer {
+	return &tokenReviewAuthorizer{
+		client: client,
+		cache:  map[string]authDecision{},
+	}
+}
+
+// authorize returns the authenticated user name, and whether it is allowed to `verb` the `path`.
+// Decisions are cached for `authCacheTTL` to avoid calling the API server on every request, and the unauthenticated
+// tokens for `authNegativeCacheTTL`.
+func (a *tokenReviewAuthorizer) authorize(ctx context.Context, token, verb, path string) (string, bool, error) {
+	tokenHash := sha256.Sum256([]byte(token))
+	cacheKey := hex.EncodeToString(tokenHash[:]) + ":" + verb + ":" + path
+
+	a.cacheLock.Lock()
+	decision, ok := a.cache[cacheKey]
+	a.cacheLock.Unlock()
+	if ok && time.Now().Before(decision.expires) {
+		if decision.unauthenticated {
+			return "", false, errUnauthenticated
+		}
+		return decision.user, decision.allowed, nil
+	}
+
+	review := k8s.NewTokenReview(token)
+	if err := a.client.Post(ctx, k8s.TokenReviewsPath, review, review); err != nil {
+		return "", false, fmt.Errorf("failed to review token: %w", err)
+	}
+	if !review.Status.Authenticated {
+		a.cacheDecision(cacheKey, authDecision{unauthenticated: true, expires: time.Now().Add(authNegativeCacheTTL)})
+		return "", false, errUnauthenticated
+	}
+
+	access := k8s.NewNonResourceAccessReview(&review.Status.User, verb, path)
+	if err := a.client.Post(ctx, k8s.SubjectAccessReviewsPath, access, access); err != nil {
+		return "", false, fmt.Errorf("failed to review access: %w", err)
+	}
+
+	decision = authDecision{
+		user:    review.Status.User.Username,
+		allowed: access.Status.Allowed,
+		expires: time.Now().Add(authCacheTTL),
+	}
+	a.cacheDecision(cacheKey, decision)
+	return decision.user, decision.allowed, nil
+}
+
+func (a *tokenReviewAuthorizer) cacheDecision(cacheKey string, decision authDecision) {
+	a.cacheLock.Lock()
+	defer a.cacheLock.Unlock()
+	// drop expired decisions, so the cache won't grow with rotated tokens
+	for key, cached := range a.cache {
+		if time.Now().After(cached.expires) {
+			delete(a.cache, key)
+		}
+	}
+	a.cache[cacheKey] = decision
+}
+
+// bearerToken returns the bearer token of the request, or empty string if there is none
+func bearerToken(r *http.Request) string {
+	const prefix = "bearer "
+	auth := r.Header.Get("Authorization")
+	if len(auth) <= len(prefix) || !strings.EqualFold(auth[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(auth[len(prefix):])
+}
+
+// authenticateRequest is a middleware rejecting requests of unauthorized callers (if authorization is enabled)
+func authenticateRequest(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if authorizer == nil || unauthenticatedPaths[r.URL.Path] {
+		next(rw, r)
+		return
+	}
+
+	token := bearerToken(r)
+	if token == "" {
+		writeSenseError(rw, errUnauthenticated, "authenticate")
+		return
+	}
+
+	user, allowed, err := authorizer.authorize(r.Context(), token, strings.ToLower(r.Method), r.URL.Path)
+	if err != nil {
+		if err == errUnauthenticated {
+			writeSenseError(rw, errUnauthenticated, "authenticate")
+			return
+		}
+		sensor.Logger(r.Context()).Error("failed to authorize request", zap.String("path", r.URL.Path), zap.Error(err))
+		writeSenseError(rw, err, "authorize")
+		return
+	}
+
+	if !allowed {
+		sensor.Logger(r.Context()).Warn("request forbidden", zap.String("user", user), zap.String("path", r.URL.Path))
+		writeSenseError(rw, errForbidden, "authorize")
+		return
+	}
+
+	next(rw, r.WithContext(withRequestUser(r.Context(), user)))
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"sync/atomic"
+	"testing"
+
+	"github.com/armosec/host-sensor/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newFakeAuthAPIServer returns a client of a fake API server, which authenticates `validToken`
+// as `allowedUser`, allowed to get `allowedPath` only
+func newFakeAuthAPIServer(t *testing.T, reviews *int32) *k8s.Client {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case k8s.TokenReviewsPath:
+			atomic.AddInt32(reviews, 1)
+			review := k8s.TokenReview{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&review))
+			if review.Spec.Token == "valid-token" {
+				review.Status.Authenticated = true
+				review.Status.User = k8s.UserInfo{Username: "system:serviceaccount:kubescape:kubescape"}
+			}
+			json.NewEncoder(w).Encode(review)
+		case k8s.SubjectAccessReviewsPath:
+			access := k8s.SubjectAccessReview{}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&access))
+			access.Status.Allowed = access.Spec.User == "system:serviceaccount:kubescape:kubescape" &&
+				access.Spec.NonResourceAttributes.Path == "/kubeletInfo" &&
+				access.Spec.NonResourceAttributes.Verb == "get"
+			json.NewEncoder(w).Encode(access)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	tokenFile := path.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("sensor-token"), 0600))
+	return k8s.NewClient(srv.URL, tokenFile, srv.Client(), "default")
+}
+
+func TestAuthenticateRequest(t *testing.T) {
+	var reviews int32
+	authorizer = newTokenReviewAuthorizer(newFakeAuthAPIServer(t, &reviews))
+	defer func() { authorizer = nil }()
+
+	serve := func(path, token string) int {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		authenticateRequest(w, r, func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(http.StatusOK)
+		})
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/version", ""))
+	assert.Equal(t, http.StatusUnauthorized, serve("/kubeletInfo", ""))
+	assert.Equal(t, http.StatusUnauthorized, serve("/kubeletInfo", "invalid-token"))
+	assert.Equal(t, http.StatusForbidden, serve("/controlPlaneInfo", "valid-token"))
+	assert.Equal(t, http.StatusOK, serve("/kubeletInfo", "valid-token"))
+
+	// cached decision
+	before := atomic.LoadInt32(&reviews)
+	assert.Equal(t, http.StatusOK, serve("/kubeletInfo", "valid-token"))
+	assert.Equal(t, before, atomic.LoadInt32(&reviews))
+
+	// cached rejection
+	assert.Equal(t, http.StatusUnauthorized, serve("/kubeletInfo", "invalid-token"))
+	assert.Equal(t, before, atomic.LoadInt32(&reviews))
+}
+
+func TestBearerToken(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	assert.Equal(t, "", bearerToken(r))
+	r.Header.Set("Authorization", "bearer foo")
+	assert.Equal(t, "foo", bearerToken(r))
+	r.Header.Set("Authorization", "Basic foo")
+	assert.Equal(t, "", bearerToken(r))
+}
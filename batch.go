@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/armosec/host-sensor/sensor"
+	"github.com/armosec/host-sensor/tracing"
+	"go.uber.org/zap"
+)
+
+// maximal size of a batch scan request body
+const batchScanMaxRequestSize = 64 * 1024
+
+// BatchScanRequest selects the sensors of a batch scan
+type BatchScanRequest struct {
+	Sensors []BatchSensor `json:"sensors"`
+}
+
+// BatchSensor is a sensor of a batch scan, with its options
+type BatchSensor struct {
+	Name string `json:"name"`
+
+	// Add the file contents to the result, true by default
+	Content *bool `json:"content,omitempty"`
+
+	// Let the sensor probe the network (e.g. query the time servers), true by default
+	Probes *bool `json:"probes,omitempty"`
+}
+
+// options returns the sense options of the sensor
+func (s *BatchSensor) options() sensor.SenseOptions {
+	opts := sensor.SenseOptions{Content: true, Probes: true}
+	if s.Content != nil {
+		opts.Content = *s.Content
+	}
+	if s.Probes != nil {
+		opts.Probes = *s.Probes
+	}
+	return opts
+}
+
+// BatchScanResult holds the results of the sensors of a batch scan, keyed by sensor name
+type BatchScanResult struct {
+	Time      time.Time `json:"time"`
+	Identity  Identity  `json:"identity"`
+	RequestID string    `json:"requestID,omitempty"`
+
+	Results          map[string]json.RawMessage          `json:"results"`
+	Errors           map[string]*sensor.SenseError       `json:"errors,omitempty"`
+	CollectionErrors map[string][]sensor.CollectionError `json:"collectionErrors,omitempty"`
+	Degraded         map[string]*sensor.Degradation      `json:"degraded,omitempty"`
+	Metrics          map[string]*sensor.SenseMetrics     `json:"metrics,omitempty"`
+}
+
+// validate returns an error if a sensor of the request isn't registered or is selected twice
+func (req *BatchScanRequest) validate() error {
+	if len(req.Sensors) == 0 {
+		return fmt.Errorf("no sensors selected")
+	}
+	selected := map[string]bool{}
+	for _, s := range req.Sensors {
+		if sensor.Lookup(s.Name) == nil {
+			return fmt.Errorf("unknown sensor %q", s.Name)
+		}
+		if selected[s.Name] {
+			return fmt.Errorf("sensor %q is selected twice", s.Name)
+		}
+		selected[s.Name] = true
+	}
+	return nil
+}
+
+// runBatchScan runs the selected sensors with their options, in the order of the request. `progress` (if not nil)
+// is called before running every sensor, with the number of completed sensors.
+func runBatchScan(ctx context.Context, req *BatchScanRequest, progress func(completed int, current string)) *BatchScanResult {
+	ctx, span := tracing.Start(ctx, "scan", tracing.KindInternal)
+	defer span.Finish()
+
+	conf := getConfig()
+	result := &BatchScanResult{
+		Time:             time.Now().UTC(),
+		Identity:         conf.Identity,
+		RequestID:        sensor.RequestID(ctx),
+		Results:          map[string]json.RawMessage{},
+		Errors:           map[string]*sensor.SenseError{},
+		CollectionErrors: map[string][]sensor.CollectionError{},
+		Degraded:         map[string]*sensor.Degradation{},
+		Metrics:          map[string]*sensor.SenseMetrics{},
+	}
+
+	for i := range req.Sensors {
+		s := sensor.Lookup(req.Sensors[i].Name)
+		if progress != nil {
+			progress(i, s.Name())
+		}
+		if conf.isSensorDisabled(s.Name()) {
+			result.Errors[s.Name()] = sensor.AsSenseError(sensor.ErrSensorDisabled, s.Name())
+			continue
+		}
+
+		var out json.RawMessage
+		var err error
+		senseCtx := sensor.WithSenseOptions(ctx, req.Sensors[i].options())
+		var collectionErrors []sensor.CollectionError
+		result.Metrics[s.Name()], collectionErrors = sensor.Instrument(senseCtx, s.Name(), func(ctx context.Context) { out, err = s.Sense(ctx) })
+		sensorMetrics.observe(s.Name(), result.Metrics[s.Name()], errorStatus(err, s.Name()))
+		if len(collectionErrors) > 0 {
+			result.CollectionErrors[s.Name()] = collectionErrors
+		}
+		if degradation := sensor.Degraded(ctx, collectionErrors); degradation != nil {
+			result.Degraded[s.Name()] = degradation
+		}
+		if err != nil {
+			result.Errors[s.Name()] = sensor.AsSenseError(err, s.Name())
+			continue
+		}
+		result.Results[s.Name()] = out
+	}
+
+	if reportAnonymizer != nil {
+		anonymized := &BatchScanResult{}
+		if err := reportAnonymizer.anonymizeInto(result, anonymized); err != nil {
+			sensor.Logger(ctx).Error("failed to anonymize batch scan result", zap.Error(err))
+			return &BatchScanResult{Time: result.Time, RequestID: result.RequestID, Results: map[string]json.RawMessage{}}
+		}
+		result = anonymized
+	}
+	return result
+}
+
+// batchScanHandler runs the sensors selected by the request body (POST only)
+func batchScanHandler(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		writeSenseError(rw, &sensor.SenseError{Massage: "method not allowed", Code: http.StatusMethodNotAllowed}, "scan")
+		return
+	}
+
+	req, err := readBatchScanRequest(rw, r)
+	if err != nil {
+		writeSenseError(rw, err, "scan")
+		return
+	}
+	release := acquireScanSlot(rw, r, "scan")
+	if release == nil {
+		return
+	}
+	defer release()
+	GenericSensorHandler(rw, r, runBatchScan(r.Context(), req, nil), nil, "scan")
+}
+
+// readBatchScanRequest reads and validates the batch scan request of the body
+func readBatchScanRequest(rw http.ResponseWriter, r *http.Request) (*BatchScanRequest, error) {
+	req := &BatchScanRequest{}
+	decoder := json.NewDecoder(http.MaxBytesReader(rw, r.Body, batchScanMaxRequestSize))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(req); err != nil {
+		return nil, &sensor.SenseError{Massage: fmt.Sprintf("invalid request: %v", err), Code: http.StatusBadRequest}
+	}
+	if err := req.validate(); err != nil {
+		return nil, &sensor.SenseError{Massage: err.Error(), Code: http.StatusBadRequest}
+	}
+	return req, nil
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/armosec/host-sensor/sensor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func init() {
+	sensor.Register(sensor.NewSensor("batchTest", func(ctx context.Context) (interface{}, error) {
+		return map[string]interface{}{
+			"configFile": sensor.FileInfo{Path: "/etc/test.conf", Content: []byte("secret")},
+			"probes":     sensor.GetSenseOptions(ctx).Probes,
+		}, nil
+	}))
+}
+
+func TestBatchScanHandler(t *testing.T) {
+	defer setConfig(getConfig())
+	setConfig(defaultConfig())
+
+	body := `{"sensors": [{"name": "batchTest", "content": false, "probes": false}]}`
+	rw := httptest.NewRecorder()
+	batchScanHandler(rw, httptest.NewRequest(http.MethodPost, "/scan", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	result := BatchScanResult{}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &result))
+	assert.Len(t, result.Results, 1)
+	assert.JSONEq(t, `{"configFile": {"path": "/etc/test.conf", "permissions": 0, "ownership": null}, "probes": false}`,
+		string(result.Results["batchTest"]))
+
+	body = `{"sensors": [{"name": "batchTest"}]}`
+	rw = httptest.NewRecorder()
+	batchScanHandler(rw, httptest.NewRequest(http.MethodPost, "/scan", strings.NewReader(body)))
+	require.Equal(t, http.StatusOK, rw.Code)
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &result))
+	assert.Contains(t, string(result.Results["batchTest"]), `"content":"c2VjcmV0"`)
+	assert.Contains(t, string(result.Results["batchTest"]), `"probes":true`)
+}
+
+func TestBatchScanHandlerInvalid(t *testing.T) {
+	for name, body := range map[string]string{
+		"empty":     `{"sensors": []}`,
+		"unknown":   `{"sensors": [{"name": "notASensor"}]}`,
+		"duplicate": `{"sensors": [{"name": "batchTest"}, {"name": "batchTest"}]}`,
+		"field":     `{"sensors": [{"name": "batchTest", "contents": false}]}`,
+	} {
+		rw := httptest.NewRecorder()
+		batchScanHandler(rw, httptest.NewRequest(http.MethodPost, "/scan", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, rw.Code, name)
+	}
+
+	rw := httptest.NewRecorder()
+	batchScanHandler(rw, httptest.NewRequest(http.MethodGet, "/scan", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+}
@@ -0,0 +1,611 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armosec/host-sensor/alert"
+	"github.com/armosec/host-sensor/evaluation"
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Config holds the host sensor configuration.
+// A Config must not be modified after it was set by `setConfig`.
+type Config struct {
+	// Names of the sensors to disable
+	DisabledSensors []string
+
+	// Initial level of the logger, changed at runtime through /logLevel
+	LogLevel zapcore.Level
+
+	// Interval of logging the repeated sensor warnings and errors, 0 logs every repeat
+	LogRepeatInterval time.Duration
+
+	// Names of the evaluation rules to disable
+	DisabledRules []string
+
+	// Maximal size in bytes of a file content added to a result, 0 means unlimited
+	MaxContentSize int64
+
+	// Certificates expiring within the window are flagged
+	CertExpiryWindow time.Duration
+
+	// Path of the configuration file, empty if not used
+	ConfigFile string
+
+	// Directory of the host root, detected at startup if empty
+	HostRoot string
+
+	// Generation of the active configuration, incremented on every (re)load
+	Generation int64
+
+	// Publish scan results as NodeScanReport custom resources
+	PublishCRD bool
+
+	// Emit Kubernetes Events on the Node object for new critical findings
+	EmitEvents bool
+
+	// Stream the changes and the new findings of the periodic scans as Server-Sent Events
+	EventStream bool
+
+	// Interval between periodic scans (used by NodeScanReport publishing, events and push)
+	ScanInterval time.Duration
+
+	// Maximal random delay added to the scan interval
+	ScanJitter time.Duration
+
+	// Time for draining the in-flight requests, scans and push reports on shutdown
+	ShutdownGracePeriod time.Duration
+
+	// Consecutive failures of a sensor from which it's degraded, and from which it's failing
+	HealthDegradedThreshold int
+	HealthFailingThreshold  int
+
+	// Requests per second of all the clients and of every client, 0 means unlimited
+	RateLimit       int
+	ClientRateLimit int
+
+	// Maximal number of concurrent scans (on demand sensing, batch scans and jobs) of all the clients and
+	// of every client, 0 means unlimited
+	MaxConcurrentScans       int
+	MaxClientConcurrentScans int
+
+	// Serve the sensor endpoints from the latest periodic scan, instead of sensing on every request
+	ServeCached bool
+
+	// Add the node object metadata to scan reports
+	NodeMetadata bool
+
+	// Identity of the sensor pod
+	Identity Identity
+
+	// Authorization mode of API consumers, one of authMode*
+	AuthMode string
+
+	// Log the requests to the endpoints serving sensitive content, and serve the latest at /audit
+	AuditLog bool
+
+	// Certificate and key of the server, TLS is disabled if empty
+	TLSCertFile string
+	TLSKeyFile  string
+
+	// CA of the client certificates, required from all the clients if set
+	TLSClientCAFile string
+
+	// Rules restricting paths to the client certificate identities (from the config file)
+	ClientAllowlist []ClientAllowRule
+
+	// Push scan reports to a remote collector
+	Push PushConfig
+
+	// Export traces of the requests, scans and sensors
+	Tracing TracingConfig
+
+	// Path of the scans history store, history is disabled if empty
+	HistoryFile string
+
+	// Number of scans to keep in the history store
+	HistorySize int
+
+	// Serve a cluster report, merged from the scan reports of the peer sensors
+	Aggregator bool
+
+	// Label selector of the peer sensor pods (in the sensor namespace)
+	AggregatorSelector string
+
+	// CA of the serving certificates of the peers, which are queried over TLS if the sensor serves TLS
+	AggregatorCAFile string
+
+	// Name verified in the serving certificates of the peers, their pod IP if empty
+	AggregatorServerName string
+
+	// Directory of exec sensor plugins, plugins are disabled if empty
+	PluginsDir string
+
+	// Timeout of a single plugin run
+	PluginTimeout time.Duration
+
+	// Monitor the integrity of the files collected by the sensors
+	Integrity bool
+
+	// File to persist the integrity baseline in, empty to keep it in memory only
+	IntegrityBaselineFile string
+
+	// Additional host paths to monitor, e.g. binaries
+	IntegrityPaths []string
+
+	// Detect likely secrets in the collected file contents
+	SecretScan bool
+
+	// Strip or hash the identifying values of the reports, one of anonymizeMode*, disabled if empty
+	Anonymize string
+
+	// File holding the key of the hashed pseudonyms, a random key is used if empty
+	AnonymizeKeyFile string
+
+	// Webhooks fired for new findings
+	Webhooks []WebhookConfig
+
+	// Observe the control plane processes between scans with eBPF
+	RuntimeObserver bool
+
+	// Names of the observed processes, empty for the defaults
+	RuntimeObserverProcesses []string
+
+	// Patterns of the reported opened files, empty for the defaults
+	RuntimeObserverPaths []string
+}
+
+// PushConfig configures pushing scan reports to a remote collector
+type PushConfig struct {
+	// URL of the collector, push is disabled if empty
+	URL string
+
+	// Compress the reports with gzip
+	Compress bool
+
+	// File holding the key for signing the reports with HMAC-SHA256
+	SigningKeyFile string
+
+	// Directory for spooling reports while the collector is unreachable
+	SpoolDir string
+
+	// Interval between heartbeats, 0 disables them
+	HeartbeatInterval time.Duration
+
+	// URL of the heartbeats, defaults to the collector URL
+	HeartbeatURL string
+}
+
+// TracingConfig configures exporting traces with OTLP over HTTP
+type TracingConfig struct {
+	// URL of the OTLP traces endpoint, tracing is disabled if empty
+	Endpoint string
+
+	// Headers of the export requests
+	Headers map[string]string
+
+	// Service name of the spans
+	ServiceName string
+}
+
+// WebhookConfig configures a webhook fired for new findings
+type WebhookConfig struct {
+	URL string
+
+	// One of alert.Format*
+	Format string
+
+	// File holding the payload template, empty for the default payload
+	TemplateFile string
+
+	// Minimal severity of the alerted findings
+	MinSeverity evaluation.Severity
+
+	// Maximal number of webhooks fired in an hour
+	RateLimit int
+}
+
+// Identity of the sensor pod, provided through the downward API
+type Identity struct {
+	NodeName     string `json:"nodeName,omitempty"`
+	PodName      string `json:"podName,omitempty"`
+	PodNamespace string `json:"podNamespace,omitempty"`
+}
+
+// logFields returns the identity as log fields
+func (i Identity) logFields() []zap.Field {
+	fields := []zap.Field{}
+	if i.NodeName != "" {
+		fields = append(fields, zap.String("nodeName", i.NodeName))
+	}
+	if i.PodName != "" {
+		fields = append(fields, zap.String("podName", i.PodName))
+	}
+	if i.PodNamespace != "" {
+		fields = append(fields, zap.String("podNamespace", i.PodNamespace))
+	}
+	return fields
+}
+
+var (
+	currentConfig     = defaultConfig()
+	currentConfigLock sync.RWMutex
+)
+
+// getConfig returns the active configuration
+func getConfig() *Config {
+	currentConfigLock.RLock()
+	defer currentConfigLock.RUnlock()
+	return currentConfig
+}
+
+// setConfig replaces the active configuration, and applies the settings of the sensor package
+func setConfig(conf *Config) {
+	currentConfigLock.Lock()
+	defer currentConfigLock.Unlock()
+	sensor.SetMaxContentSize(conf.MaxContentSize)
+	sensor.SetCertExpiryWindow(conf.CertExpiryWindow)
+	sensor.SetLogRepeatInterval(conf.LogRepeatInterval)
+	currentConfig = conf
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		LogLevel:          zapcore.DebugLevel,
+		LogRepeatInterval: 6 * time.Hour,
+
+		ScanInterval: time.Hour,
+		ScanJitter:   time.Minute,
+		HistorySize:  10,
+
+		ShutdownGracePeriod: 25 * time.Second,
+
+		HealthDegradedThreshold: 1,
+		HealthFailingThreshold:  3,
+
+		Push: PushConfig{HeartbeatInterval: time.Minute},
+
+		MaxConcurrentScans:       4,
+		MaxClientConcurrentScans: 2,
+
+		CertExpiryWindow: 30 * 24 * time.Hour,
+
+		AggregatorSelector: "name=host-sensor",
+		PluginTimeout:      sensor.ExecSensorTimeout,
+	}
+}
+
+// loadConfigFromEnv reads the configuration from environment variables
+func loadConfigFromEnv() (*Config, error) {
+	conf := defaultConfig()
+
+	conf.DisabledSensors = getListEnv("HOST_SENSOR_DISABLED_SENSORS")
+
+	var err error
+	if val := os.Getenv("HOST_SENSOR_LOG_LEVEL"); val != "" {
+		if conf.LogLevel, err = parseLogLevel(val); err != nil {
+			return nil, fmt.Errorf("invalid HOST_SENSOR_LOG_LEVEL value %q", val)
+		}
+	}
+	if conf.LogRepeatInterval, err = getDurationEnv("HOST_SENSOR_LOG_REPEAT_INTERVAL", conf.LogRepeatInterval); err != nil {
+		return nil, err
+	}
+	if conf.PublishCRD, err = getBoolEnv("HOST_SENSOR_PUBLISH_CRD"); err != nil {
+		return nil, err
+	}
+
+	if conf.EmitEvents, err = getBoolEnv("HOST_SENSOR_EMIT_EVENTS"); err != nil {
+		return nil, err
+	}
+
+	if conf.EventStream, err = getBoolEnv("HOST_SENSOR_EVENT_STREAM"); err != nil {
+		return nil, err
+	}
+
+	if conf.ScanInterval, err = getDurationEnv("HOST_SENSOR_SCAN_INTERVAL", conf.ScanInterval); err != nil {
+		return nil, err
+	}
+	if conf.ScanJitter, err = getDurationEnv("HOST_SENSOR_SCAN_JITTER", conf.ScanJitter); err != nil {
+		return nil, err
+	}
+	if conf.ShutdownGracePeriod, err = getDurationEnv("HOST_SENSOR_SHUTDOWN_GRACE_PERIOD", conf.ShutdownGracePeriod); err != nil {
+		return nil, err
+	}
+	if conf.HealthDegradedThreshold, err = getIntEnv("HOST_SENSOR_HEALTH_DEGRADED_THRESHOLD", conf.HealthDegradedThreshold); err != nil {
+		return nil, err
+	}
+	if conf.HealthFailingThreshold, err = getIntEnv("HOST_SENSOR_HEALTH_FAILING_THRESHOLD", conf.HealthFailingThreshold); err != nil {
+		return nil, err
+	}
+	if conf.HealthFailingThreshold < conf.HealthDegradedThreshold {
+		return nil, fmt.Errorf("HOST_SENSOR_HEALTH_FAILING_THRESHOLD must not be lower than HOST_SENSOR_HEALTH_DEGRADED_THRESHOLD")
+	}
+
+	if conf.RateLimit, err = getLimitEnv("HOST_SENSOR_RATE_LIMIT", conf.RateLimit); err != nil {
+		return nil, err
+	}
+	if conf.ClientRateLimit, err = getLimitEnv("HOST_SENSOR_CLIENT_RATE_LIMIT", conf.ClientRateLimit); err != nil {
+		return nil, err
+	}
+	if conf.MaxConcurrentScans, err = getLimitEnv("HOST_SENSOR_MAX_CONCURRENT_SCANS", conf.MaxConcurrentScans); err != nil {
+		return nil, err
+	}
+	if conf.MaxClientConcurrentScans, err = getLimitEnv("HOST_SENSOR_MAX_CLIENT_CONCURRENT_SCANS", conf.MaxClientConcurrentScans); err != nil {
+		return nil, err
+	}
+	if conf.CertExpiryWindow, err = getDurationEnv("HOST_SENSOR_CERT_EXPIRY_WINDOW", conf.CertExpiryWindow); err != nil {
+		return nil, err
+	}
+	if conf.ServeCached, err = getBoolEnv("HOST_SENSOR_SERVE_CACHED"); err != nil {
+		return nil, err
+	}
+
+	if conf.NodeMetadata, err = getBoolEnv("HOST_SENSOR_NODE_METADATA"); err != nil {
+		return nil, err
+	}
+
+	conf.Identity = Identity{
+		NodeName:     os.Getenv("NODE_NAME"),
+		PodName:      os.Getenv("POD_NAME"),
+		PodNamespace: os.Getenv("POD_NAMESPACE"),
+	}
+	conf.ConfigFile = os.Getenv("HOST_SENSOR_CONFIG_FILE")
+	conf.HostRoot = os.Getenv("HOST_SENSOR_HOST_ROOT")
+
+	conf.Push.URL = os.Getenv("HOST_SENSOR_PUSH_URL")
+	if conf.Push.Compress, err = getBoolEnv("HOST_SENSOR_PUSH_COMPRESS"); err != nil {
+		return nil, err
+	}
+	conf.Push.SigningKeyFile = os.Getenv("HOST_SENSOR_PUSH_SIGNING_KEY_FILE")
+	conf.Push.SpoolDir = os.Getenv("HOST_SENSOR_PUSH_SPOOL_DIR")
+	if conf.Push.HeartbeatInterval, err = getDurationEnv("HOST_SENSOR_PUSH_HEARTBEAT_INTERVAL", conf.Push.HeartbeatInterval); err != nil {
+		return nil, err
+	}
+	conf.Push.HeartbeatURL = os.Getenv("HOST_SENSOR_PUSH_HEARTBEAT_URL")
+
+	conf.Tracing.Endpoint = os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT")
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); conf.Tracing.Endpoint == "" && endpoint != "" {
+		conf.Tracing.Endpoint = strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+	if conf.Tracing.Headers, err = getHeadersEnv("OTEL_EXPORTER_OTLP_HEADERS"); err != nil {
+		return nil, err
+	}
+	if val := os.Getenv("OTEL_SERVICE_NAME"); val != "" {
+		conf.Tracing.ServiceName = val
+	}
+
+	conf.HistoryFile = os.Getenv("HOST_SENSOR_HISTORY_FILE")
+	if conf.HistorySize, err = getIntEnv("HOST_SENSOR_HISTORY_SIZE", conf.HistorySize); err != nil {
+		return nil, err
+	}
+
+	if conf.Aggregator, err = getBoolEnv("HOST_SENSOR_AGGREGATOR"); err != nil {
+		return nil, err
+	}
+	if val := os.Getenv("HOST_SENSOR_AGGREGATOR_SELECTOR"); val != "" {
+		conf.AggregatorSelector = val
+	}
+	conf.AggregatorCAFile = os.Getenv("HOST_SENSOR_AGGREGATOR_CA_FILE")
+	conf.AggregatorServerName = os.Getenv("HOST_SENSOR_AGGREGATOR_SERVER_NAME")
+
+	conf.PluginsDir = os.Getenv("HOST_SENSOR_PLUGINS_DIR")
+	if conf.PluginTimeout, err = getDurationEnv("HOST_SENSOR_PLUGIN_TIMEOUT", conf.PluginTimeout); err != nil {
+		return nil, err
+	}
+
+	if conf.Integrity, err = getBoolEnv("HOST_SENSOR_INTEGRITY"); err != nil {
+		return nil, err
+	}
+	conf.IntegrityBaselineFile = os.Getenv("HOST_SENSOR_INTEGRITY_BASELINE_FILE")
+	conf.IntegrityPaths = getListEnv("HOST_SENSOR_INTEGRITY_PATHS")
+
+	if conf.SecretScan, err = getBoolEnv("HOST_SENSOR_SECRET_SCAN"); err != nil {
+		return nil, err
+	}
+
+	conf.Anonymize = os.Getenv("HOST_SENSOR_ANONYMIZE")
+	if conf.Anonymize != "" && conf.Anonymize != anonymizeModeStrip && conf.Anonymize != anonymizeModeHash {
+		return nil, fmt.Errorf("invalid HOST_SENSOR_ANONYMIZE value %q", conf.Anonymize)
+	}
+	conf.AnonymizeKeyFile = os.Getenv("HOST_SENSOR_ANONYMIZE_KEY_FILE")
+
+	for _, format := range []string{alert.FormatGeneric, alert.FormatSlack} {
+		webhook, err := loadWebhookConfigFromEnv(format)
+		if err != nil {
+			return nil, err
+		}
+		if webhook != nil {
+			conf.Webhooks = append(conf.Webhooks, *webhook)
+		}
+	}
+
+	if conf.RuntimeObserver, err = getBoolEnv("HOST_SENSOR_EBPF"); err != nil {
+		return nil, err
+	}
+	conf.RuntimeObserverProcesses = getListEnv("HOST_SENSOR_EBPF_PROCESSES")
+	conf.RuntimeObserverPaths = getListEnv("HOST_SENSOR_EBPF_PATHS")
+
+	conf.AuthMode = os.Getenv("HOST_SENSOR_AUTH_MODE")
+	if conf.AuthMode != authModeNone && conf.AuthMode != authModeTokenReview {
+		return nil, fmt.Errorf("invalid HOST_SENSOR_AUTH_MODE value %q", conf.AuthMode)
+	}
+
+	if conf.AuditLog, err = getBoolEnv("HOST_SENSOR_AUDIT_LOG"); err != nil {
+		return nil, err
+	}
+
+	conf.TLSCertFile = os.Getenv("HOST_SENSOR_TLS_CERT_FILE")
+	conf.TLSKeyFile = os.Getenv("HOST_SENSOR_TLS_KEY_FILE")
+	conf.TLSClientCAFile = os.Getenv("HOST_SENSOR_TLS_CLIENT_CA_FILE")
+	if (conf.TLSCertFile == "") != (conf.TLSKeyFile == "") {
+		return nil, fmt.Errorf("HOST_SENSOR_TLS_CERT_FILE and HOST_SENSOR_TLS_KEY_FILE must be set together")
+	}
+	if conf.TLSClientCAFile != "" && conf.TLSCertFile == "" {
+		return nil, fmt.Errorf("HOST_SENSOR_TLS_CLIENT_CA_FILE requires HOST_SENSOR_TLS_CERT_FILE")
+	}
+	// the peers serve as the sensor does, and the service account token is never sent to them in cleartext
+	if conf.Aggregator && conf.TLSCertFile != "" && conf.AggregatorCAFile == "" {
+		return nil, fmt.Errorf("HOST_SENSOR_AGGREGATOR with TLS requires HOST_SENSOR_AGGREGATOR_CA_FILE")
+	}
+	if conf.Aggregator && conf.AuthMode == authModeTokenReview && conf.TLSCertFile == "" {
+		return nil, fmt.Errorf("HOST_SENSOR_AGGREGATOR with HOST_SENSOR_AUTH_MODE=tokenreview requires HOST_SENSOR_TLS_CERT_FILE")
+	}
+
+	if (conf.PublishCRD || conf.NodeMetadata || conf.EmitEvents) && conf.Identity.NodeName == "" {
+		return nil, fmt.Errorf("NODE_NAME environment variable is required when using the node object")
+	}
+
+	return conf, nil
+}
+
+// loadWebhookConfigFromEnv reads the configuration of the webhook of `format`, prefixed by HOST_SENSOR_WEBHOOK_
+// for generic webhooks and HOST_SENSOR_SLACK_ for Slack. It returns nil if the webhook URL is not set.
+func loadWebhookConfigFromEnv(format string) (*WebhookConfig, error) {
+	prefix := "HOST_SENSOR_WEBHOOK_"
+	if format == alert.FormatSlack {
+		prefix = "HOST_SENSOR_SLACK_"
+	}
+
+	webhook := &WebhookConfig{
+		URL:          os.Getenv(prefix + "URL"),
+		Format:       format,
+		TemplateFile: os.Getenv(prefix + "TEMPLATE_FILE"),
+		MinSeverity:  evaluation.Severity(os.Getenv(prefix + "SEVERITY")),
+	}
+	if webhook.URL == "" {
+		return nil, nil
+	}
+	if webhook.MinSeverity == "" {
+		webhook.MinSeverity = evaluation.SeverityHigh
+	}
+	if !evaluation.IsValidSeverity(webhook.MinSeverity) {
+		return nil, fmt.Errorf("invalid %sSEVERITY value %q", prefix, webhook.MinSeverity)
+	}
+
+	var err error
+	if webhook.RateLimit, err = getIntEnv(prefix+"RATE_LIMIT", 10); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// getListEnv parses a comma separated list environment variable
+func getListEnv(name string) []string {
+	var ret []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+// getHeadersEnv returns the headers of a comma separated list of key=value pairs, the values are URL encoded
+func getHeadersEnv(name string) (map[string]string, error) {
+	items := getListEnv(name)
+	if len(items) == 0 {
+		return nil, nil
+	}
+	ret := map[string]string{}
+	for _, item := range items {
+		key, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid %s header %q", name, item)
+		}
+		decoded, err := url.QueryUnescape(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s header %q: %w", name, item, err)
+		}
+		ret[strings.TrimSpace(key)] = decoded
+	}
+	return ret, nil
+}
+
+// getBoolEnv parses a boolean environment variable, an unset variable is false
+func getBoolEnv(name string) (bool, error) {
+	val := os.Getenv(name)
+	if val == "" {
+		return false, nil
+	}
+	ret, err := strconv.ParseBool(val)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s value %q: %w", name, val, err)
+	}
+	return ret, nil
+}
+
+// getDurationEnv parses a positive duration environment variable, an unset variable is `defaultValue`
+func getDurationEnv(name string, defaultValue time.Duration) (time.Duration, error) {
+	val := os.Getenv(name)
+	if val == "" {
+		return defaultValue, nil
+	}
+	ret, err := time.ParseDuration(val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q: %w", name, val, err)
+	}
+	if ret <= 0 {
+		return 0, fmt.Errorf("invalid %s value %q: must be positive", name, val)
+	}
+	return ret, nil
+}
+
+// getIntEnv parses a positive integer environment variable, an unset variable is `defaultValue`
+func getIntEnv(name string, defaultValue int) (int, error) {
+	val := os.Getenv(name)
+	if val == "" {
+		return defaultValue, nil
+	}
+	ret, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q: %w", name, val, err)
+	}
+	if ret <= 0 {
+		return 0, fmt.Errorf("invalid %s value %q: must be positive", name, val)
+	}
+	return ret, nil
+}
+
+// getLimitEnv parses a non-negative integer environment variable of a limit, where 0 means unlimited, an unset
+// variable is `defaultValue`
+func getLimitEnv(name string, defaultValue int) (int, error) {
+	val := os.Getenv(name)
+	if val == "" {
+		return defaultValue, nil
+	}
+	ret, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s value %q: %w", name, val, err)
+	}
+	if ret < 0 {
+		return 0, fmt.Errorf("invalid %s value %q: must not be negative", name, val)
+	}
+	return ret, nil
+}
+
+// needsKubeClient returns true if any of the enabled features uses the Kubernetes API
+func (c *Config) needsKubeClient() bool {
+	return c.PublishCRD || c.NodeMetadata || c.EmitEvents || c.Aggregator || c.AuthMode == authModeTokenReview
+}
+
+// isSensorDisabled returns true if the sensor is disabled by configuration
+func (c *Config) isSensorDisabled(sensorName string) bool {
+	return containsString(c.DisabledSensors, sensorName)
+}
+
+func containsString(list []string, str string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
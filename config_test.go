@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfigUnlimited(t *testing.T) {
+	for _, name := range []string{"HOST_SENSOR_RATE_LIMIT", "HOST_SENSOR_CLIENT_RATE_LIMIT", "HOST_SENSOR_MAX_CONCURRENT_SCANS", "HOST_SENSOR_MAX_CLIENT_CONCURRENT_SCANS"} {
+		t.Setenv(name, "0")
+	}
+	conf, err := loadConfigFromEnv()
+	require.NoError(t, err)
+	assert.Zero(t, conf.RateLimit)
+	assert.Zero(t, conf.ClientRateLimit)
+	assert.Zero(t, conf.MaxConcurrentScans)
+	assert.Zero(t, conf.MaxClientConcurrentScans)
+
+	defer func() { limiter = nil }()
+	limiter = newRequestLimiter(conf)
+	next := func(rw http.ResponseWriter, r *http.Request) { rw.WriteHeader(http.StatusOK) }
+	for i := 0; i < 10; i++ {
+		r := httptest.NewRequest(http.MethodGet, "/kubeletInfo", nil)
+		rw := httptest.NewRecorder()
+		limitRequests(rw, r, next)
+		assert.Equal(t, http.StatusOK, rw.Code)
+		assert.NotNil(t, acquireScanSlot(httptest.NewRecorder(), r, "kubeletInfo"))
+	}
+
+	t.Setenv("HOST_SENSOR_MAX_CONCURRENT_SCANS", "-1")
+	_, err = loadConfigFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"time"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+// This file contains the loading and hot-reloading of the configuration file (usually a mounted ConfigMap).
+
+const (
+	configFilePollInterval = 10 * time.Second
+)
+
+// fileConfig is the schema of the configuration file. All its fields are reloaded on change,
+// and take precedence over the environment variables.
+type fileConfig struct {
+	DisabledSensors []string `json:"disabledSensors,omitempty"`
+	DisabledRules   []string `json:"disabledRules,omitempty"`
+	MaxContentSize  *int64   `json:"maxContentSize,omitempty"`
+
+	ClientAllowlist []ClientAllowRule `json:"clientAllowlist,omitempty"`
+}
+
+// applyConfigFile returns a copy of `base` overridden by the config file content
+func applyConfigFile(base *Config, content []byte) (*Config, error) {
+	fileConf := fileConfig{}
+	if err := yaml.UnmarshalStrict(content, &fileConf); err != nil {
+		return nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+
+	conf := *base
+	if fileConf.DisabledSensors != nil {
+		conf.DisabledSensors = fileConf.DisabledSensors
+	}
+	if fileConf.DisabledRules != nil {
+		conf.DisabledRules = fileConf.DisabledRules
+	}
+	if fileConf.MaxContentSize != nil {
+		if *fileConf.MaxContentSize < 0 {
+			return nil, fmt.Errorf("invalid maxContentSize %d: must not be negative", *fileConf.MaxContentSize)
+		}
+		conf.MaxContentSize = *fileConf.MaxContentSize
+	}
+	if fileConf.ClientAllowlist != nil {
+		if err := validateAllowlist(fileConf.ClientAllowlist); err != nil {
+			return nil, err
+		}
+		conf.ClientAllowlist = fileConf.ClientAllowlist
+	}
+
+	return &conf, nil
+}
+
+// configFileWatcher reloads the configuration when the config file content changes.
+// Polling is used (rather than inotify) since ConfigMap volumes are updated by an atomic symlink swap.
+type configFileWatcher struct {
+	// configuration from the environment, the config file is applied on top of it
+	base *Config
+
+	lastHash []byte
+}
+
+func newConfigFileWatcher(base *Config) *configFileWatcher {
+	return &configFileWatcher{base: base}
+}
+
+// load reads the config file and activates it if its content changed.
+// It returns true if a new configuration was activated.
+func (w *configFileWatcher) load() (bool, error) {
+	content, err := os.ReadFile(w.base.ConfigFile)
+	if err != nil {
+		return false, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	hash := sha256.Sum256(content)
+	if bytes.Equal(hash[:], w.lastHash) {
+		return false, nil
+	}
+
+	conf, err := applyConfigFile(w.base, content)
+	if err != nil {
+		return false, err
+	}
+	conf.Generation = getConfig().Generation + 1
+
+	w.lastHash = hash[:]
+	setConfig(conf)
+	return true, nil
+}
+
+// watch polls the config file until `ctx` is done
+func (w *configFileWatcher) watch(ctx context.Context) {
+	ticker := time.NewTicker(configFilePollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := w.load()
+		if err != nil {
+			zap.L().Error("failed to reload config file, keeping the active configuration",
+				zap.String("path", w.base.ConfigFile), zap.Error(err))
+			continue
+		}
+		if changed {
+			zap.L().Info("config file reloaded",
+				zap.String("path", w.base.ConfigFile), zap.Int64("generation", getConfig().Generation))
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyConfigFile(t *testing.T) {
+	base := &Config{DisabledSensors: []string{"openedPorts"}, MaxContentSize: 10}
+
+	conf, err := applyConfigFile(base, []byte("disabledRules: [kubelet-anonymous-auth]\nmaxContentSize: 1024\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"openedPorts"}, conf.DisabledSensors)
+	assert.Equal(t, []string{"kubelet-anonymous-auth"}, conf.DisabledRules)
+	assert.Equal(t, int64(1024), conf.MaxContentSize)
+	assert.Equal(t, int64(10), base.MaxContentSize, "base config should not change")
+
+	conf, err = applyConfigFile(base, []byte("disabledSensors: []\n"))
+	require.NoError(t, err)
+	assert.Empty(t, conf.DisabledSensors)
+
+	_, err = applyConfigFile(base, []byte("maxContentSize: -1\n"))
+	assert.Error(t, err)
+
+	_, err = applyConfigFile(base, []byte("unknownField: true\n"))
+	assert.Error(t, err)
+}
+
+func TestConfigFileWatcherLoad(t *testing.T) {
+	defer setConfig(defaultConfig())
+
+	configFile := path.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("disabledSensors: [kubeletInfo]\n"), 0644))
+
+	base := defaultConfig()
+	base.ConfigFile = configFile
+	base.Generation = 1
+	setConfig(base)
+
+	watcher := newConfigFileWatcher(base)
+	changed, err := watcher.load()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, getConfig().isSensorDisabled("kubeletInfo"))
+	assert.Equal(t, int64(2), getConfig().Generation)
+
+	// unchanged content
+	changed, err = watcher.load()
+	require.NoError(t, err)
+	assert.False(t, changed)
+	assert.Equal(t, int64(2), getConfig().Generation)
+
+	// invalid content keeps the active configuration
+	require.NoError(t, os.WriteFile(configFile, []byte("disabledSensors: foo: bar\n"), 0644))
+	_, err = watcher.load()
+	assert.Error(t, err)
+	assert.True(t, getConfig().isSensorDisabled("kubeletInfo"))
+
+	require.NoError(t, os.WriteFile(configFile, []byte("disabledSensors: [openedPorts]\n"), 0644))
+	changed, err = watcher.load()
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.False(t, getConfig().isSensorDisabled("kubeletInfo"))
+	assert.Equal(t, int64(3), getConfig().Generation)
+}
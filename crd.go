@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/armosec/host-sensor/k8s"
+	"go.uber.org/zap"
+)
+
+// This file contains the controller mode, publishing the scan results as NodeScanReport custom resources.
+
+const (
+	nodeScanReportAPIVersion = "hostsensor.kubescape.io/v1alpha1"
+	nodeScanReportKind       = "NodeScanReport"
+	nodeScanReportResource   = "nodescanreports"
+	crdFieldManager          = "host-sensor"
+)
+
+// NodeScanReport is a custom resource holding the scan report of a single node
+type NodeScanReport struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Metadata   k8s.ObjectMeta     `json:"metadata"`
+	Spec       NodeScanReportSpec `json:"spec"`
+}
+
+// NodeScanReportSpec holds the scanned node name and its report
+type NodeScanReportSpec struct {
+	NodeName string      `json:"nodeName"`
+	Report   *ScanReport `json:"report"`
+}
+
+// nodeScanReportPath returns the API path of a NodeScanReport
+func nodeScanReportPath(namespace, name string) string {
+	return fmt.Sprintf("/apis/%s/namespaces/%s/%s/%s", nodeScanReportAPIVersion, namespace, nodeScanReportResource, name)
+}
+
+// publishReportCRD creates or updates the NodeScanReport of the node, owned by the node object
+func publishReportCRD(ctx context.Context, client *k8s.Client, nodeName string, report *ScanReport) error {
+	node := k8s.Node{}
+	if err := client.Get(ctx, k8s.NodePath(nodeName), &node); err != nil {
+		return fmt.Errorf("failed to get node %s: %w", nodeName, err)
+	}
+
+	obj := NodeScanReport{
+		APIVersion: nodeScanReportAPIVersion,
+		Kind:       nodeScanReportKind,
+		Metadata: k8s.ObjectMeta{
+			Name:            nodeName,
+			Namespace:       client.Namespace,
+			OwnerReferences: []k8s.OwnerReference{node.OwnerReference()},
+		},
+		Spec: NodeScanReportSpec{
+			NodeName: nodeName,
+			Report:   report,
+		},
+	}
+
+	return client.Apply(ctx, nodeScanReportPath(client.Namespace, nodeName), obj, crdFieldManager)
+}
+
+// crdPublisher publishes every scan report as the NodeScanReport of the node
+type crdPublisher struct {
+	client   *k8s.Client
+	nodeName string
+}
+
+func (p *crdPublisher) onScan(ctx context.Context, report *ScanReport) {
+	if err := publishReportCRD(ctx, p.client, p.nodeName, report); err != nil {
+		zap.L().Error("failed to publish NodeScanReport", zap.String("node", p.nodeName), zap.Error(err))
+	} else {
+		zap.L().Info("NodeScanReport published", zap.String("node", p.nodeName))
+	}
+}
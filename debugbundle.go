@@ -0,0 +1,216 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+)
+
+// This file contains the debug bundle, a tarball of what's needed to troubleshoot the sensor on a node.
+
+// maximal number of log lines kept in memory for the debug bundle
+const maxRecentLogs = 2000
+
+const redacted = "REDACTED"
+
+var (
+	// recentLogs keeps the latest log lines, it's a sink of the logger
+	recentLogs = &logRing{}
+
+	// detectedHostAccess is how the sensor accesses the host, nil if it senses a fixture
+	detectedHostAccess *sensor.HostAccess
+)
+
+// logRing keeps the latest log lines
+type logRing struct {
+	lock  sync.Mutex
+	lines [][]byte
+}
+
+// Write implements zapcore.WriteSyncer, every write is an encoded log entry
+func (l *logRing) Write(p []byte) (int, error) {
+	line := append([]byte{}, p...)
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if len(l.lines) >= maxRecentLogs {
+		l.lines = l.lines[len(l.lines)-maxRecentLogs+1:]
+	}
+	l.lines = append(l.lines, line)
+	return len(p), nil
+}
+
+// Sync implements zapcore.WriteSyncer
+func (l *logRing) Sync() error { return nil }
+
+// content returns the kept log lines, oldest first
+func (l *logRing) content() []byte {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return bytes.Join(l.lines, nil)
+}
+
+// SelfTest is the sensor's view of its environment
+type SelfTest struct {
+	Version string `json:"version"`
+	Time    string `json:"time"`
+
+	// How the sensor accesses the host, empty when sensing a fixture
+	HostAccess *sensor.HostAccess `json:"hostAccess,omitempty"`
+
+	// The failure of the host file system check, empty if it passed
+	HostRootError string `json:"hostRootError,omitempty"`
+
+	// The privileges the sensor lacks to read the whole host
+	MissingPrivileges []string `json:"missingPrivileges"`
+
+	// The registered sensors, and whether they're enabled
+	Sensors map[string]bool `json:"sensors"`
+
+	// Time of the latest periodic scan, empty if there was none
+	LatestScan string `json:"latestScan,omitempty"`
+
+	Goroutines int `json:"goroutines"`
+}
+
+// runSelfTest checks the sensor environment
+func runSelfTest(ctx context.Context) SelfTest {
+	conf := getConfig()
+	test := SelfTest{
+		Version:           buildVersion,
+		Time:              time.Now().UTC().Format(time.RFC3339),
+		HostAccess:        detectedHostAccess,
+		MissingPrivileges: sensor.MissingPrivileges(ctx),
+		Sensors:           map[string]bool{},
+		Goroutines:        runtime.NumGoroutine(),
+	}
+	if err := sensor.CheckHostRoot(); err != nil {
+		test.HostRootError = err.Error()
+	}
+	for _, s := range sensor.Registered() {
+		test.Sensors[s.Name()] = !conf.isSensorDisabled(s.Name())
+	}
+	if scheduler != nil {
+		if report := scheduler.latestReport(); report != nil {
+			test.LatestScan = report.Time.Format(time.RFC3339)
+		}
+	}
+	return test
+}
+
+// redactConfig returns a copy of the configuration without its secrets: the webhook URLs (which are the Slack
+// credentials), the export headers (e.g. authorization) and the URL credentials
+func redactConfig(conf *Config) Config {
+	ret := *conf
+	ret.Push.URL = redactURL(conf.Push.URL)
+	ret.Push.HeartbeatURL = redactURL(conf.Push.HeartbeatURL)
+	ret.Tracing.Endpoint = redactURL(conf.Tracing.Endpoint)
+	if conf.Tracing.Headers != nil {
+		ret.Tracing.Headers = map[string]string{}
+		for name := range conf.Tracing.Headers {
+			ret.Tracing.Headers[name] = redacted
+		}
+	}
+	ret.Webhooks = make([]WebhookConfig, len(conf.Webhooks))
+	for i := range conf.Webhooks {
+		ret.Webhooks[i] = conf.Webhooks[i]
+		ret.Webhooks[i].URL = redacted
+	}
+	return ret
+}
+
+// redactURL removes the credentials and the query of a URL
+func redactURL(rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return redacted
+	}
+	parsed.User, parsed.RawQuery = nil, ""
+	return parsed.String()
+}
+
+// buildDebugBundle returns a gzipped tarball of the version, the redacted configuration, the self-test, the latest
+// scan report (if any), the metrics and the recent logs
+func buildDebugBundle(ctx context.Context) ([]byte, error) {
+	files := []struct {
+		name    string
+		content func() ([]byte, error)
+	}{
+		{"version.json", func() ([]byte, error) {
+			return json.MarshalIndent(VersionInfo{Version: buildVersion, ConfigGeneration: getConfig().Generation}, "", "  ")
+		}},
+		{"config.json", func() ([]byte, error) { return json.MarshalIndent(redactConfig(getConfig()), "", "  ") }},
+		{"selftest.json", func() ([]byte, error) { return json.MarshalIndent(runSelfTest(ctx), "", "  ") }},
+		{"scanReport.json", func() ([]byte, error) {
+			if scheduler == nil || scheduler.latestReport() == nil {
+				return nil, nil
+			}
+			return json.MarshalIndent(scheduler.latestReport(), "", "  ")
+		}},
+		{"metrics.txt", func() ([]byte, error) {
+			buf := &bytes.Buffer{}
+			err := sensorMetrics.write(buf)
+			return buf.Bytes(), err
+		}},
+		{"logs.jsonl", func() ([]byte, error) { return recentLogs.content(), nil }},
+	}
+
+	buf := &bytes.Buffer{}
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	now := time.Now()
+	for _, file := range files {
+		content, err := file.content()
+		if err != nil {
+			return nil, fmt.Errorf("failed to bundle %s: %w", file.name, err)
+		}
+		if content == nil {
+			continue
+		}
+		header := &tar.Header{Name: file.name, Mode: 0o644, Size: int64(len(content)), ModTime: now}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(content); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// debugBundleHandler responds with the debug bundle
+func debugBundleHandler(rw http.ResponseWriter, r *http.Request) {
+	bundle, err := buildDebugBundle(r.Context())
+	if err != nil {
+		writeSenseError(rw, err, "debugBundle")
+		return
+	}
+	name := "host-sensor-debug"
+	if nodeName := getConfig().Identity.NodeName; nodeName != "" {
+		name += "-" + nodeName
+	}
+	rw.Header().Set("Content-Type", "application/gzip")
+	rw.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.tar.gz"`, name, time.Now().UTC().Format("20060102T150405Z")))
+	if _, err := rw.Write(bundle); err != nil {
+		sensor.Logger(r.Context()).Error("In debugBundle handler failed to write", zap.Error(err))
+	}
+}
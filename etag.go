@@ -0,0 +1,42 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+)
+
+// contentETag returns a strong entity tag of the content
+func contentETag(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches returns true if the `If-None-Match` header of the request matches the entity tag.
+// The comparison is weak, as for GET requests.
+func etagMatches(r *http.Request, etag string) bool {
+	header := r.Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeConditional sets the entity tag of the content, and responds with `304 Not Modified` if the request matches it.
+// It returns true if the response was written.
+func writeConditional(rw http.ResponseWriter, r *http.Request, content []byte) bool {
+	etag := contentETag(content)
+	rw.Header().Set("ETag", etag)
+	if !etagMatches(r, etag) {
+		return false
+	}
+	rw.WriteHeader(http.StatusNotModified)
+	return true
+}
@@ -0,0 +1,118 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// Severity of a finding
+type Severity string
+
+const (
+	SeverityLow      Severity = "Low"
+	SeverityMedium   Severity = "Medium"
+	SeverityHigh     Severity = "High"
+	SeverityCritical Severity = "Critical"
+)
+
+var severityRanks = map[Severity]int{
+	SeverityLow:      1,
+	SeverityMedium:   2,
+	SeverityHigh:     3,
+	SeverityCritical: 4,
+}
+
+// IsValidSeverity returns true if `s` is one of the severities
+func IsValidSeverity(s Severity) bool {
+	_, ok := severityRanks[s]
+	return ok
+}
+
+// AtLeast returns true if the severity is higher than or equal to `other`
+func (s Severity) AtLeast(other Severity) bool {
+	return severityRanks[s] >= severityRanks[other]
+}
+
+// Finding is a security issue detected by a rule
+type Finding struct {
+	// ID of the rule which detected the finding
+	RuleID string `json:"ruleID"`
+
+	Severity Severity `json:"severity"`
+
+	// Name of the sensor whose result the finding is based on
+	Sensor string `json:"sensor"`
+
+	// The path of the related file (if relevant)
+	Path string `json:"path,omitempty"`
+
+	// Human readable description of the finding
+	Message string `json:"message"`
+}
+
+// Key returns a key identifying the finding across scans
+func (f *Finding) Key() string {
+	return f.RuleID + ":" + f.Path
+}
+
+// Rule evaluates sensor results
+type Rule struct {
+	ID       string
+	Severity Severity
+
+	// Sensor is the name of the evaluated sensor
+	Sensor string
+
+	// Evaluate returns the findings found in the sensor result
+	Evaluate func(result json.RawMessage) ([]Finding, error)
+}
+
+var rules = []Rule{}
+
+// registerRule adds a rule to the rules evaluated by `Evaluate`
+func registerRule(rule Rule) {
+	rules = append(rules, rule)
+}
+
+// Evaluate runs all the rules on the sensors results (keyed by sensor name), and returns the findings.
+// Rules of sensors which are missing from the results, and rules listed in `disabledRules` are skipped.
+func Evaluate(results map[string]json.RawMessage, disabledRules []string) []Finding {
+	findings := []Finding{}
+
+	disabled := map[string]bool{}
+	for _, id := range disabledRules {
+		disabled[id] = true
+	}
+
+	for _, rule := range rules {
+		if disabled[rule.ID] {
+			continue
+		}
+
+		result, ok := results[rule.Sensor]
+		if !ok {
+			continue
+		}
+
+		ruleFindings, err := rule.Evaluate(result)
+		if err != nil {
+			zap.L().Error("failed to evaluate rule", zap.String("rule", rule.ID), zap.Error(err))
+			continue
+		}
+
+		for i := range ruleFindings {
+			ruleFindings[i].RuleID = rule.ID
+			ruleFindings[i].Severity = rule.Severity
+			ruleFindings[i].Sensor = rule.Sensor
+		}
+		findings = append(findings, ruleFindings...)
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Key() < findings[j].Key()
+	})
+
+	return findings
+}
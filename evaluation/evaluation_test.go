@@ -0,0 +1,1106 @@
+package evaluation
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/armosec/host-sensor/sensor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustMarshal(t *testing.T, v interface{}) json.RawMessage {
+	raw, err := json.Marshal(v)
+	require.NoError(t, err)
+	return raw
+}
+
+func TestSeverityAtLeast(t *testing.T) {
+	assert.True(t, SeverityCritical.AtLeast(SeverityHigh))
+	assert.True(t, SeverityHigh.AtLeast(SeverityHigh))
+	assert.False(t, SeverityLow.AtLeast(SeverityMedium))
+}
+
+func TestEvaluateWorldReadablePKIKeys(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"controlPlaneInfo": mustMarshal(t, sensor.ControlPlaneInfo{
+			PKIFiles: []*sensor.FileInfo{
+				{Path: "/etc/kubernetes/pki/ca.crt", Permissions: 0o644},
+				{Path: "/etc/kubernetes/pki/ca.key", Permissions: 0o600},
+				{Path: "/etc/kubernetes/pki/sa.key", Permissions: 0o644},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "world-readable-pki-key", findings[0].RuleID)
+	assert.Equal(t, SeverityCritical, findings[0].Severity)
+	assert.Equal(t, "/etc/kubernetes/pki/sa.key", findings[0].Path)
+
+	assert.Empty(t, Evaluate(results, []string{"world-readable-pki-key"}))
+}
+
+func TestEvaluateKubeletAnonymousAuth(t *testing.T) {
+	tests := []struct {
+		name        string
+		info        sensor.KubeletInfo
+		wantFinding bool
+	}{
+		{
+			name:        "flag enabled",
+			info:        sensor.KubeletInfo{CmdLine: "/usr/bin/kubelet --anonymous-auth=true"},
+			wantFinding: true,
+		},
+		{
+			name: "flag disabled overrides config",
+			info: sensor.KubeletInfo{
+				CmdLine:    "/usr/bin/kubelet --anonymous-auth=false",
+				ConfigFile: &sensor.FileInfo{Path: "/var/lib/kubelet/config.yaml", Content: []byte("authentication:\n  anonymous:\n    enabled: true\n")},
+			},
+			wantFinding: false,
+		},
+		{
+			name: "config disabled",
+			info: sensor.KubeletInfo{
+				CmdLine:    "/usr/bin/kubelet",
+				ConfigFile: &sensor.FileInfo{Path: "/var/lib/kubelet/config.yaml", Content: []byte("authentication:\n  anonymous:\n    enabled: false\n")},
+			},
+			wantFinding: false,
+		},
+		{
+			name: "config default",
+			info: sensor.KubeletInfo{
+				CmdLine:    "/usr/bin/kubelet",
+				ConfigFile: &sensor.FileInfo{Path: "/var/lib/kubelet/config.yaml", Content: []byte("kind: KubeletConfiguration\n")},
+			},
+			wantFinding: true,
+		},
+		{
+			name:        "unknown",
+			info:        sensor.KubeletInfo{CmdLine: "/usr/bin/kubelet"},
+			wantFinding: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			findings := Evaluate(map[string]json.RawMessage{"kubeletInfo": mustMarshal(t, tt.info)}, nil)
+			if tt.wantFinding {
+				require.Len(t, findings, 1)
+				assert.Equal(t, "kubelet-anonymous-auth", findings[0].RuleID)
+			} else {
+				assert.Empty(t, findings)
+			}
+		})
+	}
+}
+
+func TestEvaluateEscapeSurface(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"escapeSurface": mustMarshal(t, sensor.EscapeSurface{
+			Containers: []sensor.ContainerEscapeSurface{{
+				ID:           "abc",
+				PodName:      "debug",
+				PodNamespace: "default",
+				Container:    "shell",
+				Conditions: []sensor.EscapeCondition{
+					{Type: sensor.EscapePrivileged},
+					{Type: sensor.EscapeHostMount, Detail: "/etc"},
+				},
+			}},
+		}),
+	}
+
+	findings := Evaluate(results, []string{"container-writable-host-mount"})
+	require.Len(t, findings, 1)
+	assert.Equal(t, "privileged-container", findings[0].RuleID)
+	assert.Equal(t, SeverityCritical, findings[0].Severity)
+	assert.Equal(t, "default/debug/shell", findings[0].Path)
+	assert.Equal(t, "container default/debug/shell is privileged", findings[0].Message)
+}
+
+func TestEvaluateKubeconfigs(t *testing.T) {
+	now := time.Now()
+	results := map[string]json.RawMessage{
+		"kubeconfigs": mustMarshal(t, []sensor.KubeconfigInfo{
+			{
+				Component: "admin",
+				File:      &sensor.FileInfo{Path: "/etc/kubernetes/admin.conf"},
+				Clusters:  []sensor.KubeconfigCluster{{Name: "kubernetes", Server: "https://10.0.0.1:6443"}},
+				Users: []sensor.KubeconfigUser{{
+					Name:              "kubernetes-admin",
+					EmbeddedClientKey: true,
+					ClientCertificate: &sensor.CertificateInfo{Embedded: true, NotBefore: now.Add(-24 * time.Hour), NotAfter: now.Add(10 * 365 * 24 * time.Hour)},
+				}},
+			},
+			{
+				Component: "kubelet",
+				File:      &sensor.FileInfo{Path: "/etc/kubernetes/kubelet.conf"},
+				Clusters:  []sensor.KubeconfigCluster{{Name: "default-cluster", Server: "https://10.0.0.1:6443"}},
+				Users: []sensor.KubeconfigUser{{
+					Name:              "default-auth",
+					ClientCertificate: &sensor.CertificateInfo{Path: "/var/lib/kubelet/pki/kubelet-client-current.pem", NotBefore: now.Add(-350 * 24 * time.Hour), NotAfter: now.Add(15 * 24 * time.Hour)},
+				}},
+			},
+			{
+				Component: "scheduler",
+				File:      &sensor.FileInfo{Path: "/etc/kubernetes/scheduler.conf"},
+				Clusters:  []sensor.KubeconfigCluster{{Name: "kubernetes", Server: "http://10.0.0.1:8080", InsecureSkipTLSVerify: true}},
+				Users: []sensor.KubeconfigUser{{
+					Name:              "system:kube-scheduler",
+					ClientCertificate: &sensor.CertificateInfo{Embedded: true, NotBefore: now.Add(-365 * 24 * time.Hour), NotAfter: now.Add(-time.Hour)},
+				}},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	found := map[string]string{}
+	for _, finding := range findings {
+		found[finding.RuleID] = finding.Path
+	}
+	assert.Equal(t, map[string]string{
+		"kubeconfig-long-lived-client-cert":   "/etc/kubernetes/admin.conf:kubernetes-admin",
+		"kubeconfig-client-cert-expiring":     "/etc/kubernetes/kubelet.conf:default-auth",
+		"kubeconfig-client-cert-expired":      "/etc/kubernetes/scheduler.conf:system:kube-scheduler",
+		"kubeconfig-insecure-skip-tls-verify": "/etc/kubernetes/scheduler.conf:kubernetes",
+		"kubeconfig-plaintext-server":         "/etc/kubernetes/scheduler.conf:kubernetes",
+	}, found)
+	assert.Len(t, findings, 5)
+}
+
+func TestEvaluatePrivateKeyPermissions(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"privateKeys": mustMarshal(t, []sensor.PrivateKeyInfo{
+			{File: &sensor.FileInfo{Path: "/etc/kubernetes/pki/ca.key", Permissions: 0o600}, Kind: sensor.PrivateKeyPEM},
+			{
+				File:   &sensor.FileInfo{Path: "/etc/kubernetes/pki/etcd/peer.key", Permissions: 0o640},
+				Kind:   sensor.PrivateKeyPEM,
+				Issues: []string{sensor.KeyGroupReadable, sensor.KeyNonRootOwner},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "private-key-permissions", findings[0].RuleID)
+	assert.Equal(t, "/etc/kubernetes/pki/etcd/peer.key", findings[0].Path)
+	assert.Equal(t, "private key /etc/kubernetes/pki/etcd/peer.key has insecure permissions: groupReadable, nonRootOwner (permissions 640)", findings[0].Message)
+}
+
+func TestEvaluateTokens(t *testing.T) {
+	expired := time.Now().Add(-time.Hour)
+	results := map[string]json.RawMessage{
+		"tokens": mustMarshal(t, []sensor.TokenInfo{
+			{File: &sensor.FileInfo{Path: "/etc/kubernetes/bootstrap-kubelet.conf"}, Type: sensor.TokenBootstrap, TokenID: "abcdef"},
+			{File: &sensor.FileInfo{Path: "/tmp/curl.sh"}, Type: sensor.TokenServiceAccount, Location: sensor.TokenLocationTmp, Namespace: "default", ServiceAccount: "deployer"},
+			{File: &sensor.FileInfo{Path: "/tmp/old"}, Type: sensor.TokenServiceAccount, Location: sensor.TokenLocationTmp, ExpiresAt: &expired},
+			{File: &sensor.FileInfo{Path: "/var/lib/kubelet/pods/a/token"}, Type: sensor.TokenServiceAccount, Location: sensor.TokenLocationPodVolume, ExpiresAt: &expired},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 3)
+	assert.Equal(t, "bootstrap-token-on-disk", findings[0].RuleID)
+	assert.Equal(t, "legacy-service-account-token", findings[1].RuleID)
+	assert.Equal(t, "stray-service-account-token", findings[2].RuleID)
+	assert.Equal(t, "token of service account default/deployer is left on disk in /tmp/curl.sh", findings[2].Message)
+}
+
+func TestEvaluateReadableRegistryCredentials(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"registryCredentials": mustMarshal(t, []sensor.RegistryCredentialsFile{
+			{
+				File:          &sensor.FileInfo{Path: "/root/.docker/config.json", Permissions: 0o644},
+				InlineSecrets: true,
+				Registries:    []sensor.RegistryCredential{{Registry: "registry.example.com", Type: sensor.RegistryAuth}},
+			},
+			{
+				File:          &sensor.FileInfo{Path: "/var/lib/kubelet/config.json", Permissions: 0o600},
+				InlineSecrets: true,
+				Registries:    []sensor.RegistryCredential{{Registry: "registry.example.com", Type: sensor.RegistryAuth}},
+			},
+			{
+				File:       &sensor.FileInfo{Path: "/home/user/.docker/config.json", Permissions: 0o644},
+				Registries: []sensor.RegistryCredential{{Registry: "*", Type: sensor.RegistryCredsStore, Helper: "pass"}},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "readable-registry-credentials", findings[0].RuleID)
+	assert.Equal(t, "registry credentials /root/.docker/config.json for registry.example.com are readable by other users (permissions 644)", findings[0].Message)
+}
+
+func TestEvaluateDevices(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"devices": mustMarshal(t, sensor.DeviceInventory{
+			Devices: []sensor.DeviceFile{
+				{Kind: sensor.DeviceKVM, File: &sensor.FileInfo{Path: "/dev/kvm", Permissions: 0o660}},
+				{Kind: sensor.DeviceFUSE, File: &sensor.FileInfo{Path: "/dev/fuse", Permissions: 0o666}},
+				{Kind: sensor.DeviceGPU, File: &sensor.FileInfo{Path: "/dev/nvidia0", Permissions: 0o666}},
+			},
+			DevicePlugins: []*sensor.FileInfo{
+				{Path: "/var/lib/kubelet/device-plugins/nvidia-gpu.sock", Permissions: 0o755, Ownership: &sensor.FileOwnership{UID: 0}},
+				{Path: "/var/lib/kubelet/device-plugins/rogue.sock", Permissions: 0o777, Ownership: &sensor.FileOwnership{UID: 1000}},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	got := []string{}
+	for _, finding := range findings {
+		got = append(got, finding.RuleID+" "+finding.Message)
+	}
+	assert.ElementsMatch(t, []string{
+		"device-world-accessible gpu device /dev/nvidia0 is world accessible (permissions 666)",
+		"device-plugin-socket-permissions device plugin socket /var/lib/kubelet/device-plugins/rogue.sock is writable by non-root users and owned by uid 1000 (permissions 777)",
+	}, got)
+}
+
+func TestEvaluateContainerLogs(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"containerLogs": mustMarshal(t, sensor.ContainerLogsInfo{
+			Directories: []*sensor.FileInfo{
+				{Path: "/var/log/pods", Permissions: 0o755},
+				{Path: "/var/log/pods/default_web-1_0a1b", Permissions: 0o777},
+			},
+			LogFiles: []*sensor.FileInfo{
+				{Path: "/var/log/pods/default_web-1_0a1b/nginx/0.log", Permissions: 0o644},
+				{Path: "/var/log/pods/default_web-1_0a1b/nginx/1.log", Permissions: 0o640},
+			},
+			Links: []sensor.ContainerLogLink{
+				{Path: "/var/log/containers/web-1_default_nginx-0a1b.log", Target: "/var/log/pods/default_web-1_0a1b/nginx/1.log"},
+				{Path: "/var/log/containers/rogue.log", Target: "/etc/shadow"},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	got := []string{}
+	for _, finding := range findings {
+		got = append(got, finding.RuleID+" "+finding.Message)
+	}
+	assert.ElementsMatch(t, []string{
+		"container-log-world-readable container log /var/log/pods/default_web-1_0a1b/nginx/0.log is world readable (permissions 644)",
+		"container-log-dir-world-writable container log directory /var/log/pods/default_web-1_0a1b is world writable (permissions 777)",
+		"container-log-link-outside-pods container log link /var/log/containers/rogue.log points to /etc/shadow, outside of /var/log/pods",
+	}, got)
+}
+
+func TestEvaluateImageStores(t *testing.T) {
+	root := &sensor.FileOwnership{UID: 0}
+	results := map[string]json.RawMessage{
+		"imageStores": mustMarshal(t, []sensor.ImageStore{
+			{
+				Runtime: "containerd",
+				Root:    &sensor.FileInfo{Path: "/var/lib/containerd", Permissions: 0o711, Ownership: root},
+				Directories: []*sensor.FileInfo{
+					{Path: "/var/lib/containerd/io.containerd.content.v1.content", Permissions: 0o711, Ownership: root},
+					{Path: "/var/lib/containerd/io.containerd.snapshotter.v1.overlayfs", Permissions: 0o755, Ownership: root},
+				},
+			},
+			{
+				Runtime: "docker",
+				Root:    &sensor.FileInfo{Path: "/var/lib/docker", Permissions: 0o700, Ownership: &sensor.FileOwnership{UID: 1000}},
+				Directories: []*sensor.FileInfo{
+					{Path: "/var/lib/docker/overlay2", Permissions: 0o755, Ownership: root},
+				},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	got := []string{}
+	for _, finding := range findings {
+		got = append(got, finding.RuleID+" "+finding.Message)
+	}
+	assert.ElementsMatch(t, []string{
+		"image-store-world-accessible containerd image store directory /var/lib/containerd/io.containerd.snapshotter.v1.overlayfs is world accessible (permissions 755)",
+		"image-store-not-root-owned docker image store directory /var/lib/docker is owned by uid 1000",
+	}, got)
+}
+
+func TestEvaluateStaticPods(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"staticPods": mustMarshal(t, []sensor.StaticPod{
+			{
+				File:         &sensor.FileInfo{Path: "/etc/kubernetes/manifests/etcd.yaml"},
+				Name:         "etcd",
+				ControlPlane: true,
+				HostNetwork:  true,
+				HostPathVolumes: []sensor.StaticPodHostPath{
+					{Name: "etcd-certs", Path: "/etc/kubernetes/pki/etcd", Sensitive: true},
+				},
+				Containers: []sensor.StaticPodContainer{{Name: "etcd"}},
+			},
+			{
+				File:        &sensor.FileInfo{Path: "/etc/kubernetes/manifests/node-agent.yaml"},
+				Name:        "node-agent",
+				HostPID:     true,
+				HostNetwork: true,
+				HostPathVolumes: []sensor.StaticPodHostPath{
+					{Name: "host-etc", Path: "/etc", Sensitive: true},
+					{Name: "host-log", Path: "/var/log", ReadOnly: true},
+				},
+				Containers: []sensor.StaticPodContainer{{Name: "setup", Init: true}, {Name: "agent", Privileged: true}},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	got := []string{}
+	for _, finding := range findings {
+		got = append(got, finding.RuleID+" "+finding.Message)
+	}
+	assert.ElementsMatch(t, []string{
+		"static-pod-privileged static pod node-agent (/etc/kubernetes/manifests/node-agent.yaml) has the privileged container agent",
+		"static-pod-host-namespace static pod node-agent (/etc/kubernetes/manifests/node-agent.yaml) shares the host network and pid namespaces",
+		"static-pod-writable-host-path static pod node-agent (/etc/kubernetes/manifests/node-agent.yaml) mounts the host path /etc writable",
+	}, got)
+}
+
+func TestEvaluateCredentialProtection(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"credentialProtection": mustMarshal(t, sensor.CredentialProtection{
+			Sysctls: map[string]string{
+				"fs.protected_hardlinks": "1",
+				"fs.protected_symlinks":  "0",
+				"fs.protected_fifos":     "1",
+				"fs.suid_dumpable":       "2",
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "protected-links-disabled", findings[0].RuleID)
+	assert.Equal(t, "fs.protected_symlinks is 0: links in world writable directories can be followed to files of other users", findings[0].Message)
+}
+
+func TestEvaluateResourcePressure(t *testing.T) {
+	pressureSome60 := 25.5
+	results := map[string]json.RawMessage{
+		"resourcePressure": mustMarshal(t, sensor.ResourcePressure{
+			Disks: []sensor.DiskUsage{
+				{Path: "/var/lib/etcd", UsedPercent: 40, TotalInodes: 1000, FreeInodes: 10, InodesUsedPercent: 99},
+				{Path: "/var/lib/kubelet", AvailableBytes: 1536 * 1024 * 1024, UsedPercent: 93.2, TotalInodes: 1000, InodesUsedPercent: 50},
+				{Path: "/var/log", UsedPercent: 99},
+			},
+			Memory: &sensor.MemoryUsage{UsedPercent: 60, PressureSome60: &pressureSome60},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	got := []string{}
+	for _, finding := range findings {
+		got = append(got, finding.RuleID+" "+finding.Message)
+	}
+	assert.ElementsMatch(t, []string{
+		"low-disk-space the file system of /var/lib/kubelet is 93.2% full (1.5GiB available)",
+		"low-disk-space the file system of /var/log is 99.0% full (0B available)",
+		"low-inodes the file system of /var/lib/etcd used 99.0% of its inodes (10 free)",
+		"memory-pressure tasks were stalled on memory 25.5% of the last minute",
+	}, got)
+}
+
+func TestEvaluateKernelConfig(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"kernelConfig": mustMarshal(t, sensor.KernelConfig{
+			Release:    "4.14.0",
+			ConfigFile: "/boot/config-4.14.0",
+			Options: []sensor.KernelConfigOption{
+				{Name: "CONFIG_SECCOMP", Value: "y", Enabled: true},
+				{Name: "CONFIG_SECCOMP_FILTER", Value: "n"},
+				{Name: "CONFIG_STRICT_KERNEL_RWX", Value: "y", Enabled: true},
+				{Name: "CONFIG_STRICT_MODULE_RWX"},
+				{Name: "CONFIG_RANDOMIZE_BASE", Value: "n"},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 2)
+	assert.Equal(t, "kernel-hardening-disabled", findings[0].RuleID)
+	assert.Equal(t, "kernel 4.14.0 is built without CONFIG_STRICT_MODULE_RWX, CONFIG_RANDOMIZE_BASE", findings[0].Message)
+	assert.Equal(t, "kernel-seccomp-unsupported", findings[1].RuleID)
+	assert.Equal(t, "/boot/config-4.14.0", findings[1].Path)
+}
+
+func TestEvaluateTimeSync(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"timeSync": mustMarshal(t, sensor.TimeSyncInfo{
+			Daemons:     []sensor.TimeSyncDaemon{{Name: sensor.TimeSyncChrony, ConfigFiles: []string{"/etc/chrony/chrony.conf"}}},
+			ClockOffset: &sensor.ClockOffset{Server: "169.254.169.123", OffsetMillis: 2500},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 2)
+	assert.Equal(t, "clock-skew", findings[0].RuleID)
+	assert.Equal(t, "the clock is 2.5s behind time server 169.254.169.123", findings[0].Message)
+	assert.Equal(t, "time-not-synchronized", findings[1].RuleID)
+	assert.Equal(t, "no time synchronization daemon (chronyd, ntpd or systemd-timesyncd) is running", findings[1].Message)
+
+	results["timeSync"] = mustMarshal(t, sensor.TimeSyncInfo{
+		Daemons:      []sensor.TimeSyncDaemon{{Name: sensor.TimeSyncTimesyncd, Running: true}},
+		Synchronized: true,
+		ClockOffset:  &sensor.ClockOffset{Server: "ntp.ubuntu.com", OffsetMillis: -20},
+	})
+	assert.Empty(t, Evaluate(results, nil))
+}
+
+func TestEvaluateKubeadmLeftoverCredentials(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"kubeadmArtifacts": mustMarshal(t, []sensor.KubeadmArtifact{
+			{
+				File:        &sensor.FileInfo{Path: "/etc/kubernetes/admin.conf.bak", Permissions: 0o644},
+				Kind:        sensor.KubeadmArtifactKubeconfigCopy,
+				Credentials: true,
+				LiveFile:    &sensor.FileInfo{Path: "/etc/kubernetes/admin.conf", Permissions: 0o600},
+			},
+			{
+				File:        &sensor.FileInfo{Path: "/etc/kubernetes/tmp/kubeadm-backup-etcd-2022-09-01-10-00-00/etcd/member/snap/db", Permissions: 0o600},
+				Kind:        sensor.KubeadmArtifactEtcdBackup,
+				Credentials: true,
+			},
+			{
+				File: &sensor.FileInfo{Path: "/etc/kubernetes/tmp/kubeadm-backup-manifests-2022-09-01-10-00-00/kube-apiserver.yaml", Permissions: 0o600},
+				Kind: sensor.KubeadmArtifactManifestsBackup,
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 2)
+	assert.Equal(t, "kubeadm-leftover-credentials", findings[0].RuleID)
+	assert.Equal(t, "kubeconfigCopy /etc/kubernetes/admin.conf.bak holds credentials (permissions 644), more permissive than /etc/kubernetes/admin.conf (permissions 600)", findings[0].Message)
+	assert.Equal(t, "etcdBackup /etc/kubernetes/tmp/kubeadm-backup-etcd-2022-09-01-10-00-00/etcd/member/snap/db holds credentials (permissions 600)", findings[1].Message)
+}
+
+func TestEvaluateCertificates(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"certificates": mustMarshal(t, []sensor.NodeCertificate{
+			{Path: "/etc/kubernetes/pki/etcd/server.crt", Source: sensor.CertificateSourceEtcd, Subject: "CN=kube-etcd", SerialNumber: "3", NotAfter: time.Date(2022, 9, 1, 0, 0, 0, 0, time.UTC), Expired: true},
+			{Path: "/etc/kubernetes/pki/apiserver.crt", Source: sensor.CertificateSourcePKI, Subject: "CN=kube-apiserver", SerialNumber: "2", Expiring: true},
+			{Path: "/etc/kubernetes/pki/ca.crt", Source: sensor.CertificateSourcePKI, SerialNumber: "1"},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 2)
+	assert.Equal(t, "certificate-expired", findings[0].RuleID)
+	assert.Equal(t, "/etc/kubernetes/pki/etcd/server.crt:3", findings[0].Path)
+	assert.Equal(t, "etcd certificate /etc/kubernetes/pki/etcd/server.crt (CN=kube-etcd) expired on 2022-09-01T00:00:00Z", findings[0].Message)
+	assert.Equal(t, "certificate-expiring", findings[1].RuleID)
+	assert.Equal(t, "/etc/kubernetes/pki/apiserver.crt:2", findings[1].Path)
+}
+
+func TestEvaluateCertificateChains(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"certificates": mustMarshal(t, []sensor.NodeCertificate{
+			{Path: "/etc/kubernetes/pki/ca.crt", Subject: "CN=kubernetes", SerialNumber: "1", Chain: sensor.CertificateChainSelfSigned},
+			{Path: "/etc/kubernetes/pki/apiserver.crt", SerialNumber: "2", Chain: sensor.CertificateChainValid, ChainRoot: "/etc/kubernetes/pki/ca.crt", ExpectedCA: "/etc/kubernetes/pki/ca.crt"},
+			{Path: "/etc/kubernetes/pki/etcd/server.crt", Subject: "CN=kube-etcd", SerialNumber: "3", Chain: sensor.CertificateChainValid, ChainRoot: "/etc/kubernetes/pki/ca.crt", ExpectedCA: "/etc/kubernetes/pki/etcd/ca.crt"},
+			{Path: "/var/lib/kubelet/pki/kubelet-client-2022-09-01.pem", Subject: "CN=system:node:a", Issuer: "CN=kubernetes", SerialNumber: "4", Chain: sensor.CertificateChainInvalidSignature, ExpectedCA: "/etc/kubernetes/pki/ca.crt"},
+			{Path: "/var/lib/kubelet/pki/kubelet.crt", SerialNumber: "5", Chain: sensor.CertificateChainSelfSigned},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 2)
+	assert.Equal(t, "certificate-broken-chain", findings[0].RuleID)
+	assert.Equal(t, "/var/lib/kubelet/pki/kubelet-client-2022-09-01.pem:4", findings[0].Path)
+	assert.Equal(t, "certificate-unexpected-issuer", findings[1].RuleID)
+	assert.Equal(t, "/etc/kubernetes/pki/etcd/server.crt:3", findings[1].Path)
+	assert.Equal(t, "certificate /etc/kubernetes/pki/etcd/server.crt (CN=kube-etcd) chains to /etc/kubernetes/pki/ca.crt instead of /etc/kubernetes/pki/etcd/ca.crt", findings[1].Message)
+}
+
+func TestEvaluateWeakCryptography(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"certificates": mustMarshal(t, []sensor.NodeCertificate{
+			{Path: "/etc/kubernetes/pki/ca.crt", Subject: "CN=kubernetes", Issuer: "CN=kubernetes", SerialNumber: "1", PublicKeyAlgorithm: "RSA", KeyBits: 2048, SignatureAlgorithm: "SHA1-RSA"},
+			{Path: "/etc/kubernetes/pki/apiserver.crt", Subject: "CN=kube-apiserver", Issuer: "CN=kubernetes", SerialNumber: "2", PublicKeyAlgorithm: "RSA", KeyBits: 1024, SignatureAlgorithm: "SHA1-RSA"},
+			{Path: "/var/lib/kubelet/pki/kubelet.crt", Subject: "CN=node-a", Issuer: "CN=node-a-ca", SerialNumber: "3", PublicKeyAlgorithm: "ECDSA", KeyBits: 256, SignatureAlgorithm: "ECDSA-SHA256"},
+		}),
+		"tlsConfigs": mustMarshal(t, []sensor.TLSConfig{
+			{Component: "kubelet", CipherSuites: []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_ECDHE_RSA_WITH_RC4_128_SHA"}, MinVersion: "VersionTLS12"},
+			{Component: "etcd", MinVersion: "TLS1.1"},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	got := map[string]string{}
+	for _, finding := range findings {
+		got[finding.RuleID] = finding.Path
+	}
+	assert.Equal(t, map[string]string{
+		"weak-certificate-key":       "/etc/kubernetes/pki/apiserver.crt:2",
+		"weak-certificate-signature": "/etc/kubernetes/pki/apiserver.crt:2",
+		"weak-tls-cipher-suite":      "kubelet:TLS_ECDHE_RSA_WITH_RC4_128_SHA",
+		"weak-tls-min-version":       "etcd",
+	}, got)
+	assert.Len(t, findings, 4)
+}
+
+func TestEvaluateEtcdSecretsNotEncrypted(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"etcdEncryption": mustMarshal(t, sensor.EtcdEncryptionInfo{
+			Source: sensor.EtcdSourceDataDir,
+			Samples: []sensor.EtcdSecretSample{
+				{Key: "/registry/secrets/default/plain"},
+				{Key: "/registry/secrets/kube-system/encrypted", Encrypted: true, Provider: "aescbc:v1:key1"},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "etcd-secrets-not-encrypted", findings[0].RuleID)
+	assert.Equal(t, "1 of 2 sampled secrets are stored in etcd without encryption (default/plain)", findings[0].Message)
+
+	results["etcdEncryption"] = mustMarshal(t, sensor.EtcdEncryptionInfo{Samples: []sensor.EtcdSecretSample{{Key: "/registry/secrets/a/b", Encrypted: true}}})
+	assert.Empty(t, Evaluate(results, nil))
+}
+
+func TestEvaluateEgressProxyUnencrypted(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"controlPlaneInfo": mustMarshal(t, sensor.ControlPlaneInfo{
+			APIServerInfo: &sensor.ApiServerInfo{EgressSelectorConfig: &sensor.EgressSelectorConfig{
+				File: &sensor.FileInfo{Path: "/etc/kubernetes/egress-selector-configuration.yaml"},
+				Selections: []sensor.EgressSelection{
+					{Name: "cluster", ProxyProtocol: sensor.EgressProtocolHTTPConnect, Transport: "tcp", URL: "https://konnectivity:8131"},
+					{Name: "controlplane", ProxyProtocol: sensor.EgressProtocolHTTPConnect, Transport: "tcp", URL: "https://konnectivity:8131", CABundle: "/etc/pki/ca.crt"},
+					{Name: "etcd", ProxyProtocol: sensor.EgressProtocolGRPC, Transport: "uds", UDSName: "/etc/kubernetes/konnectivity.sock"},
+				},
+			}},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "egress-proxy-unencrypted", findings[0].RuleID)
+	assert.Equal(t, "the cluster egress of the API server is tunneled to https://konnectivity:8131 without TLS", findings[0].Message)
+}
+
+func TestEvaluateStaticTokenFile(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"controlPlaneInfo": mustMarshal(t, sensor.ControlPlaneInfo{
+			APIServerInfo: &sensor.ApiServerInfo{
+				TokenAuthFile:    &sensor.FileInfo{Path: "/etc/kubernetes/tokens.csv"},
+				StaticTokenCount: 2,
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "api-server-static-token-file", findings[0].RuleID)
+	assert.Equal(t, SeverityCritical, findings[0].Severity)
+	assert.Equal(t, "the API server authenticates 2 static tokens of /etc/kubernetes/tokens.csv", findings[0].Message)
+}
+
+func TestEvaluateEtcdDataDirPermissions(t *testing.T) {
+	etcdUser := &sensor.FileOwnership{UID: 998}
+	results := map[string]json.RawMessage{
+		"controlPlaneInfo": mustMarshal(t, sensor.ControlPlaneInfo{
+			EtcdDataDir: &sensor.FileInfo{Path: "/var/lib/etcd", Permissions: 0o700, Ownership: etcdUser},
+			EtcdDataDirFiles: []*sensor.FileInfo{
+				{Path: "/var/lib/etcd/member", Permissions: 0o700, Ownership: etcdUser},
+				{Path: "/var/lib/etcd/member/wal/0000000000000000-0000000000000000.wal", Permissions: 0o644, Ownership: etcdUser},
+				{Path: "/var/lib/etcd/member/snap/db", Permissions: 0o600, Ownership: &sensor.FileOwnership{UID: 0}},
+			},
+		}),
+	}
+
+	got := []string{}
+	for _, finding := range Evaluate(results, nil) {
+		got = append(got, finding.RuleID+" "+finding.Message)
+	}
+	assert.Equal(t, []string{
+		"etcd-data-dir-permissions etcd data /var/lib/etcd/member/snap/db is owned by UID 0 instead of the etcd user (UID 998)",
+		"etcd-data-dir-permissions etcd data /var/lib/etcd/member/wal/0000000000000000-0000000000000000.wal is group or world accessible (permissions 644)",
+	}, got)
+}
+
+func TestEvaluateContainerdServerExposed(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"containerdServer": mustMarshal(t, sensor.ContainerdServerInfo{
+			ConfigFile: &sensor.FileInfo{Path: "/etc/containerd/config.toml"},
+			Stream:     sensor.ContainerdStreamServer{Address: "", Port: "10010", Exposed: true},
+			GRPC:       sensor.ContainerdGRPCServer{Socket: "/run/containerd/containerd.sock", TCPAddress: "0.0.0.0:2376", Exposed: true},
+		}),
+	}
+
+	got := []string{}
+	for _, finding := range Evaluate(results, nil) {
+		got = append(got, finding.RuleID+" "+finding.Message)
+	}
+	assert.ElementsMatch(t, []string{
+		"containerd-stream-server-exposed the containerd stream server serves the exec and attach streams on all the interfaces without TLS",
+		"containerd-api-exposed the containerd API is served on 0.0.0.0:2376 without client certificate authentication",
+	}, got)
+}
+
+func TestEvaluateKubeProxyNetworking(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"kubeProxyInfo": mustMarshal(t, sensor.KubeProxyInfo{
+			Mode:      "ipvs",
+			Conntrack: &sensor.KubeProxyConntrack{MaxPerCore: 32768, Min: 131072, ExpectedMax: 262144, KernelMax: 65536},
+			IPVS:      &sensor.KubeProxyIPVS{Scheduler: "sh"},
+			KernelModules: []sensor.KernelModule{
+				{Name: "nf_conntrack", Loaded: true, Available: true},
+				{Name: "ip_vs", Loaded: true, Available: true},
+				{Name: "ip_vs_sh"},
+			},
+		}),
+	}
+
+	got := []string{}
+	for _, finding := range Evaluate(results, nil) {
+		got = append(got, finding.RuleID+" "+finding.Message)
+	}
+	assert.ElementsMatch(t, []string{
+		"kube-proxy-conntrack-table-small the conntrack table of the kernel holds 65536 connections while kube-proxy expects 262144",
+		"kube-proxy-ipvs-module-missing kube-proxy runs in ipvs mode but the kernel module ip_vs_sh isn't available",
+	}, got)
+}
+
+func TestEvaluateIPTablesBackendMismatch(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"openedPorts": mustMarshal(t, map[string]interface{}{
+			"iptablesBackend": sensor.IPTablesBackendInfo{
+				Node:    sensor.IPTablesBackendNFT,
+				Verdict: sensor.IPTablesVerdictMismatch,
+				Reason:  "calico-node uses the legacy backend while the node uses nft",
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "iptables-backend-mismatch", findings[0].RuleID)
+	assert.Equal(t, "the iptables backends aren't compatible: calico-node uses the legacy backend while the node uses nft", findings[0].Message)
+}
+
+func TestEvaluateRuntimeSocketNonRootAccess(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"runtimeSockets": mustMarshal(t, []sensor.RuntimeSocket{
+			{Runtime: "docker", File: &sensor.FileInfo{Path: "/run/docker.sock"}, Group: "docker", Users: []string{"alice", "ci"}},
+			{Runtime: "containerd", File: &sensor.FileInfo{Path: "/run/containerd/containerd.sock"}, Users: []string{}},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "runtime-socket-non-root-access", findings[0].RuleID)
+	assert.Equal(t, "the users alice, ci (through the group docker) can connect to the docker socket /run/docker.sock, which is equivalent to root", findings[0].Message)
+}
+
+func TestEvaluateKubeletAuthorizationAlwaysAllow(t *testing.T) {
+	alwaysAllow, webhook := "AlwaysAllow", "Webhook"
+	for _, tc := range []struct {
+		info     sensor.KubeletInfo
+		findings int
+	}{
+		{sensor.KubeletInfo{Settings: &sensor.KubeletSettings{AuthorizationMode: &alwaysAllow}}, 1},
+		{sensor.KubeletInfo{Settings: &sensor.KubeletSettings{}}, 1},
+		{sensor.KubeletInfo{Settings: &sensor.KubeletSettings{}, ConfigFile: &sensor.FileInfo{Path: "/var/lib/kubelet/config.yaml"}}, 0},
+		{sensor.KubeletInfo{Settings: &sensor.KubeletSettings{AuthorizationMode: &webhook}}, 0},
+	} {
+		findings := []string{}
+		for _, finding := range Evaluate(map[string]json.RawMessage{"kubeletInfo": mustMarshal(t, tc.info)}, nil) {
+			if finding.RuleID == "kubelet-authorization-always-allow" {
+				findings = append(findings, finding.Message)
+			}
+		}
+		assert.Len(t, findings, tc.findings)
+	}
+}
+
+func TestEvaluateCNIRiskySettings(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"controlPlaneInfo": mustMarshal(t, sensor.ControlPlaneInfo{
+			CNINetworks: []sensor.CNINetwork{
+				{File: "/etc/cni/net.d/10-containerd-net.conflist", Name: "containerd-net", Risks: []string{sensor.CNIRiskHairpinOff}},
+				{File: "/etc/cni/net.d/10-flannel.conflist", Name: "cbr0", Risks: []string{}},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "cni-risky-settings", findings[0].RuleID)
+	assert.Equal(t, "CNI network containerd-net: its bridge has hairpin mode off, so pods can't reach themselves through a service", findings[0].Message)
+}
+
+func TestEvaluateCNIConfigConflict(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"controlPlaneInfo": mustMarshal(t, sensor.ControlPlaneInfo{
+			CNIConflicts: &sensor.CNIConflictInfo{
+				ConfigDir: "/etc/cni/net.d",
+				Selected:  "/etc/cni/net.d/05-flannel.conflist",
+				Ignored:   []string{"/etc/cni/net.d/10-calico.conflist"},
+				Agents:    []string{"calico-node"},
+				Conflicts: []string{sensor.CNIConflictMultipleConfigs, sensor.CNIConflictSelectedNotRunning},
+				Reasons: []string{
+					"the runtime uses /etc/cni/net.d/05-flannel.conflist and ignores /etc/cni/net.d/10-calico.conflist",
+					"the runtime uses the flannel config /etc/cni/net.d/05-flannel.conflist, but its agent doesn't run",
+				},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 2)
+	assert.Equal(t, "cni-config-conflict", findings[0].RuleID)
+	assert.Equal(t, "/etc/cni/net.d", findings[0].Path)
+	assert.Equal(t, "CNI conflict: the runtime uses the flannel config /etc/cni/net.d/05-flannel.conflist, but its agent doesn't run", findings[1].Message)
+}
+
+func TestEvaluateNodeDNSExposedOffNode(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"openedPorts": mustMarshal(t, map[string]interface{}{
+			"dns": sensor.NodeDNSInfo{
+				Servers: []sensor.DNSServer{{
+					Kind:        sensor.DNSServerNodeLocal,
+					HostNetwork: true,
+					Listeners: []sensor.DNSListener{
+						{Protocol: "udp", Address: "169.254.20.10", Port: 53, Exposure: sensor.DNSExposurePods},
+						{Protocol: "tcp", Address: "0.0.0.0", Port: 53, Exposure: sensor.DNSExposureOffNode},
+					},
+					Exposure: sensor.DNSExposureOffNode,
+				}},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "node-dns-exposed-off-node", findings[0].RuleID)
+	assert.Equal(t, "node-local-dns listens on tcp 0.0.0.0:53, reachable off the node", findings[0].Message)
+}
+
+func TestEvaluateCachedAdminKubeconfig(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"adminTools": mustMarshal(t, sensor.AdminToolsInfo{
+			Kubeconfigs: []sensor.KubeconfigInfo{
+				{
+					Component: "user",
+					Owner:     "alice",
+					File:      &sensor.FileInfo{Path: "/home/alice/.kube/config"},
+					Users: []sensor.KubeconfigUser{{
+						Name:              "kubernetes-admin",
+						ClientCertificate: &sensor.CertificateInfo{Embedded: true, Subject: "CN=kubernetes-admin,O=system:masters"},
+						Admin:             true,
+					}},
+				},
+				{
+					Component: "user",
+					Owner:     "bob",
+					File:      &sensor.FileInfo{Path: "/home/bob/.kube/config"},
+					Users:     []sensor.KubeconfigUser{{Name: "bob", Exec: true}},
+				},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "cached-admin-kubeconfig", findings[0].RuleID)
+	assert.Equal(t, "/home/alice/.kube/config", findings[0].Path)
+	assert.Equal(t, "the kubeconfig of alice grants cluster admin as kubernetes-admin (CN=kubernetes-admin,O=system:masters)", findings[0].Message)
+}
+
+func TestEvaluateKernelRebootPending(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"kernelPatching": mustMarshal(t, sensor.KernelPatchingInfo{
+			RunningRelease:    "5.15.0-91-generic",
+			InstalledReleases: []string{"5.15.0-91-generic", "5.15.0-101-generic"},
+			LivePatchActive:   true,
+			RebootRequired:    true,
+			RebootReasons:     []string{sensor.RebootReasonNewerKernel, sensor.RebootReasonPendingUpdates},
+			RebootPackages:    []string{"linux-image-5.15.0-101-generic"},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 2)
+	assert.Equal(t, "kernel-reboot-pending", findings[0].RuleID)
+	assert.Equal(t, "the node runs kernel 5.15.0-91-generic while 5.15.0-101-generic is installed", findings[0].Message)
+	assert.Equal(t, "the package manager requires a reboot to apply the installed updates of linux-image-5.15.0-101-generic", findings[1].Message)
+}
+
+func TestEvaluateNVIDIAToolkitWeakIsolation(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"accelerators": mustMarshal(t, sensor.AcceleratorInfo{
+			NVIDIAToolkit: &sensor.NVIDIAToolkit{
+				ConfigFile:       &sensor.FileInfo{Path: "/etc/nvidia-container-runtime/config.toml"},
+				NoCgroups:        true,
+				DefaultRuntimeOf: []string{"containerd"},
+				Risks:            []string{sensor.NVIDIAToolkitRiskNoCgroups, sensor.NVIDIAToolkitRiskDefaultRuntime},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 2)
+	assert.Equal(t, "nvidia-toolkit-weak-isolation", findings[0].RuleID)
+	assert.Equal(t, "/etc/nvidia-container-runtime/config.toml", findings[0].Path)
+	assert.Equal(t, "NVIDIA container toolkit: nvidia is the default runtime of containerd, so every container goes through the toolkit hook", findings[1].Message)
+}
+
+func TestEvaluateAdmissionBypass(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"admissionBypass": mustMarshal(t, sensor.AdmissionBypassInfo{
+			StaticPodPath:           "/tmp/manifests",
+			UnexpectedStaticPodPath: true,
+			NonRootWritable: []sensor.NonRootWritablePath{
+				{File: &sensor.FileInfo{Path: "/tmp/manifests"}, Writers: []string{"user:alice"}},
+			},
+			StaticPodURL:              "http://10.0.0.5/pods.yaml",
+			MirrorPods:                3,
+			NonControlPlaneMirrorPods: 1,
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 4)
+	assert.Equal(t, "admission-bypass-mirror-pods", findings[0].RuleID)
+	assert.Equal(t, "1 of the 3 static pods aren't control plane pods, their mirror pods bypassed admission control", findings[0].Message)
+	assert.Equal(t, "admission-bypass-static-pod-url", findings[1].RuleID)
+	assert.Equal(t, "admission-bypass-unexpected-static-pod-path", findings[2].RuleID)
+	assert.Equal(t, "admission-bypass-writable-static-pod-path", findings[3].RuleID)
+	assert.Equal(t, "/tmp/manifests is writable by user:alice, who can add static pods to /tmp/manifests", findings[3].Message)
+
+	results["admissionBypass"] = mustMarshal(t, sensor.AdmissionBypassInfo{StaticPodPath: "/etc/kubernetes/manifests", MirrorPods: 4})
+	assert.Empty(t, Evaluate(results, nil))
+}
+
+func TestEvaluateKubeletCredentialProviderWritable(t *testing.T) {
+	info := sensor.KubeletInfo{ImageCredentialProviders: &sensor.KubeletCredentialProviders{
+		BinDir:        "/usr/libexec/kubernetes/credential-providers",
+		BinDirWriters: []string{"group:ops"},
+		Binaries: []sensor.KubeletCredentialProviderBinary{
+			{File: &sensor.FileInfo{Path: "/usr/libexec/kubernetes/credential-providers/acr-credential-provider"}, Writers: []string{}},
+			{File: &sensor.FileInfo{Path: "/usr/libexec/kubernetes/credential-providers/ecr-credential-provider"}, Writers: []string{"everyone"}},
+		},
+	}}
+
+	findings := []Finding{}
+	for _, finding := range Evaluate(map[string]json.RawMessage{"kubeletInfo": mustMarshal(t, info)}, nil) {
+		if finding.RuleID == "kubelet-credential-provider-writable" {
+			findings = append(findings, finding)
+		}
+	}
+	require.Len(t, findings, 2)
+	assert.Equal(t, "the image credential provider bin dir /usr/libexec/kubernetes/credential-providers is writable by group:ops", findings[0].Message)
+	assert.Equal(t, "/usr/libexec/kubernetes/credential-providers/ecr-credential-provider", findings[1].Path)
+	assert.Equal(t, SeverityCritical, findings[1].Severity)
+}
+
+func TestEvaluatePodVolumes(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"podVolumes": mustMarshal(t, sensor.PodVolumesInfo{
+			PodsDir:         "/var/lib/kubelet/pods",
+			ContainersKnown: true,
+			Pods: []sensor.PodVolumes{
+				{UID: "0b6c3f7e", PodName: "db", PodNamespace: "default", Volumes: []sensor.PodVolume{
+					{Plugin: "kubernetes.io/secret", Name: "db-credentials", File: &sensor.FileInfo{Path: "/var/lib/kubelet/pods/0b6c3f7e/volumes/kubernetes.io~secret/db-credentials"},
+						WorldReadable: []string{"/var/lib/kubelet/pods/0b6c3f7e/volumes/kubernetes.io~secret/db-credentials/..data/password"}},
+				}},
+				{UID: "5d2e9a41", Orphaned: true, Volumes: []sensor.PodVolume{
+					{Plugin: "kubernetes.io/configmap", Name: "config", File: &sensor.FileInfo{Path: "/var/lib/kubelet/pods/5d2e9a41/volumes/kubernetes.io~configmap/config"}},
+				}},
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 2)
+	assert.Equal(t, "orphaned-pod-volumes", findings[0].RuleID)
+	assert.Equal(t, "/var/lib/kubelet/pods/5d2e9a41", findings[0].Path)
+	assert.Equal(t, "pod-secret-volume-world-readable", findings[1].RuleID)
+	assert.Equal(t, "1 files of the secret volume db-credentials of the pod default/db are readable by any user on the node", findings[1].Message)
+}
+
+func TestEvaluateSeccomp(t *testing.T) {
+	enabled, disabled := true, false
+	for _, tc := range []struct {
+		settings *sensor.KubeletSettings
+		findings int
+	}{
+		{nil, 0},
+		{&sensor.KubeletSettings{}, 1},
+		{&sensor.KubeletSettings{SeccompDefault: &disabled}, 1},
+		{&sensor.KubeletSettings{SeccompDefault: &enabled}, 0},
+	} {
+		findings := 0
+		for _, finding := range Evaluate(map[string]json.RawMessage{"kubeletInfo": mustMarshal(t, sensor.KubeletInfo{Settings: tc.settings})}, nil) {
+			if finding.RuleID == "kubelet-seccomp-default-disabled" {
+				findings++
+			}
+		}
+		assert.Equal(t, tc.findings, findings)
+	}
+
+	results := map[string]json.RawMessage{
+		"seccompProfiles": mustMarshal(t, sensor.SeccompProfilesInfo{
+			Dir: "/var/lib/kubelet/seccomp",
+			Profiles: []sensor.SeccompProfile{
+				{File: &sensor.FileInfo{Path: "/var/lib/kubelet/seccomp/profiles/audit.json"}, Name: "profiles/audit.json", DefaultAction: "SCMP_ACT_LOG", Permissive: true},
+				{File: &sensor.FileInfo{Path: "/var/lib/kubelet/seccomp/profiles/fine-grained.json"}, Name: "profiles/fine-grained.json", DefaultAction: "SCMP_ACT_ERRNO", PermissiveSyscalls: 4},
+			},
+		}),
+	}
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "seccomp-profile-permissive", findings[0].RuleID)
+	assert.Equal(t, "the seccomp profile localhost/profiles/audit.json lets every syscall through by default (SCMP_ACT_LOG)", findings[0].Message)
+}
+
+func TestEvaluateKubeletCertRotation(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"kubeletCertRotation": mustMarshal(t, sensor.KubeletCertRotationInfo{
+			ClientCertificate: &sensor.CertificateInfo{Path: "/var/lib/kubelet/pki/kubelet-client-current.pem"},
+			Verdict:           sensor.KubeletCertVerdictRotationStalled,
+			Reasons:           []string{"the client certificate wasn't rotated by 90% of its lifetime, it expires at 2024-06-06T00:00:00Z"},
+		}),
+	}
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "kubelet-client-cert-rotation", findings[0].RuleID)
+	assert.Equal(t, "/var/lib/kubelet/pki/kubelet-client-current.pem", findings[0].Path)
+	assert.Equal(t, "kubelet client certificate: the client certificate wasn't rotated by 90% of its lifetime, it expires at 2024-06-06T00:00:00Z", findings[0].Message)
+
+	results["kubeletCertRotation"] = mustMarshal(t, sensor.KubeletCertRotationInfo{Verdict: sensor.KubeletCertVerdictExpired, Reasons: []string{"the client certificate expired"}})
+	findings = Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "kubelet-client-cert-expired", findings[0].RuleID)
+
+	results["kubeletCertRotation"] = mustMarshal(t, sensor.KubeletCertRotationInfo{Verdict: sensor.KubeletCertVerdictRotationDisabled})
+	assert.Empty(t, Evaluate(results, nil))
+}
+
+func TestEvaluateKonnectivity(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"konnectivity": mustMarshal(t, sensor.KonnectivityInfo{
+			Agent: &sensor.KonnectivityAgent{
+				ServiceAccountTokenFile: &sensor.FileInfo{Path: "/var/run/secrets/tokens/konnectivity-agent-token", Permissions: 0o600},
+			},
+			Server: &sensor.KonnectivityServer{
+				UDSSocket:        &sensor.FileInfo{Path: "/etc/kubernetes/konnectivity-server/konnectivity-server.socket", Permissions: 0o777},
+				UDSSocketWriters: []string{"everyone"},
+				ClusterKeyFile:   &sensor.FileInfo{Path: "/etc/kubernetes/pki/konnectivity-cluster.key", Permissions: 0o644},
+			},
+			SSHTunnel: &sensor.APIServerSSHTunnel{User: "tunnel"},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 3)
+	assert.Equal(t, "apiserver-ssh-tunnel", findings[0].RuleID)
+	assert.Equal(t, "konnectivity-credentials-readable", findings[1].RuleID)
+	assert.Equal(t, "the tunnel credentials /etc/kubernetes/pki/konnectivity-cluster.key are readable by other users (permissions 644)", findings[1].Message)
+	assert.Equal(t, "konnectivity-socket-writable", findings[2].RuleID)
+	assert.Equal(t, "the konnectivity server socket /etc/kubernetes/konnectivity-server/konnectivity-server.socket is writable by everyone", findings[2].Message)
+
+	results["konnectivity"] = mustMarshal(t, sensor.KonnectivityInfo{})
+	assert.Empty(t, Evaluate(results, nil))
+}
+
+func TestEvaluateRiskyServices(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"riskyServices": mustMarshal(t, sensor.RiskyServicesInfo{Services: []sensor.RiskyService{
+			{Kind: sensor.RiskyServiceTelnet, Ports: []int{23}, Reason: "a service listens on the telnet port 23"},
+			{Kind: sensor.RiskyServiceDockerTCP, Ports: []int{2375}, Reason: "the docker daemon serves its API on tcp://0.0.0.0:2375 without TLS client verification"},
+			{Kind: sensor.RiskyServiceKubeletDebugging, Ports: []int{10250}, Reason: "the kubelet serves its debugging handlers on port 10250 to every client (authorization mode AlwaysAllow)"},
+		}}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 3)
+	assert.Equal(t, "docker-api-unauthenticated", findings[0].RuleID)
+	assert.Equal(t, "the docker daemon serves its API on tcp://0.0.0.0:2375 without TLS client verification", findings[0].Message)
+	assert.Equal(t, "kubelet-debugging-handlers-exposed", findings[1].RuleID)
+	assert.Equal(t, "plaintext-remote-service", findings[2].RuleID)
+	assert.Equal(t, SeverityHigh, findings[2].Severity)
+}
+
+func TestEvaluateNodeRestriction(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"nodeRestriction": mustMarshal(t, sensor.NodeRestrictionInfo{
+			APIServer: &sensor.NodeRestrictionAPIServer{AuthorizationModes: []string{"RBAC"}},
+			Kubelet: &sensor.KubeletIdentity{
+				ClientCertificate: &sensor.CertificateInfo{Path: "/var/lib/kubelet/pki/kubelet-client-current.pem", Subject: "CN=kubelet"},
+				Username:          "kubelet",
+			},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 3)
+	assert.Equal(t, "kubelet-not-node-identity", findings[0].RuleID)
+	assert.Equal(t, "/var/lib/kubelet/pki/kubelet-client-current.pem", findings[0].Path)
+	assert.Equal(t, "node-authorizer-disabled", findings[1].RuleID)
+	assert.Equal(t, "the API server authorization modes RBAC don't include Node, the kubelets aren't restricted to their own node", findings[1].Message)
+	assert.Equal(t, "node-restriction-disabled", findings[2].RuleID)
+
+	results["nodeRestriction"] = mustMarshal(t, sensor.NodeRestrictionInfo{
+		APIServer: &sensor.NodeRestrictionAPIServer{AuthorizationModes: []string{"Node", "RBAC"}, NodeAuthorizer: true, NodeRestriction: true},
+		Kubelet: &sensor.KubeletIdentity{
+			ClientCertificate:  &sensor.CertificateInfo{Path: "/var/lib/kubelet/pki/kubelet-client-current.pem", Subject: "CN=system:node:node-1,O=system:nodes"},
+			NodeIdentity:       true,
+			NodeName:           "node-1",
+			RegisteredNodeName: "node-2",
+		},
+	})
+	findings = Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, `the kubelet client certificate is the identity of node "node-1", but the kubelet registers node "node-2"`, findings[0].Message)
+}
+
+func TestEvaluateKubeletDiskGC(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"kubeletDiskGC": mustMarshal(t, sensor.KubeletDiskGCInfo{
+			DiskExhaustionRisk: sensor.DiskExhaustionRiskHigh,
+			Reasons:            []string{"the kubelet root dir /var/lib/kubelet is below the hard eviction threshold nodefs.available<10%", "the image GC is disabled (high threshold 100%)"},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "disk-exhaustion-imminent", findings[0].RuleID)
+	assert.Equal(t, "disk exhaustion risk: the kubelet root dir /var/lib/kubelet is below the hard eviction threshold nodefs.available<10%; the image GC is disabled (high threshold 100%)", findings[0].Message)
+
+	results["kubeletDiskGC"] = mustMarshal(t, sensor.KubeletDiskGCInfo{DiskExhaustionRisk: sensor.DiskExhaustionRiskMedium, Reasons: []string{"the image GC is disabled (high threshold 100%)"}})
+	findings = Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "disk-gc-policy-weak", findings[0].RuleID)
+	assert.Equal(t, SeverityMedium, findings[0].Severity)
+
+	results["kubeletDiskGC"] = mustMarshal(t, sensor.KubeletDiskGCInfo{DiskExhaustionRisk: sensor.DiskExhaustionRiskLow})
+	assert.Empty(t, Evaluate(results, nil))
+}
+
+func TestEvaluateContainerLogRotation(t *testing.T) {
+	results := map[string]json.RawMessage{
+		"containerLogRotation": mustMarshal(t, sensor.ContainerLogRotationInfo{
+			Kubelet: &sensor.KubeletLogRotation{RotatedBy: sensor.ContainerLogRotatorDocker},
+			Docker:  &sensor.DockerLogging{LogDriver: "json-file", LogOpts: map[string]string{}},
+		}),
+	}
+
+	findings := Evaluate(results, nil)
+	require.Len(t, findings, 1)
+	assert.Equal(t, "container-log-rotation-missing", findings[0].RuleID)
+	assert.Equal(t, "the docker daemon writes the container logs with the json-file log driver without a max-size, they grow unbounded", findings[0].Message)
+
+	results["containerLogRotation"] = mustMarshal(t, sensor.ContainerLogRotationInfo{
+		Kubelet:    &sensor.KubeletLogRotation{RotatedBy: sensor.ContainerLogRotatorKubelet, Rotated: true},
+		Containerd: &sensor.ContainerdLogging{},
+	})
+	assert.Empty(t, Evaluate(results, nil))
+}
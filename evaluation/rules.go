@@ -0,0 +1,2239 @@
+package evaluation
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/armosec/host-sensor/sensor"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	worldReadablePerm = 0o004
+	worldWritablePerm = 0o002
+	worldSearchPerm   = 0o001
+	groupReadablePerm = 0o040
+	groupWritablePerm = 0o020
+
+	kubeletAnonymousAuthArg = "--anonymous-auth"
+
+	// Client certificates valid for longer are long-lived (kubeadm issues them for a year)
+	longLivedCertValidity = 366 * 24 * time.Hour
+
+	// Client certificates expiring sooner are reported as expiring
+	certExpiryWarning = 30 * 24 * time.Hour
+
+	// RSA keys shorter than this are weak
+	minRSAKeyBits = 2048
+
+	// Clock offsets beyond this break the correlation of logs across nodes
+	maxClockOffset = time.Second
+
+	// Usage percentages beyond which the kubelet evicts pods, or the node fails
+	maxDiskUsedPercent   = 90
+	maxInodesUsedPercent = 95
+	maxMemoryUsedPercent = 95
+
+	// Percentage of time tasks are stalled on memory beyond which the node is under memory pressure
+	maxMemoryPressure = 10
+)
+
+var (
+	// Signature algorithms with broken hash functions
+	weakSignatureHashes = []string{"MD2", "MD5", "SHA1"}
+
+	// Cipher suite name parts of broken ciphers, in addition to the insecure cipher suites of Go
+	weakCipherParts = []string{"RC4", "3DES", "_DES_", "NULL", "EXPORT"}
+
+	// Kernel build options of memory protections, which should be enabled
+	kernelHardeningOptions = []string{
+		"CONFIG_STRICT_KERNEL_RWX",
+		"CONFIG_STRICT_MODULE_RWX",
+		"CONFIG_STACKPROTECTOR_STRONG",
+		"CONFIG_RANDOMIZE_BASE",
+		"CONFIG_HARDENED_USERCOPY",
+	}
+
+	// Device kinds which are meant for unprivileged users, and are world accessible by default
+	unprivilegedDeviceKinds = []string{sensor.DeviceFUSE, sensor.DeviceTUN}
+
+	// TLS versions older than 1.2, as named by the Kubernetes components and etcd
+	weakTLSVersions = []string{"VersionTLS10", "VersionTLS11", "TLS1.0", "TLS1.1"}
+)
+
+func init() {
+	registerRule(Rule{
+		ID:       "world-readable-pki-key",
+		Severity: SeverityCritical,
+		Sensor:   "controlPlaneInfo",
+		Evaluate: evaluateWorldReadablePKIKeys,
+	})
+	registerRule(Rule{
+		ID:       "kubelet-anonymous-auth",
+		Severity: SeverityCritical,
+		Sensor:   "kubeletInfo",
+		Evaluate: evaluateKubeletAnonymousAuth,
+	})
+	registerRule(Rule{
+		ID:       "privileged-container",
+		Severity: SeverityCritical,
+		Sensor:   "escapeSurface",
+		Evaluate: escapeConditionEvaluator(sensor.EscapePrivileged, "container %s is privileged"),
+	})
+	registerRule(Rule{
+		ID:       "container-host-namespace",
+		Severity: SeverityHigh,
+		Sensor:   "escapeSurface",
+		Evaluate: escapeConditionEvaluator(sensor.EscapeHostNamespace, "container %s shares the host %s namespace"),
+	})
+	registerRule(Rule{
+		ID:       "container-writable-host-mount",
+		Severity: SeverityHigh,
+		Sensor:   "escapeSurface",
+		Evaluate: escapeConditionEvaluator(sensor.EscapeHostMount, "container %s mounts the host path %s writable"),
+	})
+	registerRule(Rule{
+		ID:       "container-host-device",
+		Severity: SeverityHigh,
+		Sensor:   "escapeSurface",
+		Evaluate: escapeConditionEvaluator(sensor.EscapeHostDevice, "container %s has access to the host device %s"),
+	})
+	registerRule(Rule{
+		ID:       "container-dangerous-capability",
+		Severity: SeverityHigh,
+		Sensor:   "escapeSurface",
+		Evaluate: escapeConditionEvaluator(sensor.EscapeCapability, "container %s has the %s capability"),
+	})
+	registerRule(Rule{
+		ID:       "device-world-accessible",
+		Severity: SeverityHigh,
+		Sensor:   "devices",
+		Evaluate: evaluateWorldAccessibleDevices,
+	})
+	registerRule(Rule{
+		ID:       "device-plugin-socket-permissions",
+		Severity: SeverityHigh,
+		Sensor:   "devices",
+		Evaluate: evaluateDevicePluginSocketPermissions,
+	})
+	registerRule(Rule{
+		ID:       "container-log-world-readable",
+		Severity: SeverityMedium,
+		Sensor:   "containerLogs",
+		Evaluate: evaluateWorldReadableContainerLogs,
+	})
+	registerRule(Rule{
+		ID:       "container-log-dir-world-writable",
+		Severity: SeverityHigh,
+		Sensor:   "containerLogs",
+		Evaluate: evaluateWorldWritableContainerLogDirs,
+	})
+	registerRule(Rule{
+		ID:       "container-log-link-outside-pods",
+		Severity: SeverityHigh,
+		Sensor:   "containerLogs",
+		Evaluate: evaluateContainerLogLinksOutsidePods,
+	})
+	registerRule(Rule{
+		ID:       "image-store-world-accessible",
+		Severity: SeverityHigh,
+		Sensor:   "imageStores",
+		Evaluate: evaluateWorldAccessibleImageStores,
+	})
+	registerRule(Rule{
+		ID:       "image-store-not-root-owned",
+		Severity: SeverityHigh,
+		Sensor:   "imageStores",
+		Evaluate: evaluateNonRootImageStores,
+	})
+	registerRule(Rule{
+		ID:       "static-pod-privileged",
+		Severity: SeverityHigh,
+		Sensor:   "staticPods",
+		Evaluate: staticPodEvaluator(evaluateStaticPodPrivileged),
+	})
+	registerRule(Rule{
+		ID:       "static-pod-host-namespace",
+		Severity: SeverityMedium,
+		Sensor:   "staticPods",
+		Evaluate: staticPodEvaluator(evaluateStaticPodHostNamespaces),
+	})
+	registerRule(Rule{
+		ID:       "static-pod-writable-host-path",
+		Severity: SeverityHigh,
+		Sensor:   "staticPods",
+		Evaluate: staticPodEvaluator(evaluateStaticPodWritableHostPaths),
+	})
+	registerRule(Rule{
+		ID:       "kubeconfig-insecure-skip-tls-verify",
+		Severity: SeverityHigh,
+		Sensor:   "kubeconfigs",
+		Evaluate: kubeconfigEvaluator(evaluateKubeconfigInsecureSkipTLSVerify),
+	})
+	registerRule(Rule{
+		ID:       "kubeconfig-plaintext-server",
+		Severity: SeverityHigh,
+		Sensor:   "kubeconfigs",
+		Evaluate: kubeconfigEvaluator(evaluateKubeconfigPlaintextServer),
+	})
+	registerRule(Rule{
+		ID:       "kubeconfig-client-cert-expired",
+		Severity: SeverityHigh,
+		Sensor:   "kubeconfigs",
+		Evaluate: kubeconfigEvaluator(evaluateKubeconfigClientCertExpired),
+	})
+	registerRule(Rule{
+		ID:       "kubeconfig-client-cert-expiring",
+		Severity: SeverityMedium,
+		Sensor:   "kubeconfigs",
+		Evaluate: kubeconfigEvaluator(evaluateKubeconfigClientCertExpiring),
+	})
+	registerRule(Rule{
+		ID:       "kubeconfig-long-lived-client-cert",
+		Severity: SeverityMedium,
+		Sensor:   "kubeconfigs",
+		Evaluate: kubeconfigEvaluator(evaluateKubeconfigLongLivedClientCert),
+	})
+	registerRule(Rule{
+		ID:       "private-key-permissions",
+		Severity: SeverityHigh,
+		Sensor:   "privateKeys",
+		Evaluate: evaluatePrivateKeyPermissions,
+	})
+	registerRule(Rule{
+		ID:       "bootstrap-token-on-disk",
+		Severity: SeverityHigh,
+		Sensor:   "tokens",
+		Evaluate: tokenEvaluator(func(token *sensor.TokenInfo) string {
+			if token.Type != sensor.TokenBootstrap {
+				return ""
+			}
+			return fmt.Sprintf("bootstrap token %s is left on disk in %s", token.TokenID, token.File.Path)
+		}),
+	})
+	registerRule(Rule{
+		ID:       "stray-service-account-token",
+		Severity: SeverityHigh,
+		Sensor:   "tokens",
+		Evaluate: tokenEvaluator(func(token *sensor.TokenInfo) string {
+			if token.Type != sensor.TokenServiceAccount || token.Location == sensor.TokenLocationPodVolume ||
+				(token.ExpiresAt != nil && time.Now().After(*token.ExpiresAt)) {
+				return ""
+			}
+			return fmt.Sprintf("token of service account %s/%s is left on disk in %s", token.Namespace, token.ServiceAccount, token.File.Path)
+		}),
+	})
+	registerRule(Rule{
+		ID:       "legacy-service-account-token",
+		Severity: SeverityMedium,
+		Sensor:   "tokens",
+		Evaluate: tokenEvaluator(func(token *sensor.TokenInfo) string {
+			if token.Type != sensor.TokenServiceAccount || token.ExpiresAt != nil {
+				return ""
+			}
+			return fmt.Sprintf("token of service account %s/%s in %s never expires", token.Namespace, token.ServiceAccount, token.File.Path)
+		}),
+	})
+	registerRule(Rule{
+		ID:       "readable-registry-credentials",
+		Severity: SeverityHigh,
+		Sensor:   "registryCredentials",
+		Evaluate: evaluateReadableRegistryCredentials,
+	})
+	registerRule(Rule{
+		ID:       "protected-links-disabled",
+		Severity: SeverityMedium,
+		Sensor:   "credentialProtection",
+		Evaluate: sysctlEvaluator([]string{"fs.protected_hardlinks", "fs.protected_symlinks"}, "0",
+			"links in world writable directories can be followed to files of other users"),
+	})
+	registerRule(Rule{
+		ID:       "protected-fifos-disabled",
+		Severity: SeverityLow,
+		Sensor:   "credentialProtection",
+		Evaluate: sysctlEvaluator([]string{"fs.protected_fifos", "fs.protected_regular"}, "0",
+			"FIFOs and files of other users in world writable directories can be opened for writing"),
+	})
+	registerRule(Rule{
+		ID:       "suid-core-dumps",
+		Severity: SeverityMedium,
+		Sensor:   "credentialProtection",
+		Evaluate: sysctlEvaluator([]string{"fs.suid_dumpable"}, "1", "setuid programs dump core readable by their user"),
+	})
+	registerRule(Rule{
+		ID:       "low-disk-space",
+		Severity: SeverityHigh,
+		Sensor:   "resourcePressure",
+		Evaluate: evaluateLowDiskSpace,
+	})
+	registerRule(Rule{
+		ID:       "low-inodes",
+		Severity: SeverityHigh,
+		Sensor:   "resourcePressure",
+		Evaluate: evaluateLowInodes,
+	})
+	registerRule(Rule{
+		ID:       "memory-pressure",
+		Severity: SeverityMedium,
+		Sensor:   "resourcePressure",
+		Evaluate: evaluateMemoryPressure,
+	})
+	registerRule(Rule{
+		ID:       "kernel-seccomp-unsupported",
+		Severity: SeverityHigh,
+		Sensor:   "kernelConfig",
+		Evaluate: evaluateKernelSeccompUnsupported,
+	})
+	registerRule(Rule{
+		ID:       "kernel-hardening-disabled",
+		Severity: SeverityLow,
+		Sensor:   "kernelConfig",
+		Evaluate: evaluateKernelHardeningDisabled,
+	})
+	registerRule(Rule{
+		ID:       "time-not-synchronized",
+		Severity: SeverityMedium,
+		Sensor:   "timeSync",
+		Evaluate: evaluateTimeNotSynchronized,
+	})
+	registerRule(Rule{
+		ID:       "clock-skew",
+		Severity: SeverityMedium,
+		Sensor:   "timeSync",
+		Evaluate: evaluateClockSkew,
+	})
+	registerRule(Rule{
+		ID:       "kubeadm-leftover-credentials",
+		Severity: SeverityHigh,
+		Sensor:   "kubeadmArtifacts",
+		Evaluate: evaluateKubeadmLeftoverCredentials,
+	})
+	registerRule(Rule{
+		ID:       "certificate-expired",
+		Severity: SeverityHigh,
+		Sensor:   "certificates",
+		Evaluate: certificateEvaluator(func(cert *sensor.NodeCertificate) bool { return cert.Expired }, "expired"),
+	})
+	registerRule(Rule{
+		ID:       "certificate-expiring",
+		Severity: SeverityMedium,
+		Sensor:   "certificates",
+		Evaluate: certificateEvaluator(func(cert *sensor.NodeCertificate) bool { return cert.Expiring }, "expires"),
+	})
+	registerRule(Rule{
+		ID:       "certificate-broken-chain",
+		Severity: SeverityHigh,
+		Sensor:   "certificates",
+		Evaluate: evaluateCertificateBrokenChains,
+	})
+	registerRule(Rule{
+		ID:       "certificate-unexpected-issuer",
+		Severity: SeverityHigh,
+		Sensor:   "certificates",
+		Evaluate: evaluateCertificateUnexpectedIssuers,
+	})
+	registerRule(Rule{
+		ID:       "weak-certificate-key",
+		Severity: SeverityHigh,
+		Sensor:   "certificates",
+		Evaluate: evaluateWeakCertificateKeys,
+	})
+	registerRule(Rule{
+		ID:       "weak-certificate-signature",
+		Severity: SeverityHigh,
+		Sensor:   "certificates",
+		Evaluate: evaluateWeakCertificateSignatures,
+	})
+	registerRule(Rule{
+		ID:       "weak-tls-cipher-suite",
+		Severity: SeverityHigh,
+		Sensor:   "tlsConfigs",
+		Evaluate: evaluateWeakTLSCipherSuites,
+	})
+	registerRule(Rule{
+		ID:       "weak-tls-min-version",
+		Severity: SeverityMedium,
+		Sensor:   "tlsConfigs",
+		Evaluate: evaluateWeakTLSMinVersion,
+	})
+	registerRule(Rule{
+		ID:       "etcd-secrets-not-encrypted",
+		Severity: SeverityHigh,
+		Sensor:   "etcdEncryption",
+		Evaluate: evaluateEtcdSecretsNotEncrypted,
+	})
+	registerRule(Rule{
+		ID:       "egress-proxy-unencrypted",
+		Severity: SeverityHigh,
+		Sensor:   "controlPlaneInfo",
+		Evaluate: evaluateEgressProxyUnencrypted,
+	})
+	registerRule(Rule{
+		ID:       "api-server-static-token-file",
+		Severity: SeverityCritical,
+		Sensor:   "controlPlaneInfo",
+		Evaluate: evaluateStaticTokenFile,
+	})
+	registerRule(Rule{
+		ID:       "etcd-data-dir-permissions",
+		Severity: SeverityHigh,
+		Sensor:   "controlPlaneInfo",
+		Evaluate: evaluateEtcdDataDirPermissions,
+	})
+	registerRule(Rule{
+		ID:       "containerd-stream-server-exposed",
+		Severity: SeverityHigh,
+		Sensor:   "containerdServer",
+		Evaluate: evaluateContainerdStreamServerExposed,
+	})
+	registerRule(Rule{
+		ID:       "containerd-api-exposed",
+		Severity: SeverityCritical,
+		Sensor:   "containerdServer",
+		Evaluate: evaluateContainerdAPIExposed,
+	})
+	registerRule(Rule{
+		ID:       "kube-proxy-conntrack-table-small",
+		Severity: SeverityMedium,
+		Sensor:   "kubeProxyInfo",
+		Evaluate: evaluateKubeProxyConntrackTableSmall,
+	})
+	registerRule(Rule{
+		ID:       "kube-proxy-ipvs-module-missing",
+		Severity: SeverityHigh,
+		Sensor:   "kubeProxyInfo",
+		Evaluate: evaluateKubeProxyIPVSModuleMissing,
+	})
+	registerRule(Rule{
+		ID:       "iptables-backend-mismatch",
+		Severity: SeverityHigh,
+		Sensor:   "openedPorts",
+		Evaluate: evaluateIPTablesBackendMismatch,
+	})
+	registerRule(Rule{
+		ID:       "runtime-socket-non-root-access",
+		Severity: SeverityCritical,
+		Sensor:   "runtimeSockets",
+		Evaluate: evaluateRuntimeSocketNonRootAccess,
+	})
+	registerRule(Rule{
+		ID:       "kubelet-authorization-always-allow",
+		Severity: SeverityCritical,
+		Sensor:   "kubeletInfo",
+		Evaluate: evaluateKubeletAuthorizationAlwaysAllow,
+	})
+	registerRule(Rule{
+		ID:       "cni-risky-settings",
+		Severity: SeverityMedium,
+		Sensor:   "controlPlaneInfo",
+		Evaluate: evaluateCNIRiskySettings,
+	})
+	registerRule(Rule{
+		ID:       "cni-config-conflict",
+		Severity: SeverityHigh,
+		Sensor:   "controlPlaneInfo",
+		Evaluate: evaluateCNIConfigConflict,
+	})
+	registerRule(Rule{
+		ID:       "node-dns-exposed-off-node",
+		Severity: SeverityMedium,
+		Sensor:   "openedPorts",
+		Evaluate: evaluateNodeDNSExposedOffNode,
+	})
+	registerRule(Rule{
+		ID:       "cached-admin-kubeconfig",
+		Severity: SeverityHigh,
+		Sensor:   "adminTools",
+		Evaluate: evaluateCachedAdminKubeconfig,
+	})
+	registerRule(Rule{
+		ID:       "kernel-reboot-pending",
+		Severity: SeverityMedium,
+		Sensor:   "kernelPatching",
+		Evaluate: evaluateKernelRebootPending,
+	})
+	registerRule(Rule{
+		ID:       "nvidia-toolkit-weak-isolation",
+		Severity: SeverityHigh,
+		Sensor:   "accelerators",
+		Evaluate: evaluateNVIDIAToolkitWeakIsolation,
+	})
+	registerRule(Rule{
+		ID:       "admission-bypass-writable-static-pod-path",
+		Severity: SeverityCritical,
+		Sensor:   "admissionBypass",
+		Evaluate: admissionBypassEvaluator(evaluateAdmissionBypassWritableStaticPodPath),
+	})
+	registerRule(Rule{
+		ID:       "admission-bypass-static-pod-url",
+		Severity: SeverityHigh,
+		Sensor:   "admissionBypass",
+		Evaluate: admissionBypassEvaluator(evaluateAdmissionBypassStaticPodURL),
+	})
+	registerRule(Rule{
+		ID:       "admission-bypass-unexpected-static-pod-path",
+		Severity: SeverityMedium,
+		Sensor:   "admissionBypass",
+		Evaluate: admissionBypassEvaluator(evaluateAdmissionBypassUnexpectedStaticPodPath),
+	})
+	registerRule(Rule{
+		ID:       "admission-bypass-mirror-pods",
+		Severity: SeverityLow,
+		Sensor:   "admissionBypass",
+		Evaluate: admissionBypassEvaluator(evaluateAdmissionBypassMirrorPods),
+	})
+	registerRule(Rule{
+		ID:       "kubelet-credential-provider-writable",
+		Severity: SeverityCritical,
+		Sensor:   "kubeletInfo",
+		Evaluate: evaluateKubeletCredentialProviderWritable,
+	})
+	registerRule(Rule{
+		ID:       "pod-secret-volume-world-readable",
+		Severity: SeverityHigh,
+		Sensor:   "podVolumes",
+		Evaluate: evaluatePodSecretVolumeWorldReadable,
+	})
+	registerRule(Rule{
+		ID:       "orphaned-pod-volumes",
+		Severity: SeverityLow,
+		Sensor:   "podVolumes",
+		Evaluate: evaluateOrphanedPodVolumes,
+	})
+	registerRule(Rule{
+		ID:       "kubelet-seccomp-default-disabled",
+		Severity: SeverityLow,
+		Sensor:   "kubeletInfo",
+		Evaluate: evaluateKubeletSeccompDefaultDisabled,
+	})
+	registerRule(Rule{
+		ID:       "seccomp-profile-permissive",
+		Severity: SeverityMedium,
+		Sensor:   "seccompProfiles",
+		Evaluate: evaluateSeccompProfilePermissive,
+	})
+	registerRule(Rule{
+		ID:       "kubelet-client-cert-expired",
+		Severity: SeverityCritical,
+		Sensor:   "kubeletCertRotation",
+		Evaluate: kubeletCertRotationEvaluator(sensor.KubeletCertVerdictExpired),
+	})
+	registerRule(Rule{
+		ID:       "kubelet-client-cert-rotation",
+		Severity: SeverityHigh,
+		Sensor:   "kubeletCertRotation",
+		Evaluate: kubeletCertRotationEvaluator(sensor.KubeletCertVerdictRotationStalled, sensor.KubeletCertVerdictExpiring, sensor.KubeletCertVerdictBootstrapIdentity),
+	})
+	registerRule(Rule{
+		ID:       "konnectivity-socket-writable",
+		Severity: SeverityHigh,
+		Sensor:   "konnectivity",
+		Evaluate: evaluateKonnectivitySocketWritable,
+	})
+	registerRule(Rule{
+		ID:       "konnectivity-credentials-readable",
+		Severity: SeverityHigh,
+		Sensor:   "konnectivity",
+		Evaluate: evaluateKonnectivityCredentialsReadable,
+	})
+	registerRule(Rule{
+		ID:       "apiserver-ssh-tunnel",
+		Severity: SeverityMedium,
+		Sensor:   "konnectivity",
+		Evaluate: evaluateAPIServerSSHTunnel,
+	})
+	registerRule(Rule{
+		ID:       "plaintext-remote-service",
+		Severity: SeverityHigh,
+		Sensor:   "riskyServices",
+		Evaluate: riskyServiceEvaluator(sensor.RiskyServiceTelnet, sensor.RiskyServiceFTP),
+	})
+	registerRule(Rule{
+		ID:       "docker-api-unauthenticated",
+		Severity: SeverityCritical,
+		Sensor:   "riskyServices",
+		Evaluate: riskyServiceEvaluator(sensor.RiskyServiceDockerTCP),
+	})
+	registerRule(Rule{
+		ID:       "kubelet-debugging-handlers-exposed",
+		Severity: SeverityCritical,
+		Sensor:   "riskyServices",
+		Evaluate: riskyServiceEvaluator(sensor.RiskyServiceKubeletDebugging),
+	})
+	registerRule(Rule{
+		ID:       "node-authorizer-disabled",
+		Severity: SeverityHigh,
+		Sensor:   "nodeRestriction",
+		Evaluate: evaluateNodeAuthorizerDisabled,
+	})
+	registerRule(Rule{
+		ID:       "node-restriction-disabled",
+		Severity: SeverityHigh,
+		Sensor:   "nodeRestriction",
+		Evaluate: evaluateNodeRestrictionDisabled,
+	})
+	registerRule(Rule{
+		ID:       "kubelet-not-node-identity",
+		Severity: SeverityHigh,
+		Sensor:   "nodeRestriction",
+		Evaluate: evaluateKubeletNotNodeIdentity,
+	})
+	registerRule(Rule{
+		ID:       "disk-exhaustion-imminent",
+		Severity: SeverityHigh,
+		Sensor:   "kubeletDiskGC",
+		Evaluate: diskExhaustionEvaluator(sensor.DiskExhaustionRiskHigh),
+	})
+	registerRule(Rule{
+		ID:       "disk-gc-policy-weak",
+		Severity: SeverityMedium,
+		Sensor:   "kubeletDiskGC",
+		Evaluate: diskExhaustionEvaluator(sensor.DiskExhaustionRiskMedium),
+	})
+	registerRule(Rule{
+		ID:       "container-log-rotation-missing",
+		Severity: SeverityMedium,
+		Sensor:   "containerLogRotation",
+		Evaluate: evaluateContainerLogRotationMissing,
+	})
+}
+
+// escapeConditionEvaluator returns an evaluator finding the escape conditions of `conditionType`.
+// The message format is given the container name and the condition detail.
+func escapeConditionEvaluator(conditionType, messageFormat string) func(result json.RawMessage) ([]Finding, error) {
+	return func(result json.RawMessage) ([]Finding, error) {
+		surface := sensor.EscapeSurface{}
+		if err := json.Unmarshal(result, &surface); err != nil {
+			return nil, err
+		}
+
+		findings := []Finding{}
+		for _, container := range surface.Containers {
+			name := container.ID
+			if container.PodName != "" {
+				name = fmt.Sprintf("%s/%s/%s", container.PodNamespace, container.PodName, container.Container)
+			}
+			for _, condition := range container.Conditions {
+				if condition.Type != conditionType {
+					continue
+				}
+				finding := Finding{Path: name, Message: fmt.Sprintf(messageFormat, name)}
+				if condition.Detail != "" {
+					finding.Path = name + ":" + condition.Detail
+					finding.Message = fmt.Sprintf(messageFormat, name, condition.Detail)
+				}
+				findings = append(findings, finding)
+			}
+		}
+		return findings, nil
+	}
+}
+
+// evaluateWorldReadablePKIKeys finds private keys in the PKI directory which are readable by any user
+func evaluateWorldReadablePKIKeys(result json.RawMessage) ([]Finding, error) {
+	info := sensor.ControlPlaneInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, file := range info.PKIFiles {
+		if file == nil || !strings.HasSuffix(file.Path, ".key") {
+			continue
+		}
+		if file.Permissions&worldReadablePerm != 0 {
+			findings = append(findings, Finding{
+				Path:    file.Path,
+				Message: fmt.Sprintf("private key %s is world readable (permissions %o)", file.Path, file.Permissions),
+			})
+		}
+	}
+
+	return findings, nil
+}
+
+// evaluateKubeletAnonymousAuth detects kubelet with anonymous authentication enabled.
+// The command line flag takes precedence over the config file, and anonymous authentication is enabled by default.
+func evaluateKubeletAnonymousAuth(result json.RawMessage) ([]Finding, error) {
+	info := sensor.KubeletInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+
+	p := sensor.ProcessDetails{CmdLine: strings.Split(info.CmdLine, " ")}
+	if val, ok := p.GetArg(kubeletAnonymousAuthArg); ok {
+		// boolean flags without value are true
+		enabled, err := strconv.ParseBool(val)
+		if val != "" && err != nil {
+			return nil, fmt.Errorf("invalid %s value %q: %w", kubeletAnonymousAuthArg, val, err)
+		}
+		if val != "" && !enabled {
+			return nil, nil
+		}
+		return []Finding{{
+			Message: fmt.Sprintf("kubelet anonymous authentication is enabled by the %s flag", kubeletAnonymousAuthArg),
+		}}, nil
+	}
+
+	// Can't tell without the config file
+	if info.ConfigFile == nil || info.ConfigFile.Content == nil {
+		return nil, nil
+	}
+
+	conf := struct {
+		Authentication struct {
+			Anonymous struct {
+				Enabled *bool `json:"enabled"`
+			} `json:"anonymous"`
+		} `json:"authentication"`
+	}{}
+	if err := yaml.Unmarshal(info.ConfigFile.Content, &conf); err != nil {
+		return nil, fmt.Errorf("failed to parse kubelet config: %w", err)
+	}
+
+	enabled := conf.Authentication.Anonymous.Enabled
+	if enabled == nil {
+		return []Finding{{
+			Path:    info.ConfigFile.Path,
+			Message: "kubelet anonymous authentication is enabled by default (authentication.anonymous.enabled is not set)",
+		}}, nil
+	}
+	if *enabled {
+		return []Finding{{
+			Path:    info.ConfigFile.Path,
+			Message: "kubelet anonymous authentication is enabled (authentication.anonymous.enabled is true)",
+		}}, nil
+	}
+
+	return nil, nil
+}
+
+// kubeconfigEvaluator returns an evaluator calling `evaluate` for every kubeconfig of the kubeconfigs sensor
+func kubeconfigEvaluator(evaluate func(kubeconfig *sensor.KubeconfigInfo) []Finding) func(result json.RawMessage) ([]Finding, error) {
+	return func(result json.RawMessage) ([]Finding, error) {
+		kubeconfigs := []sensor.KubeconfigInfo{}
+		if err := json.Unmarshal(result, &kubeconfigs); err != nil {
+			return nil, err
+		}
+
+		findings := []Finding{}
+		for i := range kubeconfigs {
+			if kubeconfigs[i].File == nil {
+				continue
+			}
+			findings = append(findings, evaluate(&kubeconfigs[i])...)
+		}
+		return findings, nil
+	}
+}
+
+// evaluateKubeconfigInsecureSkipTLSVerify finds clusters whose server certificate isn't verified
+func evaluateKubeconfigInsecureSkipTLSVerify(kubeconfig *sensor.KubeconfigInfo) []Finding {
+	findings := []Finding{}
+	for _, cluster := range kubeconfig.Clusters {
+		if cluster.InsecureSkipTLSVerify {
+			findings = append(findings, Finding{
+				Path:    kubeconfig.File.Path + ":" + cluster.Name,
+				Message: fmt.Sprintf("%s kubeconfig %s skips the TLS verification of cluster %s", kubeconfig.Component, kubeconfig.File.Path, cluster.Name),
+			})
+		}
+	}
+	return findings
+}
+
+// evaluateKubeconfigPlaintextServer finds clusters which are connected without TLS
+func evaluateKubeconfigPlaintextServer(kubeconfig *sensor.KubeconfigInfo) []Finding {
+	findings := []Finding{}
+	for _, cluster := range kubeconfig.Clusters {
+		if strings.HasPrefix(strings.ToLower(cluster.Server), "http://") {
+			findings = append(findings, Finding{
+				Path:    kubeconfig.File.Path + ":" + cluster.Name,
+				Message: fmt.Sprintf("%s kubeconfig %s connects to cluster %s in plaintext (%s)", kubeconfig.Component, kubeconfig.File.Path, cluster.Name, cluster.Server),
+			})
+		}
+	}
+	return findings
+}
+
+// evaluateKubeconfigClientCertExpired finds expired client certificates
+func evaluateKubeconfigClientCertExpired(kubeconfig *sensor.KubeconfigInfo) []Finding {
+	findings := []Finding{}
+	for _, user := range kubeconfig.Users {
+		cert := user.ClientCertificate
+		if cert != nil && time.Now().After(cert.NotAfter) {
+			findings = append(findings, Finding{
+				Path:    kubeconfig.File.Path + ":" + user.Name,
+				Message: fmt.Sprintf("client certificate of user %s in %s kubeconfig %s expired on %s", user.Name, kubeconfig.Component, kubeconfig.File.Path, cert.NotAfter.Format(time.RFC3339)),
+			})
+		}
+	}
+	return findings
+}
+
+// evaluateKubeconfigClientCertExpiring finds client certificates which are about to expire
+func evaluateKubeconfigClientCertExpiring(kubeconfig *sensor.KubeconfigInfo) []Finding {
+	findings := []Finding{}
+	now := time.Now()
+	for _, user := range kubeconfig.Users {
+		cert := user.ClientCertificate
+		if cert != nil && !now.After(cert.NotAfter) && cert.NotAfter.Sub(now) < certExpiryWarning {
+			findings = append(findings, Finding{
+				Path:    kubeconfig.File.Path + ":" + user.Name,
+				Message: fmt.Sprintf("client certificate of user %s in %s kubeconfig %s expires on %s", user.Name, kubeconfig.Component, kubeconfig.File.Path, cert.NotAfter.Format(time.RFC3339)),
+			})
+		}
+	}
+	return findings
+}
+
+// evaluateKubeconfigLongLivedClientCert finds embedded client certificates valid for more than a year.
+// Embedded certificates aren't rotated and can't be revoked, so they stay usable until they expire.
+func evaluateKubeconfigLongLivedClientCert(kubeconfig *sensor.KubeconfigInfo) []Finding {
+	findings := []Finding{}
+	for _, user := range kubeconfig.Users {
+		cert := user.ClientCertificate
+		if cert != nil && cert.Embedded && cert.NotAfter.Sub(cert.NotBefore) > longLivedCertValidity {
+			findings = append(findings, Finding{
+				Path: kubeconfig.File.Path + ":" + user.Name,
+				Message: fmt.Sprintf("%s kubeconfig %s embeds a client certificate of user %s valid until %s",
+					kubeconfig.Component, kubeconfig.File.Path, user.Name, cert.NotAfter.Format(time.RFC3339)),
+			})
+		}
+	}
+	return findings
+}
+
+// evaluatePrivateKeyPermissions finds private keys which are group or world readable, or owned by a non-root user
+func evaluatePrivateKeyPermissions(result json.RawMessage) ([]Finding, error) {
+	keys := []sensor.PrivateKeyInfo{}
+	if err := json.Unmarshal(result, &keys); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, key := range keys {
+		if key.File == nil || len(key.Issues) == 0 {
+			continue
+		}
+		findings = append(findings, Finding{
+			Path: key.File.Path,
+			Message: fmt.Sprintf("private key %s has insecure permissions: %s (permissions %o)",
+				key.File.Path, strings.Join(key.Issues, ", "), key.File.Permissions),
+		})
+	}
+	return findings, nil
+}
+
+// tokenEvaluator returns an evaluator of the tokens sensor.
+// `message` returns the message of the finding of a token, or an empty string if the token is fine.
+func tokenEvaluator(message func(token *sensor.TokenInfo) string) func(result json.RawMessage) ([]Finding, error) {
+	return func(result json.RawMessage) ([]Finding, error) {
+		tokens := []sensor.TokenInfo{}
+		if err := json.Unmarshal(result, &tokens); err != nil {
+			return nil, err
+		}
+
+		findings := []Finding{}
+		for i := range tokens {
+			if tokens[i].File == nil {
+				continue
+			}
+			if msg := message(&tokens[i]); msg != "" {
+				findings = append(findings, Finding{Path: tokens[i].File.Path, Message: msg})
+			}
+		}
+		return findings, nil
+	}
+}
+
+// evaluateReadableRegistryCredentials finds registry credentials files which are readable by other users than their owner
+func evaluateReadableRegistryCredentials(result json.RawMessage) ([]Finding, error) {
+	files := []sensor.RegistryCredentialsFile{}
+	if err := json.Unmarshal(result, &files); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, file := range files {
+		if file.File == nil || !file.InlineSecrets || file.File.Permissions&(groupReadablePerm|worldReadablePerm) == 0 {
+			continue
+		}
+		registries := []string{}
+		for _, registry := range file.Registries {
+			registries = append(registries, registry.Registry)
+		}
+		findings = append(findings, Finding{
+			Path: file.File.Path,
+			Message: fmt.Sprintf("registry credentials %s for %s are readable by other users (permissions %o)",
+				file.File.Path, strings.Join(registries, ", "), file.File.Permissions),
+		})
+	}
+	return findings, nil
+}
+
+// certificateEvaluator returns an evaluator finding the certificates matching `match`.
+// The message says the certificate `verb` on its expiry date.
+func certificateEvaluator(match func(cert *sensor.NodeCertificate) bool, verb string) func(result json.RawMessage) ([]Finding, error) {
+	return func(result json.RawMessage) ([]Finding, error) {
+		certs := []sensor.NodeCertificate{}
+		if err := json.Unmarshal(result, &certs); err != nil {
+			return nil, err
+		}
+
+		findings := []Finding{}
+		for i := range certs {
+			if !match(&certs[i]) {
+				continue
+			}
+			findings = append(findings, Finding{
+				Path: certs[i].Path + ":" + certs[i].SerialNumber,
+				Message: fmt.Sprintf("%s certificate %s (%s) %s on %s",
+					certs[i].Source, certs[i].Path, certs[i].Subject, verb, certs[i].NotAfter.Format(time.RFC3339)),
+			})
+		}
+		return findings, nil
+	}
+}
+
+// evaluateWeakCertificateKeys finds certificates with RSA keys shorter than 2048 bits
+func evaluateWeakCertificateKeys(result json.RawMessage) ([]Finding, error) {
+	certs := []sensor.NodeCertificate{}
+	if err := json.Unmarshal(result, &certs); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, cert := range certs {
+		if cert.PublicKeyAlgorithm == "RSA" && cert.KeyBits > 0 && cert.KeyBits < minRSAKeyBits {
+			findings = append(findings, Finding{
+				Path:    cert.Path + ":" + cert.SerialNumber,
+				Message: fmt.Sprintf("certificate %s (%s) has a %d bits RSA key", cert.Path, cert.Subject, cert.KeyBits),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// evaluateWeakCertificateSignatures finds certificates signed with MD5 or SHA-1.
+// Self-signed certificates are skipped, since their signature isn't verified.
+func evaluateWeakCertificateSignatures(result json.RawMessage) ([]Finding, error) {
+	certs := []sensor.NodeCertificate{}
+	if err := json.Unmarshal(result, &certs); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, cert := range certs {
+		if cert.Subject == cert.Issuer {
+			continue
+		}
+		for _, hash := range weakSignatureHashes {
+			if strings.Contains(cert.SignatureAlgorithm, hash) {
+				findings = append(findings, Finding{
+					Path:    cert.Path + ":" + cert.SerialNumber,
+					Message: fmt.Sprintf("certificate %s (%s) is signed with %s", cert.Path, cert.Subject, cert.SignatureAlgorithm),
+				})
+				break
+			}
+		}
+	}
+	return findings, nil
+}
+
+// evaluateWeakTLSCipherSuites finds components configured with insecure cipher suites
+func evaluateWeakTLSCipherSuites(result json.RawMessage) ([]Finding, error) {
+	configs := []sensor.TLSConfig{}
+	if err := json.Unmarshal(result, &configs); err != nil {
+		return nil, err
+	}
+
+	insecure := map[string]bool{}
+	for _, suite := range tls.InsecureCipherSuites() {
+		insecure[suite.Name] = true
+	}
+
+	findings := []Finding{}
+	for _, conf := range configs {
+		for _, suite := range conf.CipherSuites {
+			suite = strings.TrimSpace(suite)
+			weak := insecure[suite]
+			for _, part := range weakCipherParts {
+				weak = weak || strings.Contains(suite, part)
+			}
+			if weak {
+				findings = append(findings, Finding{
+					Path:    conf.Component + ":" + suite,
+					Message: fmt.Sprintf("%s is configured with the weak cipher suite %s", conf.Component, suite),
+				})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// evaluateWeakTLSMinVersion finds components accepting TLS versions older than 1.2
+func evaluateWeakTLSMinVersion(result json.RawMessage) ([]Finding, error) {
+	configs := []sensor.TLSConfig{}
+	if err := json.Unmarshal(result, &configs); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, conf := range configs {
+		if containsString(weakTLSVersions, conf.MinVersion) {
+			findings = append(findings, Finding{
+				Path:    conf.Component,
+				Message: fmt.Sprintf("%s accepts TLS versions from %s", conf.Component, conf.MinVersion),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// evaluateEtcdSecretsNotEncrypted detects secrets which are stored in etcd in plaintext
+func evaluateEtcdSecretsNotEncrypted(result json.RawMessage) ([]Finding, error) {
+	info := sensor.EtcdEncryptionInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+
+	plaintext := []string{}
+	for _, sample := range info.Samples {
+		if !sample.Encrypted {
+			plaintext = append(plaintext, strings.TrimPrefix(sample.Key, "/registry/secrets/"))
+		}
+	}
+	if len(plaintext) == 0 {
+		return nil, nil
+	}
+	return []Finding{{
+		Path: "etcd",
+		Message: fmt.Sprintf("%d of %d sampled secrets are stored in etcd without encryption (%s)",
+			len(plaintext), len(info.Samples), strings.Join(plaintext, ", ")),
+	}}, nil
+}
+
+// evaluateStaticTokenFile detects the static token file of the API server, whose tokens never expire and can't be
+// revoked without restarting the API server
+func evaluateStaticTokenFile(result json.RawMessage) ([]Finding, error) {
+	info := sensor.ControlPlaneInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	if info.APIServerInfo == nil || info.APIServerInfo.TokenAuthFile == nil {
+		return []Finding{}, nil
+	}
+
+	file := info.APIServerInfo.TokenAuthFile
+	return []Finding{{
+		Path:    file.Path,
+		Message: fmt.Sprintf("the API server authenticates %d static tokens of %s", info.APIServerInfo.StaticTokenCount, file.Path),
+	}}, nil
+}
+
+// evaluateEtcdDataDirPermissions finds the etcd data dir, WAL and snapshot files which are group or world
+// accessible, or not owned by the owner of the data dir (the etcd user)
+func evaluateEtcdDataDirPermissions(result json.RawMessage) ([]Finding, error) {
+	info := sensor.ControlPlaneInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	if info.EtcdDataDir == nil {
+		return []Finding{}, nil
+	}
+
+	owner := info.EtcdDataDir.Ownership
+	findings := []Finding{}
+	for _, file := range append([]*sensor.FileInfo{info.EtcdDataDir}, info.EtcdDataDirFiles...) {
+		if file == nil {
+			continue
+		}
+		issues := []string{}
+		if file.Permissions&(groupReadablePerm|groupWritablePerm|worldReadablePerm|worldWritablePerm) != 0 {
+			issues = append(issues, fmt.Sprintf("group or world accessible (permissions %o)", file.Permissions))
+		}
+		if owner != nil && owner.Err == "" && file.Ownership != nil && file.Ownership.Err == "" && file.Ownership.UID != owner.UID {
+			issues = append(issues, fmt.Sprintf("owned by UID %d instead of the etcd user (UID %d)", file.Ownership.UID, owner.UID))
+		}
+		if len(issues) > 0 {
+			findings = append(findings, Finding{
+				Path:    file.Path,
+				Message: fmt.Sprintf("etcd data %s is %s", file.Path, strings.Join(issues, ", ")),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// evaluateEgressProxyUnencrypted finds the egress selections of the API server tunneled to a tcp proxy without TLS,
+// which carry the API server traffic (e.g. kubelet exec and logs) in plaintext
+func evaluateEgressProxyUnencrypted(result json.RawMessage) ([]Finding, error) {
+	info := sensor.ControlPlaneInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	if info.APIServerInfo == nil || info.APIServerInfo.EgressSelectorConfig == nil {
+		return []Finding{}, nil
+	}
+
+	config := info.APIServerInfo.EgressSelectorConfig
+	findings := []Finding{}
+	for _, selection := range config.Selections {
+		if selection.Transport != "tcp" || (selection.CABundle != "" && !strings.HasPrefix(selection.URL, "http://")) {
+			continue
+		}
+		findings = append(findings, Finding{
+			Path: config.File.Path,
+			Message: fmt.Sprintf("the %s egress of the API server is tunneled to %s without TLS",
+				selection.Name, selection.URL),
+		})
+	}
+	return findings, nil
+}
+
+// evaluateContainerdStreamServerExposed finds a CRI stream server serving the exec and attach streams unencrypted
+// on a non-loopback address
+func evaluateContainerdStreamServerExposed(result json.RawMessage) ([]Finding, error) {
+	info := sensor.ContainerdServerInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	if info.ConfigFile == nil || !info.Stream.Exposed {
+		return []Finding{}, nil
+	}
+
+	address := info.Stream.Address
+	if address == "" {
+		address = "all the interfaces"
+	}
+	return []Finding{{
+		Path:    info.ConfigFile.Path,
+		Message: fmt.Sprintf("the containerd stream server serves the exec and attach streams on %s without TLS", address),
+	}}, nil
+}
+
+// evaluateContainerdAPIExposed finds a containerd API served on a non-loopback TCP address without client
+// authentication
+func evaluateContainerdAPIExposed(result json.RawMessage) ([]Finding, error) {
+	info := sensor.ContainerdServerInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	if info.ConfigFile == nil || !info.GRPC.Exposed {
+		return []Finding{}, nil
+	}
+	return []Finding{{
+		Path:    info.ConfigFile.Path,
+		Message: fmt.Sprintf("the containerd API is served on %s without client certificate authentication", info.GRPC.TCPAddress),
+	}}, nil
+}
+
+// evaluateKubeProxyConntrackTableSmall finds a kernel conntrack table smaller than the one kube-proxy sets, e.g.
+// when kube-proxy failed to set it or another agent lowered it
+func evaluateKubeProxyConntrackTableSmall(result json.RawMessage) ([]Finding, error) {
+	info := sensor.KubeProxyInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	conntrack := info.Conntrack
+	if conntrack == nil || conntrack.KernelMax == 0 || conntrack.KernelMax >= conntrack.ExpectedMax {
+		return []Finding{}, nil
+	}
+	return []Finding{{
+		Path: "/proc/sys/net/netfilter/nf_conntrack_max",
+		Message: fmt.Sprintf("the conntrack table of the kernel holds %d connections while kube-proxy expects %d",
+			conntrack.KernelMax, conntrack.ExpectedMax),
+	}}, nil
+}
+
+// evaluateKubeProxyIPVSModuleMissing finds the kernel modules of the ipvs mode which the running kernel lacks
+func evaluateKubeProxyIPVSModuleMissing(result json.RawMessage) ([]Finding, error) {
+	info := sensor.KubeProxyInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	findings := []Finding{}
+	if info.IPVS == nil {
+		return findings, nil
+	}
+	for _, module := range info.KernelModules {
+		if module.Available {
+			continue
+		}
+		findings = append(findings, Finding{
+			Path:    module.Name,
+			Message: fmt.Sprintf("kube-proxy runs in ipvs mode but the kernel module %s isn't available", module.Name),
+		})
+	}
+	return findings, nil
+}
+
+// evaluateIPTablesBackendMismatch finds iptables rules split between the legacy and the nft backends
+func evaluateIPTablesBackendMismatch(result json.RawMessage) ([]Finding, error) {
+	// the open ports status has no iptables backend on the other platforms
+	status := struct {
+		IPTablesBackend *sensor.IPTablesBackendInfo `json:"iptablesBackend"`
+	}{}
+	if err := json.Unmarshal(result, &status); err != nil {
+		return nil, err
+	}
+	backend := status.IPTablesBackend
+	if backend == nil || (backend.Verdict != sensor.IPTablesVerdictMismatch && backend.Verdict != sensor.IPTablesVerdictMixedRules) {
+		return []Finding{}, nil
+	}
+	return []Finding{{
+		Path:    "iptables",
+		Message: fmt.Sprintf("the iptables backends aren't compatible: %s", backend.Reason),
+	}}, nil
+}
+
+// evaluateRuntimeSocketNonRootAccess finds container runtime sockets which users other than root can connect to,
+// which makes them equivalent to root
+func evaluateRuntimeSocketNonRootAccess(result json.RawMessage) ([]Finding, error) {
+	sockets := []sensor.RuntimeSocket{}
+	if err := json.Unmarshal(result, &sockets); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, socket := range sockets {
+		var who string
+		switch {
+		case socket.WorldAccessible:
+			who = "any user on the node"
+		case len(socket.Users) > 0 && socket.Group != "":
+			who = fmt.Sprintf("the users %s (through the group %s)", strings.Join(socket.Users, ", "), socket.Group)
+		case len(socket.Users) > 0:
+			who = "the users " + strings.Join(socket.Users, ", ")
+		default:
+			continue
+		}
+		findings = append(findings, Finding{
+			Path:    socket.File.Path,
+			Message: fmt.Sprintf("%s can connect to the %s socket %s, which is equivalent to root", who, socket.Runtime, socket.File.Path),
+		})
+	}
+	return findings, nil
+}
+
+// evaluateKubeletAuthorizationAlwaysAllow finds a kubelet which authorizes every authenticated request to its API
+func evaluateKubeletAuthorizationAlwaysAllow(result json.RawMessage) ([]Finding, error) {
+	info := sensor.KubeletInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	if info.Settings == nil {
+		return []Finding{}, nil
+	}
+
+	mode := info.Settings.AuthorizationMode
+	// without a config file, the flag defaults to AlwaysAllow
+	if (mode == nil && info.ConfigFile == nil) || (mode != nil && *mode == "AlwaysAllow") {
+		return []Finding{{Message: "kubelet authorizes every request to its API (authorization mode AlwaysAllow)"}}, nil
+	}
+	return []Finding{}, nil
+}
+
+// evaluateCNIRiskySettings finds the risky settings of the CNI plugin chains
+func evaluateCNIRiskySettings(result json.RawMessage) ([]Finding, error) {
+	info := sensor.ControlPlaneInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+
+	reasons := map[string]string{
+		sensor.CNIRiskHairpinOff:         "its bridge has hairpin mode off, so pods can't reach themselves through a service",
+		sensor.CNIRiskPortmapNoIsolation: "it forwards host ports with the portmap plugin without the firewall plugin isolating the pods",
+	}
+	findings := []Finding{}
+	for _, network := range info.CNINetworks {
+		for _, risk := range network.Risks {
+			findings = append(findings, Finding{
+				Path:    network.File,
+				Message: fmt.Sprintf("CNI network %s: %s", network.Name, reasons[risk]),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// evaluateCNIConfigConflict finds the conflicting CNI configs and agents
+func evaluateCNIConfigConflict(result json.RawMessage) ([]Finding, error) {
+	info := sensor.ControlPlaneInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	if info.CNIConflicts == nil {
+		return nil, nil
+	}
+
+	findings := []Finding{}
+	for _, reason := range info.CNIConflicts.Reasons {
+		findings = append(findings, Finding{
+			Path:    info.CNIConflicts.ConfigDir,
+			Message: fmt.Sprintf("CNI conflict: %s", reason),
+		})
+	}
+	return findings, nil
+}
+
+// evaluateNodeDNSExposedOffNode finds the DNS servers of the node which listen on the node addresses
+func evaluateNodeDNSExposedOffNode(result json.RawMessage) ([]Finding, error) {
+	// the open ports status has no DNS servers on the other platforms
+	status := struct {
+		DNS *sensor.NodeDNSInfo `json:"dns"`
+	}{}
+	if err := json.Unmarshal(result, &status); err != nil {
+		return nil, err
+	}
+	if status.DNS == nil {
+		return []Finding{}, nil
+	}
+
+	findings := []Finding{}
+	for _, server := range status.DNS.Servers {
+		for _, listener := range server.Listeners {
+			if listener.Exposure != sensor.DNSExposureOffNode {
+				continue
+			}
+			address := net.JoinHostPort(listener.Address, strconv.Itoa(listener.Port))
+			findings = append(findings, Finding{
+				Path:    address,
+				Message: fmt.Sprintf("%s listens on %s %s, reachable off the node", server.Kind, listener.Protocol, address),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// evaluateCachedAdminKubeconfig finds the kubeconfigs of the local users which grant cluster admin
+func evaluateCachedAdminKubeconfig(result json.RawMessage) ([]Finding, error) {
+	info := sensor.AdminToolsInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, kubeconfig := range info.Kubeconfigs {
+		if kubeconfig.File == nil {
+			continue
+		}
+		for _, user := range kubeconfig.Users {
+			if !user.Admin {
+				continue
+			}
+			findings = append(findings, Finding{
+				Path:    kubeconfig.File.Path,
+				Message: fmt.Sprintf("the kubeconfig of %s grants cluster admin as %s (%s)", kubeconfig.Owner, user.Name, user.ClientCertificate.Subject),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// evaluateKernelRebootPending finds a node which needs a reboot to run its installed updates
+func evaluateKernelRebootPending(result json.RawMessage) ([]Finding, error) {
+	info := sensor.KernelPatchingInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, reason := range info.RebootReasons {
+		message := ""
+		switch reason {
+		case sensor.RebootReasonNewerKernel:
+			if len(info.InstalledReleases) == 0 {
+				continue
+			}
+			message = fmt.Sprintf("the node runs kernel %s while %s is installed", info.RunningRelease, info.InstalledReleases[len(info.InstalledReleases)-1])
+		case sensor.RebootReasonPendingUpdates:
+			message = "the package manager requires a reboot to apply the installed updates"
+			if len(info.RebootPackages) > 0 {
+				message += fmt.Sprintf(" of %s", strings.Join(info.RebootPackages, ", "))
+			}
+		default:
+			continue
+		}
+		findings = append(findings, Finding{Path: "kernel", Message: message})
+	}
+	return findings, nil
+}
+
+// evaluateNVIDIAToolkitWeakIsolation finds the settings of the NVIDIA container toolkit which weaken the isolation of
+// the GPUs
+func evaluateNVIDIAToolkitWeakIsolation(result json.RawMessage) ([]Finding, error) {
+	info := sensor.AcceleratorInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	toolkit := info.NVIDIAToolkit
+	if toolkit == nil || toolkit.ConfigFile == nil {
+		return []Finding{}, nil
+	}
+
+	reasons := map[string]string{
+		sensor.NVIDIAToolkitRiskNoCgroups:          "no-cgroups leaves the GPUs out of the device cgroup of the containers",
+		sensor.NVIDIAToolkitRiskEnvvarUnprivileged: "unprivileged containers get any GPU of their NVIDIA_VISIBLE_DEVICES variable, regardless of their allocation",
+		sensor.NVIDIAToolkitRiskDefaultRuntime:     fmt.Sprintf("nvidia is the default runtime of %s, so every container goes through the toolkit hook", strings.Join(toolkit.DefaultRuntimeOf, " and ")),
+	}
+	findings := []Finding{}
+	for _, risk := range toolkit.Risks {
+		if reason, ok := reasons[risk]; ok {
+			findings = append(findings, Finding{Path: toolkit.ConfigFile.Path, Message: "NVIDIA container toolkit: " + reason})
+		}
+	}
+	return findings, nil
+}
+
+// admissionBypassEvaluator returns a rule evaluating the admission bypass paths of the node
+func admissionBypassEvaluator(evaluate func(info *sensor.AdmissionBypassInfo) []Finding) func(result json.RawMessage) ([]Finding, error) {
+	return func(result json.RawMessage) ([]Finding, error) {
+		info := sensor.AdmissionBypassInfo{}
+		if err := json.Unmarshal(result, &info); err != nil {
+			return nil, err
+		}
+		return evaluate(&info), nil
+	}
+}
+
+// evaluateAdmissionBypassWritableStaticPodPath finds the static pod path, or a parent directory, which users other
+// than root can write, and so run any pod on the node
+func evaluateAdmissionBypassWritableStaticPodPath(info *sensor.AdmissionBypassInfo) []Finding {
+	findings := []Finding{}
+	for _, writable := range info.NonRootWritable {
+		if writable.File == nil {
+			continue
+		}
+		findings = append(findings, Finding{
+			Path:    writable.File.Path,
+			Message: fmt.Sprintf("%s is writable by %s, who can add static pods to %s", writable.File.Path, strings.Join(writable.Writers, ", "), info.StaticPodPath),
+		})
+	}
+	return findings
+}
+
+// evaluateAdmissionBypassStaticPodURL finds a kubelet fetching static pods over HTTP
+func evaluateAdmissionBypassStaticPodURL(info *sensor.AdmissionBypassInfo) []Finding {
+	if info.StaticPodURL == "" {
+		return []Finding{}
+	}
+	return []Finding{{
+		Path:    info.StaticPodURL,
+		Message: fmt.Sprintf("the kubelet runs the static pods served by %s", info.StaticPodURL),
+	}}
+}
+
+// evaluateAdmissionBypassUnexpectedStaticPodPath finds a static pod path other than the one of the distribution
+func evaluateAdmissionBypassUnexpectedStaticPodPath(info *sensor.AdmissionBypassInfo) []Finding {
+	if !info.UnexpectedStaticPodPath {
+		return []Finding{}
+	}
+	return []Finding{{
+		Path:    info.StaticPodPath,
+		Message: fmt.Sprintf("the kubelet runs the static pods of the unexpected path %s", info.StaticPodPath),
+	}}
+}
+
+// evaluateAdmissionBypassMirrorPods finds static pods other than the control plane ones
+func evaluateAdmissionBypassMirrorPods(info *sensor.AdmissionBypassInfo) []Finding {
+	if info.NonControlPlaneMirrorPods == 0 {
+		return []Finding{}
+	}
+	return []Finding{{
+		Path:    info.StaticPodPath,
+		Message: fmt.Sprintf("%d of the %d static pods aren't control plane pods, their mirror pods bypassed admission control", info.NonControlPlaneMirrorPods, info.MirrorPods),
+	}}
+}
+
+// evaluateKubeletCredentialProviderWritable finds image credential provider executables, or their bin directory,
+// which users other than root can write, i.e. replace a plugin the kubelet executes as root
+func evaluateKubeletCredentialProviderWritable(result json.RawMessage) ([]Finding, error) {
+	info := sensor.KubeletInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	findings := []Finding{}
+	providers := info.ImageCredentialProviders
+	if providers == nil {
+		return findings, nil
+	}
+	if len(providers.BinDirWriters) > 0 {
+		findings = append(findings, Finding{
+			Path:    providers.BinDir,
+			Message: fmt.Sprintf("the image credential provider bin dir %s is writable by %s", providers.BinDir, strings.Join(providers.BinDirWriters, ", ")),
+		})
+	}
+	for _, binary := range providers.Binaries {
+		if len(binary.Writers) == 0 {
+			continue
+		}
+		findings = append(findings, Finding{
+			Path:    binary.File.Path,
+			Message: fmt.Sprintf("the image credential provider %s, which the kubelet executes, is writable by %s", binary.File.Path, strings.Join(binary.Writers, ", ")),
+		})
+	}
+	return findings, nil
+}
+
+// evaluatePodSecretVolumeWorldReadable finds secret volumes whose files any user on the node can read
+func evaluatePodSecretVolumeWorldReadable(result json.RawMessage) ([]Finding, error) {
+	info := sensor.PodVolumesInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	findings := []Finding{}
+	for _, pod := range info.Pods {
+		for _, volume := range pod.Volumes {
+			if len(volume.WorldReadable) == 0 {
+				continue
+			}
+			findings = append(findings, Finding{
+				Path:    volume.File.Path,
+				Message: fmt.Sprintf("%d files of the secret volume %s of the pod %s are readable by any user on the node", len(volume.WorldReadable), volume.Name, podVolumesName(&pod)),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// evaluateOrphanedPodVolumes finds pod directories which hold volumes but no container of the pod runs
+func evaluateOrphanedPodVolumes(result json.RawMessage) ([]Finding, error) {
+	info := sensor.PodVolumesInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	findings := []Finding{}
+	for _, pod := range info.Pods {
+		if !pod.Orphaned {
+			continue
+		}
+		findings = append(findings, Finding{
+			Path:    path.Join(info.PodsDir, pod.UID),
+			Message: fmt.Sprintf("the pod directory %s holds %d volumes but no container of the pod runs", pod.UID, len(pod.Volumes)),
+		})
+	}
+	return findings, nil
+}
+
+// podVolumesName returns the namespace/name of the pod of a pod directory, or its UID if no container of it runs
+func podVolumesName(pod *sensor.PodVolumes) string {
+	if pod.PodName == "" {
+		return pod.UID
+	}
+	return pod.PodNamespace + "/" + pod.PodName
+}
+
+// evaluateKubeletSeccompDefaultDisabled finds a kubelet which runs the containers without a seccomp profile unconfined
+func evaluateKubeletSeccompDefaultDisabled(result json.RawMessage) ([]Finding, error) {
+	info := sensor.KubeletInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	if info.Settings == nil || (info.Settings.SeccompDefault != nil && *info.Settings.SeccompDefault) {
+		return []Finding{}, nil
+	}
+	return []Finding{{Message: "kubelet runs the containers without a seccomp profile unconfined rather than RuntimeDefault (seccompDefault is disabled)"}}, nil
+}
+
+// evaluateSeccompProfilePermissive finds Localhost seccomp profiles which let every syscall they don't list through
+func evaluateSeccompProfilePermissive(result json.RawMessage) ([]Finding, error) {
+	info := sensor.SeccompProfilesInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	findings := []Finding{}
+	for _, profile := range info.Profiles {
+		if !profile.Permissive {
+			continue
+		}
+		findings = append(findings, Finding{
+			Path:    profile.File.Path,
+			Message: fmt.Sprintf("the seccomp profile localhost/%s lets every syscall through by default (%s)", profile.Name, profile.DefaultAction),
+		})
+	}
+	return findings, nil
+}
+
+// kubeletCertRotationEvaluator returns an evaluator which finds a kubelet client certificate of one of the verdicts,
+// with its reasons
+func kubeletCertRotationEvaluator(verdicts ...string) func(json.RawMessage) ([]Finding, error) {
+	return func(result json.RawMessage) ([]Finding, error) {
+		info := sensor.KubeletCertRotationInfo{}
+		if err := json.Unmarshal(result, &info); err != nil {
+			return nil, err
+		}
+		if !containsString(verdicts, info.Verdict) {
+			return []Finding{}, nil
+		}
+		finding := Finding{Message: "kubelet client certificate: " + strings.Join(info.Reasons, "; ")}
+		if info.ClientCertificate != nil {
+			finding.Path = info.ClientCertificate.Path
+		}
+		return []Finding{finding}, nil
+	}
+}
+
+// evaluateKonnectivitySocketWritable finds a konnectivity server socket which users other than root can connect to,
+// and tunnel through the agents to the nodes
+func evaluateKonnectivitySocketWritable(result json.RawMessage) ([]Finding, error) {
+	info := sensor.KonnectivityInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	if info.Server == nil || info.Server.UDSSocket == nil || len(info.Server.UDSSocketWriters) == 0 {
+		return []Finding{}, nil
+	}
+	return []Finding{{
+		Path:    info.Server.UDSSocket.Path,
+		Message: fmt.Sprintf("the konnectivity server socket %s is writable by %s", info.Server.UDSSocket.Path, strings.Join(info.Server.UDSSocketWriters, ", ")),
+	}}, nil
+}
+
+// evaluateKonnectivityCredentialsReadable finds the keys and tokens of the konnectivity agent and server, and of the
+// SSH tunnel, which are readable by other users than their owner
+func evaluateKonnectivityCredentialsReadable(result json.RawMessage) ([]Finding, error) {
+	info := sensor.KonnectivityInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	files := []*sensor.FileInfo{}
+	if info.Agent != nil {
+		files = append(files, info.Agent.ServiceAccountTokenFile, info.Agent.KeyFile)
+	}
+	if info.Server != nil {
+		files = append(files, info.Server.ServerKeyFile, info.Server.ClusterKeyFile)
+	}
+	if info.SSHTunnel != nil {
+		files = append(files, info.SSHTunnel.KeyFile)
+	}
+
+	findings := []Finding{}
+	for _, file := range files {
+		if file == nil || file.Permissions&(groupReadablePerm|worldReadablePerm) == 0 {
+			continue
+		}
+		findings = append(findings, Finding{
+			Path:    file.Path,
+			Message: fmt.Sprintf("the tunnel credentials %s are readable by other users (permissions %o)", file.Path, file.Permissions),
+		})
+	}
+	return findings, nil
+}
+
+// evaluateAPIServerSSHTunnel finds an API server which tunnels to the nodes through the legacy SSH tunnels
+func evaluateAPIServerSSHTunnel(result json.RawMessage) ([]Finding, error) {
+	info := sensor.KonnectivityInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	if info.SSHTunnel == nil {
+		return []Finding{}, nil
+	}
+	return []Finding{{Message: fmt.Sprintf("the API server tunnels to the nodes through the legacy SSH tunnels (user %q), which were removed in 1.22", info.SSHTunnel.User)}}, nil
+}
+
+// riskyServiceEvaluator returns an evaluator which finds the risky services of the kinds
+func riskyServiceEvaluator(kinds ...string) func(json.RawMessage) ([]Finding, error) {
+	return func(result json.RawMessage) ([]Finding, error) {
+		info := sensor.RiskyServicesInfo{}
+		if err := json.Unmarshal(result, &info); err != nil {
+			return nil, err
+		}
+		findings := []Finding{}
+		for _, service := range info.Services {
+			if containsString(kinds, service.Kind) {
+				findings = append(findings, Finding{Message: service.Reason})
+			}
+		}
+		return findings, nil
+	}
+}
+
+// evaluateNodeAuthorizerDisabled finds an API server which doesn't authorize the kubelets with the Node authorizer
+func evaluateNodeAuthorizerDisabled(result json.RawMessage) ([]Finding, error) {
+	info := sensor.NodeRestrictionInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	if info.APIServer == nil || info.APIServer.NodeAuthorizer {
+		return []Finding{}, nil
+	}
+	return []Finding{{Message: fmt.Sprintf("the API server authorization modes %s don't include Node, the kubelets aren't restricted to their own node", strings.Join(info.APIServer.AuthorizationModes, ","))}}, nil
+}
+
+// evaluateNodeRestrictionDisabled finds an API server which doesn't admit the kubelet writes with NodeRestriction
+func evaluateNodeRestrictionDisabled(result json.RawMessage) ([]Finding, error) {
+	info := sensor.NodeRestrictionInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	if info.APIServer == nil || info.APIServer.NodeRestriction {
+		return []Finding{}, nil
+	}
+	return []Finding{{Message: "the NodeRestriction admission plugin isn't enabled, the kubelets can modify the other nodes and their pods"}}, nil
+}
+
+// evaluateKubeletNotNodeIdentity finds a kubelet whose client certificate isn't a node identity of the node
+func evaluateKubeletNotNodeIdentity(result json.RawMessage) ([]Finding, error) {
+	info := sensor.NodeRestrictionInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	kubelet := info.Kubelet
+	if kubelet == nil || kubelet.ClientCertificate == nil {
+		return []Finding{}, nil
+	}
+	switch {
+	case !kubelet.NodeIdentity:
+		return []Finding{{
+			Path:    kubelet.ClientCertificate.Path,
+			Message: fmt.Sprintf("the kubelet client certificate %q isn't a node identity (system:node:<node name> in the system:nodes group), the Node authorizer doesn't restrict it", kubelet.ClientCertificate.Subject),
+		}}, nil
+	case kubelet.RegisteredNodeName != "" && kubelet.NodeName != kubelet.RegisteredNodeName:
+		return []Finding{{
+			Path:    kubelet.ClientCertificate.Path,
+			Message: fmt.Sprintf("the kubelet client certificate is the identity of node %q, but the kubelet registers node %q", kubelet.NodeName, kubelet.RegisteredNodeName),
+		}}, nil
+	}
+	return []Finding{}, nil
+}
+
+// diskExhaustionEvaluator returns an evaluator which finds a node of the disk exhaustion risk, with its reasons
+func diskExhaustionEvaluator(risk string) func(json.RawMessage) ([]Finding, error) {
+	return func(result json.RawMessage) ([]Finding, error) {
+		info := sensor.KubeletDiskGCInfo{}
+		if err := json.Unmarshal(result, &info); err != nil {
+			return nil, err
+		}
+		if info.DiskExhaustionRisk != risk {
+			return []Finding{}, nil
+		}
+		return []Finding{{Message: "disk exhaustion risk: " + strings.Join(info.Reasons, "; ")}}, nil
+	}
+}
+
+// evaluateContainerLogRotationMissing finds container logs which aren't rotated, and can fill the node disk
+func evaluateContainerLogRotationMissing(result json.RawMessage) ([]Finding, error) {
+	info := sensor.ContainerLogRotationInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+	findings := []Finding{}
+	if info.Docker != nil && !info.Docker.Rotated {
+		findings = append(findings, Finding{Message: fmt.Sprintf("the docker daemon writes the container logs with the %s log driver without a max-size, they grow unbounded", info.Docker.LogDriver)})
+	}
+	// the logs of cri-dockerd are rotated by docker, found above
+	if info.Kubelet != nil && !info.Kubelet.Rotated && info.Kubelet.RotatedBy == sensor.ContainerLogRotatorKubelet {
+		findings = append(findings, Finding{Message: "the kubelet doesn't rotate the container logs (container log max size 0)"})
+	}
+	return findings, nil
+}
+
+// evaluateCertificateBrokenChains finds certificates which don't chain to a CA certificate on the node
+func evaluateCertificateBrokenChains(result json.RawMessage) ([]Finding, error) {
+	certs := []sensor.NodeCertificate{}
+	if err := json.Unmarshal(result, &certs); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, cert := range certs {
+		var reason string
+		switch cert.Chain {
+		case sensor.CertificateChainUnknownIssuer:
+			reason = "its issuer isn't found"
+		case sensor.CertificateChainInvalidSignature:
+			reason = "its signature doesn't verify with its issuer's key"
+		default:
+			continue
+		}
+		findings = append(findings, Finding{
+			Path:    cert.Path + ":" + cert.SerialNumber,
+			Message: fmt.Sprintf("certificate %s (%s) issued by %s has a broken chain: %s", cert.Path, cert.Subject, cert.Issuer, reason),
+		})
+	}
+	return findings, nil
+}
+
+// evaluateCertificateUnexpectedIssuers finds kubeadm certificates which aren't issued by their expected CA,
+// e.g. an etcd server certificate issued by the cluster CA, which lets every cluster client certificate access etcd.
+func evaluateCertificateUnexpectedIssuers(result json.RawMessage) ([]Finding, error) {
+	certs := []sensor.NodeCertificate{}
+	if err := json.Unmarshal(result, &certs); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, cert := range certs {
+		var actual string
+		switch {
+		case cert.ExpectedCA == "":
+			continue
+		case cert.Chain == sensor.CertificateChainSelfSigned:
+			actual = "is self-signed"
+		case cert.Chain == sensor.CertificateChainValid && cert.ChainRoot != cert.ExpectedCA:
+			actual = "chains to " + cert.ChainRoot
+		default:
+			continue
+		}
+		findings = append(findings, Finding{
+			Path:    cert.Path + ":" + cert.SerialNumber,
+			Message: fmt.Sprintf("certificate %s (%s) %s instead of %s", cert.Path, cert.Subject, actual, cert.ExpectedCA),
+		})
+	}
+	return findings, nil
+}
+
+// evaluateKubeadmLeftoverCredentials finds backups and copies holding credentials, which outlive the rotation
+// of the live files and are often less protected
+func evaluateKubeadmLeftoverCredentials(result json.RawMessage) ([]Finding, error) {
+	artifacts := []sensor.KubeadmArtifact{}
+	if err := json.Unmarshal(result, &artifacts); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, artifact := range artifacts {
+		if artifact.File == nil || !artifact.Credentials {
+			continue
+		}
+		message := fmt.Sprintf("%s %s holds credentials (permissions %o)", artifact.Kind, artifact.File.Path, artifact.File.Permissions)
+		if live := artifact.LiveFile; live != nil && artifact.File.Permissions&^live.Permissions != 0 {
+			message += fmt.Sprintf(", more permissive than %s (permissions %o)", live.Path, live.Permissions)
+		}
+		findings = append(findings, Finding{Path: artifact.File.Path, Message: message})
+	}
+	return findings, nil
+}
+
+// evaluateTimeNotSynchronized detects nodes without a running time synchronization daemon, or whose kernel clock
+// isn't synchronized
+func evaluateTimeNotSynchronized(result json.RawMessage) ([]Finding, error) {
+	info := sensor.TimeSyncInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+
+	running := false
+	for _, daemon := range info.Daemons {
+		running = running || daemon.Running
+	}
+	switch {
+	case !running:
+		return []Finding{{Path: "clock", Message: "no time synchronization daemon (chronyd, ntpd or systemd-timesyncd) is running"}}, nil
+	case !info.Synchronized:
+		return []Finding{{Path: "clock", Message: "the kernel clock isn't synchronized"}}, nil
+	}
+	return nil, nil
+}
+
+// evaluateClockSkew detects clock offsets from the time servers beyond maxClockOffset
+func evaluateClockSkew(result json.RawMessage) ([]Finding, error) {
+	info := sensor.TimeSyncInfo{}
+	if err := json.Unmarshal(result, &info); err != nil {
+		return nil, err
+	}
+
+	if info.ClockOffset == nil {
+		return nil, nil
+	}
+	offset := time.Duration(info.ClockOffset.OffsetMillis) * time.Millisecond
+	if offset > -maxClockOffset && offset < maxClockOffset {
+		return nil, nil
+	}
+	direction := "behind"
+	if offset < 0 {
+		offset, direction = -offset, "ahead of"
+	}
+	return []Finding{{
+		Path:    "clock",
+		Message: fmt.Sprintf("the clock is %s %s time server %s", offset, direction, info.ClockOffset.Server),
+	}}, nil
+}
+
+// evaluateKernelSeccompUnsupported detects kernels without seccomp filters, on which the seccomp profiles of
+// the containers have no effect
+func evaluateKernelSeccompUnsupported(result json.RawMessage) ([]Finding, error) {
+	config := sensor.KernelConfig{}
+	if err := json.Unmarshal(result, &config); err != nil {
+		return nil, err
+	}
+
+	for _, option := range config.Options {
+		if option.Name == "CONFIG_SECCOMP_FILTER" && !option.Enabled {
+			return []Finding{{
+				Path:    config.ConfigFile,
+				Message: fmt.Sprintf("kernel %s is built without seccomp filters (CONFIG_SECCOMP_FILTER)", config.Release),
+			}}, nil
+		}
+	}
+	return nil, nil
+}
+
+// evaluateKernelHardeningDisabled detects kernels built without the memory protections of kernelHardeningOptions
+func evaluateKernelHardeningDisabled(result json.RawMessage) ([]Finding, error) {
+	config := sensor.KernelConfig{}
+	if err := json.Unmarshal(result, &config); err != nil {
+		return nil, err
+	}
+
+	disabled := []string{}
+	for _, option := range config.Options {
+		if containsString(kernelHardeningOptions, option.Name) && !option.Enabled {
+			disabled = append(disabled, option.Name)
+		}
+	}
+	if len(disabled) == 0 {
+		return nil, nil
+	}
+	return []Finding{{
+		Path:    config.ConfigFile,
+		Message: fmt.Sprintf("kernel %s is built without %s", config.Release, strings.Join(disabled, ", ")),
+	}}, nil
+}
+
+// sysctlEvaluator returns an evaluator of the credential protection sensor, which finds the sysctls of `names`
+// set to `value`. Sysctls the kernel doesn't have aren't findings.
+func sysctlEvaluator(names []string, value, impact string) func(result json.RawMessage) ([]Finding, error) {
+	return func(result json.RawMessage) ([]Finding, error) {
+		protection := sensor.CredentialProtection{}
+		if err := json.Unmarshal(result, &protection); err != nil {
+			return nil, err
+		}
+
+		findings := []Finding{}
+		for _, name := range names {
+			if actual, ok := protection.Sysctls[name]; ok && actual == value {
+				findings = append(findings, Finding{
+					Path:    name,
+					Message: fmt.Sprintf("%s is %s: %s", name, value, impact),
+				})
+			}
+		}
+		return findings, nil
+	}
+}
+
+// evaluateLowDiskSpace finds the critical paths whose file systems are almost full
+func evaluateLowDiskSpace(result json.RawMessage) ([]Finding, error) {
+	pressure := sensor.ResourcePressure{}
+	if err := json.Unmarshal(result, &pressure); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, disk := range pressure.Disks {
+		if disk.UsedPercent >= maxDiskUsedPercent {
+			findings = append(findings, Finding{
+				Path:    disk.Path,
+				Message: fmt.Sprintf("the file system of %s is %.1f%% full (%s available)", disk.Path, disk.UsedPercent, formatBytes(disk.AvailableBytes)),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// evaluateLowInodes finds the critical paths whose file systems are running out of inodes
+func evaluateLowInodes(result json.RawMessage) ([]Finding, error) {
+	pressure := sensor.ResourcePressure{}
+	if err := json.Unmarshal(result, &pressure); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, disk := range pressure.Disks {
+		if disk.TotalInodes > 0 && disk.InodesUsedPercent >= maxInodesUsedPercent {
+			findings = append(findings, Finding{
+				Path:    disk.Path,
+				Message: fmt.Sprintf("the file system of %s used %.1f%% of its inodes (%d free)", disk.Path, disk.InodesUsedPercent, disk.FreeInodes),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// evaluateMemoryPressure detects nodes which are almost out of memory, or whose tasks are stalled on memory
+func evaluateMemoryPressure(result json.RawMessage) ([]Finding, error) {
+	pressure := sensor.ResourcePressure{}
+	if err := json.Unmarshal(result, &pressure); err != nil {
+		return nil, err
+	}
+
+	memory := pressure.Memory
+	switch {
+	case memory == nil:
+	case memory.UsedPercent >= maxMemoryUsedPercent:
+		return []Finding{{
+			Path:    "memory",
+			Message: fmt.Sprintf("%.1f%% of the memory is used (%s available)", memory.UsedPercent, formatBytes(memory.AvailableBytes)),
+		}}, nil
+	case memory.PressureSome60 != nil && *memory.PressureSome60 >= maxMemoryPressure:
+		return []Finding{{
+			Path:    "memory",
+			Message: fmt.Sprintf("tasks were stalled on memory %.1f%% of the last minute", *memory.PressureSome60),
+		}}, nil
+	}
+	return nil, nil
+}
+
+// evaluateWorldAccessibleDevices finds device files which any user on the node can read or write, except the
+// devices meant for unprivileged users
+func evaluateWorldAccessibleDevices(result json.RawMessage) ([]Finding, error) {
+	inventory := sensor.DeviceInventory{}
+	if err := json.Unmarshal(result, &inventory); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, device := range inventory.Devices {
+		if device.File == nil || containsString(unprivilegedDeviceKinds, device.Kind) ||
+			device.File.Permissions&(worldReadablePerm|worldWritablePerm) == 0 {
+			continue
+		}
+		findings = append(findings, Finding{
+			Path:    device.File.Path,
+			Message: fmt.Sprintf("%s device %s is world accessible (permissions %o)", device.Kind, device.File.Path, device.File.Permissions),
+		})
+	}
+	return findings, nil
+}
+
+// evaluateDevicePluginSocketPermissions finds device plugin sockets which are group or world writable, or owned by a
+// non-root user, which enables registering rogue devices with the kubelet
+func evaluateDevicePluginSocketPermissions(result json.RawMessage) ([]Finding, error) {
+	inventory := sensor.DeviceInventory{}
+	if err := json.Unmarshal(result, &inventory); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, socket := range inventory.DevicePlugins {
+		if socket == nil {
+			continue
+		}
+		issues := []string{}
+		if socket.Permissions&(groupWritablePerm|worldWritablePerm) != 0 {
+			issues = append(issues, "writable by non-root users")
+		}
+		if socket.Ownership != nil && socket.Ownership.Err == "" && socket.Ownership.UID != 0 {
+			issues = append(issues, fmt.Sprintf("owned by uid %d", socket.Ownership.UID))
+		}
+		if len(issues) > 0 {
+			findings = append(findings, Finding{
+				Path: socket.Path,
+				Message: fmt.Sprintf("device plugin socket %s is %s (permissions %o)",
+					socket.Path, strings.Join(issues, " and "), socket.Permissions),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// evaluateWorldReadableContainerLogs finds container log files which any user on the node can read
+func evaluateWorldReadableContainerLogs(result json.RawMessage) ([]Finding, error) {
+	logs := sensor.ContainerLogsInfo{}
+	if err := json.Unmarshal(result, &logs); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, file := range logs.LogFiles {
+		if file != nil && file.Permissions&worldReadablePerm != 0 {
+			findings = append(findings, Finding{
+				Path:    file.Path,
+				Message: fmt.Sprintf("container log %s is world readable (permissions %o)", file.Path, file.Permissions),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// evaluateWorldWritableContainerLogDirs finds container log directories in which any user on the node can
+// create, replace or remove logs
+func evaluateWorldWritableContainerLogDirs(result json.RawMessage) ([]Finding, error) {
+	logs := sensor.ContainerLogsInfo{}
+	if err := json.Unmarshal(result, &logs); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, dir := range logs.Directories {
+		if dir != nil && dir.Permissions&worldWritablePerm != 0 {
+			findings = append(findings, Finding{
+				Path:    dir.Path,
+				Message: fmt.Sprintf("container log directory %s is world writable (permissions %o)", dir.Path, dir.Permissions),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// evaluateContainerLogLinksOutsidePods finds links of /var/log/containers to files outside of /var/log/pods,
+// through which reading the container logs reads other host files
+func evaluateContainerLogLinksOutsidePods(result json.RawMessage) ([]Finding, error) {
+	logs := sensor.ContainerLogsInfo{}
+	if err := json.Unmarshal(result, &logs); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, link := range logs.Links {
+		if link.Target == "" || strings.HasPrefix(link.Target, "/var/log/pods/") {
+			continue
+		}
+		findings = append(findings, Finding{
+			Path:    link.Path,
+			Message: fmt.Sprintf("container log link %s points to %s, outside of /var/log/pods", link.Path, link.Target),
+		})
+	}
+	return findings, nil
+}
+
+// evaluateWorldAccessibleImageStores finds image store directories which any user on the node can list and
+// traverse, i.e. read the images and the container file systems
+func evaluateWorldAccessibleImageStores(result json.RawMessage) ([]Finding, error) {
+	stores := []sensor.ImageStore{}
+	if err := json.Unmarshal(result, &stores); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, store := range stores {
+		// the directories are reachable only through the root
+		if store.Root == nil || store.Root.Permissions&worldSearchPerm == 0 {
+			continue
+		}
+		for _, dir := range store.Directories {
+			if dir != nil && dir.Permissions&(worldReadablePerm|worldSearchPerm) == worldReadablePerm|worldSearchPerm {
+				findings = append(findings, Finding{
+					Path:    dir.Path,
+					Message: fmt.Sprintf("%s image store directory %s is world accessible (permissions %o)", store.Runtime, dir.Path, dir.Permissions),
+				})
+			}
+		}
+	}
+	return findings, nil
+}
+
+// evaluateNonRootImageStores finds image store directories owned by a non-root user
+func evaluateNonRootImageStores(result json.RawMessage) ([]Finding, error) {
+	stores := []sensor.ImageStore{}
+	if err := json.Unmarshal(result, &stores); err != nil {
+		return nil, err
+	}
+
+	findings := []Finding{}
+	for _, store := range stores {
+		for _, dir := range append([]*sensor.FileInfo{store.Root}, store.Directories...) {
+			if dir == nil || dir.Ownership == nil || dir.Ownership.Err != "" || dir.Ownership.UID == 0 {
+				continue
+			}
+			findings = append(findings, Finding{
+				Path:    dir.Path,
+				Message: fmt.Sprintf("%s image store directory %s is owned by uid %d", store.Runtime, dir.Path, dir.Ownership.UID),
+			})
+		}
+	}
+	return findings, nil
+}
+
+// staticPodEvaluator returns an evaluator of the static pods sensor, which evaluates every pod with `evaluate`.
+// `evaluate` returns the messages of the findings of a pod.
+func staticPodEvaluator(evaluate func(pod *sensor.StaticPod) []string) func(result json.RawMessage) ([]Finding, error) {
+	return func(result json.RawMessage) ([]Finding, error) {
+		pods := []sensor.StaticPod{}
+		if err := json.Unmarshal(result, &pods); err != nil {
+			return nil, err
+		}
+
+		findings := []Finding{}
+		for i := range pods {
+			if pods[i].File == nil {
+				continue
+			}
+			for _, message := range evaluate(&pods[i]) {
+				findings = append(findings, Finding{
+					Path:    pods[i].File.Path,
+					Message: fmt.Sprintf("static pod %s (%s) %s", pods[i].Name, pods[i].File.Path, message),
+				})
+			}
+		}
+		return findings, nil
+	}
+}
+
+// evaluateStaticPodPrivileged finds the privileged containers of a static pod
+func evaluateStaticPodPrivileged(pod *sensor.StaticPod) []string {
+	messages := []string{}
+	for _, container := range pod.Containers {
+		if container.Privileged {
+			messages = append(messages, fmt.Sprintf("has the privileged container %s", container.Name))
+		}
+	}
+	return messages
+}
+
+// evaluateStaticPodHostNamespaces finds the host namespaces shared by a static pod, except by the control plane
+// pods which use the host network
+func evaluateStaticPodHostNamespaces(pod *sensor.StaticPod) []string {
+	if pod.ControlPlane {
+		return nil
+	}
+	namespaces := []string{}
+	for namespace, shared := range map[string]bool{"network": pod.HostNetwork, "pid": pod.HostPID, "ipc": pod.HostIPC} {
+		if shared {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	if len(namespaces) == 0 {
+		return nil
+	}
+	sort.Strings(namespaces)
+	return []string{fmt.Sprintf("shares the host %s namespaces", strings.Join(namespaces, " and "))}
+}
+
+// evaluateStaticPodWritableHostPaths finds the sensitive host paths mounted writable by a static pod, except by the
+// control plane pods
+func evaluateStaticPodWritableHostPaths(pod *sensor.StaticPod) []string {
+	if pod.ControlPlane {
+		return nil
+	}
+	messages := []string{}
+	for _, volume := range pod.HostPathVolumes {
+		if volume.Sensitive && !volume.ReadOnly {
+			messages = append(messages, fmt.Sprintf("mounts the host path %s writable", volume.Path))
+		}
+	}
+	return messages
+}
+
+// formatBytes formats a size in binary units, e.g. "1.5GiB"
+func formatBytes(size uint64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	value, exp := float64(size)/unit, 0
+	for value >= unit && exp < 4 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", value, "KMGTP"[exp])
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
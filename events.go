@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/armosec/host-sensor/evaluation"
+	"github.com/armosec/host-sensor/k8s"
+	"go.uber.org/zap"
+)
+
+const (
+	// Node events are recorded in the default namespace, like the kubelet does
+	nodeEventsNamespace = "default"
+	eventSourceName     = "host-sensor"
+	criticalEventReason = "CriticalFinding"
+)
+
+// nodeEventRecorder emits a Kubernetes Event on the Node object for every new critical finding
+type nodeEventRecorder struct {
+	client   *k8s.Client
+	nodeName string
+
+	// keys of the critical findings of the previous scan
+	previous map[string]bool
+}
+
+func newNodeEventRecorder(client *k8s.Client, nodeName string) *nodeEventRecorder {
+	return &nodeEventRecorder{client: client, nodeName: nodeName}
+}
+
+// onScan emits events for critical findings which were not present in the previous scan
+func (r *nodeEventRecorder) onScan(ctx context.Context, report *ScanReport) {
+	current := map[string]bool{}
+	newFindings := []evaluation.Finding{}
+	for _, finding := range report.Findings {
+		if finding.Severity != evaluation.SeverityCritical {
+			continue
+		}
+		current[finding.Key()] = true
+		if !r.previous[finding.Key()] {
+			newFindings = append(newFindings, finding)
+		}
+	}
+
+	if len(newFindings) == 0 {
+		r.previous = current
+		return
+	}
+
+	node := k8s.Node{}
+	if err := r.client.Get(ctx, k8s.NodePath(r.nodeName), &node); err != nil {
+		zap.L().Error("failed to get node for events", zap.String("node", r.nodeName), zap.Error(err))
+		return
+	}
+
+	for i := range newFindings {
+		if err := r.emit(ctx, &node, &newFindings[i]); err != nil {
+			zap.L().Error("failed to emit event", zap.String("rule", newFindings[i].RuleID), zap.Error(err))
+			// keep the finding as new, so it will be emitted next scan
+			delete(current, newFindings[i].Key())
+		}
+	}
+	r.previous = current
+}
+
+func (r *nodeEventRecorder) emit(ctx context.Context, node *k8s.Node, finding *evaluation.Finding) error {
+	now := time.Now().UTC()
+	event := k8s.Event{
+		APIVersion: "v1",
+		Kind:       "Event",
+		Metadata: k8s.ObjectMeta{
+			Name:      fmt.Sprintf("%s.%x", r.nodeName, now.UnixNano()),
+			Namespace: nodeEventsNamespace,
+		},
+		InvolvedObject: node.ObjectReference(),
+		Reason:         criticalEventReason,
+		Message:        fmt.Sprintf("[%s] %s", finding.RuleID, finding.Message),
+		Type:           k8s.EventTypeWarning,
+		Source:         k8s.EventSource{Component: eventSourceName, Host: r.nodeName},
+		FirstTimestamp: now.Format(time.RFC3339),
+		LastTimestamp:  now.Format(time.RFC3339),
+		Count:          1,
+	}
+
+	return r.client.Post(ctx, k8s.EventsPath(nodeEventsNamespace), event, nil)
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/armosec/host-sensor/evaluation"
+	"github.com/armosec/host-sensor/history"
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+)
+
+// Stream event types
+const (
+	// A value changed between two periodic scans
+	StreamEventChange = "change"
+
+	// A finding which wasn't in the previous periodic scan
+	StreamEventFinding = "finding"
+
+	// The progress of a sensor of the running periodic scan
+	StreamEventProgress = "progress"
+)
+
+const (
+	// Number of events buffered for a subscriber, further events are dropped until it catches up
+	eventStreamBuffer = 256
+
+	eventStreamMaxSubscribers = 100
+
+	// Interval of the comments keeping idle streams open through proxies
+	eventStreamHeartbeat = 30 * time.Second
+)
+
+var (
+	errEventStreamDisabled = &sensor.SenseError{
+		Massage: "events stream is disabled",
+		Kind:    "EventStreamDisabled",
+		Code:    http.StatusServiceUnavailable,
+	}
+
+	// eventsStream streams the events of the periodic scans, nil if disabled
+	eventsStream *eventStream
+)
+
+// StreamEvent is an event of the periodic scans, sent to the subscribers of the events stream
+type StreamEvent struct {
+	// Sequence number of the event, the SSE event ID
+	ID uint64 `json:"-"`
+
+	// One of StreamEvent*, the SSE event type
+	Type string `json:"-"`
+
+	// Time of the scan which produced the event
+	ScanTime time.Time `json:"scanTime"`
+
+	Change   *history.Change     `json:"change,omitempty"`
+	Finding  *evaluation.Finding `json:"finding,omitempty"`
+	Progress *sensor.Progress    `json:"progress,omitempty"`
+}
+
+// eventStream publishes the changes and the new findings of every periodic scan to its subscribers
+type eventStream struct {
+	lock        sync.Mutex
+	nextID      uint64
+	subscribers map[chan StreamEvent]bool
+	closed      bool
+
+	// the report and the finding keys of the previous scan
+	previous         json.RawMessage
+	previousFindings map[string]bool
+}
+
+func newEventStream() *eventStream {
+	return &eventStream{subscribers: map[chan StreamEvent]bool{}}
+}
+
+// onScan publishes the changes since the previous scan, and the findings which weren't in it. The file contents
+// aren't streamed, their changes are the changes of the file hashes.
+func (s *eventStream) onScan(ctx context.Context, report *ScanReport) {
+	content, err := json.Marshal(report)
+	if err == nil {
+		content, err = sensor.StripContent(content)
+	}
+	if err != nil {
+		zap.L().Error("failed to marshal scan report", zap.Error(err))
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.previous != nil {
+		changes, err := history.Diff(s.previous, content)
+		if err != nil {
+			zap.L().Error("failed to diff scan reports", zap.Error(err))
+		}
+		for i := range changes {
+			s.publish(StreamEvent{Type: StreamEventChange, ScanTime: report.Time, Change: &changes[i]})
+		}
+	}
+
+	current := map[string]bool{}
+	for i := range report.Findings {
+		current[report.Findings[i].Key()] = true
+		if !s.previousFindings[report.Findings[i].Key()] {
+			s.publish(StreamEvent{Type: StreamEventFinding, ScanTime: report.Time, Finding: &report.Findings[i]})
+		}
+	}
+	s.previous, s.previousFindings = content, current
+}
+
+// onProgress returns a reporter publishing the progress of the sensors of the scan started at `scanTime`
+func (s *eventStream) onProgress(scanTime time.Time) func(sensor.Progress) {
+	return func(progress sensor.Progress) {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		s.publish(StreamEvent{Type: StreamEventProgress, ScanTime: scanTime, Progress: &progress})
+	}
+}
+
+// publish sends the event to the subscribers, without blocking on slow ones. The lock must be held.
+func (s *eventStream) publish(event StreamEvent) {
+	s.nextID++
+	event.ID = s.nextID
+	for subscriber := range s.subscribers {
+		select {
+		case subscriber <- event:
+		default:
+			zap.L().Debug("events stream subscriber is full, event dropped", zap.Uint64("id", event.ID))
+		}
+	}
+}
+
+// subscribe returns a channel of the published events
+func (s *eventStream) subscribe() (chan StreamEvent, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.closed {
+		return nil, &sensor.SenseError{Massage: "events stream is closed", Code: http.StatusServiceUnavailable}
+	}
+	if len(s.subscribers) >= eventStreamMaxSubscribers {
+		return nil, &sensor.SenseError{Massage: "too many events stream subscribers", Code: http.StatusServiceUnavailable}
+	}
+	subscriber := make(chan StreamEvent, eventStreamBuffer)
+	s.subscribers[subscriber] = true
+	return subscriber, nil
+}
+
+func (s *eventStream) unsubscribe(subscriber chan StreamEvent) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.subscribers, subscriber)
+}
+
+// close ends the streams of all the subscribers, on shutdown
+func (s *eventStream) close() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.closed = true
+	for subscriber := range s.subscribers {
+		close(subscriber)
+		delete(s.subscribers, subscriber)
+	}
+}
+
+// eventsHandler streams the events of the periodic scans as Server-Sent Events, until the client disconnects
+func eventsHandler(rw http.ResponseWriter, r *http.Request) {
+	if eventsStream == nil {
+		writeSenseError(rw, errEventStreamDisabled, "events")
+		return
+	}
+	flusher, ok := rw.(http.Flusher)
+	if !ok {
+		writeSenseError(rw, fmt.Errorf("streaming is not supported"), "events")
+		return
+	}
+	subscriber, err := eventsStream.subscribe()
+	if err != nil {
+		writeSenseError(rw, err, "events")
+		return
+	}
+	defer eventsStream.unsubscribe(subscriber)
+
+	rw.Header().Set("Content-Type", "text/event-stream")
+	rw.Header().Set("Cache-Control", "no-cache")
+	rw.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventStreamHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(rw, ": heartbeat\n\n")
+		case event, ok := <-subscriber:
+			if !ok {
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				zap.L().Error("failed to marshal stream event", zap.Error(err))
+				continue
+			}
+			fmt.Fprintf(rw, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		}
+		flusher.Flush()
+	}
+}
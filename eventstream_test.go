@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/armosec/host-sensor/evaluation"
+	"github.com/armosec/host-sensor/history"
+	"github.com/armosec/host-sensor/sensor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventStream(t *testing.T) {
+	stream := newEventStream()
+	subscriber, err := stream.subscribe()
+	require.NoError(t, err)
+
+	finding := evaluation.Finding{RuleID: "rule", Sensor: "test", Message: "message"}
+	stream.onScan(context.Background(), &ScanReport{
+		Results:  map[string]json.RawMessage{"test": json.RawMessage(`{"value": 1}`)},
+		Findings: []evaluation.Finding{finding},
+	})
+	event := <-subscriber
+	assert.Equal(t, StreamEventFinding, event.Type)
+	assert.Equal(t, uint64(1), event.ID)
+	assert.Equal(t, finding, *event.Finding)
+
+	// the finding isn't new, the value changed
+	stream.onScan(context.Background(), &ScanReport{
+		Results:  map[string]json.RawMessage{"test": json.RawMessage(`{"value": 2}`)},
+		Findings: []evaluation.Finding{finding},
+	})
+	event = <-subscriber
+	assert.Equal(t, StreamEventChange, event.Type)
+	assert.Equal(t, uint64(2), event.ID)
+	assert.Equal(t, "results.test.value", event.Change.Path)
+	assert.Equal(t, history.ChangeChanged, event.Change.Type)
+	assert.Empty(t, subscriber)
+
+	scanTime := time.Now().UTC()
+	stream.onProgress(scanTime)(sensor.Progress{Sensor: "packages", Processed: 10, Total: 20})
+	event = <-subscriber
+	assert.Equal(t, StreamEventProgress, event.Type)
+	assert.Equal(t, scanTime, event.ScanTime)
+	assert.Equal(t, 10, event.Progress.Processed)
+
+	stream.unsubscribe(subscriber)
+	assert.Empty(t, stream.subscribers)
+}
+
+func TestEventStreamStripsContent(t *testing.T) {
+	stream := newEventStream()
+	subscriber, err := stream.subscribe()
+	require.NoError(t, err)
+
+	stream.onScan(context.Background(), &ScanReport{
+		Results: map[string]json.RawMessage{"test": json.RawMessage(`[{"path": "/etc/foo", "sha256": "1"}]`)},
+	})
+	stream.onScan(context.Background(), &ScanReport{
+		Results: map[string]json.RawMessage{"test": json.RawMessage(`[{"path": "/etc/foo", "sha256": "2"}, {"path": "/etc/bar", "content": "c2VjcmV0"}]`)},
+	})
+	events := []StreamEvent{}
+	for len(subscriber) > 0 {
+		events = append(events, <-subscriber)
+	}
+	require.NotEmpty(t, events)
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "c2VjcmV0")
+	}
+}
+
+func TestEventsHandler(t *testing.T) {
+	defer func() { eventsStream = nil }()
+
+	rw := httptest.NewRecorder()
+	eventsHandler(rw, httptest.NewRequest(http.MethodGet, "/events", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, rw.Code)
+
+	eventsStream = newEventStream()
+	server := httptest.NewServer(http.HandlerFunc(eventsHandler))
+	defer server.Close()
+	res, err := http.Get(server.URL)
+	require.NoError(t, err)
+	defer res.Body.Close()
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	require.Eventually(t, func() bool {
+		eventsStream.lock.Lock()
+		defer eventsStream.lock.Unlock()
+		return len(eventsStream.subscribers) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	eventsStream.onScan(context.Background(), &ScanReport{Findings: []evaluation.Finding{{RuleID: "rule"}}})
+
+	reader := bufio.NewReader(res.Body)
+	lines := []string{}
+	for len(lines) < 3 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		lines = append(lines, strings.TrimSuffix(line, "\n"))
+	}
+	assert.Equal(t, "id: 1", lines[0])
+	assert.Equal(t, "event: finding", lines[1])
+	assert.True(t, strings.HasPrefix(lines[2], "data: {"))
+	assert.Contains(t, lines[2], `"ruleID":"rule"`)
+}
+
+func TestEventStreamClose(t *testing.T) {
+	stream := newEventStream()
+	subscriber, err := stream.subscribe()
+	require.NoError(t, err)
+
+	stream.close()
+	_, ok := <-subscriber
+	assert.False(t, ok)
+	_, err = stream.subscribe()
+	assert.Error(t, err)
+}
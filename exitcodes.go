@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/armosec/host-sensor/sensor"
+)
+
+// Process exit codes, so automation can branch on the failure cause
+const (
+	exitCodeOK               = 0
+	exitCodeGeneralError     = 1
+	exitCodeNotControlPlane  = 3
+	exitCodePermissionDenied = 4
+	exitCodeSensorDisabled   = 5
+	exitCodeHostRootMissing  = 6
+)
+
+// exitCode maps an error to the process exit code
+func exitCode(err error) int {
+	switch {
+	case err == nil:
+		return exitCodeOK
+	case errors.Is(err, sensor.ErrNotControlPlane):
+		return exitCodeNotControlPlane
+	case errors.Is(err, sensor.ErrPermissionDenied):
+		return exitCodePermissionDenied
+	case errors.Is(err, sensor.ErrSensorDisabled):
+		return exitCodeSensorDisabled
+	case errors.Is(err, sensor.ErrHostRootMissing):
+		return exitCodeHostRootMissing
+	default:
+		return exitCodeGeneralError
+	}
+}
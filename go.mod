@@ -4,25 +4,28 @@ go 1.18
 
 require (
 	github.com/BurntSushi/toml v1.2.0
+	github.com/cilium/ebpf v0.9.3
 	github.com/codegangsta/negroni v1.0.0
 	github.com/coreos/go-systemd/v22 v22.4.0
 	github.com/godbus/dbus/v5 v5.1.0
 	github.com/weaveworks/procspy v0.0.0-20150706124340-cb970aa190c3
+	go.etcd.io/bbolt v1.3.7
 	go.uber.org/zap v1.19.1
+	golang.org/x/sys v0.4.0
+	gopkg.in/yaml.v3 v3.0.1
 	sigs.k8s.io/yaml v1.3.0
 )
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
 require (
 	github.com/kr/text v0.2.0 // indirect
 	github.com/niemeyer/pretty v0.0.0-20200227124842-a10e7caefd8e // indirect
 	github.com/pkg/errors v0.9.1 // indirect
-	github.com/stretchr/testify v1.8.0
+	github.com/stretchr/testify v1.8.1
 	go.uber.org/atomic v1.7.0 // indirect
 	go.uber.org/multierr v1.6.0 // indirect
 	gopkg.in/check.v1 v1.0.0-20200227125254-8fa46927fb4f // indirect
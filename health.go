@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// This file contains the health of the sensors, computed from their consecutive failures and served at /healthz.
+
+// The health states, from the best to the worst
+const (
+	healthOK       = "ok"
+	healthDegraded = "degraded"
+	healthFailing  = "failing"
+)
+
+var healthStates = []string{healthOK, healthDegraded, healthFailing}
+
+// SensorHealth is the health of a sensor
+type SensorHealth struct {
+	Status string `json:"status"`
+
+	// Number of the sensor runs which failed in a row
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+
+	// HTTP status code of the error of the latest run, if it failed
+	LastErrorCode int `json:"lastErrorCode,omitempty"`
+}
+
+// Health is the health of the sensor, its status is the worst status of the sensors
+type Health struct {
+	Status  string                   `json:"status"`
+	Sensors map[string]*SensorHealth `json:"sensors"`
+}
+
+// isSensorFailure returns true if a sensor run responding with `status` failed. The client errors (e.g. not a
+// control plane node, or a missing privilege) are the state of the node, not a failure of the sensor.
+func isSensorFailure(status int) bool {
+	return status >= http.StatusInternalServerError
+}
+
+// healthState returns the health state of a sensor which failed `failures` times in a row
+func healthState(failures int, conf *Config) string {
+	switch {
+	case failures >= conf.HealthFailingThreshold:
+		return healthFailing
+	case failures >= conf.HealthDegradedThreshold:
+		return healthDegraded
+	default:
+		return healthOK
+	}
+}
+
+// logHealthChange logs the change of the health state of a sensor
+func logHealthChange(sensorName, previous, current string, failures int) {
+	if previous == current {
+		return
+	}
+	fields := []zap.Field{zap.String("sensor", sensorName), zap.String("previous", previous), zap.String("status", current),
+		zap.Int("consecutiveFailures", failures)}
+	if current == healthOK {
+		zap.L().Info("sensor recovered", fields...)
+		return
+	}
+	zap.L().Warn("sensor health changed", fields...)
+}
+
+// health returns the health of the observed sensors
+func (m *metricsRegistry) health() *Health {
+	conf := getConfig()
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	ret := &Health{Status: healthOK, Sensors: map[string]*SensorHealth{}}
+	names := make([]string, 0, len(m.sensors))
+	for name := range m.sensors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		counters := m.sensors[name]
+		health := &SensorHealth{
+			Status:              healthState(counters.consecutiveFailures, conf),
+			ConsecutiveFailures: counters.consecutiveFailures,
+			LastErrorCode:       counters.lastErrorCode,
+		}
+		ret.Sensors[name] = health
+		if healthRank(health.Status) > healthRank(ret.Status) {
+			ret.Status = health.Status
+		}
+	}
+	return ret
+}
+
+func healthRank(state string) int {
+	for i, s := range healthStates {
+		if s == state {
+			return i
+		}
+	}
+	return 0
+}
+
+// healthzHandler responds with the health of the sensors. It responds with 200 even when sensors fail, since
+// restarting the sensor won't fix the node.
+func healthzHandler(rw http.ResponseWriter, r *http.Request) {
+	GenericSensorHandler(rw, r, sensorMetrics.health(), nil, "healthz")
+}
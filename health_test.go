@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/armosec/host-sensor/sensor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealth(t *testing.T) {
+	defer func(registry *metricsRegistry) { sensorMetrics = registry }(sensorMetrics)
+	sensorMetrics = newMetricsRegistry()
+	defer setConfig(getConfig())
+	conf := defaultConfig()
+	conf.HealthFailingThreshold = 2
+	setConfig(conf)
+
+	health := func() *Health {
+		rw := httptest.NewRecorder()
+		healthzHandler(rw, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		require.Equal(t, http.StatusOK, rw.Code)
+		ret := &Health{}
+		require.NoError(t, json.NewDecoder(rw.Body).Decode(ret))
+		return ret
+	}
+	assert.Equal(t, healthOK, health().Status)
+
+	sensorMetrics.observe("kubeletInfo", &sensor.SenseMetrics{}, http.StatusOK)
+	sensorMetrics.observe("controlPlaneInfo", &sensor.SenseMetrics{}, http.StatusNotFound)
+	sensorMetrics.observe("packages", &sensor.SenseMetrics{}, http.StatusInternalServerError)
+	current := health()
+	assert.Equal(t, healthDegraded, current.Status)
+	assert.Equal(t, healthOK, current.Sensors["controlPlaneInfo"].Status)
+	assert.Equal(t, &SensorHealth{Status: healthDegraded, ConsecutiveFailures: 1, LastErrorCode: 500}, current.Sensors["packages"])
+
+	sensorMetrics.observe("packages", &sensor.SenseMetrics{}, http.StatusInternalServerError)
+	assert.Equal(t, healthFailing, health().Status)
+	rw := httptest.NewRecorder()
+	metricsHandler(rw, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Contains(t, rw.Body.String(), `host_sensor_sensor_health{sensor="packages",state="failing"} 1`+"\n")
+	assert.Contains(t, rw.Body.String(), `host_sensor_sensor_consecutive_failures{sensor="packages"} 2`+"\n")
+
+	sensorMetrics.observe("packages", &sensor.SenseMetrics{}, http.StatusOK)
+	assert.Equal(t, healthOK, health().Status)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/armosec/host-sensor/push"
+	"go.uber.org/zap"
+)
+
+// Heartbeat is pushed to the collector between the scan reports, so the fleet view can tell a node whose reports
+// are unchanged from a dead sensor
+type Heartbeat struct {
+	NodeName string `json:"nodeName,omitempty"`
+	PodName  string `json:"podName,omitempty"`
+	Version  string `json:"version"`
+	Time     string `json:"time"`
+
+	// Time the sensor started
+	StartTime string `json:"startTime"`
+
+	// Time of the latest completed periodic scan, empty if there was none
+	LastScanTime string `json:"lastScanTime,omitempty"`
+}
+
+// startTime is the time the sensor started
+var startTime = time.Now()
+
+// newHeartbeat returns the current heartbeat of the sensor
+func newHeartbeat() *Heartbeat {
+	conf := getConfig()
+	heartbeat := &Heartbeat{
+		NodeName:  conf.Identity.NodeName,
+		PodName:   conf.Identity.PodName,
+		Version:   buildVersion,
+		Time:      time.Now().UTC().Format(time.RFC3339),
+		StartTime: startTime.UTC().Format(time.RFC3339),
+	}
+	if scheduler != nil {
+		if report := scheduler.latestReport(); report != nil {
+			heartbeat.LastScanTime = report.Time.UTC().Format(time.RFC3339)
+		}
+	}
+	return heartbeat
+}
+
+// sendHeartbeats pushes a heartbeat on start and every interval, until `ctx` is done
+func sendHeartbeats(ctx context.Context, pusher *push.Pusher, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := pusher.Heartbeat(ctx, newHeartbeat()); err != nil && ctx.Err() == nil {
+			zap.L().Warn("failed to push heartbeat", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
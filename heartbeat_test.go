@@ -0,0 +1,46 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/armosec/host-sensor/push"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeartbeats(t *testing.T) {
+	defer setConfig(getConfig())
+	conf := defaultConfig()
+	conf.Identity.NodeName = "node-1"
+	setConfig(conf)
+
+	scanTime := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	scheduler = newScanScheduler(time.Hour, 0)
+	scheduler.latest = &ScanReport{Time: scanTime}
+	defer func() { scheduler = nil }()
+
+	heartbeats := make(chan Heartbeat, 10)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, push.MessageHeartbeat, r.Header.Get(push.MessageHeader))
+		heartbeat := Heartbeat{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&heartbeat))
+		heartbeats <- heartbeat
+	}))
+	defer collector.Close()
+	pusher, err := push.New(push.Options{URL: collector.URL})
+	require.NoError(t, err)
+
+	pushes := startPusher(pusher, 10*time.Millisecond)
+	first, second := <-heartbeats, <-heartbeats
+	pushes.cancel()
+	pushes.done.Wait()
+
+	assert.Equal(t, "node-1", first.NodeName)
+	assert.Equal(t, buildVersion, first.Version)
+	assert.Equal(t, scanTime.Format(time.RFC3339), first.LastScanTime)
+	assert.Equal(t, first.StartTime, second.StartTime)
+}
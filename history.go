@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/armosec/host-sensor/history"
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+)
+
+var (
+	errHistoryDisabled = &sensor.SenseError{
+		Massage: "scans history is disabled",
+		Kind:    "HistoryDisabled",
+		Code:    http.StatusServiceUnavailable,
+	}
+	errScanNotFound = &sensor.SenseError{
+		Massage: "scan not found",
+		Kind:    "ScanNotFound",
+		Code:    http.StatusNotFound,
+	}
+
+	// historyStore keeps the latest scans, nil if history is disabled
+	historyStore *history.Store
+)
+
+// ScanDiff holds the differences between two scans
+type ScanDiff struct {
+	From    uint64           `json:"from"`
+	To      uint64           `json:"to"`
+	Changes []history.Change `json:"changes"`
+}
+
+// newHistoryScanHandler returns a scan handler which saves every scan in the store
+func newHistoryScanHandler(store *history.Store) scanHandler {
+	return func(ctx context.Context, report *ScanReport) {
+		content, err := json.Marshal(report)
+		if err != nil {
+			zap.L().Error("failed to marshal scan report", zap.Error(err))
+			return
+		}
+		if _, err := store.Save(report.Time, content); err != nil {
+			zap.L().Error("failed to save scan report", zap.Error(err))
+		}
+	}
+}
+
+// historyHandler lists the stored scans, oldest first
+func historyHandler(rw http.ResponseWriter, r *http.Request) {
+	if historyStore == nil {
+		writeSenseError(rw, errHistoryDisabled, "history")
+		return
+	}
+	entries, err := historyStore.List()
+	GenericSensorHandler(rw, r, entries, err, "history")
+}
+
+// diffHandler responds with the differences between the scans `from` and `to`.
+// By default, the two latest scans are compared.
+func diffHandler(rw http.ResponseWriter, r *http.Request) {
+	if historyStore == nil {
+		writeSenseError(rw, errHistoryDisabled, "diff")
+		return
+	}
+	diff, err := diffScans(historyStore, r.URL.Query().Get("from"), r.URL.Query().Get("to"))
+	GenericSensorHandler(rw, r, diff, err, "diff")
+}
+
+func diffScans(store *history.Store, fromParam, toParam string) (*ScanDiff, error) {
+	entries, err := store.List()
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ScanDiff{}
+	if diff.To, err = parseScanID("to", toParam, entries, 1); err != nil {
+		return nil, err
+	}
+	if diff.From, err = parseScanID("from", fromParam, entries, 2); err != nil {
+		return nil, err
+	}
+
+	from, err := getScan(store, diff.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := getScan(store, diff.To)
+	if err != nil {
+		return nil, err
+	}
+
+	if diff.Changes, err = history.Diff(from, to); err != nil {
+		return nil, err
+	}
+	return diff, nil
+}
+
+// parseScanID parses a scan ID parameter, an empty parameter is the scan `defaultFromLast` places from the last
+func parseScanID(name, param string, entries []history.Entry, defaultFromLast int) (uint64, error) {
+	if param == "" {
+		if len(entries) < defaultFromLast {
+			return 0, &sensor.SenseError{
+				Massage: fmt.Sprintf("not enough scans to compare (%d)", len(entries)),
+				Kind:    errScanNotFound.Kind,
+				Code:    errScanNotFound.Code,
+			}
+		}
+		return entries[len(entries)-defaultFromLast].ID, nil
+	}
+
+	id, err := strconv.ParseUint(param, 10, 64)
+	if err != nil {
+		return 0, &sensor.SenseError{
+			Massage: fmt.Sprintf("invalid %s scan ID %q", name, param),
+			Code:    http.StatusBadRequest,
+		}
+	}
+	return id, nil
+}
+
+func getScan(store *history.Store, id uint64) (json.RawMessage, error) {
+	report, err := store.Get(id)
+	if errors.Is(err, history.ErrNotFound) {
+		return nil, &sensor.SenseError{
+			Massage: fmt.Sprintf("scan %d not found", id),
+			Kind:    errScanNotFound.Kind,
+			Code:    errScanNotFound.Code,
+		}
+	}
+	return report, err
+}
@@ -0,0 +1,172 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Change types
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// Change is a structural difference between two scans
+type Change struct {
+	// Path of the changed value, e.g. `results.controlPlaneInfo.PKIFiles[/etc/kubernetes/pki/ca.key].permissions`
+	Path string `json:"path"`
+
+	// One of the Change* types
+	Type string `json:"type"`
+
+	Old interface{} `json:"old,omitempty"`
+	New interface{} `json:"new,omitempty"`
+}
+
+// ignoredFields are not compared, since they differ between any two scans
+var ignoredFields = map[string]bool{"time": true}
+
+// elementKeyFields identify the elements of arrays of objects, e.g. files by their path
+var elementKeyFields = []string{"ruleID", "path", "key"}
+
+// cmdLineFields are command lines, compared by their flags
+var cmdLineFields = map[string]bool{"cmdLine": true}
+
+// contentFields hold file contents, their values are not included in the changes
+var contentFields = map[string]bool{"content": true}
+
+// Diff returns the structural differences between two scan reports, sorted by path.
+// Array elements are matched by their identifying field (e.g. file path), or by their value,
+// and command lines are compared flag by flag.
+func Diff(oldReport, newReport json.RawMessage) ([]Change, error) {
+	var oldValue, newValue interface{}
+	if err := json.Unmarshal(oldReport, &oldValue); err != nil {
+		return nil, fmt.Errorf("failed to parse old report: %w", err)
+	}
+	if err := json.Unmarshal(newReport, &newValue); err != nil {
+		return nil, fmt.Errorf("failed to parse new report: %w", err)
+	}
+
+	changes := []Change{}
+	diffValues("", "", oldValue, newValue, &changes)
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func diffValues(path, field string, oldValue, newValue interface{}, changes *[]Change) {
+	if reflect.DeepEqual(oldValue, newValue) {
+		return
+	}
+
+	switch {
+	case oldValue == nil:
+		*changes = append(*changes, Change{Path: path, Type: ChangeAdded, New: changeValue(field, newValue)})
+		return
+	case newValue == nil:
+		*changes = append(*changes, Change{Path: path, Type: ChangeRemoved, Old: changeValue(field, oldValue)})
+		return
+	}
+
+	switch oldTyped := oldValue.(type) {
+	case map[string]interface{}:
+		if newTyped, ok := newValue.(map[string]interface{}); ok {
+			diffObjects(path, oldTyped, newTyped, changes)
+			return
+		}
+	case []interface{}:
+		if newTyped, ok := newValue.([]interface{}); ok {
+			diffObjects(path, arrayToObject(oldTyped), arrayToObject(newTyped), changes)
+			return
+		}
+	case string:
+		if newTyped, ok := newValue.(string); ok && cmdLineFields[field] {
+			diffObjects(path, cmdLineToObject(oldTyped), cmdLineToObject(newTyped), changes)
+			return
+		}
+	}
+
+	*changes = append(*changes, Change{
+		Path: path,
+		Type: ChangeChanged,
+		Old:  changeValue(field, oldValue),
+		New:  changeValue(field, newValue),
+	})
+}
+
+func diffObjects(path string, oldObject, newObject map[string]interface{}, changes *[]Change) {
+	for field, oldValue := range oldObject {
+		if ignoredFields[field] {
+			continue
+		}
+		diffValues(joinPath(path, field), field, oldValue, newObject[field], changes)
+	}
+	for field, newValue := range newObject {
+		if _, ok := oldObject[field]; ok || ignoredFields[field] {
+			continue
+		}
+		diffValues(joinPath(path, field), field, nil, newValue, changes)
+	}
+}
+
+// joinPath appends a field to the path, array elements (keys in brackets) are appended as is
+func joinPath(path, field string) string {
+	if path == "" || strings.HasPrefix(field, "[") {
+		return path + field
+	}
+	return path + "." + field
+}
+
+// arrayToObject keys the array elements by their identifying fields, or by their value
+func arrayToObject(array []interface{}) map[string]interface{} {
+	ret := make(map[string]interface{}, len(array))
+	for _, element := range array {
+		ret["["+elementKey(element)+"]"] = element
+	}
+	return ret
+}
+
+func elementKey(element interface{}) string {
+	if object, ok := element.(map[string]interface{}); ok {
+		keys := []string{}
+		for _, field := range elementKeyFields {
+			if val, ok := object[field].(string); ok && val != "" {
+				keys = append(keys, val)
+			}
+		}
+		if len(keys) > 0 {
+			return strings.Join(keys, ",")
+		}
+	}
+	// encoding/json sorts object keys, so equal values have equal keys
+	content, _ := json.Marshal(element)
+	return string(content)
+}
+
+// cmdLineToObject keys the command line arguments by flag name, arguments which are not flags are keyed by value
+func cmdLineToObject(cmdLine string) map[string]interface{} {
+	ret := map[string]interface{}{}
+	for _, arg := range strings.Fields(cmdLine) {
+		if !strings.HasPrefix(arg, "-") {
+			ret["["+arg+"]"] = arg
+			continue
+		}
+		name, value := arg, ""
+		if i := strings.Index(arg, "="); i >= 0 {
+			name, value = arg[:i], arg[i+1:]
+		}
+		ret["["+name+"]"] = value
+	}
+	return ret
+}
+
+// changeValue omits file contents from the changes
+func changeValue(field string, value interface{}) interface{} {
+	if contentFields[field] {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,61 @@
+package history
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"), 2)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i := 0; i < 3; i++ {
+		_, err := store.Save(now.Add(time.Duration(i)*time.Minute), json.RawMessage(`{"scan":`+string(rune('0'+i))+`}`))
+		assert.NoError(t, err)
+	}
+
+	entries, err := store.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{{ID: 2, Time: now.Add(time.Minute)}, {ID: 3, Time: now.Add(2 * time.Minute)}}, entries)
+
+	report, err := store.Get(3)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"scan":2}`, string(report))
+
+	_, err = store.Get(1)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestDiff(t *testing.T) {
+	oldReport := `{
+		"time": "2022-01-01T00:00:00Z",
+		"results": {
+			"kubeletInfo": {"cmdLine": "/usr/bin/kubelet --anonymous-auth=false --v=2", "configFile": {"path": "/var/lib/kubelet/config.yaml", "permissions": 384, "content": "YQ=="}},
+			"controlPlaneInfo": {"PKIFiles": [{"path": "/etc/kubernetes/pki/ca.key", "permissions": 384}]}
+		}
+	}`
+	newReport := `{
+		"time": "2022-01-02T00:00:00Z",
+		"results": {
+			"kubeletInfo": {"cmdLine": "/usr/bin/kubelet --anonymous-auth=true --rotate-certificates", "configFile": {"path": "/var/lib/kubelet/config.yaml", "permissions": 420, "content": "Yg=="}},
+			"controlPlaneInfo": {"PKIFiles": [{"path": "/etc/kubernetes/pki/ca.key", "permissions": 384}, {"path": "/etc/kubernetes/pki/sa.key", "permissions": 420}]}
+		}
+	}`
+
+	changes, err := Diff(json.RawMessage(oldReport), json.RawMessage(newReport))
+	assert.NoError(t, err)
+	assert.Equal(t, []Change{
+		{Path: "results.controlPlaneInfo.PKIFiles[/etc/kubernetes/pki/sa.key]", Type: ChangeAdded, New: map[string]interface{}{"path": "/etc/kubernetes/pki/sa.key", "permissions": float64(420)}},
+		{Path: "results.kubeletInfo.cmdLine[--anonymous-auth]", Type: ChangeChanged, Old: "false", New: "true"},
+		{Path: "results.kubeletInfo.cmdLine[--rotate-certificates]", Type: ChangeAdded, New: ""},
+		{Path: "results.kubeletInfo.cmdLine[--v]", Type: ChangeRemoved, Old: "2"},
+		{Path: "results.kubeletInfo.configFile.content", Type: ChangeChanged},
+		{Path: "results.kubeletInfo.configFile.permissions", Type: ChangeChanged, Old: float64(384), New: float64(420)},
+	}, changes)
+}
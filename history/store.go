@@ -0,0 +1,146 @@
+package history
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var scansBucket = []byte("scans")
+
+// ErrNotFound is returned when a scan is not in the store
+var ErrNotFound = errors.New("scan not found")
+
+// Store persists the latest scans in an embedded bbolt database
+type Store struct {
+	db *bolt.DB
+
+	// maximal number of scans to keep, the oldest are deleted
+	maxScans int
+}
+
+// Entry describes a stored scan
+type Entry struct {
+	ID   uint64    `json:"id"`
+	Time time.Time `json:"time"`
+}
+
+// record is the stored form of a scan
+type record struct {
+	Time   time.Time       `json:"time"`
+	Report json.RawMessage `json:"report"`
+}
+
+// Open opens (or creates) the store at `path`, keeping up to `maxScans` scans
+func Open(path string, maxScans int) (*Store, error) {
+	if maxScans <= 0 {
+		return nil, fmt.Errorf("invalid max scans %d: must be positive", maxScans)
+	}
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history store: %w", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(scansBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize history store: %w", err)
+	}
+	return &Store{db: db, maxScans: maxScans}, nil
+}
+
+// Close closes the store
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Save stores the report of a scan made at `scanTime`, deleting the oldest scans above the limit
+func (s *Store) Save(scanTime time.Time, report json.RawMessage) (uint64, error) {
+	value, err := json.Marshal(record{Time: scanTime, Report: report})
+	if err != nil {
+		return 0, err
+	}
+
+	var id uint64
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(scansBucket)
+		if id, err = bucket.NextSequence(); err != nil {
+			return err
+		}
+		if err := bucket.Put(idKey(id), value); err != nil {
+			return err
+		}
+
+		keys := [][]byte{}
+		if err := bucket.ForEach(func(k, v []byte) error {
+			keys = append(keys, append([]byte{}, k...))
+			return nil
+		}); err != nil {
+			return err
+		}
+
+		// keys are ordered, so the oldest scans are first
+		for i := 0; i < len(keys)-s.maxScans; i++ {
+			if err := bucket.Delete(keys[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to save scan: %w", err)
+	}
+	return id, nil
+}
+
+// List returns the stored scans, oldest first
+func (s *Store) List() ([]Entry, error) {
+	entries := []Entry{}
+	err := s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(scansBucket).ForEach(func(k, v []byte) error {
+			rec := record{}
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return err
+			}
+			entries = append(entries, Entry{ID: binary.BigEndian.Uint64(k), Time: rec.Time})
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list scans: %w", err)
+	}
+	return entries, nil
+}
+
+// Get returns the report of the scan `id`
+func (s *Store) Get(id uint64) (json.RawMessage, error) {
+	var report json.RawMessage
+	err := s.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(scansBucket).Get(idKey(id))
+		if value == nil {
+			return ErrNotFound
+		}
+		rec := record{}
+		if err := json.Unmarshal(value, &rec); err != nil {
+			return err
+		}
+		report = rec.Report
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+func idKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/armosec/host-sensor/sensor"
 	"go.uber.org/zap"
@@ -12,114 +15,589 @@ import (
 
 func initHTTPHandlers() {
 	// TODO: implement probe endpoint
-	http.HandleFunc("/kubeletConfigurations", func(rw http.ResponseWriter, r *http.Request) {
-		conf, err := sensor.SenseKubeletConfigurations()
+	http.HandleFunc("/kubeletConfigurations", withSensorEnabled("kubeletConfigurations", kubeletConfigurationsHandler))
+	http.HandleFunc("/kubeletCommandLine", withSensorEnabled("kubeletCommandLine", kubeletCommandLineHandler))
+	http.HandleFunc("/osRelease", withSensorEnabled("osRelease", osReleaseHandler))
+	http.HandleFunc("/kernelVersion", withSensorEnabled("kernelVersion", kernelVersionHandler))
+	http.HandleFunc("/linuxSecurityHardening", withSensorEnabled("linuxSecurityHardening", linuxSecurityHardeningHandler))
+	http.HandleFunc("/openedPorts", withSensorEnabled("openedPorts", openedPortsHandler))
+	http.HandleFunc("/LinuxKernelVariables", withSensorEnabled("LinuxKernelVariables", LinuxKernelVariablesHandler))
+	http.HandleFunc("/kubeletInfo", withSensorEnabled("kubeletInfo", kubeletInfoHandler))
+	http.HandleFunc("/kubeProxyInfo", withSensorEnabled("kubeProxyInfo", kubeProxyHandler))
+	http.HandleFunc("/controlPlaneInfo", withSensorEnabled("controlPlaneInfo", controlPlaneHandler))
+	http.HandleFunc("/escapeSurface", withSensorEnabled("escapeSurface", escapeSurfaceHandler))
+	http.HandleFunc("/kubeconfigs", withSensorEnabled("kubeconfigs", kubeconfigsHandler))
+	http.HandleFunc("/privateKeys", withSensorEnabled("privateKeys", privateKeysHandler))
+	http.HandleFunc("/tokens", withSensorEnabled("tokens", tokensHandler))
+	http.HandleFunc("/registryCredentials", withSensorEnabled("registryCredentials", registryCredentialsHandler))
+	http.HandleFunc("/certificates", withSensorEnabled("certificates", certificatesHandler))
+	http.HandleFunc("/tlsConfigs", withSensorEnabled("tlsConfigs", tlsConfigsHandler))
+	http.HandleFunc("/etcdEncryption", withSensorEnabled("etcdEncryption", etcdEncryptionHandler))
+	http.HandleFunc("/kubeadmArtifacts", withSensorEnabled("kubeadmArtifacts", kubeadmArtifactsHandler))
+	http.HandleFunc("/systemdUnits", withSensorEnabled("systemdUnits", systemdUnitsHandler))
+	http.HandleFunc("/timeSync", withSensorEnabled("timeSync", timeSyncHandler))
+	http.HandleFunc("/packages", withSensorEnabled("packages", packagesHandler))
+	http.HandleFunc("/kernelConfig", withSensorEnabled("kernelConfig", kernelConfigHandler))
+	http.HandleFunc("/resourcePressure", withSensorEnabled("resourcePressure", resourcePressureHandler))
+	http.HandleFunc("/devices", withSensorEnabled("devices", devicesHandler))
+	http.HandleFunc("/containerLogs", withSensorEnabled("containerLogs", containerLogsHandler))
+	http.HandleFunc("/imageStores", withSensorEnabled("imageStores", imageStoresHandler))
+	http.HandleFunc("/staticPods", withSensorEnabled("staticPods", staticPodsHandler))
+	http.HandleFunc("/credentialProtection", withSensorEnabled("credentialProtection", credentialProtectionHandler))
+	http.HandleFunc("/nodeIdentity", withSensorEnabled("nodeIdentity", nodeIdentityHandler))
+	http.HandleFunc("/windowsSecurityHardening", withSensorEnabled("windowsSecurityHardening", windowsSecurityHardeningHandler))
+	http.HandleFunc("/konnectivity", withSensorEnabled("konnectivity", konnectivityHandler))
+	http.HandleFunc("/containerdServer", withSensorEnabled("containerdServer", containerdServerHandler))
+	http.HandleFunc("/hostInfo", withSensorEnabled("hostInfo", hostInfoHandler))
+	http.HandleFunc("/runtimeSockets", withSensorEnabled("runtimeSockets", runtimeSocketsHandler))
+	http.HandleFunc("/adminTools", withSensorEnabled("adminTools", adminToolsHandler))
+	http.HandleFunc("/kernelPatching", withSensorEnabled("kernelPatching", kernelPatchingHandler))
+	http.HandleFunc("/accelerators", withSensorEnabled("accelerators", acceleratorsHandler))
+	http.HandleFunc("/admissionBypass", withSensorEnabled("admissionBypass", admissionBypassHandler))
+	http.HandleFunc("/podVolumes", withSensorEnabled("podVolumes", podVolumesHandler))
+	http.HandleFunc("/seccompProfiles", withSensorEnabled("seccompProfiles", seccompProfilesHandler))
+	http.HandleFunc("/kubeletCertRotation", withSensorEnabled("kubeletCertRotation", kubeletCertRotationHandler))
+	http.HandleFunc("/riskyServices", withSensorEnabled("riskyServices", riskyServicesHandler))
+	http.HandleFunc("/nodeRestriction", withSensorEnabled("nodeRestriction", nodeRestrictionHandler))
+	http.HandleFunc("/kubeletDiskGC", withSensorEnabled("kubeletDiskGC", kubeletDiskGCHandler))
+	http.HandleFunc("/containerLogRotation", withSensorEnabled("containerLogRotation", containerLogRotationHandler))
+	http.HandleFunc("/scanReport", scanReportHandler)
+	http.HandleFunc(summaryTierPrefix+"/", summaryHandler)
+	http.HandleFunc("/scan", batchScanHandler)
+	http.HandleFunc("/jobs", jobsHandler)
+	http.HandleFunc("/jobs/", jobHandler)
+	http.HandleFunc("/history", historyHandler)
+	http.HandleFunc("/diff", diffHandler)
+	http.HandleFunc("/events", eventsHandler)
+	http.HandleFunc("/clusterReport", clusterReportHandler)
+	http.HandleFunc("/integrity", integrityHandler)
+	http.HandleFunc("/integrity/baseline", integrityBaselineHandler)
+	http.HandleFunc("/audit", auditHandler)
+	http.HandleFunc("/metrics", metricsHandler)
+	http.HandleFunc("/healthz", healthzHandler)
+	http.HandleFunc("/logLevel", logLevelHandler)
+	http.HandleFunc("/debug/bundle", debugBundleHandler)
+	http.HandleFunc("/version", versionHandler)
+	http.HandleFunc("/openapi.json", openAPIHandler)
+}
 
-		if err != nil {
-			http.Error(rw, fmt.Sprintf("failed to sense kubelet conf: %v", err), http.StatusInternalServerError)
-		} else {
-			rw.WriteHeader(http.StatusOK)
-			if _, err := rw.Write(conf); err != nil {
-				zap.L().Error("In kubeletConfigurations handler failed to write", zap.Error(err))
+// VersionInfo describes the running sensor
+type VersionInfo struct {
+	Version string `json:"version"`
+
+	// Generation of the active configuration, incremented on every config file reload
+	ConfigGeneration int64 `json:"configGeneration"`
+}
+
+func versionHandler(rw http.ResponseWriter, r *http.Request) {
+	resp := VersionInfo{
+		Version:          buildVersion,
+		ConfigGeneration: getConfig().Generation,
+	}
+	GenericSensorHandler(rw, r, resp, nil, "version")
+}
+
+// withSensorEnabled responds with `ErrSensorDisabled` instead of calling the handler if the sensor is disabled.
+// If serving from cache is enabled, it responds with the result of the latest periodic scan (when available).
+// The lists of the paginated sensors are paginated if requested. A panic of the handler responds with `ErrSensorPanic`.
+func withSensorEnabled(sensorName string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(rw http.ResponseWriter, r *http.Request) {
+		if getConfig().isSensorDisabled(sensorName) {
+			writeSenseError(rw, sensor.ErrSensorDisabled, sensorName)
+			return
+		}
+		if _, ok := paginatedLists[sensorName]; ok {
+			page, err := parsePageRequest(r)
+			if err != nil {
+				writeSenseError(rw, err, sensorName)
+				return
 			}
+			if page != nil {
+				writer := &pageWriter{ResponseWriter: rw, sensorName: sensorName, page: page}
+				defer writer.flush()
+				rw = writer
+			}
+		}
+		if getConfig().ServeCached && serveCachedResult(rw, r, sensorName) {
+			return
 		}
-	})
-	http.HandleFunc("/kubeletCommandLine", func(rw http.ResponseWriter, r *http.Request) {
-		proc, err := sensor.LocateKubeletProcess()
+		release := acquireScanSlot(rw, r, sensorName)
+		if release == nil {
+			return
+		}
+		defer release()
+		// the handler senses with the context of the request, which carries the run and its collection errors
+		recorder := &statusRecorder{ResponseWriter: rw}
+		metrics, _ := sensor.Instrument(r.Context(), sensorName, func(ctx context.Context) {
+			if err := sensor.RecoverPanic(ctx, sensorName, func() { handler(recorder, r.WithContext(ctx)) }); err != nil && recorder.status == 0 {
+				writeSenseError(recorder, err, sensorName)
+			}
+		})
+		sensorMetrics.observe(sensorName, metrics, recorder.status)
+	}
+}
+
+// serveCachedResult responds with the sensor result of the latest periodic scan, or with `304 Not Modified`
+// if the request matches its entity tag. It returns false if there is no cached result for the sensor.
+func serveCachedResult(rw http.ResponseWriter, r *http.Request, sensorName string) bool {
+	if scheduler == nil {
+		return false
+	}
+	report := scheduler.latestReport()
+	if report == nil {
+		return false
+	}
+
+	if senseErr, ok := report.Errors[sensorName]; ok {
+		writeSenseError(rw, senseErr, sensorName)
+		return true
+	}
+
+	result, ok := report.Results[sensorName]
+	if !ok {
+		return false
+	}
 
-		cmdLine := strings.Join(proc.CmdLine, " ")
+	rw.Header().Set("X-Scan-Time", report.Time.Format(time.RFC3339))
+	if writeConditional(rw, r, result) {
+		return true
+	}
+	content := []byte(result)
+	if rawResultSensors[sensorName] {
+		str := ""
+		if err := json.Unmarshal(result, &str); err != nil {
+			return false
+		}
+		setCollectionErrorsHeader(rw, report.CollectionErrors[sensorName])
+		content = []byte(str)
+	} else {
+		withErrors, err := addCollectionErrors(rw, content, report.CollectionErrors[sensorName])
 		if err != nil {
-			http.Error(rw, fmt.Sprintf("failed to sense kubelet conf: %v", err), http.StatusInternalServerError)
-		} else {
-			rw.WriteHeader(http.StatusOK)
-			if _, err := rw.Write([]byte(cmdLine)); err != nil {
-				zap.L().Error("In kubeletConfigurations handler failed to write", zap.Error(err))
-			}
+			return false
 		}
-	})
-	http.HandleFunc("/osRelease", osReleaseHandler)
-	http.HandleFunc("/kernelVersion", kernelVersionHandler)
-	http.HandleFunc("/linuxSecurityHardening", linuxSecurityHardeningHandler)
-	http.HandleFunc("/openedPorts", openedPortsHandler)
-	http.HandleFunc("/LinuxKernelVariables", LinuxKernelVariablesHandler)
-	http.HandleFunc("/kubeletInfo", kubeletInfoHandler)
-	http.HandleFunc("/kubeProxyInfo", kubeProxyHandler)
-	http.HandleFunc("/controlPlaneInfo", controlPlaneHandler)
+		rw.Header().Set("Content-Type", "application/json")
+		content = append(withErrors, '\n')
+	}
+
+	rw.WriteHeader(http.StatusOK)
+	if _, err := rw.Write(content); err != nil {
+		sensor.Logger(r.Context()).Error(fmt.Sprintf("In %s handler failed to write", sensorName), zap.Error(err))
+	}
+	return true
+}
+
+// scanReportHandler responds with the latest periodic scan report (honoring `If-None-Match`), or scans the node if there is none
+func scanReportHandler(rw http.ResponseWriter, r *http.Request) {
+	var report *ScanReport
+	if scheduler != nil {
+		report = scheduler.latestReport()
+	}
+	if report == nil {
+		release := acquireScanSlot(rw, r, "scanReport")
+		if release == nil {
+			return
+		}
+		defer release()
+		GenericSensorHandler(rw, r, runScan(r.Context(), false), nil, "scanReport")
+		return
+	}
+
+	content, err := json.Marshal(report)
+	if err != nil {
+		writeSenseError(rw, err, "scanReport")
+		return
+	}
+	rw.Header().Set("X-Scan-Time", report.Time.Format(time.RFC3339))
+	if writeConditional(rw, r, content) {
+		return
+	}
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	if _, err := rw.Write(append(content, '\n')); err != nil {
+		sensor.Logger(r.Context()).Error("In scanReport handler failed to write", zap.Error(err))
+	}
 }
 
 func controlPlaneHandler(rw http.ResponseWriter, r *http.Request) {
-	resp, err := sensor.SenseControlPlaneInfo()
+	resp, err := sensor.SenseControlPlaneInfo(r.Context())
 	GenericSensorHandler(rw, r, resp, err, "SenseControlPlaneInfo")
 }
 
+func escapeSurfaceHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseEscapeSurface(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseEscapeSurface")
+}
+
+func kubeconfigsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseKubeconfigs(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseKubeconfigs")
+}
+
+func privateKeysHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SensePrivateKeys(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SensePrivateKeys")
+}
+
+func tokensHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseTokens(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseTokens")
+}
+
+func registryCredentialsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseRegistryCredentials(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseRegistryCredentials")
+}
+
+func certificatesHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseCertificates(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseCertificates")
+}
+
+func tlsConfigsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseTLSConfigs(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseTLSConfigs")
+}
+
+func etcdEncryptionHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseEtcdEncryption(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseEtcdEncryption")
+}
+
+func kubeadmArtifactsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseKubeadmArtifacts(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseKubeadmArtifacts")
+}
+
+func systemdUnitsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseSystemdUnits(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseSystemdUnits")
+}
+
+func timeSyncHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseTimeSync(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseTimeSync")
+}
+
+func packagesHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SensePackages(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SensePackages")
+}
+
+func kernelConfigHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseKernelConfig(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseKernelConfig")
+}
+
+func resourcePressureHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseResourcePressure(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseResourcePressure")
+}
+
+func devicesHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseDevices(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseDevices")
+}
+
+func containerLogsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseContainerLogs(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseContainerLogs")
+}
+
+func imageStoresHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseImageStores(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseImageStores")
+}
+
+func staticPodsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseStaticPods(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseStaticPods")
+}
+
+func credentialProtectionHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseCredentialProtection(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseCredentialProtection")
+}
+
+func nodeIdentityHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseNodeIdentity(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseNodeIdentity")
+}
+
+func windowsSecurityHardeningHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseWindowsSecurityHardening(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseWindowsSecurityHardening")
+}
+
+func konnectivityHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseKonnectivity(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseKonnectivity")
+}
+
+func containerdServerHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseContainerdServer(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseContainerdServer")
+}
+
+func hostInfoHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseHostInfo(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseHostInfo")
+}
+
+func runtimeSocketsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseRuntimeSockets(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseRuntimeSockets")
+}
+
+func adminToolsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseAdminTools(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseAdminTools")
+}
+
+func kernelPatchingHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseKernelPatching(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseKernelPatching")
+}
+
+func acceleratorsHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseAccelerators(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseAccelerators")
+}
+
+func admissionBypassHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseAdmissionBypass(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseAdmissionBypass")
+}
+
+func podVolumesHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SensePodVolumes(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SensePodVolumes")
+}
+
+func seccompProfilesHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseSeccompProfiles(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseSeccompProfiles")
+}
+
+func kubeletCertRotationHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseKubeletCertRotation(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseKubeletCertRotation")
+}
+
+func riskyServicesHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseRiskyServices(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseRiskyServices")
+}
+
+func nodeRestrictionHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseNodeRestriction(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseNodeRestriction")
+}
+
+func kubeletDiskGCHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseKubeletDiskGC(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseKubeletDiskGC")
+}
+
+func containerLogRotationHandler(rw http.ResponseWriter, r *http.Request) {
+	resp, err := sensor.SenseContainerLogRotation(r.Context())
+	GenericSensorHandler(rw, r, resp, err, "SenseContainerLogRotation")
+}
+
 func kubeProxyHandler(rw http.ResponseWriter, r *http.Request) {
-	resp, err := sensor.SenseKubeProxyInfo()
+	resp, err := sensor.SenseKubeProxyInfo(r.Context())
 	GenericSensorHandler(rw, r, resp, err, "SenseKubeProxyInfo")
 }
 
 func kubeletInfoHandler(rw http.ResponseWriter, r *http.Request) {
-	resp, err := sensor.SenseKubeletInfo()
+	resp, err := sensor.SenseKubeletInfo(r.Context())
 	GenericSensorHandler(rw, r, resp, err, "SenseKubeletInfo")
 }
 
 func LinuxKernelVariablesHandler(rw http.ResponseWriter, r *http.Request) {
-	resp, err := sensor.SenseKernelVariables()
+	resp, err := sensor.SenseKernelVariables(r.Context())
 	GenericSensorHandler(rw, r, resp, err, "SenseKernelVariables")
 }
 
 func openedPortsHandler(rw http.ResponseWriter, r *http.Request) {
-	resp, err := sensor.SenseOpenPorts()
+	resp, err := sensor.SenseOpenPorts(r.Context())
 	GenericSensorHandler(rw, r, resp, err, "SenseOpenPorts")
 }
 
+func kubeletConfigurationsHandler(rw http.ResponseWriter, r *http.Request) {
+	// the whole response is the content of the kubelet config, which has nothing left once anonymized
+	if reportAnonymizer != nil {
+		writeSenseError(rw, errRawContentAnonymized, "SenseKubeletConfigurations")
+		return
+	}
+	conf, err := sensor.SenseKubeletConfigurations(r.Context())
+
+	if err != nil {
+		writeSenseError(rw, err, "SenseKubeletConfigurations")
+	} else if collectionErrors, err := requestCollectionErrors(r); err != nil {
+		writeSenseError(rw, err, "SenseKubeletConfigurations")
+	} else {
+		setCollectionErrorsHeader(rw, collectionErrors)
+		rw.WriteHeader(http.StatusOK)
+		if _, err := rw.Write(conf); err != nil {
+			sensor.Logger(r.Context()).Error("In kubeletConfigurations handler failed to write", zap.Error(err))
+		}
+	}
+}
+
+func kubeletCommandLineHandler(rw http.ResponseWriter, r *http.Request) {
+	proc, err := sensor.LocateKubeletProcess(r.Context())
+
+	var cmdLine []byte
+	if err == nil {
+		cmdLine, err = anonymizeText([]byte(strings.Join(proc.CmdLine, " ")))
+	}
+	if err != nil {
+		writeSenseError(rw, err, "LocateKubeletProcess")
+	} else if collectionErrors, err := requestCollectionErrors(r); err != nil {
+		writeSenseError(rw, err, "LocateKubeletProcess")
+	} else {
+		setCollectionErrorsHeader(rw, collectionErrors)
+		rw.WriteHeader(http.StatusOK)
+		if _, err := rw.Write(cmdLine); err != nil {
+			sensor.Logger(r.Context()).Error("In kubeletCommandLine handler failed to write", zap.Error(err))
+		}
+	}
+}
+
 func osReleaseHandler(rw http.ResponseWriter, r *http.Request) {
-	fileContent, err := sensor.SenseOsRelease()
+	fileContent, err := sensor.SenseOsRelease(r.Context())
+	if err == nil {
+		fileContent, err = anonymizeText(fileContent)
+	}
 	if err != nil {
-		http.Error(rw, fmt.Sprintf("failed to SenseOsRelease: %v", err), http.StatusInternalServerError)
+		writeSenseError(rw, err, "SenseOsRelease")
+	} else if collectionErrors, err := requestCollectionErrors(r); err != nil {
+		writeSenseError(rw, err, "SenseOsRelease")
 	} else {
+		setCollectionErrorsHeader(rw, collectionErrors)
 		rw.WriteHeader(http.StatusOK)
 		if _, err := rw.Write(fileContent); err != nil {
-			zap.L().Error("In SenseOsRelease handler failed to write", zap.Error(err))
+			sensor.Logger(r.Context()).Error("In SenseOsRelease handler failed to write", zap.Error(err))
 		}
 	}
 }
 
 func kernelVersionHandler(rw http.ResponseWriter, r *http.Request) {
-	fileContent, err := sensor.SenseKernelVersion()
+	fileContent, err := sensor.SenseKernelVersion(r.Context())
+	if err == nil {
+		fileContent, err = anonymizeText(fileContent)
+	}
 	if err != nil {
-		http.Error(rw, fmt.Sprintf("failed to sense kernelVersionHandler: %v", err), http.StatusInternalServerError)
+		writeSenseError(rw, err, "SenseKernelVersion")
+	} else if collectionErrors, err := requestCollectionErrors(r); err != nil {
+		writeSenseError(rw, err, "SenseKernelVersion")
 	} else {
+		setCollectionErrorsHeader(rw, collectionErrors)
 		rw.WriteHeader(http.StatusOK)
 		if _, err := rw.Write(fileContent); err != nil {
-			zap.L().Error("In kernelVersionHandler handler failed to write", zap.Error(err))
+			sensor.Logger(r.Context()).Error("In kernelVersionHandler handler failed to write", zap.Error(err))
 		}
 	}
 }
 
 func linuxSecurityHardeningHandler(rw http.ResponseWriter, r *http.Request) {
-	resp, err := sensor.SenseLinuxSecurityHardening()
+	resp, err := sensor.SenseLinuxSecurityHardening(r.Context())
 	GenericSensorHandler(rw, r, resp, err, "sense linuxSecurityHardeningHandler")
 }
 
 // GenericSensorHandler do the generic job of encoding the response and error handeling
 func GenericSensorHandler(w http.ResponseWriter, r *http.Request, respContent interface{}, err error, senseName string) {
 
+	if err == nil {
+		respContent, err = anonymizeResult(respContent)
+	}
+	var collectionErrors []sensor.CollectionError
+	if err == nil {
+		collectionErrors, err = requestCollectionErrors(r)
+	}
+	if err == nil {
+		respContent, err = withCollectionErrors(w, respContent, collectionErrors)
+	}
+
 	// Response ok
 	if err == nil {
 		w.WriteHeader(http.StatusOK)
 		if err := json.NewEncoder(w).Encode(respContent); err != nil {
-			zap.L().Error(fmt.Sprintf("In %s handler failed to write", senseName), zap.Error(err))
+			sensor.Logger(r.Context()).Error(fmt.Sprintf("In %s handler failed to write", senseName), zap.Error(err))
 		}
 		return
 	}
 
-	// Handle errors
-	senseErr, ok := err.(*sensor.SenseError)
-	if !ok {
-		http.Error(w, fmt.Sprintf("failed to %s: %v", senseName, err), http.StatusInternalServerError)
+	writeSenseError(w, err, senseName)
+}
+
+// collectionErrorsHeader holds the collection errors of the results which aren't JSON objects
+const collectionErrorsHeader = "X-Collection-Errors"
+
+// requestCollectionErrors returns the items the sensor run of the request failed to collect, anonymized as the
+// results are
+func requestCollectionErrors(r *http.Request) ([]sensor.CollectionError, error) {
+	collectionErrors := sensor.CollectionErrors(r.Context())
+	if reportAnonymizer == nil || len(collectionErrors) == 0 {
+		return collectionErrors, nil
+	}
+	anonymized := []sensor.CollectionError{}
+	if err := reportAnonymizer.anonymizeInto(collectionErrors, &anonymized); err != nil {
+		return nil, err
+	}
+	return anonymized, nil
+}
+
+// withCollectionErrors returns the result with the items its sensor failed to collect, see `addCollectionErrors`
+func withCollectionErrors(rw http.ResponseWriter, result interface{}, collectionErrors []sensor.CollectionError) (interface{}, error) {
+	if len(collectionErrors) == 0 {
+		return result, nil
+	}
+	content, err := json.Marshal(result)
+	if err != nil {
+		return nil, err
+	}
+	content, err = addCollectionErrors(rw, content, collectionErrors)
+	return json.RawMessage(content), err
+}
+
+// addCollectionErrors returns the JSON encoded result with the items its sensor failed to collect. They are added
+// under `collectionErrors` to a JSON object, the other results (lists) keep their format and the collection errors
+// are set in the `X-Collection-Errors` header.
+func addCollectionErrors(rw http.ResponseWriter, result []byte, collectionErrors []sensor.CollectionError) ([]byte, error) {
+	if len(collectionErrors) == 0 {
+		return result, nil
+	}
+	if trimmed := bytes.TrimSpace(result); len(trimmed) == 0 || trimmed[0] != '{' {
+		setCollectionErrorsHeader(rw, collectionErrors)
+		return result, nil
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(result, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse result: %w", err)
+	}
+	var err error
+	if fields["collectionErrors"], err = json.Marshal(collectionErrors); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
+
+// setCollectionErrorsHeader sets the collection errors of a result in the `X-Collection-Errors` header
+func setCollectionErrorsHeader(rw http.ResponseWriter, collectionErrors []sensor.CollectionError) {
+	if len(collectionErrors) == 0 {
 		return
 	}
+	encoded, _ := json.Marshal(collectionErrors)
+	rw.Header().Set(collectionErrorsHeader, string(encoded))
+}
+
+// writeSenseError writes the error as a JSON encoded `SenseError`, with the HTTP status code matching its kind
+func writeSenseError(w http.ResponseWriter, err error, senseName string) {
+	senseErr := sensor.AsSenseError(err, senseName)
 
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(senseErr.Code)
 	if err := json.NewEncoder(w).Encode(senseErr); err != nil {
 		zap.L().Error(fmt.Sprintf("In %s handler failed to write", senseName), zap.Error(err))
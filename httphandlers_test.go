@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/armosec/host-sensor/sensor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSensorPanicIsolation(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+	defer setConfig(getConfig())
+	setConfig(defaultConfig())
+
+	rw := httptest.NewRecorder()
+	handler := withSensorEnabled("kubeletInfo", func(rw http.ResponseWriter, r *http.Request) {
+		panic("malformed kubelet config")
+	})
+	handler(rw, httptest.NewRequest(http.MethodGet, "/kubeletInfo", nil))
+	assert.Equal(t, http.StatusInternalServerError, rw.Code)
+	senseErr := sensor.SenseError{}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &senseErr))
+	assert.Equal(t, sensor.ErrKindSensorPanic, senseErr.Kind)
+	// the stack is logged, and not served
+	assert.NotContains(t, rw.Body.String(), "goroutine")
+	entries := logs.FilterMessage("sensor panicked").All()
+	if assert.Len(t, entries, 1) {
+		assert.Contains(t, entries[0].ContextMap()["stack"], "TestSensorPanicIsolation")
+	}
+}
+
+func TestSensorsRunConcurrently(t *testing.T) {
+	defer setConfig(getConfig())
+	setConfig(defaultConfig())
+
+	// the first sensor completes only after the second one, which fails if the sensors are serialized
+	started, second := make(chan struct{}), make(chan struct{})
+	first := withSensorEnabled("kubeletInfo", func(rw http.ResponseWriter, r *http.Request) {
+		close(started)
+		select {
+		case <-second:
+		case <-time.After(5 * time.Second):
+			t.Error("the sensors are serialized")
+		}
+		GenericSensorHandler(rw, r, map[string]string{}, nil, "kubeletInfo")
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		first(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/kubeletInfo", nil))
+	}()
+
+	<-started
+	rw := httptest.NewRecorder()
+	withSensorEnabled("kubeProxyInfo", func(rw http.ResponseWriter, r *http.Request) {
+		GenericSensorHandler(rw, r, map[string]string{}, nil, "kubeProxyInfo")
+	})(rw, httptest.NewRequest(http.MethodGet, "/kubeProxyInfo", nil))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	close(second)
+	<-done
+}
+
+func TestAddCollectionErrors(t *testing.T) {
+	collectionErrors := []sensor.CollectionError{{Path: "/etc/kubernetes/pki/ca.key", Op: "open", Errno: "EACCES", Error: "permission denied"}}
+
+	// an object result holds its collection errors
+	rw := httptest.NewRecorder()
+	result, err := addCollectionErrors(rw, []byte(`{"path":"/etc/kubernetes/kubelet.conf"}`), collectionErrors)
+	require.NoError(t, err)
+	withErrors := struct {
+		Path             string                   `json:"path"`
+		CollectionErrors []sensor.CollectionError `json:"collectionErrors"`
+	}{}
+	require.NoError(t, json.Unmarshal(result, &withErrors))
+	assert.Equal(t, "/etc/kubernetes/kubelet.conf", withErrors.Path)
+	assert.Equal(t, collectionErrors, withErrors.CollectionErrors)
+	assert.Empty(t, rw.Header().Get(collectionErrorsHeader))
+
+	// a list keeps its format, its collection errors are in the header
+	rw = httptest.NewRecorder()
+	result, err = addCollectionErrors(rw, []byte(`[{"path":"/etc/kubernetes/pki/ca.key"}]`), collectionErrors)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[{"path":"/etc/kubernetes/pki/ca.key"}]`, string(result))
+	fromHeader := []sensor.CollectionError{}
+	require.NoError(t, json.Unmarshal([]byte(rw.Header().Get(collectionErrorsHeader)), &fromHeader))
+	assert.Equal(t, collectionErrors, fromHeader)
+
+	// nothing is added without collection errors
+	rw = httptest.NewRecorder()
+	result, err = addCollectionErrors(rw, []byte(`{}`), nil)
+	require.NoError(t, err)
+	assert.Equal(t, `{}`, string(result))
+	assert.Empty(t, rw.Header().Get(collectionErrorsHeader))
+}
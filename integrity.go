@@ -0,0 +1,331 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armosec/host-sensor/evaluation"
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+)
+
+// This file contains the file integrity monitoring (FIM), which baselines the hashes, permissions and
+// ownership of the files collected by the sensors, and reports deviations on subsequent scans.
+
+const (
+	integrityRuleID = "file-integrity"
+
+	// integritySensorName is the sensor of the additional monitored paths
+	integritySensorName = "integrityFiles"
+)
+
+// Deviation types
+const (
+	deviationModified    = "modified"
+	deviationPermissions = "permissions"
+	deviationOwnership   = "ownership"
+	deviationAttributes  = "attributes"
+	deviationAdded       = "added"
+	deviationRemoved     = "removed"
+)
+
+var (
+	errIntegrityDisabled = &sensor.SenseError{
+		Massage: "file integrity monitoring is disabled",
+		Kind:    "IntegrityDisabled",
+		Code:    http.StatusServiceUnavailable,
+	}
+
+	// integrity monitors the files integrity, nil if disabled
+	integrity *integrityMonitor
+)
+
+// FileState is the monitored state of a file
+type FileState struct {
+	SHA256      string `json:"sha256"`
+	Permissions int    `json:"permissions"`
+	UID         int64  `json:"uid"`
+	GID         int64  `json:"gid"`
+
+	// The immutable and append-only attributes, e.g. set by an attacker to keep a backdoor in place
+	Attributes []string `json:"attributes,omitempty"`
+
+	// The sensor which collected the file
+	Sensor string `json:"sensor,omitempty"`
+}
+
+// IntegrityDeviation is a difference of a file from its baseline
+type IntegrityDeviation struct {
+	Path string `json:"path"`
+
+	// One of deviation*
+	Type string `json:"type"`
+
+	Baseline *FileState `json:"baseline,omitempty"`
+	Current  *FileState `json:"current,omitempty"`
+}
+
+// IntegrityBaseline is the accepted state of the monitored files
+type IntegrityBaseline struct {
+	Time  time.Time            `json:"time"`
+	Files map[string]FileState `json:"files"`
+}
+
+// IntegrityStatus is the response of the integrity endpoint
+type IntegrityStatus struct {
+	BaselineTime time.Time            `json:"baselineTime"`
+	Files        int                  `json:"files"`
+	Deviations   []IntegrityDeviation `json:"deviations"`
+}
+
+// integrityMonitor compares the files of every scan with the baseline.
+// The first periodic scan is the initial baseline.
+type integrityMonitor struct {
+	// file to persist the baseline in, empty to keep it in memory only
+	baselineFile string
+
+	lock       sync.Mutex
+	baseline   *IntegrityBaseline
+	current    map[string]FileState
+	deviations []IntegrityDeviation
+}
+
+// newIntegrityMonitor creates a monitor, loading the baseline from `baselineFile` (if exists)
+func newIntegrityMonitor(baselineFile string) (*integrityMonitor, error) {
+	m := &integrityMonitor{baselineFile: baselineFile}
+	if baselineFile == "" {
+		return m, nil
+	}
+
+	content, err := os.ReadFile(baselineFile)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read integrity baseline: %w", err)
+	}
+	m.baseline = &IntegrityBaseline{}
+	if err := json.Unmarshal(content, m.baseline); err != nil {
+		return nil, fmt.Errorf("failed to parse integrity baseline: %w", err)
+	}
+	return m, nil
+}
+
+// check compares the files in the scan results with the baseline, and returns the deviations. Without a baseline,
+// the files of a `scheduled` scan are the baseline, and a scan on demand has no deviations. The files of the sensors
+// which failed or are disabled (the errors of the scan) are kept as in the baseline, rather than removed.
+func (m *integrityMonitor) check(results map[string]json.RawMessage, sensorErrors map[string]*sensor.SenseError, scheduled bool) []IntegrityDeviation {
+	current := collectFileStates(results)
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.baseline != nil {
+		for filePath, state := range m.baseline.Files {
+			if _, failed := sensorErrors[state.Sensor]; failed && state.Sensor != "" {
+				if _, ok := current[filePath]; !ok {
+					current[filePath] = state
+				}
+			}
+		}
+	}
+	m.current = current
+	if m.baseline == nil {
+		if !scheduled {
+			m.deviations = []IntegrityDeviation{}
+			return m.deviations
+		}
+		m.setBaseline(current)
+	}
+	m.deviations = compareFileStates(m.baseline.Files, current)
+	return m.deviations
+}
+
+// acceptBaseline makes the files of the latest check the new baseline, of either a periodic scan or a scan on demand,
+// since the sensor may have no scan interval
+func (m *integrityMonitor) acceptBaseline() bool {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if m.current == nil {
+		return false
+	}
+	m.setBaseline(m.current)
+	m.deviations = []IntegrityDeviation{}
+	return true
+}
+
+func (m *integrityMonitor) status() *IntegrityStatus {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	status := &IntegrityStatus{Deviations: m.deviations}
+	if m.baseline != nil {
+		status.BaselineTime = m.baseline.Time
+		status.Files = len(m.baseline.Files)
+	}
+	return status
+}
+
+// setBaseline replaces the baseline and persists it, must be called with the lock held
+func (m *integrityMonitor) setBaseline(files map[string]FileState) {
+	m.baseline = &IntegrityBaseline{Time: time.Now().UTC(), Files: files}
+	if m.baselineFile == "" {
+		return
+	}
+	content, err := json.Marshal(m.baseline)
+	if err == nil {
+		err = os.WriteFile(m.baselineFile, content, 0o600)
+	}
+	if err != nil {
+		zap.L().Error("failed to save integrity baseline", zap.String("path", m.baselineFile), zap.Error(err))
+	}
+}
+
+// collectFileStates returns the states of all the hashed files in the scan results, keyed by path
+func collectFileStates(results map[string]json.RawMessage) map[string]FileState {
+	states := map[string]FileState{}
+	for sensorName, result := range results {
+		var value interface{}
+		if err := json.Unmarshal(result, &value); err != nil {
+			continue
+		}
+		collectValueFileStates(value, sensorName, states)
+	}
+	return states
+}
+
+func collectValueFileStates(value interface{}, sensorName string, states map[string]FileState) {
+	switch typed := value.(type) {
+	case []interface{}:
+		for _, item := range typed {
+			collectValueFileStates(item, sensorName, states)
+		}
+	case map[string]interface{}:
+		filePath, _ := typed["path"].(string)
+		hash, _ := typed["sha256"].(string)
+		if filePath != "" && hash != "" {
+			state := FileState{SHA256: hash, Sensor: sensorName}
+			if perm, ok := typed["permissions"].(float64); ok {
+				state.Permissions = int(perm)
+			}
+			if ownership, ok := typed["ownership"].(map[string]interface{}); ok {
+				uid, _ := ownership["uid"].(float64)
+				gid, _ := ownership["gid"].(float64)
+				state.UID, state.GID = int64(uid), int64(gid)
+			}
+			if attributes, ok := typed["attributes"].([]interface{}); ok {
+				for _, attribute := range attributes {
+					if name, ok := attribute.(string); ok {
+						state.Attributes = append(state.Attributes, name)
+					}
+				}
+			}
+			states[filePath] = state
+			return
+		}
+		for _, item := range typed {
+			collectValueFileStates(item, sensorName, states)
+		}
+	}
+}
+
+// compareFileStates returns the deviations of the current files from the baseline, sorted by path
+func compareFileStates(baseline, current map[string]FileState) []IntegrityDeviation {
+	deviations := []IntegrityDeviation{}
+	for filePath, baselineState := range baseline {
+		baselineState := baselineState
+		currentState, ok := current[filePath]
+		if !ok {
+			deviations = append(deviations, IntegrityDeviation{Path: filePath, Type: deviationRemoved, Baseline: &baselineState})
+			continue
+		}
+
+		deviationType := ""
+		switch {
+		case currentState.SHA256 != baselineState.SHA256:
+			deviationType = deviationModified
+		case currentState.Permissions != baselineState.Permissions:
+			deviationType = deviationPermissions
+		case currentState.UID != baselineState.UID || currentState.GID != baselineState.GID:
+			deviationType = deviationOwnership
+		case strings.Join(currentState.Attributes, ",") != strings.Join(baselineState.Attributes, ","):
+			deviationType = deviationAttributes
+		default:
+			continue
+		}
+		deviations = append(deviations, IntegrityDeviation{Path: filePath, Type: deviationType, Baseline: &baselineState, Current: &currentState})
+	}
+	for filePath, currentState := range current {
+		currentState := currentState
+		if _, ok := baseline[filePath]; !ok {
+			deviations = append(deviations, IntegrityDeviation{Path: filePath, Type: deviationAdded, Current: &currentState})
+		}
+	}
+
+	sort.Slice(deviations, func(i, j int) bool { return deviations[i].Path < deviations[j].Path })
+	return deviations
+}
+
+// integrityFindings returns a finding for every deviation
+func integrityFindings(deviations []IntegrityDeviation) []evaluation.Finding {
+	findings := []evaluation.Finding{}
+	for _, deviation := range deviations {
+		findings = append(findings, evaluation.Finding{
+			RuleID:   integrityRuleID,
+			Severity: evaluation.SeverityHigh,
+			Path:     deviation.Path,
+			Message:  fmt.Sprintf("file %s deviates from the integrity baseline (%s)", deviation.Path, deviation.Type),
+		})
+	}
+	return findings
+}
+
+// newIntegrityFilesSensor creates a sensor of additional host files to monitor, e.g. binaries
+func newIntegrityFilesSensor(paths []string) sensor.Sensor {
+	return sensor.NewSensor(integritySensorName, func(ctx context.Context) (interface{}, error) {
+		files := []*sensor.FileInfo{}
+		for _, filePath := range paths {
+			info, err := sensor.HostFileInfo(ctx, filePath)
+			if err != nil {
+				zap.L().Debug("failed to get monitored file info", zap.String("path", filePath), zap.Error(err))
+				continue
+			}
+			files = append(files, info)
+		}
+		return files, nil
+	})
+}
+
+// integrityHandler responds with the baseline summary and the deviations of the latest scan
+func integrityHandler(rw http.ResponseWriter, r *http.Request) {
+	if integrity == nil {
+		writeSenseError(rw, errIntegrityDisabled, "integrity")
+		return
+	}
+	GenericSensorHandler(rw, r, integrity.status(), nil, "integrity")
+}
+
+// integrityBaselineHandler accepts the files of the latest scan as the new baseline (POST only)
+func integrityBaselineHandler(rw http.ResponseWriter, r *http.Request) {
+	if integrity == nil {
+		writeSenseError(rw, errIntegrityDisabled, "integrityBaseline")
+		return
+	}
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		writeSenseError(rw, &sensor.SenseError{Massage: "method not allowed", Code: http.StatusMethodNotAllowed}, "integrityBaseline")
+		return
+	}
+
+	// scan on demand, if there was no scan yet
+	if !integrity.acceptBaseline() {
+		runScan(r.Context(), false)
+		integrity.acceptBaseline()
+	}
+	GenericSensorHandler(rw, r, integrity.status(), nil, "integrityBaseline")
+}
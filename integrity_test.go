@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"path"
+	"testing"
+
+	"github.com/armosec/host-sensor/sensor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIntegrityMonitor(t *testing.T) {
+	results := func(hash string, perm int) map[string]json.RawMessage {
+		kubeletInfo, _ := json.Marshal(map[string]interface{}{
+			"configFile": map[string]interface{}{"path": "/var/lib/kubelet/config.yaml", "sha256": hash, "permissions": perm, "ownership": map[string]interface{}{"uid": 0, "gid": 0}},
+		})
+		return map[string]json.RawMessage{"kubeletInfo": kubeletInfo}
+	}
+
+	baselineFile := path.Join(t.TempDir(), "baseline.json")
+	m, err := newIntegrityMonitor(baselineFile)
+	require.NoError(t, err)
+
+	// a scan on demand doesn't set the baseline, the first periodic scan does
+	assert.Empty(t, m.check(results("ccc", 0o600), nil, false))
+	assert.True(t, m.status().BaselineTime.IsZero())
+	assert.Empty(t, m.check(results("aaa", 0o600), nil, true))
+	assert.Equal(t, 1, m.status().Files)
+
+	// a scan on demand is compared with the baseline
+	deviations := m.check(results("ccc", 0o600), nil, false)
+	require.Len(t, deviations, 1)
+	assert.Equal(t, "aaa", deviations[0].Baseline.SHA256)
+
+	deviations = m.check(results("bbb", 0o600), nil, true)
+	require.Len(t, deviations, 1)
+	assert.Equal(t, deviationModified, deviations[0].Type)
+	assert.Equal(t, "aaa", deviations[0].Baseline.SHA256)
+	assert.Equal(t, "bbb", deviations[0].Current.SHA256)
+
+	deviations = m.check(results("aaa", 0o644), nil, true)
+	require.Len(t, deviations, 1)
+	assert.Equal(t, deviationPermissions, deviations[0].Type)
+
+	deviations = m.check(map[string]json.RawMessage{}, nil, true)
+	require.Len(t, deviations, 1)
+	assert.Equal(t, deviationRemoved, deviations[0].Type)
+
+	// the files of a failed or disabled sensor aren't removed
+	deviations = m.check(map[string]json.RawMessage{}, map[string]*sensor.SenseError{"kubeletInfo": sensor.ErrSensorDisabled}, true)
+	assert.Empty(t, deviations)
+
+	// the accepted baseline is persisted
+	m.check(results("bbb", 0o600), nil, true)
+	assert.True(t, m.acceptBaseline())
+	assert.Empty(t, m.status().Deviations)
+
+	m, err = newIntegrityMonitor(baselineFile)
+	require.NoError(t, err)
+	assert.Empty(t, m.check(results("bbb", 0o600), nil, true))
+}
+
+func TestCompareFileStatesAttributes(t *testing.T) {
+	baseline := map[string]FileState{"/etc/kubernetes/manifests/kube-apiserver.yaml": {SHA256: "aaa", Permissions: 0o600}}
+	current := map[string]FileState{"/etc/kubernetes/manifests/kube-apiserver.yaml": {SHA256: "aaa", Permissions: 0o600, Attributes: []string{"immutable"}}}
+
+	deviations := compareFileStates(baseline, current)
+	require.Len(t, deviations, 1)
+	assert.Equal(t, deviationAttributes, deviations[0].Type)
+	assert.Equal(t, []string{"immutable"}, deviations[0].Current.Attributes)
+
+	assert.Empty(t, compareFileStates(current, current))
+}
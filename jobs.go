@@ -0,0 +1,191 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+)
+
+// Job statuses
+const (
+	JobRunning = "running"
+	JobDone    = "done"
+)
+
+const (
+	// maximal number of kept jobs, the oldest finished jobs are removed first
+	maxJobs = 100
+
+	// finished jobs are removed after the TTL
+	jobTTL = time.Hour
+)
+
+// Job is an asynchronous batch scan
+type Job struct {
+	ID string `json:"id"`
+
+	// One of Job*
+	Status string `json:"status"`
+
+	Created  time.Time  `json:"created"`
+	Finished *time.Time `json:"finished,omitempty"`
+
+	Progress JobProgress `json:"progress"`
+
+	// The result of the scan, once done
+	Result *BatchScanResult `json:"result,omitempty"`
+}
+
+// JobProgress is the progress of a job
+type JobProgress struct {
+	// Number of completed and selected sensors
+	Completed int `json:"completed"`
+	Total     int `json:"total"`
+
+	// The running sensor
+	Current string `json:"current,omitempty"`
+
+	// The progress of the running sensor, if it reports it
+	Sensor *sensor.Progress `json:"sensor,omitempty"`
+}
+
+// jobStore keeps the jobs in memory
+type jobStore struct {
+	lock sync.Mutex
+	jobs map[string]*Job
+}
+
+var jobs = &jobStore{jobs: map[string]*Job{}}
+
+// start starts a job running the batch scan in the background, and returns its ID. `finished` is called once done.
+// The scan logs are stamped with the ID of the request which started the job.
+func (s *jobStore) start(req *BatchScanRequest, requestID string, finished func()) (*Job, error) {
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+	job := &Job{
+		ID:       id,
+		Status:   JobRunning,
+		Created:  time.Now().UTC(),
+		Progress: JobProgress{Total: len(req.Sensors)},
+	}
+
+	s.lock.Lock()
+	s.expire(job.Created)
+	if len(s.jobs) >= maxJobs {
+		s.lock.Unlock()
+		return nil, &sensor.SenseError{Massage: "too many jobs", Code: http.StatusTooManyRequests}
+	}
+	s.jobs[id] = job
+	snapshot := *job
+	s.lock.Unlock()
+
+	go func() {
+		defer finished()
+		ctx := sensor.WithProgress(sensor.WithRequestID(sensingCtx, requestID), func(progress sensor.Progress) {
+			s.lock.Lock()
+			defer s.lock.Unlock()
+			job.Progress.Sensor = &progress
+		})
+		result := runBatchScan(ctx, req, func(completed int, current string) {
+			s.lock.Lock()
+			defer s.lock.Unlock()
+			job.Progress.Completed, job.Progress.Current, job.Progress.Sensor = completed, current, nil
+		})
+
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		finished := time.Now().UTC()
+		job.Status, job.Finished, job.Result = JobDone, &finished, result
+		job.Progress.Completed, job.Progress.Current, job.Progress.Sensor = job.Progress.Total, "", nil
+	}()
+	return &snapshot, nil
+}
+
+// get returns a copy of the job, nil if not found
+func (s *jobStore) get(id string) *Job {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.expire(time.Now().UTC())
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil
+	}
+	snapshot := *job
+	return &snapshot
+}
+
+// expire removes the jobs finished more than the TTL ago, and the oldest finished jobs while there are too many
+func (s *jobStore) expire(now time.Time) {
+	var oldest *Job
+	for id, job := range s.jobs {
+		if job.Finished == nil {
+			continue
+		}
+		if now.Sub(*job.Finished) > jobTTL {
+			delete(s.jobs, id)
+			continue
+		}
+		if oldest == nil || job.Finished.Before(*oldest.Finished) {
+			oldest = job
+		}
+	}
+	if len(s.jobs) >= maxJobs && oldest != nil {
+		delete(s.jobs, oldest.ID)
+	}
+}
+
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// jobsHandler starts a job of the batch scan of the request body (POST only), see `batchScanHandler`
+func jobsHandler(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", http.MethodPost)
+		writeSenseError(rw, &sensor.SenseError{Massage: "method not allowed", Code: http.StatusMethodNotAllowed}, "jobs")
+		return
+	}
+	req, err := readBatchScanRequest(rw, r)
+	if err != nil {
+		writeSenseError(rw, err, "jobs")
+		return
+	}
+	release := acquireScanSlot(rw, r, "jobs")
+	if release == nil {
+		return
+	}
+	job, err := jobs.start(req, sensor.RequestID(r.Context()), release)
+	if err != nil {
+		release()
+		writeSenseError(rw, err, "jobs")
+		return
+	}
+	rw.Header().Set("Location", "/jobs/"+job.ID)
+	rw.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(rw).Encode(job); err != nil {
+		sensor.Logger(r.Context()).Error("In jobs handler failed to write", zap.Error(err))
+	}
+}
+
+// jobHandler responds with the progress of the job /jobs/{id}, and its result once done
+func jobHandler(rw http.ResponseWriter, r *http.Request) {
+	job := jobs.get(strings.TrimPrefix(r.URL.Path, "/jobs/"))
+	if job == nil {
+		writeSenseError(rw, &sensor.SenseError{Massage: "job not found", Code: http.StatusNotFound}, "jobs")
+		return
+	}
+	GenericSensorHandler(rw, r, job, nil, "jobs")
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobs(t *testing.T) {
+	defer setConfig(getConfig())
+	setConfig(defaultConfig())
+
+	rw := httptest.NewRecorder()
+	jobsHandler(rw, httptest.NewRequest(http.MethodPost, "/jobs", strings.NewReader(`{"sensors": [{"name": "batchTest"}]}`)))
+	require.Equal(t, http.StatusAccepted, rw.Code)
+	job := Job{}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &job))
+	assert.Equal(t, "/jobs/"+job.ID, rw.Header().Get("Location"))
+	assert.Equal(t, 1, job.Progress.Total)
+
+	require.Eventually(t, func() bool {
+		rw := httptest.NewRecorder()
+		jobHandler(rw, httptest.NewRequest(http.MethodGet, "/jobs/"+job.ID, nil))
+		require.Equal(t, http.StatusOK, rw.Code)
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &job))
+		return job.Status == JobDone
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, job.Progress.Completed)
+	require.NotNil(t, job.Result)
+	assert.Contains(t, job.Result.Results, "batchTest")
+
+	rw = httptest.NewRecorder()
+	jobHandler(rw, httptest.NewRequest(http.MethodGet, "/jobs/notAJob", nil))
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+
+	rw = httptest.NewRecorder()
+	jobsHandler(rw, httptest.NewRequest(http.MethodGet, "/jobs", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+}
+
+func TestJobStoreExpire(t *testing.T) {
+	now := time.Now()
+	old, recent := now.Add(-2*jobTTL), now.Add(-time.Minute)
+	store := &jobStore{jobs: map[string]*Job{
+		"old":     {ID: "old", Status: JobDone, Finished: &old},
+		"recent":  {ID: "recent", Status: JobDone, Finished: &recent},
+		"running": {ID: "running", Status: JobRunning},
+	}}
+	store.expire(now)
+	assert.NotContains(t, store.jobs, "old")
+	assert.Contains(t, store.jobs, "recent")
+	assert.Contains(t, store.jobs, "running")
+}
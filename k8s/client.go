@@ -0,0 +1,176 @@
+package k8s
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// This file contains a minimal Kubernetes API client, authenticating with the pod's service account.
+
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	requestTimeout    = 30 * time.Second
+)
+
+var (
+	ErrNotInCluster = errors.New("not running inside a kubernetes cluster")
+)
+
+// StatusError is returned when the API server responds with a non 2xx status code
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+// Error implements error interface
+func (err *StatusError) Error() string {
+	return fmt.Sprintf("kubernetes API responded with status %d: %s", err.Code, err.Message)
+}
+
+// IsNotFound returns true if the API server responded with 404
+func IsNotFound(err error) bool {
+	var statusErr *StatusError
+	return errors.As(err, &statusErr) && statusErr.Code == http.StatusNotFound
+}
+
+// Client is a Kubernetes API client
+type Client struct {
+	host       string
+	tokenFile  string
+	httpClient *http.Client
+
+	// Namespace of the running pod
+	Namespace string
+}
+
+// NewInClusterClient creates a client using the pod's service account credentials
+func NewInClusterClient() (*Client, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, ErrNotInCluster
+	}
+
+	caCert, err := os.ReadFile(path.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account CA: %w", err)
+	}
+	caPool := x509.NewCertPool()
+	if !caPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to parse service account CA")
+	}
+
+	namespace, err := os.ReadFile(path.Join(serviceAccountDir, "namespace"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read service account namespace: %w", err)
+	}
+
+	httpClient := &http.Client{
+		Timeout: requestTimeout,
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{RootCAs: caPool, MinVersion: tls.VersionTLS12},
+		},
+	}
+
+	return NewClient("https://"+net.JoinHostPort(host, port), path.Join(serviceAccountDir, "token"), httpClient, strings.TrimSpace(string(namespace))), nil
+}
+
+// NewClient creates a client for the API server at `host` (URL), authenticating with the bearer token in `tokenFile`
+func NewClient(host, tokenFile string, httpClient *http.Client, namespace string) *Client {
+	return &Client{
+		host:       host,
+		tokenFile:  tokenFile,
+		httpClient: httpClient,
+		Namespace:  namespace,
+	}
+}
+
+// Get reads the object at `apiPath` into `out`
+func (c *Client) Get(ctx context.Context, apiPath string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, apiPath, "", nil, out)
+}
+
+// Post creates the object `obj` at `apiPath`, and reads the response into `out` (if not nil)
+func (c *Client) Post(ctx context.Context, apiPath string, obj interface{}, out interface{}) error {
+	return c.do(ctx, http.MethodPost, apiPath, "application/json", obj, out)
+}
+
+// Apply creates or updates the object at `apiPath` using server side apply
+func (c *Client) Apply(ctx context.Context, apiPath string, obj interface{}, fieldManager string) error {
+	apiPath = fmt.Sprintf("%s?fieldManager=%s&force=true", apiPath, fieldManager)
+	// JSON is a valid YAML
+	return c.do(ctx, http.MethodPatch, apiPath, "application/apply-patch+yaml", obj, nil)
+}
+
+// Token returns the service account token of the client.
+// The token is read on every call since projected tokens are rotated by the kubelet.
+func (c *Client) Token() (string, error) {
+	token, err := os.ReadFile(c.tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read service account token: %w", err)
+	}
+	return strings.TrimSpace(string(token)), nil
+}
+
+func (c *Client) do(ctx context.Context, method, apiPath, contentType string, body interface{}, out interface{}) error {
+	var bodyReader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+		bodyReader = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.host+apiPath, bodyReader)
+	if err != nil {
+		return err
+	}
+
+	token, err := c.Token()
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		status := struct {
+			Message string `json:"message"`
+		}{}
+		if json.Unmarshal(respBody, &status) != nil || status.Message == "" {
+			status.Message = string(respBody)
+		}
+		return &StatusError{Code: resp.StatusCode, Message: status.Message}
+	}
+
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
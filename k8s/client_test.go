@@ -0,0 +1,62 @@
+package k8s
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	srv := httptest.NewTLSServer(handler)
+	t.Cleanup(srv.Close)
+
+	tokenFile := path.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("foo-token\n"), 0600))
+
+	return NewClient(srv.URL, tokenFile, srv.Client(), "default")
+}
+
+func TestClientGet(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer foo-token", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/v1/nodes/node1":
+			w.Write([]byte(`{"metadata": {"name": "node1", "uid": "1234"}, "spec": {"providerID": "aws:///foo", "taints": [{"key": "foo", "effect": "NoSchedule"}]}, "status": {"nodeInfo": {"kubeletVersion": "v1.25.0"}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"kind": "Status", "message": "nodes \"node2\" not found"}`))
+		}
+	})
+
+	node := Node{}
+	require.NoError(t, client.Get(context.Background(), NodePath("node1"), &node))
+	assert.Equal(t, "1234", node.Metadata.UID)
+	assert.Equal(t, "aws:///foo", node.Spec.ProviderID)
+	assert.Equal(t, []Taint{{Key: "foo", Effect: "NoSchedule"}}, node.Spec.Taints)
+	assert.Equal(t, "v1.25.0", node.Status.NodeInfo.KubeletVersion)
+	assert.Equal(t, OwnerReference{APIVersion: "v1", Kind: "Node", Name: "node1", UID: "1234"}, node.OwnerReference())
+
+	err := client.Get(context.Background(), NodePath("node2"), &node)
+	assert.True(t, IsNotFound(err))
+	assert.Contains(t, err.Error(), `nodes "node2" not found`)
+}
+
+func TestClientApply(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPatch, r.Method)
+		assert.Equal(t, "application/apply-patch+yaml", r.Header.Get("Content-Type"))
+		assert.Equal(t, "host-sensor", r.URL.Query().Get("fieldManager"))
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{"metadata": {"name": "foo"}}`, string(body))
+	})
+
+	obj := map[string]interface{}{"metadata": ObjectMeta{Name: "foo"}}
+	assert.NoError(t, client.Apply(context.Background(), "/foo", obj, "host-sensor"))
+}
@@ -0,0 +1,251 @@
+package k8s
+
+import "net/url"
+
+// ObjectMeta holds the metadata fields of a Kubernetes object used by the host sensor
+type ObjectMeta struct {
+	Name            string            `json:"name,omitempty"`
+	Namespace       string            `json:"namespace,omitempty"`
+	UID             string            `json:"uid,omitempty"`
+	ResourceVersion string            `json:"resourceVersion,omitempty"`
+	Labels          map[string]string `json:"labels,omitempty"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+	OwnerReferences []OwnerReference  `json:"ownerReferences,omitempty"`
+}
+
+// OwnerReference points to the owner of an object
+type OwnerReference struct {
+	APIVersion string `json:"apiVersion"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	UID        string `json:"uid"`
+}
+
+// Node is a Kubernetes Node object
+type Node struct {
+	APIVersion string     `json:"apiVersion,omitempty"`
+	Kind       string     `json:"kind,omitempty"`
+	Metadata   ObjectMeta `json:"metadata"`
+	Spec       NodeSpec   `json:"spec"`
+	Status     NodeStatus `json:"status"`
+}
+
+// NodeSpec holds the node spec fields used by the host sensor
+type NodeSpec struct {
+	ProviderID    string  `json:"providerID,omitempty"`
+	Taints        []Taint `json:"taints,omitempty"`
+	Unschedulable bool    `json:"unschedulable,omitempty"`
+}
+
+// Taint is a node taint
+type Taint struct {
+	Key    string `json:"key"`
+	Value  string `json:"value,omitempty"`
+	Effect string `json:"effect"`
+}
+
+// NodeStatus holds the node status fields used by the host sensor
+type NodeStatus struct {
+	NodeInfo NodeSystemInfo `json:"nodeInfo"`
+}
+
+// NodeSystemInfo is the system information reported by the kubelet
+type NodeSystemInfo struct {
+	KubeletVersion          string `json:"kubeletVersion,omitempty"`
+	KubeProxyVersion        string `json:"kubeProxyVersion,omitempty"`
+	ContainerRuntimeVersion string `json:"containerRuntimeVersion,omitempty"`
+	KernelVersion           string `json:"kernelVersion,omitempty"`
+	OSImage                 string `json:"osImage,omitempty"`
+	OperatingSystem         string `json:"operatingSystem,omitempty"`
+	Architecture            string `json:"architecture,omitempty"`
+}
+
+// OwnerReference returns a reference to the node, to be used as the owner of other objects
+func (n *Node) OwnerReference() OwnerReference {
+	return OwnerReference{
+		APIVersion: "v1",
+		Kind:       "Node",
+		Name:       n.Metadata.Name,
+		UID:        n.Metadata.UID,
+	}
+}
+
+// NodePath returns the API path of a node
+func NodePath(name string) string {
+	return "/api/v1/nodes/" + name
+}
+
+// Pod is a Kubernetes Pod object
+type Pod struct {
+	Metadata ObjectMeta `json:"metadata"`
+	Spec     PodSpec    `json:"spec"`
+	Status   PodStatus  `json:"status"`
+}
+
+// PodSpec holds the pod spec fields used by the host sensor
+type PodSpec struct {
+	NodeName string `json:"nodeName,omitempty"`
+}
+
+// PodStatus holds the pod status fields used by the host sensor
+type PodStatus struct {
+	Phase string `json:"phase,omitempty"`
+	PodIP string `json:"podIP,omitempty"`
+}
+
+// PodPhaseRunning is the phase of a pod whose containers were started
+const PodPhaseRunning = "Running"
+
+// PodList is a list of pods
+type PodList struct {
+	Items []Pod `json:"items"`
+}
+
+// PodsPath returns the API path for listing the pods in a namespace, matching the label selector (if not empty)
+func PodsPath(namespace, labelSelector string) string {
+	apiPath := "/api/v1/namespaces/" + namespace + "/pods"
+	if labelSelector != "" {
+		apiPath += "?labelSelector=" + url.QueryEscape(labelSelector)
+	}
+	return apiPath
+}
+
+// ObjectReference points to an object
+type ObjectReference struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind"`
+	Name       string `json:"name"`
+	Namespace  string `json:"namespace,omitempty"`
+	UID        string `json:"uid,omitempty"`
+}
+
+// EventSource identifies the component which reported an event
+type EventSource struct {
+	Component string `json:"component,omitempty"`
+	Host      string `json:"host,omitempty"`
+}
+
+// Event is a core/v1 Kubernetes Event
+type Event struct {
+	APIVersion     string          `json:"apiVersion"`
+	Kind           string          `json:"kind"`
+	Metadata       ObjectMeta      `json:"metadata"`
+	InvolvedObject ObjectReference `json:"involvedObject"`
+	Reason         string          `json:"reason"`
+	Message        string          `json:"message"`
+	Type           string          `json:"type"`
+	Source         EventSource     `json:"source"`
+	FirstTimestamp string          `json:"firstTimestamp"`
+	LastTimestamp  string          `json:"lastTimestamp"`
+	Count          int32           `json:"count"`
+}
+
+// Event types
+const (
+	EventTypeNormal  = "Normal"
+	EventTypeWarning = "Warning"
+)
+
+// EventsPath returns the API path of the events of a namespace
+func EventsPath(namespace string) string {
+	return "/api/v1/namespaces/" + namespace + "/events"
+}
+
+// ObjectReference returns a reference to the node, to be used as the involved object of events
+func (n *Node) ObjectReference() ObjectReference {
+	return ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Node",
+		Name:       n.Metadata.Name,
+		UID:        n.Metadata.UID,
+	}
+}
+
+// TokenReview authenticates a token (authentication.k8s.io/v1)
+type TokenReview struct {
+	APIVersion string            `json:"apiVersion"`
+	Kind       string            `json:"kind"`
+	Spec       TokenReviewSpec   `json:"spec"`
+	Status     TokenReviewStatus `json:"status,omitempty"`
+}
+
+// TokenReviewSpec holds the token to review
+type TokenReviewSpec struct {
+	Token     string   `json:"token"`
+	Audiences []string `json:"audiences,omitempty"`
+}
+
+// TokenReviewStatus is the result of a token review
+type TokenReviewStatus struct {
+	Authenticated bool     `json:"authenticated"`
+	User          UserInfo `json:"user,omitempty"`
+	Error         string   `json:"error,omitempty"`
+}
+
+// UserInfo holds the information about an authenticated user
+type UserInfo struct {
+	Username string              `json:"username,omitempty"`
+	UID      string              `json:"uid,omitempty"`
+	Groups   []string            `json:"groups,omitempty"`
+	Extra    map[string][]string `json:"extra,omitempty"`
+}
+
+// TokenReviewsPath is the API path of token reviews
+const TokenReviewsPath = "/apis/authentication.k8s.io/v1/tokenreviews"
+
+// NewTokenReview returns a token review of `token`
+func NewTokenReview(token string) *TokenReview {
+	return &TokenReview{
+		APIVersion: "authentication.k8s.io/v1",
+		Kind:       "TokenReview",
+		Spec:       TokenReviewSpec{Token: token},
+	}
+}
+
+// SubjectAccessReview checks whether a user is allowed to perform an action (authorization.k8s.io/v1)
+type SubjectAccessReview struct {
+	APIVersion string                    `json:"apiVersion"`
+	Kind       string                    `json:"kind"`
+	Spec       SubjectAccessReviewSpec   `json:"spec"`
+	Status     SubjectAccessReviewStatus `json:"status,omitempty"`
+}
+
+// SubjectAccessReviewSpec holds the user and the action to check
+type SubjectAccessReviewSpec struct {
+	NonResourceAttributes *NonResourceAttributes `json:"nonResourceAttributes,omitempty"`
+	User                  string                 `json:"user,omitempty"`
+	Groups                []string               `json:"groups,omitempty"`
+	Extra                 map[string][]string    `json:"extra,omitempty"`
+	UID                   string                 `json:"uid,omitempty"`
+}
+
+// NonResourceAttributes describes an access to a non resource URL
+type NonResourceAttributes struct {
+	Path string `json:"path,omitempty"`
+	Verb string `json:"verb,omitempty"`
+}
+
+// SubjectAccessReviewStatus is the result of a subject access review
+type SubjectAccessReviewStatus struct {
+	Allowed bool   `json:"allowed"`
+	Denied  bool   `json:"denied,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// SubjectAccessReviewsPath is the API path of subject access reviews
+const SubjectAccessReviewsPath = "/apis/authorization.k8s.io/v1/subjectaccessreviews"
+
+// NewNonResourceAccessReview returns a subject access review of `user` performing `verb` on the non resource URL `path`
+func NewNonResourceAccessReview(user *UserInfo, verb, path string) *SubjectAccessReview {
+	return &SubjectAccessReview{
+		APIVersion: "authorization.k8s.io/v1",
+		Kind:       "SubjectAccessReview",
+		Spec: SubjectAccessReviewSpec{
+			NonResourceAttributes: &NonResourceAttributes{Path: path, Verb: verb},
+			User:                  user.Username,
+			Groups:                user.Groups,
+			Extra:                 user.Extra,
+			UID:                   user.UID,
+		},
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// logLevel is the level of the logger, changed at runtime through /logLevel
+var logLevel = zap.NewAtomicLevelAt(zapcore.DebugLevel)
+
+// LogLevel is the request and the response of the /logLevel endpoint
+type LogLevel struct {
+	// One of debug, info, warn, error
+	Level string `json:"level"`
+}
+
+// parseLogLevel returns the level of its name, e.g. "debug"
+func parseLogLevel(name string) (zapcore.Level, error) {
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return level, fmt.Errorf("invalid log level %q", name)
+	}
+	return level, nil
+}
+
+// logLevelHandler responds with the log level, and changes it on PUT
+func logLevelHandler(rw http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		req := LogLevel{}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeSenseError(rw, &sensor.SenseError{Massage: fmt.Sprintf("invalid request: %v", err), Code: http.StatusBadRequest}, "logLevel")
+			return
+		}
+		level, err := parseLogLevel(req.Level)
+		if err != nil {
+			writeSenseError(rw, &sensor.SenseError{Massage: err.Error(), Code: http.StatusBadRequest}, "logLevel")
+			return
+		}
+		// logged as a warning, so the change shows whatever the levels are
+		sensor.Logger(r.Context()).Warn("log level changed", zap.Stringer("from", logLevel.Level()), zap.Stringer("to", level),
+			zap.String("user", requestUser(r.Context())))
+		logLevel.SetLevel(level)
+	default:
+		rw.Header().Set("Allow", http.MethodGet+", "+http.MethodPut)
+		writeSenseError(rw, &sensor.SenseError{Massage: "method not allowed", Code: http.StatusMethodNotAllowed}, "logLevel")
+		return
+	}
+	GenericSensorHandler(rw, r, LogLevel{Level: logLevel.Level().String()}, nil, "logLevel")
+}
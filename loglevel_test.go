@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestLogLevelHandler(t *testing.T) {
+	defer logLevel.SetLevel(logLevel.Level())
+	logLevel.SetLevel(zapcore.InfoLevel)
+
+	rw := httptest.NewRecorder()
+	logLevelHandler(rw, httptest.NewRequest(http.MethodPut, "/logLevel", strings.NewReader(`{"level": "debug"}`)))
+	require.Equal(t, http.StatusOK, rw.Code)
+	assert.Equal(t, zapcore.DebugLevel, logLevel.Level())
+
+	rw = httptest.NewRecorder()
+	logLevelHandler(rw, httptest.NewRequest(http.MethodGet, "/logLevel", nil))
+	resp := LogLevel{}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &resp))
+	assert.Equal(t, "debug", resp.Level)
+
+	rw = httptest.NewRecorder()
+	logLevelHandler(rw, httptest.NewRequest(http.MethodPut, "/logLevel", strings.NewReader(`{"level": "verbose"}`)))
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+	assert.Equal(t, zapcore.DebugLevel, logLevel.Level())
+
+	rw = httptest.NewRecorder()
+	logLevelHandler(rw, httptest.NewRequest(http.MethodDelete, "/logLevel", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+	assert.Equal(t, "GET, PUT", rw.Header().Get("Allow"))
+}
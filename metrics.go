@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+)
+
+// This file contains the metrics of the sensor runs, served at /metrics in the Prometheus text format.
+
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// sensorMetrics accumulates the metrics of all the sensor runs
+var sensorMetrics = newMetricsRegistry()
+
+// sensorCounters are the accumulated metrics of a sensor
+type sensorCounters struct {
+	runs        int
+	seconds     float64
+	lastSeconds float64
+	failedFiles int
+	lastFailed  int
+
+	// Number of failed runs, keyed by the HTTP status code of the error
+	errors map[int]int
+
+	// Number of the runs which failed in a row, and the status code of the latest failure
+	consecutiveFailures int
+	lastErrorCode       int
+
+	steps map[string]*stepCounters
+}
+
+type stepCounters struct {
+	count   int
+	failed  int
+	seconds float64
+}
+
+type metricsRegistry struct {
+	lock    sync.Mutex
+	sensors map[string]*sensorCounters
+}
+
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{sensors: map[string]*sensorCounters{}}
+}
+
+// errorStatus returns the HTTP status code of a sensor error, 200 if `err` is nil
+func errorStatus(err error, sensorName string) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	return sensor.AsSenseError(err, sensorName).Code
+}
+
+// observe accumulates the metrics of a sensor run, which responded with `status`
+func (m *metricsRegistry) observe(name string, metrics *sensor.SenseMetrics, status int) {
+	if metrics == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	counters := m.sensors[name]
+	if counters == nil {
+		counters = &sensorCounters{errors: map[int]int{}, steps: map[string]*stepCounters{}}
+		m.sensors[name] = counters
+	}
+	counters.runs++
+	counters.lastSeconds = metrics.Duration.Seconds()
+	counters.seconds += counters.lastSeconds
+	counters.lastFailed = metrics.FailedFiles
+	counters.failedFiles += metrics.FailedFiles
+	if status >= http.StatusBadRequest {
+		counters.errors[status]++
+	}
+	conf := getConfig()
+	previous := healthState(counters.consecutiveFailures, conf)
+	if isSensorFailure(status) {
+		counters.consecutiveFailures++
+		counters.lastErrorCode = status
+	} else {
+		counters.consecutiveFailures, counters.lastErrorCode = 0, 0
+	}
+	logHealthChange(name, previous, healthState(counters.consecutiveFailures, conf), counters.consecutiveFailures)
+	for stepName, stepMetrics := range metrics.Steps {
+		step := counters.steps[stepName]
+		if step == nil {
+			step = &stepCounters{}
+			counters.steps[stepName] = step
+		}
+		step.count += stepMetrics.Count
+		step.failed += stepMetrics.Failed
+		step.seconds += stepMetrics.Duration.Seconds()
+	}
+}
+
+// write writes the metrics in the Prometheus text format, sorted by sensor and step
+func (m *metricsRegistry) write(w io.Writer) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	names := make([]string, 0, len(m.sensors))
+	for name := range m.sensors {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := &strings.Builder{}
+	family := func(name, kind, help string, samples func(sample func(labels string, value float64))) {
+		fmt.Fprintf(out, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+		samples(func(labels string, value float64) {
+			fmt.Fprintf(out, "%s{%s} %s\n", name, labels, strconv.FormatFloat(value, 'g', -1, 64))
+		})
+	}
+	eachSensor := func(value func(c *sensorCounters) float64) func(sample func(string, float64)) {
+		return func(sample func(string, float64)) {
+			for _, name := range names {
+				sample(metricLabels("sensor", name), value(m.sensors[name]))
+			}
+		}
+	}
+	eachStep := func(value func(s *stepCounters) float64) func(sample func(string, float64)) {
+		return func(sample func(string, float64)) {
+			for _, name := range names {
+				steps := m.sensors[name].steps
+				for _, stepName := range sortedKeys(steps) {
+					sample(metricLabels("sensor", name, "step", stepName), value(steps[stepName]))
+				}
+			}
+		}
+	}
+
+	family("host_sensor_sensor_runs_total", "counter", "Number of the sensor runs.",
+		eachSensor(func(c *sensorCounters) float64 { return float64(c.runs) }))
+	family("host_sensor_sensor_duration_seconds_total", "counter", "Total duration of the sensor runs.",
+		eachSensor(func(c *sensorCounters) float64 { return c.seconds }))
+	family("host_sensor_sensor_last_duration_seconds", "gauge", "Duration of the latest sensor run.",
+		eachSensor(func(c *sensorCounters) float64 { return c.lastSeconds }))
+	family("host_sensor_sensor_failed_files_total", "counter", "Number of the items the sensor failed to collect.",
+		eachSensor(func(c *sensorCounters) float64 { return float64(c.failedFiles) }))
+	family("host_sensor_sensor_last_failed_files", "gauge", "Number of the items the latest sensor run failed to collect.",
+		eachSensor(func(c *sensorCounters) float64 { return float64(c.lastFailed) }))
+	family("host_sensor_sensor_errors_total", "counter", "Number of the failed sensor runs, by the HTTP status code of the error.",
+		func(sample func(string, float64)) {
+			for _, name := range names {
+				codes := []int{}
+				for code := range m.sensors[name].errors {
+					codes = append(codes, code)
+				}
+				sort.Ints(codes)
+				for _, code := range codes {
+					sample(metricLabels("sensor", name, "code", strconv.Itoa(code)), float64(m.sensors[name].errors[code]))
+				}
+			}
+		})
+	family("host_sensor_sensor_consecutive_failures", "gauge", "Number of the sensor runs which failed in a row.",
+		eachSensor(func(c *sensorCounters) float64 { return float64(c.consecutiveFailures) }))
+	family("host_sensor_sensor_health", "gauge", "Health state of the sensor, 1 for its current state (ok, degraded or failing).",
+		func(sample func(string, float64)) {
+			conf := getConfig()
+			for _, name := range names {
+				current := healthState(m.sensors[name].consecutiveFailures, conf)
+				for _, state := range healthStates {
+					value := 0.0
+					if state == current {
+						value = 1
+					}
+					sample(metricLabels("sensor", name, "state", state), value)
+				}
+			}
+		})
+	family("host_sensor_step_total", "counter", "Number of the sub-collections of the sensor runs.",
+		eachStep(func(s *stepCounters) float64 { return float64(s.count) }))
+	family("host_sensor_step_failures_total", "counter", "Number of the failed sub-collections of the sensor runs.",
+		eachStep(func(s *stepCounters) float64 { return float64(s.failed) }))
+	family("host_sensor_step_duration_seconds_total", "counter", "Total duration of the sub-collections of the sensor runs.",
+		eachStep(func(s *stepCounters) float64 { return s.seconds }))
+	family("host_sensor_log_suppressed_total", "counter", "Number of the suppressed repeated sensor warnings and errors.",
+		func(sample func(string, float64)) {
+			suppressed := sensor.SuppressedLogs()
+			levels := make([]string, 0, len(suppressed))
+			for level := range suppressed {
+				levels = append(levels, level)
+			}
+			sort.Strings(levels)
+			for _, level := range levels {
+				sample(metricLabels("level", level), float64(suppressed[level]))
+			}
+		})
+
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// metricLabels formats label pairs, e.g. `sensor="kubeletInfo",step="file read"`
+func metricLabels(pairs ...string) string {
+	labels := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		labels = append(labels, pairs[i]+"="+strconv.Quote(pairs[i+1]))
+	}
+	return strings.Join(labels, ",")
+}
+
+func sortedKeys(steps map[string]*stepCounters) []string {
+	keys := make([]string, 0, len(steps))
+	for key := range steps {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// statusRecorder records the status code of a sensor handler response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.ResponseWriter.Write(b)
+}
+
+// metricsHandler responds with the metrics of the sensor runs
+func metricsHandler(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", metricsContentType)
+	if err := sensorMetrics.write(rw); err != nil {
+		zap.L().Error("In metrics handler failed to write", zap.Error(err))
+	}
+}
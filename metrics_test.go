@@ -0,0 +1,46 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/armosec/host-sensor/sensor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	defer func(registry *metricsRegistry) { sensorMetrics = registry }(sensorMetrics)
+	sensorMetrics = newMetricsRegistry()
+
+	sensorMetrics.observe("kubeletInfo", &sensor.SenseMetrics{
+		Duration:    1500 * time.Millisecond,
+		FailedFiles: 2,
+		Steps:       map[string]*sensor.StepMetrics{"file read": {Count: 3, Failed: 2, Duration: 250 * time.Millisecond}},
+	}, http.StatusOK)
+	sensorMetrics.observe("kubeletInfo", &sensor.SenseMetrics{Duration: 500 * time.Millisecond}, http.StatusInternalServerError)
+
+	rw := httptest.NewRecorder()
+	metricsHandler(rw, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, metricsContentType, rw.Header().Get("Content-Type"))
+	body := rw.Body.String()
+	assert.Contains(t, body, "# TYPE host_sensor_sensor_runs_total counter\n")
+	assert.Contains(t, body, `host_sensor_sensor_runs_total{sensor="kubeletInfo"} 2`+"\n")
+	assert.Contains(t, body, `host_sensor_sensor_duration_seconds_total{sensor="kubeletInfo"} 2`+"\n")
+	assert.Contains(t, body, `host_sensor_sensor_last_duration_seconds{sensor="kubeletInfo"} 0.5`+"\n")
+	assert.Contains(t, body, `host_sensor_sensor_failed_files_total{sensor="kubeletInfo"} 2`+"\n")
+	assert.Contains(t, body, `host_sensor_sensor_last_failed_files{sensor="kubeletInfo"} 0`+"\n")
+	assert.Contains(t, body, `host_sensor_sensor_errors_total{sensor="kubeletInfo",code="500"} 1`+"\n")
+	assert.Contains(t, body, `host_sensor_step_total{sensor="kubeletInfo",step="file read"} 3`+"\n")
+	assert.Contains(t, body, `host_sensor_step_failures_total{sensor="kubeletInfo",step="file read"} 2`+"\n")
+	assert.Contains(t, body, `host_sensor_step_duration_seconds_total{sensor="kubeletInfo",step="file read"} 0.25`+"\n")
+}
+
+func TestScanReportMetrics(t *testing.T) {
+	defer setConfig(getConfig())
+	setConfig(defaultConfig())
+
+	report := runBatchScan(sensingCtx, &BatchScanRequest{Sensors: []BatchSensor{{Name: "batchTest"}}}, nil)
+	assert.Contains(t, report.Metrics, "batchTest")
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+)
+
+// This file contains serving over (mutual) TLS, and the allowlisting of the client certificates per endpoint.
+
+// allowlistSensitivePaths is the allowlist rule path matching the endpoints serving sensitive content
+const allowlistSensitivePaths = "sensitive"
+
+var (
+	errClientNotAllowed = &sensor.SenseError{
+		Massage: "client certificate is not allowed",
+		Kind:    "ClientNotAllowed",
+		Code:    http.StatusForbidden,
+	}
+
+	// sensitivePaths are the endpoints serving file contents, matched by the `sensitive` rule path.
+	// Paths ending with `/` match their sub-paths.
+	sensitivePaths = buildSensitivePaths()
+)
+
+// buildSensitivePaths returns the paths of the sensor endpoints whose result holds file contents, of the raw kubelet
+// configuration file, and of the other endpoints serving file contents (see `apiOperation.Content`)
+func buildSensitivePaths() []string {
+	paths := []string{"/kubeletConfigurations"}
+	for name, function := range sensorFunctions {
+		if hasContentField(reflect.TypeOf(function).Out(0), map[reflect.Type]bool{}) {
+			paths = append(paths, "/"+name)
+		}
+	}
+	for _, op := range apiOperations {
+		if op.Content {
+			// a path parameter matches the sub-paths, e.g. /jobs/{id}
+			if i := strings.Index(op.Path, "{"); i >= 0 {
+				paths = append(paths, op.Path[:i])
+			} else {
+				paths = append(paths, op.Path)
+			}
+		}
+	}
+	return paths
+}
+
+// hasContentField returns true if the type has a `content` field, i.e. holds file contents (e.g. a sensor.FileInfo)
+func hasContentField(t reflect.Type, seen map[reflect.Type]bool) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return hasContentField(t.Elem(), seen)
+	case reflect.Struct:
+		if seen[t] {
+			return false
+		}
+		seen[t] = true
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name == "content" {
+				return true
+			}
+			if hasContentField(field.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isSensitivePath returns true if the endpoint serves file contents
+func isSensitivePath(path string) bool {
+	for _, sensitive := range sensitivePaths {
+		if path == sensitive || (strings.HasSuffix(sensitive, "/") && strings.HasPrefix(path, sensitive)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientAllowRule restricts the paths to the clients presenting a certificate with one of the identities
+type ClientAllowRule struct {
+	// Exact paths, prefixes ending with `/`, `sensitive` for the endpoints serving sensitive content, or `*` for all
+	Paths []string `json:"paths"`
+
+	// Common names, DNS or URI (e.g. SPIFFE ID) subject alternative names of the allowed client certificates
+	Identities []string `json:"identities"`
+}
+
+// matches returns true if the rule covers the path
+func (rule *ClientAllowRule) matches(path string) bool {
+	for _, rulePath := range rule.Paths {
+		switch {
+		case rulePath == "*" || rulePath == path:
+			return true
+		case rulePath == allowlistSensitivePaths && isSensitivePath(path):
+			return true
+		case strings.HasSuffix(rulePath, "/") && strings.HasPrefix(path, rulePath):
+			return true
+		}
+	}
+	return false
+}
+
+// validateAllowlist checks that every rule has paths and identities
+func validateAllowlist(rules []ClientAllowRule) error {
+	for i := range rules {
+		if len(rules[i].Paths) == 0 || len(rules[i].Identities) == 0 {
+			return fmt.Errorf("client allowlist rule %d must have paths and identities", i)
+		}
+	}
+	return nil
+}
+
+// clientIdentities returns the common name and the DNS and URI SANs of the verified client certificate
+func clientIdentities(r *http.Request) []string {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	cert := r.TLS.PeerCertificates[0]
+	identities := []string{}
+	if cert.Subject.CommonName != "" {
+		identities = append(identities, cert.Subject.CommonName)
+	}
+	identities = append(identities, cert.DNSNames...)
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	return identities
+}
+
+// isClientAllowed returns true if no rule covers the path, or the client has an identity allowed by a covering rule
+func isClientAllowed(rules []ClientAllowRule, path string, identities []string) bool {
+	covered := false
+	for i := range rules {
+		if !rules[i].matches(path) {
+			continue
+		}
+		covered = true
+		for _, identity := range identities {
+			if containsString(rules[i].Identities, identity) {
+				return true
+			}
+		}
+	}
+	return !covered
+}
+
+// allowlistClient is a middleware rejecting the requests of clients not allowed by the client allowlist
+func allowlistClient(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	identities := clientIdentities(r)
+	if !isClientAllowed(getConfig().ClientAllowlist, r.URL.Path, identities) {
+		sensor.Logger(r.Context()).Warn("client not allowed", zap.Strings("identities", identities), zap.String("path", r.URL.Path))
+		writeSenseError(rw, errClientNotAllowed, "allowlist")
+		return
+	}
+	next(rw, r)
+}
+
+// newServerTLSConfig returns the TLS configuration of the server, requiring client certificates signed by the
+// client CA if configured
+func newServerTLSConfig(conf *Config) (*tls.Config, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if conf.TLSClientCAFile == "" {
+		return tlsConfig, nil
+	}
+
+	content, err := os.ReadFile(conf.TLSClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA: %w", err)
+	}
+	tlsConfig.ClientCAs = x509.NewCertPool()
+	if !tlsConfig.ClientCAs.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("no certificates found in client CA %s", conf.TLSClientCAFile)
+	}
+	tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	return tlsConfig, nil
+}
+
+// newPeerTLSConfig returns the TLS configuration of the aggregator's queries to the peers, verifying them with the
+// aggregator CA and presenting the sensor certificate (for the peers requiring client certificates). It returns nil
+// if the sensor doesn't serve TLS, and then neither do the peers.
+func newPeerTLSConfig(conf *Config) (*tls.Config, error) {
+	if conf.TLSCertFile == "" {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(conf.TLSCertFile, conf.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %w", err)
+	}
+	content, err := os.ReadFile(conf.AggregatorCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read aggregator CA: %w", err)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(content) {
+		return nil, fmt.Errorf("no certificates found in aggregator CA %s", conf.AggregatorCAFile)
+	}
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		RootCAs:      rootCAs,
+		Certificates: []tls.Certificate{cert},
+		ServerName:   conf.AggregatorServerName,
+	}, nil
+}
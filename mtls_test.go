@@ -0,0 +1,110 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsClientAllowed(t *testing.T) {
+	rules := []ClientAllowRule{
+		{Paths: []string{"sensitive"}, Identities: []string{"spiffe://cluster.local/ns/kubescape/sa/aggregator"}},
+		{Paths: []string{"/jobs/", "/scanReport"}, Identities: []string{"collector.kubescape.svc"}},
+	}
+	aggregator := []string{"aggregator", "spiffe://cluster.local/ns/kubescape/sa/aggregator"}
+	monitoring := []string{"monitoring"}
+
+	assert.True(t, isClientAllowed(rules, "/privateKeys", aggregator))
+	assert.False(t, isClientAllowed(rules, "/privateKeys", monitoring))
+	assert.False(t, isClientAllowed(rules, "/privateKeys", nil))
+
+	// the rules covering a path are combined
+	assert.True(t, isClientAllowed(rules, "/scanReport", aggregator))
+	assert.True(t, isClientAllowed(rules, "/scanReport", []string{"collector.kubescape.svc"}))
+	assert.True(t, isClientAllowed(rules, "/jobs/1234", []string{"collector.kubescape.svc"}))
+
+	// not covered
+	assert.True(t, isClientAllowed(rules, "/osRelease", monitoring))
+	assert.True(t, isClientAllowed(nil, "/privateKeys", nil))
+}
+
+func TestSensitivePaths(t *testing.T) {
+	for _, path := range []string{"/privateKeys", "/kubeconfigs", "/kubeletConfigurations", "/openedPorts", "/nodeRestriction",
+		"/scanReport", "/jobs/1234", "/diff", "/events", "/clusterReport", "/debug/bundle"} {
+		assert.True(t, isSensitivePath(path), path)
+	}
+	for _, path := range []string{"/osRelease", "/certificates", "/jobs", "/history", "/summary/privateKeys", "/healthz"} {
+		assert.False(t, isSensitivePath(path), path)
+	}
+}
+
+func TestAllowlistClient(t *testing.T) {
+	defer setConfig(getConfig())
+	conf := defaultConfig()
+	conf.ClientAllowlist = []ClientAllowRule{{Paths: []string{"*"}, Identities: []string{"spiffe://cluster.local/ns/kubescape/sa/aggregator"}}}
+	setConfig(conf)
+
+	next := func(rw http.ResponseWriter, r *http.Request) { rw.WriteHeader(http.StatusOK) }
+	r := httptest.NewRequest(http.MethodGet, "/kubeletInfo", nil)
+	rw := httptest.NewRecorder()
+	allowlistClient(rw, r, next)
+	assert.Equal(t, http.StatusForbidden, rw.Code)
+
+	spiffeID, _ := url.Parse("spiffe://cluster.local/ns/kubescape/sa/aggregator")
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{{URIs: []*url.URL{spiffeID}}}}
+	rw = httptest.NewRecorder()
+	allowlistClient(rw, r, next)
+	assert.Equal(t, http.StatusOK, rw.Code)
+}
+
+func TestApplyConfigFileAllowlist(t *testing.T) {
+	conf, err := applyConfigFile(defaultConfig(), []byte("clientAllowlist:\n- paths: [sensitive]\n  identities: [aggregator]\n"))
+	require.NoError(t, err)
+	assert.Equal(t, []ClientAllowRule{{Paths: []string{"sensitive"}, Identities: []string{"aggregator"}}}, conf.ClientAllowlist)
+
+	_, err = applyConfigFile(defaultConfig(), []byte("clientAllowlist:\n- paths: [sensitive]\n"))
+	assert.Error(t, err)
+}
+
+func TestNewServerTLSConfig(t *testing.T) {
+	tlsConfig, err := newServerTLSConfig(&Config{})
+	require.NoError(t, err)
+	assert.Equal(t, tls.NoClientCert, tlsConfig.ClientAuth)
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "client-ca"},
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	caFile := path.Join(t.TempDir(), "ca.crt")
+	require.NoError(t, os.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644))
+
+	tlsConfig, err = newServerTLSConfig(&Config{TLSClientCAFile: caFile})
+	require.NoError(t, err)
+	assert.Equal(t, tls.RequireAndVerifyClientCert, tlsConfig.ClientAuth)
+
+	require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0644))
+	_, err = newServerTLSConfig(&Config{TLSClientCAFile: caFile})
+	assert.Error(t, err)
+}
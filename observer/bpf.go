@@ -0,0 +1,153 @@
+//go:build linux
+
+package observer
+
+import (
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/link"
+)
+
+// The eBPF programs are assembled in Go, so no compiler or kernel headers are needed to build the sensor.
+// They are attached to the syscall tracepoints, whose context layout is a stable kernel ABI.
+//
+// The programs aren't CO-RE: they aren't relocated against the BTF of the running kernel, so they only read the
+// syscall arguments, at the offsets of the tracepoint context of 64-bit kernels, and can't read kernel structures
+// (e.g. the parent, cgroup or credentials of the process).
+
+const (
+	commsMapName  = "comms"
+	eventsMapName = "events"
+
+	// Offsets of the filename argument in the context of the sys_enter_execve and sys_enter_openat tracepoints
+	execveFilenameOffset = 16
+	openatFilenameOffset = 24
+
+	// bpf_perf_event_output flag for the perf buffer of the current CPU
+	perfCurrentCPU = 0xffffffff
+)
+
+// tracepointProgram returns a program which reports the filename argument of a syscall,
+// if it is called by a process whose name (comm) is in the comms map
+func tracepointProgram(name string, eventType int64, filenameOffset int16) *ebpf.ProgramSpec {
+	stack := func(offset int) int16 { return int16(offset - eventSize) }
+
+	return &ebpf.ProgramSpec{
+		Name:    name,
+		Type:    ebpf.TracePoint,
+		License: "GPL",
+		Instructions: asm.Instructions{
+			// r6 = ctx
+			asm.Mov.Reg(asm.R6, asm.R1),
+
+			// event.pid = bpf_get_current_pid_tgid() >> 32
+			asm.FnGetCurrentPidTgid.Call(),
+			asm.RSh.Imm(asm.R0, 32),
+			asm.StoreMem(asm.RFP, stack(eventPIDOffset), asm.R0, asm.Word),
+			asm.StoreImm(asm.RFP, stack(eventTypeOffset), eventType, asm.Word),
+
+			// bpf_get_current_comm(&event.comm, sizeof(event.comm))
+			asm.Mov.Reg(asm.R1, asm.RFP),
+			asm.Add.Imm(asm.R1, int32(stack(eventCommOffset))),
+			asm.Mov.Imm(asm.R2, commSize),
+			asm.FnGetCurrentComm.Call(),
+
+			// if (!bpf_map_lookup_elem(&comms, &event.comm)) return 0
+			asm.LoadMapPtr(asm.R1, 0).WithReference(commsMapName),
+			asm.Mov.Reg(asm.R2, asm.RFP),
+			asm.Add.Imm(asm.R2, int32(stack(eventCommOffset))),
+			asm.FnMapLookupElem.Call(),
+			asm.JEq.Imm(asm.R0, 0, "exit"),
+
+			// bpf_probe_read_user_str(&event.filename, sizeof(event.filename), ctx->filename)
+			asm.Mov.Reg(asm.R1, asm.RFP),
+			asm.Add.Imm(asm.R1, int32(stack(eventFilenameOffset))),
+			asm.Mov.Imm(asm.R2, filenameSize),
+			asm.LoadMem(asm.R3, asm.R6, filenameOffset, asm.DWord),
+			asm.FnProbeReadUserStr.Call(),
+
+			// bpf_perf_event_output(ctx, &events, BPF_F_CURRENT_CPU, &event, sizeof(event))
+			asm.Mov.Reg(asm.R1, asm.R6),
+			asm.LoadMapPtr(asm.R2, 0).WithReference(eventsMapName),
+			asm.LoadImm(asm.R3, perfCurrentCPU, asm.DWord),
+			asm.Mov.Reg(asm.R4, asm.RFP),
+			asm.Add.Imm(asm.R4, int32(stack(0))),
+			asm.Mov.Imm(asm.R5, eventSize),
+			asm.FnPerfEventOutput.Call(),
+
+			asm.Mov.Imm(asm.R0, 0).WithSymbol("exit"),
+			asm.Return(),
+		},
+	}
+}
+
+// collectionSpec returns the maps and programs of the observer
+func collectionSpec() *ebpf.CollectionSpec {
+	return &ebpf.CollectionSpec{
+		Maps: map[string]*ebpf.MapSpec{
+			commsMapName: {
+				Name:       commsMapName,
+				Type:       ebpf.Hash,
+				KeySize:    commSize,
+				ValueSize:  1,
+				MaxEntries: 64,
+			},
+			eventsMapName: {
+				Name: eventsMapName,
+				Type: ebpf.PerfEventArray,
+			},
+		},
+		Programs: map[string]*ebpf.ProgramSpec{
+			"trace_execve": tracepointProgram("trace_execve", rawEventExec, execveFilenameOffset),
+			"trace_openat": tracepointProgram("trace_openat", rawEventOpen, openatFilenameOffset),
+		},
+	}
+}
+
+// loadedObjects are the loaded eBPF objects
+type loadedObjects struct {
+	collection *ebpf.Collection
+	links      []link.Link
+}
+
+// load loads the programs, fills the comms map and attaches the programs to their tracepoints
+func load(comms []string) (*loadedObjects, error) {
+	collection, err := ebpf.NewCollection(collectionSpec())
+	if err != nil {
+		return nil, fmt.Errorf("failed to load eBPF programs: %w", err)
+	}
+	objs := &loadedObjects{collection: collection}
+
+	for _, comm := range comms {
+		key := make([]byte, commSize)
+		// comm is truncated by the kernel, and always null terminated
+		copy(key[:commSize-1], comm)
+		if err := collection.Maps[commsMapName].Put(key, uint8(1)); err != nil {
+			objs.close()
+			return nil, fmt.Errorf("failed to add %s to the observed processes: %w", comm, err)
+		}
+	}
+
+	tracepoints := map[string]string{
+		"trace_execve": "sys_enter_execve",
+		"trace_openat": "sys_enter_openat",
+	}
+	for prog, tracepoint := range tracepoints {
+		l, err := link.Tracepoint("syscalls", tracepoint, collection.Programs[prog], nil)
+		if err != nil {
+			objs.close()
+			return nil, fmt.Errorf("failed to attach to %s: %w", tracepoint, err)
+		}
+		objs.links = append(objs.links, l)
+	}
+	return objs, nil
+}
+
+func (o *loadedObjects) close() {
+	for _, l := range o.links {
+		l.Close()
+	}
+	o.collection.Close()
+}
@@ -0,0 +1,23 @@
+//go:build linux
+
+package observer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionSpec(t *testing.T) {
+	for name, prog := range collectionSpec().Programs {
+		// the jumps and references are resolvable
+		assert.NoError(t, prog.Instructions.Marshal(&bytes.Buffer{}, binary.LittleEndian), name)
+		for _, ins := range prog.Instructions {
+			if ins.IsLoadFromMap() {
+				assert.Contains(t, []string{commsMapName, eventsMapName}, ins.Reference(), name)
+			}
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package observer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This package observes the control plane processes between scans using eBPF: the processes they execute,
+// which point-in-time scans miss if they are transient, and the credential files they open.
+
+// Event types
+const (
+	EventExec = "exec"
+	EventOpen = "open"
+)
+
+// DefaultComms are the names of the observed processes. Process names are truncated to 15 characters.
+var DefaultComms = []string{"kubelet", "kube-apiserver", "etcd", "kube-controller", "kube-scheduler"}
+
+// DefaultPathPatterns are the patterns of the reported opened files, credential files by default
+var DefaultPathPatterns = []string{
+	"*.key",
+	"*.crt",
+	"*.pem",
+	"*.conf",
+	"*kubeconfig*",
+	"*/serviceaccount/token",
+	"*/secrets/*",
+}
+
+// Event layout, the stack of the program holds the event at offset -eventSize
+const (
+	eventPIDOffset      = 0
+	eventTypeOffset     = 4
+	eventCommOffset     = 8
+	eventFilenameOffset = eventCommOffset + commSize
+	eventSize           = eventFilenameOffset + filenameSize
+
+	commSize     = 16
+	filenameSize = 256
+)
+
+// Raw event types
+const (
+	rawEventExec = 1
+	rawEventOpen = 2
+)
+
+// Event is an observed (aggregated) event
+type Event struct {
+	// One of Event*
+	Type string `json:"type"`
+
+	// Name of the observing process (comm)
+	Process string `json:"process"`
+
+	// The executed or opened file
+	Path string `json:"path"`
+
+	// Number of occurrences, and the PID of the last one
+	Count   int `json:"count"`
+	LastPID int `json:"lastPID"`
+
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// Options of an Observer
+type Options struct {
+	// Names of the observed processes. Defaults to DefaultComms
+	Comms []string
+
+	// Shell patterns of the reported opened files (matched against the full path). Defaults to DefaultPathPatterns
+	PathPatterns []string
+
+	// Maximal number of distinct events kept between drains, further events are dropped. Defaults to 1000
+	MaxEvents int
+}
+
+func (o *Options) setDefaults() {
+	if len(o.Comms) == 0 {
+		o.Comms = DefaultComms
+	}
+	if len(o.PathPatterns) == 0 {
+		o.PathPatterns = DefaultPathPatterns
+	}
+	if o.MaxEvents <= 0 {
+		o.MaxEvents = 1000
+	}
+}
+
+// Observer records the events of the observed processes between drains
+type Observer struct {
+	opts Options
+	probe
+
+	lock    sync.Mutex
+	events  map[string]*Event
+	dropped int
+}
+
+// New creates an observer, call Start to start observing
+func New(opts Options) *Observer {
+	opts.setDefaults()
+	return &Observer{opts: opts, events: map[string]*Event{}}
+}
+
+// handleRawEvent parses an event written by the eBPF programs, and records it if it matches the filters
+func (o *Observer) handleRawEvent(raw []byte, now time.Time) {
+	if len(raw) < eventSize {
+		return
+	}
+
+	event := Event{
+		LastPID: int(binary.LittleEndian.Uint32(raw[eventPIDOffset:])),
+		Process: cString(raw[eventCommOffset : eventCommOffset+commSize]),
+		Path:    cString(raw[eventFilenameOffset : eventFilenameOffset+filenameSize]),
+	}
+	switch binary.LittleEndian.Uint32(raw[eventTypeOffset:]) {
+	case rawEventExec:
+		event.Type = EventExec
+	case rawEventOpen:
+		event.Type = EventOpen
+		if !o.matchesPath(event.Path) {
+			return
+		}
+	default:
+		return
+	}
+
+	o.record(&event, now)
+}
+
+func (o *Observer) matchesPath(filePath string) bool {
+	for _, pattern := range o.opts.PathPatterns {
+		if ok, _ := path.Match(pattern, filePath); ok {
+			return true
+		}
+		// patterns starting with a wildcard match any directory
+		if strings.HasPrefix(pattern, "*") {
+			if ok, _ := path.Match(pattern, path.Base(filePath)); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// record aggregates the event with the events of the same type, process and path
+func (o *Observer) record(event *Event, now time.Time) {
+	key := event.Type + "\x00" + event.Process + "\x00" + event.Path
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	if existing, ok := o.events[key]; ok {
+		existing.Count++
+		existing.LastPID = event.LastPID
+		existing.LastSeen = now
+		return
+	}
+	if len(o.events) >= o.opts.MaxEvents {
+		o.dropped++
+		return
+	}
+	event.Count = 1
+	event.FirstSeen, event.LastSeen = now, now
+	o.events[key] = event
+}
+
+// Drain returns the events recorded since the previous drain, sorted by first seen,
+// and the number of dropped events
+func (o *Observer) Drain() ([]Event, int) {
+	o.lock.Lock()
+	events, dropped := o.events, o.dropped
+	o.events, o.dropped = map[string]*Event{}, 0
+	o.lock.Unlock()
+	return sortedEvents(events), dropped
+}
+
+// Peek returns the events recorded since the previous drain, and the number of dropped events, without draining them
+func (o *Observer) Peek() ([]Event, int) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	return sortedEvents(o.events), o.dropped
+}
+
+// sortedEvents returns copies of the events, sorted by first seen
+func sortedEvents(events map[string]*Event) []Event {
+	ret := make([]Event, 0, len(events))
+	for _, event := range events {
+		ret = append(ret, *event)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if !ret[i].FirstSeen.Equal(ret[j].FirstSeen) {
+			return ret[i].FirstSeen.Before(ret[j].FirstSeen)
+		}
+		return ret[i].Path < ret[j].Path
+	})
+	return ret
+}
+
+// cString returns the null terminated string in `b`
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
@@ -0,0 +1,65 @@
+package observer
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/cilium/ebpf/perf"
+	"go.uber.org/zap"
+)
+
+// probe holds the attached eBPF programs of an Observer
+type probe struct {
+	objects *loadedObjects
+	reader  *perf.Reader
+}
+
+// Start loads and attaches the eBPF programs, and starts reading their events.
+// It requires the CAP_BPF and CAP_PERFMON (or CAP_SYS_ADMIN) capabilities, and a mounted tracefs.
+func (o *Observer) Start() error {
+	objects, err := load(o.opts.Comms)
+	if err != nil {
+		return err
+	}
+	reader, err := perf.NewReader(objects.collection.Maps[eventsMapName], os.Getpagesize()*16)
+	if err != nil {
+		objects.close()
+		return fmt.Errorf("failed to create perf reader: %w", err)
+	}
+	o.objects, o.reader = objects, reader
+
+	go o.read()
+	return nil
+}
+
+// Close stops observing
+func (o *Observer) Close() error {
+	if o.reader == nil {
+		return nil
+	}
+	err := o.reader.Close()
+	o.objects.close()
+	return err
+}
+
+func (o *Observer) read() {
+	for {
+		record, err := o.reader.Read()
+		if errors.Is(err, perf.ErrClosed) {
+			return
+		}
+		if err != nil {
+			zap.L().Error("failed to read eBPF event", zap.Error(err))
+			continue
+		}
+		if record.LostSamples > 0 {
+			o.lock.Lock()
+			o.dropped += int(record.LostSamples)
+			o.lock.Unlock()
+			continue
+		}
+		o.handleRawEvent(record.RawSample, time.Now().UTC())
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package observer
+
+import "errors"
+
+// probe holds the attached eBPF programs of an Observer, eBPF is only supported on Linux
+type probe struct{}
+
+// Start returns an error, eBPF is only supported on Linux
+func (o *Observer) Start() error {
+	return errors.New("observing processes is only supported on Linux")
+}
+
+// Close stops observing
+func (o *Observer) Close() error {
+	return nil
+}
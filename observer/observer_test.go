@@ -0,0 +1,49 @@
+package observer
+
+import (
+	"encoding/binary"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rawEvent(eventType uint32, pid uint32, comm, filename string) []byte {
+	raw := make([]byte, eventSize)
+	binary.LittleEndian.PutUint32(raw[eventPIDOffset:], pid)
+	binary.LittleEndian.PutUint32(raw[eventTypeOffset:], eventType)
+	copy(raw[eventCommOffset:], comm)
+	copy(raw[eventFilenameOffset:], filename)
+	return raw
+}
+
+func TestHandleRawEvent(t *testing.T) {
+	o := New(Options{MaxEvents: 3})
+	now := time.Now()
+
+	o.handleRawEvent(rawEvent(rawEventExec, 10, "kubelet", "/usr/sbin/iptables"), now)
+	o.handleRawEvent(rawEvent(rawEventExec, 11, "kubelet", "/usr/sbin/iptables"), now.Add(time.Second))
+	o.handleRawEvent(rawEvent(rawEventOpen, 12, "kubelet", "/var/lib/kubelet/pki/kubelet-client-current.pem"), now)
+	// not a credential file
+	o.handleRawEvent(rawEvent(rawEventOpen, 12, "kubelet", "/proc/self/mountinfo"), now)
+	o.handleRawEvent(rawEvent(rawEventOpen, 13, "etcd", "/etc/kubernetes/pki/etcd/server.key"), now)
+	// above the limit
+	o.handleRawEvent(rawEvent(rawEventExec, 14, "kubelet", "/bin/mount"), now)
+
+	// peeking doesn't drain the events
+	events, dropped := o.Peek()
+	assert.Equal(t, 1, dropped)
+	assert.Len(t, events, 3)
+
+	events, dropped = o.Drain()
+	assert.Equal(t, 1, dropped)
+	require.Len(t, events, 3)
+	assert.Equal(t, Event{Type: EventOpen, Process: "etcd", Path: "/etc/kubernetes/pki/etcd/server.key", Count: 1, LastPID: 13, FirstSeen: now, LastSeen: now}, events[0])
+	assert.Equal(t, Event{Type: EventExec, Process: "kubelet", Path: "/usr/sbin/iptables", Count: 2, LastPID: 11, FirstSeen: now, LastSeen: now.Add(time.Second)}, events[1])
+	assert.Equal(t, EventOpen, events[2].Type)
+
+	events, dropped = o.Drain()
+	assert.Empty(t, events)
+	assert.Zero(t, dropped)
+}
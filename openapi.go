@@ -0,0 +1,344 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/armosec/host-sensor/history"
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+)
+
+// sensorFunctions are the functions sensing the results of the sensor endpoints, keyed by sensor name.
+// The schema of an endpoint is generated from the result type of its function.
+var sensorFunctions = map[string]interface{}{
+	"kubeletConfigurations":    sensor.SenseKubeletConfigurations,
+	"osRelease":                sensor.SenseOsRelease,
+	"kernelVersion":            sensor.SenseKernelVersion,
+	"linuxSecurityHardening":   sensor.SenseLinuxSecurityHardening,
+	"openedPorts":              sensor.SenseOpenPorts,
+	"LinuxKernelVariables":     sensor.SenseKernelVariables,
+	"kubeletInfo":              sensor.SenseKubeletInfo,
+	"kubeProxyInfo":            sensor.SenseKubeProxyInfo,
+	"controlPlaneInfo":         sensor.SenseControlPlaneInfo,
+	"escapeSurface":            sensor.SenseEscapeSurface,
+	"kubeconfigs":              sensor.SenseKubeconfigs,
+	"privateKeys":              sensor.SensePrivateKeys,
+	"tokens":                   sensor.SenseTokens,
+	"registryCredentials":      sensor.SenseRegistryCredentials,
+	"certificates":             sensor.SenseCertificates,
+	"tlsConfigs":               sensor.SenseTLSConfigs,
+	"etcdEncryption":           sensor.SenseEtcdEncryption,
+	"kubeadmArtifacts":         sensor.SenseKubeadmArtifacts,
+	"systemdUnits":             sensor.SenseSystemdUnits,
+	"timeSync":                 sensor.SenseTimeSync,
+	"packages":                 sensor.SensePackages,
+	"kernelConfig":             sensor.SenseKernelConfig,
+	"resourcePressure":         sensor.SenseResourcePressure,
+	"devices":                  sensor.SenseDevices,
+	"containerLogs":            sensor.SenseContainerLogs,
+	"imageStores":              sensor.SenseImageStores,
+	"staticPods":               sensor.SenseStaticPods,
+	"credentialProtection":     sensor.SenseCredentialProtection,
+	"nodeIdentity":             sensor.SenseNodeIdentity,
+	"windowsSecurityHardening": sensor.SenseWindowsSecurityHardening,
+	"konnectivity":             sensor.SenseKonnectivity,
+	"containerdServer":         sensor.SenseContainerdServer,
+	"hostInfo":                 sensor.SenseHostInfo,
+	"runtimeSockets":           sensor.SenseRuntimeSockets,
+	"adminTools":               sensor.SenseAdminTools,
+	"kernelPatching":           sensor.SenseKernelPatching,
+	"accelerators":             sensor.SenseAccelerators,
+	"admissionBypass":          sensor.SenseAdmissionBypass,
+	"podVolumes":               sensor.SensePodVolumes,
+	"seccompProfiles":          sensor.SenseSeccompProfiles,
+	"kubeletCertRotation":      sensor.SenseKubeletCertRotation,
+	"riskyServices":            sensor.SenseRiskyServices,
+	"nodeRestriction":          sensor.SenseNodeRestriction,
+	"kubeletDiskGC":            sensor.SenseKubeletDiskGC,
+	"containerLogRotation":     sensor.SenseContainerLogRotation,
+}
+
+// apiParameter is a query or path parameter of an endpoint
+type apiParameter struct {
+	Name        string
+	In          string
+	Description string
+	Schema      interface{}
+}
+
+// apiOperation describes an endpoint of the sensor, besides the sensor endpoints
+type apiOperation struct {
+	Path        string
+	Method      string
+	Summary     string
+	Parameters  []apiParameter
+	Request     interface{} // Body of the request, nil if none
+	Status      int         // Status of a successful response, 200 by default
+	Response    interface{} // Body of a successful response, a string is text/plain
+	ContentType string      // Content type of a non JSON response
+	Content     bool        // Serves file contents or their changes, see `sensitivePaths`
+}
+
+var (
+	pageParameters = []apiParameter{
+		{Name: "limit", In: "query", Description: "Maximal number of items, all if 0", Schema: 0},
+		{Name: "continue", In: "query", Description: "Token of the next page, from the previous page", Schema: ""},
+		{Name: "offset", In: "query", Description: "Number of items to skip, instead of a continue token", Schema: 0},
+	}
+
+	apiOperations = []apiOperation{
+		{Path: "/kubeletCommandLine", Method: http.MethodGet, Summary: "The command line of the kubelet", Response: ""},
+		{Path: "/scanReport", Method: http.MethodGet, Content: true, Summary: "The latest scan report", Response: ScanReport{}},
+		{Path: "/scan", Method: http.MethodPost, Content: true, Summary: "Scans the selected sensors", Request: BatchScanRequest{}, Response: BatchScanResult{}},
+		{Path: "/jobs", Method: http.MethodPost, Summary: "Starts a scan job", Request: BatchScanRequest{}, Status: http.StatusAccepted, Response: Job{}},
+		{Path: "/jobs/{id}", Method: http.MethodGet, Content: true, Summary: "The status of a scan job", Response: Job{},
+			Parameters: []apiParameter{{Name: "id", In: "path", Description: "ID of the job", Schema: ""}}},
+		{Path: "/summary/{endpoint}", Method: http.MethodGet, Summary: "The response of an endpoint without the file contents", Response: map[string]interface{}{},
+			Parameters: []apiParameter{{Name: "endpoint", In: "path", Description: "Path of the endpoint, e.g. privateKeys or scanReport", Schema: ""}}},
+		{Path: "/history", Method: http.MethodGet, Summary: "The stored scans, oldest first", Response: []history.Entry{}},
+		{Path: "/diff", Method: http.MethodGet, Content: true, Summary: "The differences between two stored scans", Response: ScanDiff{},
+			Parameters: []apiParameter{
+				{Name: "from", In: "query", Description: "ID of the older scan, the one before the latest by default", Schema: uint64(0)},
+				{Name: "to", In: "query", Description: "ID of the newer scan, the latest by default", Schema: uint64(0)},
+			}},
+		{Path: "/events", Method: http.MethodGet, Content: true, Summary: "Server-Sent Events stream of the scan changes and new findings", Response: StreamEvent{}, ContentType: "text/event-stream"},
+		{Path: "/clusterReport", Method: http.MethodGet, Content: true, Summary: "The scan reports of all the sensors in the cluster", Response: ClusterReport{}},
+		{Path: "/integrity", Method: http.MethodGet, Summary: "The integrity baseline and the deviations of the latest scan", Response: IntegrityStatus{}},
+		{Path: "/integrity/baseline", Method: http.MethodPost, Summary: "Accepts the files of the latest scan as the integrity baseline", Response: IntegrityStatus{}},
+		{Path: "/audit", Method: http.MethodGet, Summary: "The latest requests to the endpoints serving sensitive content", Response: []AuditRecord{}},
+		{Path: "/metrics", Method: http.MethodGet, Summary: "Durations and failures of the sensor runs, in the Prometheus text format", Response: "", ContentType: "text/plain"},
+		{Path: "/healthz", Method: http.MethodGet, Summary: "The health of the sensors, by their consecutive failures", Response: Health{}},
+		{Path: "/logLevel", Method: http.MethodGet, Summary: "The log level", Response: LogLevel{}},
+		{Path: "/logLevel", Method: http.MethodPut, Summary: "Changes the log level", Request: LogLevel{}, Response: LogLevel{}},
+		{Path: "/debug/bundle", Method: http.MethodGet, Content: true, Summary: "A tarball of the configuration, self-test, latest scan report, metrics and recent logs", Response: "", ContentType: "application/gzip"},
+		{Path: "/version", Method: http.MethodGet, Summary: "The version of the sensor", Response: VersionInfo{}},
+		{Path: "/openapi.json", Method: http.MethodGet, Summary: "This document", Response: map[string]interface{}{}},
+	}
+
+	openAPIOnce     sync.Once
+	openAPIDocument []byte
+)
+
+// openAPIHandler responds with the OpenAPI document of the sensor API
+func openAPIHandler(rw http.ResponseWriter, r *http.Request) {
+	openAPIOnce.Do(func() {
+		openAPIDocument, _ = json.MarshalIndent(buildOpenAPI(), "", "  ")
+	})
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(http.StatusOK)
+	if _, err := rw.Write(openAPIDocument); err != nil {
+		zap.L().Error("In openapi handler failed to write", zap.Error(err))
+	}
+}
+
+// buildOpenAPI generates the OpenAPI 3 document of the endpoints, with the schemas of their Go types
+func buildOpenAPI() map[string]interface{} {
+	gen := &schemaGenerator{schemas: map[string]interface{}{}, types: map[string]reflect.Type{}}
+	errorResponse := map[string]interface{}{
+		"description": "The sensing failed",
+		"content":     jsonContent(gen.schema(reflect.TypeOf(sensor.SenseError{}))),
+	}
+
+	paths := map[string]interface{}{}
+	addOperation := func(op apiOperation) {
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		schema := gen.schema(reflect.TypeOf(op.Response))
+		content := jsonContent(schema)
+		if op.ContentType != "" {
+			content = map[string]interface{}{op.ContentType: map[string]interface{}{"schema": schema}}
+		} else if _, ok := op.Response.(string); ok {
+			content = map[string]interface{}{"text/plain": map[string]interface{}{"schema": schema}}
+		}
+		operation := map[string]interface{}{
+			"summary": op.Summary,
+			"responses": map[string]interface{}{
+				strconv.Itoa(status): map[string]interface{}{"description": http.StatusText(status), "content": content},
+				"default":            errorResponse,
+			},
+		}
+		if op.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(gen.schema(reflect.TypeOf(op.Request))),
+			}
+		}
+		if len(op.Parameters) > 0 {
+			parameters := []interface{}{}
+			for _, param := range op.Parameters {
+				parameters = append(parameters, map[string]interface{}{
+					"name":        param.Name,
+					"in":          param.In,
+					"description": param.Description,
+					"required":    param.In == "path",
+					"schema":      gen.schema(reflect.TypeOf(param.Schema)),
+				})
+			}
+			operation["parameters"] = parameters
+		}
+		item, ok := paths[op.Path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[op.Path] = item
+		}
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	// sorted, so that the component names are stable
+	names := []string{}
+	for name := range sensorFunctions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		function := sensorFunctions[name]
+		op := apiOperation{Path: "/" + name, Method: http.MethodGet, Summary: "Result of the " + name + " sensor"}
+		resultType := reflect.TypeOf(function).Out(0)
+		if resultType == reflect.TypeOf([]byte{}) {
+			op.Response = ""
+		} else {
+			op.Response = reflect.Zero(resultType).Interface()
+		}
+		if _, ok := paginatedLists[name]; ok {
+			op.Parameters = pageParameters
+		}
+		addOperation(op)
+	}
+	for _, op := range apiOperations {
+		addOperation(op)
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Host-sensor",
+			"version": buildVersion,
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": gen.schemas},
+	}
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// schemaGenerator generates the schemas of Go types, as encoded by `encoding/json`.
+// Named structs are added to the components, and referenced.
+type schemaGenerator struct {
+	schemas map[string]interface{}
+	types   map[string]reflect.Type
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+func (g *schemaGenerator) schema(t reflect.Type) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+	switch t {
+	case timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case rawMessageType:
+		return map[string]interface{}{}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.schema(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case reflect.Int64, reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return map[string]interface{}{"type": "integer", "format": "int64"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]interface{}{"type": "string", "format": "byte"}
+		}
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.structSchema(t)
+		}
+		name := g.componentName(t)
+		if _, ok := g.schemas[name]; !ok {
+			g.schemas[name] = nil // placeholder for recursive types
+			g.schemas[name] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+	return map[string]interface{}{}
+}
+
+// componentName names the component of the type, with the package name if the type name is taken
+func (g *schemaGenerator) componentName(t reflect.Type) string {
+	name := t.Name()
+	if registered, ok := g.types[name]; ok && registered != t {
+		name = t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:] + "." + name
+	}
+	g.types[name] = t
+	return name
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	required := []string{}
+	g.addFields(t, properties, &required)
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// addFields adds the properties of the exported fields of the struct, and of its embedded structs
+func (g *schemaGenerator) addFields(t reflect.Type, properties map[string]interface{}, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				g.addFields(embedded, properties, required)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schema(field.Type)
+		if !strings.Contains(options, "omitempty") && field.Type.Kind() != reflect.Ptr {
+			*required = append(*required, name)
+		}
+	}
+}
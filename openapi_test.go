@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/armosec/host-sensor/sensor"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPI(t *testing.T) {
+	rw := httptest.NewRecorder()
+	openAPIHandler(rw, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	require.Equal(t, http.StatusOK, rw.Code)
+
+	document := struct {
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]struct {
+				Properties map[string]interface{} `json:"properties"`
+			} `json:"schemas"`
+		} `json:"components"`
+	}{}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &document))
+
+	for _, s := range sensor.Registered() {
+		if s.Name() == "batchTest" {
+			continue
+		}
+		assert.Contains(t, document.Paths, "/"+s.Name())
+	}
+	assert.Contains(t, document.Paths["/scan"], "post")
+	assert.Contains(t, document.Paths["/jobs/{id}"], "get")
+
+	for _, name := range []string{"FileInfo", "ControlPlaneInfo", "KubeletInfo", "Finding", "ScanReport", "SenseError"} {
+		assert.Contains(t, document.Components.Schemas, name)
+	}
+	assert.Contains(t, document.Components.Schemas["FileInfo"].Properties, "path")
+
+	// every reference resolves
+	for _, ref := range strings.Split(rw.Body.String(), `"$ref": "#/components/schemas/`)[1:] {
+		name := ref[:strings.Index(ref, `"`)]
+		assert.Contains(t, document.Components.Schemas, name)
+	}
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+)
+
+var (
+	errInvalidContinue = &sensor.SenseError{
+		Massage: "invalid continue token",
+		Kind:    "InvalidContinue",
+		Code:    http.StatusBadRequest,
+	}
+	errContinueExpired = &sensor.SenseError{
+		Massage: "continue token expired, the results changed since the previous page",
+		Kind:    "ContinueExpired",
+		Code:    http.StatusGone,
+	}
+)
+
+// paginatedLists are the lists of the sensor results which can be paginated, keyed by sensor name.
+// A result with multiple lists is paginated as their concatenation.
+var paginatedLists = map[string][]string{
+	"packages":         {"packages"},
+	"controlPlaneInfo": {"PKIFiles"},
+	"openedPorts":      {"tcpPorts", "udpPorts", "icmpPorts"},
+}
+
+// pageRequest selects the items [offset, offset+limit) of the paginated lists
+type pageRequest struct {
+	offset int
+
+	// A non-positive limit means no limit
+	limit int
+
+	// Time of the scan the continue token was issued for, empty if the results were sensed on demand
+	scanTime string
+}
+
+// parsePageRequest parses the `limit`, `continue` and `offset` parameters. It returns nil if none is set.
+func parsePageRequest(r *http.Request) (*pageRequest, error) {
+	query := r.URL.Query()
+	if query.Get("limit") == "" && query.Get("continue") == "" && query.Get("offset") == "" {
+		return nil, nil
+	}
+
+	page := &pageRequest{}
+	var err error
+	if page.limit, err = parsePaginationParam(r, "limit"); err != nil {
+		return nil, err
+	}
+	if token := query.Get("continue"); token != "" {
+		if page.offset, page.scanTime, err = decodeContinueToken(token); err != nil {
+			return nil, err
+		}
+	} else if page.offset, err = parsePaginationParam(r, "offset"); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// parsePaginationParam parses a non-negative integer parameter, 0 if missing
+func parsePaginationParam(r *http.Request, name string) (int, error) {
+	param := r.URL.Query().Get(name)
+	if param == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(param)
+	if err != nil || value < 0 {
+		return 0, &sensor.SenseError{
+			Massage: fmt.Sprintf("invalid %s %q", name, param),
+			Code:    http.StatusBadRequest,
+		}
+	}
+	return value, nil
+}
+
+// encodeContinueToken returns an opaque token of the offset of the next page, and the scan it belongs to
+func encodeContinueToken(offset int, scanTime string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(offset) + "/" + scanTime))
+}
+
+func decodeContinueToken(token string) (int, string, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, "", errInvalidContinue
+	}
+	offsetStr, scanTime, ok := strings.Cut(string(decoded), "/")
+	offset, err := strconv.Atoi(offsetStr)
+	if !ok || err != nil || offset < 0 {
+		return 0, "", errInvalidContinue
+	}
+	return offset, scanTime, nil
+}
+
+// paginate returns the result with the requested page of its lists. The `continue` token of the next page and the
+// `remainingItemCount` are added if there are more items, and `offset` if it isn't 0.
+func paginate(result []byte, lists []string, page *pageRequest, scanTime string) ([]byte, error) {
+	if page.scanTime != "" && page.scanTime != scanTime {
+		return nil, errContinueExpired
+	}
+
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(result, &fields); err != nil {
+		return nil, fmt.Errorf("failed to parse result: %w", err)
+	}
+
+	// the window of the page, relative to the current list
+	offset, remaining, total := page.offset, page.limit, 0
+	for _, list := range lists {
+		items := []json.RawMessage{}
+		if raw, ok := fields[list]; ok && string(raw) != "null" {
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", list, err)
+			}
+		}
+		total += len(items)
+
+		start := offset
+		if start > len(items) {
+			start = len(items)
+		}
+		end := len(items)
+		if page.limit > 0 && start+remaining < end {
+			end = start + remaining
+		}
+		offset -= start
+		remaining -= end - start
+
+		encoded, err := json.Marshal(items[start:end])
+		if err != nil {
+			return nil, err
+		}
+		fields[list] = encoded
+	}
+
+	if page.offset > 0 {
+		fields["offset"] = json.RawMessage(strconv.Itoa(page.offset))
+	}
+	if next := page.offset + page.limit; page.limit > 0 && next < total {
+		fields["continue"], _ = json.Marshal(encodeContinueToken(next, scanTime))
+		fields["remainingItemCount"] = json.RawMessage(strconv.Itoa(total - next))
+	}
+	return json.Marshal(fields)
+}
+
+// pageWriter buffers the response of a sensor handler, and writes the requested page of a successful result
+type pageWriter struct {
+	http.ResponseWriter
+	sensorName string
+	page       *pageRequest
+	status     int
+	body       bytes.Buffer
+}
+
+func (w *pageWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *pageWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// flush writes the buffered response, paginated if it succeeded
+func (w *pageWriter) flush() {
+	status, content := w.status, w.body.Bytes()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status == http.StatusOK {
+		paginated, err := paginate(content, paginatedLists[w.sensorName], w.page, w.Header().Get("X-Scan-Time"))
+		if err != nil {
+			writeSenseError(w.ResponseWriter, err, w.sensorName)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		content = append(paginated, '\n')
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+	if _, err := w.ResponseWriter.Write(content); err != nil {
+		zap.L().Error(fmt.Sprintf("In %s handler failed to write", w.sensorName), zap.Error(err))
+	}
+}
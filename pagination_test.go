@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaginate(t *testing.T) {
+	result := []byte(`{"name": "ports", "tcpPorts": [1, 2, 3], "udpPorts": [4, 5], "icmpPorts": null}`)
+	lists := []string{"tcpPorts", "udpPorts", "icmpPorts"}
+
+	page := struct {
+		Name               string `json:"name"`
+		TCPPorts           []int  `json:"tcpPorts"`
+		UDPPorts           []int  `json:"udpPorts"`
+		ICMPPorts          []int  `json:"icmpPorts"`
+		Offset             int    `json:"offset"`
+		Continue           string `json:"continue"`
+		RemainingItemCount int    `json:"remainingItemCount"`
+	}{}
+	paginated, err := paginate(result, lists, &pageRequest{limit: 2}, "")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(paginated, &page))
+	assert.Equal(t, "ports", page.Name)
+	assert.Equal(t, []int{1, 2}, page.TCPPorts)
+	assert.Empty(t, page.UDPPorts)
+	assert.Equal(t, 3, page.RemainingItemCount)
+
+	// the page spans two lists
+	next := &pageRequest{limit: 2}
+	next.offset, next.scanTime, err = decodeContinueToken(page.Continue)
+	require.NoError(t, err)
+	page.Continue = ""
+	paginated, err = paginate(result, lists, next, "")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(paginated, &page))
+	assert.Equal(t, []int{3}, page.TCPPorts)
+	assert.Equal(t, []int{4}, page.UDPPorts)
+	assert.Equal(t, 2, page.Offset)
+	assert.NotEmpty(t, page.Continue)
+
+	// the last page
+	page.Continue = ""
+	paginated, err = paginate(result, lists, &pageRequest{offset: 4, limit: 2}, "")
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(paginated, &page))
+	assert.Empty(t, page.TCPPorts)
+	assert.Equal(t, []int{5}, page.UDPPorts)
+	assert.Empty(t, page.Continue)
+
+	_, err = paginate(result, lists, &pageRequest{offset: 2, limit: 2, scanTime: "old"}, "new")
+	assert.Equal(t, errContinueExpired, err)
+}
+
+func TestPaginatedSensorHandler(t *testing.T) {
+	defer setConfig(getConfig())
+	setConfig(defaultConfig())
+
+	handler := withSensorEnabled("packages", func(rw http.ResponseWriter, r *http.Request) {
+		GenericSensorHandler(rw, r, map[string]interface{}{"total": 3, "packages": []string{"a", "b", "c"}}, nil, "packages")
+	})
+
+	rw := httptest.NewRecorder()
+	handler(rw, httptest.NewRequest(http.MethodGet, "/packages", nil))
+	assert.JSONEq(t, `{"total": 3, "packages": ["a", "b", "c"]}`, rw.Body.String())
+
+	rw = httptest.NewRecorder()
+	handler(rw, httptest.NewRequest(http.MethodGet, "/packages?limit=2&offset=1", nil))
+	require.Equal(t, http.StatusOK, rw.Code)
+	assert.JSONEq(t, `{"total": 3, "packages": ["b", "c"], "offset": 1}`, rw.Body.String())
+
+	rw = httptest.NewRecorder()
+	handler(rw, httptest.NewRequest(http.MethodGet, "/packages?limit=1&continue=notAToken", nil))
+	assert.Equal(t, http.StatusBadRequest, rw.Code)
+}
@@ -0,0 +1,392 @@
+package push
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// This package pushes reports to a remote collector, retrying with exponential backoff,
+// and spooling them to disk while the collector is unreachable.
+
+const (
+	// SignatureHeader holds the hex encoded HMAC-SHA256 of the request body, prefixed by "sha256="
+	SignatureHeader = "X-Host-Sensor-Signature"
+
+	// MessageHeader holds the kind of the pushed message, MessageReports or MessageHeartbeat
+	MessageHeader    = "X-Host-Sensor-Message"
+	MessageReports   = "reports"
+	MessageHeartbeat = "heartbeat"
+
+	spoolFilePrefix = "batch-"
+	spoolFileSuffix = ".json"
+	queueSize       = 64
+)
+
+// Options of a Pusher
+type Options struct {
+	// URL of the collector, reports are POSTed to it as a JSON array
+	URL string
+
+	// URL heartbeats are POSTed to as a JSON object. Defaults to URL
+	HeartbeatURL string
+
+	// Compress the request body with gzip
+	Compress bool
+
+	// Key for signing the request body with HMAC-SHA256, not signed if empty
+	SigningKey []byte
+
+	// Directory for spooling reports while the collector is unreachable, no spooling if empty
+	SpoolDir string
+
+	// Maximal number of spooled batches, the oldest batches are dropped. Defaults to 100
+	MaxSpoolFiles int
+
+	// Maximal number of reports in a request. Defaults to 10
+	BatchSize int
+
+	// Number of attempts to send a batch before spooling it. Defaults to 5
+	MaxAttempts int
+
+	// Backoff before the first retry, doubled on every retry up to MaxBackoff. Defaults to 1s and 1m
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	HTTPClient *http.Client
+}
+
+func (o *Options) setDefaults() {
+	if o.HeartbeatURL == "" {
+		o.HeartbeatURL = o.URL
+	}
+	if o.MaxSpoolFiles <= 0 {
+		o.MaxSpoolFiles = 100
+	}
+	if o.BatchSize <= 0 {
+		o.BatchSize = 10
+	}
+	if o.MaxAttempts <= 0 {
+		o.MaxAttempts = 5
+	}
+	if o.InitialBackoff <= 0 {
+		o.InitialBackoff = time.Second
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = time.Minute
+	}
+	if o.HTTPClient == nil {
+		o.HTTPClient = &http.Client{Timeout: 30 * time.Second}
+	}
+}
+
+// Pusher sends reports to a remote collector
+type Pusher struct {
+	opts  Options
+	queue chan json.RawMessage
+
+	// reports which were not sent yet
+	pending []json.RawMessage
+}
+
+// New creates a Pusher. Call `Run` to start sending the pushed reports.
+func New(opts Options) (*Pusher, error) {
+	opts.setDefaults()
+	if opts.URL == "" {
+		return nil, fmt.Errorf("collector URL is required")
+	}
+	if opts.SpoolDir != "" {
+		if err := os.MkdirAll(opts.SpoolDir, 0700); err != nil {
+			return nil, fmt.Errorf("failed to create spool dir: %w", err)
+		}
+	}
+	return &Pusher{opts: opts, queue: make(chan json.RawMessage, queueSize)}, nil
+}
+
+// Push queues a report for sending. It doesn't block, if the queue is full the report is dropped.
+func (p *Pusher) Push(report interface{}) error {
+	raw, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+
+	select {
+	case p.queue <- raw:
+		return nil
+	default:
+		return fmt.Errorf("push queue is full, report dropped")
+	}
+}
+
+// Run sends the queued reports until `ctx` is done. Call `Flush` then to send or spool the unsent reports.
+func (p *Pusher) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case report := <-p.queue:
+			p.pending = append(p.pending, report)
+		}
+
+		p.drainQueue()
+		p.sendAll(ctx)
+	}
+}
+
+// Flush sends the queued reports (and the spooled batches) until `ctx` is done, and spools the unsent reports.
+// It must not be called while `Run` is running.
+func (p *Pusher) Flush(ctx context.Context) {
+	p.drainQueue()
+	if len(p.pending) == 0 {
+		return
+	}
+	p.sendAll(ctx)
+	p.spool(p.pending)
+	p.pending = nil
+}
+
+// drainQueue moves all the queued reports to the pending reports
+func (p *Pusher) drainQueue() {
+	for {
+		select {
+		case report := <-p.queue:
+			p.pending = append(p.pending, report)
+		default:
+			return
+		}
+	}
+}
+
+// sendAll sends the spooled batches and then the pending reports.
+// If the collector is unreachable, the pending reports are spooled. They are kept pending if `ctx` is done.
+func (p *Pusher) sendAll(ctx context.Context) {
+	if !p.sendSpooled(ctx) {
+		if ctx.Err() != nil {
+			return
+		}
+		p.spool(p.pending)
+		p.pending = nil
+		return
+	}
+
+	for len(p.pending) > 0 {
+		size := p.opts.BatchSize
+		if size > len(p.pending) {
+			size = len(p.pending)
+		}
+
+		if err := p.sendWithRetry(ctx, p.pending[:size]); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			zap.L().Error("failed to push reports, spooling", zap.String("url", p.opts.URL), zap.Error(err))
+			p.spool(p.pending)
+			p.pending = nil
+			return
+		}
+		p.pending = p.pending[size:]
+	}
+}
+
+// sendWithRetry sends a batch, retrying with exponential backoff and jitter
+func (p *Pusher) sendWithRetry(ctx context.Context, batch []json.RawMessage) error {
+	backoff := p.opts.InitialBackoff
+	var err error
+	for attempt := 1; attempt <= p.opts.MaxAttempts; attempt++ {
+		if err = p.send(ctx, batch); err == nil {
+			return nil
+		}
+		if attempt == p.opts.MaxAttempts {
+			break
+		}
+
+		zap.L().Warn("failed to push reports, retrying",
+			zap.Int("attempt", attempt), zap.Duration("backoff", backoff), zap.Error(err))
+
+		jitter := time.Duration(rand.Int63n(int64(backoff)/2 + 1))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff + jitter):
+		}
+
+		backoff *= 2
+		if backoff > p.opts.MaxBackoff {
+			backoff = p.opts.MaxBackoff
+		}
+	}
+	return err
+}
+
+// Heartbeat sends a heartbeat to the collector, so it can tell a live sensor with unchanged reports from a dead one.
+// Heartbeats aren't retried nor spooled, the next heartbeat replaces a failed one.
+func (p *Pusher) Heartbeat(ctx context.Context, heartbeat interface{}) error {
+	body, err := json.Marshal(heartbeat)
+	if err != nil {
+		return fmt.Errorf("failed to marshal heartbeat: %w", err)
+	}
+	return p.post(ctx, p.opts.HeartbeatURL, MessageHeartbeat, body)
+}
+
+// send POSTs a batch of reports to the collector
+func (p *Pusher) send(ctx context.Context, batch []json.RawMessage) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	return p.post(ctx, p.opts.URL, MessageReports, body)
+}
+
+// post POSTs a message to the collector, compressed and signed as configured
+func (p *Pusher) post(ctx context.Context, url, message string, body []byte) error {
+
+	if p.opts.Compress {
+		buf := bytes.Buffer{}
+		gz := gzip.NewWriter(&buf)
+		if _, err := gz.Write(body); err != nil {
+			return err
+		}
+		if err := gz.Close(); err != nil {
+			return err
+		}
+		body = buf.Bytes()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(MessageHeader, message)
+	if p.opts.Compress {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if len(p.opts.SigningKey) > 0 {
+		req.Header.Set(SignatureHeader, "sha256="+Sign(p.opts.SigningKey, body))
+	}
+
+	resp, err := p.opts.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("collector responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of `body`
+func Sign(key, body []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// spool writes the reports to the spool dir in batches, dropping the oldest batches above the limit
+func (p *Pusher) spool(reports []json.RawMessage) {
+	if len(reports) == 0 {
+		return
+	}
+	if p.opts.SpoolDir == "" {
+		zap.L().Warn("spooling is disabled, dropping reports", zap.Int("count", len(reports)))
+		return
+	}
+
+	for start := 0; start < len(reports); start += p.opts.BatchSize {
+		end := start + p.opts.BatchSize
+		if end > len(reports) {
+			end = len(reports)
+		}
+		content, err := json.Marshal(reports[start:end])
+		if err != nil {
+			zap.L().Error("failed to marshal spooled reports", zap.Error(err))
+			continue
+		}
+		name := fmt.Sprintf("%s%020d%s", spoolFilePrefix, time.Now().UnixNano(), spoolFileSuffix)
+		if err := os.WriteFile(path.Join(p.opts.SpoolDir, name), content, 0600); err != nil {
+			zap.L().Error("failed to spool reports", zap.String("dir", p.opts.SpoolDir), zap.Error(err))
+		}
+	}
+
+	files, err := p.spooledFiles()
+	if err != nil {
+		zap.L().Error("failed to list spooled reports", zap.String("dir", p.opts.SpoolDir), zap.Error(err))
+		return
+	}
+	for len(files) > p.opts.MaxSpoolFiles {
+		zap.L().Warn("spool is full, dropping the oldest batch", zap.String("file", files[0]))
+		os.Remove(path.Join(p.opts.SpoolDir, files[0]))
+		files = files[1:]
+	}
+}
+
+// spooledFiles returns the spooled batch files, oldest first
+func (p *Pusher) spooledFiles() ([]string, error) {
+	entries, err := os.ReadDir(p.opts.SpoolDir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{}
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, spoolFilePrefix) && strings.HasSuffix(name, spoolFileSuffix) {
+			files = append(files, name)
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// sendSpooled sends the spooled batches, oldest first. It returns false if the collector is unreachable.
+func (p *Pusher) sendSpooled(ctx context.Context) bool {
+	if p.opts.SpoolDir == "" {
+		return true
+	}
+
+	files, err := p.spooledFiles()
+	if err != nil {
+		zap.L().Error("failed to list spooled reports", zap.String("dir", p.opts.SpoolDir), zap.Error(err))
+		return true
+	}
+
+	for _, name := range files {
+		filePath := path.Join(p.opts.SpoolDir, name)
+		batch := []json.RawMessage{}
+		content, err := os.ReadFile(filePath)
+		if err == nil {
+			err = json.Unmarshal(content, &batch)
+		}
+		if err != nil {
+			zap.L().Error("dropping invalid spooled batch", zap.String("file", filePath), zap.Error(err))
+			os.Remove(filePath)
+			continue
+		}
+
+		if err := p.sendWithRetry(ctx, batch); err != nil {
+			zap.L().Error("failed to push spooled reports", zap.String("url", p.opts.URL), zap.Error(err))
+			return false
+		}
+		os.Remove(filePath)
+	}
+
+	return true
+}
@@ -0,0 +1,161 @@
+package push
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCollector records the received reports, and fails while `down` is set
+type fakeCollector struct {
+	lock     sync.Mutex
+	down     bool
+	received []string
+}
+
+func (c *fakeCollector) handler(t *testing.T, key []byte) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+		if c.down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		if key != nil {
+			assert.Equal(t, "sha256="+Sign(key, body), r.Header.Get(SignatureHeader))
+		}
+
+		var reader io.Reader = bytes.NewReader(body)
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			reader, err = gzip.NewReader(reader)
+			require.NoError(t, err)
+		}
+		batch := []string{}
+		require.NoError(t, json.NewDecoder(reader).Decode(&batch))
+		c.received = append(c.received, batch...)
+	}
+}
+
+func (c *fakeCollector) setDown(down bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.down = down
+}
+
+func (c *fakeCollector) getReceived() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return append([]string{}, c.received...)
+}
+
+func TestPusherSend(t *testing.T) {
+	key := []byte("secret")
+	collector := &fakeCollector{}
+	srv := httptest.NewServer(collector.handler(t, key))
+	defer srv.Close()
+
+	p, err := New(Options{URL: srv.URL, Compress: true, SigningKey: key, BatchSize: 2})
+	require.NoError(t, err)
+
+	p.pending = []json.RawMessage{[]byte(`"a"`), []byte(`"b"`), []byte(`"c"`)}
+	p.sendAll(context.Background())
+	assert.Equal(t, []string{"a", "b", "c"}, collector.getReceived())
+	assert.Empty(t, p.pending)
+}
+
+func TestPusherSpool(t *testing.T) {
+	collector := &fakeCollector{down: true}
+	srv := httptest.NewServer(collector.handler(t, nil))
+	defer srv.Close()
+
+	p, err := New(Options{
+		URL:            srv.URL,
+		SpoolDir:       t.TempDir(),
+		MaxSpoolFiles:  2,
+		MaxAttempts:    2,
+		InitialBackoff: time.Millisecond,
+	})
+	require.NoError(t, err)
+
+	// collector is down - reports are spooled, the oldest batch is dropped
+	for _, report := range []string{`"a"`, `"b"`, `"c"`} {
+		p.pending = []json.RawMessage{[]byte(report)}
+		p.sendAll(context.Background())
+	}
+	files, err := p.spooledFiles()
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Empty(t, collector.getReceived())
+
+	// collector is up - spooled reports are sent first
+	collector.setDown(false)
+	p.pending = []json.RawMessage{[]byte(`"d"`)}
+	p.sendAll(context.Background())
+	assert.Equal(t, []string{"b", "c", "d"}, collector.getReceived())
+	files, err = p.spooledFiles()
+	require.NoError(t, err)
+	assert.Empty(t, files)
+}
+
+func TestPusherRun(t *testing.T) {
+	collector := &fakeCollector{}
+	srv := httptest.NewServer(collector.handler(t, nil))
+	defer srv.Close()
+
+	p, err := New(Options{URL: srv.URL})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		p.Run(ctx)
+		close(done)
+	}()
+
+	require.NoError(t, p.Push("a"))
+	assert.Eventually(t, func() bool { return len(collector.getReceived()) == 1 }, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+
+	// reports pushed after Run returned are sent by Flush
+	require.NoError(t, p.Push("b"))
+	p.Flush(context.Background())
+	assert.Equal(t, []string{"a", "b"}, collector.getReceived())
+}
+
+func TestPusherHeartbeat(t *testing.T) {
+	key := []byte("secret")
+	received := make(chan map[string]string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "/heartbeat", r.URL.Path)
+		assert.Equal(t, MessageHeartbeat, r.Header.Get(MessageHeader))
+		assert.Equal(t, "sha256="+Sign(key, body), r.Header.Get(SignatureHeader))
+		heartbeat := map[string]string{}
+		require.NoError(t, json.Unmarshal(body, &heartbeat))
+		received <- heartbeat
+	}))
+	defer srv.Close()
+
+	p, err := New(Options{URL: srv.URL + "/reports", HeartbeatURL: srv.URL + "/heartbeat", SigningKey: key})
+	require.NoError(t, err)
+	require.NoError(t, p.Heartbeat(context.Background(), map[string]string{"nodeName": "node-1"}))
+	assert.Equal(t, map[string]string{"nodeName": "node-1"}, <-received)
+
+	srv.Close()
+	assert.Error(t, p.Heartbeat(context.Background(), map[string]string{"nodeName": "node-1"}))
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/armosec/host-sensor/sensor"
+)
+
+// This file contains the limits of the requests rate and of the concurrent scans, globally and per client.
+
+const (
+	// Retry-After of the requests rejected since too many scans are running
+	scanRetryAfter = 5 * time.Second
+
+	// idle clients are forgotten, so the limiter won't grow with the clients
+	clientIdleTTL = 10 * time.Minute
+)
+
+var (
+	errTooManyRequests = &sensor.SenseError{
+		Massage: "too many requests",
+		Kind:    "TooManyRequests",
+		Code:    http.StatusTooManyRequests,
+	}
+	errTooManyScans = &sensor.SenseError{
+		Massage: "too many concurrent scans",
+		Kind:    "TooManyRequests",
+		Code:    http.StatusTooManyRequests,
+	}
+
+	// limiter limits the requests and the scans, nil if unlimited
+	limiter *requestLimiter
+)
+
+// tokenBucket allows `rate` events per second, in bursts of up to `rate` events
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate int, now time.Time) *tokenBucket {
+	return &tokenBucket{rate: float64(rate), tokens: float64(rate), last: now}
+}
+
+// take takes a token if available, otherwise it returns the time until one is
+func (b *tokenBucket) take(now time.Time) (bool, time.Duration) {
+	b.tokens = math.Min(b.rate, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// clientLimits are the requests rate and the running scans of a client
+type clientLimits struct {
+	requests *tokenBucket
+	scans    int
+	lastSeen time.Time
+}
+
+// requestLimiter limits the requests per second and the concurrent scans, globally and per client.
+// A zero limit means unlimited.
+type requestLimiter struct {
+	rate, clientRate         int
+	maxScans, maxClientScans int
+
+	lock     sync.Mutex
+	requests *tokenBucket
+	scans    int
+	clients  map[string]*clientLimits
+}
+
+func newRequestLimiter(conf *Config) *requestLimiter {
+	l := &requestLimiter{
+		rate:           conf.RateLimit,
+		clientRate:     conf.ClientRateLimit,
+		maxScans:       conf.MaxConcurrentScans,
+		maxClientScans: conf.MaxClientConcurrentScans,
+		clients:        map[string]*clientLimits{},
+	}
+	if l.rate > 0 {
+		l.requests = newTokenBucket(l.rate, time.Now())
+	}
+	return l
+}
+
+// client returns the limits of the client, forgetting the idle clients. Must be called with the lock held.
+func (l *requestLimiter) client(address string, now time.Time) *clientLimits {
+	client, ok := l.clients[address]
+	if !ok {
+		for key, idle := range l.clients {
+			if idle.scans == 0 && now.Sub(idle.lastSeen) > clientIdleTTL {
+				delete(l.clients, key)
+			}
+		}
+		client = &clientLimits{}
+		if l.clientRate > 0 {
+			client.requests = newTokenBucket(l.clientRate, now)
+		}
+		l.clients[address] = client
+	}
+	client.lastSeen = now
+	return client
+}
+
+// allowRequest returns true if the request is within the rates, otherwise the time until it would be
+func (l *requestLimiter) allowRequest(address string, now time.Time) (bool, time.Duration) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if client := l.client(address, now); client.requests != nil {
+		if ok, retryAfter := client.requests.take(now); !ok {
+			return false, retryAfter
+		}
+	}
+	if l.requests != nil {
+		return l.requests.take(now)
+	}
+	return true, 0
+}
+
+// acquireScan takes a scan slot of the client, it returns false if too many scans are running
+func (l *requestLimiter) acquireScan(address string) bool {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	client := l.client(address, time.Now())
+	if (l.maxScans > 0 && l.scans >= l.maxScans) || (l.maxClientScans > 0 && client.scans >= l.maxClientScans) {
+		return false
+	}
+	l.scans++
+	client.scans++
+	return true
+}
+
+func (l *requestLimiter) releaseScan(address string) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.scans--
+	if client, ok := l.clients[address]; ok {
+		client.scans--
+	}
+}
+
+// clientAddress returns the address identifying the client of the request for the limits
+func clientAddress(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+func writeTooManyRequests(rw http.ResponseWriter, err error, retryAfter time.Duration, name string) {
+	rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+	writeSenseError(rw, err, name)
+}
+
+// limitRequests is a middleware rejecting the requests above the rate limits (if enabled)
+func limitRequests(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	if limiter == nil || unauthenticatedPaths[r.URL.Path] {
+		next(rw, r)
+		return
+	}
+	if ok, retryAfter := limiter.allowRequest(clientAddress(r), time.Now()); !ok {
+		writeTooManyRequests(rw, errTooManyRequests, retryAfter, "limitRequests")
+		return
+	}
+	next(rw, r)
+}
+
+// acquireScanSlot takes a scan slot for the request, or responds with 429 if too many scans are running.
+// The returned release function must be called once the scan completes, it is nil if no slot was taken.
+func acquireScanSlot(rw http.ResponseWriter, r *http.Request, name string) func() {
+	if limiter == nil {
+		return func() {}
+	}
+	address := clientAddress(r)
+	if !limiter.acquireScan(address) {
+		writeTooManyRequests(rw, errTooManyScans, scanRetryAfter, name)
+		return nil
+	}
+	return func() { limiter.releaseScan(address) }
+}
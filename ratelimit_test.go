@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucket(t *testing.T) {
+	now := time.Now()
+	bucket := newTokenBucket(2, now)
+	ok, _ := bucket.take(now)
+	assert.True(t, ok)
+	ok, _ = bucket.take(now)
+	assert.True(t, ok)
+	ok, retryAfter := bucket.take(now)
+	assert.False(t, ok)
+	assert.Equal(t, 500*time.Millisecond, retryAfter)
+
+	ok, _ = bucket.take(now.Add(500 * time.Millisecond))
+	assert.True(t, ok)
+}
+
+func TestLimitRequests(t *testing.T) {
+	defer func() { limiter = nil }()
+	limiter = newRequestLimiter(&Config{ClientRateLimit: 1})
+
+	next := func(rw http.ResponseWriter, r *http.Request) { rw.WriteHeader(http.StatusOK) }
+	request := func(address, path string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.RemoteAddr = address
+		rw := httptest.NewRecorder()
+		limitRequests(rw, r, next)
+		return rw
+	}
+
+	assert.Equal(t, http.StatusOK, request("10.0.0.1:1234", "/kubeletInfo").Code)
+	rw := request("10.0.0.1:1235", "/kubeletInfo")
+	assert.Equal(t, http.StatusTooManyRequests, rw.Code)
+	assert.Equal(t, "1", rw.Header().Get("Retry-After"))
+
+	// other clients and unlimited paths
+	assert.Equal(t, http.StatusOK, request("10.0.0.2:1234", "/kubeletInfo").Code)
+	assert.Equal(t, http.StatusOK, request("10.0.0.1:1234", "/version").Code)
+}
+
+func TestAcquireScanSlot(t *testing.T) {
+	defer func() { limiter = nil }()
+	limiter = newRequestLimiter(&Config{MaxConcurrentScans: 2, MaxClientConcurrentScans: 1})
+
+	request := func(address string) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/kubeletInfo", nil)
+		r.RemoteAddr = address
+		return r
+	}
+
+	release := acquireScanSlot(httptest.NewRecorder(), request("10.0.0.1:1234"), "kubeletInfo")
+	require.NotNil(t, release)
+
+	// the client limit
+	rw := httptest.NewRecorder()
+	assert.Nil(t, acquireScanSlot(rw, request("10.0.0.1:1234"), "kubeletInfo"))
+	assert.Equal(t, http.StatusTooManyRequests, rw.Code)
+	assert.Equal(t, "5", rw.Header().Get("Retry-After"))
+
+	// the global limit
+	require.NotNil(t, acquireScanSlot(httptest.NewRecorder(), request("10.0.0.2:1234"), "kubeletInfo"))
+	assert.Nil(t, acquireScanSlot(httptest.NewRecorder(), request("10.0.0.3:1234"), "kubeletInfo"))
+
+	release()
+	assert.NotNil(t, acquireScanSlot(httptest.NewRecorder(), request("10.0.0.1:1234"), "kubeletInfo"))
+}
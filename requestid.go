@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"github.com/armosec/host-sensor/sensor"
+)
+
+// requestIDHeader holds the request ID of the responses, and of the requests forwarded by a proxy (or a peer sensor)
+const requestIDHeader = "X-Request-ID"
+
+// maximal length of a request ID set by the client
+const maxRequestIDLength = 64
+
+// newRequestID returns a random request ID
+func newRequestID() string {
+	id := make([]byte, 16)
+	rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// isValidRequestID returns true if the client request ID is safe to log, i.e. short and of ID characters only
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// identifyRequest assigns an ID to every request, the client's if set. The ID is added to the response headers,
+// and to the logs of the request and of the sensors it runs.
+func identifyRequest(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	id := r.Header.Get(requestIDHeader)
+	if !isValidRequestID(id) {
+		id = newRequestID()
+	}
+	rw.Header().Set(requestIDHeader, id)
+	next(rw, r.WithContext(sensor.WithRequestID(r.Context(), id)))
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/armosec/host-sensor/sensor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentifyRequest(t *testing.T) {
+	identify := func(clientID string) (string, string) {
+		r := httptest.NewRequest(http.MethodGet, "/kubeletInfo", nil)
+		if clientID != "" {
+			r.Header.Set(requestIDHeader, clientID)
+		}
+		rw := httptest.NewRecorder()
+		var contextID string
+		identifyRequest(rw, r, func(rw http.ResponseWriter, r *http.Request) { contextID = sensor.RequestID(r.Context()) })
+		return contextID, rw.Header().Get(requestIDHeader)
+	}
+
+	contextID, headerID := identify("")
+	assert.Len(t, contextID, 32)
+	assert.Equal(t, contextID, headerID)
+
+	contextID, headerID = identify("ingress-3f2a.1")
+	assert.Equal(t, "ingress-3f2a.1", contextID)
+	assert.Equal(t, "ingress-3f2a.1", headerID)
+
+	contextID, _ = identify("bad id\n")
+	assert.NotEqual(t, "bad id\n", contextID)
+	assert.Len(t, contextID, 32)
+}
+
+func TestBatchScanRequestID(t *testing.T) {
+	defer setConfig(getConfig())
+	setConfig(defaultConfig())
+
+	ctx := sensor.WithRequestID(sensingCtx, "req-1")
+	result := runBatchScan(ctx, &BatchScanRequest{Sensors: []BatchSensor{{Name: "batchTest"}}}, nil)
+	assert.Equal(t, "req-1", result.RequestID)
+}
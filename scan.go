@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/armosec/host-sensor/evaluation"
+	"github.com/armosec/host-sensor/k8s"
+	"github.com/armosec/host-sensor/observer"
+	"github.com/armosec/host-sensor/push"
+	"github.com/armosec/host-sensor/sensor"
+	"github.com/armosec/host-sensor/tracing"
+	"go.uber.org/zap"
+)
+
+// ScanReport holds the results of a full scan of all the enabled sensors
+type ScanReport struct {
+	// Time the scan started
+	Time time.Time `json:"time"`
+
+	// Identity of the sensor which made the scan
+	Identity Identity `json:"identity"`
+
+	// ID of the request which made the scan, or of the periodic scan. The logs of the scan are stamped with it.
+	RequestID string `json:"requestID,omitempty"`
+
+	// Metadata of the scanned node object (if enabled)
+	Node *NodeMetadata `json:"node,omitempty"`
+
+	// Whether the scan failed as a whole, e.g. its report couldn't be anonymized, the cause is in the errors
+	Failed bool `json:"failed,omitempty"`
+
+	// Results of the succeeded sensors, keyed by sensor name
+	Results map[string]json.RawMessage `json:"results"`
+
+	// Errors of the failed and disabled sensors, keyed by sensor name, and the error of a failed scan
+	Errors map[string]*sensor.SenseError `json:"errors,omitempty"`
+
+	// The items the sensors failed to collect (e.g. files which aren't readable), keyed by sensor name
+	CollectionErrors map[string][]sensor.CollectionError `json:"collectionErrors,omitempty"`
+
+	// The partial results, which the missing privileges of the sensor kept from being collected entirely,
+	// keyed by sensor name
+	Degraded map[string]*sensor.Degradation `json:"degraded,omitempty"`
+
+	// Durations and failures of the sensors, keyed by sensor name
+	Metrics map[string]*sensor.SenseMetrics `json:"metrics,omitempty"`
+
+	// Findings of the evaluation rules
+	Findings []evaluation.Finding `json:"findings"`
+
+	// Deviations of the files from the integrity baseline (if enabled)
+	Integrity []IntegrityDeviation `json:"integrity,omitempty"`
+
+	// Likely secrets in the collected file contents (if enabled)
+	Secrets []SecretLocation `json:"secrets,omitempty"`
+
+	// Events of the observed processes since the previous scan (if enabled)
+	Runtime *RuntimeObservations `json:"runtime,omitempty"`
+}
+
+// RuntimeObservations holds the events observed with eBPF between scans
+type RuntimeObservations struct {
+	Events []observer.Event `json:"events"`
+
+	// Number of events which were dropped, since the limit of events was reached
+	Dropped int `json:"dropped,omitempty"`
+}
+
+// scanHandler is called with the report of every periodic scan
+type scanHandler func(ctx context.Context, report *ScanReport)
+
+// NodeMetadata holds information about the scanned node, read from its Node object
+type NodeMetadata struct {
+	Name           string            `json:"name"`
+	UID            string            `json:"uid"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Taints         []k8s.Taint       `json:"taints,omitempty"`
+	ProviderID     string            `json:"providerID,omitempty"`
+	KubeletVersion string            `json:"kubeletVersion,omitempty"`
+}
+
+// rawResultSensors are the sensors whose endpoints respond with the raw content (the result is a string), rather than JSON
+var rawResultSensors = map[string]bool{
+	"osRelease":     true,
+	"kernelVersion": true,
+}
+
+// getNodeMetadata reads the node object from the API server
+func getNodeMetadata(ctx context.Context, client *k8s.Client, nodeName string) (*NodeMetadata, error) {
+	node := k8s.Node{}
+	if err := client.Get(ctx, k8s.NodePath(nodeName), &node); err != nil {
+		return nil, fmt.Errorf("failed to get node %s: %w", nodeName, err)
+	}
+
+	return &NodeMetadata{
+		Name:           node.Metadata.Name,
+		UID:            node.Metadata.UID,
+		Labels:         node.Metadata.Labels,
+		Taints:         node.Spec.Taints,
+		ProviderID:     node.Spec.ProviderID,
+		KubeletVersion: node.Status.NodeInfo.KubeletVersion,
+	}, nil
+}
+
+// runScan runs all the enabled sensors and collects their results. Only the `scheduled` (periodic) scans set the
+// initial integrity baseline and drain the runtime events, the scans on demand are compared with the baseline and
+// hold the events since the latest periodic scan.
+func runScan(ctx context.Context, scheduled bool) *ScanReport {
+	ctx, span := tracing.Start(ctx, "scan", tracing.KindInternal)
+	defer span.Finish()
+	if sensor.RequestID(ctx) == "" {
+		ctx = sensor.WithRequestID(ctx, newRequestID())
+	}
+
+	conf := getConfig()
+	report := &ScanReport{
+		Time:      time.Now().UTC(),
+		Identity:  conf.Identity,
+		RequestID: sensor.RequestID(ctx),
+		Results:   map[string]json.RawMessage{},
+		Errors:    map[string]*sensor.SenseError{},
+
+		CollectionErrors: map[string][]sensor.CollectionError{},
+		Degraded:         map[string]*sensor.Degradation{},
+		Metrics:          map[string]*sensor.SenseMetrics{},
+	}
+
+	if conf.NodeMetadata && kubeClient != nil {
+		node, err := getNodeMetadata(ctx, kubeClient, conf.Identity.NodeName)
+		if err != nil {
+			sensor.Logger(ctx).Error("failed to get node metadata", zap.Error(err))
+		}
+		report.Node = node
+	}
+
+	for _, s := range sensor.Registered() {
+		if conf.isSensorDisabled(s.Name()) {
+			report.Errors[s.Name()] = sensor.AsSenseError(sensor.ErrSensorDisabled, s.Name())
+			continue
+		}
+
+		var result json.RawMessage
+		var err error
+		var collectionErrors []sensor.CollectionError
+		report.Metrics[s.Name()], collectionErrors = sensor.Instrument(ctx, s.Name(), func(ctx context.Context) { result, err = s.Sense(ctx) })
+		sensorMetrics.observe(s.Name(), report.Metrics[s.Name()], errorStatus(err, s.Name()))
+		if len(collectionErrors) > 0 {
+			report.CollectionErrors[s.Name()] = collectionErrors
+		}
+		if degradation := sensor.Degraded(ctx, collectionErrors); degradation != nil {
+			report.Degraded[s.Name()] = degradation
+		}
+		if err != nil {
+			report.Errors[s.Name()] = sensor.AsSenseError(err, s.Name())
+			continue
+		}
+		report.Results[s.Name()] = result
+	}
+
+	if runtimeObserver != nil {
+		// only the periodic scans drain the events, so the scans on demand don't take them from the periodic reports
+		observed := runtimeObserver.Peek
+		if scheduled {
+			observed = runtimeObserver.Drain
+		}
+		events, dropped := observed()
+		report.Runtime = &RuntimeObservations{Events: events, Dropped: dropped}
+	}
+
+	report.Findings = evaluation.Evaluate(report.Results, conf.DisabledRules)
+
+	if integrity != nil {
+		report.Integrity = integrity.check(report.Results, report.Errors, scheduled)
+		if !containsString(conf.DisabledRules, integrityRuleID) {
+			report.Findings = append(report.Findings, integrityFindings(report.Integrity)...)
+		}
+	}
+
+	if conf.SecretScan {
+		report.Secrets = scanSecrets(report.Results)
+		if !containsString(conf.DisabledRules, secretRuleID) {
+			report.Findings = append(report.Findings, secretFindings(report.Secrets)...)
+		}
+	}
+	sort.SliceStable(report.Findings, func(i, j int) bool { return report.Findings[i].Key() < report.Findings[j].Key() })
+
+	// the findings were evaluated on the identifying values
+	if reportAnonymizer != nil {
+		if report = anonymizeReport(ctx, reportAnonymizer, report); report.Failed {
+			span.RecordError(report.Errors[anonymizationErrorKey])
+		}
+	}
+	return report
+}
+
+// anonymizeReport returns the anonymized scan report. If it fails, none of the report is kept and the returned report
+// is failed, with the error under `anonymizationErrorKey`.
+func anonymizeReport(ctx context.Context, a *anonymizer, report *ScanReport) *ScanReport {
+	anonymized := &ScanReport{}
+	if err := a.anonymizeInto(report, anonymized); err != nil {
+		sensor.Logger(ctx).Error("failed to anonymize scan report", zap.Error(err))
+		return &ScanReport{
+			Time:      report.Time,
+			RequestID: report.RequestID,
+			Failed:    true,
+			Results:   map[string]json.RawMessage{},
+			Errors:    map[string]*sensor.SenseError{anonymizationErrorKey: errAnonymizationFailed},
+			Findings:  []evaluation.Finding{},
+		}
+	}
+	return anonymized
+}
+
+// newPushScanHandler returns a scan handler pushing the reports to the collector
+func newPushScanHandler(pusher *push.Pusher) scanHandler {
+	return func(ctx context.Context, report *ScanReport) {
+		if err := pusher.Push(report); err != nil {
+			zap.L().Error("failed to push scan report", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/armosec/host-sensor/sensor"
+)
+
+// scanScheduler runs a full scan every interval (plus a random jitter, so the sensors of
+// a cluster won't scan all at once), keeps the latest report, and passes it to the handlers.
+type scanScheduler struct {
+	interval time.Duration
+	jitter   time.Duration
+	handlers []scanHandler
+
+	lock   sync.RWMutex
+	latest *ScanReport
+
+	// closed to stop scheduling scans, and when `run` returns
+	stopped  chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+var (
+	// scheduler runs the periodic scans, nil if periodic scans are disabled
+	scheduler *scanScheduler
+)
+
+func newScanScheduler(interval, jitter time.Duration, handlers ...scanHandler) *scanScheduler {
+	return &scanScheduler{
+		interval: interval,
+		jitter:   jitter,
+		handlers: handlers,
+		stopped:  make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// nextDelay returns the delay until the next scan
+func (s *scanScheduler) nextDelay() time.Duration {
+	return s.interval + s.randomJitter()
+}
+
+func (s *scanScheduler) randomJitter() time.Duration {
+	if s.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(s.jitter)))
+}
+
+// latestReport returns the report of the latest scan, nil if no scan completed yet
+func (s *scanScheduler) latestReport() *ScanReport {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.latest
+}
+
+// scan runs a full scan, stores it as the latest report and passes it to the handlers.
+// The progress of its sensors is streamed, if the events stream is enabled.
+func (s *scanScheduler) scan(ctx context.Context) {
+	if eventsStream != nil {
+		ctx = sensor.WithProgress(ctx, eventsStream.onProgress(time.Now().UTC()))
+	}
+	report := runScan(ctx, true)
+
+	s.lock.Lock()
+	s.latest = report
+	s.lock.Unlock()
+
+	for _, handler := range s.handlers {
+		handler(ctx, report)
+	}
+}
+
+// run scans periodically until `ctx` is done or the scheduler is stopped. The first scan starts after a random jitter.
+// The scans are cancelled with `ctx`.
+func (s *scanScheduler) run(ctx context.Context) {
+	defer close(s.done)
+	timer := time.NewTimer(s.randomJitter())
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopped:
+			return
+		case <-timer.C:
+		}
+
+		s.scan(ctx)
+		timer.Reset(s.nextDelay())
+	}
+}
+
+// stop stops scheduling scans, the running scan completes. `done` is closed once it does.
+func (s *scanScheduler) stop() {
+	s.stopOnce.Do(func() { close(s.stopped) })
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/armosec/host-sensor/sensor"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScanSchedulerNextDelay(t *testing.T) {
+	s := newScanScheduler(time.Hour, time.Minute)
+	for i := 0; i < 100; i++ {
+		delay := s.nextDelay()
+		assert.GreaterOrEqual(t, delay, time.Hour)
+		assert.Less(t, delay, time.Hour+time.Minute)
+	}
+
+	s = newScanScheduler(time.Hour, 0)
+	assert.Equal(t, time.Hour, s.nextDelay())
+}
+
+func TestScanSchedulerScan(t *testing.T) {
+	called := 0
+	s := newScanScheduler(time.Hour, 0, func(ctx context.Context, report *ScanReport) { called++ })
+	assert.Nil(t, s.latestReport())
+
+	s.scan(context.Background())
+	assert.NotNil(t, s.latestReport())
+	assert.Equal(t, 1, called)
+}
+
+func TestServeCachedResult(t *testing.T) {
+	defer func() { scheduler = nil }()
+	scheduler = newScanScheduler(time.Hour, 0)
+
+	rw := httptest.NewRecorder()
+	assert.False(t, serveCachedResult(rw, httptest.NewRequest(http.MethodGet, "/", nil), "osRelease"))
+
+	scheduler.latest = &ScanReport{
+		Time: time.Now(),
+		Results: map[string]json.RawMessage{
+			"osRelease":   json.RawMessage(`"NAME=\"Ubuntu\"\n"`),
+			"openedPorts": json.RawMessage(`{"tcpPorts":[]}`),
+		},
+		Errors: map[string]*sensor.SenseError{
+			"controlPlaneInfo": sensor.ErrNotControlPlane,
+		},
+	}
+
+	rw = httptest.NewRecorder()
+	assert.True(t, serveCachedResult(rw, httptest.NewRequest(http.MethodGet, "/", nil), "osRelease"))
+	assert.Equal(t, "NAME=\"Ubuntu\"\n", rw.Body.String())
+
+	rw = httptest.NewRecorder()
+	assert.True(t, serveCachedResult(rw, httptest.NewRequest(http.MethodGet, "/", nil), "openedPorts"))
+	assert.Equal(t, "application/json", rw.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"tcpPorts":[]}`, rw.Body.String())
+
+	rw = httptest.NewRecorder()
+	assert.True(t, serveCachedResult(rw, httptest.NewRequest(http.MethodGet, "/", nil), "controlPlaneInfo"))
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+
+	rw = httptest.NewRecorder()
+	assert.False(t, serveCachedResult(rw, httptest.NewRequest(http.MethodGet, "/", nil), "kubeletInfo"))
+}
+
+func TestServeCachedResultETag(t *testing.T) {
+	defer func() { scheduler = nil }()
+	scheduler = newScanScheduler(time.Hour, 0)
+	scheduler.latest = &ScanReport{
+		Time:    time.Now(),
+		Results: map[string]json.RawMessage{"openedPorts": json.RawMessage(`{"tcpPorts":[]}`)},
+	}
+
+	rw := httptest.NewRecorder()
+	assert.True(t, serveCachedResult(rw, httptest.NewRequest(http.MethodGet, "/openedPorts", nil), "openedPorts"))
+	etag := rw.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+
+	r := httptest.NewRequest(http.MethodGet, "/openedPorts", nil)
+	r.Header.Set("If-None-Match", `"other", W/`+etag)
+	rw = httptest.NewRecorder()
+	assert.True(t, serveCachedResult(rw, r, "openedPorts"))
+	assert.Equal(t, http.StatusNotModified, rw.Code)
+	assert.Empty(t, rw.Body.String())
+
+	// the result changed
+	scheduler.latest.Results["openedPorts"] = json.RawMessage(`{"tcpPorts":[{"port":22}]}`)
+	rw = httptest.NewRecorder()
+	assert.True(t, serveCachedResult(rw, r, "openedPorts"))
+	assert.Equal(t, http.StatusOK, rw.Code)
+	assert.NotEqual(t, etag, rw.Header().Get("ETag"))
+
+	r = httptest.NewRequest(http.MethodGet, "/scanReport", nil)
+	rw = httptest.NewRecorder()
+	scanReportHandler(rw, r)
+	r.Header.Set("If-None-Match", rw.Header().Get("ETag"))
+	rw = httptest.NewRecorder()
+	scanReportHandler(rw, r)
+	assert.Equal(t, http.StatusNotModified, rw.Code)
+}
+
+func TestScanSchedulerStop(t *testing.T) {
+	s := newScanScheduler(time.Hour, 0)
+	go s.run(context.Background())
+	s.stop()
+	s.stop()
+	select {
+	case <-s.done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("scheduler didn't stop")
+	}
+}
@@ -0,0 +1,154 @@
+package secrets
+
+import (
+	"bytes"
+	"encoding/base64"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// This package detects likely secrets in file contents, by patterns and by entropy.
+
+// Secret types
+const (
+	TypeAWSAccessKey = "awsAccessKey"
+	TypeAWSSecretKey = "awsSecretKey"
+	TypeJWT          = "jwt"
+	TypePrivateKey   = "privateKey"
+	TypeHighEntropy  = "highEntropy"
+)
+
+const (
+	// Minimal Shannon entropy (bits per character) of a high entropy string.
+	// Hex strings (e.g. hashes) have at most 4 bits per character, so they aren't reported.
+	minEntropy = 4.5
+
+	// Number of characters of a matched value kept in the redacted value
+	redactedPrefixLen = 4
+
+	redactedMask = "********"
+)
+
+var (
+	patterns = []struct {
+		secretType string
+		re         *regexp.Regexp
+	}{
+		{TypeAWSAccessKey, regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+		{TypeAWSSecretKey, regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+=]{40})\b`)},
+		{TypeJWT, regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)},
+	}
+
+	pemBlockRegexp = regexp.MustCompile(`(?s)-----BEGIN ([A-Z0-9 ]+)-----.*?-----END [A-Z0-9 ]+-----`)
+	// strings checked for entropy, shorter strings are ignored
+	candidateRegexp  = regexp.MustCompile(`[A-Za-z0-9+/=_-]{24,}`)
+	privateKeyMarker = []byte("PRIVATE KEY-----")
+)
+
+// Match is a likely secret in a content. The value itself is redacted.
+type Match struct {
+	// One of Type*
+	Type string `json:"type"`
+
+	// The lines range of the match, starting from 1
+	StartLine int `json:"startLine"`
+	EndLine   int `json:"endLine"`
+
+	// The prefix of the matched value, with the rest masked
+	Redacted string `json:"redacted"`
+}
+
+// Scan returns the likely secrets in the content, sorted by position.
+// PEM certificates are skipped, and the high entropy strings which are part of a pattern match aren't reported again.
+func Scan(content []byte) []Match {
+	matches := []Match{}
+
+	// byte ranges already reported or ignored, skipped by the entropy check
+	covered := [][]int{}
+
+	for _, loc := range pemBlockRegexp.FindAllSubmatchIndex(content, -1) {
+		covered = append(covered, loc[:2])
+		if strings.HasSuffix(string(content[loc[2]:loc[3]]), "PRIVATE KEY") {
+			matches = append(matches, newMatch(content, TypePrivateKey, loc[0], loc[1], "-----BEGIN "+string(content[loc[2]:loc[3]])+"-----"))
+		}
+	}
+
+	for _, pattern := range patterns {
+		for _, loc := range pattern.re.FindAllSubmatchIndex(content, -1) {
+			if isCovered(covered, loc[0], loc[1]) {
+				continue
+			}
+			covered = append(covered, loc[:2])
+			value := content[loc[0]:loc[1]]
+			if len(loc) >= 4 && loc[2] >= 0 {
+				value = content[loc[2]:loc[3]]
+			}
+			matches = append(matches, newMatch(content, pattern.secretType, loc[0], loc[1], redact(string(value))))
+		}
+	}
+
+	for _, loc := range candidateRegexp.FindAllIndex(content, -1) {
+		if isCovered(covered, loc[0], loc[1]) {
+			continue
+		}
+		value := content[loc[0]:loc[1]]
+		if entropy(value) < minEntropy {
+			continue
+		}
+
+		// base64 encoded PEM blocks, e.g. kubeconfig certificate data
+		if decoded, err := base64.StdEncoding.DecodeString(string(value)); err == nil && bytes.HasPrefix(decoded, []byte("-----BEGIN ")) {
+			if bytes.Contains(decoded, privateKeyMarker) {
+				matches = append(matches, newMatch(content, TypePrivateKey, loc[0], loc[1], redact(string(value))))
+			}
+			continue
+		}
+		matches = append(matches, newMatch(content, TypeHighEntropy, loc[0], loc[1], redact(string(value))))
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].StartLine < matches[j].StartLine })
+	return matches
+}
+
+func newMatch(content []byte, secretType string, start, end int, redacted string) Match {
+	startLine := bytes.Count(content[:start], []byte("\n")) + 1
+	return Match{
+		Type:      secretType,
+		StartLine: startLine,
+		EndLine:   startLine + bytes.Count(content[start:end], []byte("\n")),
+		Redacted:  redacted,
+	}
+}
+
+func isCovered(covered [][]int, start, end int) bool {
+	for _, r := range covered {
+		if start < r[1] && end > r[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// redact keeps the prefix of the value, and masks the rest
+func redact(value string) string {
+	if len(value) <= redactedPrefixLen*2 {
+		return redactedMask
+	}
+	return value[:redactedPrefixLen] + redactedMask
+}
+
+// entropy returns the Shannon entropy of the value, in bits per character
+func entropy(value []byte) float64 {
+	counts := map[byte]int{}
+	for _, c := range value {
+		counts[c]++
+	}
+	ret := 0.0
+	for _, count := range counts {
+		p := float64(count) / float64(len(value))
+		ret -= p * math.Log2(p)
+	}
+	return ret
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/armosec/host-sensor/evaluation"
+	"github.com/armosec/host-sensor/secrets"
+)
+
+// This file contains the optional pass over the file contents collected by the sensors, which detects likely secrets.
+
+const secretRuleID = "exposed-secret"
+
+// SecretLocation is a likely secret in the content of a file collected by a sensor
+type SecretLocation struct {
+	// Name of the sensor which collected the file
+	Sensor string `json:"sensor"`
+
+	Path string `json:"path"`
+
+	secrets.Match
+}
+
+// scanSecrets returns the likely secrets in the file contents of the scan results, sorted by path and line
+func scanSecrets(results map[string]json.RawMessage) []SecretLocation {
+	locations := []SecretLocation{}
+	for sensorName, result := range results {
+		var value interface{}
+		if err := json.Unmarshal(result, &value); err != nil {
+			continue
+		}
+		locations = append(locations, scanValueSecrets(sensorName, value)...)
+	}
+
+	sort.Slice(locations, func(i, j int) bool {
+		if locations[i].Path != locations[j].Path {
+			return locations[i].Path < locations[j].Path
+		}
+		if locations[i].StartLine != locations[j].StartLine {
+			return locations[i].StartLine < locations[j].StartLine
+		}
+		return locations[i].Sensor < locations[j].Sensor
+	})
+	return locations
+}
+
+// scanValueSecrets scans the contents of the file infos in a decoded JSON value.
+// File contents are encoded as base64 strings.
+func scanValueSecrets(sensorName string, value interface{}) []SecretLocation {
+	locations := []SecretLocation{}
+	switch typed := value.(type) {
+	case []interface{}:
+		for _, item := range typed {
+			locations = append(locations, scanValueSecrets(sensorName, item)...)
+		}
+	case map[string]interface{}:
+		filePath, _ := typed["path"].(string)
+		encoded, _ := typed["content"].(string)
+		if filePath != "" && encoded != "" {
+			content, err := base64.StdEncoding.DecodeString(encoded)
+			if err == nil {
+				for _, match := range secrets.Scan(content) {
+					locations = append(locations, SecretLocation{Sensor: sensorName, Path: filePath, Match: match})
+				}
+				return locations
+			}
+		}
+		for _, item := range typed {
+			locations = append(locations, scanValueSecrets(sensorName, item)...)
+		}
+	}
+	return locations
+}
+
+// secretFindings returns a finding for every likely secret
+func secretFindings(locations []SecretLocation) []evaluation.Finding {
+	findings := []evaluation.Finding{}
+	for _, location := range locations {
+		findings = append(findings, evaluation.Finding{
+			RuleID:   secretRuleID,
+			Severity: evaluation.SeverityHigh,
+			Sensor:   location.Sensor,
+			Path:     fmt.Sprintf("%s:%d", location.Path, location.StartLine),
+			Message: fmt.Sprintf("file %s contains a likely secret (%s) in lines %d-%d: %s",
+				location.Path, location.Type, location.StartLine, location.EndLine, location.Redacted),
+		})
+	}
+	return findings
+}
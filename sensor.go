@@ -3,11 +3,12 @@ package main
 import (
 	"bytes"
 	"context"
-	"crypto/tls"
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -15,6 +16,12 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/armosec/host-sensor/history"
+	"github.com/armosec/host-sensor/k8s"
+	"github.com/armosec/host-sensor/observer"
+	"github.com/armosec/host-sensor/push"
+	"github.com/armosec/host-sensor/sensor"
+	"github.com/armosec/host-sensor/tracing"
 	"github.com/codegangsta/negroni"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
@@ -25,30 +32,39 @@ type bodyLogWriter struct {
 	resBody *bytes.Buffer
 }
 
+// maximal size of a logged response body, e.g. of long lived streams
+const maxLoggedResponseSize = 64 * 1024
+
 // Common HTTP utils
 func (blw bodyLogWriter) Write(b []byte) (int, error) {
-	blw.resBody.Write(b)
+	if remaining := maxLoggedResponseSize - blw.resBody.Len(); remaining > 0 {
+		if len(b) > remaining {
+			blw.resBody.Write(b[:remaining])
+		} else {
+			blw.resBody.Write(b)
+		}
+	}
 	return blw.ResponseWriter.Write(b)
 }
 
 var (
+	// buildVersion is set at build time using `-ldflags "-X main.buildVersion=<version>"`
+	buildVersion = "dev"
+
 	zapLogger *zap.Logger
+
+	// kubeClient is the Kubernetes API client, initialized only if used by the enabled features
+	kubeClient *k8s.Client
+
+	// runtimeObserver observes the control plane processes between scans, nil if disabled
+	runtimeObserver *observer.Observer
 )
 
 func initLogger() *log.Logger {
 	var err error
-	// https://godoc.org/go.uber.org/zap#AtomicLevel.UnmarshalText
-	lvl := zap.NewAtomicLevel()
-	// if config.LogLevel == "" {
-	// 	config.LogLevel = "warn"
-	// }
-	logLevel := "debug"
-	if err := lvl.UnmarshalText([]byte(logLevel)); err != nil {
-		panic(err)
-	}
 	ec := zap.NewProductionEncoderConfig()
 	ec.EncodeTime = zapcore.RFC3339NanoTimeEncoder
-	zapConf := zap.Config{DisableCaller: true, DisableStacktrace: true, Level: lvl,
+	zapConf := zap.Config{DisableCaller: true, DisableStacktrace: true, Level: logLevel,
 		Encoding: "json", EncoderConfig: ec,
 		OutputPaths: []string{"stdout"}, ErrorOutputPaths: []string{"stderr"}}
 	// if config.LogFileName != "" { // empty string means the output is stdout
@@ -56,7 +72,11 @@ func initLogger() *log.Logger {
 	// 	zapConf.ErrorOutputPaths = append(zapConf.ErrorOutputPaths, config.LogFileName)
 	// }
 
-	zapLogger, err = zapConf.Build()
+	// the recent logs are kept for the debug bundle
+	keepRecentLogs := zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return zapcore.NewTee(core, zapcore.NewCore(zapcore.NewJSONEncoder(ec), recentLogs, logLevel))
+	})
+	zapLogger, err = zapConf.Build(keepRecentLogs)
 	if err != nil {
 		panic(err)
 	}
@@ -72,7 +92,14 @@ func initHTTPRouter() http.Handler {
 	nLogger.SetFormat("{{.StartTime}} | {{.Status}} | \t {{.Duration}} | {{.Hostname}} | {{.Method}}" + " {{.Request.RequestURI}}")
 	negroniRouter.Use(negroni.NewRecovery())
 	negroniRouter.Use(nLogger)
+	negroniRouter.UseFunc(identifyRequest)
 	negroniRouter.UseFunc(filterNLogHTTPErrors)
+	negroniRouter.UseFunc(stampIdentityHeaders)
+	negroniRouter.UseFunc(traceRequest)
+	negroniRouter.UseFunc(limitRequests)
+	negroniRouter.UseFunc(allowlistClient)
+	negroniRouter.UseFunc(authenticateRequest)
+	negroniRouter.UseFunc(auditRequest)
 	negroniRouter.UseHandler(http.DefaultServeMux)
 	return negroniRouter
 }
@@ -83,6 +110,7 @@ func filterNLogHTTPErrors(rw http.ResponseWriter, r *http.Request, next http.Han
 	zapArr := []zapcore.Field{zap.String("method", r.Method),
 		zap.String("requestURI", r.RequestURI),
 		zap.String("remoteAddr", r.RemoteAddr),
+		zap.String("requestID", sensor.RequestID(r.Context())),
 	}
 	if !strings.HasPrefix(r.RequestURI, "/isAlive") {
 		zap.L().Debug("In filterNLogHTTPErrors", zapArr...)
@@ -105,7 +133,9 @@ func filterNLogHTTPErrors(rw http.ResponseWriter, r *http.Request, next http.Han
 	} else {
 		next(blw, r)
 	}
-	if blw.Status() < 200 || blw.Status() >= 300 || startTime.Before(time.Now().Add(time.Second*50*(-1))) {
+	// streams are long lived by design
+	slow := startTime.Before(time.Now().Add(time.Second*50*(-1))) && blw.Header().Get("Content-Type") != "text/event-stream"
+	if blw.Status() < 200 || blw.Status() >= 300 || slow {
 		zapLogger.With(append(zapArr,
 			zap.Timep("requestStartTime", &startTime),
 			zap.String("Request body", string(bodyBuffer)),
@@ -118,18 +148,185 @@ func filterNLogHTTPErrors(rw http.ResponseWriter, r *http.Request, next http.Han
 	}
 }
 
+// stampIdentityHeaders adds the sensor identity headers to every response
+func stampIdentityHeaders(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	identity := getConfig().Identity
+	if identity.NodeName != "" {
+		rw.Header().Set("X-Node-Name", identity.NodeName)
+	}
+	if identity.PodName != "" {
+		rw.Header().Set("X-Pod-Name", identity.PodName)
+	}
+	if identity.PodNamespace != "" {
+		rw.Header().Set("X-Pod-Namespace", identity.PodNamespace)
+	}
+	next(rw, r)
+}
+
 // main
 func main() {
+	fixtureRoot := flag.String("fixture-root", "", "Point the sensors at a recorded host fixture directory, instead of the host")
+	recordFixture := flag.String("record-fixture", "", "Record a fixture of the host into a directory, and exit")
+	flag.Parse()
+
 	fmt.Println("Starting Kubescape cluster node host scanner service")
 	baseLogger := initLogger()
 	negroniRouter := initHTTPRouter()
 
 	defer zapLogger.Sync()
 
+	conf, err := loadConfigFromEnv()
+	if err != nil {
+		zap.L().Error("failed to load configuration", zap.Error(err))
+		zapLogger.Sync()
+		os.Exit(exitCodeGeneralError)
+	}
+	conf.Generation = 1
+	setConfig(conf)
+	logLevel.SetLevel(conf.LogLevel)
+
+	// stamp the sensor identity into every log line
+	zapLogger = zapLogger.With(conf.Identity.logFields()...)
+	zap.ReplaceGlobals(zapLogger)
+
+	configCtx, configCancel := context.WithCancel(context.Background())
+	defer configCancel()
+	if conf.ConfigFile != "" {
+		watcher := newConfigFileWatcher(conf)
+		if _, err := watcher.load(); err != nil {
+			zap.L().Error("failed to load config file", zap.String("path", conf.ConfigFile), zap.Error(err))
+			zapLogger.Sync()
+			os.Exit(exitCodeGeneralError)
+		}
+		go watcher.watch(configCtx)
+	}
+
+	if *fixtureRoot != "" {
+		if err := sensor.UseFixtureRoot(*fixtureRoot); err != nil {
+			zap.L().Error("failed to use the fixture", zap.String("path", *fixtureRoot), zap.Error(err))
+			zapLogger.Sync()
+			os.Exit(exitCode(err))
+		}
+		zap.L().Info("sensing a fixture", zap.String("path", *fixtureRoot))
+	} else {
+		access := sensor.DetectHostAccess(conf.HostRoot)
+		detectedHostAccess = &access
+		zap.L().Info("host access detected", zap.String("mode", access.Mode), zap.String("root", access.Root),
+			zap.String("processAccess", access.ProcessAccess))
+	}
+
+	if *recordFixture != "" {
+		if err := sensor.RecordFixture(context.Background(), *recordFixture); err != nil {
+			zap.L().Error("failed to record the fixture", zap.String("path", *recordFixture), zap.Error(err))
+			zapLogger.Sync()
+			os.Exit(exitCodeGeneralError)
+		}
+		zap.L().Info("fixture recorded", zap.String("path", *recordFixture))
+		zapLogger.Sync()
+		os.Exit(exitCodeOK)
+	}
+
+	if err := sensor.CheckHostRoot(); err != nil {
+		zap.L().Error("host file system check failed", zap.Error(err))
+		zapLogger.Sync()
+		os.Exit(exitCode(err))
+	}
+
+	if missing := sensor.MissingPrivileges(context.Background()); len(missing) > 0 {
+		zap.L().Warn("running without all the privileges, results may be partial", zap.Strings("missing", missing))
+	}
+
+	if conf.PluginsDir != "" {
+		registerPlugins(conf.PluginsDir, conf.PluginTimeout)
+	}
+
+	if conf.Integrity {
+		if integrity, err = newIntegrityMonitor(conf.IntegrityBaselineFile); err != nil {
+			zap.L().Error("failed to create integrity monitor", zap.Error(err))
+			zapLogger.Sync()
+			os.Exit(exitCodeGeneralError)
+		}
+		if len(conf.IntegrityPaths) > 0 {
+			sensor.Register(newIntegrityFilesSensor(conf.IntegrityPaths))
+		}
+		sensor.SetFileHashing(true)
+	}
+
+	if conf.Anonymize != "" {
+		// the identifiers of the node are gathered once, they don't change while the sensor runs
+		nodeIDs := gatherNodeIdentifiers(context.Background(), conf.Identity.NodeName)
+		if reportAnonymizer, err = newAnonymizer(conf.Anonymize, conf.AnonymizeKeyFile, nodeIDs); err != nil {
+			zap.L().Error("failed to create anonymizer", zap.Error(err))
+			zapLogger.Sync()
+			os.Exit(exitCodeGeneralError)
+		}
+	}
+
+	if conf.RuntimeObserver {
+		obs := observer.New(observer.Options{
+			Comms:        conf.RuntimeObserverProcesses,
+			PathPatterns: conf.RuntimeObserverPaths,
+		})
+		// the observer is optional, since it depends on the kernel and the capabilities of the sensor
+		if err := obs.Start(); err != nil {
+			zap.L().Error("failed to start eBPF runtime observer", zap.Error(err))
+		} else {
+			runtimeObserver = obs
+			defer obs.Close()
+		}
+	}
+
+	if conf.needsKubeClient() {
+		if kubeClient, err = k8s.NewInClusterClient(); err != nil {
+			zap.L().Error("failed to create kubernetes client", zap.Error(err))
+		}
+	}
+
+	if conf.AuthMode == authModeTokenReview {
+		// never serve unauthorized requests if authorization was requested
+		if kubeClient == nil {
+			zap.L().Error("token review authorization requires a kubernetes client")
+			zapLogger.Sync()
+			os.Exit(exitCodeGeneralError)
+		}
+		authorizer = newTokenReviewAuthorizer(kubeClient)
+	}
+	if conf.AuditLog {
+		auditTrail = newAuditLog()
+	}
+	limiter = newRequestLimiter(conf)
+	if tracer = newTracer(conf); tracer != nil {
+		tracing.SetTracer(tracer)
+	}
+
+	if conf.HistoryFile != "" {
+		if historyStore, err = history.Open(conf.HistoryFile, conf.HistorySize); err != nil {
+			zap.L().Error("failed to open scans history", zap.String("path", conf.HistoryFile), zap.Error(err))
+			zapLogger.Sync()
+			os.Exit(exitCodeGeneralError)
+		}
+		defer historyStore.Close()
+	}
+
+	listeningPort := 7888
+	if conf.Aggregator {
+		if kubeClient == nil {
+			zap.L().Error("aggregator mode requires a kubernetes client")
+			zapLogger.Sync()
+			os.Exit(exitCodeGeneralError)
+		}
+		peerTLSConfig, err := newPeerTLSConfig(conf)
+		if err != nil {
+			zap.L().Error("failed to configure the aggregator TLS", zap.Error(err))
+			zapLogger.Sync()
+			os.Exit(exitCodeGeneralError)
+		}
+		aggregator = newPeerAggregator(kubeClient, conf.AggregatorSelector, listeningPort, peerTLSConfig)
+	}
+
 	sensorManagerAddress := os.Getenv("ARMO_SENSORS_MANAGER")
 	connectSensorsManagerWebSocket(sensorManagerAddress)
 	initHTTPHandlers()
-	listeningPort := 7888
 	zapLogger.Info("Listening...", zap.Int("port", listeningPort))
 	if strings.Contains(os.Getenv("CADB_DEBUG"), "pprof") {
 		fmt.Println("Debug mode - pprof on")
@@ -139,27 +336,116 @@ func main() {
 		}()
 	}
 	listenAddress := fmt.Sprintf(":%d", listeningPort)
-	server := http.Server{Addr: listenAddress, Handler: negroniRouter, ErrorLog: baseLogger, TLSConfig: &tls.Config{}}
+	tlsConfig, err := newServerTLSConfig(conf)
+	if err != nil {
+		zap.L().Error("failed to configure TLS", zap.Error(err))
+		zapLogger.Sync()
+		os.Exit(exitCodeGeneralError)
+	}
+	server := http.Server{Addr: listenAddress, Handler: negroniRouter, ErrorLog: baseLogger, TLSConfig: tlsConfig,
+		BaseContext: func(net.Listener) context.Context { return sensingCtx }}
 
+	scanHandlers := []scanHandler{}
+	if conf.PublishCRD && kubeClient != nil {
+		publisher := &crdPublisher{client: kubeClient, nodeName: conf.Identity.NodeName}
+		scanHandlers = append(scanHandlers, publisher.onScan)
+	}
+	if conf.EmitEvents && kubeClient != nil {
+		scanHandlers = append(scanHandlers, newNodeEventRecorder(kubeClient, conf.Identity.NodeName).onScan)
+	}
+	if conf.EventStream {
+		eventsStream = newEventStream()
+		scanHandlers = append(scanHandlers, eventsStream.onScan)
+	}
+
+	var pushes *pushRunner
+	if conf.Push.URL != "" {
+		pusher, err := newPusher(&conf.Push)
+		if err != nil {
+			zap.L().Error("failed to create reports pusher", zap.Error(err))
+			zapLogger.Sync()
+			os.Exit(exitCodeGeneralError)
+		}
+		pushes = startPusher(pusher, conf.Push.HeartbeatInterval)
+		scanHandlers = append(scanHandlers, newPushScanHandler(pusher))
+	}
+	for i := range conf.Webhooks {
+		webhook, err := newWebhook(&conf.Webhooks[i])
+		if err != nil {
+			zap.L().Error("failed to create webhook", zap.String("format", conf.Webhooks[i].Format), zap.Error(err))
+			zapLogger.Sync()
+			os.Exit(exitCodeGeneralError)
+		}
+		scanHandlers = append(scanHandlers, newWebhookScanHandler(webhook, conf.Webhooks[i].Format))
+	}
+	if historyStore != nil {
+		scanHandlers = append(scanHandlers, newHistoryScanHandler(historyStore))
+	}
+	if len(scanHandlers) > 0 || conf.ServeCached {
+		scheduler = newScanScheduler(conf.ScanInterval, conf.ScanJitter, scanHandlers...)
+		go scheduler.run(sensingCtx)
+	}
+
+	// serve only once the scheduler and the event stream the handlers read are set
 	go func() {
-		server.ListenAndServe()
+		var err error
+		if conf.TLSCertFile != "" {
+			err = server.ListenAndServeTLS(conf.TLSCertFile, conf.TLSKeyFile)
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			zap.L().Error("failed to serve", zap.Error(err))
+			zapLogger.Sync()
+			os.Exit(exitCodeGeneralError)
+		}
 	}()
 
 	termChan := make(chan os.Signal, 1)
 	//  os.Kill,syscall.SIGKILL, cannot be trapped
 	signal.Notify(termChan, os.Interrupt, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP, syscall.SIGQUIT)
 	<-termChan // Blocks here until either SIGINT or SIGTERM is received.
-	zap.L().Warn("signal received")
-	ctx, ctxCancel := context.WithTimeout(context.Background(), 61*time.Second)
-	defer ctxCancel()
-	if err := server.Shutdown(ctx); err != nil {
-		zap.L().Error("HTTP shutdown error", zap.Error(err))
-	}
+	zap.L().Warn("signal received", zap.Duration("gracePeriod", conf.ShutdownGracePeriod))
+	gracefulShutdown(conf.ShutdownGracePeriod, &server, pushes)
 
 	zap.L().Warn("shutdown gracefully")
 
 }
 
+// registerPlugins registers the exec sensor plugins in `dir`
+func registerPlugins(dir string, timeout time.Duration) {
+	plugins, err := sensor.LoadExecSensors(dir, timeout)
+	if err != nil {
+		zap.L().Error("failed to load plugins", zap.String("path", dir), zap.Error(err))
+		return
+	}
+	for _, plugin := range plugins {
+		if err := sensor.Register(plugin); err != nil {
+			zap.L().Error("failed to register plugin", zap.String("plugin", plugin.Name()), zap.Error(err))
+			continue
+		}
+		zap.L().Info("registered plugin", zap.String("plugin", plugin.Name()))
+	}
+}
+
+// newPusher creates the pusher of scan reports to the remote collector
+func newPusher(conf *PushConfig) (*push.Pusher, error) {
+	opts := push.Options{
+		URL:          conf.URL,
+		HeartbeatURL: conf.HeartbeatURL,
+		Compress:     conf.Compress,
+		SpoolDir:     conf.SpoolDir,
+	}
+	if conf.SigningKeyFile != "" {
+		key, err := os.ReadFile(conf.SigningKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+		opts.SigningKey = bytes.TrimSpace(key)
+	}
+	return push.New(opts)
+}
+
 func connectSensorsManagerWebSocket(sensorManagerAddress string) {
 	zap.L().Warn("Not implemented")
 }
@@ -0,0 +1,267 @@
+package sensor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"go.uber.org/zap"
+)
+
+// The NVIDIA container toolkit injects the GPUs into the containers with a runtime hook, outside of the device
+// isolation of the runtime. Its settings decide which containers can ask for GPUs, and whether the device cgroup
+// still applies.
+
+// Accelerator vendors
+const (
+	AcceleratorNVIDIA = "nvidia"
+	AcceleratorAMD    = "amd"
+)
+
+// The risky settings of the NVIDIA container toolkit
+const (
+	// The toolkit doesn't add the GPUs to the device cgroup of the containers
+	NVIDIAToolkitRiskNoCgroups = "noCgroups"
+
+	// Any unprivileged container gets the GPUs of its NVIDIA_VISIBLE_DEVICES variable, e.g. "all", regardless of the
+	// GPUs the kubelet allocated to it
+	NVIDIAToolkitRiskEnvvarUnprivileged = "envvarWhenUnprivileged"
+
+	// The nvidia runtime is the default runtime, so every container goes through the toolkit hook
+	NVIDIAToolkitRiskDefaultRuntime = "defaultRuntime"
+)
+
+const (
+	nvidiaDriverVersionPath = "/proc/driver/nvidia/version"
+	nvidiaModuleDir         = "/sys/module/nvidia"
+	amdgpuModuleDir         = "/sys/module/amdgpu"
+
+	nvidiaRuntimeName = "nvidia"
+)
+
+var (
+	// The configs of the NVIDIA container toolkit, of the packages and of the GPU operator
+	nvidiaToolkitConfigPaths = []string{
+		"/etc/nvidia-container-runtime/config.toml",
+		"/usr/local/nvidia/toolkit/.config/nvidia-container-runtime/config.toml",
+	}
+
+	// The names of the accelerator device plugin sockets contain one of these
+	acceleratorPluginNames = []string{"nvidia", "amd", "gpu", "neuron", "habana"}
+
+	nvidiaDriverVersionRegexp = regexp.MustCompile(`Kernel Module\s+(\S+)`)
+)
+
+// AcceleratorInfo holds the GPU drivers of the node, the NVIDIA container toolkit config, and the accelerator device
+// plugins
+type AcceleratorInfo struct {
+	// The loaded accelerator drivers
+	Drivers []AcceleratorDriver `json:"drivers"`
+
+	// The NVIDIA container toolkit, nil if it isn't installed
+	NVIDIAToolkit *NVIDIAToolkit `json:"nvidiaToolkit,omitempty"`
+
+	// The sockets of the accelerator device plugins registered with the kubelet
+	DevicePlugins []*FileInfo `json:"devicePlugins"`
+}
+
+// AcceleratorDriver is a loaded GPU driver
+type AcceleratorDriver struct {
+	// One of Accelerator*
+	Vendor string `json:"vendor"`
+
+	// The kernel module, e.g. "nvidia" or "amdgpu"
+	Module string `json:"module"`
+
+	// The driver version, empty for a driver of the kernel tree
+	Version string `json:"version,omitempty"`
+}
+
+// NVIDIAToolkit holds the settings of the NVIDIA container toolkit
+type NVIDIAToolkit struct {
+	ConfigFile *FileInfo `json:"configFile"`
+
+	// The device cgroup setting of nvidia-container-cli
+	NoCgroups bool `json:"noCgroups"`
+
+	// Whether the unprivileged containers get the GPUs of their NVIDIA_VISIBLE_DEVICES variable (the default), or
+	// of their volume mounts under /var/run/nvidia-container-devices
+	AcceptEnvvarWhenUnprivileged bool `json:"acceptEnvvarWhenUnprivileged"`
+	AcceptVolumeMounts           bool `json:"acceptVolumeMounts"`
+
+	// The user and the mode of nvidia-container-runtime, e.g. "root:video" and "auto", "legacy" or "cdi"
+	User string `json:"user,omitempty"`
+	Mode string `json:"mode,omitempty"`
+
+	// The container runtimes whose default runtime is nvidia, e.g. "containerd" or "docker"
+	DefaultRuntimeOf []string `json:"defaultRuntimeOf"`
+
+	// The risky settings, NVIDIAToolkitRisk*
+	Risks []string `json:"risks"`
+
+	// The parse error of the config, empty if it was parsed
+	Error string `json:"error,omitempty"`
+}
+
+// parseNVIDIADriverVersion parses the version of /proc/driver/nvidia/version, e.g.
+// "NVRM version: NVIDIA UNIX x86_64 Kernel Module  535.104.05  Sat Aug 19 01:15:15 UTC 2023"
+func parseNVIDIADriverVersion(content []byte) string {
+	if match := nvidiaDriverVersionRegexp.FindSubmatch(content); match != nil {
+		return string(match[1])
+	}
+	return ""
+}
+
+// parseNVIDIAToolkitConfig parses the settings of a config of the NVIDIA container toolkit
+func parseNVIDIAToolkitConfig(content []byte, toolkit *NVIDIAToolkit) error {
+	config := struct {
+		AcceptEnvvarWhenUnprivileged *bool `toml:"accept-nvidia-visible-devices-envvar-when-unprivileged"`
+		AcceptVolumeMounts           bool  `toml:"accept-nvidia-visible-devices-as-volume-mounts"`
+		CLI                          struct {
+			NoCgroups bool   `toml:"no-cgroups"`
+			User      string `toml:"user"`
+		} `toml:"nvidia-container-cli"`
+		Runtime struct {
+			Mode string `toml:"mode"`
+		} `toml:"nvidia-container-runtime"`
+	}{}
+	// the toolkit accepts the variable of the unprivileged containers by default
+	toolkit.AcceptEnvvarWhenUnprivileged = true
+	if _, err := toml.Decode(string(content), &config); err != nil {
+		return err
+	}
+	if config.AcceptEnvvarWhenUnprivileged != nil {
+		toolkit.AcceptEnvvarWhenUnprivileged = *config.AcceptEnvvarWhenUnprivileged
+	}
+	toolkit.AcceptVolumeMounts = config.AcceptVolumeMounts
+	toolkit.NoCgroups, toolkit.User, toolkit.Mode = config.CLI.NoCgroups, config.CLI.User, config.Runtime.Mode
+	return nil
+}
+
+// nvidiaToolkitRisks returns the risky settings of the toolkit
+func nvidiaToolkitRisks(toolkit *NVIDIAToolkit) []string {
+	risks := []string{}
+	if toolkit.NoCgroups {
+		risks = append(risks, NVIDIAToolkitRiskNoCgroups)
+	}
+	if toolkit.AcceptEnvvarWhenUnprivileged {
+		risks = append(risks, NVIDIAToolkitRiskEnvvarUnprivileged)
+	}
+	if len(toolkit.DefaultRuntimeOf) > 0 {
+		risks = append(risks, NVIDIAToolkitRiskDefaultRuntime)
+	}
+	return risks
+}
+
+// containerdDefaultRuntime returns the default runtime of the CRI plugin of a containerd config
+func containerdDefaultRuntime(content []byte) string {
+	config := struct {
+		Plugins map[string]struct {
+			Containerd struct {
+				DefaultRuntimeName string `toml:"default_runtime_name"`
+			} `toml:"containerd"`
+		} `toml:"plugins"`
+	}{}
+	if _, err := toml.Decode(string(content), &config); err != nil {
+		return ""
+	}
+	cri, ok := config.Plugins[containerdConfigSection]
+	if !ok {
+		cri = config.Plugins[containerdConfigSectionV1]
+	}
+	return cri.Containerd.DefaultRuntimeName
+}
+
+// dockerDefaultRuntime returns the default runtime of a docker daemon config
+func dockerDefaultRuntime(content []byte) string {
+	config := struct {
+		DefaultRuntime string `json:"default-runtime"`
+	}{}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return ""
+	}
+	return config.DefaultRuntime
+}
+
+// senseNVIDIAToolkit returns the settings of the NVIDIA container toolkit, nil if it isn't installed
+func senseNVIDIAToolkit(ctx context.Context) *NVIDIAToolkit {
+	for _, configPath := range nvidiaToolkitConfigPaths {
+		content, err := ReadFileOnHostFileSystem(ctx, configPath)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				logger(ctx).Debug("failed to read NVIDIA container toolkit config", zap.String("path", configPath), zap.Error(err))
+			}
+			continue
+		}
+
+		toolkit := &NVIDIAToolkit{DefaultRuntimeOf: []string{}}
+		if toolkit.ConfigFile, err = makeHostFileStatInfo(ctx, configPath); err != nil {
+			logger(ctx).Debug("failed to stat NVIDIA container toolkit config", zap.String("path", configPath), zap.Error(err))
+		}
+		if err := parseNVIDIAToolkitConfig(content, toolkit); err != nil {
+			toolkit.Error = err.Error()
+		}
+		if content, err := ReadFileOnHostFileSystem(ctx, getContainerdConfigPath(ctx)); err == nil && containerdDefaultRuntime(content) == nvidiaRuntimeName {
+			toolkit.DefaultRuntimeOf = append(toolkit.DefaultRuntimeOf, containerdContainerRuntimeName)
+		}
+		if content, err := ReadFileOnHostFileSystem(ctx, dockerDaemonConfigPath); err == nil && dockerDefaultRuntime(content) == nvidiaRuntimeName {
+			toolkit.DefaultRuntimeOf = append(toolkit.DefaultRuntimeOf, dockerRuntimeName)
+		}
+		toolkit.Risks = nvidiaToolkitRisks(toolkit)
+		return toolkit
+	}
+	return nil
+}
+
+// senseAcceleratorDrivers returns the loaded NVIDIA and AMD GPU drivers
+func senseAcceleratorDrivers(ctx context.Context) []AcceleratorDriver {
+	ret := []AcceleratorDriver{}
+	if _, err := statHostFile(ctx, nvidiaModuleDir); err == nil {
+		driver := AcceleratorDriver{Vendor: AcceleratorNVIDIA, Module: path.Base(nvidiaModuleDir)}
+		if content, err := ReadFileOnHostFileSystem(ctx, nvidiaDriverVersionPath); err == nil {
+			driver.Version = parseNVIDIADriverVersion(content)
+		}
+		if driver.Version == "" {
+			driver.Version = readHostString(ctx, path.Join(nvidiaModuleDir, "version"))
+		}
+		ret = append(ret, driver)
+	}
+	if _, err := statHostFile(ctx, amdgpuModuleDir); err == nil {
+		// the amdgpu of the kernel tree has no version, the one of ROCm (DKMS) has
+		ret = append(ret, AcceleratorDriver{
+			Vendor:  AcceleratorAMD,
+			Module:  path.Base(amdgpuModuleDir),
+			Version: readHostString(ctx, path.Join(amdgpuModuleDir, "version")),
+		})
+	}
+	return ret
+}
+
+// SenseAccelerators returns the GPU drivers of the node, the settings of the NVIDIA container toolkit, and the
+// accelerator device plugin sockets
+func SenseAccelerators(ctx context.Context) (*AcceleratorInfo, error) {
+	ret := &AcceleratorInfo{
+		Drivers:       senseAcceleratorDrivers(ctx),
+		NVIDIAToolkit: senseNVIDIAToolkit(ctx),
+		DevicePlugins: []*FileInfo{},
+	}
+	for _, socketPath := range globHostPaths(ctx, path.Join(devicePluginsDir, "*.sock")) {
+		name := strings.ToLower(path.Base(socketPath))
+		accelerator := false
+		for _, pluginName := range acceleratorPluginNames {
+			accelerator = accelerator || strings.Contains(name, pluginName)
+		}
+		if !accelerator {
+			continue
+		}
+		if file := makeHostFileInfoVerbose(ctx, socketPath, false); file != nil {
+			ret.DevicePlugins = append(ret.DevicePlugins, file)
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,69 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseNVIDIADriverVersion(t *testing.T) {
+	assert.Equal(t, "535.104.05", parseNVIDIADriverVersion([]byte("NVRM version: NVIDIA UNIX x86_64 Kernel Module  535.104.05  Sat Aug 19 01:15:15 UTC 2023\nGCC version:  gcc version 11.4.0\n")))
+	assert.Equal(t, "", parseNVIDIADriverVersion([]byte("")))
+}
+
+func TestSenseAccelerators(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+	writeFSFile(t, hfs, "/sys/module/nvidia/version", []byte("535.104.05\n"))
+	writeFSFile(t, hfs, "/sys/module/amdgpu/initstate", []byte("live\n"))
+	writeFSFile(t, hfs, nvidiaToolkitConfigPaths[0], []byte(`disable-require = false
+#accept-nvidia-visible-devices-envvar-when-unprivileged = true
+
+[nvidia-container-cli]
+load-kmods = true
+no-cgroups = true
+#user = "root:video"
+
+[nvidia-container-runtime]
+mode = "auto"
+`))
+	writeFSFile(t, hfs, containerdConfigPath, []byte(`version = 2
+[plugins."io.containerd.grpc.v1.cri".containerd]
+  default_runtime_name = "nvidia"
+`))
+	writeFSFile(t, hfs, dockerDaemonConfigPath, []byte(`{"runtimes": {"nvidia": {"path": "nvidia-container-runtime"}}}`))
+	writeFSFile(t, hfs, devicePluginsDir+"/nvidia-gpu.sock", []byte{})
+	writeFSFile(t, hfs, devicePluginsDir+"/kubelet.sock", []byte{})
+
+	info, err := SenseAccelerators(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []AcceleratorDriver{
+		{Vendor: AcceleratorNVIDIA, Module: "nvidia", Version: "535.104.05"},
+		{Vendor: AcceleratorAMD, Module: "amdgpu"},
+	}, info.Drivers)
+
+	toolkit := info.NVIDIAToolkit
+	require.NotNil(t, toolkit)
+	assert.Equal(t, nvidiaToolkitConfigPaths[0], toolkit.ConfigFile.Path)
+	assert.True(t, toolkit.NoCgroups)
+	assert.True(t, toolkit.AcceptEnvvarWhenUnprivileged)
+	assert.Equal(t, "auto", toolkit.Mode)
+	assert.Equal(t, []string{"containerd"}, toolkit.DefaultRuntimeOf)
+	assert.Equal(t, []string{NVIDIAToolkitRiskNoCgroups, NVIDIAToolkitRiskEnvvarUnprivileged, NVIDIAToolkitRiskDefaultRuntime}, toolkit.Risks)
+
+	require.Len(t, info.DevicePlugins, 1)
+	assert.Equal(t, devicePluginsDir+"/nvidia-gpu.sock", info.DevicePlugins[0].Path)
+}
+
+func TestSenseAcceleratorsNone(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	info, err := SenseAccelerators(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, info.Drivers)
+	assert.Nil(t, info.NVIDIAToolkit)
+	assert.Empty(t, info.DevicePlugins)
+}
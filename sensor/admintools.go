@@ -0,0 +1,135 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Admin kubeconfigs and tools left on a node (e.g. by the installer or a debugging session) let anyone who gets on
+// the node move laterally to the cluster.
+
+const (
+	userKubeconfigComponent = "user"
+)
+
+var (
+	// The Kubernetes admin tools
+	adminToolNames = []string{"kubectl", "helm", "k9s", "kubectx", "kubens", "oc"}
+
+	// The directories of the admin tools, the PATH of the usual distributions and of the Kubernetes distributions
+	adminToolDirs = []string{"/usr/local/bin", "/usr/bin", "/bin", "/usr/local/sbin", "/usr/sbin", "/sbin", "/snap/bin", "/opt/bin", "/var/lib/rancher/rke2/bin"}
+)
+
+// AdminToolsInfo holds the Kubernetes admin tools installed on the node, and the kubeconfigs of the local users
+type AdminToolsInfo struct {
+	Binaries []AdminToolBinary `json:"binaries"`
+
+	// The ~/.kube/config files of the local users, with the clusters and users they grant
+	Kubeconfigs []KubeconfigInfo `json:"kubeconfigs"`
+}
+
+// AdminToolBinary is an installed Kubernetes admin tool
+type AdminToolBinary struct {
+	// e.g. "kubectl"
+	Name string `json:"name"`
+
+	File *FileInfo `json:"file"`
+
+	// The file the binary links to, e.g. the k3s multi-call binary, empty if it isn't a link
+	LinkTarget string `json:"linkTarget,omitempty"`
+}
+
+// parseHomeDirs parses the users of /etc/passwd and their home directories, root first
+func parseHomeDirs(passwd []byte) map[string]string {
+	ret := map[string]string{"root": "/root"}
+	for _, line := range strings.Split(string(passwd), "\n") {
+		// name:password:uid:gid:gecos:home:shell
+		fields := strings.Split(line, ":")
+		if len(fields) < 7 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if home := path.Clean(fields[5]); home != "/" && path.IsAbs(home) {
+			ret[fields[0]] = home
+		}
+	}
+	return ret
+}
+
+// senseAdminToolBinaries returns the admin tools in the PATH directories, the binaries linked from several
+// directories (e.g. /bin to /usr/bin) are reported once
+func senseAdminToolBinaries(ctx context.Context) []AdminToolBinary {
+	ret := []AdminToolBinary{}
+	seen := map[string]bool{}
+	for _, dir := range adminToolDirs {
+		for _, name := range adminToolNames {
+			binaryPath := path.Join(dir, name)
+			resolved, err := resolveHostPath(ctx, binaryPath)
+			if err != nil || seen[resolved] {
+				continue
+			}
+			file, err := makeHostFileStatInfo(ctx, binaryPath)
+			if err != nil {
+				if !errors.Is(err, fs.ErrNotExist) {
+					logger(ctx).Debug("failed to stat admin tool", zap.String("path", binaryPath), zap.Error(err))
+				}
+				continue
+			}
+			seen[resolved] = true
+
+			binary := AdminToolBinary{Name: name, File: file}
+			if target := hostFS(ctx).RelPath(resolved); target != binaryPath {
+				binary.LinkTarget = target
+			}
+			ret = append(ret, binary)
+		}
+	}
+	return ret
+}
+
+// senseUserKubeconfigs returns the analysis of the ~/.kube/config files of the local users
+func senseUserKubeconfigs(ctx context.Context) []KubeconfigInfo {
+	passwd, err := ReadFileOnHostFileSystem(ctx, userFile)
+	if err != nil {
+		logger(ctx).Debug("senseUserKubeconfigs failed to read the users", zap.Error(err))
+	}
+	homes := parseHomeDirs(passwd)
+	users := make([]string, 0, len(homes))
+	for user := range homes {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+
+	ret := []KubeconfigInfo{}
+	seen := map[string]bool{}
+	for _, user := range users {
+		kubeconfigPath := path.Join(homes[user], ".kube", "config")
+		if seen[kubeconfigPath] {
+			continue
+		}
+		seen[kubeconfigPath] = true
+		info, err := makeKubeconfigInfo(ctx, userKubeconfigComponent, kubeconfigPath)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				logger(ctx).Debug("senseUserKubeconfigs failed to analyze kubeconfig", zap.String("path", kubeconfigPath), zap.Error(err))
+			}
+			continue
+		}
+		info.Owner = user
+		ret = append(ret, *info)
+	}
+	return ret
+}
+
+// SenseAdminTools returns the Kubernetes admin tools installed on the node, and the kubeconfigs of the local users
+func SenseAdminTools(ctx context.Context) (*AdminToolsInfo, error) {
+	return &AdminToolsInfo{
+		Binaries:    senseAdminToolBinaries(ctx),
+		Kubeconfigs: senseUserKubeconfigs(ctx),
+	}, nil
+}
@@ -0,0 +1,42 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenseAdminTools(t *testing.T) {
+	adminConf, err := os.ReadFile("testdata/kubeconfigs/etc/kubernetes/admin.conf")
+	require.NoError(t, err)
+
+	hfs := NewHostFS(t.TempDir())
+
+	ctx := WithHostFS(context.Background(), hfs)
+	writeFSFile(t, hfs, userFile, []byte("root:x:0:0:root:/root:/bin/bash\nalice:x:1000:1000::/home/alice:/bin/bash\nbob:x:1001:1001::/home/bob:/bin/bash\nnobody:x:65534:65534::/nonexistent:/usr/sbin/nologin\n"))
+	writeFSFile(t, hfs, "/home/alice/.kube/config", adminConf)
+	writeFSFile(t, hfs, "/usr/local/bin/k3s", []byte("binary"))
+	require.NoError(t, os.Symlink("k3s", hfs.Path("/usr/local/bin/kubectl")))
+	writeFSFile(t, hfs, "/usr/bin/helm", []byte("binary"))
+	require.NoError(t, os.Symlink("usr/bin", hfs.Path("/bin")))
+
+	info, err := SenseAdminTools(ctx)
+	require.NoError(t, err)
+	require.Len(t, info.Binaries, 2)
+	assert.Equal(t, "kubectl", info.Binaries[0].Name)
+	assert.Equal(t, "/usr/local/bin/kubectl", info.Binaries[0].File.Path)
+	assert.Equal(t, "/usr/local/bin/k3s", info.Binaries[0].LinkTarget)
+	assert.Equal(t, "helm", info.Binaries[1].Name)
+	assert.Empty(t, info.Binaries[1].LinkTarget)
+
+	require.Len(t, info.Kubeconfigs, 1)
+	kubeconfig := info.Kubeconfigs[0]
+	assert.Equal(t, "user", kubeconfig.Component)
+	assert.Equal(t, "alice", kubeconfig.Owner)
+	assert.Equal(t, "/home/alice/.kube/config", kubeconfig.File.Path)
+	require.Len(t, kubeconfig.Users, 1)
+	assert.True(t, kubeconfig.Users[0].Admin)
+}
@@ -0,0 +1,118 @@
+package sensor
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// The kubelet runs the static pods of its static pod path and URL without admission control, and creates their
+// mirror pods in the API server. Whoever can write the static pod path, or serve the static pod URL, runs any pod on
+// the node.
+
+var (
+	// The static pod paths of the kubelet of kubeadm, k3s and RKE2
+	expectedStaticPodPaths = []string{
+		staticPodDefaultDir,
+		"/var/lib/rancher/k3s/agent/pod-manifests",
+		"/var/lib/rancher/rke2/agent/pod-manifests",
+	}
+)
+
+// AdmissionBypassInfo holds the ways to run pods on the node which bypass admission control
+type AdmissionBypassInfo struct {
+	// The static pod path of the kubelet, a directory or a single manifest
+	StaticPodPath string `json:"staticPodPath"`
+
+	// Whether the static pod path isn't the one of kubeadm, k3s or RKE2
+	UnexpectedStaticPodPath bool `json:"unexpectedStaticPodPath"`
+
+	// The static pod path and its parent directories which users other than root can write
+	NonRootWritable []NonRootWritablePath `json:"nonRootWritable"`
+
+	// The URL the kubelet fetches static pods from (--manifest-url or staticPodURL), empty if none
+	StaticPodURL string `json:"staticPodURL,omitempty"`
+
+	// The static pods of the static pod path, each has a mirror pod in the API server, and those which aren't the
+	// control plane pods of kubeadm
+	MirrorPods                int `json:"mirrorPods"`
+	NonControlPlaneMirrorPods int `json:"nonControlPlaneMirrorPods"`
+}
+
+// NonRootWritablePath is a path which users other than root can write
+type NonRootWritablePath struct {
+	File *FileInfo `json:"file"`
+
+	// The writers, e.g. "user:alice", "group:docker" or "everyone"
+	Writers []string `json:"writers"`
+}
+
+// nonRootWriters returns the users other than root who can write a file, by its ownership and permissions. A sticky
+// directory only lets them add files, not replace the existing ones, so it isn't writable by everyone.
+func nonRootWriters(file *FileInfo, sticky bool) []string {
+	writers := []string{}
+	if ownership := file.Ownership; ownership != nil && ownership.Err == "" {
+		if ownership.UID != 0 && file.Permissions&0o200 != 0 {
+			name := ownership.Username
+			if name == "" {
+				name = strconv.FormatInt(ownership.UID, 10)
+			}
+			writers = append(writers, "user:"+name)
+		}
+		if ownership.GID != 0 && file.Permissions&0o020 != 0 {
+			name := ownership.Groupname
+			if name == "" {
+				name = strconv.FormatInt(ownership.GID, 10)
+			}
+			writers = append(writers, "group:"+name)
+		}
+	}
+	if file.Permissions&0o002 != 0 && !sticky {
+		writers = append(writers, "everyone")
+	}
+	return writers
+}
+
+// senseNonRootWritablePaths returns a path and its parent directories which users other than root can write
+func senseNonRootWritablePaths(ctx context.Context, filePath string) []NonRootWritablePath {
+	ret := []NonRootWritablePath{}
+	for current := path.Clean(filePath); ; current = path.Dir(current) {
+		info, err := statHostFile(ctx, current)
+		if err == nil {
+			file, err := makeHostFileStatInfo(ctx, current)
+			if err != nil {
+				logger(ctx).Debug("failed to stat static pod path", zap.String("path", current), zap.Error(err))
+			} else if writers := nonRootWriters(file, info.Mode()&fs.ModeSticky != 0); len(writers) > 0 {
+				ret = append(ret, NonRootWritablePath{File: file, Writers: writers})
+			}
+		}
+		if current == "/" || current == "." {
+			break
+		}
+	}
+	return ret
+}
+
+// SenseAdmissionBypass returns the static pod path and URL of the kubelet, who can write the path, and the number of
+// static pods, which bypass admission control
+func SenseAdmissionBypass(ctx context.Context) (*AdmissionBypassInfo, error) {
+	ret := &AdmissionBypassInfo{}
+	ret.StaticPodPath, ret.StaticPodURL = getStaticPodSources(ctx)
+	ret.UnexpectedStaticPodPath = !containsString(expectedStaticPodPaths, path.Clean(ret.StaticPodPath))
+	ret.NonRootWritable = senseNonRootWritablePaths(ctx, ret.StaticPodPath)
+
+	pods, err := SenseStaticPods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret.MirrorPods = len(pods)
+	for _, pod := range pods {
+		if !pod.ControlPlane {
+			ret.NonControlPlaneMirrorPods++
+		}
+	}
+	return ret, nil
+}
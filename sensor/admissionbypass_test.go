@@ -0,0 +1,42 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNonRootWriters(t *testing.T) {
+	file := &FileInfo{Permissions: 0o775, Ownership: &FileOwnership{UID: 1000, GID: 998, Username: "alice", Groupname: "docker"}}
+	assert.Equal(t, []string{"user:alice", "group:docker"}, nonRootWriters(file, false))
+
+	file = &FileInfo{Permissions: 0o777, Ownership: &FileOwnership{}}
+	assert.Equal(t, []string{"everyone"}, nonRootWriters(file, false))
+	assert.Empty(t, nonRootWriters(file, true))
+
+	file = &FileInfo{Permissions: 0o755, Ownership: &FileOwnership{UID: 1000, Err: "not a UNIX file system"}}
+	assert.Empty(t, nonRootWriters(file, false))
+}
+
+func TestSenseAdmissionBypass(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+	writeFSFile(t, hfs, "/etc/kubernetes/manifests/kube-apiserver.yaml", []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: kube-apiserver\n"))
+	writeFSFile(t, hfs, "/etc/kubernetes/manifests/miner.yaml", []byte("apiVersion: v1\nkind: Pod\nmetadata:\n  name: miner\n"))
+	require.NoError(t, os.Chmod(hfs.Path(staticPodDefaultDir), 0o777))
+
+	info, err := SenseAdmissionBypass(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, staticPodDefaultDir, info.StaticPodPath)
+	assert.False(t, info.UnexpectedStaticPodPath)
+	assert.Empty(t, info.StaticPodURL)
+	assert.Equal(t, 2, info.MirrorPods)
+	assert.Equal(t, 1, info.NonControlPlaneMirrorPods)
+
+	require.NotEmpty(t, info.NonRootWritable)
+	assert.Equal(t, staticPodDefaultDir, info.NonRootWritable[0].File.Path)
+	assert.Contains(t, info.NonRootWritable[0].Writers, "everyone")
+}
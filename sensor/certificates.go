@@ -0,0 +1,344 @@
+package sensor
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"go.uber.org/zap"
+)
+
+// Certificate sources
+const (
+	CertificateSourcePKI        = "pki"
+	CertificateSourceKubelet    = "kubelet"
+	CertificateSourceEtcd       = "etcd"
+	CertificateSourceContainerd = "containerd"
+)
+
+// Certificate chain statuses
+const (
+	CertificateChainValid            = "valid"
+	CertificateChainSelfSigned       = "selfSigned"
+	CertificateChainUnknownIssuer    = "unknownIssuer"
+	CertificateChainInvalidSignature = "invalidSignature"
+)
+
+const (
+	etcdPKIDir = "/etc/kubernetes/pki/etcd"
+
+	// Max number of intermediate CAs in a chain
+	maxCertificateChainDepth = 5
+
+	// Default window of expiring certificates
+	defaultCertExpiryWindow = 30 * 24 * time.Hour
+)
+
+var (
+	// Directories searched (recursively) for certificates, by source
+	certificateDirs = []struct {
+		dir    string
+		source string
+	}{
+		{etcdPKIDir, CertificateSourceEtcd},
+		{pkiDir, CertificateSourcePKI},
+		{"/var/lib/kubelet/pki", CertificateSourceKubelet},
+		{"/etc/etcd", CertificateSourceEtcd},
+		{"/etc/ssl/etcd", CertificateSourceEtcd},
+	}
+
+	// Command line arguments of certificate files, by process
+	certificateArgs = []struct {
+		exe    string
+		source string
+		args   []string
+	}{
+		{kubeletProcessSuffix, CertificateSourceKubelet, []string{"--tls-cert-file", kubeletClientCAArgName}},
+		{etcdExe, CertificateSourceEtcd, []string{"--cert-file", "--peer-cert-file", "--trusted-ca-file", "--peer-trusted-ca-file"}},
+	}
+
+	// The CAs issuing the certificates of a kubeadm cluster, by certificate path pattern
+	certificateExpectedCAs = []struct {
+		pattern string
+		ca      string
+	}{
+		{pkiDir + "/apiserver.crt", pkiDir + "/ca.crt"},
+		{pkiDir + "/apiserver-kubelet-client.crt", pkiDir + "/ca.crt"},
+		{pkiDir + "/apiserver-etcd-client.crt", etcdPKIDir + "/ca.crt"},
+		{pkiDir + "/front-proxy-client.crt", pkiDir + "/front-proxy-ca.crt"},
+		{etcdPKIDir + "/server.crt", etcdPKIDir + "/ca.crt"},
+		{etcdPKIDir + "/peer.crt", etcdPKIDir + "/ca.crt"},
+		{etcdPKIDir + "/healthcheck-client.crt", etcdPKIDir + "/ca.crt"},
+		{"/var/lib/kubelet/pki/kubelet-client-*.pem", pkiDir + "/ca.crt"},
+	}
+
+	// Certificates expiring within the window are flagged. Accessed atomically since it's reloadable.
+	certExpiryWindow = int64(defaultCertExpiryWindow)
+)
+
+// SetCertExpiryWindow sets the window of the certificates flagged as expiring. 0 means the default (30 days).
+func SetCertExpiryWindow(window time.Duration) {
+	if window <= 0 {
+		window = defaultCertExpiryWindow
+	}
+	atomic.StoreInt64(&certExpiryWindow, int64(window))
+}
+
+// NodeCertificate is a certificate in a file on the node
+type NodeCertificate struct {
+	Path string `json:"path"`
+
+	// One of CertificateSource*
+	Source string `json:"source"`
+
+	Subject      string    `json:"subject"`
+	Issuer       string    `json:"issuer"`
+	SerialNumber string    `json:"serialNumber"`
+	NotBefore    time.Time `json:"notBefore"`
+	NotAfter     time.Time `json:"notAfter"`
+	IsCA         bool      `json:"isCA,omitempty"`
+	DNSNames     []string  `json:"dnsNames,omitempty"`
+
+	// e.g. "RSA" and "SHA256-RSA"
+	PublicKeyAlgorithm string `json:"publicKeyAlgorithm"`
+	SignatureAlgorithm string `json:"signatureAlgorithm"`
+
+	// Size of the public key in bits (RSA modulus or ECDSA curve size)
+	KeyBits int `json:"keyBits,omitempty"`
+
+	// Expired is set if the certificate has expired, and Expiring if it expires within the expiry window
+	Expired  bool `json:"expired,omitempty"`
+	Expiring bool `json:"expiring,omitempty"`
+
+	// One of CertificateChain*, verified against the CA certificates on the node
+	Chain string `json:"chain"`
+
+	// The path of the root CA the certificate chains to, if the chain is valid
+	ChainRoot string `json:"chainRoot,omitempty"`
+
+	// The path of the CA expected to issue the certificate (for the certificates of a kubeadm cluster)
+	ExpectedCA string `json:"expectedCA,omitempty"`
+}
+
+// hostCertificate is a parsed certificate and its path
+type hostCertificate struct {
+	path string
+	cert *x509.Certificate
+}
+
+// SenseCertificates returns the certificates of the PKI directories, the kubelet, etcd and the containerd
+// stream server, sorted by expiry. The chain of every certificate is verified against the CA certificates found.
+func SenseCertificates(ctx context.Context) ([]NodeCertificate, error) {
+	// source by path
+	files := map[string]string{}
+	addFile := func(filePath, source string) {
+		if _, ok := files[filePath]; !ok {
+			files[filePath] = source
+		}
+	}
+
+	for _, certDir := range certificateDirs {
+		source := certDir.source
+		err := fs.WalkDir(hostFS(ctx), certDir.dir, func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				logger(ctx).Debug("SenseCertificates failed to walk", zap.String("path", filePath), zap.Error(err))
+				recordCollectionError(ctx, "walk", filePath, err)
+				return nil
+			}
+			if !d.Type().IsRegular() || strings.HasSuffix(filePath, ".key") {
+				return nil
+			}
+			advanceProgress(ctx, filePath, 1, 0)
+			addFile(filePath, source)
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, processArgs := range certificateArgs {
+		proc, err := LocateProcessByExecSuffix(ctx, processArgs.exe)
+		if err != nil {
+			continue
+		}
+		for _, arg := range processArgs.args {
+			if certPath, ok := proc.GetArg(arg); ok && certPath != "" {
+				addFile(certPath, processArgs.source)
+			}
+		}
+	}
+
+	if certPath, err := getContainerdStreamingCertFile(ctx, containerdConfigPath); err == nil && certPath != "" {
+		addFile(certPath, CertificateSourceContainerd)
+	} else if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger(ctx).Debug("SenseCertificates failed to read containerd config", zap.Error(err))
+	}
+
+	now := time.Now()
+	window := time.Duration(atomic.LoadInt64(&certExpiryWindow))
+	ret := []NodeCertificate{}
+	parsed := []hostCertificate{}
+	cas := []hostCertificate{}
+	for filePath, source := range files {
+		certs, err := readHostCertificates(ctx, filePath)
+		if err != nil {
+			logger(ctx).Debug("SenseCertificates failed to read certificates", zap.String("path", filePath), zap.Error(err))
+			continue
+		}
+		for _, cert := range certs {
+			parsed = append(parsed, hostCertificate{path: filePath, cert: cert})
+			if cert.IsCA {
+				cas = append(cas, hostCertificate{path: filePath, cert: cert})
+			}
+			ret = append(ret, NodeCertificate{
+				Path:         filePath,
+				Source:       source,
+				Subject:      cert.Subject.String(),
+				Issuer:       cert.Issuer.String(),
+				SerialNumber: cert.SerialNumber.String(),
+				NotBefore:    cert.NotBefore,
+				NotAfter:     cert.NotAfter,
+				IsCA:         cert.IsCA,
+				DNSNames:     cert.DNSNames,
+
+				PublicKeyAlgorithm: cert.PublicKeyAlgorithm.String(),
+				SignatureAlgorithm: cert.SignatureAlgorithm.String(),
+				KeyBits:            publicKeyBits(cert),
+
+				Expired:  now.After(cert.NotAfter),
+				Expiring: !now.After(cert.NotAfter) && cert.NotAfter.Sub(now) < window,
+
+				ExpectedCA: getExpectedCA(filePath),
+			})
+		}
+	}
+
+	for i := range parsed {
+		ret[i].Chain, ret[i].ChainRoot = verifyCertificateChain(parsed[i].cert, cas, 0)
+	}
+
+	sort.Slice(ret, func(i, j int) bool {
+		if !ret[i].NotAfter.Equal(ret[j].NotAfter) {
+			return ret[i].NotAfter.Before(ret[j].NotAfter)
+		}
+		return ret[i].Path < ret[j].Path
+	})
+	return ret, nil
+}
+
+// publicKeyBits returns the size of the certificate's RSA or ECDSA public key, or 0 for other keys
+func publicKeyBits(cert *x509.Certificate) int {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return key.N.BitLen()
+	case *ecdsa.PublicKey:
+		return key.Curve.Params().BitSize
+	}
+	return 0
+}
+
+// verifyCertificateChain returns the chain status of the certificate, and the path of its root CA if the chain is valid.
+// The issuers are looked up by subject among `cas`, and their signatures are verified (regardless of expiry).
+func verifyCertificateChain(cert *x509.Certificate, cas []hostCertificate, depth int) (string, string) {
+	if isSelfSigned(cert) {
+		return CertificateChainSelfSigned, ""
+	}
+	if depth >= maxCertificateChainDepth {
+		return CertificateChainUnknownIssuer, ""
+	}
+
+	status := CertificateChainUnknownIssuer
+	for _, ca := range cas {
+		if !bytes.Equal(ca.cert.RawSubject, cert.RawIssuer) {
+			continue
+		}
+		if err := cert.CheckSignatureFrom(ca.cert); err != nil {
+			status = CertificateChainInvalidSignature
+			continue
+		}
+		if isSelfSigned(ca.cert) {
+			return CertificateChainValid, ca.path
+		}
+		caStatus, root := verifyCertificateChain(ca.cert, cas, depth+1)
+		if caStatus == CertificateChainValid {
+			return caStatus, root
+		}
+		status = caStatus
+	}
+	return status, ""
+}
+
+func isSelfSigned(cert *x509.Certificate) bool {
+	return bytes.Equal(cert.RawSubject, cert.RawIssuer) &&
+		cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature) == nil
+}
+
+// getExpectedCA returns the path of the CA expected to issue the certificate file, or an empty string if unknown
+func getExpectedCA(filePath string) string {
+	for _, expected := range certificateExpectedCAs {
+		if matched, _ := filepath.Match(expected.pattern, filePath); matched {
+			return expected.ca
+		}
+	}
+	return ""
+}
+
+// readHostCertificates returns the PEM certificates in a host file. Other PEM blocks (e.g. keys) are ignored.
+func readHostCertificates(ctx context.Context, filePath string) ([]*x509.Certificate, error) {
+	content, err := ReadFileOnHostFileSystem(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	certs := []*x509.Certificate{}
+	for block, rest := pem.Decode(content); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	return certs, nil
+}
+
+// getContainerdStreamingCertFile returns the certificate file of the containerd CRI stream server,
+// or an empty string if TLS streaming is disabled.
+func getContainerdStreamingCertFile(ctx context.Context, configPath string) (string, error) {
+	config := struct {
+		Plugins map[string]struct {
+			EnableTLSStreaming bool `toml:"enable_tls_streaming"`
+			X509KeyPair        struct {
+				TLSCertFile string `toml:"tls_cert_file"`
+			} `toml:"x509_key_pair_streaming"`
+		} `toml:"plugins"`
+	}{}
+	content, err := ReadFileOnHostFileSystem(ctx, configPath)
+	if err != nil {
+		return "", err
+	}
+	if _, err := toml.Decode(string(content), &config); err != nil {
+		return "", err
+	}
+
+	cri := config.Plugins[containerdConfigSection]
+	if !cri.EnableTLSStreaming {
+		return "", nil
+	}
+	return cri.X509KeyPair.TLSCertFile, nil
+}
@@ -0,0 +1,156 @@
+package sensor
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestCertificate writes a self signed certificate expiring at `notAfter` to the host path
+func writeTestCertificate(t *testing.T, hfs HostFS, filePath string, serial int64, notAfter time.Time, extra ...*pem.Block) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: filepath.Base(filePath)},
+		NotBefore:    notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	content := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	for _, block := range extra {
+		content = append(content, pem.EncodeToMemory(block)...)
+	}
+	writeFSFile(t, hfs, filePath, content)
+}
+
+func TestSenseCertificates(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+	defer SetCertExpiryWindow(0)
+	SetCertExpiryWindow(20 * 24 * time.Hour)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	writeTestCertificate(t, hfs, "/etc/kubernetes/pki/ca.crt", 1, now.Add(10*365*24*time.Hour))
+	writeTestCertificate(t, hfs, "/etc/kubernetes/pki/apiserver.crt", 2, now.Add(10*24*time.Hour))
+	writeTestCertificate(t, hfs, "/etc/kubernetes/pki/etcd/server.crt", 3, now.Add(-time.Hour))
+	writeTestCertificate(t, hfs, "/var/lib/kubelet/pki/kubelet-client-2022-09-01.pem", 4, now.Add(25*24*time.Hour),
+		&pem.Block{Type: "EC PRIVATE KEY", Bytes: []byte("not-a-key")})
+	writeTestCertificate(t, hfs, "/etc/containerd/stream.crt", 5, now.Add(100*24*time.Hour))
+	require.NoError(t, os.WriteFile(hfs.Path("/etc/kubernetes/pki/apiserver.key"), []byte("not-a-key"), 0o600))
+	require.NoError(t, os.WriteFile(hfs.Path("/etc/containerd/config.toml"), []byte(`
+version = 2
+[plugins."io.containerd.grpc.v1.cri"]
+  enable_tls_streaming = true
+  [plugins."io.containerd.grpc.v1.cri".x509_key_pair_streaming]
+    tls_cert_file = "/etc/containerd/stream.crt"
+    tls_key_file = "/etc/containerd/stream.key"
+`), 0o644))
+
+	certs, err := SenseCertificates(ctx)
+	require.NoError(t, err)
+
+	type cert struct {
+		path     string
+		source   string
+		expired  bool
+		expiring bool
+	}
+	got := []cert{}
+	for _, c := range certs {
+		got = append(got, cert{c.Path, c.Source, c.Expired, c.Expiring})
+	}
+	assert.Equal(t, []cert{
+		{"/etc/kubernetes/pki/etcd/server.crt", CertificateSourceEtcd, true, false},
+		{"/etc/kubernetes/pki/apiserver.crt", CertificateSourcePKI, false, true},
+		{"/var/lib/kubelet/pki/kubelet-client-2022-09-01.pem", CertificateSourceKubelet, false, false},
+		{"/etc/containerd/stream.crt", CertificateSourceContainerd, false, false},
+		{"/etc/kubernetes/pki/ca.crt", CertificateSourcePKI, false, false},
+	}, got)
+	assert.Equal(t, "CN=apiserver.crt", certs[1].Subject)
+	assert.Equal(t, "2", certs[1].SerialNumber)
+	assert.Equal(t, now.Add(10*24*time.Hour), certs[1].NotAfter.UTC())
+	assert.Equal(t, "ECDSA", certs[1].PublicKeyAlgorithm)
+	assert.Equal(t, "ECDSA-SHA256", certs[1].SignatureAlgorithm)
+	assert.Equal(t, 256, certs[1].KeyBits)
+}
+
+// issueTestCertificate writes a certificate of `commonName` issued by `parent` (self signed if nil) to the host path
+func issueTestCertificate(t *testing.T, hfs HostFS, filePath, commonName string, isCA bool, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+	}
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	writeFSFile(t, hfs, filePath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+	return cert, key
+}
+
+func TestSenseCertificatesChains(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	ca, caKey := issueTestCertificate(t, hfs, "/etc/kubernetes/pki/ca.crt", "kubernetes", true, nil, nil)
+	issueTestCertificate(t, hfs, "/etc/kubernetes/pki/etcd/ca.crt", "etcd-ca", true, nil, nil)
+	intermediate, intermediateKey := issueTestCertificate(t, hfs, "/etc/kubernetes/pki/front-proxy-ca.crt", "front-proxy-ca", true, ca, caKey)
+	issueTestCertificate(t, hfs, "/etc/kubernetes/pki/apiserver.crt", "kube-apiserver", false, ca, caKey)
+	issueTestCertificate(t, hfs, "/etc/kubernetes/pki/front-proxy-client.crt", "front-proxy-client", false, intermediate, intermediateKey)
+	issueTestCertificate(t, hfs, "/etc/kubernetes/pki/etcd/server.crt", "kube-etcd", false, ca, caKey)
+	_, otherKey := issueTestCertificate(t, hfs, "/tmp/other-ca.crt", "other-ca", true, nil, nil)
+	// issued by the cluster CA name, but signed by another key
+	forgedCA := *ca
+	forgedCA.PublicKey = &otherKey.PublicKey
+	issueTestCertificate(t, hfs, "/var/lib/kubelet/pki/kubelet-client-2022-09-01.pem", "system:node:a", false, &forgedCA, otherKey)
+	unknownCA, unknownKey := issueTestCertificate(t, hfs, "/tmp/unknown-ca.crt", "unknown-ca", true, nil, nil)
+	issueTestCertificate(t, hfs, "/var/lib/kubelet/pki/kubelet.crt", "node-a", false, unknownCA, unknownKey)
+
+	certs, err := SenseCertificates(ctx)
+	require.NoError(t, err)
+
+	type chain struct {
+		status     string
+		root       string
+		expectedCA string
+	}
+	got := map[string]chain{}
+	for _, c := range certs {
+		got[c.Path] = chain{c.Chain, c.ChainRoot, c.ExpectedCA}
+	}
+	assert.Equal(t, map[string]chain{
+		"/etc/kubernetes/pki/ca.crt":                         {CertificateChainSelfSigned, "", ""},
+		"/etc/kubernetes/pki/etcd/ca.crt":                    {CertificateChainSelfSigned, "", ""},
+		"/etc/kubernetes/pki/front-proxy-ca.crt":             {CertificateChainValid, "/etc/kubernetes/pki/ca.crt", ""},
+		"/etc/kubernetes/pki/apiserver.crt":                  {CertificateChainValid, "/etc/kubernetes/pki/ca.crt", "/etc/kubernetes/pki/ca.crt"},
+		"/etc/kubernetes/pki/front-proxy-client.crt":         {CertificateChainValid, "/etc/kubernetes/pki/ca.crt", "/etc/kubernetes/pki/front-proxy-ca.crt"},
+		"/etc/kubernetes/pki/etcd/server.crt":                {CertificateChainValid, "/etc/kubernetes/pki/ca.crt", "/etc/kubernetes/pki/etcd/ca.crt"},
+		"/var/lib/kubelet/pki/kubelet-client-2022-09-01.pem": {CertificateChainInvalidSignature, "", "/etc/kubernetes/pki/ca.crt"},
+		"/var/lib/kubelet/pki/kubelet.crt":                   {CertificateChainUnknownIssuer, "", ""},
+	}, got)
+}
@@ -0,0 +1,209 @@
+package sensor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+
+	"go.uber.org/zap"
+)
+
+// The risky settings of a CNI network
+const (
+	// A bridge without hairpin mode drops the traffic of a pod to itself through a service
+	CNIRiskHairpinOff = "hairpinOff"
+
+	// The host ports of the portmap plugin are forwarded without the firewall plugin isolating the pods
+	CNIRiskPortmapNoIsolation = "portmapNoIsolation"
+)
+
+const (
+	cniPluginBridge   = "bridge"
+	cniPluginFlannel  = "flannel"
+	cniPluginPortmap  = "portmap"
+	cniPluginFirewall = "firewall"
+)
+
+var (
+	cniConfigExtensions = []string{".conf", ".conflist", ".json"}
+)
+
+// CNINetwork is a CNI network configuration, i.e. its plugin chain
+type CNINetwork struct {
+	// The path of the conf or conflist file
+	File string `json:"file"`
+
+	Name       string `json:"name,omitempty"`
+	CNIVersion string `json:"cniVersion,omitempty"`
+
+	// The plugins in the order of the chain, a conf file is a chain of a single plugin
+	Plugins []CNIPlugin `json:"plugins"`
+
+	// The risky settings of the chain, CNIRisk*
+	Risks []string `json:"risks"`
+
+	// The parse error, empty if the file was parsed
+	Error string `json:"error,omitempty"`
+}
+
+// CNIPlugin is a plugin of a CNI network
+type CNIPlugin struct {
+	Type string `json:"type"`
+
+	IPAM *CNIIPAM `json:"ipam,omitempty"`
+
+	// The runtime capabilities of the plugin, e.g. portMappings or bandwidth
+	Capabilities []string `json:"capabilities,omitempty"`
+
+	// The bridge settings, of the bridge plugin or the bridge delegate of flannel
+	Bridge      string `json:"bridge,omitempty"`
+	HairpinMode *bool  `json:"hairpinMode,omitempty"`
+	IsGateway   *bool  `json:"isGateway,omitempty"`
+	IPMasq      *bool  `json:"ipMasq,omitempty"`
+
+	// The SNAT of the portmap plugin
+	SNAT *bool `json:"snat,omitempty"`
+}
+
+// CNIIPAM is the IP address management of a CNI plugin
+type CNIIPAM struct {
+	Type string `json:"type"`
+
+	// The subnets of the address ranges
+	Subnets []string `json:"subnets,omitempty"`
+}
+
+// cniPluginConfig is the configuration of a CNI plugin, with the settings the sensor reports
+type cniPluginConfig struct {
+	Type         string          `json:"type"`
+	Capabilities map[string]bool `json:"capabilities"`
+	IPAM         *struct {
+		Type   string `json:"type"`
+		Subnet string `json:"subnet"`
+		Ranges [][]struct {
+			Subnet string `json:"subnet"`
+		} `json:"ranges"`
+	} `json:"ipam"`
+	Bridge      string           `json:"bridge"`
+	HairpinMode *bool            `json:"hairpinMode"`
+	IsGateway   *bool            `json:"isGateway"`
+	IPMasq      *bool            `json:"ipMasq"`
+	SNAT        *bool            `json:"snat"`
+	Delegate    *cniPluginConfig `json:"delegate"`
+}
+
+// parseCNIConfig parses a CNI conf or conflist file into its plugin chain
+func parseCNIConfig(content []byte) (CNINetwork, error) {
+	config := struct {
+		Name       string            `json:"name"`
+		CNIVersion string            `json:"cniVersion"`
+		Plugins    []cniPluginConfig `json:"plugins"`
+	}{}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return CNINetwork{}, err
+	}
+	if config.Plugins == nil {
+		// a conf file, of a single plugin
+		plugin := cniPluginConfig{}
+		if err := json.Unmarshal(content, &plugin); err != nil {
+			return CNINetwork{}, err
+		}
+		if plugin.Type == "" {
+			return CNINetwork{}, fmt.Errorf("neither plugins nor a plugin type")
+		}
+		config.Plugins = []cniPluginConfig{plugin}
+	}
+
+	ret := CNINetwork{Name: config.Name, CNIVersion: config.CNIVersion, Plugins: []CNIPlugin{}}
+	for i := range config.Plugins {
+		ret.Plugins = append(ret.Plugins, makeCNIPlugin(&config.Plugins[i]))
+	}
+	ret.Risks = cniRisks(ret.Plugins)
+	return ret, nil
+}
+
+func makeCNIPlugin(config *cniPluginConfig) CNIPlugin {
+	plugin := CNIPlugin{Type: config.Type, Capabilities: sortedCapabilities(config.Capabilities)}
+	if config.IPAM != nil {
+		plugin.IPAM = &CNIIPAM{Type: config.IPAM.Type}
+		if config.IPAM.Subnet != "" {
+			plugin.IPAM.Subnets = append(plugin.IPAM.Subnets, config.IPAM.Subnet)
+		}
+		for _, rangeSet := range config.IPAM.Ranges {
+			for _, addressRange := range rangeSet {
+				plugin.IPAM.Subnets = append(plugin.IPAM.Subnets, addressRange.Subnet)
+			}
+		}
+	}
+	bridge := config
+	if config.Type == cniPluginFlannel && config.Delegate != nil {
+		bridge = config.Delegate
+	}
+	plugin.Bridge, plugin.HairpinMode, plugin.IsGateway, plugin.IPMasq = bridge.Bridge, bridge.HairpinMode, bridge.IsGateway, bridge.IPMasq
+	plugin.SNAT = config.SNAT
+	return plugin
+}
+
+// sortedCapabilities returns the enabled capabilities, sorted
+func sortedCapabilities(capabilities map[string]bool) []string {
+	ret := []string{}
+	for capability, enabled := range capabilities {
+		if enabled {
+			ret = append(ret, capability)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// cniRisks returns the risky settings of a plugin chain
+func cniRisks(plugins []CNIPlugin) []string {
+	risks := []string{}
+	hasPortmap, hasFirewall := false, false
+	for _, plugin := range plugins {
+		switch plugin.Type {
+		case cniPluginBridge:
+			// the bridge plugin disables hairpin mode by default
+			if plugin.HairpinMode == nil || !*plugin.HairpinMode {
+				risks = append(risks, CNIRiskHairpinOff)
+			}
+		case cniPluginFlannel:
+			// the flannel manifests set it on the bridge delegate, only disabling it is flagged
+			if plugin.HairpinMode != nil && !*plugin.HairpinMode {
+				risks = append(risks, CNIRiskHairpinOff)
+			}
+		case cniPluginPortmap:
+			hasPortmap = true
+		case cniPluginFirewall:
+			hasFirewall = true
+		}
+	}
+	if hasPortmap && !hasFirewall {
+		risks = append(risks, CNIRiskPortmapNoIsolation)
+	}
+	return risks
+}
+
+// makeCNINetworks parses the CNI conf and conflist files into their plugin chains
+func makeCNINetworks(ctx context.Context, files []*FileInfo) []CNINetwork {
+	ret := []CNINetwork{}
+	for _, file := range files {
+		if !containsString(cniConfigExtensions, path.Ext(file.Path)) {
+			continue
+		}
+		content, err := ReadFileOnHostFileSystem(ctx, file.Path)
+		if err != nil {
+			logger(ctx).Debug("failed to read CNI config", zap.String("path", file.Path), zap.Error(err))
+			continue
+		}
+		network, err := parseCNIConfig(content)
+		if err != nil {
+			network = CNINetwork{Plugins: []CNIPlugin{}, Risks: []string{}, Error: err.Error()}
+		}
+		network.File = file.Path
+		ret = append(ret, network)
+	}
+	return ret
+}
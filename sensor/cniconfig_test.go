@@ -0,0 +1,71 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCNIConfig(t *testing.T) {
+	network, err := parseCNIConfig([]byte(`{
+  "cniVersion": "1.0.0",
+  "name": "containerd-net",
+  "plugins": [
+    {
+      "type": "bridge",
+      "bridge": "cni0",
+      "isGateway": true,
+      "ipMasq": true,
+      "ipam": {"type": "host-local", "ranges": [[{"subnet": "10.88.0.0/16"}], [{"subnet": "2001:4860:4860::/64"}]]}
+    },
+    {"type": "portmap", "capabilities": {"portMappings": true}},
+    {"type": "bandwidth", "capabilities": {"bandwidth": true}}
+  ]
+}`))
+	require.NoError(t, err)
+	gateway := true
+	assert.Equal(t, CNINetwork{
+		Name:       "containerd-net",
+		CNIVersion: "1.0.0",
+		Plugins: []CNIPlugin{
+			{Type: "bridge", Bridge: "cni0", IsGateway: &gateway, IPMasq: &gateway, Capabilities: []string{},
+				IPAM: &CNIIPAM{Type: "host-local", Subnets: []string{"10.88.0.0/16", "2001:4860:4860::/64"}}},
+			{Type: "portmap", Capabilities: []string{"portMappings"}},
+			{Type: "bandwidth", Capabilities: []string{"bandwidth"}},
+		},
+		Risks: []string{CNIRiskHairpinOff, CNIRiskPortmapNoIsolation},
+	}, network)
+
+	// a conf file of flannel, with its bridge delegate
+	network, err = parseCNIConfig([]byte(`{"name": "cbr0", "type": "flannel", "delegate": {"hairpinMode": true, "isDefaultGateway": true}}`))
+	require.NoError(t, err)
+	require.Len(t, network.Plugins, 1)
+	assert.True(t, *network.Plugins[0].HairpinMode)
+	assert.Empty(t, network.Risks)
+
+	_, err = parseCNIConfig([]byte(`{"name": "empty"}`))
+	assert.Error(t, err)
+}
+
+func TestMakeCNINetworks(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+	writeFSFile(t, hfs, "/etc/cni/net.d/10-calico.conflist", []byte(`{"name": "k8s-pod-network", "plugins": [{"type": "calico", "ipam": {"type": "calico-ipam"}}, {"type": "portmap", "snat": true, "capabilities": {"portMappings": true}}, {"type": "firewall"}]}`))
+	writeFSFile(t, hfs, "/etc/cni/net.d/99-broken.conf", []byte(`{`))
+	writeFSFile(t, hfs, "/etc/cni/net.d/calico-kubeconfig", []byte(`apiVersion: v1`))
+
+	networks := makeCNINetworks(ctx, []*FileInfo{
+		{Path: "/etc/cni/net.d/10-calico.conflist"},
+		{Path: "/etc/cni/net.d/99-broken.conf"},
+		{Path: "/etc/cni/net.d/calico-kubeconfig"},
+	})
+	require.Len(t, networks, 2)
+	assert.Equal(t, "k8s-pod-network", networks[0].Name)
+	assert.Equal(t, []string{"calico", "portmap", "firewall"}, []string{networks[0].Plugins[0].Type, networks[0].Plugins[1].Type, networks[0].Plugins[2].Type})
+	assert.True(t, *networks[0].Plugins[1].SNAT)
+	assert.Empty(t, networks[0].Risks)
+	assert.Equal(t, "/etc/cni/net.d/99-broken.conf", networks[1].File)
+	assert.NotEmpty(t, networks[1].Error)
+}
@@ -0,0 +1,131 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// The container runtimes load the CNI config files of the config dir in lexicographic order, and use the first one
+// (containerd fails if it's invalid, CRI-O uses the first valid one). A stale config file which sorts first (e.g. a
+// flannel config left over after a migration to calico) silently selects another network than the one of the running
+// CNI agent.
+
+// CNI conflicts
+const (
+	// The config dir has several network configs, the runtime uses only the selected one
+	CNIConflictMultipleConfigs = "multipleConfigs"
+
+	// Several CNI agents run on the node
+	CNIConflictMultipleAgents = "multipleAgents"
+
+	// The selected config is of a CNI whose agent doesn't run, while the agent of another CNI does
+	CNIConflictSelectedNotRunning = "selectedNotRunning"
+)
+
+var (
+	// The CNI agents, by their executable suffix, and the plugin type of their network config (empty if they use a
+	// generic plugin, e.g. the bridge plugin of kube-router)
+	cniAgents = []struct {
+		exe    string
+		plugin string
+	}{
+		{"/calico-node", "calico"},
+		{"/flanneld", cniPluginFlannel},
+		{"/cilium-agent", "cilium-cni"},
+		{"/kube-router", ""},
+		{"/antrea-agent", "antrea"},
+		{"/weaver", "weave-net"},
+	}
+)
+
+// CNIConflictInfo holds the network config which the container runtime uses, and the conflicts of the CNI configs
+// and agents of the node
+type CNIConflictInfo struct {
+	ConfigDir string `json:"configDir"`
+
+	// The config file which the runtime uses, the first valid one in lexicographic order, empty if none
+	Selected string `json:"selected"`
+
+	// The other network configs of the config dir, which the runtime ignores
+	Ignored []string `json:"ignored"`
+
+	// The CNI agents running on the node, e.g. "calico-node"
+	Agents []string `json:"agents"`
+
+	// The conflicts, CNIConflict*
+	Conflicts []string `json:"conflicts"`
+
+	// The details of the conflicts
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// detectCNIConflicts returns the selected network config of a config dir, and the conflicts of its network configs
+// with each other and with the running agents
+func detectCNIConflicts(configDir string, networks []CNINetwork, agents []string) *CNIConflictInfo {
+	ret := &CNIConflictInfo{ConfigDir: configDir, Ignored: []string{}, Agents: agents, Conflicts: []string{}}
+
+	// the runtime loads only the files of the config dir itself
+	loaded := []CNINetwork{}
+	for _, network := range networks {
+		if path.Dir(network.File) == path.Clean(configDir) {
+			loaded = append(loaded, network)
+		}
+	}
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].File < loaded[j].File })
+
+	var selected *CNINetwork
+	for i := range loaded {
+		if selected == nil && loaded[i].Error == "" {
+			selected = &loaded[i]
+			ret.Selected = loaded[i].File
+			continue
+		}
+		ret.Ignored = append(ret.Ignored, loaded[i].File)
+	}
+	if len(ret.Ignored) > 0 && selected != nil {
+		ret.Conflicts = append(ret.Conflicts, CNIConflictMultipleConfigs)
+		ret.Reasons = append(ret.Reasons, fmt.Sprintf("the runtime uses %s and ignores %s", ret.Selected, strings.Join(ret.Ignored, ", ")))
+	}
+	if len(agents) > 1 {
+		ret.Conflicts = append(ret.Conflicts, CNIConflictMultipleAgents)
+		ret.Reasons = append(ret.Reasons, fmt.Sprintf("the CNI agents %s run on the node", strings.Join(agents, ", ")))
+	}
+
+	if selected != nil && len(agents) > 0 {
+		for _, agent := range cniAgents {
+			if agent.plugin == "" || !cniNetworkHasPlugin(selected, agent.plugin) {
+				continue
+			}
+			if !containsString(agents, strings.TrimPrefix(agent.exe, "/")) {
+				ret.Conflicts = append(ret.Conflicts, CNIConflictSelectedNotRunning)
+				ret.Reasons = append(ret.Reasons, fmt.Sprintf("the runtime uses the %s config %s, but its agent doesn't run", agent.plugin, selected.File))
+			}
+			break
+		}
+	}
+	return ret
+}
+
+// cniNetworkHasPlugin returns whether a plugin type is in the chain of a network
+func cniNetworkHasPlugin(network *CNINetwork, pluginType string) bool {
+	for _, plugin := range network.Plugins {
+		if plugin.Type == pluginType {
+			return true
+		}
+	}
+	return false
+}
+
+// senseCNIAgents returns the CNI agents running on the node
+func senseCNIAgents(ctx context.Context) []string {
+	ret := []string{}
+	for _, agent := range cniAgents {
+		if _, err := LocateProcessByExecSuffix(ctx, agent.exe); err == nil {
+			ret = append(ret, strings.TrimPrefix(agent.exe, "/"))
+		}
+	}
+	return ret
+}
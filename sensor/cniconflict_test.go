@@ -0,0 +1,35 @@
+package sensor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectCNIConflicts(t *testing.T) {
+	networks := []CNINetwork{
+		{File: "/etc/cni/net.d/10-flannel.conflist", Name: "cbr0", Plugins: []CNIPlugin{{Type: "flannel"}, {Type: "portmap"}}},
+		{File: "/etc/cni/net.d/10-calico.conflist", Name: "k8s-pod-network", Plugins: []CNIPlugin{{Type: "calico"}, {Type: "portmap"}}},
+		{File: "/etc/cni/net.d/00-broken.conf", Error: "unexpected end of JSON input"},
+		{File: "/etc/cni/net.d/calico/10-calico.conflist", Name: "k8s-pod-network", Plugins: []CNIPlugin{{Type: "calico"}}},
+	}
+
+	info := detectCNIConflicts("/etc/cni/net.d/", networks, []string{"calico-node"})
+	assert.Equal(t, "/etc/cni/net.d/10-calico.conflist", info.Selected)
+	assert.Equal(t, []string{"/etc/cni/net.d/00-broken.conf", "/etc/cni/net.d/10-flannel.conflist"}, info.Ignored)
+	assert.Equal(t, []string{CNIConflictMultipleConfigs}, info.Conflicts)
+
+	// a flannel leftover which sorts first
+	networks[0].File = "/etc/cni/net.d/05-flannel.conflist"
+	info = detectCNIConflicts("/etc/cni/net.d", networks, []string{"calico-node", "flanneld"})
+	assert.Equal(t, "/etc/cni/net.d/05-flannel.conflist", info.Selected)
+	assert.Equal(t, []string{CNIConflictMultipleConfigs, CNIConflictMultipleAgents}, info.Conflicts)
+
+	info = detectCNIConflicts("/etc/cni/net.d", networks, []string{"calico-node"})
+	assert.Equal(t, []string{CNIConflictMultipleConfigs, CNIConflictSelectedNotRunning}, info.Conflicts)
+	assert.Equal(t, "the runtime uses the flannel config /etc/cni/net.d/05-flannel.conflist, but its agent doesn't run", info.Reasons[1])
+
+	info = detectCNIConflicts("/etc/cni/net.d", networks[1:2], []string{"calico-node"})
+	assert.Empty(t, info.Conflicts)
+	assert.Empty(t, info.Ignored)
+}
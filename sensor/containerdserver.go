@@ -0,0 +1,169 @@
+package sensor
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"go.uber.org/zap"
+)
+
+// The CRI plugin of containerd serves the exec, attach and port-forward streams of the kubelet. The stream URLs
+// carry a single use token but the stream server doesn't authenticate its clients, so a stream server listening on
+// a non-loopback address without TLS exposes the tokens (and the streams) to the network.
+
+const (
+	// The CRI plugin section of the config, before config version 2
+	containerdConfigSectionV1 = "cri"
+
+	containerdDefaultStreamAddress = "127.0.0.1"
+	containerdDefaultStreamPort    = "0"
+	containerdDefaultSocket        = "/run/containerd/containerd.sock"
+)
+
+// ContainerdServerInfo holds the servers of containerd
+type ContainerdServerInfo struct {
+	ConfigFile *FileInfo `json:"configFile"`
+
+	// The CRI stream server, of exec, attach and port-forward
+	Stream ContainerdStreamServer `json:"streamServer"`
+
+	// The containerd API server
+	GRPC ContainerdGRPCServer `json:"grpc"`
+
+	// The parse error of the config, empty if it was parsed
+	Error string `json:"error,omitempty"`
+}
+
+// ContainerdStreamServer holds the address and the TLS settings of the CRI stream server
+type ContainerdStreamServer struct {
+	// The listen address, empty for all the interfaces
+	Address string `json:"address"`
+
+	// The listen port, "0" for a random port
+	Port string `json:"port"`
+
+	// Whether the streams are served over TLS, with a self-signed certificate if there is no key pair
+	TLS         bool   `json:"tls"`
+	TLSCertFile string `json:"tlsCertFile,omitempty"`
+	TLSKeyFile  string `json:"tlsKeyFile,omitempty"`
+
+	IdleTimeout string `json:"idleTimeout,omitempty"`
+
+	// Whether the streams are served unencrypted on a non-loopback address
+	Exposed bool `json:"exposed"`
+}
+
+// ContainerdGRPCServer holds the socket and the TCP listener of the containerd API
+type ContainerdGRPCServer struct {
+	Socket string `json:"socket"`
+
+	// The TCP listen address, empty if the API is served on the socket only
+	TCPAddress string `json:"tcpAddress,omitempty"`
+
+	// The TLS files of the TCP listener, the clients aren't authenticated if there is no CA
+	TCPTLSCert string `json:"tcpTLSCert,omitempty"`
+	TCPTLSKey  string `json:"tcpTLSKey,omitempty"`
+	TCPTLSCA   string `json:"tcpTLSCA,omitempty"`
+
+	// Whether the API is served on a non-loopback address without client authentication
+	Exposed bool `json:"exposed"`
+}
+
+type containerdStreamConfig struct {
+	StreamServerAddress *string `toml:"stream_server_address"`
+	StreamServerPort    *string `toml:"stream_server_port"`
+	StreamIdleTimeout   string  `toml:"stream_idle_timeout"`
+	EnableTLSStreaming  bool    `toml:"enable_tls_streaming"`
+	X509KeyPair         struct {
+		TLSCertFile string `toml:"tls_cert_file"`
+		TLSKeyFile  string `toml:"tls_key_file"`
+	} `toml:"x509_key_pair_streaming"`
+}
+
+// parseContainerdServerConfig parses the stream server and API settings of a containerd config
+func parseContainerdServerConfig(content []byte) (ContainerdStreamServer, ContainerdGRPCServer, error) {
+	config := struct {
+		GRPC struct {
+			Address    string `toml:"address"`
+			TCPAddress string `toml:"tcp_address"`
+			TCPTLSCert string `toml:"tcp_tls_cert"`
+			TCPTLSKey  string `toml:"tcp_tls_key"`
+			TCPTLSCA   string `toml:"tcp_tls_ca"`
+		} `toml:"grpc"`
+		Plugins map[string]containerdStreamConfig `toml:"plugins"`
+	}{}
+	stream := ContainerdStreamServer{Address: containerdDefaultStreamAddress, Port: containerdDefaultStreamPort}
+	grpc := ContainerdGRPCServer{Socket: containerdDefaultSocket}
+	if _, err := toml.Decode(string(content), &config); err != nil {
+		return stream, grpc, err
+	}
+
+	cri, ok := config.Plugins[containerdConfigSection]
+	if !ok {
+		cri = config.Plugins[containerdConfigSectionV1]
+	}
+	if cri.StreamServerAddress != nil {
+		stream.Address = *cri.StreamServerAddress
+	}
+	if cri.StreamServerPort != nil {
+		stream.Port = *cri.StreamServerPort
+	}
+	stream.IdleTimeout = cri.StreamIdleTimeout
+	stream.TLS, stream.TLSCertFile, stream.TLSKeyFile = cri.EnableTLSStreaming, cri.X509KeyPair.TLSCertFile, cri.X509KeyPair.TLSKeyFile
+	stream.Exposed = !stream.TLS && !isLoopbackAddress(stream.Address)
+
+	if config.GRPC.Address != "" {
+		grpc.Socket = config.GRPC.Address
+	}
+	grpc.TCPAddress, grpc.TCPTLSCert, grpc.TCPTLSKey, grpc.TCPTLSCA = config.GRPC.TCPAddress, config.GRPC.TCPTLSCert, config.GRPC.TCPTLSKey, config.GRPC.TCPTLSCA
+	if grpc.TCPAddress != "" {
+		host, _, err := net.SplitHostPort(grpc.TCPAddress)
+		if err != nil {
+			host = grpc.TCPAddress
+		}
+		grpc.Exposed = grpc.TCPTLSCA == "" && !isLoopbackAddress(host)
+	}
+	return stream, grpc, nil
+}
+
+// isLoopbackAddress returns true if a listen address is a loopback one, an empty address listens on all the interfaces
+func isLoopbackAddress(address string) bool {
+	if strings.EqualFold(address, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(address)
+	return ip != nil && ip.IsLoopback()
+}
+
+// getContainerdConfigPath returns the config of the containerd process (--config), or the default one
+func getContainerdConfigPath(ctx context.Context) string {
+	if proc, err := LocateProcessByExecSuffix(ctx, containerdProps().ProcessSuffix); err == nil {
+		if configPath, ok := proc.GetArg(containerdProps().ConfigArgName); ok && configPath != "" {
+			return configPath
+		}
+	}
+	return containerdConfigPath
+}
+
+// SenseContainerdServer returns the CRI stream server and the API listeners of containerd, from its config. It returns
+// nil if the node has no containerd config.
+func SenseContainerdServer(ctx context.Context) (*ContainerdServerInfo, error) {
+	configPath := getContainerdConfigPath(ctx)
+	content, err := ReadFileOnHostFileSystem(ctx, configPath)
+	if err != nil {
+		logger(ctx).Warn("failed to read containerd config", zap.String("path", configPath), zap.Error(err))
+		recordCollectionError(ctx, "read", configPath, err)
+		return nil, nil
+	}
+
+	ret := &ContainerdServerInfo{}
+	if ret.ConfigFile, err = makeHostFileStatInfo(ctx, configPath); err != nil {
+		logger(ctx).Debug("SenseContainerdServer failed to stat the containerd config", zap.String("path", configPath), zap.Error(err))
+	}
+	if ret.Stream, ret.GRPC, err = parseContainerdServerConfig(content); err != nil {
+		ret.Error = err.Error()
+	}
+	return ret, nil
+}
@@ -0,0 +1,68 @@
+package sensor
+
+import (
+	"context"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseContainerdServerConfig(t *testing.T) {
+	stream, grpc, err := parseContainerdServerConfig([]byte(`version = 2
+[plugins."io.containerd.grpc.v1.cri"]
+  sandbox_image = "registry.k8s.io/pause:3.9"
+`))
+	require.NoError(t, err)
+	assert.Equal(t, ContainerdStreamServer{Address: "127.0.0.1", Port: "0"}, stream)
+	assert.Equal(t, ContainerdGRPCServer{Socket: "/run/containerd/containerd.sock"}, grpc)
+
+	stream, grpc, err = parseContainerdServerConfig([]byte(`version = 2
+[grpc]
+  address = "/run/k3s/containerd/containerd.sock"
+  tcp_address = "10.0.0.4:2376"
+  tcp_tls_cert = "/etc/containerd/server.crt"
+  tcp_tls_key = "/etc/containerd/server.key"
+[plugins."io.containerd.grpc.v1.cri"]
+  stream_server_address = ""
+  stream_server_port = "10010"
+  stream_idle_timeout = "4h0m0s"
+`))
+	require.NoError(t, err)
+	assert.Equal(t, ContainerdStreamServer{Address: "", Port: "10010", IdleTimeout: "4h0m0s", Exposed: true}, stream)
+	assert.Equal(t, ContainerdGRPCServer{Socket: "/run/k3s/containerd/containerd.sock", TCPAddress: "10.0.0.4:2376",
+		TCPTLSCert: "/etc/containerd/server.crt", TCPTLSKey: "/etc/containerd/server.key", Exposed: true}, grpc)
+
+	// config version 1, with TLS streaming
+	stream, _, err = parseContainerdServerConfig([]byte(`[plugins.cri]
+  stream_server_address = "0.0.0.0"
+  enable_tls_streaming = true
+  [plugins.cri.x509_key_pair_streaming]
+    tls_cert_file = "/etc/containerd/stream.crt"
+    tls_key_file = "/etc/containerd/stream.key"
+`))
+	require.NoError(t, err)
+	assert.Equal(t, ContainerdStreamServer{Address: "0.0.0.0", Port: "0", TLS: true,
+		TLSCertFile: "/etc/containerd/stream.crt", TLSKeyFile: "/etc/containerd/stream.key"}, stream)
+
+	_, _, err = parseContainerdServerConfig([]byte(`[plugins`))
+	assert.Error(t, err)
+}
+
+func TestSenseContainerdServer(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	info, err := SenseContainerdServer(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, info)
+
+	writeFSFile(t, hfs, containerdConfigPath, []byte("[plugins.\"io.containerd.grpc.v1.cri\"]\n  stream_server_address = \"localhost\"\n"))
+	info, err = SenseContainerdServer(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, info)
+	assert.Equal(t, path.Clean(containerdConfigPath), info.ConfigFile.Path)
+	assert.Equal(t, "localhost", info.Stream.Address)
+	assert.False(t, info.Stream.Exposed)
+}
@@ -0,0 +1,245 @@
+package sensor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+// The kubelet rotates the container logs of the CRI runtimes (containerd and CRI-O), by --container-log-max-size and
+// --container-log-max-files. The logs of the containers of the docker daemon, including those the kubelet runs
+// through cri-dockerd, are rotated by the docker log driver: json-file only rotates with a max-size log option.
+
+// Container log rotators
+const (
+	// The kubelet rotates the container logs of a CRI runtime
+	ContainerLogRotatorKubelet = "kubelet"
+
+	// The docker log driver rotates the container logs of cri-dockerd
+	ContainerLogRotatorDocker = "docker"
+)
+
+const (
+	kubeletContainerLogMaxSizeArg  = "--container-log-max-size"
+	kubeletContainerLogMaxFilesArg = "--container-log-max-files"
+	dockerLogDriverArg             = "--log-driver"
+	dockerLogOptArg                = "--log-opt"
+	dockerJSONFileLogDriver        = "json-file"
+	crioConfigPath                 = "/etc/crio/crio.conf"
+)
+
+// ContainerLogRotationInfo holds the container log rotation settings of the kubelet and the container runtimes
+type ContainerLogRotationInfo struct {
+	// The kubelet settings, nil if the kubelet doesn't run on the node
+	Kubelet *KubeletLogRotation `json:"kubelet,omitempty"`
+
+	// The settings of the running container runtimes
+	Docker     *DockerLogging     `json:"docker,omitempty"`
+	Containerd *ContainerdLogging `json:"containerd,omitempty"`
+	Crio       *CrioLogging       `json:"crio,omitempty"`
+}
+
+// KubeletLogRotation holds the container log rotation settings of the kubelet
+type KubeletLogRotation struct {
+	// The --container-runtime-endpoint of the kubelet
+	RuntimeEndpoint string `json:"runtimeEndpoint,omitempty"`
+
+	// One of ContainerLogRotator*, by the runtime endpoint
+	RotatedBy string `json:"rotatedBy"`
+
+	// The max size of a log file and the max files per container, from the flags, which override the config file. A
+	// setting which is set in neither is nil, and has its default value: 10Mi and 5.
+	ContainerLogMaxSize  *string `json:"containerLogMaxSize,omitempty"`
+	ContainerLogMaxFiles *int    `json:"containerLogMaxFiles,omitempty"`
+
+	// Whether the container logs of the kubelet are rotated, by the kubelet or by the docker log driver
+	Rotated bool `json:"rotated"`
+}
+
+// DockerLogging holds the logging settings of the docker daemon, from its flags, which override daemon.json
+type DockerLogging struct {
+	// The default log driver of the containers, json-file if unset
+	LogDriver string `json:"logDriver"`
+
+	// The options of the log driver, e.g. "max-size": "10m" and "max-file": "3"
+	LogOpts map[string]string `json:"logOpts"`
+
+	// Whether the log driver rotates the logs, or doesn't write them to json files on the node
+	Rotated bool `json:"rotated"`
+}
+
+// ContainerdLogging holds the logging settings of the containerd CRI plugin
+type ContainerdLogging struct {
+	// The max size of a log line, longer lines are split, nil if unset (16384)
+	MaxContainerLogLineSize *int `json:"maxContainerLogLineSize,omitempty"`
+}
+
+// CrioLogging holds the logging settings of CRI-O
+type CrioLogging struct {
+	// The max size of a container log, nil if unset (-1, unlimited)
+	LogSizeMax *int64 `json:"logSizeMax,omitempty"`
+}
+
+// parseKubeletLogRotation returns the container log rotation settings of the kubelet flags, or else of its config
+// file content (if any)
+func parseKubeletLogRotation(ctx context.Context, p *ProcessDetails, configContent []byte) *KubeletLogRotation {
+	ret := &KubeletLogRotation{RotatedBy: ContainerLogRotatorKubelet}
+	ret.RuntimeEndpoint, _ = p.GetArg(kubeletContainerRuntimeEndPoint)
+	if strings.HasSuffix(ret.RuntimeEndpoint, cridockerdSock) {
+		ret.RotatedBy = ContainerLogRotatorDocker
+	}
+
+	if configContent != nil {
+		config := struct {
+			ContainerLogMaxSize  *string `json:"containerLogMaxSize"`
+			ContainerLogMaxFiles *int    `json:"containerLogMaxFiles"`
+		}{}
+		if err := yaml.Unmarshal(configContent, &config); err != nil {
+			logger(ctx).Warn("failed to parse kubelet config", zap.Error(err))
+		} else {
+			ret.ContainerLogMaxSize, ret.ContainerLogMaxFiles = config.ContainerLogMaxSize, config.ContainerLogMaxFiles
+		}
+	}
+	if val, ok := p.GetArg(kubeletContainerLogMaxSizeArg); ok {
+		ret.ContainerLogMaxSize = &val
+	}
+	if val, ok := p.GetArg(kubeletContainerLogMaxFilesArg); ok {
+		files, err := strconv.Atoi(val)
+		if err != nil {
+			logger(ctx).Warn("invalid flag value", zap.String("flag", kubeletContainerLogMaxFilesArg), zap.String("value", val))
+		} else {
+			ret.ContainerLogMaxFiles = &files
+		}
+	}
+	return ret
+}
+
+// parseDockerLogging returns the logging settings of the docker daemon flags and config
+func parseDockerLogging(ctx context.Context, p *ProcessDetails, configContent []byte) *DockerLogging {
+	ret := &DockerLogging{LogDriver: dockerJSONFileLogDriver, LogOpts: map[string]string{}}
+	if configContent != nil {
+		config := struct {
+			LogDriver string            `json:"log-driver"`
+			LogOpts   map[string]string `json:"log-opts"`
+		}{}
+		if err := json.Unmarshal(configContent, &config); err != nil {
+			logger(ctx).Debug("failed to parse docker daemon config", zap.Error(err))
+		} else {
+			if config.LogDriver != "" {
+				ret.LogDriver = config.LogDriver
+			}
+			for key, val := range config.LogOpts {
+				ret.LogOpts[key] = val
+			}
+		}
+	}
+	if val, ok := p.GetArg(dockerLogDriverArg); ok && val != "" {
+		ret.LogDriver = val
+	}
+	// --log-opt may be repeated
+	for i, arg := range p.CmdLine {
+		opt := ""
+		switch {
+		case arg == dockerLogOptArg && i+1 < len(p.CmdLine):
+			opt = p.CmdLine[i+1]
+		case strings.HasPrefix(arg, dockerLogOptArg+"="):
+			opt = strings.TrimPrefix(arg, dockerLogOptArg+"=")
+		}
+		if key, val, ok := strings.Cut(opt, "="); ok {
+			ret.LogOpts[key] = val
+		}
+	}
+
+	// the local driver rotates by default, and the other drivers don't write json files on the node
+	ret.Rotated = ret.LogDriver != dockerJSONFileLogDriver || ret.LogOpts["max-size"] != "" && ret.LogOpts["max-size"] != "-1"
+	return ret
+}
+
+// parseContainerdLogging returns the logging settings of the containerd CRI plugin config
+func parseContainerdLogging(content []byte) (*ContainerdLogging, error) {
+	config := struct {
+		Plugins map[string]struct {
+			MaxContainerLogLineSize *int `toml:"max_container_log_line_size"`
+		} `toml:"plugins"`
+	}{}
+	if _, err := toml.Decode(string(content), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse containerd config: %w", err)
+	}
+	ret := &ContainerdLogging{}
+	for _, plugin := range config.Plugins {
+		if plugin.MaxContainerLogLineSize != nil {
+			ret.MaxContainerLogLineSize = plugin.MaxContainerLogLineSize
+		}
+	}
+	return ret, nil
+}
+
+// parseCrioLogging returns the logging settings of the CRI-O config
+func parseCrioLogging(content []byte) (*CrioLogging, error) {
+	config := struct {
+		Crio struct {
+			Runtime struct {
+				LogSizeMax *int64 `toml:"log_size_max"`
+			} `toml:"runtime"`
+		} `toml:"crio"`
+	}{}
+	if _, err := toml.Decode(string(content), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse crio config: %w", err)
+	}
+	return &CrioLogging{LogSizeMax: config.Crio.Runtime.LogSizeMax}, nil
+}
+
+// SenseContainerLogRotation returns the container log rotation settings of the kubelet, and the logging settings of
+// the running container runtimes
+func SenseContainerLogRotation(ctx context.Context) (*ContainerLogRotationInfo, error) {
+	ret := &ContainerLogRotationInfo{}
+	if proc, err := LocateKubeletProcess(ctx); err == nil {
+		configPath := kubeletConfigDefaultPath
+		if p, ok := proc.GetArg(kubeletConfigArgName); ok {
+			configPath = p
+		}
+		configContent, err := ReadFileOnHostFileSystem(ctx, configPath)
+		if err != nil {
+			configContent = nil
+		}
+		ret.Kubelet = parseKubeletLogRotation(ctx, proc, configContent)
+	}
+
+	if proc, err := LocateProcessByExecSuffix(ctx, dockerdExe); err == nil {
+		content, err := ReadFileOnHostFileSystem(ctx, dockerDaemonConfigPath)
+		if err != nil {
+			content = nil
+		}
+		ret.Docker = parseDockerLogging(ctx, proc, content)
+	}
+	if ret.Kubelet != nil {
+		ret.Kubelet.Rotated = ret.Kubelet.RotatedBy == ContainerLogRotatorKubelet && (ret.Kubelet.ContainerLogMaxSize == nil || *ret.Kubelet.ContainerLogMaxSize != "0") ||
+			ret.Kubelet.RotatedBy == ContainerLogRotatorDocker && ret.Docker != nil && ret.Docker.Rotated
+	}
+
+	if _, err := LocateProcessByExecSuffix(ctx, "/containerd"); err == nil {
+		ret.Containerd = &ContainerdLogging{}
+		if content, err := ReadFileOnHostFileSystem(ctx, containerdConfigPath); err == nil {
+			if ret.Containerd, err = parseContainerdLogging(content); err != nil {
+				logger(ctx).Debug("SenseContainerLogRotation failed to parse the containerd config", zap.Error(err))
+				ret.Containerd = &ContainerdLogging{}
+			}
+		}
+	}
+	if _, err := LocateProcessByExecSuffix(ctx, "/crio"); err == nil {
+		ret.Crio = &CrioLogging{}
+		if content, err := ReadFileOnHostFileSystem(ctx, crioConfigPath); err == nil {
+			if ret.Crio, err = parseCrioLogging(content); err != nil {
+				logger(ctx).Debug("SenseContainerLogRotation failed to parse the crio config", zap.Error(err))
+				ret.Crio = &CrioLogging{}
+			}
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,72 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDockerLogging(t *testing.T) {
+	proc := &ProcessDetails{CmdLine: []string{"/usr/bin/dockerd", "-H", "fd://"}}
+	logging := parseDockerLogging(context.Background(), proc, nil)
+	assert.Equal(t, "json-file", logging.LogDriver)
+	assert.False(t, logging.Rotated)
+
+	logging = parseDockerLogging(context.Background(), proc, []byte(`{"log-driver": "json-file", "log-opts": {"max-size": "10m", "max-file": "3"}}`))
+	assert.Equal(t, map[string]string{"max-size": "10m", "max-file": "3"}, logging.LogOpts)
+	assert.True(t, logging.Rotated)
+
+	proc = &ProcessDetails{CmdLine: []string{"/usr/bin/dockerd", "--log-opt", "max-size=-1", "--log-opt=max-file=2"}}
+	logging = parseDockerLogging(context.Background(), proc, []byte(`{"log-opts": {"max-size": "10m"}}`))
+	assert.Equal(t, map[string]string{"max-size": "-1", "max-file": "2"}, logging.LogOpts)
+	assert.False(t, logging.Rotated)
+
+	proc = &ProcessDetails{CmdLine: []string{"/usr/bin/dockerd", "--log-driver=journald"}}
+	logging = parseDockerLogging(context.Background(), proc, nil)
+	assert.Equal(t, "journald", logging.LogDriver)
+	assert.True(t, logging.Rotated)
+}
+
+func TestParseContainerRuntimeLogging(t *testing.T) {
+	containerd, err := parseContainerdLogging([]byte(`version = 2
+[plugins."io.containerd.grpc.v1.cri"]
+  max_container_log_line_size = 8192
+`))
+	require.NoError(t, err)
+	require.NotNil(t, containerd.MaxContainerLogLineSize)
+	assert.Equal(t, 8192, *containerd.MaxContainerLogLineSize)
+
+	crio, err := parseCrioLogging([]byte(`[crio.runtime]
+log_size_max = -1
+`))
+	require.NoError(t, err)
+	require.NotNil(t, crio.LogSizeMax)
+	assert.Equal(t, int64(-1), *crio.LogSizeMax)
+}
+
+func TestSenseContainerLogRotation(t *testing.T) {
+	origPlatform := hostPlatform
+	defer func() { hostPlatform = origPlatform }()
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+	hostPlatform = fixturePlatform{root: hfs.Root()}
+	writeFSFile(t, hfs, "/proc/51/cmdline", []byte("/usr/bin/kubelet\x00--container-runtime-endpoint=unix:///run/cri-dockerd.sock\x00--container-log-max-files=3\x00"))
+	writeFSFile(t, hfs, "/proc/52/cmdline", []byte("/usr/bin/dockerd\x00"))
+	writeFSFile(t, hfs, "/var/lib/kubelet/config.yaml", []byte("kind: KubeletConfiguration\ncontainerLogMaxSize: 50Mi\n"))
+
+	info, err := SenseContainerLogRotation(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, info.Kubelet)
+	assert.Equal(t, ContainerLogRotatorDocker, info.Kubelet.RotatedBy)
+	require.NotNil(t, info.Kubelet.ContainerLogMaxSize)
+	assert.Equal(t, "50Mi", *info.Kubelet.ContainerLogMaxSize)
+	require.NotNil(t, info.Kubelet.ContainerLogMaxFiles)
+	assert.Equal(t, 3, *info.Kubelet.ContainerLogMaxFiles)
+	// the kubelet settings don't apply to the docker json-file logs
+	assert.False(t, info.Kubelet.Rotated)
+	require.NotNil(t, info.Docker)
+	assert.False(t, info.Docker.Rotated)
+	assert.Nil(t, info.Containerd)
+}
@@ -0,0 +1,133 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+
+	"go.uber.org/zap"
+)
+
+const (
+	podLogsDir       = "/var/log/pods"
+	containerLogsDir = "/var/log/containers"
+
+	// Max number of reported log files, to bound the response on nodes with many pods
+	maxContainerLogFiles = 5000
+)
+
+// errMaxContainerLogFiles stops the walk of the pod logs
+var errMaxContainerLogFiles = errors.New("max number of container log files reached")
+
+// ContainerLogsInfo holds the permissions of the container logs of the node
+type ContainerLogsInfo struct {
+	// /var/log/pods, /var/log/containers, and the pod and container log directories
+	Directories []*FileInfo `json:"directories"`
+
+	// The log files under /var/log/pods, and the regular files of /var/log/containers
+	LogFiles []*FileInfo `json:"logFiles"`
+
+	// The symlinks of /var/log/containers to the log files
+	Links []ContainerLogLink `json:"links"`
+
+	// True if there were more than the max number of log files
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// ContainerLogLink is a symlink of /var/log/containers
+type ContainerLogLink struct {
+	Path string `json:"path"`
+
+	// The absolute link target
+	Target string `json:"target"`
+
+	// The target file, nil if the link is dangling
+	TargetFile *FileInfo `json:"targetFile,omitempty"`
+}
+
+// SenseContainerLogs returns the permissions of the container log directories and files, and the symlink targets
+// of /var/log/containers. The log files aren't hashed, since they may be large.
+func SenseContainerLogs(ctx context.Context) (*ContainerLogsInfo, error) {
+	ret := &ContainerLogsInfo{Directories: []*FileInfo{}, LogFiles: []*FileInfo{}, Links: []ContainerLogLink{}}
+
+	fs.WalkDir(hostFS(ctx), podLogsDir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			logger(ctx).Debug("failed to walk", zap.String("path", filePath), zap.Error(err))
+			recordCollectionError(ctx, "walk", filePath, err)
+			return nil
+		}
+		advanceProgress(ctx, filePath, 1, 0)
+		switch {
+		case d.IsDir():
+			ret.addDirectory(ctx, filePath)
+		case d.Type().IsRegular():
+			if !ret.addLogFile(ctx, filePath) {
+				return errMaxContainerLogFiles
+			}
+		}
+		return nil
+	})
+
+	entries, err := readHostDir(ctx, containerLogsDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ret, nil
+		}
+		return nil, err
+	}
+	ret.addDirectory(ctx, containerLogsDir)
+	for _, entry := range entries {
+		filePath := path.Join(containerLogsDir, entry.Name())
+		switch {
+		case entry.Type()&fs.ModeSymlink != 0:
+			ret.Links = append(ret.Links, readContainerLogLink(ctx, filePath))
+		case entry.Type().IsRegular():
+			ret.addLogFile(ctx, filePath)
+		}
+	}
+	return ret, nil
+}
+
+func (info *ContainerLogsInfo) addDirectory(ctx context.Context, dirPath string) {
+	if dir := makeHostFileInfoVerbose(ctx, dirPath, false); dir != nil {
+		info.Directories = append(info.Directories, dir)
+	}
+}
+
+// addLogFile adds a log file, returns false if the max number of log files is reached
+func (info *ContainerLogsInfo) addLogFile(ctx context.Context, filePath string) bool {
+	if len(info.LogFiles) == maxContainerLogFiles {
+		info.Truncated = true
+		return false
+	}
+	file, err := makeHostFileStatInfo(ctx, filePath)
+	if err != nil {
+		logger(ctx).Debug("failed to stat log file", zap.String("path", filePath), zap.Error(err))
+		return true
+	}
+	info.LogFiles = append(info.LogFiles, file)
+	return true
+}
+
+// readContainerLogLink reads a symlink of a host path, whose target is resolved in the host file system
+func readContainerLogLink(ctx context.Context, linkPath string) ContainerLogLink {
+	link := ContainerLogLink{Path: linkPath}
+	target, err := hostFS(ctx).Readlink(linkPath)
+	if err != nil {
+		logger(ctx).Debug("failed to read link", zap.String("path", linkPath), zap.Error(err))
+		return link
+	}
+	if !path.IsAbs(target) {
+		target = path.Join(path.Dir(linkPath), target)
+	}
+	link.Target = path.Clean(target)
+
+	if file, err := makeHostFileStatInfo(ctx, link.Target); err == nil {
+		link.TargetFile = file
+	} else {
+		logger(ctx).Debug("failed to stat link target", zap.String("path", link.Target), zap.Error(err))
+		recordCollectionError(ctx, "stat", link.Target, err)
+	}
+	return link
+}
@@ -0,0 +1,54 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenseContainerLogs(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	logFile := "/var/log/pods/default_web-1_0a1b/nginx/0.log"
+	writeFSFile(t, hfs, logFile, []byte("log line\n"))
+	require.NoError(t, os.Chmod(hfs.Path(logFile), 0o640))
+	writeFSFile(t, hfs, "/etc/shadow", []byte("root:*:19000:0:99999:7:::\n"))
+	require.NoError(t, os.MkdirAll(hfs.Path(containerLogsDir), 0o755))
+	require.NoError(t, os.Symlink(logFile, hfs.Path("/var/log/containers/web-1_default_nginx-0a1b.log")))
+	require.NoError(t, os.Symlink("../../../etc/shadow", hfs.Path("/var/log/containers/rogue.log")))
+	require.NoError(t, os.Symlink("/var/log/pods/gone/app/0.log", hfs.Path("/var/log/containers/gone.log")))
+
+	info, err := SenseContainerLogs(ctx)
+	require.NoError(t, err)
+
+	dirs := []string{}
+	for _, dir := range info.Directories {
+		dirs = append(dirs, dir.Path)
+	}
+	assert.Equal(t, []string{
+		"/var/log/pods",
+		"/var/log/pods/default_web-1_0a1b",
+		"/var/log/pods/default_web-1_0a1b/nginx",
+		"/var/log/containers",
+	}, dirs)
+
+	require.Len(t, info.LogFiles, 1)
+	assert.Equal(t, logFile, info.LogFiles[0].Path)
+	assert.Equal(t, 0o640, info.LogFiles[0].Permissions)
+	assert.Empty(t, info.LogFiles[0].SHA256)
+
+	require.Len(t, info.Links, 3)
+	assert.Equal(t, "/var/log/containers/gone.log", info.Links[0].Path)
+	assert.Equal(t, "/var/log/pods/gone/app/0.log", info.Links[0].Target)
+	assert.Nil(t, info.Links[0].TargetFile)
+	assert.Equal(t, "/etc/shadow", info.Links[1].Target)
+	require.NotNil(t, info.Links[1].TargetFile)
+	assert.Equal(t, "/etc/shadow", info.Links[1].TargetFile.Path)
+	assert.Equal(t, logFile, info.Links[2].Target)
+	require.NotNil(t, info.Links[2].TargetFile)
+	assert.False(t, info.Truncated)
+}
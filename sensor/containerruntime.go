@@ -1,6 +1,7 @@
 package sensor
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
@@ -80,30 +81,30 @@ type ContainerRuntimeInfo struct {
 // 	1. Find CNI config dir through kubelet flag (--container-runtime-endpoint). If not found:
 // 	2. Find CNI config dir through process of supported container runtimes. If not found:
 // 	3. return CNI config dir default that is defined in the container runtime properties.
-func getCNIConfigPath() string {
+func getCNIConfigPath(ctx context.Context) string {
 
 	// Attempting to find CR from kubelet.
-	CNIConfigDir := CNIConfigDirFromKubelet()
+	CNIConfigDir := CNIConfigDirFromKubelet(ctx)
 
 	if CNIConfigDir != "" {
 		return CNIConfigDir
 	}
 
 	// Could construct container runtime from kubelet
-	zap.L().Debug("getCNIConfigPath - failed to get CNI config dir through kubelete flags.")
+	logger(ctx).Debug("getCNIConfigPath - failed to get CNI config dir through kubelete flags.")
 
 	// Attempting to find CR through process.
-	cr, err := getContainerRuntimeFromProcess()
+	cr, err := getContainerRuntimeFromProcess(ctx)
 
 	if err != nil {
 		//Failed to get container runtime from process
-		zap.L().Debug("getCNIConfigPath - failed to get container runtime from process, return cni config dir default",
+		logger(ctx).Debug("getCNIConfigPath - failed to get container runtime from process, return cni config dir default",
 			zap.Error(err))
 
 		return CNIDefaultConfigDir
 	}
 
-	CNIConfigDir = cr.getCNIConfigDir()
+	CNIConfigDir = cr.getCNIConfigDir(ctx)
 	if CNIConfigDir == "" {
 		return CNIDefaultConfigDir
 	}
@@ -123,16 +124,16 @@ func (cr *ContainerRuntimeInfo) getConfigDirPath() string {
 }
 
 // getConfigPath - returns container runtime config path through process flag. If not found returns default.
-func (cr *ContainerRuntimeInfo) getConfigPath() string {
+func (cr *ContainerRuntimeInfo) getConfigPath(ctx context.Context) string {
 	configPath, _ := cr.process.GetArg(cr.properties.ConfigArgName)
 	if configPath == "" {
-		zap.L().Debug("getConfigPath - container runtime config file wasn't found through process flags, return default path",
+		logger(ctx).Debug("getConfigPath - container runtime config file wasn't found through process flags, return default path",
 			zap.String("Container Runtime Name", cr.properties.Name),
 			zap.String("defaultConfigPath", cr.properties.DefaultConfigPath))
 		configPath = cr.properties.DefaultConfigPath
 
 	} else {
-		zap.L().Debug("getConfigPath - container runtime config file found through process flags",
+		logger(ctx).Debug("getConfigPath - container runtime config file found through process flags",
 			zap.String("Container Runtime Name", cr.properties.Name),
 			zap.String("configPath", configPath))
 	}
@@ -145,7 +146,7 @@ func (cr *ContainerRuntimeInfo) getConfigPath() string {
 // 	1. Getting container runtime configs directory path and container runtime config path.
 // 	2. Build a decending ordered list of configs from configs directory and adding the config path as last. This is the order of precedence for configuration.
 // 	3. Get CNI config path from ordered list. If not found, return empty string.
-func (cr *ContainerRuntimeInfo) getCNIConfigDirFromConfig() string {
+func (cr *ContainerRuntimeInfo) getCNIConfigDirFromConfig(ctx context.Context) string {
 
 	var configDirFilesFullPath []string
 
@@ -156,7 +157,7 @@ func (cr *ContainerRuntimeInfo) getCNIConfigDirFromConfig() string {
 	outputDirFiles, err := os.ReadDir(configDirPath)
 
 	if err != nil {
-		zap.L().Error("getCNIConfigDirFromConfig- Failed to Call ReadDir",
+		logger(ctx).Error("getCNIConfigDirFromConfig- Failed to Call ReadDir",
 			zap.String("configDirPath", configDirPath),
 			zap.Error(err))
 	} else {
@@ -170,17 +171,17 @@ func (cr *ContainerRuntimeInfo) getCNIConfigDirFromConfig() string {
 		sort.Sort(sort.Reverse(sort.StringSlice(configDirFilesFullPath)))
 	}
 
-	configPath := cr.getConfigPath()
+	configPath := cr.getConfigPath(ctx)
 
 	//appending config file to the end of the list as it always has the lowest priority.
 	if configPath != "" {
 		configDirFilesFullPath = append(configDirFilesFullPath, configPath)
 	}
 
-	CNIConfigDir := cr.getCNIConfigDirFromConfigPaths(configDirFilesFullPath)
+	CNIConfigDir := cr.getCNIConfigDirFromConfigPaths(ctx, configDirFilesFullPath)
 
 	if CNIConfigDir == "" {
-		zap.L().Debug("getCNIConfigDirFromConfig didn't find CNI Config dir in container runtime configs", zap.String("Container Runtime Name", cr.properties.Name))
+		logger(ctx).Debug("getCNIConfigDirFromConfig didn't find CNI Config dir in container runtime configs", zap.String("Container Runtime Name", cr.properties.Name))
 	}
 
 	return CNIConfigDir
@@ -188,13 +189,13 @@ func (cr *ContainerRuntimeInfo) getCNIConfigDirFromConfig() string {
 }
 
 // getCNIConfigDirFromConfigPaths - Get a list of configpaths, run through the paths by order, parse the CNI config dir and return once found. If not found, return empty string.
-func (cr *ContainerRuntimeInfo) getCNIConfigDirFromConfigPaths(configPaths []string) string {
+func (cr *ContainerRuntimeInfo) getCNIConfigDirFromConfigPaths(ctx context.Context, configPaths []string) string {
 
 	for _, configPath := range configPaths {
 		CNIConfigDir, err := cr.properties.ParseCNIFromConfigFunc(configPath)
 
 		if err != nil {
-			zap.L().Debug("getCNIConfigDirFromConfigPaths - Failed to parse config file", zap.String("configPath", configPath), zap.Error(err))
+			logger(ctx).Debug("getCNIConfigDirFromConfigPaths - Failed to parse config file", zap.String("configPath", configPath), zap.Error(err))
 			continue
 		}
 
@@ -209,12 +210,12 @@ func (cr *ContainerRuntimeInfo) getCNIConfigDirFromConfigPaths(configPaths []str
 }
 
 // getCNIConfigDirFromProcess - returns CNI config dir from process cmdline flags if defined, otherwise returns empty string.
-func (cr *ContainerRuntimeInfo) getCNIConfigDirFromProcess() string {
+func (cr *ContainerRuntimeInfo) getCNIConfigDirFromProcess(ctx context.Context) string {
 
 	if cr.properties.CNIConfigDirArgName != "" {
 		CNIConfigDir, _ := cr.process.GetArg(cr.properties.CNIConfigDirArgName)
 		if CNIConfigDir != "" {
-			zap.L().Debug("getCNIConfigDir found CNI Config Dir in process", zap.String("Container Runtime Name", cr.properties.Name))
+			logger(ctx).Debug("getCNIConfigDir found CNI Config Dir in process", zap.String("Container Runtime Name", cr.properties.Name))
 		}
 
 		return CNIConfigDir
@@ -228,15 +229,15 @@ func (cr *ContainerRuntimeInfo) getCNIConfigDirFromProcess() string {
 // 	1. Get dir from container runtime process flags. If not found:
 // 	2. Get dir from container runtime config file(s). If not found:
 // 	3. return default CNI config dir
-func (cr *ContainerRuntimeInfo) getCNIConfigDir() string {
+func (cr *ContainerRuntimeInfo) getCNIConfigDir(ctx context.Context) string {
 
-	CNIConfigDir := cr.getCNIConfigDirFromProcess()
+	CNIConfigDir := cr.getCNIConfigDirFromProcess(ctx)
 
 	if CNIConfigDir != "" {
 		return CNIConfigDir
 	}
 
-	CNIConfigDir = cr.getCNIConfigDirFromConfig()
+	CNIConfigDir = cr.getCNIConfigDirFromConfig(ctx)
 
 	return CNIConfigDir
 }
@@ -271,7 +272,7 @@ func crioProps() *containerRuntimeProperties {
 
 // newContainerRuntime is a constructor for ContainerRuntime object. Constructor will fail if process wasn't found for container runtime.
 // Constructor accept CRIKind as parameter which can be either a container runtime name or container runtime process suffix.
-func newContainerRuntime(CRIKind string) (*ContainerRuntimeInfo, error) {
+func newContainerRuntime(ctx context.Context, CRIKind string) (*ContainerRuntimeInfo, error) {
 
 	cr := &ContainerRuntimeInfo{}
 
@@ -285,7 +286,7 @@ func newContainerRuntime(CRIKind string) (*ContainerRuntimeInfo, error) {
 		return nil, fmt.Errorf("newContainerRuntime of kind '%s' is not supported", CRIKind)
 
 	}
-	p, err := LocateProcessByExecSuffix(cr.properties.ProcessSuffix)
+	p, err := LocateProcessByExecSuffix(ctx, cr.properties.ProcessSuffix)
 
 	// if process wasn't find, fail to construct object
 	if err != nil || p == nil {
@@ -293,19 +294,19 @@ func newContainerRuntime(CRIKind string) (*ContainerRuntimeInfo, error) {
 	}
 
 	cr.process = p
-	cr.rootDir = hostFileSystemDefaultLocation
+	cr.rootDir = hostFS(ctx).Root()
 
 	return cr, nil
 
 }
 
 // getContainerRuntimeFromProcess - returns first container runtime found by process.
-func getContainerRuntimeFromProcess() (*ContainerRuntimeInfo, error) {
+func getContainerRuntimeFromProcess(ctx context.Context) (*ContainerRuntimeInfo, error) {
 
-	crObj, err := newContainerRuntime(containerdContainerRuntimeName)
+	crObj, err := newContainerRuntime(ctx, containerdContainerRuntimeName)
 
 	if err != nil {
-		crObj, err = newContainerRuntime(crioContainerRuntimeName)
+		crObj, err = newContainerRuntime(ctx, crioContainerRuntimeName)
 
 		if err != nil {
 			return nil, fmt.Errorf("getContainerRuntimeFromProcess didnt find Container Runtime process")
@@ -356,12 +357,12 @@ func parseCNIConfigDirFromConfigCrio(configPath string) (string, error) {
 
 // CNIConfigDirFromKubelet - returns cni config dir by kubelet --container-runtime-endpoint flag. Returns empty string if not found.
 // A specific case is cri-dockerd.sock process which it's container runtime is determined by kubernetes docs.
-func CNIConfigDirFromKubelet() string {
+func CNIConfigDirFromKubelet(ctx context.Context) string {
 
 	var containerProcessSock string
-	proc, err := LocateKubeletProcess()
+	proc, err := LocateKubeletProcess(ctx)
 	if err != nil {
-		zap.L().Debug("CNIConfigDirFromKubelet - failed to locate kube-proxy process")
+		logger(ctx).Debug("CNIConfigDirFromKubelet - failed to locate kube-proxy process")
 		return ""
 	}
 
@@ -379,17 +380,17 @@ func CNIConfigDirFromKubelet() string {
 		if (!crEndPointOK && !crOK) || (cr != "remote") {
 			// From docs: "If your nodes use Kubernetes v1.23 and earlier and these flags aren't present
 			// or if the --container-runtime flag is not remote, you use the dockershim socket with Docker Engine."
-			zap.L().Debug("CNIConfigDirFromKubelet - no kubelet flags or --container-runtime not 'remote' means dockershim.sock which is not supported")
+			logger(ctx).Debug("CNIConfigDirFromKubelet - no kubelet flags or --container-runtime not 'remote' means dockershim.sock which is not supported")
 			return ""
 
 		}
 		// Uknown
-		zap.L().Debug("CNIConfigDirFromKubelet - failed to find Container Runtime EndPoint")
+		logger(ctx).Debug("CNIConfigDirFromKubelet - failed to find Container Runtime EndPoint")
 		return ""
 
 	}
 	// there is crEndpoint
-	zap.L().Debug("crEndPoint from kubelete found", zap.String("crEndPoint", crEndpoint))
+	logger(ctx).Debug("crEndPoint from kubelete found", zap.String("crEndPoint", crEndpoint))
 
 	containerProcessSock = crEndpoint
 
@@ -400,11 +401,11 @@ func CNIConfigDirFromKubelet() string {
 
 	}
 
-	crObj, err := newContainerRuntime(containerProcessSock)
+	crObj, err := newContainerRuntime(ctx, containerProcessSock)
 
 	if err != nil {
 		return ""
 	}
 
-	return crObj.getCNIConfigDir()
+	return crObj.getCNIConfigDir(ctx)
 }
@@ -1,22 +1,37 @@
 package sensor
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"net/http"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
 
 	"go.uber.org/zap"
 	"gopkg.in/yaml.v3"
 )
 
 const (
-	apiServerExe                   = "/kube-apiserver"
-	controllerManagerExe           = "/kube-controller-manager"
-	schedulerExe                   = "/kube-scheduler"
-	etcdExe                        = "/etcd"
-	etcdDataDirArg                 = "--data-dir"
-	apiEncryptionProviderConfigArg = "--encryption-provider-config"
+	apiServerExe                      = "/kube-apiserver"
+	controllerManagerExe              = "/kube-controller-manager"
+	schedulerExe                      = "/kube-scheduler"
+	etcdExe                           = "/etcd"
+	etcdDataDirArg                    = "--data-dir"
+	apiServerEtcdServersArg           = "--etcd-servers"
+	apiEncryptionProviderConfigArg    = "--encryption-provider-config"
+	apiTokenAuthFileArg               = "--token-auth-file"
+	apiAuthenticationWebhookConfigArg = "--authentication-token-webhook-config-file"
+	apiAuthorizationWebhookConfigArg  = "--authorization-webhook-config-file"
+	clientCAFileArg                   = "--client-ca-file"
+	bindAddressArg                    = "--bind-address"
+	profilingArg                      = "--profiling"
+	useServiceAccountCredentialsArg   = "--use-service-account-credentials"
+	terminatedPodGCThresholdArg       = "--terminated-pod-gc-threshold"
 
 	// Default files paths according to https://workbench.cisecurity.org/benchmarks/8973/sections/1126652
 	apiServerSpecsPath          = "/etc/kubernetes/manifests/kube-apiserver.yaml"
@@ -31,8 +46,21 @@ const (
 	// TODO: cni
 )
 
+// etcd topologies
+const (
+	// etcd runs on the control plane nodes, next to the API server
+	EtcdTopologyStacked = "stacked"
+
+	// etcd runs off the control plane nodes
+	EtcdTopologyExternal = "external"
+)
+
 var (
 	ErrDataDirNotFound = errors.New("failed to find etcd data-dir")
+	ErrEtcdNotRunning  = errors.New("etcd is not running on the node")
+
+	// Overridden by tests
+	interfaceAddrs = net.InterfaceAddrs
 )
 
 // KubeProxyInfo holds information about kube-proxy process
@@ -42,10 +70,18 @@ type ControlPlaneInfo struct {
 	SchedulerInfo         *K8sProcessInfo `json:"schedulerInfo,omitempty"`
 	EtcdConfigFile        *FileInfo       `json:"etcdConfigFile,omitempty"`
 	EtcdDataDir           *FileInfo       `json:"etcdDataDir,omitempty"`
+	EtcdDataDirFiles      []*FileInfo     `json:"etcdDataDirFiles,omitempty"`
+	Etcd                  *EtcdTopology   `json:"etcd,omitempty"`
 	AdminConfigFile       *FileInfo       `json:"adminConfigFile,omitempty"`
 	PKIDIr                *FileInfo       `json:"PKIDir,omitempty"`
 	PKIFiles              []*FileInfo     `json:"PKIFiles,omitempty"`
 	CNIConfigFiles        []*FileInfo     `json:"CNIConfigFiles"`
+
+	// The plugin chains of the CNI config files
+	CNINetworks []CNINetwork `json:"CNINetworks,omitempty"`
+
+	// The network config which the container runtime uses, and the conflicts of the CNI configs and agents
+	CNIConflicts *CNIConflictInfo `json:"CNIConflicts,omitempty"`
 }
 
 // K8sProcessInfo holds information about a k8s process
@@ -64,19 +100,78 @@ type K8sProcessInfo struct {
 
 	// Raw cmd line of the process
 	CmdLine string `json:"cmdLine"`
+
+	// The systemd unit of the process if it doesn't run as a static pod, its unit file is the specs file
+	Service *ProcessService `json:"service,omitempty"`
+
+	// The security flags of the scheduler and the controller manager
+	Flags *ControlPlaneFlags `json:"flags,omitempty"`
+}
+
+// ControlPlaneFlags are the security flags of the scheduler and the controller manager (CIS 1.3 and 1.4).
+// A flag which isn't set is nil, and has its default value: 0.0.0.0, true, false and 12500.
+type ControlPlaneFlags struct {
+	BindAddress *string `json:"bindAddress,omitempty"`
+	Profiling   *bool   `json:"profiling,omitempty"`
+
+	// Of the controller manager only
+	UseServiceAccountCredentials *bool `json:"useServiceAccountCredentials,omitempty"`
+	TerminatedPodGCThreshold     *int  `json:"terminatedPodGCThreshold,omitempty"`
+}
+
+// ProcessService holds the systemd unit of a control plane process
+type ProcessService struct {
+	Unit string `json:"unit"`
+
+	DropInFiles []*FileInfo `json:"dropInFiles,omitempty"`
+
+	// The EnvironmentFiles of the unit, which hold the args with some installers
+	EnvironmentFiles []*FileInfo `json:"environmentFiles,omitempty"`
+}
+
+// EtcdTopology holds the topology of the etcd cluster of the API server
+type EtcdTopology struct {
+	// One of EtcdTopology*, empty if the API server doesn't run on the node
+	Topology string `json:"topology,omitempty"`
+
+	// The etcd servers of the API server
+	Servers []EtcdServer `json:"servers,omitempty"`
+
+	// True if an etcd member runs on the node
+	LocalMember bool `json:"localMember"`
+}
+
+// EtcdServer is an etcd server of the API server
+type EtcdServer struct {
+	URL string `json:"url"`
+
+	// True if the server is on the node
+	Local bool `json:"local"`
 }
 
 type ApiServerInfo struct {
 	EncryptionProviderConfigFile *FileInfo `json:"encryptionProviderConfigFile,omitempty"`
-	*K8sProcessInfo              `json:",inline"`
+
+	// The egress selector configuration, nil if the API server connects directly
+	EgressSelectorConfig *EgressSelectorConfig `json:"egressSelectorConfig,omitempty"`
+
+	// The static token file (without content), and the number of its tokens
+	TokenAuthFile    *FileInfo `json:"tokenAuthFile,omitempty"`
+	StaticTokenCount int       `json:"staticTokenCount,omitempty"`
+
+	// The kubeconfig files of the authentication and authorization webhooks (without content)
+	AuthenticationTokenWebhookConfigFile *FileInfo `json:"authenticationTokenWebhookConfigFile,omitempty"`
+	AuthorizationWebhookConfigFile       *FileInfo `json:"authorizationWebhookConfigFile,omitempty"`
+
+	*K8sProcessInfo `json:",inline"`
 }
 
 // getEtcdDataDir find the `data-dir` path of etcd k8s component
-func getEtcdDataDir() (string, error) {
+func getEtcdDataDir(ctx context.Context) (string, error) {
 
-	proc, err := LocateProcessByExecSuffix(etcdExe)
+	proc, err := LocateProcessByExecSuffix(ctx, etcdExe)
 	if err != nil {
-		return "", fmt.Errorf("failed to locate kube-proxy process: %w", err)
+		return "", ErrEtcdNotRunning
 	}
 
 	dataDir, ok := proc.GetArg(etcdDataDirArg)
@@ -87,29 +182,58 @@ func getEtcdDataDir() (string, error) {
 	return dataDir, nil
 }
 
-func makeProcessInfoVerbose(p *ProcessDetails, specsPath, configPath, kubeConfigPath, clientCaPath string) *K8sProcessInfo {
+// k8sProcessFiles holds the default paths of the files of a k8s process, and the args overriding them.
+// The paths of the args are resolved in the mount namespace of the process.
+type k8sProcessFiles struct {
+	specsPath      string
+	configPath     string
+	configArg      string
+	kubeConfigPath string
+	kubeConfigArg  string
+	clientCAPath   string
+	clientCAArg    string
+}
+
+func makeProcessInfoVerbose(ctx context.Context, p *ProcessDetails, processFiles k8sProcessFiles) *K8sProcessInfo {
 	ret := K8sProcessInfo{}
 
 	// init files
 	files := []struct {
 		data **FileInfo
 		path string
+		arg  string
 		file string
 	}{
-		{&ret.SpecsFile, specsPath, "specs"},
-		{&ret.ConfigFile, configPath, "config"},
-		{&ret.KubeConfigFile, kubeConfigPath, "kubeconfig"},
-		{&ret.ClientCAFile, clientCaPath, "calient ca certificate"},
+		{&ret.SpecsFile, processFiles.specsPath, "", "specs"},
+		{&ret.ConfigFile, processFiles.configPath, processFiles.configArg, "config"},
+		{&ret.KubeConfigFile, processFiles.kubeConfigPath, processFiles.kubeConfigArg, "kubeconfig"},
+		{&ret.ClientCAFile, processFiles.clientCAPath, processFiles.clientCAArg, "client ca certificate"},
 	}
 
 	// get data
 	for i := range files {
 		file := &files[i]
+		if p != nil && file.arg != "" {
+			if argPath, ok := p.GetArg(file.arg); ok && argPath != "" {
+				fileInfo, err := makeContaineredFileInfo(ctx, argPath, false, p)
+				if err != nil {
+					logger(ctx).Error("failed to makeContaineredFileInfo",
+						zap.String("in", "makeProcessInfoVerbose"),
+						zap.String("file", file.file),
+						zap.String("path", argPath),
+						zap.Error(err),
+					)
+					recordCollectionError(ctx, "stat", argPath, err)
+				}
+				*file.data = fileInfo
+				continue
+			}
+		}
 		if file.path == "" {
 			continue
 		}
 
-		*file.data = makeHostFileInfoVerbose(file.path, false,
+		*file.data = makeHostFileInfoVerbose(ctx, file.path, false,
 			zap.String("in", "makeProcessInfoVerbose"),
 			zap.String("path", file.path),
 		)
@@ -117,6 +241,11 @@ func makeProcessInfoVerbose(p *ProcessDetails, specsPath, configPath, kubeConfig
 
 	if p != nil {
 		ret.CmdLine = p.RawCmd()
+
+		// some installers run the process as a systemd service
+		if ret.SpecsFile == nil {
+			ret.SpecsFile, ret.Service = makeProcessServiceInfo(ctx, p)
+		}
 	}
 
 	// Return `nil` if wasn't able to find any data
@@ -127,17 +256,113 @@ func makeProcessInfoVerbose(p *ProcessDetails, specsPath, configPath, kubeConfig
 	return &ret
 }
 
+// parseControlPlaneFlags returns the security flags of the scheduler or the controller manager process.
+// Invalid values are logged, and reported as not set.
+func parseControlPlaneFlags(ctx context.Context, p *ProcessDetails) *ControlPlaneFlags {
+	ret := &ControlPlaneFlags{}
+	if val, ok := p.GetArg(bindAddressArg); ok {
+		ret.BindAddress = &val
+	}
+	ret.Profiling = getBoolArg(ctx, p, profilingArg)
+	ret.UseServiceAccountCredentials = getBoolArg(ctx, p, useServiceAccountCredentialsArg)
+	if val, ok := p.GetArg(terminatedPodGCThresholdArg); ok {
+		threshold, err := strconv.Atoi(val)
+		if err != nil {
+			logger(ctx).Warn("invalid flag value", zap.String("flag", terminatedPodGCThresholdArg), zap.String("value", val))
+		} else {
+			ret.TerminatedPodGCThreshold = &threshold
+		}
+	}
+	return ret
+}
+
+// getBoolArg returns the value of a boolean flag, true if set without a value, nil if not set or invalid.
+// Unlike other flags, a boolean flag's value is never the next argument.
+func getBoolArg(ctx context.Context, p *ProcessDetails, arg string) *bool {
+	val, ok := "", false
+	for _, cmdArg := range p.CmdLine {
+		if cmdArg == arg {
+			val, ok = "true", true
+		} else if strings.HasPrefix(cmdArg, arg+"=") {
+			val, ok = cmdArg[len(arg)+1:], true
+		}
+	}
+	if !ok {
+		return nil
+	}
+	enabled, err := strconv.ParseBool(val)
+	if err != nil {
+		logger(ctx).Warn("invalid flag value", zap.String("flag", arg), zap.String("value", val))
+		return nil
+	}
+	return &enabled
+}
+
+// makeProcessServiceInfo returns the unit file and the systemd unit of a process, or nil if it has no unit file
+func makeProcessServiceInfo(ctx context.Context, p *ProcessDetails) (*FileInfo, *ProcessService) {
+	unit := readSystemdUnit(ctx, getProcessUnitName(ctx, p))
+	if unit.UnitFile == "" {
+		return nil, nil
+	}
+	unitFile := makeHostFileInfoVerbose(ctx, unit.UnitFile, false, zap.String("in", "makeProcessServiceInfo"))
+	if unitFile == nil {
+		return nil, nil
+	}
+
+	service := &ProcessService{Unit: unit.Name}
+	for _, dropIn := range unit.DropInFiles {
+		if file := makeHostFileInfoVerbose(ctx, dropIn, false, zap.String("in", "makeProcessServiceInfo")); file != nil {
+			service.DropInFiles = append(service.DropInFiles, file)
+		}
+	}
+	for _, envFile := range getUnitEnvironmentFiles(ctx, append([]string{unit.UnitFile}, unit.DropInFiles...)) {
+		file, err := makeHostFileInfo(ctx, envFile, false)
+		if err != nil {
+			logger(ctx).Debug("failed to stat environment file", zap.String("path", envFile), zap.Error(err))
+			recordCollectionError(ctx, "stat", envFile, err)
+			continue
+		}
+		service.EnvironmentFiles = append(service.EnvironmentFiles, file)
+	}
+	return unitFile, service
+}
+
+// getProcessUnitName returns the systemd unit of a process from systemd, or its cgroup, or its executable name
+func getProcessUnitName(ctx context.Context, p *ProcessDetails) string {
+	unitName, err := getUnitNameByPID(ctx, int(p.PID))
+	if err != nil {
+		logger(ctx).Debug("failed to get unit by PID from systemd", zap.Error(err))
+	} else if strings.HasSuffix(unitName, ".service") {
+		return unitName
+	}
+
+	content, err := ReadFileOnHostFileSystem(ctx, path.Join(procDirName, fmt.Sprint(p.PID), "cgroup"))
+	if err == nil {
+		// e.g. "0::/system.slice/kube-apiserver.service"
+		for _, line := range strings.Split(strings.TrimSpace(string(content)), "\n") {
+			if unitName := path.Base(line); strings.HasSuffix(unitName, ".service") {
+				return unitName
+			}
+		}
+	}
+
+	if len(p.CmdLine) == 0 {
+		return ""
+	}
+	return path.Base(p.CmdLine[0]) + ".service"
+}
+
 // makeAPIserverEncryptionProviderConfigFile returns a FileInfo object for the encryption provider config file of the API server. Required for https://workbench.cisecurity.org/sections/1126663/recommendations/1838675
-func makeAPIserverEncryptionProviderConfigFile(p *ProcessDetails) *FileInfo {
+func makeAPIserverEncryptionProviderConfigFile(ctx context.Context, p *ProcessDetails) *FileInfo {
 	encryptionProviderConfigPath, ok := p.GetArg(apiEncryptionProviderConfigArg)
 	if !ok {
-		zap.L().Warn("failed to find encryption provider config path", zap.String("in", "makeAPIserverEncryptionProviderConfigFile"))
+		logger(ctx).Warn("failed to find encryption provider config path", zap.String("in", "makeAPIserverEncryptionProviderConfigFile"))
 		return nil
 	}
 
-	fi, err := makeContaineredFileInfo(encryptionProviderConfigPath, true, p)
+	fi, err := makeContaineredFileInfo(ctx, encryptionProviderConfigPath, true, p)
 	if err != nil {
-		zap.L().Warn("failed to create encryption provider config file info", zap.Error(err))
+		logger(ctx).Warn("failed to create encryption provider config file info", zap.Error(err))
 		return nil
 	}
 
@@ -147,7 +372,7 @@ func makeAPIserverEncryptionProviderConfigFile(p *ProcessDetails) *FileInfo {
 	if err != nil {
 		err = json.Unmarshal(fi.Content, &data)
 		if err != nil {
-			zap.L().Warn("failed to unmarshal encryption provider config file")
+			logger(ctx).Warn("failed to unmarshal encryption provider config file")
 			return nil
 		}
 	}
@@ -157,13 +382,53 @@ func makeAPIserverEncryptionProviderConfigFile(p *ProcessDetails) *FileInfo {
 	// marshal back to yaml
 	fi.Content, err = yaml.Marshal(data)
 	if err != nil {
-		zap.L().Warn("failed to marshal encryption provider config file", zap.Error(err))
+		logger(ctx).Warn("failed to marshal encryption provider config file", zap.Error(err))
 		return nil
 	}
 
 	return fi
 }
 
+// makeAPIServerArgFile returns the file (without content) of an API server flag, nil if the flag isn't set
+func makeAPIServerArgFile(ctx context.Context, p *ProcessDetails, arg string) *FileInfo {
+	filePath, ok := p.GetArg(arg)
+	if !ok || filePath == "" {
+		return nil
+	}
+	fi, err := makeContaineredFileInfo(ctx, filePath, false, p)
+	if err != nil {
+		logger(ctx).Warn("failed to create API server file info", zap.String("arg", arg), zap.String("path", filePath), zap.Error(err))
+		recordCollectionError(ctx, "stat", filePath, err)
+	}
+	return fi
+}
+
+// makeAPIServerTokenAuthFile returns the static token file of the API server (without content), and the number of
+// its tokens. The tokens themselves are never reported.
+func makeAPIServerTokenAuthFile(ctx context.Context, p *ProcessDetails) (*FileInfo, int) {
+	fi := makeAPIServerArgFile(ctx, p, apiTokenAuthFileArg)
+	if fi == nil {
+		return nil, 0
+	}
+	content, err := makeContaineredFileInfo(ctx, fi.Path, true, p)
+	if err != nil {
+		return fi, 0
+	}
+	return fi, countStaticTokens(content.Content)
+}
+
+// countStaticTokens counts the tokens of a static token file, a CSV of "token,user,uid[,groups]" lines
+func countStaticTokens(content []byte) int {
+	count := 0
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			count++
+		}
+	}
+	return count
+}
+
 func removeEncryptionProviderConfigSecrets(data map[string]interface{}) {
 	resources, ok := data["resources"].([]interface{})
 	if !ok {
@@ -216,81 +481,107 @@ func removeEncryptionProviderConfigSecrets(data map[string]interface{}) {
 }
 
 // SenseControlPlaneInfo return `ControlPlaneInfo`
-func SenseControlPlaneInfo() (*ControlPlaneInfo, error) {
+func SenseControlPlaneInfo(ctx context.Context) (*ControlPlaneInfo, error) {
 	var err error
 	ret := ControlPlaneInfo{}
 
 	debugInfo := zap.String("in", "SenseControlPlaneInfo")
 
-	apiProc, err := LocateProcessByExecSuffix(apiServerExe)
+	apiProc, err := LocateProcessByExecSuffix(ctx, apiServerExe)
 	if err == nil {
 		ret.APIServerInfo = &ApiServerInfo{}
-		ret.APIServerInfo.K8sProcessInfo = makeProcessInfoVerbose(apiProc, apiServerSpecsPath, "", "", "")
-		ret.APIServerInfo.EncryptionProviderConfigFile = makeAPIserverEncryptionProviderConfigFile(apiProc)
+		ret.APIServerInfo.K8sProcessInfo = makeProcessInfoVerbose(ctx, apiProc, k8sProcessFiles{
+			specsPath:   apiServerSpecsPath,
+			clientCAArg: clientCAFileArg,
+		})
+		ret.APIServerInfo.EncryptionProviderConfigFile = makeAPIserverEncryptionProviderConfigFile(ctx, apiProc)
+		ret.APIServerInfo.EgressSelectorConfig = makeAPIServerEgressSelectorConfig(ctx, apiProc)
+		ret.APIServerInfo.TokenAuthFile, ret.APIServerInfo.StaticTokenCount = makeAPIServerTokenAuthFile(ctx, apiProc)
+		ret.APIServerInfo.AuthenticationTokenWebhookConfigFile = makeAPIServerArgFile(ctx, apiProc, apiAuthenticationWebhookConfigArg)
+		ret.APIServerInfo.AuthorizationWebhookConfigFile = makeAPIServerArgFile(ctx, apiProc, apiAuthorizationWebhookConfigArg)
 	} else {
-		zap.L().Error("SenseControlPlaneInfo", zap.Error(err))
+		logger(ctx).Error("SenseControlPlaneInfo", zap.Error(err))
 	}
 
-	controllerMangerProc, err := LocateProcessByExecSuffix(controllerManagerExe)
+	controllerMangerProc, err := LocateProcessByExecSuffix(ctx, controllerManagerExe)
 	if err == nil {
-		ret.ControllerManagerInfo = makeProcessInfoVerbose(controllerMangerProc, controllerManagerSpecsPath, controllerManagerConfigPath, "", "")
+		ret.ControllerManagerInfo = makeProcessInfoVerbose(ctx, controllerMangerProc, k8sProcessFiles{
+			specsPath:   controllerManagerSpecsPath,
+			configPath:  controllerManagerConfigPath,
+			configArg:   kubeConfigArgName,
+			clientCAArg: clientCAFileArg,
+		})
+		ret.ControllerManagerInfo.Flags = parseControlPlaneFlags(ctx, controllerMangerProc)
 	} else {
-		zap.L().Error("SenseControlPlaneInfo", zap.Error(err))
+		logger(ctx).Error("SenseControlPlaneInfo", zap.Error(err))
 	}
 
-	SchedulerProc, err := LocateProcessByExecSuffix(schedulerExe)
+	SchedulerProc, err := LocateProcessByExecSuffix(ctx, schedulerExe)
 	if err == nil {
-		ret.SchedulerInfo = makeProcessInfoVerbose(SchedulerProc, schedulerSpecsPath, schedulerConfigPath, "", "")
+		ret.SchedulerInfo = makeProcessInfoVerbose(ctx, SchedulerProc, k8sProcessFiles{
+			specsPath:   schedulerSpecsPath,
+			configPath:  schedulerConfigPath,
+			configArg:   kubeConfigArgName,
+			clientCAArg: clientCAFileArg,
+		})
+		ret.SchedulerInfo.Flags = parseControlPlaneFlags(ctx, SchedulerProc)
 	} else {
-		zap.L().Error("SenseControlPlaneInfo", zap.Error(err))
+		logger(ctx).Error("SenseControlPlaneInfo", zap.Error(err))
 	}
 
 	// EtcdConfigFile
-	ret.EtcdConfigFile = makeHostFileInfoVerbose(etcdConfigPath,
+	ret.EtcdConfigFile = makeHostFileInfoVerbose(ctx, etcdConfigPath,
 		false,
 		debugInfo,
 		zap.String("component", "EtcdConfigFile"),
 	)
 
 	// AdminConfigFile
-	ret.AdminConfigFile = makeHostFileInfoVerbose(adminConfigPath,
+	ret.AdminConfigFile = makeHostFileInfoVerbose(ctx, adminConfigPath,
 		false,
 		debugInfo,
 		zap.String("component", "AdminConfigFile"),
 	)
 
 	// PKIDIr
-	ret.PKIDIr = makeHostFileInfoVerbose(pkiDir,
+	ret.PKIDIr = makeHostFileInfoVerbose(ctx, pkiDir,
 		false,
 		debugInfo,
 		zap.String("component", "PKIDIr"),
 	)
 
 	// PKIFiles
-	ret.PKIFiles, err = makeHostDirFilesInfo(pkiDir, true, nil, 0)
+	ret.PKIFiles, err = makeHostDirFilesInfo(ctx, pkiDir, true, nil, 0)
 	if err != nil {
-		zap.L().Error("SenseControlPlaneInfo failed to get PKIFiles info", zap.Error(err))
+		logger(ctx).Error("SenseControlPlaneInfo failed to get PKIFiles info", zap.Error(err))
 	}
 
-	// etcd data-dir
-	etcdDataDir, err := getEtcdDataDir()
-	if err != nil {
-		zap.L().Error("SenseControlPlaneInfo", zap.Error(ErrDataDirNotFound))
-	} else {
-		ret.EtcdDataDir = makeHostFileInfoVerbose(etcdDataDir,
+	// etcd topology and data-dir
+	ret.Etcd = getEtcdTopology(ctx, apiProc)
+	etcdDataDir, err := getEtcdDataDir(ctx)
+	switch {
+	case errors.Is(err, ErrEtcdNotRunning):
+		logger(ctx).Debug("SenseControlPlaneInfo", zap.Error(err))
+	case err != nil:
+		logger(ctx).Error("SenseControlPlaneInfo", zap.Error(err))
+	default:
+		ret.EtcdDataDir = makeHostFileInfoVerbose(ctx, etcdDataDir,
 			false,
 			debugInfo,
 			zap.String("component", "EtcdDataDir"),
 		)
+		ret.EtcdDataDirFiles = makeEtcdDataDirFiles(ctx, etcdDataDir)
 	}
 
 	// make cni config files
-	CNIConfigInfo, err := makeCNIConfigFilesInfo()
+	CNIConfigDir, CNIConfigInfo, err := makeCNIConfigFilesInfo(ctx)
 
 	if err != nil {
-		zap.L().Error("SenseControlPlaneInfo", zap.Error(err))
+		logger(ctx).Error("SenseControlPlaneInfo", zap.Error(err))
 	} else {
 		ret.CNIConfigFiles = CNIConfigInfo
+		ret.CNINetworks = makeCNINetworks(ctx, CNIConfigInfo)
+		ret.CNIConflicts = detectCNIConflicts(CNIConfigDir, ret.CNINetworks, senseCNIAgents(ctx))
 	}
 
 	// If wasn't able to find any data - this is not a control plane
@@ -299,37 +590,93 @@ func SenseControlPlaneInfo() (*ControlPlaneInfo, error) {
 		ret.SchedulerInfo == nil &&
 		ret.EtcdConfigFile == nil &&
 		ret.EtcdDataDir == nil &&
+		ret.Etcd == nil &&
 		ret.AdminConfigFile == nil {
-		return nil, &SenseError{
-			Massage:  "not a control plane node",
-			Function: "SenseControlPlaneInfo",
-			Code:     http.StatusNotFound,
-		}
+		return nil, newSenseError(ErrNotControlPlane, "SenseControlPlaneInfo", nil)
 	}
 
 	return &ret, nil
 }
 
-// makeCNIConfigFilesInfo - returns a list of FileInfos of cni config files.
-func makeCNIConfigFilesInfo() ([]*FileInfo, error) {
+// getEtcdTopology returns the etcd topology of the API server process (which may be nil), and whether an etcd member
+// runs on the node. It returns nil if neither the API server nor etcd run on the node.
+func getEtcdTopology(ctx context.Context, apiProc *ProcessDetails) *EtcdTopology {
+	ret := &EtcdTopology{}
+	if _, err := LocateProcessByExecSuffix(ctx, etcdExe); err == nil {
+		ret.LocalMember = true
+	}
+	if apiProc == nil {
+		if !ret.LocalMember {
+			return nil
+		}
+		return ret
+	}
+
+	servers, _ := apiProc.GetArg(apiServerEtcdServersArg)
+	ret.Servers, ret.Topology = classifyEtcdServers(ctx, servers)
+	return ret
+}
+
+// classifyEtcdServers returns the servers of the --etcd-servers flag, and the topology: stacked if any server is on
+// the node, external otherwise
+func classifyEtcdServers(ctx context.Context, servers string) ([]EtcdServer, string) {
+	localIPs := map[string]bool{}
+	if addrs, err := interfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				localIPs[ipNet.IP.String()] = true
+			}
+		}
+	} else {
+		logger(ctx).Debug("failed to get the node addresses", zap.Error(err))
+	}
+	hostname, _ := os.Hostname()
+
+	ret := []EtcdServer{}
+	topology := EtcdTopologyExternal
+	for _, server := range strings.Split(servers, ",") {
+		server = strings.TrimSpace(server)
+		if server == "" {
+			continue
+		}
+		host := server
+		if u, err := url.Parse(server); err == nil && u.Host != "" {
+			host = u.Hostname()
+		}
+		ip := net.ParseIP(host)
+		local := host == "localhost" || (hostname != "" && host == hostname) ||
+			(ip != nil && (ip.IsLoopback() || localIPs[ip.String()]))
+		if local {
+			topology = EtcdTopologyStacked
+		}
+		ret = append(ret, EtcdServer{URL: server, Local: local})
+	}
+	if len(ret) == 0 {
+		return ret, ""
+	}
+	return ret, topology
+}
+
+// makeCNIConfigFilesInfo - returns the cni config dir, and a list of FileInfos of its cni config files.
+func makeCNIConfigFilesInfo(ctx context.Context) (string, []*FileInfo, error) {
 	// *** Start handling CNI Files
-	CNIConfigDir := getCNIConfigPath()
+	CNIConfigDir := getCNIConfigPath(ctx)
 
 	if CNIConfigDir == "" {
-		return nil, fmt.Errorf("no CNI Config dir found in getCNIConfigPath")
+		return "", nil, fmt.Errorf("no CNI Config dir found in getCNIConfigPath")
 	}
 
 	//Getting CNI config files
-	CNIConfigInfo, err := makeHostDirFilesInfo(CNIConfigDir, true, nil, 0)
+	CNIConfigInfo, err := makeHostDirFilesInfo(ctx, CNIConfigDir, true, nil, 0)
 
 	if err != nil {
-		return nil, fmt.Errorf("failed to makeHostDirFilesInfo for CNIConfigDir %s: %w", CNIConfigDir, err)
+		return "", nil, fmt.Errorf("failed to makeHostDirFilesInfo for CNIConfigDir %s: %w", CNIConfigDir, err)
 	}
 
 	if len(CNIConfigInfo) == 0 {
-		zap.L().Debug("SenseControlPlaneInfo - no cni config files were found.",
+		logger(ctx).Debug("SenseControlPlaneInfo - no cni config files were found.",
 			zap.String("path", CNIConfigDir))
 	}
 
-	return CNIConfigInfo, nil
+	return CNIConfigDir, CNIConfigInfo, nil
 }
@@ -1,9 +1,14 @@
 package sensor
 
 import (
+	"context"
 	"encoding/json"
+	"net"
+	"os"
+	"path"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -84,3 +89,106 @@ func Test_removeEncryptionProviderConfigSecrets(t *testing.T) {
 		})
 	}
 }
+
+func TestClassifyEtcdServers(t *testing.T) {
+	defer func(orig func() ([]net.Addr, error)) { interfaceAddrs = orig }(interfaceAddrs)
+	interfaceAddrs = func() ([]net.Addr, error) {
+		return []net.Addr{&net.IPNet{IP: net.ParseIP("10.0.0.5"), Mask: net.CIDRMask(24, 32)}}, nil
+	}
+
+	servers, topology := classifyEtcdServers(context.Background(), "https://127.0.0.1:2379")
+	assert.Equal(t, EtcdTopologyStacked, topology)
+	assert.Equal(t, []EtcdServer{{URL: "https://127.0.0.1:2379", Local: true}}, servers)
+
+	servers, topology = classifyEtcdServers(context.Background(), "https://10.0.0.5:2379,https://10.0.0.6:2379")
+	assert.Equal(t, EtcdTopologyStacked, topology)
+	assert.Equal(t, []EtcdServer{{URL: "https://10.0.0.5:2379", Local: true}, {URL: "https://10.0.0.6:2379"}}, servers)
+
+	servers, topology = classifyEtcdServers(context.Background(), "https://etcd-0.example.com:2379,https://10.0.1.7:2379")
+	assert.Equal(t, EtcdTopologyExternal, topology)
+	assert.Equal(t, []EtcdServer{{URL: "https://etcd-0.example.com:2379"}, {URL: "https://10.0.1.7:2379"}}, servers)
+
+	servers, topology = classifyEtcdServers(context.Background(), "")
+	assert.Empty(t, topology)
+	assert.Empty(t, servers)
+}
+
+func TestMakeProcessInfoVerboseSystemd(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+	writeFSFile(t, hfs, "/etc/systemd/system/kube-scheduler.service", []byte(`[Service]
+EnvironmentFile=-/etc/kubernetes/scheduler.env
+EnvironmentFile=-/etc/kubernetes/missing.env
+ExecStart=/usr/local/bin/kube-scheduler $KUBE_SCHEDULER_ARGS
+`))
+	writeFSFile(t, hfs, "/etc/systemd/system/kube-scheduler.service.d/10-args.conf", []byte("[Service]\nEnvironment=LOG_LEVEL=2\n"))
+	writeFSFile(t, hfs, "/etc/kubernetes/scheduler.env", []byte("KUBE_SCHEDULER_ARGS=--kubeconfig=/etc/kubernetes/scheduler.conf\n"))
+
+	p := &ProcessDetails{PID: -1, CmdLine: []string{"/usr/local/bin/kube-scheduler", "--v=2"}}
+	info := makeProcessInfoVerbose(ctx, p, k8sProcessFiles{specsPath: schedulerSpecsPath})
+	require.NotNil(t, info)
+	require.NotNil(t, info.SpecsFile)
+	assert.Equal(t, "/etc/systemd/system/kube-scheduler.service", info.SpecsFile.Path)
+	require.NotNil(t, info.Service)
+	assert.Equal(t, "kube-scheduler.service", info.Service.Unit)
+	require.Len(t, info.Service.DropInFiles, 1)
+	assert.Equal(t, "/etc/systemd/system/kube-scheduler.service.d/10-args.conf", info.Service.DropInFiles[0].Path)
+	require.Len(t, info.Service.EnvironmentFiles, 1)
+	assert.Equal(t, "/etc/kubernetes/scheduler.env", info.Service.EnvironmentFiles[0].Path)
+
+	// the static pod manifest wins
+	writeFSFile(t, hfs, schedulerSpecsPath, []byte("kind: Pod\n"))
+	info = makeProcessInfoVerbose(ctx, p, k8sProcessFiles{specsPath: schedulerSpecsPath})
+	require.NotNil(t, info)
+	assert.Equal(t, schedulerSpecsPath, info.SpecsFile.Path)
+	assert.Nil(t, info.Service)
+}
+
+func TestMakeProcessInfoVerboseArgs(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+	writeFSFile(t, hfs, controllerManagerConfigPath, []byte("kind: Config\n"))
+
+	// the args are resolved in the root of the process, the root of the tests
+	kubeconfigPath := path.Join(t.TempDir(), "controller-manager.conf")
+	require.NoError(t, os.WriteFile(kubeconfigPath, []byte("kind: Config\n"), 0600))
+	p := &ProcessDetails{PID: int32(os.Getpid()), CmdLine: []string{"kube-controller-manager", "--kubeconfig=" + kubeconfigPath}}
+
+	info := makeProcessInfoVerbose(ctx, p, k8sProcessFiles{
+		configPath:  controllerManagerConfigPath,
+		configArg:   kubeConfigArgName,
+		clientCAArg: clientCAFileArg,
+	})
+	require.NotNil(t, info)
+	require.NotNil(t, info.ConfigFile)
+	assert.Equal(t, kubeconfigPath, info.ConfigFile.Path)
+	assert.Nil(t, info.ClientCAFile)
+
+	// the default paths are host paths
+	p.CmdLine = p.CmdLine[:1]
+	info = makeProcessInfoVerbose(ctx, p, k8sProcessFiles{configPath: controllerManagerConfigPath, configArg: kubeConfigArgName})
+	require.NotNil(t, info)
+	require.NotNil(t, info.ConfigFile)
+	assert.Equal(t, controllerManagerConfigPath, info.ConfigFile.Path)
+}
+
+func TestCountStaticTokens(t *testing.T) {
+	assert.Equal(t, 2, countStaticTokens([]byte("# static tokens\n31ada4fd,admin,admin,\"system:masters\"\n\n8f2e9a1c,deployer,1001\n")))
+	assert.Zero(t, countStaticTokens(nil))
+}
+
+func TestParseControlPlaneFlags(t *testing.T) {
+	flags := parseControlPlaneFlags(context.Background(), &ProcessDetails{CmdLine: []string{"kube-controller-manager",
+		"--bind-address=127.0.0.1", "--profiling=false", "--use-service-account-credentials", "--terminated-pod-gc-threshold", "10"}})
+	require.NotNil(t, flags.BindAddress)
+	assert.Equal(t, "127.0.0.1", *flags.BindAddress)
+	require.NotNil(t, flags.Profiling)
+	assert.False(t, *flags.Profiling)
+	require.NotNil(t, flags.UseServiceAccountCredentials)
+	assert.True(t, *flags.UseServiceAccountCredentials)
+	require.NotNil(t, flags.TerminatedPodGCThreshold)
+	assert.Equal(t, 10, *flags.TerminatedPodGCThreshold)
+
+	flags = parseControlPlaneFlags(context.Background(), &ProcessDetails{CmdLine: []string{"kube-scheduler", "--profiling=maybe"}})
+	assert.Equal(t, &ControlPlaneFlags{}, flags)
+}
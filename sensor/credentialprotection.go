@@ -0,0 +1,100 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"path"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	procSysDir   = "/proc/sys"
+	procKeysPath = "/proc/keys"
+)
+
+var (
+	// The sysctls of the keyrings and of the protection of credentials and of files in world writable directories
+	credentialProtectionSysctls = []string{
+		"fs.protected_hardlinks",
+		"fs.protected_symlinks",
+		"fs.protected_fifos",
+		"fs.protected_regular",
+		"fs.suid_dumpable",
+		"kernel.yama.ptrace_scope",
+		"kernel.keys.maxkeys",
+		"kernel.keys.maxbytes",
+		"kernel.keys.root_maxkeys",
+		"kernel.keys.root_maxbytes",
+		"kernel.keys.gc_delay",
+		"kernel.keys.persistent_keyring_expiry",
+	}
+)
+
+// CredentialProtection holds the credential protection settings of the kernel
+type CredentialProtection struct {
+	// The values of the sysctls, by name, e.g. "fs.protected_hardlinks". Sysctls the kernel doesn't have are omitted.
+	Sysctls map[string]string `json:"sysctls"`
+
+	// A summary of the keys of /proc/keys, nil if not readable
+	Keys *KeysSummary `json:"keys,omitempty"`
+}
+
+// KeysSummary is a summary of the kernel keys viewable by the sensor. The key descriptions aren't reported.
+type KeysSummary struct {
+	Total int `json:"total"`
+
+	// The number of keys by type, e.g. "keyring", "user" or "logon"
+	ByType map[string]int `json:"byType"`
+
+	// The number of users owning keys
+	Owners int `json:"owners"`
+}
+
+// SenseCredentialProtection returns the keyring sysctls, a summary of the kernel keys, and the protected links,
+// FIFOs, and core dumps of setuid programs sysctls
+func SenseCredentialProtection(ctx context.Context) (*CredentialProtection, error) {
+	return senseCredentialProtection(ctx, hostFS(ctx))
+}
+
+func senseCredentialProtection(ctx context.Context, hfs HostFS) (*CredentialProtection, error) {
+	ret := &CredentialProtection{Sysctls: map[string]string{}}
+	for _, name := range credentialProtectionSysctls {
+		content, err := hfs.ReadFile(path.Join(procSysDir, strings.ReplaceAll(name, ".", "/")))
+		if err != nil {
+			logger(ctx).Debug("failed to read sysctl", zap.String("name", name), zap.Error(err))
+			recordCollectionError(ctx, "read", path.Join(procSysDir, strings.ReplaceAll(name, ".", "/")), err)
+			continue
+		}
+		ret.Sysctls[name] = strings.TrimSpace(string(content))
+	}
+
+	content, err := hfs.ReadFile(procKeysPath)
+	if err != nil {
+		logger(ctx).Debug("failed to read kernel keys", zap.Error(err))
+		return ret, nil
+	}
+	ret.Keys = summarizeKeys(content)
+	return ret, nil
+}
+
+// summarizeKeys summarizes the lines of /proc/keys, e.g.
+// "1b5f8c1a I--Q---     1 perm 3f010000     0     0 user      kubelet: 32"
+func summarizeKeys(content []byte) *KeysSummary {
+	ret := &KeysSummary{ByType: map[string]int{}}
+	owners := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 8 {
+			continue
+		}
+		ret.Total++
+		ret.ByType[fields[7]]++
+		owners[fields[5]] = true
+	}
+	ret.Owners = len(owners)
+	return ret
+}
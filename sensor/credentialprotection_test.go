@@ -0,0 +1,29 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenseCredentialProtection(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	writeFSFile(t, hfs, "/proc/sys/fs/protected_hardlinks", []byte("1\n"))
+	writeFSFile(t, hfs, "/proc/sys/fs/protected_fifos", []byte("0\n"))
+	writeFSFile(t, hfs, "/proc/sys/kernel/keys/maxkeys", []byte("200\n"))
+	writeFSFile(t, hfs, procKeysPath, []byte(`0b6a2bc1 I--Q---     1 perm 3f030000     0     0 keyring   _ses: 1
+1b5f8c1a I--Q---     1 perm 3f010000     0     0 user      kubelet: 32
+2c2b9a77 I------     1 perm 1f0b0000  1000  1000 keyring   _uid.1000: empty
+`))
+
+	protection, err := senseCredentialProtection(context.Background(), hfs)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"fs.protected_hardlinks": "1",
+		"fs.protected_fifos":     "0",
+		"kernel.keys.maxkeys":    "200",
+	}, protection.Sysctls)
+	assert.Equal(t, &KeysSummary{Total: 3, ByType: map[string]int{"keyring": 2, "user": 1}, Owners: 2}, protection.Keys)
+}
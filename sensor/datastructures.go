@@ -5,6 +5,41 @@ type LinuxSecurityHardeningStatus struct {
 	SeLinux  string `json:"seLinux"`
 }
 
+// WindowsSecurityHardeningStatus is the LinuxSecurityHardeningStatus of Windows nodes
+type WindowsSecurityHardeningStatus struct {
+	// The status of Microsoft Defender Antivirus, nil if not installed
+	Defender *DefenderStatus `json:"defender,omitempty"`
+
+	// The Windows Firewall profiles, i.e. "domain", "private" and "public"
+	FirewallProfiles []FirewallProfile `json:"firewallProfiles"`
+
+	// The containerd config, nil if not found
+	ContainerdConfig *FileInfo `json:"containerdConfig,omitempty"`
+}
+
+// DefenderStatus holds the status of Microsoft Defender Antivirus
+type DefenderStatus struct {
+	// The state of the WinDefend service, e.g. "running" or "stopped"
+	ServiceState string `json:"serviceState"`
+
+	// True if disabled by policy
+	DisabledByPolicy bool `json:"disabledByPolicy"`
+
+	// True if the real-time protection is enabled
+	RealTimeProtection bool `json:"realTimeProtection"`
+}
+
+// FirewallProfile holds the settings of a Windows Firewall profile. Group policy settings override the local ones.
+type FirewallProfile struct {
+	// One of "domain", "private" or "public"
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+
+	// The action of the connections no rule matches, "allow" or "block"
+	DefaultInboundAction  string `json:"defaultInboundAction"`
+	DefaultOutboundAction string `json:"defaultOutboundAction"`
+}
+
 // FileInfo holds information about a file
 type FileInfo struct {
 	// Ownership information
@@ -17,6 +52,15 @@ type FileInfo struct {
 	// Content of the file
 	Content     []byte `json:"content,omitempty"`
 	Permissions int    `json:"permissions"`
+
+	// True if the content wasn't read since the file is larger than the max content size
+	ContentOmitted bool `json:"contentOmitted,omitempty"`
+
+	// SHA256 hash of the content (hex encoded), for regular files only
+	SHA256 string `json:"sha256,omitempty"`
+
+	// The immutable and append-only attributes of the file (FileAttribute*), Linux only
+	Attributes []string `json:"attributes,omitempty"`
 }
 
 // User
@@ -0,0 +1,144 @@
+package sensor
+
+import (
+	"context"
+	"path"
+
+	"go.uber.org/zap"
+)
+
+// Device kinds
+const (
+	DeviceKVM    = "kvm"
+	DeviceFUSE   = "fuse"
+	DeviceTUN    = "tun"
+	DeviceVhost  = "vhost"
+	DeviceGPU    = "gpu"
+	DeviceMemory = "memory"
+)
+
+const (
+	devicePluginsDir = "/var/lib/kubelet/device-plugins"
+
+	// The kubelet registration socket, the other sockets are of the device plugins
+	kubeletDevicePluginSocket = "kubelet.sock"
+)
+
+var (
+	// Glob patterns of the device files commonly passed to workloads, by kind
+	deviceFilePatterns = []struct {
+		kind    string
+		pattern string
+	}{
+		{DeviceKVM, "/dev/kvm"},
+		{DeviceFUSE, "/dev/fuse"},
+		{DeviceTUN, "/dev/net/tun"},
+		{DeviceVhost, "/dev/vhost-*"},
+		{DeviceGPU, "/dev/nvidia*"},
+		{DeviceGPU, "/dev/nvidia-caps/*"},
+		{DeviceGPU, "/dev/dri/*"},
+		{DeviceGPU, "/dev/kfd"},
+		{DeviceMemory, "/dev/mem"},
+		{DeviceMemory, "/dev/kmem"},
+		{DeviceMemory, "/dev/port"},
+	}
+)
+
+// DeviceInventory holds the device files passed to workloads and the device plugins of the node
+type DeviceInventory struct {
+	Devices []DeviceFile `json:"devices"`
+
+	// The sockets of the device plugins registered with the kubelet
+	DevicePlugins []*FileInfo `json:"devicePlugins"`
+}
+
+// DeviceFile is a device file and the containers which have access to it
+type DeviceFile struct {
+	// One of Device*
+	Kind string `json:"kind"`
+
+	File *FileInfo `json:"file"`
+
+	Containers []DeviceContainer `json:"containers"`
+}
+
+// DeviceContainer is a container which has access to a device
+type DeviceContainer struct {
+	ID           string `json:"id"`
+	PodName      string `json:"podName,omitempty"`
+	PodNamespace string `json:"podNamespace,omitempty"`
+	Container    string `json:"container,omitempty"`
+
+	// True if the container has access to all the host devices
+	Privileged bool `json:"privileged,omitempty"`
+}
+
+// SenseDevices returns the device files commonly passed to workloads (KVM, FUSE, TUN, vhost, GPU and memory devices)
+// with their permissions and the containers which have access to them, and the device plugin sockets
+func SenseDevices(ctx context.Context) (*DeviceInventory, error) {
+	containers, err := readOCIContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &DeviceInventory{Devices: []DeviceFile{}, DevicePlugins: []*FileInfo{}}
+	for _, devicePattern := range deviceFilePatterns {
+		for _, devicePath := range globHostPaths(ctx, devicePattern.pattern) {
+			// e.g. /dev/nvidia-caps and /dev/dri/by-path
+			if info, err := statHostFile(ctx, devicePath); err != nil || info.IsDir() {
+				continue
+			}
+			file, err := makeHostFileInfo(ctx, devicePath, false)
+			if err != nil {
+				logger(ctx).Debug("failed to stat device", zap.String("path", devicePath), zap.Error(err))
+				continue
+			}
+			ret.Devices = append(ret.Devices, DeviceFile{
+				Kind:       devicePattern.kind,
+				File:       file,
+				Containers: getDeviceContainers(devicePath, containers),
+			})
+		}
+	}
+
+	for _, socketPath := range globHostPaths(ctx, path.Join(devicePluginsDir, "*.sock")) {
+		if path.Base(socketPath) == kubeletDevicePluginSocket {
+			continue
+		}
+		if file := makeHostFileInfoVerbose(ctx, socketPath, false); file != nil {
+			ret.DevicePlugins = append(ret.DevicePlugins, file)
+		}
+	}
+	return ret, nil
+}
+
+// getDeviceContainers returns the containers which have the device, or mount it, or are privileged
+func getDeviceContainers(devicePath string, containers []ociContainer) []DeviceContainer {
+	ret := []DeviceContainer{}
+	for i := range containers {
+		container := &containers[i]
+		if container.Spec.Linux == nil {
+			continue
+		}
+		privileged := container.privileged()
+		access := privileged
+		for _, device := range container.Spec.Linux.Devices {
+			access = access || device.Path == devicePath
+		}
+		// the runtimes mount a tmpfs on /dev, a /dev source is a host path mount of all the devices
+		for _, mount := range container.Spec.Mounts {
+			source := path.Clean(mount.Source)
+			access = access || source == devicePath || source == "/dev"
+		}
+		if access {
+			ret = append(ret, DeviceContainer{
+				ID:           container.ID,
+				PodName:      container.PodName,
+				PodNamespace: container.PodNamespace,
+				Container:    container.Container,
+				Privileged:   privileged,
+			})
+		}
+	}
+	return ret
+}
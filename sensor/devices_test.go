@@ -0,0 +1,52 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenseDevices(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	writeFSFile(t, hfs, "/dev/kvm", nil)
+	writeFSFile(t, hfs, "/dev/nvidia0", nil)
+	writeFSFile(t, hfs, "/dev/nvidiactl", nil)
+	require.NoError(t, os.MkdirAll(hfs.Path("/dev/nvidia-caps"), 0o755))
+	writeFSFile(t, hfs, "/var/lib/kubelet/device-plugins/kubelet.sock", nil)
+	writeFSFile(t, hfs, "/var/lib/kubelet/device-plugins/nvidia-gpu.sock", nil)
+
+	bundles := "/run/containerd/io.containerd.runtime.v2.task/k8s.io/"
+	writeFSFile(t, hfs, bundles+"gpu/config.json", []byte(`{
+		"annotations": {"io.kubernetes.cri.sandbox-name": "train", "io.kubernetes.cri.sandbox-namespace": "ml", "io.kubernetes.cri.container-name": "trainer"},
+		"linux": {"devices": [{"path": "/dev/nvidia0"}, {"path": "/dev/nvidiactl"}], "maskedPaths": ["/proc/kcore"]}
+	}`))
+	writeFSFile(t, hfs, bundles+"privileged/config.json", []byte(`{
+		"annotations": {"io.kubernetes.cri.sandbox-name": "debug", "io.kubernetes.cri.sandbox-namespace": "default", "io.kubernetes.cri.container-name": "shell"},
+		"process": {"capabilities": {"bounding": ["CAP_SYS_ADMIN"]}},
+		"linux": {}
+	}`))
+	writeFSFile(t, hfs, bundles+"sandbox/config.json", []byte(`{"annotations": {"io.kubernetes.cri.container-type": "sandbox"}, "linux": {}}`))
+
+	inventory, err := SenseDevices(ctx)
+	require.NoError(t, err)
+
+	privileged := DeviceContainer{ID: "privileged", PodName: "debug", PodNamespace: "default", Container: "shell", Privileged: true}
+	gpu := DeviceContainer{ID: "gpu", PodName: "train", PodNamespace: "ml", Container: "trainer"}
+	got := map[string][]DeviceContainer{}
+	for _, device := range inventory.Devices {
+		got[device.Kind+" "+device.File.Path] = device.Containers
+	}
+	assert.Equal(t, map[string][]DeviceContainer{
+		"kvm /dev/kvm":       {privileged},
+		"gpu /dev/nvidia0":   {gpu, privileged},
+		"gpu /dev/nvidiactl": {gpu, privileged},
+	}, got)
+
+	require.Len(t, inventory.DevicePlugins, 1)
+	assert.Equal(t, "/var/lib/kubelet/device-plugins/nvidia-gpu.sock", inventory.DevicePlugins[0].Path)
+}
@@ -0,0 +1,261 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// The egress selector of the API server tunnels its traffic to the nodes (and possibly to the control plane and
+// etcd) through a proxy, usually konnectivity. The konnectivity agents on the nodes dial the konnectivity servers,
+// so the node to control plane traffic flows in the opposite direction of the API server to kubelet traffic. Before
+// konnectivity, the API server tunneled it through SSH (--ssh-user and --ssh-keyfile, removed in 1.22).
+
+const (
+	apiEgressSelectorConfigArg = "--egress-selector-config-file"
+
+	konnectivityAgentExe  = "/proxy-agent"
+	konnectivityServerExe = "/proxy-server"
+
+	apiSSHUserArg    = "--ssh-user"
+	apiSSHKeyFileArg = "--ssh-keyfile"
+)
+
+// Egress proxy protocols
+const (
+	EgressProtocolDirect      = "Direct"
+	EgressProtocolHTTPConnect = "HTTPConnect"
+	EgressProtocolGRPC        = "GRPC"
+)
+
+// EgressSelectorConfig is the egress selector configuration of the API server
+type EgressSelectorConfig struct {
+	File *FileInfo `json:"file"`
+
+	// The egress of every traffic type, in the order of the file
+	Selections []EgressSelection `json:"egressSelections"`
+
+	// The parse error, empty if the file was parsed
+	Error string `json:"error,omitempty"`
+}
+
+// EgressSelection is the egress of a traffic type of the API server
+type EgressSelection struct {
+	// The traffic type: cluster, controlplane or etcd
+	Name string `json:"name"`
+
+	// One of EgressProtocol*
+	ProxyProtocol string `json:"proxyProtocol"`
+
+	// The transport to the proxy, tcp or uds, empty for direct connections
+	Transport string `json:"transport,omitempty"`
+
+	// URL of the tcp proxy, or the socket of the uds proxy
+	URL     string `json:"url,omitempty"`
+	UDSName string `json:"udsName,omitempty"`
+
+	// The TLS files of the tcp proxy, the connection isn't encrypted if there is no CA bundle
+	CABundle   string `json:"caBundle,omitempty"`
+	ClientCert string `json:"clientCert,omitempty"`
+	ClientKey  string `json:"clientKey,omitempty"`
+}
+
+// egressSelectorFile is the EgressSelectorConfiguration file, of apiserver.k8s.io/v1beta1 (or v1alpha1)
+type egressSelectorFile struct {
+	Kind            string `yaml:"kind"`
+	EgressSelection []struct {
+		Name       string `yaml:"name"`
+		Connection struct {
+			ProxyProtocol string `yaml:"proxyProtocol"`
+			Transport     *struct {
+				TCP *struct {
+					URL       string `yaml:"url"`
+					TLSConfig *struct {
+						CABundle   string `yaml:"caBundle"`
+						ClientKey  string `yaml:"clientKey"`
+						ClientCert string `yaml:"clientCert"`
+					} `yaml:"tlsConfig"`
+				} `yaml:"tcp"`
+				UDS *struct {
+					UDSName string `yaml:"udsName"`
+				} `yaml:"uds"`
+			} `yaml:"transport"`
+		} `yaml:"connection"`
+	} `yaml:"egressSelections"`
+}
+
+// parseEgressSelectorConfig parses an EgressSelectorConfiguration file (YAML or JSON)
+func parseEgressSelectorConfig(content []byte) ([]EgressSelection, error) {
+	file := egressSelectorFile{}
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return nil, err
+	}
+	if file.Kind != "EgressSelectorConfiguration" {
+		return nil, fmt.Errorf("unexpected kind %q", file.Kind)
+	}
+
+	ret := []EgressSelection{}
+	for _, item := range file.EgressSelection {
+		selection := EgressSelection{Name: strings.ToLower(item.Name), ProxyProtocol: item.Connection.ProxyProtocol}
+		if transport := item.Connection.Transport; transport != nil {
+			switch {
+			case transport.TCP != nil:
+				selection.Transport, selection.URL = "tcp", transport.TCP.URL
+				if tlsConfig := transport.TCP.TLSConfig; tlsConfig != nil {
+					selection.CABundle, selection.ClientCert, selection.ClientKey = tlsConfig.CABundle, tlsConfig.ClientCert, tlsConfig.ClientKey
+				}
+			case transport.UDS != nil:
+				selection.Transport, selection.UDSName = "uds", transport.UDS.UDSName
+			}
+		}
+		ret = append(ret, selection)
+	}
+	return ret, nil
+}
+
+// makeAPIServerEgressSelectorConfig returns the egress selector configuration of the API server, nil if it isn't set
+func makeAPIServerEgressSelectorConfig(ctx context.Context, p *ProcessDetails) *EgressSelectorConfig {
+	configPath, ok := p.GetArg(apiEgressSelectorConfigArg)
+	if !ok || configPath == "" {
+		return nil
+	}
+
+	fi, err := makeContaineredFileInfo(ctx, configPath, true, p)
+	if err != nil {
+		logger(ctx).Warn("failed to create egress selector config file info", zap.String("path", configPath), zap.Error(err))
+		recordCollectionError(ctx, "read", configPath, err)
+		return nil
+	}
+
+	ret := &EgressSelectorConfig{File: fi, Selections: []EgressSelection{}}
+	if selections, err := parseEgressSelectorConfig(fi.Content); err != nil {
+		ret.Error = err.Error()
+	} else {
+		ret.Selections = selections
+	}
+	return ret
+}
+
+// KonnectivityInfo holds the konnectivity processes of the node, and the legacy SSH tunnels of the API server
+type KonnectivityInfo struct {
+	// The agent tunneling the traffic of the API server to the node, nil if it doesn't run on the node
+	Agent *KonnectivityAgent `json:"agent,omitempty"`
+
+	// The server the agents dial, nil if it doesn't run on the node
+	Server *KonnectivityServer `json:"server,omitempty"`
+
+	// The legacy SSH tunnels of the API server to the nodes, nil if the API server doesn't run on the node or doesn't
+	// tunnel through SSH
+	SSHTunnel *APIServerSSHTunnel `json:"sshTunnel,omitempty"`
+}
+
+// KonnectivityAgent is a konnectivity agent (proxy-agent) process
+type KonnectivityAgent struct {
+	CmdLine string `json:"cmdLine"`
+
+	// The konnectivity server the agent dials
+	ProxyServerHost string `json:"proxyServerHost,omitempty"`
+	ProxyServerPort string `json:"proxyServerPort,omitempty"`
+
+	// The CA of the server certificate
+	CAFile *FileInfo `json:"caFile,omitempty"`
+
+	// The token authenticating the agent to the server, if it uses a service account
+	ServiceAccountTokenPath string    `json:"serviceAccountTokenPath,omitempty"`
+	ServiceAccountTokenFile *FileInfo `json:"serviceAccountTokenFile,omitempty"`
+
+	// The client certificate and key authenticating the agent to the server, if it uses mTLS
+	CertFile *FileInfo `json:"certFile,omitempty"`
+	KeyFile  *FileInfo `json:"keyFile,omitempty"`
+}
+
+// KonnectivityServer is a konnectivity server (proxy-server) process
+type KonnectivityServer struct {
+	CmdLine string `json:"cmdLine"`
+
+	// The protocol of the API server connections, grpc or http-connect
+	Mode string `json:"mode,omitempty"`
+
+	// The socket of the API server connections, empty if they're over tcp
+	UDSName   string    `json:"udsName,omitempty"`
+	UDSSocket *FileInfo `json:"udsSocket,omitempty"`
+
+	// The users and groups other than root who can connect to the socket, i.e. tunnel through the agents
+	UDSSocketWriters []string `json:"udsSocketWriters,omitempty"`
+
+	// The keys of the serving certificates of the API server connections and of the agent connections
+	ServerKeyFile  *FileInfo `json:"serverKeyFile,omitempty"`
+	ClusterKeyFile *FileInfo `json:"clusterKeyFile,omitempty"`
+
+	// The service account the agents must authenticate with, empty if they aren't authenticated
+	AgentNamespace      string `json:"agentNamespace,omitempty"`
+	AgentServiceAccount string `json:"agentServiceAccount,omitempty"`
+}
+
+// APIServerSSHTunnel is the legacy SSH tunnel of the API server to the nodes
+type APIServerSSHTunnel struct {
+	User string `json:"user,omitempty"`
+
+	// The private key the API server authenticates to the nodes with
+	KeyFile *FileInfo `json:"keyFile,omitempty"`
+}
+
+// makeFlagFileInfo returns the file of a process flag (without its content) in the mount namespace of the process,
+// nil if the flag isn't set or the file can't be read
+func makeFlagFileInfo(ctx context.Context, proc *ProcessDetails, arg string) *FileInfo {
+	filePath, ok := proc.GetArg(arg)
+	if !ok || filePath == "" {
+		return nil
+	}
+	file, err := makeContaineredFileInfo(ctx, filePath, false, proc)
+	if err != nil {
+		logger(ctx).Debug("failed to MakeFileInfo for a flag file", zap.String("flag", arg), zap.String("path", filePath), zap.Error(err))
+		return nil
+	}
+	return file
+}
+
+// SenseKonnectivity returns the konnectivity agent and server running on the node, with their credentials files and
+// socket, and the legacy SSH tunnel of the API server. They're nil if they aren't used on the node.
+func SenseKonnectivity(ctx context.Context) (*KonnectivityInfo, error) {
+	ret := &KonnectivityInfo{}
+
+	if proc, err := LocateProcessByExecSuffix(ctx, konnectivityAgentExe); err == nil {
+		agent := &KonnectivityAgent{CmdLine: proc.RawCmd()}
+		agent.ProxyServerHost, _ = proc.GetArg("--proxy-server-host")
+		agent.ProxyServerPort, _ = proc.GetArg("--proxy-server-port")
+		agent.ServiceAccountTokenPath, _ = proc.GetArg("--service-account-token-path")
+		agent.ServiceAccountTokenFile = makeFlagFileInfo(ctx, proc, "--service-account-token-path")
+		agent.CAFile = makeFlagFileInfo(ctx, proc, "--ca-cert")
+		agent.CertFile = makeFlagFileInfo(ctx, proc, "--agent-cert")
+		agent.KeyFile = makeFlagFileInfo(ctx, proc, "--agent-key")
+		ret.Agent = agent
+	}
+
+	if proc, err := LocateProcessByExecSuffix(ctx, konnectivityServerExe); err == nil {
+		server := &KonnectivityServer{CmdLine: proc.RawCmd()}
+		server.Mode, _ = proc.GetArg("--mode")
+		server.UDSName, _ = proc.GetArg("--uds-name")
+		server.UDSSocket = makeFlagFileInfo(ctx, proc, "--uds-name")
+		if server.UDSSocket != nil {
+			server.UDSSocketWriters = nonRootWriters(server.UDSSocket, false)
+		}
+		server.ServerKeyFile = makeFlagFileInfo(ctx, proc, "--server-key")
+		server.ClusterKeyFile = makeFlagFileInfo(ctx, proc, "--cluster-key")
+		server.AgentNamespace, _ = proc.GetArg("--agent-namespace")
+		server.AgentServiceAccount, _ = proc.GetArg("--agent-service-account")
+		ret.Server = server
+	}
+
+	if proc, err := LocateProcessByExecSuffix(ctx, apiServerExe); err == nil {
+		user, _ := proc.GetArg(apiSSHUserArg)
+		if keyFile, ok := proc.GetArg(apiSSHKeyFileArg); user != "" || (ok && keyFile != "") {
+			ret.SSHTunnel = &APIServerSSHTunnel{User: user, KeyFile: makeFlagFileInfo(ctx, proc, apiSSHKeyFileArg)}
+		}
+	}
+
+	return ret, nil
+}
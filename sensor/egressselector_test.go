@@ -0,0 +1,79 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEgressSelectorConfig(t *testing.T) {
+	selections, err := parseEgressSelectorConfig([]byte(`apiVersion: apiserver.k8s.io/v1beta1
+kind: EgressSelectorConfiguration
+egressSelections:
+- name: cluster
+  connection:
+    proxyProtocol: GRPC
+    transport:
+      uds:
+        udsName: /etc/kubernetes/konnectivity-server/konnectivity-server.socket
+- name: controlplane
+  connection:
+    proxyProtocol: HTTPConnect
+    transport:
+      tcp:
+        url: https://konnectivity.example.com:8131
+        tlsConfig:
+          caBundle: /etc/kubernetes/pki/konnectivity-ca.crt
+          clientKey: /etc/kubernetes/pki/konnectivity-client.key
+          clientCert: /etc/kubernetes/pki/konnectivity-client.crt
+- name: etcd
+  connection:
+    proxyProtocol: Direct
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []EgressSelection{
+		{Name: "cluster", ProxyProtocol: EgressProtocolGRPC, Transport: "uds", UDSName: "/etc/kubernetes/konnectivity-server/konnectivity-server.socket"},
+		{Name: "controlplane", ProxyProtocol: EgressProtocolHTTPConnect, Transport: "tcp", URL: "https://konnectivity.example.com:8131",
+			CABundle: "/etc/kubernetes/pki/konnectivity-ca.crt", ClientCert: "/etc/kubernetes/pki/konnectivity-client.crt", ClientKey: "/etc/kubernetes/pki/konnectivity-client.key"},
+		{Name: "etcd", ProxyProtocol: EgressProtocolDirect},
+	}, selections)
+
+	_, err = parseEgressSelectorConfig([]byte(`{"kind": "EncryptionConfiguration"}`))
+	assert.Error(t, err)
+}
+
+func TestSenseKonnectivity(t *testing.T) {
+	origPlatform := hostPlatform
+	defer func() { hostPlatform = origPlatform }()
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+	hostPlatform = fixturePlatform{root: hfs.Root()}
+	writeFSFile(t, hfs, "/proc/41/cmdline", []byte("/proxy-agent\x00--proxy-server-host=10.0.0.1\x00--agent-cert=/etc/konnectivity/agent.crt\x00--agent-key=/etc/konnectivity/agent.key\x00"))
+	writeFSFile(t, hfs, "/proc/42/cmdline", []byte("/proxy-server\x00--mode=grpc\x00--uds-name=/etc/kubernetes/konnectivity-server/konnectivity-server.socket\x00"))
+	writeFSFile(t, hfs, "/proc/43/cmdline", []byte("/usr/local/bin/kube-apiserver\x00--ssh-user=tunnel\x00--ssh-keyfile=/etc/srv/sshproxy/.sshkeyfile\x00"))
+	writeFSFile(t, hfs, "/etc/konnectivity/agent.key", []byte("key"))
+	writeFSFile(t, hfs, "/etc/kubernetes/konnectivity-server/konnectivity-server.socket", []byte{})
+	writeFSFile(t, hfs, "/etc/srv/sshproxy/.sshkeyfile", []byte("key"))
+
+	info, err := SenseKonnectivity(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, info.Agent)
+	assert.Equal(t, "10.0.0.1", info.Agent.ProxyServerHost)
+	// the agent certificate is missing
+	assert.Nil(t, info.Agent.CertFile)
+	require.NotNil(t, info.Agent.KeyFile)
+	assert.Equal(t, "/etc/konnectivity/agent.key", info.Agent.KeyFile.Path)
+
+	require.NotNil(t, info.Server)
+	require.NotNil(t, info.Server.UDSSocket)
+	assert.Equal(t, "/etc/kubernetes/konnectivity-server/konnectivity-server.socket", info.Server.UDSSocket.Path)
+	assert.Empty(t, info.Server.UDSSocketWriters)
+	assert.Nil(t, info.Server.ServerKeyFile)
+
+	require.NotNil(t, info.SSHTunnel)
+	assert.Equal(t, "tunnel", info.SSHTunnel.User)
+	require.NotNil(t, info.SSHTunnel.KeyFile)
+	assert.Equal(t, "/etc/srv/sshproxy/.sshkeyfile", info.SSHTunnel.KeyFile.Path)
+}
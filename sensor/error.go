@@ -1,15 +1,73 @@
 package sensor
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io/fs"
+	"net/http"
+	"runtime/debug"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+// Error kinds - machine readable identifiers of the failure cause.
+const (
+	ErrKindNotControlPlane  = "NotControlPlane"
+	ErrKindPermissionDenied = "PermissionDenied"
+	ErrKindSensorDisabled   = "SensorDisabled"
+	ErrKindHostRootMissing  = "HostRootMissing"
+	ErrKindSensorPanic      = "SensorPanic"
+)
+
+// Sentinel errors, use `errors.Is` to check the failure cause of a sensor
+var (
+	ErrNotControlPlane = &SenseError{
+		Massage: "not a control plane node",
+		Kind:    ErrKindNotControlPlane,
+		Code:    http.StatusNotFound,
+	}
+	ErrPermissionDenied = &SenseError{
+		Massage: "permission denied",
+		Kind:    ErrKindPermissionDenied,
+		Code:    http.StatusForbidden,
+	}
+	ErrSensorDisabled = &SenseError{
+		Massage: "sensor is disabled",
+		Kind:    ErrKindSensorDisabled,
+		Code:    http.StatusServiceUnavailable,
+	}
+	ErrHostRootMissing = &SenseError{
+		Massage: "host file system is not mounted",
+		Kind:    ErrKindHostRootMissing,
+		Code:    http.StatusInternalServerError,
+	}
+	ErrSensorPanic = &SenseError{
+		Massage: "sensor panicked",
+		Kind:    ErrKindSensorPanic,
+		Code:    http.StatusInternalServerError,
+	}
 )
 
 // SenseError is informative sensor error
 type SenseError struct {
 	err      error  // The wrapped error
-	Massage  string `json:"error"` // The error message
-	Function string `json:"-"`     // The function where the error occurred
-	Code     int    `json:"-"`     // The error code (for HTTP response codes)
+	Massage  string `json:"error"`          // The error message
+	Kind     string `json:"kind,omitempty"` // Machine readable failure cause (one of ErrKind*)
+	Function string `json:"-"`              // The function where the error occurred
+	Code     int    `json:"-"`              // The error code (for HTTP response codes)
+}
+
+// newSenseError returns a copy of the sentinel error `kind`, wrapping `err`.
+func newSenseError(kind *SenseError, function string, err error) *SenseError {
+	return &SenseError{
+		err:      err,
+		Massage:  kind.Massage,
+		Kind:     kind.Kind,
+		Function: function,
+		Code:     kind.Code,
+	}
 }
 
 // Error implements error interface
@@ -24,11 +82,110 @@ func (err *SenseError) Error() string {
 // Unwrap implementation for errors.Unwrap
 func (err *SenseError) Unwrap() error { return err.err }
 
-// Is implementation for errors.Is
+// Is implementation for errors.Is.
+// Errors with a kind are compared by kind, otherwise by message and code.
 func (err *SenseError) Is(target error) bool {
 	sensErrTarget, ok := target.(*SenseError)
 	if !ok {
 		return false
 	}
+	if sensErrTarget.Kind != "" {
+		return err.Kind == sensErrTarget.Kind
+	}
 	return err.Massage == sensErrTarget.Massage && err.Code == sensErrTarget.Code
 }
+
+// AsSenseError converts any error returned by a sensor to a `SenseError`.
+// Errors which are already a `SenseError` (or wrap one) are returned as is,
+// file system permission errors are classified as `ErrPermissionDenied`,
+// and any other error is returned as an internal error carrying its message.
+func AsSenseError(err error, function string) *SenseError {
+	if err == nil {
+		return nil
+	}
+
+	var senseErr *SenseError
+	if errors.As(err, &senseErr) {
+		return senseErr
+	}
+
+	if errors.Is(err, fs.ErrPermission) {
+		return newSenseError(ErrPermissionDenied, function, err)
+	}
+
+	return &SenseError{
+		Massage:  fmt.Sprintf("failed to %s: %v", function, err),
+		Function: function,
+		Code:     http.StatusInternalServerError,
+	}
+}
+
+// CollectionError is a failure to collect a single item of a sensor result, e.g. a file which isn't readable.
+// The sensor still succeeds, without the item.
+type CollectionError struct {
+	Path string `json:"path"`
+
+	// The failed operation, e.g. "open" or "stat"
+	Op string `json:"op"`
+
+	// The errno name, e.g. "ENOENT" or "EACCES", empty if the failure isn't a system call error
+	Errno string `json:"errno,omitempty"`
+
+	Error string `json:"error"`
+}
+
+// recordCollectionError records a failure to collect a path for the sensor run of `ctx`
+func recordCollectionError(ctx context.Context, op, filePath string, err error) {
+	run := runFromContext(ctx)
+	if run == nil {
+		return
+	}
+	run.lock.Lock()
+	defer run.lock.Unlock()
+	run.metrics.FailedFiles++
+	run.collectionErrors = append(run.collectionErrors, newCollectionError(op, filePath, err))
+}
+
+// CollectionErrors returns the collection errors recorded so far by the sensor run of `ctx`, nil if the context
+// isn't of an instrumented sensor
+func CollectionErrors(ctx context.Context) []CollectionError {
+	run := runFromContext(ctx)
+	if run == nil {
+		return nil
+	}
+	run.lock.Lock()
+	defer run.lock.Unlock()
+	return append([]CollectionError{}, run.collectionErrors...)
+}
+
+// newCollectionError returns the collection error of `err`, the op of a `fs.PathError` overrides `op`
+func newCollectionError(op, filePath string, err error) CollectionError {
+	ret := CollectionError{Path: filePath, Op: op, Error: err.Error()}
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		ret.Op = pathErr.Op
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		ret.Errno = errnoName(errno)
+	}
+	return ret
+}
+
+// RecoverPanic runs `sense`, and returns an `ErrSensorPanic` error if it panics, so a panicking sensor fails alone
+// instead of the whole process. The stack of the panic is logged, and isn't part of the error.
+func RecoverPanic(ctx context.Context, sensorName string, sense func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger(ctx).Error("sensor panicked", zap.String("sensor", sensorName), zap.Any("panic", r), zap.ByteString("stack", debug.Stack()))
+			err = &SenseError{
+				Massage:  fmt.Sprintf("%s: %v", ErrSensorPanic.Massage, r),
+				Kind:     ErrKindSensorPanic,
+				Function: sensorName,
+				Code:     ErrSensorPanic.Code,
+			}
+		}
+	}()
+	sense()
+	return nil
+}
@@ -0,0 +1,129 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSenseErrorIs(t *testing.T) {
+	err := newSenseError(ErrNotControlPlane, "SenseControlPlaneInfo", nil)
+	assert.True(t, errors.Is(err, ErrNotControlPlane))
+	assert.False(t, errors.Is(err, ErrPermissionDenied))
+
+	wrapped := fmt.Errorf("wrapped: %w", err)
+	assert.True(t, errors.Is(wrapped, ErrNotControlPlane))
+
+	// legacy errors without kind are compared by message and code
+	legacy := &SenseError{Massage: "foo", Code: http.StatusNotFound}
+	assert.True(t, errors.Is(&SenseError{Massage: "foo", Code: http.StatusNotFound}, legacy))
+	assert.False(t, errors.Is(&SenseError{Massage: "bar", Code: http.StatusNotFound}, legacy))
+}
+
+func TestAsSenseError(t *testing.T) {
+	assert.Nil(t, AsSenseError(nil, "foo"))
+
+	senseErr := AsSenseError(fmt.Errorf("failed: %w", os.ErrPermission), "foo")
+	assert.True(t, errors.Is(senseErr, ErrPermissionDenied))
+	assert.Equal(t, http.StatusForbidden, senseErr.Code)
+	assert.True(t, errors.Is(senseErr, os.ErrPermission))
+
+	senseErr = AsSenseError(errors.New("boom"), "foo")
+	assert.Equal(t, http.StatusInternalServerError, senseErr.Code)
+	assert.Equal(t, "", senseErr.Kind)
+	assert.Contains(t, senseErr.Massage, "boom")
+
+	senseErr = AsSenseError(ErrSensorDisabled, "foo")
+	assert.Equal(t, ErrKindSensorDisabled, senseErr.Kind)
+}
+
+func TestCheckHostRoot(t *testing.T) {
+	defer func(orig string) { hostFileSystemDefaultLocation = orig }(hostFileSystemDefaultLocation)
+	hostFileSystemDefaultLocation = "testdata"
+	assert.NoError(t, CheckHostRoot())
+
+	hostFileSystemDefaultLocation = "testdata/not-exist"
+	assert.ErrorIs(t, CheckHostRoot(), ErrHostRootMissing)
+}
+
+func TestCollectionErrors(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	var recorded []CollectionError
+	_, collectionErrors := Instrument(ctx, "kubeletInfo", func(ctx context.Context) {
+		assert.Nil(t, makeHostFileInfoVerbose(ctx, "/etc/kubernetes/missing.conf", false))
+		recorded = CollectionErrors(ctx)
+	})
+	if assert.Len(t, collectionErrors, 1) {
+		assert.Equal(t, "/etc/kubernetes/missing.conf", collectionErrors[0].Path)
+		assert.Equal(t, "ENOENT", collectionErrors[0].Errno)
+		assert.NotEmpty(t, collectionErrors[0].Op)
+	}
+	assert.Equal(t, collectionErrors, recorded)
+
+	// the runs are attributed their own errors, even when concurrent
+	var wg sync.WaitGroup
+	for _, filePath := range []string{"/etc/foo", "/etc/bar"} {
+		wg.Add(1)
+		go func(filePath string) {
+			defer wg.Done()
+			_, collectionErrors := Instrument(ctx, "kubeletInfo", func(ctx context.Context) {
+				recordCollectionError(ctx, "open", filePath, os.ErrNotExist)
+			})
+			if assert.Len(t, collectionErrors, 1) {
+				assert.Equal(t, filePath, collectionErrors[0].Path)
+			}
+		}(filePath)
+	}
+	wg.Wait()
+
+	// not recorded out of a sensor run
+	recordCollectionError(ctx, "open", "/etc/foo", os.ErrNotExist)
+	assert.Nil(t, CollectionErrors(ctx))
+}
+
+func TestNewCollectionError(t *testing.T) {
+	err := newCollectionError("read", "/proc/sys/fs/suid_dumpable", &fs.PathError{Op: "open", Path: "/host_fs/proc/sys/fs/suid_dumpable", Err: syscall.EACCES})
+	assert.Equal(t, CollectionError{
+		Path:  "/proc/sys/fs/suid_dumpable",
+		Op:    "open",
+		Errno: "EACCES",
+		Error: "open /host_fs/proc/sys/fs/suid_dumpable: permission denied",
+	}, err)
+
+	err = newCollectionError("parse", "/etc/docker/daemon.json", errors.New("invalid"))
+	assert.Equal(t, CollectionError{Path: "/etc/docker/daemon.json", Op: "parse", Error: "invalid"}, err)
+}
+
+func TestRecoverPanic(t *testing.T) {
+	core, logs := observer.New(zap.ErrorLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	assert.NoError(t, RecoverPanic(context.Background(), "kubeletInfo", func() {}))
+
+	s := NewSensor("panicking", func(ctx context.Context) (interface{}, error) {
+		var config map[string]string
+		config["malformed"] = "yaml"
+		return config, nil
+	})
+	_, err := s.Sense(context.Background())
+	assert.ErrorIs(t, err, ErrSensorPanic)
+	senseErr := AsSenseError(err, "panicking")
+	assert.Equal(t, http.StatusInternalServerError, senseErr.Code)
+	assert.Contains(t, senseErr.Massage, "assignment to entry in nil map")
+	entries := logs.FilterMessage("sensor panicked").All()
+	if assert.Len(t, entries, 1) {
+		assert.Contains(t, entries[0].ContextMap()["stack"], "TestRecoverPanic")
+	}
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package sensor
+
+import (
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// errnoName returns the name of an errno, e.g. "EACCES"
+func errnoName(errno syscall.Errno) string {
+	return unix.ErrnoName(errno)
+}
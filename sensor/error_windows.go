@@ -0,0 +1,11 @@
+package sensor
+
+import (
+	"strconv"
+	"syscall"
+)
+
+// errnoName returns the number of a Windows error, e.g. "5" of ERROR_ACCESS_DENIED
+func errnoName(errno syscall.Errno) string {
+	return strconv.Itoa(int(errno))
+}
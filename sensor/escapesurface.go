@@ -0,0 +1,296 @@
+package sensor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// The escape surface of the node is read from the OCI runtime bundles of the CRI containers,
+// which hold the effective configuration of the containers (namespaces, mounts, devices and capabilities).
+
+// Escape condition types
+const (
+	EscapePrivileged    = "privileged"
+	EscapeHostNamespace = "hostNamespace"
+	EscapeHostMount     = "hostMount"
+	EscapeHostDevice    = "hostDevice"
+	EscapeCapability    = "capability"
+)
+
+var (
+	// Glob patterns of the OCI runtime bundle configs of the containers, by container runtime
+	ociBundleConfigPatterns = []string{
+		// containerd
+		"/run/containerd/io.containerd.runtime.v2.task/k8s.io/*/config.json",
+		// CRI-O
+		"/run/containers/storage/overlay-containers/*/userdata/config.json",
+	}
+
+	// Host paths which enable escaping the container if mounted writable
+	sensitiveHostPaths = []string{
+		"/", "/etc", "/root", "/boot", "/dev", "/proc", "/sys", "/run", "/var/run",
+		"/var/lib/kubelet", "/var/lib/containerd", "/var/lib/docker", "/var/lib/containers",
+		"/etc/kubernetes", "/lib/modules", "/usr",
+	}
+
+	// Host paths of volumes and files managed by the kubelet and the container runtimes for a single container,
+	// which are not hostPath volumes
+	managedHostPathPrefixes = []string{
+		"/var/lib/kubelet/pods/",
+		"/var/lib/containerd/io.containerd.grpc.v1.cri/",
+		"/run/containerd/io.containerd.grpc.v1.cri/",
+		"/run/containers/storage/",
+		"/var/run/containers/storage/",
+		"/var/lib/containers/storage/",
+	}
+
+	// Capabilities which enable escaping the container, privileged containers have all of them
+	dangerousCapabilities = []string{
+		"CAP_SYS_ADMIN", "CAP_SYS_MODULE", "CAP_SYS_PTRACE", "CAP_SYS_RAWIO", "CAP_DAC_READ_SEARCH", "CAP_BPF",
+	}
+
+	// Namespaces which are shared with the host if missing from the container spec
+	hostNamespaceTypes = []string{"pid", "network", "ipc"}
+)
+
+// EscapeSurface holds the conditions on the node which enable escaping from containers to the host
+type EscapeSurface struct {
+	Containers []ContainerEscapeSurface `json:"containers"`
+}
+
+// ContainerEscapeSurface holds the escape enabling conditions of a container
+type ContainerEscapeSurface struct {
+	ID           string            `json:"id"`
+	PodName      string            `json:"podName,omitempty"`
+	PodNamespace string            `json:"podNamespace,omitempty"`
+	Container    string            `json:"container,omitempty"`
+	Conditions   []EscapeCondition `json:"conditions"`
+}
+
+// EscapeCondition is a single escape enabling condition
+type EscapeCondition struct {
+	// One of Escape*
+	Type string `json:"type"`
+
+	// The namespace type, mount host path, device path or capability
+	Detail string `json:"detail,omitempty"`
+}
+
+// ociSpec is the subset of the OCI runtime spec used by the escape surface sensor
+type ociSpec struct {
+	Process *struct {
+		Capabilities *struct {
+			Bounding []string `json:"bounding"`
+		} `json:"capabilities"`
+	} `json:"process"`
+	Mounts []struct {
+		Destination string   `json:"destination"`
+		Type        string   `json:"type"`
+		Source      string   `json:"source"`
+		Options     []string `json:"options"`
+	} `json:"mounts"`
+	Annotations map[string]string `json:"annotations"`
+	Linux       *struct {
+		Namespaces []struct {
+			Type string `json:"type"`
+			Path string `json:"path"`
+		} `json:"namespaces"`
+		Devices []struct {
+			Path string `json:"path"`
+		} `json:"devices"`
+		MaskedPaths   []string `json:"maskedPaths"`
+		ReadonlyPaths []string `json:"readonlyPaths"`
+	} `json:"linux"`
+}
+
+// ociContainer is a CRI container read from its OCI runtime bundle
+type ociContainer struct {
+	ID           string
+	PodUID       string
+	PodName      string
+	PodNamespace string
+	Container    string
+	Spec         ociSpec
+}
+
+// SenseEscapeSurface returns the escape enabling conditions of the containers running on the node.
+// Containers without any such condition are omitted.
+func SenseEscapeSurface(ctx context.Context) (*EscapeSurface, error) {
+	containers, err := readOCIContainers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := &EscapeSurface{Containers: []ContainerEscapeSurface{}}
+	for i := range containers {
+		if container := containerEscapeSurface(&containers[i]); len(container.Conditions) > 0 {
+			ret.Containers = append(ret.Containers, *container)
+		}
+	}
+	return ret, nil
+}
+
+// readOCIContainers returns the containers of the OCI runtime bundles on the node, sorted by ID.
+// Pod sandboxes and unreadable bundles are skipped.
+func readOCIContainers(ctx context.Context) ([]ociContainer, error) {
+	ret := []ociContainer{}
+	for _, pattern := range ociBundleConfigPatterns {
+		configs, err := fs.Glob(hostFS(ctx), pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, configPath := range configs {
+			container, err := readOCIContainer(ctx, configPath)
+			if err != nil {
+				logger(ctx).Debug("failed to read container bundle", zap.String("path", configPath), zap.Error(err))
+				continue
+			}
+			if container != nil {
+				ret = append(ret, *container)
+			}
+		}
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].ID < ret[j].ID })
+	return ret, nil
+}
+
+// readOCIContainer returns the container of the OCI bundle config, or nil for pod sandboxes
+func readOCIContainer(ctx context.Context, configPath string) (*ociContainer, error) {
+	content, err := hostFS(ctx).ReadFile(configPath)
+	if err != nil {
+		return nil, err
+	}
+	spec := ociSpec{}
+	if err := json.Unmarshal(content, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse OCI spec: %w", err)
+	}
+
+	// the sandbox (pause) containers share the namespaces of the pod containers
+	if spec.Annotations["io.kubernetes.cri.container-type"] == "sandbox" ||
+		spec.Annotations["io.kubernetes.cri-o.ContainerType"] == "sandbox" {
+		return nil, nil
+	}
+
+	return &ociContainer{
+		ID:           path.Base(strings.TrimSuffix(path.Dir(configPath), "/userdata")),
+		PodUID:       firstAnnotation(spec.Annotations, "io.kubernetes.cri.sandbox-uid", "io.kubernetes.pod.uid"),
+		PodName:      firstAnnotation(spec.Annotations, "io.kubernetes.cri.sandbox-name", "io.kubernetes.pod.name"),
+		PodNamespace: firstAnnotation(spec.Annotations, "io.kubernetes.cri.sandbox-namespace", "io.kubernetes.pod.namespace"),
+		Container:    firstAnnotation(spec.Annotations, "io.kubernetes.cri.container-name", "io.kubernetes.container.name"),
+		Spec:         spec,
+	}, nil
+}
+
+// capabilities returns the bounding capabilities of the container
+func (container *ociContainer) capabilities() []string {
+	if container.Spec.Process != nil && container.Spec.Process.Capabilities != nil {
+		return container.Spec.Process.Capabilities.Bounding
+	}
+	return []string{}
+}
+
+// privileged returns true if the container is privileged. The runtimes don't mask paths of privileged containers.
+func (container *ociContainer) privileged() bool {
+	linux := container.Spec.Linux
+	return linux != nil && containsString(container.capabilities(), "CAP_SYS_ADMIN") &&
+		len(linux.MaskedPaths) == 0 && len(linux.ReadonlyPaths) == 0
+}
+
+// containerEscapeSurface returns the escape enabling conditions of a container
+func containerEscapeSurface(container *ociContainer) *ContainerEscapeSurface {
+	spec := &container.Spec
+	ret := &ContainerEscapeSurface{
+		ID:           container.ID,
+		PodName:      container.PodName,
+		PodNamespace: container.PodNamespace,
+		Container:    container.Container,
+		Conditions:   []EscapeCondition{},
+	}
+	if spec.Linux == nil {
+		return ret
+	}
+
+	capabilities := container.capabilities()
+	if container.privileged() {
+		ret.Conditions = append(ret.Conditions, EscapeCondition{Type: EscapePrivileged})
+	} else {
+		for _, capability := range dangerousCapabilities {
+			if containsString(capabilities, capability) {
+				ret.Conditions = append(ret.Conditions, EscapeCondition{Type: EscapeCapability, Detail: capability})
+			}
+		}
+		// privileged containers have all the host devices
+		for _, device := range spec.Linux.Devices {
+			ret.Conditions = append(ret.Conditions, EscapeCondition{Type: EscapeHostDevice, Detail: device.Path})
+		}
+	}
+
+	for _, namespaceType := range hostNamespaceTypes {
+		shared := true
+		for _, namespace := range spec.Linux.Namespaces {
+			if namespace.Type == namespaceType {
+				shared = false
+			}
+		}
+		if shared {
+			ret.Conditions = append(ret.Conditions, EscapeCondition{Type: EscapeHostNamespace, Detail: namespaceType})
+		}
+	}
+
+	for _, mount := range spec.Mounts {
+		if mount.Type != "bind" && !containsString(mount.Options, "bind") && !containsString(mount.Options, "rbind") {
+			continue
+		}
+		if containsString(mount.Options, "ro") || !isSensitiveHostPath(mount.Source) {
+			continue
+		}
+		ret.Conditions = append(ret.Conditions, EscapeCondition{Type: EscapeHostMount, Detail: mount.Source})
+	}
+
+	return ret
+}
+
+// isSensitiveHostPath returns true if `source` is a sensitive host path or under one, and not managed by the kubelet or the runtime
+func isSensitiveHostPath(source string) bool {
+	source = path.Clean(source)
+	for _, prefix := range managedHostPathPrefixes {
+		if strings.HasPrefix(source, prefix) {
+			return false
+		}
+	}
+	for _, sensitive := range sensitiveHostPaths {
+		if source == sensitive {
+			return true
+		}
+		// any path is under the root, which is sensitive only if mounted as a whole
+		if sensitive != "/" && strings.HasPrefix(source, sensitive+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+func firstAnnotation(annotations map[string]string, keys ...string) string {
+	for _, key := range keys {
+		if val, ok := annotations[key]; ok {
+			return val
+		}
+	}
+	return ""
+}
+
+func containsString(list []string, str string) bool {
+	for _, item := range list {
+		if item == str {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenseEscapeSurface(t *testing.T) {
+	hfs := NewHostFS("testdata/escapesurface")
+	ctx := WithHostFS(context.Background(), hfs)
+
+	surface, err := SenseEscapeSurface(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []ContainerEscapeSurface{
+		{
+			ID:           "hostpath",
+			PodName:      "agent",
+			PodNamespace: "monitoring",
+			Container:    "agent",
+			Conditions: []EscapeCondition{
+				{Type: EscapeCapability, Detail: "CAP_SYS_PTRACE"},
+				{Type: EscapeHostNamespace, Detail: "network"},
+				{Type: EscapeHostMount, Detail: "/etc"},
+			},
+		},
+		{
+			ID:           "privileged",
+			PodName:      "debug",
+			PodNamespace: "default",
+			Container:    "shell",
+			Conditions: []EscapeCondition{
+				{Type: EscapePrivileged},
+				{Type: EscapeHostNamespace, Detail: "pid"},
+				{Type: EscapeHostNamespace, Detail: "network"},
+				{Type: EscapeHostNamespace, Detail: "ipc"},
+			},
+		},
+	}, surface.Containers)
+}
@@ -0,0 +1,49 @@
+package sensor
+
+import (
+	"context"
+	"io/fs"
+	"path"
+
+	"go.uber.org/zap"
+)
+
+// maximal number of the reported files of the etcd data dir, to bound the walk of a data dir with many snapshots
+const maxEtcdDataDirFiles = 1000
+
+// etcdDataSubDirs are the directories of the etcd data, walked for the permissions and the ownership of its files
+var etcdDataSubDirs = []string{"member", "member/wal", "member/snap"}
+
+// makeEtcdDataDirFiles returns the directories and the files of the etcd WAL and snapshots, without content nor
+// hash (WAL files are 64MB). The member directory itself is included, its other files (if any) aren't.
+func makeEtcdDataDirFiles(ctx context.Context, dataDir string) []*FileInfo {
+	ret := []*FileInfo{}
+	add := func(filePath string) {
+		advanceProgress(ctx, filePath, 1, 0)
+		file, err := makeHostFileStatInfo(ctx, filePath)
+		if err != nil {
+			logger(ctx).Debug("failed to stat etcd data file", zap.String("path", filePath), zap.Error(err))
+			recordCollectionError(ctx, "stat", filePath, err)
+			return
+		}
+		ret = append(ret, file)
+	}
+
+	add(path.Join(dataDir, etcdDataSubDirs[0]))
+	for _, subDir := range etcdDataSubDirs[1:] {
+		dir := path.Join(dataDir, subDir)
+		fs.WalkDir(hostFS(ctx), dir, func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				logger(ctx).Debug("failed to walk", zap.String("path", filePath), zap.Error(err))
+				recordCollectionError(ctx, "walk", filePath, err)
+				return nil
+			}
+			if len(ret) >= maxEtcdDataDirFiles {
+				return fs.SkipDir
+			}
+			add(filePath)
+			return nil
+		})
+	}
+	return ret
+}
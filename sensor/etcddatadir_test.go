@@ -0,0 +1,32 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMakeEtcdDataDirFiles(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	writeFSFile(t, hfs, "/var/lib/etcd/member/wal/0000000000000000-0000000000000000.wal", []byte("wal"))
+	writeFSFile(t, hfs, "/var/lib/etcd/member/snap/db", []byte("db"))
+	writeFSFile(t, hfs, "/var/lib/etcd/member/other", []byte("other"))
+	os.Chmod(hfs.Path("/var/lib/etcd/member/snap/db"), 0o644)
+
+	files := makeEtcdDataDirFiles(ctx, "/var/lib/etcd")
+	paths := map[string]int{}
+	for _, file := range files {
+		paths[file.Path] = file.Permissions
+		assert.Empty(t, file.Content)
+		assert.Empty(t, file.SHA256)
+	}
+	assert.Contains(t, paths, "/var/lib/etcd/member")
+	assert.Contains(t, paths, "/var/lib/etcd/member/wal")
+	assert.Contains(t, paths, "/var/lib/etcd/member/wal/0000000000000000-0000000000000000.wal")
+	assert.Equal(t, 0o644, paths["/var/lib/etcd/member/snap/db"])
+	assert.NotContains(t, paths, "/var/lib/etcd/member/other")
+}
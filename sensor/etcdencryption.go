@@ -0,0 +1,302 @@
+package sensor
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"go.uber.org/zap"
+)
+
+// The encryption of secrets at rest is verified by sampling the stored secrets. The etcd database is read
+// directly if it isn't locked (i.e. etcd isn't running), otherwise (or if etcd is external) the secrets are read with
+// the etcd v3 JSON API, using the etcd client certificate of the API server.
+
+// Sources of the sampled secrets
+const (
+	EtcdSourceDataDir = "dataDir"
+	EtcdSourceAPI     = "api"
+)
+
+const (
+	etcdSecretsPrefix = "/registry/secrets/"
+
+	// Value prefix of secrets encrypted by the API server
+	etcdEncryptedPrefix = "k8s:enc:"
+
+	// Number of sampled secrets
+	etcdSampleSize = 10
+
+	// Max number of revisions read from the database, to bound the scan of large databases
+	etcdMaxScannedRevisions = 100000
+
+	etcdDBLockTimeout = time.Second
+	etcdAPITimeout    = 10 * time.Second
+
+	etcdKeyBucket = "key"
+)
+
+// EtcdEncryptionInfo holds the encryption state of sampled secrets stored in etcd
+type EtcdEncryptionInfo struct {
+	// One of EtcdSource*
+	Source string `json:"source"`
+
+	Samples []EtcdSecretSample `json:"samples"`
+}
+
+// EtcdSecretSample is the encryption state of a stored secret, without its value
+type EtcdSecretSample struct {
+	// The etcd key, /registry/secrets/<namespace>/<name>
+	Key string `json:"key"`
+
+	Encrypted bool `json:"encrypted"`
+
+	// The encryption provider, version and key name, e.g. "aescbc:v1:key1"
+	Provider string `json:"provider,omitempty"`
+}
+
+// SenseEtcdEncryption samples the secrets stored in etcd and returns whether they are encrypted
+func SenseEtcdEncryption(ctx context.Context) (*EtcdEncryptionInfo, error) {
+	// with an external etcd, the secrets are read with the etcd API of the API server
+	dataDir, err := getEtcdDataDir(ctx)
+	if err != nil {
+		if _, apiErr := LocateProcessByExecSuffix(ctx, apiServerExe); apiErr != nil {
+			return nil, newSenseError(ErrNotControlPlane, "SenseEtcdEncryption", err)
+		}
+	}
+
+	var values []etcdKeyValue
+	source := EtcdSourceDataDir
+	if dataDir != "" {
+		var dbPath string
+		if dbPath, err = resolveHostPath(ctx, path.Join(dataDir, "member/snap/db")); err == nil {
+			values, err = readEtcdDBSecrets(dbPath)
+		}
+	}
+	if (dataDir == "" || err != nil) && !GetSenseOptions(ctx).Probes {
+		return nil, fmt.Errorf("failed to read etcd database, and the etcd API isn't probed: %w", ErrProbesDisabled)
+	}
+	if dataDir == "" || err != nil {
+		logger(ctx).Debug("failed to read etcd database, falling back to etcd API", zap.Error(err))
+		source = EtcdSourceAPI
+		var conf *etcdClientConfig
+		if conf, err = getEtcdClientConfig(ctx); err == nil {
+			values, err = readEtcdAPISecrets(ctx, conf)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &EtcdEncryptionInfo{Source: source, Samples: []EtcdSecretSample{}}
+	for _, kv := range values {
+		ret.Samples = append(ret.Samples, makeEtcdSecretSample(kv.key, kv.value))
+	}
+	return ret, nil
+}
+
+type etcdKeyValue struct {
+	key   string
+	value []byte
+}
+
+func makeEtcdSecretSample(key string, value []byte) EtcdSecretSample {
+	sample := EtcdSecretSample{Key: key}
+	if bytes.HasPrefix(value, []byte(etcdEncryptedPrefix)) {
+		sample.Encrypted = true
+		// k8s:enc:<provider>:<version>:<key name>:<data>
+		parts := strings.SplitN(string(value[len(etcdEncryptedPrefix):]), ":", 4)
+		if len(parts) == 4 {
+			sample.Provider = strings.Join(parts[:3], ":")
+		}
+	}
+	return sample
+}
+
+// readEtcdDBSecrets reads the latest values of the most recently modified secrets from the etcd database file
+func readEtcdDBSecrets(dbPath string) ([]etcdKeyValue, error) {
+	db, err := bolt.Open(dbPath, 0o400, &bolt.Options{ReadOnly: true, Timeout: etcdDBLockTimeout})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open etcd database: %w", err)
+	}
+	defer db.Close()
+
+	ret := []etcdKeyValue{}
+	err = db.View(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(etcdKeyBucket))
+		if bucket == nil {
+			return errors.New("etcd database has no key bucket")
+		}
+
+		// the bucket is keyed by revision, so the latest revisions are read first
+		seen := map[string]bool{}
+		c := bucket.Cursor()
+		scanned := 0
+		for revision, value := c.Last(); revision != nil && len(ret) < etcdSampleSize && scanned < etcdMaxScannedRevisions; revision, value = c.Prev() {
+			scanned++
+			key, kvValue, err := decodeEtcdKeyValue(value)
+			if err != nil || !strings.HasPrefix(key, etcdSecretsPrefix) || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			// tombstones of deleted keys are marked by a 't' revision suffix
+			if len(revision) > 17 && revision[17] == 't' {
+				continue
+			}
+			ret = append(ret, etcdKeyValue{key: key, value: kvValue})
+		}
+		return nil
+	})
+	return ret, err
+}
+
+// decodeEtcdKeyValue decodes the key (field 1) and the value (field 5) of a protobuf encoded mvccpb.KeyValue
+func decodeEtcdKeyValue(data []byte) (string, []byte, error) {
+	var key string
+	var value []byte
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return "", nil, errors.New("invalid field tag")
+		}
+		data = data[n:]
+
+		switch tag & 7 {
+		case 0: // varint
+			_, n = binary.Uvarint(data)
+			if n <= 0 {
+				return "", nil, errors.New("invalid varint")
+			}
+			data = data[n:]
+		case 2: // length delimited
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return "", nil, errors.New("invalid length")
+			}
+			field := data[n : n+int(length)]
+			data = data[n+int(length):]
+			switch tag >> 3 {
+			case 1:
+				key = string(field)
+			case 5:
+				value = field
+			}
+		default:
+			return "", nil, fmt.Errorf("unexpected wire type %d", tag&7)
+		}
+	}
+	return key, value, nil
+}
+
+// etcdClientConfig is the etcd endpoint and client certificate used by the API server
+type etcdClientConfig struct {
+	endpoint string
+	certFile string
+	keyFile  string
+	caFile   string
+}
+
+// getEtcdClientConfig reads the etcd client config from the API server command line
+func getEtcdClientConfig(ctx context.Context) (*etcdClientConfig, error) {
+	proc, err := LocateProcessByExecSuffix(ctx, apiServerExe)
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate kube-apiserver process: %w", err)
+	}
+
+	conf := &etcdClientConfig{}
+	servers, _ := proc.GetArg("--etcd-servers")
+	conf.endpoint = strings.Split(servers, ",")[0]
+	conf.certFile, _ = proc.GetArg("--etcd-certfile")
+	conf.keyFile, _ = proc.GetArg("--etcd-keyfile")
+	conf.caFile, _ = proc.GetArg("--etcd-cafile")
+	if conf.endpoint == "" {
+		return nil, errors.New("kube-apiserver has no --etcd-servers")
+	}
+	return conf, nil
+}
+
+// readEtcdAPISecrets reads the most recently modified secrets with the etcd v3 JSON API
+func readEtcdAPISecrets(ctx context.Context, conf *etcdClientConfig) ([]etcdKeyValue, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if conf.certFile != "" && conf.keyFile != "" {
+		certPEM, err := ReadFileOnHostFileSystem(ctx, conf.certFile)
+		if err != nil {
+			return nil, err
+		}
+		keyPEM, err := ReadFileOnHostFileSystem(ctx, conf.keyFile)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load etcd client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if conf.caFile != "" {
+		caPEM, err := ReadFileOnHostFileSystem(ctx, conf.caFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		tlsConfig.RootCAs.AppendCertsFromPEM(caPEM)
+	}
+	client := &http.Client{Timeout: etcdAPITimeout, Transport: &http.Transport{TLSClientConfig: tlsConfig}}
+
+	// the range end of a prefix is the prefix with its last byte incremented
+	rangeEnd := []byte(etcdSecretsPrefix)
+	rangeEnd[len(rangeEnd)-1]++
+	body, err := json.Marshal(map[string]interface{}{
+		"key":         []byte(etcdSecretsPrefix),
+		"range_end":   rangeEnd,
+		"limit":       etcdSampleSize,
+		"sort_order":  "DESCEND",
+		"sort_target": "MOD",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(conf.endpoint, "/")+"/v3/kv/range", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read secrets from etcd: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("etcd responded with %s: %s", resp.Status, msg)
+	}
+
+	rangeResp := struct {
+		KVs []struct {
+			Key   []byte `json:"key"`
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&rangeResp); err != nil {
+		return nil, fmt.Errorf("failed to decode etcd response: %w", err)
+	}
+
+	ret := []etcdKeyValue{}
+	for _, kv := range rangeResp.KVs {
+		ret = append(ret, etcdKeyValue{key: string(kv.Key), value: kv.Value})
+	}
+	return ret, nil
+}
@@ -0,0 +1,108 @@
+package sensor
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	bolt "go.etcd.io/bbolt"
+)
+
+func appendUvarint(buf []byte, v uint64) []byte {
+	tmp := make([]byte, binary.MaxVarintLen64)
+	return append(buf, tmp[:binary.PutUvarint(tmp, v)]...)
+}
+
+// encodeEtcdKeyValue encodes a mvccpb.KeyValue with a key, a mod revision and a value
+func encodeEtcdKeyValue(key string, modRevision uint64, value []byte) []byte {
+	ret := []byte{1<<3 | 2}
+	ret = appendUvarint(ret, uint64(len(key)))
+	ret = append(ret, key...)
+	ret = append(ret, 3<<3)
+	ret = appendUvarint(ret, modRevision)
+	if value != nil {
+		ret = append(ret, 5<<3|2)
+		ret = appendUvarint(ret, uint64(len(value)))
+		ret = append(ret, value...)
+	}
+	return ret
+}
+
+func etcdRevision(main uint64, tombstone bool) []byte {
+	ret := make([]byte, 17, 18)
+	binary.BigEndian.PutUint64(ret, main)
+	ret[8] = '_'
+	if tombstone {
+		ret = append(ret, 't')
+	}
+	return ret
+}
+
+func Test_readEtcdDBSecrets(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "db")
+	db, err := bolt.Open(dbPath, 0o600, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Update(func(tx *bolt.Tx) error {
+		bucket, err := tx.CreateBucket([]byte(etcdKeyBucket))
+		require.NoError(t, err)
+		for i, kv := range []struct {
+			key       string
+			value     []byte
+			tombstone bool
+		}{
+			{"/registry/secrets/default/plain", []byte("k8s\x00\x0a\x0c\x0a\x02v1"), false},
+			{"/registry/secrets/default/deleted", []byte("k8s\x00"), false},
+			{"/registry/configmaps/default/cm", []byte("k8s\x00"), false},
+			{"/registry/secrets/kube-system/encrypted", []byte("k8s:enc:aescbc:v1:key1:\x01\x02"), false},
+			{"/registry/secrets/default/deleted", nil, true},
+			{"/registry/secrets/default/plain", []byte("k8s:enc:kms:v2:vault:\x01\x02"), false},
+		} {
+			revision := uint64(i + 1)
+			require.NoError(t, bucket.Put(etcdRevision(revision, kv.tombstone), encodeEtcdKeyValue(kv.key, revision, kv.value)))
+		}
+		return nil
+	}))
+	require.NoError(t, db.Close())
+
+	values, err := readEtcdDBSecrets(dbPath)
+	require.NoError(t, err)
+	samples := []EtcdSecretSample{}
+	for _, kv := range values {
+		samples = append(samples, makeEtcdSecretSample(kv.key, kv.value))
+	}
+	assert.Equal(t, []EtcdSecretSample{
+		{Key: "/registry/secrets/default/plain", Encrypted: true, Provider: "kms:v2:vault"},
+		{Key: "/registry/secrets/kube-system/encrypted", Encrypted: true, Provider: "aescbc:v1:key1"},
+	}, samples)
+
+	_, err = readEtcdDBSecrets(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
+
+func Test_readEtcdAPISecrets(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/kv/range", r.URL.Path)
+		req := map[string]interface{}{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "L3JlZ2lzdHJ5L3NlY3JldHMv", req["key"])
+		assert.Equal(t, "L3JlZ2lzdHJ5L3NlY3JldHMw", req["range_end"])
+
+		assert.NoError(t, json.NewEncoder(rw).Encode(map[string]interface{}{
+			"kvs": []map[string][]byte{
+				{"key": []byte("/registry/secrets/default/plain"), "value": []byte("k8s\x00")},
+			},
+		}))
+	}))
+	defer server.Close()
+
+	values, err := readEtcdAPISecrets(context.Background(), &etcdClientConfig{endpoint: server.URL})
+	require.NoError(t, err)
+	require.Len(t, values, 1)
+	assert.Equal(t, EtcdSecretSample{Key: "/registry/secrets/default/plain"}, makeEtcdSecretSample(values[0].key, values[0].value))
+}
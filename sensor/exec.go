@@ -0,0 +1,126 @@
+package sensor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// ExecSensorTimeout is the default timeout of exec sensors
+	ExecSensorTimeout = 30 * time.Second
+
+	// maximal size of an exec sensor output
+	execSensorMaxOutput = 10 * 1024 * 1024
+)
+
+// ExecSensor is an external sensor plugin, an executable which writes its JSON result to stdout.
+// The executable gets the host file system location in the HOST_ROOT environment variable.
+type ExecSensor struct {
+	name    string
+	command string
+	timeout time.Duration
+}
+
+// NewExecSensor creates a sensor running `command`
+func NewExecSensor(name, command string, timeout time.Duration) *ExecSensor {
+	return &ExecSensor{name: name, command: command, timeout: timeout}
+}
+
+// Name implements Sensor
+func (s *ExecSensor) Name() string { return s.name }
+
+// Sense implements Sensor, it runs the executable and returns its output
+func (s *ExecSensor) Sense(ctx context.Context) (json.RawMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+
+	stdout, stderr := &limitedBuffer{limit: execSensorMaxOutput}, &limitedBuffer{limit: 4096}
+	cmd := exec.Command(s.command)
+	cmd.Env = append(os.Environ(), "HOST_ROOT="+hostFS(ctx).Root())
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	// run in a new process group, so the children of the plugin are killed with it on timeout
+	setProcessGroup(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", s.name, err)
+	}
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			killProcessGroup(cmd)
+		case <-done:
+		}
+	}()
+
+	if err := cmd.Wait(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return nil, fmt.Errorf("plugin %s timed out after %s", s.name, s.timeout)
+		}
+		return nil, fmt.Errorf("plugin %s failed: %w: %s", s.name, err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.truncated {
+		return nil, fmt.Errorf("plugin %s output exceeds %d bytes", s.name, execSensorMaxOutput)
+	}
+
+	output := bytes.TrimSpace(stdout.Bytes())
+	if !json.Valid(output) {
+		return nil, fmt.Errorf("plugin %s output is not valid JSON", s.name)
+	}
+	return output, nil
+}
+
+// LoadExecSensors creates an exec sensor for every executable in `dir`, named after the file (without extension)
+func LoadExecSensors(dir string, timeout time.Duration) ([]*ExecSensor, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugins directory: %w", err)
+	}
+
+	sensors := []*ExecSensor{}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		// follow ConfigMap mounts, whose files are symbolic links
+		if info.Mode()&os.ModeSymlink != 0 {
+			if info, err = os.Stat(path.Join(dir, entry.Name())); err != nil {
+				return nil, err
+			}
+		}
+		if !info.Mode().IsRegular() || info.Mode().Perm()&0o111 == 0 {
+			continue
+		}
+		name := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		sensors = append(sensors, NewExecSensor(name, path.Join(dir, entry.Name()), timeout))
+	}
+	return sensors, nil
+}
+
+// limitedBuffer is a buffer which discards writes beyond its limit
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if remaining := b.limit - b.Len(); n > remaining {
+		b.truncated = true
+		p = p[:remaining]
+	}
+	b.Buffer.Write(p)
+	// report a full write, so the command won't fail on a short write
+	return n, nil
+}
@@ -0,0 +1,51 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePlugin(t *testing.T, dir, name, script string, perm os.FileMode) {
+	require.NoError(t, os.WriteFile(path.Join(dir, name), []byte("#!/bin/sh\n"+script+"\n"), perm))
+}
+
+func TestExecSensors(t *testing.T) {
+	dir := t.TempDir()
+	writePlugin(t, dir, "ok.sh", `echo "{\"root\": \"$HOST_ROOT\"}"`, 0o755)
+	writePlugin(t, dir, "invalid.sh", `echo not json`, 0o755)
+	writePlugin(t, dir, "failed.sh", `echo oops >&2; exit 1`, 0o755)
+	writePlugin(t, dir, "slow.sh", `sleep 5`, 0o755)
+	writePlugin(t, dir, "notexecutable.sh", `echo {}`, 0o644)
+
+	plugins, err := LoadExecSensors(dir, 100*time.Millisecond)
+	require.NoError(t, err)
+
+	results := map[string]string{}
+	errs := map[string]string{}
+	for _, plugin := range plugins {
+		result, err := plugin.Sense(context.Background())
+		if err != nil {
+			errs[plugin.Name()] = err.Error()
+			continue
+		}
+		results[plugin.Name()] = string(result)
+	}
+
+	assert.Equal(t, map[string]string{"ok": `{"root": "/host_fs"}`}, results)
+	assert.Len(t, errs, 3)
+	assert.Contains(t, errs["invalid"], "not valid JSON")
+	assert.Contains(t, errs["failed"], "oops")
+	assert.Contains(t, errs["slow"], "timed out")
+}
+
+func TestRegister(t *testing.T) {
+	assert.NotNil(t, Lookup("kubeletInfo"))
+	assert.Error(t, Register(NewSensor("kubeletInfo", nil)))
+	assert.Nil(t, Lookup("not-registered"))
+}
@@ -0,0 +1,13 @@
+package sensor
+
+import (
+	"os/exec"
+)
+
+// setProcessGroup is a no-op, Windows has no process groups
+func setProcessGroup(cmd *exec.Cmd) {}
+
+// killProcessGroup kills the process of a started command, its children aren't killed
+func killProcessGroup(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
@@ -0,0 +1,28 @@
+package sensor
+
+// File attributes, as set by chattr
+const (
+	// The file can't be modified, removed or linked to, even by root (chattr +i)
+	FileAttributeImmutable = "immutable"
+
+	// The file can only be appended to (chattr +a)
+	FileAttributeAppendOnly = "appendOnly"
+)
+
+const (
+	// The inode flags of FS_IOC_GETFLAGS
+	fsImmutableFlag  = 0x00000010
+	fsAppendOnlyFlag = 0x00000020
+)
+
+// fileAttributesOfFlags returns the attributes of the inode flags of a file
+func fileAttributesOfFlags(flags uint32) []string {
+	ret := []string{}
+	if flags&fsImmutableFlag != 0 {
+		ret = append(ret, FileAttributeImmutable)
+	}
+	if flags&fsAppendOnlyFlag != 0 {
+		ret = append(ret, FileAttributeAppendOnly)
+	}
+	return ret
+}
@@ -0,0 +1,38 @@
+package sensor
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// fileAttributes returns the immutable and append-only attributes of a regular file or a directory, nil for the other
+// file types and the file systems without inode flags (e.g. procfs)
+func fileAttributes(filePath string) ([]string, error) {
+	// stat before opening, since opening devices and FIFOs may block or have side effects
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() && !info.IsDir() {
+		return nil, nil
+	}
+
+	fd, err := unix.Open(filePath, unix.O_RDONLY|unix.O_NONBLOCK|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer unix.Close(fd)
+	flags, err := unix.IoctlGetUint32(fd, unix.FS_IOC_GETFLAGS)
+	if errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EOPNOTSUPP) || errors.Is(err, unix.EINVAL) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if attributes := fileAttributesOfFlags(flags); len(attributes) > 0 {
+		return attributes, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package sensor
+
+// fileAttributes returns nil, the inode flags are Linux specific
+func fileAttributes(filePath string) ([]string, error) {
+	return nil, nil
+}
@@ -0,0 +1,30 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileAttributesOfFlags(t *testing.T) {
+	assert.Empty(t, fileAttributesOfFlags(0x00080000))
+	assert.Equal(t, []string{FileAttributeImmutable}, fileAttributesOfFlags(0x00080010))
+	assert.Equal(t, []string{FileAttributeImmutable, FileAttributeAppendOnly}, fileAttributesOfFlags(0x30))
+}
+
+func TestFileAttributes(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(filePath, []byte("kind: KubeletConfiguration"), 0o600))
+
+	attributes, err := fileAttributes(filePath)
+	require.NoError(t, err)
+	assert.Nil(t, attributes)
+
+	info, err := makeFileInfo(context.Background(), filePath, false, false)
+	require.NoError(t, err)
+	assert.Nil(t, info.Attributes)
+}
@@ -0,0 +1,216 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// A fixture is a recorded snapshot of the host files the sensors read, laid out as the host root. The processes of
+// the snapshot are recorded as the proc file system entries of the fixture, i.e. /proc/<pid>/cmdline.
+
+const (
+	// maximal size of a recorded file, larger files are skipped
+	fixtureMaxFileSize = 1024 * 1024
+)
+
+var (
+	// The host paths recorded in a fixture, directories are recorded recursively
+	fixturePaths = []string{
+		"/etc/kubernetes",
+		"/etc/systemd/system",
+		"/lib/systemd/system/kubelet.service",
+		"/usr/lib/systemd/system/kubelet.service",
+		"/etc/os-release",
+		"/usr/lib/os-release",
+		"/etc/passwd",
+		"/etc/group",
+		"/etc/selinux/semanage.conf",
+		"/etc/containerd",
+		"/etc/crio",
+		"/etc/docker/daemon.json",
+		"/etc/cni/net.d",
+		"/var/lib/kubelet/config.yaml",
+		"/var/lib/kubelet/kubeconfig",
+		"/var/lib/kubelet/pki",
+		"/proc/version",
+		"/proc/meminfo",
+		"/proc/sys/kernel",
+		"/proc/1/mountinfo",
+		"/sys/kernel/security/apparmor/profiles",
+	}
+
+	// The processes recorded in a fixture, by executable suffix
+	fixtureProcesses = []string{
+		apiServerExe,
+		controllerManagerExe,
+		schedulerExe,
+		etcdExe,
+		kubeletProcessSuffix,
+		kubeProxyExe,
+	}
+)
+
+// fixturePlatform is the Platform of a fixture, whose processes share its root
+type fixturePlatform struct {
+	root string
+}
+
+// Name implements Platform
+func (f fixturePlatform) Name() string { return "fixture" }
+
+// LocateProcess implements Platform
+func (f fixturePlatform) LocateProcess(ctx context.Context, execSuffix string) (*ProcessDetails, error) {
+	return locateProcfsProcess(ctx, path.Join(f.root, procDirName), execSuffix)
+}
+
+// ProcessRoot implements Platform
+func (f fixturePlatform) ProcessRoot(pid int32) string {
+	return f.root
+}
+
+// ReadXattr implements Platform, fixtures don't record extended attributes
+func (f fixturePlatform) ReadXattr(filePath, name string) ([]byte, error) {
+	return nil, errNotSupported
+}
+
+// Mounts implements Platform
+func (f fixturePlatform) Mounts(ctx context.Context) ([]MountInfo, error) {
+	content, err := NewHostFS(f.root).ReadFile(hostMountInfoPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseMountInfo(content)
+}
+
+// UseFixtureRoot points the sensors at the fixture at `root`, instead of the host
+func UseFixtureRoot(root string) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return newSenseError(ErrHostRootMissing, "UseFixtureRoot", err)
+	}
+	if !info.IsDir() {
+		return newSenseError(ErrHostRootMissing, "UseFixtureRoot", fmt.Errorf("%s is not a directory", root))
+	}
+	hostFileSystemDefaultLocation = root
+	hostPlatform = fixturePlatform{root: root}
+	return nil
+}
+
+// ResetFixtureRoot points the sensors back at the host, see `UseFixtureRoot`
+func ResetFixtureRoot() {
+	hostFileSystemDefaultLocation = defaultHostRoot
+	hostPlatform = newPlatform()
+}
+
+// RecordFixture records a fixture of the host into the directory `dst`. Unreadable and missing paths are skipped.
+// Fixtures hold the credentials of the host files, e.g. private keys and kubeconfigs.
+func RecordFixture(ctx context.Context, dst string) error {
+	if err := os.MkdirAll(dst, 0700); err != nil {
+		return err
+	}
+	hfs := hostFS(ctx)
+	for _, hostFilePath := range fixturePaths {
+		if err := recordFixturePath(ctx, hfs, dst, hostFilePath); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			logger(ctx).Warn("failed to record fixture path", zap.String("path", hostFilePath), zap.Error(err))
+		}
+	}
+
+	for _, suffix := range fixtureProcesses {
+		proc, err := LocateProcessByExecSuffix(ctx, suffix)
+		if err != nil {
+			continue
+		}
+		if err := recordFixtureProcess(ctx, hfs, dst, proc); err != nil {
+			logger(ctx).Warn("failed to record fixture process", zap.String("process", suffix), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// recordFixturePath copies a host file, symlink or directory into the fixture
+func recordFixturePath(ctx context.Context, hfs HostFS, dst, hostFilePath string) error {
+	srcPath := hfs.Path(hostFilePath)
+	dstPath := path.Join(dst, hostFilePath)
+	info, err := os.Lstat(srcPath)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(path.Dir(dstPath), 0700); err != nil {
+		return err
+	}
+
+	switch {
+	case info.Mode()&fs.ModeSymlink != 0:
+		target, err := os.Readlink(srcPath)
+		if err != nil {
+			return err
+		}
+		return os.Symlink(target, dstPath)
+	case info.IsDir():
+		entries, err := os.ReadDir(srcPath)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := recordFixturePath(ctx, hfs, dst, path.Join(hostFilePath, entry.Name())); err != nil {
+				logger(ctx).Debug("failed to record fixture path", zap.String("path", hostFilePath), zap.Error(err))
+			}
+		}
+		return nil
+	case info.Mode().IsRegular():
+		return copyFixtureFile(srcPath, dstPath, info.Mode().Perm())
+	}
+	return nil
+}
+
+// copyFixtureFile copies a regular file up to `fixtureMaxFileSize`. The proc file system reports files as empty, so
+// their size is only known when read.
+func copyFixtureFile(srcPath, dstPath string, perm fs.FileMode) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	content, err := io.ReadAll(io.LimitReader(src, fixtureMaxFileSize+1))
+	if err != nil {
+		return err
+	}
+	if len(content) > fixtureMaxFileSize {
+		return fmt.Errorf("file exceeds %d bytes", fixtureMaxFileSize)
+	}
+	return os.WriteFile(dstPath, content, perm)
+}
+
+// recordFixtureProcess records the command line and the cgroup of a process, and the files of its path flags,
+// e.g. --config=/var/lib/kubelet/config.yaml
+func recordFixtureProcess(ctx context.Context, hfs HostFS, dst string, proc *ProcessDetails) error {
+	for _, arg := range proc.CmdLine {
+		if _, value, ok := strings.Cut(arg, "="); ok {
+			arg = value
+		}
+		if strings.HasPrefix(arg, "/") {
+			if err := recordFixturePath(ctx, hfs, dst, arg); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				logger(ctx).Debug("failed to record fixture path", zap.String("path", arg), zap.Error(err))
+			}
+		}
+	}
+
+	pidDir := path.Join(procDirName, strconv.Itoa(int(proc.PID)))
+	if err := os.MkdirAll(path.Join(dst, pidDir), 0700); err != nil {
+		return err
+	}
+	cmdLine := []byte(strings.Join(proc.CmdLine, "\x00"))
+	if err := os.WriteFile(path.Join(dst, pidDir, "cmdline"), cmdLine, 0600); err != nil {
+		return err
+	}
+	return recordFixturePath(ctx, hfs, dst, path.Join(pidDir, "cgroup"))
+}
@@ -0,0 +1,76 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useFixture returns a context whose sensors read a fixture of testdata/fixtures, for the test
+func useFixture(t *testing.T, name string) context.Context {
+	origPlatform := hostPlatform
+	t.Cleanup(func() { hostPlatform = origPlatform })
+	root := path.Join("testdata/fixtures", name)
+	require.DirExists(t, root)
+	hostPlatform = fixturePlatform{root: root}
+	return WithHostFS(context.Background(), NewHostFS(root))
+}
+
+func TestFixtureKubeadmKubelet(t *testing.T) {
+	ctx := useFixture(t, "kubeadm")
+
+	info, err := SenseKubeletInfo(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, info.ConfigFile)
+	assert.Equal(t, "/var/lib/kubelet/config.yaml", info.ConfigFile.Path)
+	require.NotNil(t, info.KubeConfigFile)
+	assert.Equal(t, "/etc/kubernetes/kubelet.conf", info.KubeConfigFile.Path)
+	assert.Equal(t, KubeConfigSourceFlag, info.KubeConfigSource)
+	require.NotNil(t, info.ClientCAFile)
+	assert.Equal(t, "/etc/kubernetes/pki/ca.crt", info.ClientCAFile.Path)
+	require.Len(t, info.ServiceFiles, 1)
+	assert.Equal(t, "/etc/systemd/system/kubelet.service.d/10-kubeadm.conf", info.ServiceFiles[0].Path)
+}
+
+func TestUseFixtureRootMissing(t *testing.T) {
+	assert.ErrorIs(t, UseFixtureRoot("testdata/fixtures/not-exist"), ErrHostRootMissing)
+}
+
+func TestRecordFixture(t *testing.T) {
+	origPlatform := hostPlatform
+	defer func() { hostPlatform = origPlatform }()
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+	hostPlatform = fixturePlatform{root: hfs.Root()}
+	writeFSFile(t, hfs, "/etc/kubernetes/admin.conf", []byte("admin"))
+	writeFSFile(t, hfs, "/opt/kubelet/config.yaml", []byte("config"))
+	writeFSFile(t, hfs, "/proc/42/cmdline", []byte("/usr/bin/kubelet\x00--config=/opt/kubelet/config.yaml\x00"))
+	writeFSFile(t, hfs, "/proc/42/cgroup", []byte("0::/system.slice/kubelet.service\n"))
+	require.NoError(t, os.Symlink("/etc/kubernetes/admin.conf", hfs.Path("/etc/kubernetes/super-admin.conf")))
+
+	dst := t.TempDir()
+	require.NoError(t, RecordFixture(ctx, dst))
+
+	content, err := os.ReadFile(path.Join(dst, "/etc/kubernetes/admin.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "admin", string(content))
+	target, err := os.Readlink(path.Join(dst, "/etc/kubernetes/super-admin.conf"))
+	require.NoError(t, err)
+	assert.Equal(t, "/etc/kubernetes/admin.conf", target)
+	content, err = os.ReadFile(path.Join(dst, "/opt/kubelet/config.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "config", string(content))
+	assert.FileExists(t, path.Join(dst, "/proc/42/cgroup"))
+
+	// the recorded fixture is sensed as the host
+	defer func(orig string) { hostFileSystemDefaultLocation = orig }(hostFileSystemDefaultLocation)
+	require.NoError(t, UseFixtureRoot(dst))
+	proc, err := LocateKubeletProcess(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(42), proc.PID)
+	assert.Equal(t, []string{"/usr/bin/kubelet", "--config=/opt/kubelet/config.yaml", ""}, proc.CmdLine)
+}
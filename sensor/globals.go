@@ -9,5 +9,5 @@ const (
 var (
 	// Where the host sensor is expecting host fs to be mounted.
 	// Defined as var for testing purposes only
-	hostFileSystemDefaultLocation = "/host_fs"
+	hostFileSystemDefaultLocation = defaultHostRoot
 )
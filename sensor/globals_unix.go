@@ -0,0 +1,6 @@
+//go:build !windows
+
+package sensor
+
+// The host root is mounted into the sensor container
+const defaultHostRoot = "/host_fs"
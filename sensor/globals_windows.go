@@ -0,0 +1,4 @@
+package sensor
+
+// The sensor runs as a HostProcess container, which shares the file system of the host
+const defaultHostRoot = "C:/"
@@ -0,0 +1,49 @@
+package sensor
+
+// Host access modes, how the sensor reads the host file system
+const (
+	// The host root is mounted into the sensor container, at /host_fs by default
+	HostAccessMounted = "mounted"
+
+	// The sensor is a privileged container sharing the host PID namespace, without a mount of the host root. The
+	// host root is read through the root of the host init process, /proc/1/root.
+	HostAccessProcRoot = "proc-root"
+
+	// The sensor runs on the host, or in a container seeing the host root as its own
+	HostAccessHost = "host"
+
+	// The sensor is a Windows HostProcess container, which shares the file system of the host
+	HostAccessHostProcess = "host-process"
+)
+
+// Process access methods, how the sensor locates the host processes
+const (
+	// The /proc file system
+	ProcessAccessProcfs = "procfs"
+
+	// The Windows process snapshots and the service manager
+	ProcessAccessToolhelp = "toolhelp"
+)
+
+// HostAccess is how the sensor accesses the host
+type HostAccess struct {
+	// One of HostAccess*
+	Mode string `json:"mode"`
+
+	// The directory of the host root
+	Root string `json:"root"`
+
+	// One of ProcessAccess*
+	ProcessAccess string `json:"processAccess"`
+}
+
+// DetectHostAccess detects how the sensor runs on the platform, and reads the host root accordingly. A non empty
+// `root` overrides the detected host root.
+func DetectHostAccess(root string) HostAccess {
+	access := detectHostAccess(defaultHostRoot)
+	if root != "" {
+		access.Mode, access.Root = HostAccessMounted, root
+	}
+	hostFileSystemDefaultLocation = access.Root
+	return access
+}
@@ -0,0 +1,30 @@
+//go:build !windows
+
+package sensor
+
+import (
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectHostAccess(t *testing.T) {
+	mounted := t.TempDir()
+	assert.Equal(t, HostAccess{Mode: HostAccessMounted, Root: mounted, ProcessAccess: ProcessAccessProcfs},
+		detectHostAccess(mounted))
+
+	access := detectHostAccess(path.Join(mounted, "not-exist"))
+	assert.Contains(t, []string{HostAccessHost, HostAccessProcRoot}, access.Mode)
+	assert.Equal(t, ProcessAccessProcfs, access.ProcessAccess)
+}
+
+func TestDetectHostAccessOverride(t *testing.T) {
+	defer func(orig string) { hostFileSystemDefaultLocation = orig }(hostFileSystemDefaultLocation)
+	root := t.TempDir()
+
+	access := DetectHostAccess(root)
+	assert.Equal(t, HostAccessMounted, access.Mode)
+	assert.Equal(t, root, access.Root)
+	assert.Equal(t, root, hostFileSystemDefaultLocation)
+}
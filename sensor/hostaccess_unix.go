@@ -0,0 +1,28 @@
+//go:build !windows
+
+package sensor
+
+import (
+	"os"
+)
+
+const hostInitRoot = "/proc/1/root"
+
+// detectHostAccess returns the mounted host root if it exists, else the root of the host init process if it
+// differs from the sensor root, i.e. the sensor shares the host PID namespace, else the sensor root
+func detectHostAccess(mountedRoot string) HostAccess {
+	ret := HostAccess{Mode: HostAccessMounted, Root: mountedRoot, ProcessAccess: ProcessAccessProcfs}
+	if info, err := os.Stat(mountedRoot); err == nil && info.IsDir() {
+		return ret
+	}
+
+	ret.Mode, ret.Root = HostAccessHost, "/"
+	initRoot, err := os.Stat(hostInitRoot)
+	if err != nil {
+		return ret
+	}
+	if sensorRoot, err := os.Stat("/"); err == nil && !os.SameFile(initRoot, sensorRoot) {
+		ret.Mode, ret.Root = HostAccessProcRoot, hostInitRoot
+	}
+	return ret
+}
@@ -0,0 +1,20 @@
+package sensor
+
+import (
+	"os"
+)
+
+// HostProcess containers get the mount point of their volumes in this environment variable
+const containerSandboxMountPointEnv = "CONTAINER_SANDBOX_MOUNT_POINT"
+
+// detectHostAccess returns the system drive of the host, shared by HostProcess containers
+func detectHostAccess(defaultRoot string) HostAccess {
+	ret := HostAccess{Mode: HostAccessHost, Root: defaultRoot, ProcessAccess: ProcessAccessToolhelp}
+	if drive := os.Getenv("SystemDrive"); drive != "" {
+		ret.Root = drive + "/"
+	}
+	if os.Getenv(containerSandboxMountPointEnv) != "" {
+		ret.Mode = HostAccessHostProcess
+	}
+	return ret
+}
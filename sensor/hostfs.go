@@ -0,0 +1,123 @@
+package sensor
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// HostFS is the file system of the host, rooted at the directory the host root is mounted at. The symlinks of its
+// paths are resolved within the root (see `resolveRootPath`).
+//
+// It implements fs.FS, fs.StatFS, fs.ReadFileFS and fs.ReadDirFS. Paths are either absolute host paths, e.g.
+// "/etc/kubernetes", or fs.FS paths, e.g. "etc/kubernetes". The host directories are walked and globbed with
+// fs.WalkDir and fs.Glob, which resolve every directory within the root and return host paths.
+//
+// The sensors read the HostFS of their context (see `WithHostFS`), so they can be tested against fixture trees, e.g.
+// `WithHostFS(ctx, NewHostFS("testdata/testmakehostfiles"))`, without changing the configured host root.
+type HostFS struct {
+	root string
+}
+
+var (
+	_ fs.StatFS     = HostFS{}
+	_ fs.ReadFileFS = HostFS{}
+	_ fs.ReadDirFS  = HostFS{}
+)
+
+// NewHostFS returns the host file system rooted at `root`
+func NewHostFS(root string) HostFS {
+	return HostFS{root: root}
+}
+
+type hostFSKey struct{}
+
+// WithHostFS returns a context whose sensors read the host file system `hfs`
+func WithHostFS(ctx context.Context, hfs HostFS) context.Context {
+	return context.WithValue(ctx, hostFSKey{}, hfs)
+}
+
+// hostFS returns the host file system of the context, the one the sensor is configured with by default
+func hostFS(ctx context.Context) HostFS {
+	if hfs, ok := ctx.Value(hostFSKey{}).(HostFS); ok {
+		return hfs
+	}
+	return HostFS{root: hostFileSystemDefaultLocation}
+}
+
+// Root returns the directory of the host root
+func (h HostFS) Root() string {
+	return h.root
+}
+
+// Path returns the path of a host path in the sensor file system, without resolving its symlinks
+func (h HostFS) Path(name string) string {
+	return path.Join(h.root, name)
+}
+
+// RelPath returns the host path of a path in the sensor file system, e.g. "/etc/kubernetes" of "/host_fs/etc/kubernetes"
+func (h HostFS) RelPath(fullPath string) string {
+	relPath, err := filepath.Rel(h.Path("/"), fullPath)
+	if err != nil {
+		return fullPath
+	}
+	return path.Join("/", relPath)
+}
+
+// Resolve returns the path of a host path in the sensor file system, with its symlinks resolved within the root
+func (h HostFS) Resolve(name string) (string, error) {
+	return resolveRootPath(h.root, name)
+}
+
+// Open implements fs.FS
+func (h HostFS) Open(name string) (fs.File, error) {
+	fullPath, err := h.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(fullPath)
+}
+
+// Stat implements fs.StatFS
+func (h HostFS) Stat(name string) (fs.FileInfo, error) {
+	fullPath, err := h.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Stat(fullPath)
+}
+
+// ReadFile implements fs.ReadFileFS
+func (h HostFS) ReadFile(name string) ([]byte, error) {
+	fullPath, err := h.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(fullPath)
+}
+
+// ReadDir implements fs.ReadDirFS
+func (h HostFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	fullPath, err := h.Resolve(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadDir(fullPath)
+}
+
+// Readlink returns the target of the symlink at a host path, the symlinks of its directory are resolved within the
+// root. The target isn't resolved.
+func (h HostFS) Readlink(name string) (string, error) {
+	dir, err := h.Resolve(path.Dir(name))
+	if err != nil {
+		return "", err
+	}
+	return os.Readlink(filepath.Join(dir, path.Base(name)))
+}
+
+// FileInfo returns the FileInfo of a host file, with its ownership names read from the host users and groups
+func (h HostFS) FileInfo(ctx context.Context, name string, readContent bool) (*FileInfo, error) {
+	return makeChangedRootFileInfo(ctx, name, readContent, true, h.root)
+}
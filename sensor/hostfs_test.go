@@ -0,0 +1,48 @@
+package sensor
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHostFS(t *testing.T) {
+	hfs := NewHostFS("testdata/testmakehostfiles")
+
+	files := []string{}
+	require.NoError(t, fs.WalkDir(hfs, ".", func(filePath string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			files = append(files, filePath)
+		}
+		return err
+	}))
+	assert.Equal(t, []string{"dir/placeholder.json", "file1.yaml", "file2.yaml", "file3.yaml"}, files)
+
+	// absolute host paths and fs.FS paths are the same files
+	absContent, err := hfs.ReadFile("/dir/placeholder.json")
+	require.NoError(t, err)
+	content, err := fs.ReadFile(hfs, "dir/placeholder.json")
+	require.NoError(t, err)
+	assert.Equal(t, absContent, content)
+
+	fileInfo, err := hfs.FileInfo(context.Background(), "/file1.yaml", false)
+	require.NoError(t, err)
+	assert.Equal(t, "/file1.yaml", fileInfo.Path)
+	assert.Equal(t, "/file1.yaml", hfs.RelPath(hfs.Path("/file1.yaml")))
+
+	_, err = hfs.Open("../hostfs_test.go")
+	assert.ErrorIs(t, err, ErrPathOutsideRoot)
+}
+
+func TestHostFSContext(t *testing.T) {
+	assert.Equal(t, hostFileSystemDefaultLocation, hostFS(context.Background()).Root())
+
+	// the sensors of the context read its host file system, the configured one is unchanged
+	ctx := WithHostFS(context.Background(), NewHostFS("testdata/testmakehostfiles"))
+	assert.Equal(t, "testdata/testmakehostfiles", hostFS(ctx).Root())
+	assert.NotEqual(t, "testdata/testmakehostfiles", hostFileSystemDefaultLocation)
+	assert.NotNil(t, makeHostFileInfoVerbose(ctx, "/file1.yaml", false))
+}
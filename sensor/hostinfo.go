@@ -0,0 +1,241 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"path"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	dmiDir            = "/sys/class/dmi/id"
+	hypervisorPath    = "/sys/hypervisor/type"
+	efiDir            = "/sys/firmware/efi"
+	cpuInfoPath       = "/proc/cpuinfo"
+	bootIDPath        = "/proc/sys/kernel/random/boot_id"
+	cpuHypervisorFlag = "hypervisor"
+
+	// Virtualization of the node, named after systemd-detect-virt
+	VirtualizationNone    = "none"
+	VirtualizationOtherVM = "vm-other"
+)
+
+var (
+	machineIDPaths = []string{"/etc/machine-id", "/var/lib/dbus/machine-id"}
+
+	// The DMI vendors and products of the hypervisors, by prefix, as systemd-detect-virt matches them
+	dmiVirtualizationVendors = []struct {
+		prefix         string
+		virtualization string
+	}{
+		{"KVM", "kvm"},
+		{"OpenStack", "kvm"},
+		{"KubeVirt", "kvm"},
+		{"Amazon EC2", "amazon"},
+		{"QEMU", "qemu"},
+		{"VMware", "vmware"},
+		{"VMW", "vmware"},
+		{"innotek GmbH", "oracle"},
+		{"VirtualBox", "oracle"},
+		{"Xen", "xen"},
+		{"Bochs", "bochs"},
+		{"Parallels", "parallels"},
+		{"BHYVE", "bhyve"},
+		{"Hyper-V", "microsoft"},
+		{"Google Compute Engine", "google"},
+		{"Apple Virtualization", "apple"},
+	}
+
+	// The DMI fields matched with the hypervisors
+	dmiVirtualizationFields = []string{"product_name", "sys_vendor", "board_vendor", "bios_vendor", "product_version"}
+)
+
+// HostInfo is the hardware and operating system identity of the node
+type HostInfo struct {
+	Uname Uname `json:"uname"`
+
+	// The systemd machine ID, and the ID of the current boot
+	MachineID string `json:"machineID,omitempty"`
+	BootID    string `json:"bootID,omitempty"`
+
+	// The hardware UUID of the DMI (SMBIOS) tables
+	ProductUUID string `json:"productUUID,omitempty"`
+
+	// The virtualization of the node as named by systemd-detect-virt, e.g. "kvm", "amazon" or "none"
+	Virtualization string `json:"virtualization"`
+
+	Hardware HostHardware `json:"hardware"`
+	CPU      HostCPU      `json:"cpu"`
+	Firmware HostFirmware `json:"firmware"`
+}
+
+// Uname holds the uname fields of the node
+type Uname struct {
+	SysName    string `json:"sysName"`
+	NodeName   string `json:"nodeName"`
+	Release    string `json:"release"`
+	Version    string `json:"version"`
+	Machine    string `json:"machine"`
+	DomainName string `json:"domainName,omitempty"`
+}
+
+// HostHardware holds the DMI vendor and product of the node
+type HostHardware struct {
+	Vendor         string `json:"vendor,omitempty"`
+	ProductName    string `json:"productName,omitempty"`
+	ProductVersion string `json:"productVersion,omitempty"`
+	BoardVendor    string `json:"boardVendor,omitempty"`
+	BoardName      string `json:"boardName,omitempty"`
+}
+
+// HostCPU holds the CPU model and microcode of the node
+type HostCPU struct {
+	Architecture string `json:"architecture"`
+	Vendor       string `json:"vendor,omitempty"`
+	ModelName    string `json:"modelName,omitempty"`
+
+	// Number of the online logical CPUs
+	Count int `json:"count"`
+
+	// The microcode revision of the first CPU, empty if the kernel doesn't report it (e.g. on arm64)
+	Microcode string `json:"microcode,omitempty"`
+}
+
+// HostFirmware holds the BIOS or UEFI firmware of the node
+type HostFirmware struct {
+	Vendor  string `json:"vendor,omitempty"`
+	Version string `json:"version,omitempty"`
+	Date    string `json:"date,omitempty"`
+
+	// Whether the node booted with UEFI
+	UEFI bool `json:"uefi"`
+}
+
+// readHostString reads a one line host file, empty if it can't be read
+func readHostString(ctx context.Context, filePath string) string {
+	content, err := ReadFileOnHostFileSystem(ctx, filePath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(content))
+}
+
+// parseCPUInfo parses the CPU model, count and microcode of /proc/cpuinfo, and whether the CPUs run on a hypervisor
+func parseCPUInfo(content []byte) (HostCPU, bool) {
+	cpu := HostCPU{}
+	hypervisor := false
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "processor":
+			cpu.Count++
+		case "vendor_id":
+			if cpu.Vendor == "" {
+				cpu.Vendor = value
+			}
+		case "model name":
+			if cpu.ModelName == "" {
+				cpu.ModelName = value
+			}
+		case "microcode":
+			if cpu.Microcode == "" {
+				cpu.Microcode = value
+			}
+		case "flags":
+			hypervisor = hypervisor || containsString(strings.Fields(value), cpuHypervisorFlag)
+		}
+	}
+	return cpu, hypervisor
+}
+
+// detectVirtualization returns the virtualization of the node from its DMI fields, the hypervisor type of Xen and
+// the hypervisor CPU flag
+func detectVirtualization(dmi map[string]string, hypervisorType string, hypervisorFlag bool) string {
+	for _, field := range dmiVirtualizationFields {
+		for _, vendor := range dmiVirtualizationVendors {
+			if !strings.HasPrefix(dmi[field], vendor.prefix) {
+				continue
+			}
+			// the EC2 bare metal instances have the EC2 DMI vendor
+			if vendor.virtualization == "amazon" && !hypervisorFlag {
+				return VirtualizationNone
+			}
+			return vendor.virtualization
+		}
+	}
+	if dmi["sys_vendor"] == "Microsoft Corporation" && dmi["product_name"] == "Virtual Machine" {
+		return "microsoft"
+	}
+	if hypervisorType == "xen" {
+		return "xen"
+	}
+	if hypervisorFlag {
+		return VirtualizationOtherVM
+	}
+	return VirtualizationNone
+}
+
+// SenseHostInfo returns the uname fields, the machine ID, the virtualization, the hardware, the CPU and the firmware
+// of the node
+func SenseHostInfo(ctx context.Context) (*HostInfo, error) {
+	machine, err := unameMachine()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &HostInfo{
+		Uname: Uname{
+			SysName:    readHostString(ctx, path.Join(procSysKernelDir, "ostype")),
+			NodeName:   readHostString(ctx, path.Join(procSysKernelDir, "hostname")),
+			Release:    readHostString(ctx, kernelReleasePath),
+			Version:    readHostString(ctx, path.Join(procSysKernelDir, "version")),
+			Machine:    machine,
+			DomainName: readHostString(ctx, path.Join(procSysKernelDir, "domainname")),
+		},
+		BootID:      readHostString(ctx, bootIDPath),
+		ProductUUID: readHostString(ctx, path.Join(dmiDir, "product_uuid")),
+	}
+	if ret.Uname.DomainName == "(none)" {
+		ret.Uname.DomainName = ""
+	}
+	for _, machineIDPath := range machineIDPaths {
+		if ret.MachineID = readHostString(ctx, machineIDPath); ret.MachineID != "" {
+			break
+		}
+	}
+
+	dmi := map[string]string{}
+	for _, field := range []string{"sys_vendor", "product_name", "product_version", "board_vendor", "board_name", "bios_vendor", "bios_version", "bios_date"} {
+		dmi[field] = readHostString(ctx, path.Join(dmiDir, field))
+	}
+	ret.Hardware = HostHardware{
+		Vendor:         dmi["sys_vendor"],
+		ProductName:    dmi["product_name"],
+		ProductVersion: dmi["product_version"],
+		BoardVendor:    dmi["board_vendor"],
+		BoardName:      dmi["board_name"],
+	}
+	ret.Firmware = HostFirmware{Vendor: dmi["bios_vendor"], Version: dmi["bios_version"], Date: dmi["bios_date"]}
+	if _, err := statHostFile(ctx, efiDir); err == nil {
+		ret.Firmware.UEFI = true
+	}
+
+	hypervisorFlag := false
+	if content, err := ReadFileOnHostFileSystem(ctx, cpuInfoPath); err != nil {
+		logger(ctx).Debug("SenseHostInfo failed to read the CPU info", zap.Error(err))
+	} else {
+		ret.CPU, hypervisorFlag = parseCPUInfo(content)
+	}
+	ret.CPU.Architecture = machine
+	ret.Virtualization = detectVirtualization(dmi, readHostString(ctx, hypervisorPath), hypervisorFlag)
+	return ret, nil
+}
@@ -0,0 +1,12 @@
+package sensor
+
+import "golang.org/x/sys/unix"
+
+// unameMachine returns the hardware name of the kernel, e.g. "x86_64"
+func unameMachine() (string, error) {
+	uts := unix.Utsname{}
+	if err := unix.Uname(&uts); err != nil {
+		return "", err
+	}
+	return unix.ByteSliceToString(uts.Machine[:]), nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package sensor
+
+// unameMachine returns the hardware name of the kernel, e.g. "x86_64"
+func unameMachine() (string, error) {
+	return "", errNotSupported
+}
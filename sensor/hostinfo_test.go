@@ -0,0 +1,64 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCPUInfo(t *testing.T) {
+	cpu, hypervisor := parseCPUInfo([]byte(`processor	: 0
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) Platinum 8375C CPU @ 2.90GHz
+microcode	: 0xd0003a5
+flags		: fpu vme de pse tsc msr hypervisor lahf_lm
+
+processor	: 1
+vendor_id	: GenuineIntel
+model name	: Intel(R) Xeon(R) Platinum 8375C CPU @ 2.90GHz
+microcode	: 0xd0003a5
+flags		: fpu vme de pse tsc msr hypervisor lahf_lm
+`))
+	assert.Equal(t, HostCPU{Vendor: "GenuineIntel", ModelName: "Intel(R) Xeon(R) Platinum 8375C CPU @ 2.90GHz", Count: 2, Microcode: "0xd0003a5"}, cpu)
+	assert.True(t, hypervisor)
+}
+
+func TestDetectVirtualization(t *testing.T) {
+	assert.Equal(t, "kvm", detectVirtualization(map[string]string{"sys_vendor": "QEMU", "product_name": "KVM Virtual Machine"}, "", true))
+	assert.Equal(t, "amazon", detectVirtualization(map[string]string{"sys_vendor": "Amazon EC2", "product_name": "m6i.large"}, "", true))
+	assert.Equal(t, VirtualizationNone, detectVirtualization(map[string]string{"sys_vendor": "Amazon EC2", "product_name": "m6i.metal"}, "", false))
+	assert.Equal(t, "microsoft", detectVirtualization(map[string]string{"sys_vendor": "Microsoft Corporation", "product_name": "Virtual Machine"}, "", true))
+	assert.Equal(t, "xen", detectVirtualization(map[string]string{}, "xen", true))
+	assert.Equal(t, VirtualizationOtherVM, detectVirtualization(map[string]string{"sys_vendor": "Acme"}, "", true))
+	assert.Equal(t, VirtualizationNone, detectVirtualization(map[string]string{"sys_vendor": "Dell Inc."}, "", false))
+}
+
+func TestSenseHostInfo(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	writeFSFile(t, hfs, "/proc/sys/kernel/ostype", []byte("Linux\n"))
+	writeFSFile(t, hfs, kernelReleasePath, []byte("6.1.0-18-cloud-amd64\n"))
+	writeFSFile(t, hfs, "/proc/sys/kernel/domainname", []byte("(none)\n"))
+	writeFSFile(t, hfs, "/etc/machine-id", []byte("3f9a1c0e5b7d4e2a9c8b6d5e4f3a2b1c\n"))
+	writeFSFile(t, hfs, "/sys/class/dmi/id/sys_vendor", []byte("Google\n"))
+	writeFSFile(t, hfs, "/sys/class/dmi/id/product_name", []byte("Google Compute Engine\n"))
+	writeFSFile(t, hfs, "/sys/class/dmi/id/bios_version", []byte("Google\n"))
+	writeFSFile(t, hfs, "/sys/firmware/efi/fw_platform_size", []byte("64\n"))
+
+	info, err := SenseHostInfo(ctx)
+	if err == errNotSupported {
+		t.Skip("uname is only supported on linux")
+	}
+	assert.NoError(t, err)
+	assert.Equal(t, "Linux", info.Uname.SysName)
+	assert.Equal(t, "6.1.0-18-cloud-amd64", info.Uname.Release)
+	assert.Empty(t, info.Uname.DomainName)
+	assert.NotEmpty(t, info.Uname.Machine)
+	assert.Equal(t, info.Uname.Machine, info.CPU.Architecture)
+	assert.Equal(t, "3f9a1c0e5b7d4e2a9c8b6d5e4f3a2b1c", info.MachineID)
+	assert.Equal(t, "google", info.Virtualization)
+	assert.Equal(t, HostHardware{Vendor: "Google", ProductName: "Google Compute Engine"}, info.Hardware)
+	assert.Equal(t, HostFirmware{Version: "Google", UEFI: true}, info.Firmware)
+}
@@ -0,0 +1,200 @@
+package sensor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"go.uber.org/zap"
+)
+
+const (
+	// The mounts of the host mount namespace
+	hostMountInfoPath = "/proc/1/mountinfo"
+
+	dockerRuntimeName      = "docker"
+	dockerDaemonConfigPath = "/etc/docker/daemon.json"
+	crioStorageConfigPath  = "/etc/containers/storage.conf"
+)
+
+var (
+	// The image stores of the container runtimes, with the config of their root and their directories which hold
+	// the images and the container file systems
+	imageStores = []struct {
+		runtime     string
+		defaultRoot string
+		configPath  string
+		parseRoot   func(content []byte) (string, error)
+		rootArg     string
+		process     string
+		dirs        []string
+	}{
+		{
+			containerdContainerRuntimeName, "/var/lib/containerd", "/etc/containerd/config.toml", parseContainerdRoot, "--root", "/containerd",
+			[]string{
+				"io.containerd.content.v1.content",
+				"io.containerd.snapshotter.v1.overlayfs",
+				"io.containerd.snapshotter.v1.overlayfs/snapshots",
+				"io.containerd.snapshotter.v1.native",
+				"io.containerd.snapshotter.v1.native/snapshots",
+			},
+		},
+		{
+			dockerRuntimeName, "/var/lib/docker", dockerDaemonConfigPath, parseDockerDataRoot, "--data-root", "/dockerd",
+			[]string{"image", "overlay2", "containers", "volumes"},
+		},
+		{
+			crioContainerRuntimeName, "/var/lib/containers/storage", crioStorageConfigPath, parseCrioGraphRoot, "--root", "/crio",
+			[]string{"overlay", "overlay-images", "overlay-containers", "volumes"},
+		},
+	}
+)
+
+// ImageStore holds the permissions and the mount of the image store of a container runtime
+type ImageStore struct {
+	// One of "containerd", "docker" or "crio"
+	Runtime string `json:"runtime"`
+
+	Root *FileInfo `json:"root"`
+
+	// The directories of the images and the container file systems, which exist
+	Directories []*FileInfo `json:"directories"`
+
+	// The mount holding the root, nil if not found
+	Mount *MountInfo `json:"mount,omitempty"`
+}
+
+// MountInfo is a mount of the host
+type MountInfo struct {
+	MountPoint string `json:"mountPoint"`
+	FSType     string `json:"fsType"`
+	Source     string `json:"source"`
+
+	// The mount options and the super block options
+	Options []string `json:"options"`
+}
+
+// SenseImageStores returns the image stores of containerd, docker and CRI-O which exist on the node, with the
+// ownership and permissions of their directories, and the mount options of their file systems
+func SenseImageStores(ctx context.Context) ([]ImageStore, error) {
+	mounts, err := hostPlatform.Mounts(ctx)
+	if err != nil {
+		logger(ctx).Debug("failed to read the host mounts", zap.Error(err))
+	}
+
+	ret := []ImageStore{}
+	for _, store := range imageStores {
+		root := getImageStoreRoot(ctx, store.defaultRoot, store.configPath, store.parseRoot, store.process, store.rootArg)
+		rootInfo, err := makeHostFileInfo(ctx, root, false)
+		if err != nil {
+			logger(ctx).Debug("failed to stat image store", zap.String("path", root), zap.Error(err))
+			recordCollectionError(ctx, "stat", root, err)
+			continue
+		}
+
+		imageStore := ImageStore{Runtime: store.runtime, Root: rootInfo, Directories: []*FileInfo{}, Mount: findMount(mounts, root)}
+		for _, dir := range store.dirs {
+			if dirInfo, err := makeHostFileInfo(ctx, path.Join(root, dir), false); err == nil {
+				imageStore.Directories = append(imageStore.Directories, dirInfo)
+			}
+		}
+		ret = append(ret, imageStore)
+	}
+	return ret, nil
+}
+
+// getImageStoreRoot returns the root of an image store from the runtime process args, or its config, or the default
+func getImageStoreRoot(ctx context.Context, defaultRoot, configPath string, parseRoot func(content []byte) (string, error), process, rootArg string) string {
+	if proc, err := LocateProcessByExecSuffix(ctx, process); err == nil {
+		if root, ok := proc.GetArg(rootArg); ok && root != "" {
+			return root
+		}
+	}
+	content, err := ReadFileOnHostFileSystem(ctx, configPath)
+	if err != nil {
+		return defaultRoot
+	}
+	root, err := parseRoot(content)
+	if err != nil {
+		logger(ctx).Debug("failed to parse container runtime config", zap.String("path", configPath), zap.Error(err))
+	}
+	if root == "" {
+		return defaultRoot
+	}
+	return root
+}
+
+func parseContainerdRoot(content []byte) (string, error) {
+	config := struct {
+		Root string `toml:"root"`
+	}{}
+	_, err := toml.Decode(string(content), &config)
+	return config.Root, err
+}
+
+func parseDockerDataRoot(content []byte) (string, error) {
+	config := struct {
+		DataRoot string `json:"data-root"`
+		// deprecated
+		Graph string `json:"graph"`
+	}{}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return "", err
+	}
+	if config.DataRoot == "" {
+		return config.Graph, nil
+	}
+	return config.DataRoot, nil
+}
+
+func parseCrioGraphRoot(content []byte) (string, error) {
+	config := struct {
+		Storage struct {
+			GraphRoot string `toml:"graphroot"`
+		} `toml:"storage"`
+	}{}
+	_, err := toml.Decode(string(content), &config)
+	return config.Storage.GraphRoot, err
+}
+
+// parseMountInfo parses the mounts of a mountinfo file, e.g.
+// "36 35 98:0 / /var/lib/containerd rw,noatime master:1 - ext4 /dev/sda1 rw,errors=continue"
+func parseMountInfo(content []byte) ([]MountInfo, error) {
+	ret := []MountInfo{}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line == "" {
+			continue
+		}
+		fields, super, ok := strings.Cut(line, " - ")
+		before, after := strings.Fields(fields), strings.Fields(super)
+		if !ok || len(before) < 6 || len(after) < 3 {
+			return nil, fmt.Errorf("invalid mountinfo line: %q", line)
+		}
+		mount := MountInfo{MountPoint: strings.ReplaceAll(before[4], `\040`, " "), FSType: after[0], Source: after[1], Options: []string{}}
+		for _, option := range append(strings.Split(before[5], ","), strings.Split(after[2], ",")...) {
+			if !containsString(mount.Options, option) {
+				mount.Options = append(mount.Options, option)
+			}
+		}
+		ret = append(ret, mount)
+	}
+	return ret, nil
+}
+
+// findMount returns the mount holding a path, the last mount of the longest mount point wins
+func findMount(mounts []MountInfo, p string) *MountInfo {
+	var ret *MountInfo
+	for i := range mounts {
+		mountPoint := mounts[i].MountPoint
+		if mountPoint != "/" && p != mountPoint && !strings.HasPrefix(p, mountPoint+"/") {
+			continue
+		}
+		if ret == nil || len(mountPoint) >= len(ret.MountPoint) {
+			ret = &mounts[i]
+		}
+	}
+	return ret
+}
@@ -0,0 +1,46 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenseImageStores(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	writeFSFile(t, hfs, "/etc/docker/daemon.json", []byte(`{"data-root": "/data/docker"}`))
+	require.NoError(t, os.MkdirAll(hfs.Path("/data/docker/overlay2"), 0o755))
+	require.NoError(t, os.Chmod(hfs.Path("/data/docker"), 0o710))
+	require.NoError(t, os.MkdirAll(hfs.Path("/var/lib/containerd/io.containerd.snapshotter.v1.overlayfs/snapshots"), 0o700))
+	writeFSFile(t, hfs, hostMountInfoPath, []byte(`22 1 8:1 / / rw,relatime shared:1 - ext4 /dev/sda1 rw
+40 22 8:16 / /data rw,nosuid,nodev,noatime shared:20 - xfs /dev/sdb rw,attr2
+`))
+
+	stores, err := SenseImageStores(ctx)
+	require.NoError(t, err)
+	require.Len(t, stores, 2)
+
+	assert.Equal(t, containerdContainerRuntimeName, stores[0].Runtime)
+	assert.Equal(t, "/var/lib/containerd", stores[0].Root.Path)
+	require.Len(t, stores[0].Directories, 2)
+	assert.Equal(t, "/var/lib/containerd/io.containerd.snapshotter.v1.overlayfs/snapshots", stores[0].Directories[1].Path)
+	assert.Equal(t, 0o700, stores[0].Directories[1].Permissions)
+	assert.Equal(t, "/", stores[0].Mount.MountPoint)
+
+	assert.Equal(t, dockerRuntimeName, stores[1].Runtime)
+	assert.Equal(t, "/data/docker", stores[1].Root.Path)
+	assert.Equal(t, 0o710, stores[1].Root.Permissions)
+	require.Len(t, stores[1].Directories, 1)
+	assert.Equal(t, "/data/docker/overlay2", stores[1].Directories[0].Path)
+	assert.Equal(t, &MountInfo{
+		MountPoint: "/data",
+		FSType:     "xfs",
+		Source:     "/dev/sdb",
+		Options:    []string{"rw", "nosuid", "nodev", "noatime", "attr2"},
+	}, stores[1].Mount)
+}
@@ -0,0 +1,220 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// The iptables binary is either the legacy one, which programs the x_tables of the kernel, or the nft one, which
+// programs nftables. The rules of one backend are invisible to the other, so kube-proxy, the CNI and the node must
+// use the same backend, otherwise their rules conflict (e.g. a legacy REJECT rule which the nft rules can't see).
+
+// iptables backends
+const (
+	IPTablesBackendLegacy = "legacy"
+	IPTablesBackendNFT    = "nft"
+)
+
+// iptables backend compatibility verdicts
+const (
+	// The node, kube-proxy and the CNI use the same backend, and only its rules are loaded
+	IPTablesVerdictCompatible = "compatible"
+
+	// Both the legacy and the nft backends have rules loaded
+	IPTablesVerdictMixedRules = "mixedRules"
+
+	// kube-proxy or the CNI use another backend than the node
+	IPTablesVerdictMismatch = "mismatch"
+
+	// The backends couldn't be determined
+	IPTablesVerdictUnknown = "unknown"
+)
+
+const (
+	// The legacy tables of the host network namespace
+	legacyIPTablesNamesPath  = "/proc/1/net/ip_tables_names"
+	legacyIP6TablesNamesPath = "/proc/1/net/ip6_tables_names"
+
+	kubeProxyModeNFTables = "nftables"
+)
+
+var (
+	// The iptables binaries, by order of precedence
+	iptablesBinaries = []string{"/usr/sbin/iptables", "/sbin/iptables", "/usr/bin/iptables", "/bin/iptables"}
+
+	// The modules which nftables loads for the chains and rules of iptables-nft and kube-proxy
+	nftablesRuleModules = []string{"nft_compat", "nft_chain_nat"}
+)
+
+// IPTablesBackendInfo holds the iptables backends of the node, kube-proxy and the CNI agents
+type IPTablesBackendInfo struct {
+	// The backend of the node iptables binary, empty if unknown
+	Node string `json:"node"`
+
+	// The node iptables binary, resolved through its symlinks (e.g. the alternatives)
+	NodeBinary string `json:"nodeBinary,omitempty"`
+
+	// The legacy tables loaded in the host network namespace
+	LegacyTables []string `json:"legacyTables"`
+
+	// Whether nftables has chains or rules loaded, inferred from the modules they use
+	NFTablesRules bool `json:"nftablesRules"`
+
+	// The backend of kube-proxy, nil if it doesn't run on the node
+	KubeProxy *IPTablesUser `json:"kubeProxy,omitempty"`
+
+	// The backends of the CNI agents running on the node
+	CNI []IPTablesUser `json:"cni"`
+
+	// One of IPTablesVerdict*
+	Verdict string `json:"verdict"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// IPTablesUser is a process programming iptables rules, and its backend
+type IPTablesUser struct {
+	Process string `json:"process"`
+
+	// The backend, empty if unknown
+	Backend string `json:"backend"`
+
+	// The iptables binary of the process container, resolved through its symlinks
+	Binary string `json:"binary,omitempty"`
+}
+
+// iptablesBinaryBackend returns the backend of a resolved iptables binary, empty if unknown (e.g. a wrapper which
+// selects the backend on its first run)
+func iptablesBinaryBackend(binary string) string {
+	name := path.Base(binary)
+	switch {
+	case strings.Contains(name, "nft"):
+		return IPTablesBackendNFT
+	case strings.Contains(name, "legacy"), name == "xtables-multi", name == "iptables-multi":
+		// iptables before 1.8 only has the legacy backend
+		return IPTablesBackendLegacy
+	}
+	return ""
+}
+
+// resolveIPTablesBinary returns the iptables binary of a root directory, resolved through its symlinks, and its
+// backend. It returns an empty binary if there is no iptables binary.
+func resolveIPTablesBinary(rootDir string) (string, string) {
+	for _, binary := range iptablesBinaries {
+		resolved, err := resolveRootPath(rootDir, binary)
+		if err != nil {
+			continue
+		}
+		if _, err := os.Stat(resolved); err != nil {
+			continue
+		}
+		resolved = "/" + strings.TrimPrefix(strings.TrimPrefix(resolved, path.Clean(rootDir)), "/")
+		return resolved, iptablesBinaryBackend(resolved)
+	}
+	return "", ""
+}
+
+// parseLegacyTableNames parses an ip_tables_names file, a table name per line
+func parseLegacyTableNames(content []byte) []string {
+	ret := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		if name := strings.TrimSpace(line); name != "" {
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
+// parseModuleRefCounts parses the use counts of the loaded modules of /proc/modules
+func parseModuleRefCounts(content []byte) map[string]int {
+	ret := map[string]int{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 {
+			continue
+		}
+		count, _ := strconv.Atoi(fields[2])
+		ret[fields[0]] = count
+	}
+	return ret
+}
+
+// iptablesVerdict returns the compatibility verdict of the backends, and its reason
+func iptablesVerdict(info *IPTablesBackendInfo) (string, string) {
+	if len(info.LegacyTables) > 0 && info.NFTablesRules {
+		return IPTablesVerdictMixedRules, "both the legacy and the nft backends have rules loaded"
+	}
+	users := info.CNI
+	if info.KubeProxy != nil {
+		users = append([]IPTablesUser{*info.KubeProxy}, users...)
+	}
+	expected, expectedBy := info.Node, "the node"
+	for _, user := range users {
+		if user.Backend == "" {
+			continue
+		}
+		if expected == "" {
+			expected, expectedBy = user.Backend, user.Process
+			continue
+		}
+		if user.Backend != expected {
+			return IPTablesVerdictMismatch, fmt.Sprintf("%s uses the %s backend while %s uses %s", user.Process, user.Backend, expectedBy, expected)
+		}
+	}
+	if expected == "" {
+		return IPTablesVerdictUnknown, ""
+	}
+	return IPTablesVerdictCompatible, ""
+}
+
+// senseIPTablesUser returns the backend of a process programming iptables rules
+func senseIPTablesUser(proc *ProcessDetails, name string) IPTablesUser {
+	user := IPTablesUser{Process: name}
+	user.Binary, user.Backend = resolveIPTablesBinary(proc.RootDir())
+	return user
+}
+
+// SenseIPTablesBackend returns the iptables backends of the node, kube-proxy and the CNI agents, and whether they're
+// compatible
+func SenseIPTablesBackend(ctx context.Context) *IPTablesBackendInfo {
+	ret := &IPTablesBackendInfo{LegacyTables: []string{}, CNI: []IPTablesUser{}}
+	ret.NodeBinary, ret.Node = resolveIPTablesBinary(hostFS(ctx).Root())
+
+	for _, namesPath := range []string{legacyIPTablesNamesPath, legacyIP6TablesNamesPath} {
+		if content, err := ReadFileOnHostFileSystem(ctx, namesPath); err == nil {
+			ret.LegacyTables = append(ret.LegacyTables, parseLegacyTableNames(content)...)
+		}
+	}
+	if content, err := ReadFileOnHostFileSystem(ctx, procModulesPath); err != nil {
+		logger(ctx).Debug("SenseIPTablesBackend failed to read the kernel modules", zap.Error(err))
+	} else {
+		refCounts := parseModuleRefCounts(content)
+		for _, module := range nftablesRuleModules {
+			ret.NFTablesRules = ret.NFTablesRules || refCounts[module] > 0
+		}
+	}
+
+	if proc, err := LocateProcessByExecSuffix(ctx, kubeProxyExe); err == nil {
+		user := senseIPTablesUser(proc, kubeProxyExe)
+		if readKubeProxyMode(ctx, proc) == kubeProxyModeNFTables {
+			user.Backend = IPTablesBackendNFT
+		}
+		ret.KubeProxy = &user
+	}
+	for _, agent := range cniAgents {
+		if proc, err := LocateProcessByExecSuffix(ctx, agent.exe); err == nil {
+			ret.CNI = append(ret.CNI, senseIPTablesUser(proc, strings.TrimPrefix(agent.exe, "/")))
+		}
+	}
+
+	ret.Verdict, ret.Reason = iptablesVerdict(ret)
+	return ret
+}
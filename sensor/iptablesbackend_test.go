@@ -0,0 +1,64 @@
+package sensor
+
+import (
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveIPTablesBinary(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(root, "/usr/sbin"), 0o755))
+	require.NoError(t, os.MkdirAll(path.Join(root, "/etc/alternatives"), 0o755))
+	require.NoError(t, os.WriteFile(path.Join(root, "/usr/sbin/xtables-nft-multi"), nil, 0o755))
+	require.NoError(t, os.Symlink("/usr/sbin/xtables-nft-multi", path.Join(root, "/usr/sbin/iptables-nft")))
+	require.NoError(t, os.Symlink("/usr/sbin/iptables-nft", path.Join(root, "/etc/alternatives/iptables")))
+	require.NoError(t, os.Symlink("/etc/alternatives/iptables", path.Join(root, "/usr/sbin/iptables")))
+
+	binary, backend := resolveIPTablesBinary(root)
+	assert.Equal(t, "/usr/sbin/xtables-nft-multi", binary)
+	assert.Equal(t, IPTablesBackendNFT, backend)
+
+	binary, backend = resolveIPTablesBinary(t.TempDir())
+	assert.Empty(t, binary)
+	assert.Empty(t, backend)
+
+	assert.Equal(t, IPTablesBackendLegacy, iptablesBinaryBackend("/usr/sbin/xtables-legacy-multi"))
+	assert.Equal(t, IPTablesBackendLegacy, iptablesBinaryBackend("/sbin/xtables-multi"))
+	assert.Empty(t, iptablesBinaryBackend("/usr/sbin/iptables-wrapper"))
+}
+
+func TestParseModuleRefCounts(t *testing.T) {
+	assert.Equal(t, map[string]int{"nft_chain_nat": 7, "nf_tables": 216}, parseModuleRefCounts([]byte(
+		"nft_chain_nat 16384 7 - Live 0x0000000000000000\nnf_tables 249856 216 nft_chain_nat,nft_compat, Live 0x0000000000000000\n")))
+	assert.Equal(t, []string{"filter", "nat"}, parseLegacyTableNames([]byte("filter\nnat\n")))
+}
+
+func TestIPTablesVerdict(t *testing.T) {
+	verdict, _ := iptablesVerdict(&IPTablesBackendInfo{Node: IPTablesBackendNFT, LegacyTables: []string{"nat"}, NFTablesRules: true})
+	assert.Equal(t, IPTablesVerdictMixedRules, verdict)
+
+	verdict, reason := iptablesVerdict(&IPTablesBackendInfo{
+		Node:      IPTablesBackendNFT,
+		KubeProxy: &IPTablesUser{Process: "kube-proxy", Backend: IPTablesBackendNFT},
+		CNI:       []IPTablesUser{{Process: "calico-node", Backend: IPTablesBackendLegacy}},
+	})
+	assert.Equal(t, IPTablesVerdictMismatch, verdict)
+	assert.Equal(t, "calico-node uses the legacy backend while the node uses nft", reason)
+
+	verdict, reason = iptablesVerdict(&IPTablesBackendInfo{
+		KubeProxy: &IPTablesUser{Process: "kube-proxy", Backend: IPTablesBackendLegacy},
+		CNI:       []IPTablesUser{{Process: "flanneld", Backend: IPTablesBackendNFT}},
+	})
+	assert.Equal(t, IPTablesVerdictMismatch, verdict)
+	assert.Equal(t, "flanneld uses the nft backend while kube-proxy uses legacy", reason)
+
+	verdict, _ = iptablesVerdict(&IPTablesBackendInfo{Node: IPTablesBackendLegacy, KubeProxy: &IPTablesUser{Process: "kube-proxy"}})
+	assert.Equal(t, IPTablesVerdictCompatible, verdict)
+
+	verdict, _ = iptablesVerdict(&IPTablesBackendInfo{})
+	assert.Equal(t, IPTablesVerdictUnknown, verdict)
+}
@@ -0,0 +1,149 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+const (
+	kernelReleasePath = "/proc/sys/kernel/osrelease"
+	procConfigGzPath  = "/proc/config.gz"
+
+	// Max size of the uncompressed kernel config
+	kernelConfigMaxSize = 4 * 1024 * 1024
+)
+
+var (
+	// The security-relevant kernel build options
+	kernelSecurityOptions = []string{
+		"CONFIG_SECCOMP",
+		"CONFIG_SECCOMP_FILTER",
+		"CONFIG_SECURITY",
+		"CONFIG_LSM",
+		"CONFIG_BPF_LSM",
+		"CONFIG_SECURITY_APPARMOR",
+		"CONFIG_SECURITY_SELINUX",
+		"CONFIG_SECURITY_YAMA",
+		"CONFIG_SECURITY_LOCKDOWN_LSM",
+		"CONFIG_STRICT_KERNEL_RWX",
+		"CONFIG_STRICT_MODULE_RWX",
+		"CONFIG_STACKPROTECTOR_STRONG",
+		"CONFIG_RANDOMIZE_BASE",
+		"CONFIG_HARDENED_USERCOPY",
+		"CONFIG_FORTIFY_SOURCE",
+		"CONFIG_INIT_ON_ALLOC_DEFAULT_ON",
+		"CONFIG_PAGE_TABLE_ISOLATION",
+		"CONFIG_RETPOLINE",
+		"CONFIG_MODULE_SIG",
+		"CONFIG_MODULE_SIG_FORCE",
+		"CONFIG_STRICT_DEVMEM",
+		"CONFIG_IO_STRICT_DEVMEM",
+		"CONFIG_BPF_UNPRIV_DEFAULT_OFF",
+		"CONFIG_USER_NS",
+	}
+)
+
+// KernelConfig holds the security-relevant build options of the running kernel
+type KernelConfig struct {
+	// The kernel release, e.g. "5.15.0-1019-aws"
+	Release string `json:"release"`
+
+	// The path of the kernel config
+	ConfigFile string `json:"configFile"`
+
+	Options []KernelConfigOption `json:"options"`
+}
+
+// KernelConfigOption is a kernel build option
+type KernelConfigOption struct {
+	Name string `json:"name"`
+
+	// "y", "m", a string or numeric value, "n" if not set, or empty if the option doesn't exist in the kernel
+	Value string `json:"value"`
+
+	// True if the option is set, e.g. built in or a module
+	Enabled bool `json:"enabled"`
+}
+
+// SenseKernelConfig returns the security-relevant build options of the running kernel, from its config in /boot,
+// /lib/modules or /proc/config.gz
+func SenseKernelConfig(ctx context.Context) (*KernelConfig, error) {
+	release, err := ReadFileOnHostFileSystem(ctx, kernelReleasePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kernel release: %w", err)
+	}
+	ret := &KernelConfig{Release: strings.TrimSpace(string(release)), Options: []KernelConfigOption{}}
+
+	content, configFile, err := readKernelConfig(ctx, ret.Release)
+	if err != nil {
+		return nil, err
+	}
+	ret.ConfigFile = configFile
+
+	values := parseKernelConfig(content)
+	for _, name := range kernelSecurityOptions {
+		value := values[name]
+		ret.Options = append(ret.Options, KernelConfigOption{Name: name, Value: value, Enabled: value != "" && value != "n"})
+	}
+	return ret, nil
+}
+
+// readKernelConfig returns the config of the kernel release, and its path
+func readKernelConfig(ctx context.Context, release string) ([]byte, string, error) {
+	for _, configFile := range []string{
+		path.Join("/boot", "config-"+release),
+		path.Join("/lib/modules", release, "config"),
+		path.Join("/usr/lib/modules", release, "config"),
+	} {
+		content, err := ReadFileOnHostFileSystem(ctx, configFile)
+		if err == nil {
+			return content, configFile, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, "", err
+		}
+	}
+
+	compressed, err := ReadFileOnHostFileSystem(ctx, procConfigGzPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, "", fmt.Errorf("kernel config of %s not found", release)
+		}
+		return nil, "", err
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decompress %s: %w", procConfigGzPath, err)
+	}
+	content, err := io.ReadAll(io.LimitReader(reader, kernelConfigMaxSize))
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decompress %s: %w", procConfigGzPath, err)
+	}
+	return content, procConfigGzPath, nil
+}
+
+// parseKernelConfig returns the values of the options of a kernel config.
+// Options which aren't set ("# CONFIG_X is not set") are "n".
+func parseKernelConfig(content []byte) map[string]string {
+	ret := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "# CONFIG_") && strings.HasSuffix(line, " is not set") {
+			ret[strings.TrimSuffix(strings.TrimPrefix(line, "# "), " is not set")] = "n"
+			continue
+		}
+		if name, value, ok := strings.Cut(line, "="); ok && strings.HasPrefix(name, "CONFIG_") {
+			ret[name] = strings.Trim(value, `"`)
+		}
+	}
+	return ret
+}
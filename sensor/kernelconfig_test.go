@@ -0,0 +1,71 @@
+package sensor
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testKernelConfig = `#
+# Automatically generated file; DO NOT EDIT.
+#
+CONFIG_SECCOMP=y
+CONFIG_SECCOMP_FILTER=y
+CONFIG_LSM="landlock,lockdown,yama,integrity,apparmor"
+# CONFIG_BPF_LSM is not set
+CONFIG_SECURITY_APPARMOR=y
+CONFIG_STRICT_KERNEL_RWX=y
+CONFIG_MODULE_SIG=y
+`
+
+func kernelConfigOptions(config *KernelConfig) map[string]KernelConfigOption {
+	ret := map[string]KernelConfigOption{}
+	for _, option := range config.Options {
+		ret[option.Name] = option
+	}
+	return ret
+}
+
+func TestSenseKernelConfig(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	writeFSFile(t, hfs, kernelReleasePath, []byte("5.15.0-1019-aws\n"))
+	_, err := SenseKernelConfig(ctx)
+	assert.ErrorContains(t, err, "kernel config of 5.15.0-1019-aws not found")
+
+	writeFSFile(t, hfs, "/boot/config-5.15.0-1019-aws", []byte(testKernelConfig))
+	config, err := SenseKernelConfig(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "5.15.0-1019-aws", config.Release)
+	assert.Equal(t, "/boot/config-5.15.0-1019-aws", config.ConfigFile)
+	assert.Len(t, config.Options, len(kernelSecurityOptions))
+
+	options := kernelConfigOptions(config)
+	assert.Equal(t, KernelConfigOption{Name: "CONFIG_SECCOMP", Value: "y", Enabled: true}, options["CONFIG_SECCOMP"])
+	assert.Equal(t, KernelConfigOption{Name: "CONFIG_LSM", Value: "landlock,lockdown,yama,integrity,apparmor", Enabled: true}, options["CONFIG_LSM"])
+	assert.Equal(t, KernelConfigOption{Name: "CONFIG_BPF_LSM", Value: "n"}, options["CONFIG_BPF_LSM"])
+	assert.Equal(t, KernelConfigOption{Name: "CONFIG_USER_NS"}, options["CONFIG_USER_NS"])
+}
+
+func TestSenseKernelConfigProc(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	compressed := &bytes.Buffer{}
+	writer := gzip.NewWriter(compressed)
+	_, err := writer.Write([]byte(testKernelConfig))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	writeFSFile(t, hfs, kernelReleasePath, []byte("5.10.133+\n"))
+	writeFSFile(t, hfs, procConfigGzPath, compressed.Bytes())
+
+	config, err := SenseKernelConfig(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, procConfigGzPath, config.ConfigFile)
+	assert.True(t, kernelConfigOptions(config)["CONFIG_STRICT_KERNEL_RWX"].Enabled)
+}
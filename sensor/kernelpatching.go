@@ -0,0 +1,212 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Reasons of a pending reboot
+const (
+	// The package manager flagged the updates which need a reboot, e.g. /run/reboot-required
+	RebootReasonPendingUpdates = "pendingUpdates"
+
+	// A newer kernel than the running one is installed
+	RebootReasonNewerKernel = "newerKernel"
+)
+
+const (
+	livePatchDir = "/sys/kernel/livepatch"
+
+	// The reboot flag files of Debian and Ubuntu, and of SUSE
+	rebootRequiredPath     = "/run/reboot-required"
+	rebootRequiredPkgsPath = "/run/reboot-required.pkgs"
+	rebootNeededPath       = "/run/reboot-needed"
+)
+
+// KernelPatchingInfo holds the patch state of the running kernel: its live patches, and whether the node needs a
+// reboot to run its installed updates
+type KernelPatchingInfo struct {
+	// The running kernel release, e.g. "5.15.0-91-generic"
+	RunningRelease string `json:"runningRelease"`
+
+	// The installed kernel releases, from the oldest to the newest
+	InstalledReleases []string `json:"installedReleases"`
+
+	// Whether the running kernel is the newest installed one, true if no installed kernel was found
+	RunningLatest bool `json:"runningLatest"`
+
+	// The live patches of the running kernel (kpatch, Canonical Livepatch, kGraft, etc.)
+	LivePatches []LivePatch `json:"livePatches"`
+
+	// Whether any live patch is enabled
+	LivePatchActive bool `json:"livePatchActive"`
+
+	RebootRequired bool `json:"rebootRequired"`
+
+	// The reasons of the pending reboot, RebootReason*
+	RebootReasons []string `json:"rebootReasons"`
+
+	// The packages whose updates need a reboot, as listed by the package manager
+	RebootPackages []string `json:"rebootPackages,omitempty"`
+}
+
+// LivePatch is a live patch of the running kernel
+type LivePatch struct {
+	// The name of the patch module, e.g. "livepatch_CVE_2023_1234" or "kpatch_5_15_0_91_1_1"
+	Name string `json:"name"`
+
+	Enabled bool `json:"enabled"`
+
+	// Whether the patch is still being applied to (or removed from) the running tasks
+	Transition bool `json:"transition"`
+}
+
+// compareKernelReleases compares two kernel releases by their numeric and text parts, e.g. 5.15.0-101 is newer than
+// 5.15.0-91
+func compareKernelReleases(a, b string) int {
+	partsA, partsB := splitKernelRelease(a), splitKernelRelease(b)
+	for i := 0; i < len(partsA) && i < len(partsB); i++ {
+		numA, errA := strconv.Atoi(partsA[i])
+		numB, errB := strconv.Atoi(partsB[i])
+		switch {
+		case errA == nil && errB == nil:
+			if numA != numB {
+				if numA < numB {
+					return -1
+				}
+				return 1
+			}
+		case errA == nil:
+			// a number is newer than a suffix, e.g. 5.15.1 and 5.15-rc1
+			return 1
+		case errB == nil:
+			return -1
+		default:
+			if cmp := strings.Compare(partsA[i], partsB[i]); cmp != 0 {
+				return cmp
+			}
+		}
+	}
+	return len(partsA) - len(partsB)
+}
+
+// splitKernelRelease splits a kernel release into its numeric and text parts, without the separators
+func splitKernelRelease(release string) []string {
+	ret := []string{}
+	current := ""
+	for _, r := range release {
+		if !unicode.IsDigit(r) && !unicode.IsLetter(r) {
+			if current != "" {
+				ret = append(ret, current)
+			}
+			current = ""
+			continue
+		}
+		if current != "" && unicode.IsDigit(r) != unicode.IsDigit(rune(current[len(current)-1])) {
+			ret = append(ret, current)
+			current = ""
+		}
+		current += string(r)
+	}
+	if current != "" {
+		ret = append(ret, current)
+	}
+	return ret
+}
+
+// senseInstalledKernels returns the releases of the kernel images in /boot and in the modules directories
+func senseInstalledKernels(ctx context.Context) []string {
+	releases := map[string]bool{}
+	if entries, err := readHostDir(ctx, "/boot"); err == nil {
+		for _, entry := range entries {
+			if release := strings.TrimPrefix(entry.Name(), "vmlinuz-"); release != entry.Name() && release != "" {
+				releases[release] = true
+			}
+		}
+	}
+	for _, modulesDir := range []string{"/lib/modules", "/usr/lib/modules"} {
+		entries, err := readHostDir(ctx, modulesDir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			// the modules directories of removed kernels may be left over, only those with an image are installed
+			if _, err := statHostFile(ctx, path.Join(modulesDir, entry.Name(), "vmlinuz")); err == nil {
+				releases[entry.Name()] = true
+			}
+		}
+	}
+
+	ret := make([]string, 0, len(releases))
+	for release := range releases {
+		ret = append(ret, release)
+	}
+	sort.Slice(ret, func(i, j int) bool { return compareKernelReleases(ret[i], ret[j]) < 0 })
+	return ret
+}
+
+// senseLivePatches returns the live patches of the running kernel
+func senseLivePatches(ctx context.Context) []LivePatch {
+	ret := []LivePatch{}
+	entries, err := readHostDir(ctx, livePatchDir)
+	if err != nil {
+		return ret
+	}
+	for _, entry := range entries {
+		patchDir := path.Join(livePatchDir, entry.Name())
+		ret = append(ret, LivePatch{
+			Name:       entry.Name(),
+			Enabled:    readHostString(ctx, path.Join(patchDir, "enabled")) == "1",
+			Transition: readHostString(ctx, path.Join(patchDir, "transition")) == "1",
+		})
+	}
+	return ret
+}
+
+// SenseKernelPatching returns the live patches of the running kernel, the installed kernels, and whether the node
+// needs a reboot to run its installed updates
+func SenseKernelPatching(ctx context.Context) (*KernelPatchingInfo, error) {
+	release, err := ReadFileOnHostFileSystem(ctx, kernelReleasePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kernel release: %w", err)
+	}
+	ret := &KernelPatchingInfo{
+		RunningRelease:    strings.TrimSpace(string(release)),
+		InstalledReleases: senseInstalledKernels(ctx),
+		LivePatches:       senseLivePatches(ctx),
+		RebootReasons:     []string{},
+	}
+	for _, patch := range ret.LivePatches {
+		ret.LivePatchActive = ret.LivePatchActive || patch.Enabled
+	}
+
+	ret.RunningLatest = true
+	if len(ret.InstalledReleases) > 0 {
+		latest := ret.InstalledReleases[len(ret.InstalledReleases)-1]
+		ret.RunningLatest = compareKernelReleases(ret.RunningRelease, latest) >= 0
+	}
+	if !ret.RunningLatest {
+		ret.RebootReasons = append(ret.RebootReasons, RebootReasonNewerKernel)
+	}
+
+	for _, flagPath := range []string{rebootRequiredPath, rebootNeededPath} {
+		if _, err := statHostFile(ctx, flagPath); err == nil {
+			ret.RebootReasons = append(ret.RebootReasons, RebootReasonPendingUpdates)
+			break
+		}
+	}
+	if content, err := ReadFileOnHostFileSystem(ctx, rebootRequiredPkgsPath); err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			if pkg := strings.TrimSpace(line); pkg != "" && !containsString(ret.RebootPackages, pkg) {
+				ret.RebootPackages = append(ret.RebootPackages, pkg)
+			}
+		}
+	}
+	ret.RebootRequired = len(ret.RebootReasons) > 0
+	return ret, nil
+}
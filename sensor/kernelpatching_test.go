@@ -0,0 +1,54 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompareKernelReleases(t *testing.T) {
+	assert.Equal(t, -1, compareKernelReleases("5.15.0-91-generic", "5.15.0-101-generic"))
+	assert.Equal(t, 1, compareKernelReleases("6.1.0", "5.15.0-101-generic"))
+	assert.Equal(t, 0, compareKernelReleases("5.14.0-362.8.1.el9_3.x86_64", "5.14.0-362.8.1.el9_3.x86_64"))
+	assert.Equal(t, -1, compareKernelReleases("5.14.0-362.8.1.el9_3.x86_64", "5.14.0-362.13.1.el9_3.x86_64"))
+	assert.Equal(t, 1, compareKernelReleases("6.2.1", "6.2-rc1"))
+}
+
+func TestSenseKernelPatching(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+	writeFSFile(t, hfs, kernelReleasePath, []byte("5.15.0-91-generic\n"))
+	writeFSFile(t, hfs, "/boot/vmlinuz-5.15.0-91-generic", []byte{})
+	writeFSFile(t, hfs, "/boot/vmlinuz-5.15.0-101-generic", []byte{})
+	writeFSFile(t, hfs, "/boot/config-5.15.0-101-generic", []byte{})
+	// a leftover of a removed kernel
+	writeFSFile(t, hfs, "/lib/modules/5.15.0-105-generic/modules.dep", []byte{})
+	writeFSFile(t, hfs, "/sys/kernel/livepatch/lkp_Ubuntu_5_15_0_91_generic_106/enabled", []byte("1\n"))
+	writeFSFile(t, hfs, "/sys/kernel/livepatch/lkp_Ubuntu_5_15_0_91_generic_106/transition", []byte("0\n"))
+	writeFSFile(t, hfs, rebootRequiredPath, []byte("*** System restart required ***\n"))
+	writeFSFile(t, hfs, rebootRequiredPkgsPath, []byte("linux-image-5.15.0-101-generic\nlinux-base\nlinux-base\n"))
+
+	info, err := SenseKernelPatching(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &KernelPatchingInfo{
+		RunningRelease:    "5.15.0-91-generic",
+		InstalledReleases: []string{"5.15.0-91-generic", "5.15.0-101-generic"},
+		LivePatches:       []LivePatch{{Name: "lkp_Ubuntu_5_15_0_91_generic_106", Enabled: true}},
+		LivePatchActive:   true,
+		RebootRequired:    true,
+		RebootReasons:     []string{RebootReasonNewerKernel, RebootReasonPendingUpdates},
+		RebootPackages:    []string{"linux-image-5.15.0-101-generic", "linux-base"},
+	}, info)
+
+	// a node without kernel images, e.g. a container optimized OS
+	hfs = NewHostFS(t.TempDir())
+	ctx = WithHostFS(context.Background(), hfs)
+	writeFSFile(t, hfs, kernelReleasePath, []byte("6.1.58+\n"))
+	info, err = SenseKernelPatching(ctx)
+	require.NoError(t, err)
+	assert.True(t, info.RunningLatest)
+	assert.False(t, info.RebootRequired)
+	assert.Empty(t, info.LivePatches)
+}
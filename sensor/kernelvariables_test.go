@@ -1,9 +1,10 @@
 package sensor
 
+import "context"
 import "testing"
 
 func TestSenseProcSysKernel(t *testing.T) {
-	_, err := SenseProcSysKernel()
+	_, err := SenseProcSysKernel(context.Background())
 	if err != nil {
 		t.Errorf("%v", err)
 	}
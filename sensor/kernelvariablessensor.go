@@ -1,10 +1,12 @@
 package sensor
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"path"
+	"sort"
 	"strings"
 
 	"go.uber.org/zap"
@@ -20,17 +22,17 @@ type KernelVariable struct {
 	Source string `json:"source"`
 }
 
-func SenseProcSysKernel() ([]KernelVariable, error) {
+func SenseProcSysKernel(ctx context.Context) ([]KernelVariable, error) {
 	procDir, err := os.Open(procSysKernelDir)
 	if err != nil {
-		return nil, fmt.Errorf("failed to procSysKernelDir dir(%s): %v", procSysKernelDir, err)
+		return nil, fmt.Errorf("failed to procSysKernelDir dir(%s): %w", procSysKernelDir, err)
 	}
 	defer procDir.Close()
 
-	return walkVarsDir(procSysKernelDir, procDir)
+	return walkVarsDir(ctx, procSysKernelDir, procDir)
 }
 
-func walkVarsDir(dirPath string, procDir *os.File) ([]KernelVariable, error) {
+func walkVarsDir(ctx context.Context, dirPath string, procDir *os.File) ([]KernelVariable, error) {
 	var varsNames []string
 	varsList := make([]KernelVariable, 0, 128)
 
@@ -41,11 +43,12 @@ func walkVarsDir(dirPath string, procDir *os.File) ([]KernelVariable, error) {
 			varFile, err := os.Open(varFileName)
 			if err != nil {
 				if strings.Contains(err.Error(), "permission denied") {
-					zap.L().Error("In walkVarsDir failed to open file", zap.String("varFileName", varFileName),
+					logger(ctx).Error("In walkVarsDir failed to open file", zap.String("varFileName", varFileName),
 						zap.Error(err))
+					recordCollectionError(ctx, "open", varFileName, err)
 					continue
 				}
-				return nil, fmt.Errorf("failed to open file (%s): %v", varFileName, err)
+				return nil, fmt.Errorf("failed to open file (%s): %w", varFileName, err)
 			}
 			defer varFile.Close()
 			fileInfo, err := varFile.Stat()
@@ -54,7 +57,7 @@ func walkVarsDir(dirPath string, procDir *os.File) ([]KernelVariable, error) {
 			}
 			if fileInfo.IsDir() {
 				// CAUTION: recursive call!!!
-				innerVars, err := walkVarsDir(varFileName, varFile)
+				innerVars, err := walkVarsDir(ctx, varFileName, varFile)
 				if err != nil {
 					return nil, fmt.Errorf("failed to walkVarsDir file (%s): %v", varFileName, err)
 				}
@@ -65,8 +68,9 @@ func walkVarsDir(dirPath string, procDir *os.File) ([]KernelVariable, error) {
 				strBld := strings.Builder{}
 				if _, err := io.Copy(&strBld, varFile); err != nil {
 					if strings.Contains(err.Error(), "operation not permitted") {
-						zap.L().Error("In walkVarsDir failed to Copy file", zap.String("varFileName", varFileName),
+						logger(ctx).Error("In walkVarsDir failed to Copy file", zap.String("varFileName", varFileName),
 							zap.Error(err))
+						recordCollectionError(ctx, "read", varFileName, err)
 						continue
 					}
 					return nil, fmt.Errorf("failed to copy file (%s): %v", varFileName, err)
@@ -91,12 +95,13 @@ func SenseKernelConfs() ([]KernelVariable, error) {
 	return varsList, nil
 }
 
-func SenseKernelVariables() ([]KernelVariable, error) {
-	vars, err := SenseProcSysKernel()
+func SenseKernelVariables(ctx context.Context) ([]KernelVariable, error) {
+	vars, err := SenseProcSysKernel(ctx)
 	if confVars, err := SenseKernelConfs(); err != nil {
-		zap.L().Error("In SenseKernelVariables failed to SenseKernelConfs", zap.Error(err))
+		logger(ctx).Error("In SenseKernelVariables failed to SenseKernelConfs", zap.Error(err))
 	} else {
 		vars = append(vars, confVars...)
 	}
+	sort.SliceStable(vars, func(i, j int) bool { return vars[i].Source < vars[j].Source })
 	return vars, err
 }
@@ -0,0 +1,220 @@
+package sensor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Kubeadm artifact kinds
+const (
+	// Backups of kubeadm upgrades, in /etc/kubernetes/tmp
+	KubeadmArtifactEtcdBackup      = "etcdBackup"
+	KubeadmArtifactManifestsBackup = "manifestsBackup"
+	// Other files left in /etc/kubernetes/tmp
+	KubeadmArtifactTmp = "tmp"
+	// Copies of the PKI directory or of PKI files
+	KubeadmArtifactPKIBackup = "pkiBackup"
+	// Copies of the kubeconfig files
+	KubeadmArtifactKubeconfigCopy = "kubeconfigCopy"
+)
+
+const (
+	kubeadmTmpDir                = "/etc/kubernetes/tmp"
+	kubeadmEtcdBackupPrefix      = "kubeadm-backup-etcd-"
+	kubeadmManifestsBackupPrefix = "kubeadm-backup-manifests-"
+)
+
+var (
+	// Copies of the PKI directory
+	pkiBackupDirPatterns = []string{
+		"/etc/kubernetes/pki.*",
+		"/etc/kubernetes/pki-*",
+		"/etc/kubernetes/pki_*",
+		"/etc/kubernetes/pki~",
+	}
+
+	// Copies of PKI files, e.g. ca.key.bak
+	pkiBackupFilePatterns = []string{
+		pkiDir + "/*.crt.*",
+		pkiDir + "/*.key.*",
+		pkiDir + "/*~",
+		etcdPKIDir + "/*.crt.*",
+		etcdPKIDir + "/*.key.*",
+		etcdPKIDir + "/*~",
+	}
+
+	// Copies of the kubeconfig files, e.g. admin.conf.bak. The admin kubeconfig is often copied around.
+	kubeconfigCopyPatterns = []string{
+		"/etc/kubernetes/*.conf.*",
+		"/etc/kubernetes/*.conf~",
+		"/root/admin.conf",
+		"/home/*/admin.conf",
+		"/tmp/admin.conf",
+	}
+
+	// Content markers of credentials
+	credentialMarkers = [][]byte{
+		[]byte("PRIVATE KEY-----"),
+		[]byte("client-key-data:"),
+		[]byte("token:"),
+	}
+)
+
+// KubeadmArtifact is a file left behind by kubeadm or by a manual backup
+type KubeadmArtifact struct {
+	// Information about the file (without its content)
+	File *FileInfo `json:"file"`
+
+	// One of KubeadmArtifact*
+	Kind string `json:"kind"`
+
+	// True if the file holds credentials (private keys, kubeconfig credentials, or etcd data)
+	Credentials bool `json:"credentials"`
+
+	// The live file the artifact is a copy of, if it exists
+	LiveFile *FileInfo `json:"liveFile,omitempty"`
+}
+
+// SenseKubeadmArtifacts returns the kubeadm upgrade backups, and the copies of the PKI and kubeconfig files,
+// sorted by path.
+func SenseKubeadmArtifacts(ctx context.Context) ([]KubeadmArtifact, error) {
+	// artifacts by path
+	artifacts := map[string]KubeadmArtifact{}
+	add := func(filePath, kind, livePath string, credentials bool) {
+		if _, ok := artifacts[filePath]; ok {
+			return
+		}
+		if info, err := statHostFile(ctx, filePath); err != nil || !info.Mode().IsRegular() {
+			return
+		}
+		artifact, err := makeKubeadmArtifact(ctx, filePath, kind, livePath, credentials)
+		if err != nil {
+			logger(ctx).Debug("SenseKubeadmArtifacts failed to MakeHostFileInfo", zap.String("path", filePath), zap.Error(err))
+			recordCollectionError(ctx, "stat", filePath, err)
+			return
+		}
+		artifacts[filePath] = *artifact
+	}
+
+	for _, filePath := range walkHostFiles(ctx, kubeadmTmpDir) {
+		backupDir := strings.SplitN(strings.TrimPrefix(filePath, kubeadmTmpDir+"/"), "/", 2)[0]
+		switch {
+		case strings.HasPrefix(backupDir, kubeadmEtcdBackupPrefix):
+			// the etcd data holds all the cluster secrets
+			add(filePath, KubeadmArtifactEtcdBackup, "", true)
+		case strings.HasPrefix(backupDir, kubeadmManifestsBackupPrefix):
+			add(filePath, KubeadmArtifactManifestsBackup, "", false)
+		default:
+			add(filePath, KubeadmArtifactTmp, "", false)
+		}
+	}
+
+	for _, pattern := range pkiBackupDirPatterns {
+		for _, dir := range globHostPaths(ctx, pattern) {
+			for _, filePath := range walkHostFiles(ctx, dir) {
+				livePath := ""
+				if relPath := strings.TrimPrefix(filePath, dir); relPath != "" {
+					livePath = path.Join(pkiDir, relPath)
+				}
+				add(filePath, KubeadmArtifactPKIBackup, livePath, false)
+			}
+		}
+	}
+	for _, pattern := range pkiBackupFilePatterns {
+		for _, filePath := range globHostPaths(ctx, pattern) {
+			add(filePath, KubeadmArtifactPKIBackup, trimBackupSuffix(filePath, ".crt", ".key"), false)
+		}
+	}
+
+	for _, pattern := range kubeconfigCopyPatterns {
+		for _, filePath := range globHostPaths(ctx, pattern) {
+			livePath := adminConfigPath
+			if strings.HasPrefix(filePath, "/etc/kubernetes/") {
+				livePath = trimBackupSuffix(filePath, ".conf")
+			}
+			add(filePath, KubeadmArtifactKubeconfigCopy, livePath, false)
+		}
+	}
+
+	ret := make([]KubeadmArtifact, 0, len(artifacts))
+	for _, artifact := range artifacts {
+		ret = append(ret, artifact)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].File.Path < ret[j].File.Path })
+	return ret, nil
+}
+
+func makeKubeadmArtifact(ctx context.Context, filePath, kind, livePath string, credentials bool) (*KubeadmArtifact, error) {
+	fileInfo, err := makeHostFileInfo(ctx, filePath, false)
+	if err != nil {
+		return nil, err
+	}
+	artifact := &KubeadmArtifact{
+		File:        fileInfo,
+		Kind:        kind,
+		Credentials: credentials || hasCredentials(ctx, filePath),
+	}
+	if livePath != "" && livePath != filePath {
+		if liveFile, err := makeHostFileInfo(ctx, livePath, false); err == nil {
+			artifact.LiveFile = liveFile
+		}
+	}
+	return artifact, nil
+}
+
+// walkHostFiles returns the host paths of the regular files under the host directory
+func walkHostFiles(ctx context.Context, dir string) []string {
+	ret := []string{}
+	fs.WalkDir(hostFS(ctx), dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			logger(ctx).Debug("failed to walk", zap.String("path", filePath), zap.Error(err))
+			recordCollectionError(ctx, "walk", filePath, err)
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		advanceProgress(ctx, filePath, 1, 0)
+		ret = append(ret, filePath)
+		return nil
+	})
+	return ret
+}
+
+// trimBackupSuffix returns the path without the suffix following the last of `extensions`,
+// e.g. /etc/kubernetes/admin.conf for /etc/kubernetes/admin.conf.bak
+func trimBackupSuffix(filePath string, extensions ...string) string {
+	for _, ext := range extensions {
+		if i := strings.LastIndex(filePath, ext); i >= 0 {
+			return filePath[:i+len(ext)]
+		}
+	}
+	return filePath
+}
+
+// hasCredentials returns true if the head of the file has a private key or kubeconfig credentials
+func hasCredentials(ctx context.Context, filePath string) bool {
+	f, err := hostFS(ctx).Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	head, err := io.ReadAll(io.LimitReader(f, privateKeyHeadSize))
+	if err != nil {
+		return false
+	}
+	for _, marker := range credentialMarkers {
+		if bytes.Contains(head, marker) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,235 @@
+package sensor
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+var (
+	// The groups which are bound to cluster-admin, kubeadm uses kubeadm:cluster-admins since 1.29
+	kubeconfigAdminGroups = []string{"system:masters", "kubeadm:cluster-admins"}
+)
+
+// KubeconfigInfo holds the security relevant settings of a kubeconfig file.
+// Credentials are never included, only their kind and the details of the client certificates.
+type KubeconfigInfo struct {
+	// The component using the kubeconfig, e.g. "kubelet"
+	Component string `json:"component"`
+
+	// Information about the kubeconfig file (without its content)
+	File *FileInfo `json:"file"`
+
+	// The local user whose home directory holds the kubeconfig, for the kubeconfigs of the users
+	Owner string `json:"owner,omitempty"`
+
+	Clusters []KubeconfigCluster `json:"clusters"`
+	Users    []KubeconfigUser    `json:"users"`
+}
+
+// KubeconfigCluster holds the connection settings of a kubeconfig cluster
+type KubeconfigCluster struct {
+	Name                  string `json:"name"`
+	Server                string `json:"server"`
+	InsecureSkipTLSVerify bool   `json:"insecureSkipTLSVerify,omitempty"`
+}
+
+// KubeconfigUser holds the kinds of credentials of a kubeconfig user
+type KubeconfigUser struct {
+	Name string `json:"name"`
+
+	// True if the user has a static token (embedded or in a token file)
+	Token bool `json:"token,omitempty"`
+
+	// True if the credentials are provided by an exec plugin
+	Exec bool `json:"exec,omitempty"`
+
+	// True if the client key is embedded in the kubeconfig
+	EmbeddedClientKey bool `json:"embeddedClientKey,omitempty"`
+
+	ClientCertificate *CertificateInfo `json:"clientCertificate,omitempty"`
+
+	// True if the client certificate is of a cluster admin group, e.g. system:masters
+	Admin bool `json:"admin,omitempty"`
+}
+
+// CertificateInfo holds the details of a client certificate
+type CertificateInfo struct {
+	// True if the certificate is embedded in the kubeconfig, otherwise it is read from Path
+	Embedded bool   `json:"embedded"`
+	Path     string `json:"path,omitempty"`
+
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	NotBefore time.Time `json:"notBefore"`
+	NotAfter  time.Time `json:"notAfter"`
+}
+
+// kubeconfig is the subset of the kubeconfig format used by the kubeconfig sensor
+type kubeconfig struct {
+	Clusters []struct {
+		Name    string `json:"name"`
+		Cluster struct {
+			Server                string `json:"server"`
+			InsecureSkipTLSVerify bool   `json:"insecure-skip-tls-verify"`
+		} `json:"cluster"`
+	} `json:"clusters"`
+	Users []struct {
+		Name string `json:"name"`
+		User struct {
+			ClientCertificate     string      `json:"client-certificate"`
+			ClientCertificateData string      `json:"client-certificate-data"`
+			ClientKeyData         string      `json:"client-key-data"`
+			Token                 string      `json:"token"`
+			TokenFile             string      `json:"tokenFile"`
+			Exec                  interface{} `json:"exec"`
+		} `json:"user"`
+	} `json:"users"`
+}
+
+// SenseKubeconfigs returns the analysis of the kubeconfig files of the kubelet and the control plane components.
+// Kubeconfig files which don't exist on the node are omitted.
+func SenseKubeconfigs(ctx context.Context) ([]KubeconfigInfo, error) {
+	ret := []KubeconfigInfo{}
+	for _, component := range []struct {
+		name        string
+		processExe  string
+		defaultPath string
+	}{
+		{"kubelet", "", getKubeletKubeConfigPath(ctx)},
+		{"admin", "", adminConfigPath},
+		{"controllerManager", controllerManagerExe, controllerManagerConfigPath},
+		{"scheduler", schedulerExe, schedulerConfigPath},
+	} {
+		kubeconfigPath := component.defaultPath
+		if kubeconfigPath == "" {
+			continue
+		}
+		if component.processExe != "" {
+			if proc, err := LocateProcessByExecSuffix(ctx, component.processExe); err == nil {
+				if p, ok := proc.GetArg(kubeConfigArgName); ok {
+					kubeconfigPath = p
+				}
+			}
+		}
+
+		info, err := makeKubeconfigInfo(ctx, component.name, kubeconfigPath)
+		if err != nil {
+			logger(ctx).Debug("SenseKubeconfigs failed to analyze kubeconfig",
+				zap.String("component", component.name),
+				zap.String("path", kubeconfigPath),
+				zap.Error(err))
+			continue
+		}
+		ret = append(ret, *info)
+	}
+
+	return ret, nil
+}
+
+// makeKubeconfigInfo parses the kubeconfig file on the host
+func makeKubeconfigInfo(ctx context.Context, component, kubeconfigPath string) (*KubeconfigInfo, error) {
+	fileInfo, err := makeHostFileInfo(ctx, kubeconfigPath, false)
+	if err != nil {
+		return nil, err
+	}
+	content, err := ReadFileOnHostFileSystem(ctx, kubeconfigPath)
+	if err != nil {
+		return nil, err
+	}
+	config := kubeconfig{}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+
+	ret := &KubeconfigInfo{
+		Component: component,
+		File:      fileInfo,
+		Clusters:  []KubeconfigCluster{},
+		Users:     []KubeconfigUser{},
+	}
+	for _, cluster := range config.Clusters {
+		ret.Clusters = append(ret.Clusters, KubeconfigCluster{
+			Name:                  cluster.Name,
+			Server:                cluster.Cluster.Server,
+			InsecureSkipTLSVerify: cluster.Cluster.InsecureSkipTLSVerify,
+		})
+	}
+
+	for _, user := range config.Users {
+		userInfo := KubeconfigUser{
+			Name:              user.Name,
+			Token:             user.User.Token != "" || user.User.TokenFile != "",
+			Exec:              user.User.Exec != nil,
+			EmbeddedClientKey: user.User.ClientKeyData != "",
+		}
+
+		var certPEM []byte
+		certInfo := &CertificateInfo{}
+		if user.User.ClientCertificateData != "" {
+			certPEM, err = base64.StdEncoding.DecodeString(user.User.ClientCertificateData)
+			certInfo.Embedded = true
+		} else if user.User.ClientCertificate != "" {
+			// relative paths are relative to the kubeconfig file
+			certInfo.Path = user.User.ClientCertificate
+			if !path.IsAbs(certInfo.Path) {
+				certInfo.Path = path.Join(path.Dir(kubeconfigPath), certInfo.Path)
+			}
+			certPEM, err = ReadFileOnHostFileSystem(ctx, certInfo.Path)
+		}
+		if certPEM != nil && err == nil {
+			err = parseCertificateInfo(certPEM, certInfo)
+		}
+		if err != nil {
+			logger(ctx).Debug("failed to read kubeconfig client certificate",
+				zap.String("path", kubeconfigPath),
+				zap.String("user", user.Name),
+				zap.Error(err))
+		} else if certPEM != nil {
+			userInfo.ClientCertificate = certInfo
+			userInfo.Admin = isAdminSubject(certInfo.Subject)
+		}
+
+		ret.Users = append(ret.Users, userInfo)
+	}
+
+	return ret, nil
+}
+
+// isAdminSubject returns whether a certificate subject has the organization of a cluster admin group
+func isAdminSubject(subject string) bool {
+	for _, rdn := range strings.FieldsFunc(subject, func(r rune) bool { return r == ',' || r == '+' }) {
+		if strings.HasPrefix(rdn, "O=") && containsString(kubeconfigAdminGroups, strings.TrimPrefix(rdn, "O=")) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseCertificateInfo fills `info` with the details of the first certificate of the PEM data
+func parseCertificateInfo(certPEM []byte, info *CertificateInfo) error {
+	for block, rest := pem.Decode(certPEM); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("failed to parse certificate: %w", err)
+		}
+		info.Subject = cert.Subject.String()
+		info.Issuer = cert.Issuer.String()
+		info.NotBefore = cert.NotBefore
+		info.NotAfter = cert.NotAfter
+		return nil
+	}
+	return errors.New("no certificate found")
+}
@@ -0,0 +1,50 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenseKubeconfigs(t *testing.T) {
+	hfs := NewHostFS("testdata/kubeconfigs")
+	ctx := WithHostFS(context.Background(), hfs)
+
+	kubeconfigs, err := SenseKubeconfigs(ctx)
+	require.NoError(t, err)
+	// the controller manager kubeconfig is missing
+	require.Len(t, kubeconfigs, 3)
+
+	kubelet := kubeconfigs[0]
+	assert.Equal(t, "kubelet", kubelet.Component)
+	assert.Equal(t, "/etc/kubernetes/kubelet.conf", kubelet.File.Path)
+	assert.Nil(t, kubelet.File.Content)
+	assert.Equal(t, []KubeconfigCluster{{Name: "default-cluster", Server: "https://10.0.0.1:6443"}}, kubelet.Clusters)
+	require.Len(t, kubelet.Users, 1)
+	cert := kubelet.Users[0].ClientCertificate
+	require.NotNil(t, cert)
+	assert.False(t, cert.Embedded)
+	assert.Equal(t, "/var/lib/kubelet/pki/kubelet-client-current.pem", cert.Path)
+	assert.Equal(t, "CN=system:node:node-a,O=system:nodes", cert.Subject)
+	assert.False(t, kubelet.Users[0].Admin)
+	assert.Equal(t, 365*24*time.Hour, cert.NotAfter.Sub(cert.NotBefore))
+
+	admin := kubeconfigs[1]
+	assert.Equal(t, "admin", admin.Component)
+	require.Len(t, admin.Users, 1)
+	assert.True(t, admin.Users[0].EmbeddedClientKey)
+	cert = admin.Users[0].ClientCertificate
+	require.NotNil(t, cert)
+	assert.True(t, cert.Embedded)
+	assert.Equal(t, "CN=kubernetes-admin,O=system:masters", cert.Subject)
+	assert.True(t, admin.Users[0].Admin)
+	assert.Equal(t, 3650*24*time.Hour, cert.NotAfter.Sub(cert.NotBefore))
+
+	scheduler := kubeconfigs[2]
+	assert.Equal(t, "scheduler", scheduler.Component)
+	assert.Equal(t, []KubeconfigCluster{{Name: "kubernetes", Server: "http://10.0.0.1:8080", InsecureSkipTLSVerify: true}}, scheduler.Clusters)
+	assert.Equal(t, []KubeconfigUser{{Name: "system:kube-scheduler", Token: true}}, scheduler.Users)
+}
@@ -0,0 +1,137 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// An expired kubelet client certificate silently detaches the node from the cluster, since the kubelet can't renew
+// its lease nor report the node status. The kubelet rotates the certificate when 70-90% of its lifetime has passed,
+// if rotation is enabled.
+
+// Kubelet client certificate rotation verdicts, by order of precedence
+const (
+	// The client certificate has expired
+	KubeletCertVerdictExpired = "expired"
+
+	// The kubelet still authenticates with its bootstrap kubeconfig
+	KubeletCertVerdictBootstrapIdentity = "bootstrapIdentity"
+
+	// Rotation is enabled, but the certificate wasn't rotated by the end of its lifetime
+	KubeletCertVerdictRotationStalled = "rotationStalled"
+
+	// Rotation is disabled, and the certificate expires within the certificate expiry window
+	KubeletCertVerdictExpiring = "expiring"
+
+	// Rotation is disabled, the certificate has to be renewed manually
+	KubeletCertVerdictRotationDisabled = "rotationDisabled"
+
+	KubeletCertVerdictHealthy = "healthy"
+
+	// The client identity of the kubelet isn't a certificate, or it couldn't be read
+	KubeletCertVerdictUnknown = "unknown"
+)
+
+const (
+	kubeletBootstrapKubeConfigArg = "--bootstrap-kubeconfig"
+
+	// The kubelet rotates the certificate by 90% of its lifetime at the latest
+	kubeletCertRotationDeadline = 0.9
+)
+
+// KubeletCertRotationInfo holds the client certificate of the kubelet, its rotation settings, and a health verdict
+type KubeletCertRotationInfo struct {
+	// The kubeconfig of the kubelet, and its client certificate
+	KubeConfigFile    string           `json:"kubeConfigFile,omitempty"`
+	ClientCertificate *CertificateInfo `json:"clientCertificate,omitempty"`
+
+	// The seconds until the client certificate expires, negative if it has expired
+	ExpiresInSeconds int64 `json:"expiresInSeconds,omitempty"`
+
+	// Whether the kubelet rotates its client certificate, nil if unset (disabled)
+	RotateCertificates *bool `json:"rotateCertificates,omitempty"`
+
+	// The bootstrap kubeconfig of the kubelet, the kubeadm one if the flag isn't set, and whether it's still on the node
+	BootstrapKubeConfigFile   string `json:"bootstrapKubeConfigFile,omitempty"`
+	BootstrapKubeConfigExists bool   `json:"bootstrapKubeConfigExists"`
+
+	// One of KubeletCertVerdict*
+	Verdict string   `json:"verdict"`
+	Reasons []string `json:"reasons"`
+}
+
+// kubeletCertRotationVerdict returns the health verdict of the kubelet client certificate, and its reasons
+func kubeletCertRotationVerdict(info *KubeletCertRotationInfo, now time.Time, window time.Duration) (string, []string) {
+	reasons := []string{}
+	bootstrapIdentity := info.BootstrapKubeConfigFile != "" && path.Clean(info.KubeConfigFile) == path.Clean(info.BootstrapKubeConfigFile)
+	if info.BootstrapKubeConfigExists && !bootstrapIdentity && info.ClientCertificate != nil {
+		reasons = append(reasons, fmt.Sprintf("the bootstrap kubeconfig %s is left on the node after the TLS bootstrap", info.BootstrapKubeConfigFile))
+	}
+
+	cert := info.ClientCertificate
+	rotate := info.RotateCertificates != nil && *info.RotateCertificates
+	switch {
+	case cert != nil && !now.Before(cert.NotAfter):
+		return KubeletCertVerdictExpired, append(reasons, fmt.Sprintf("the client certificate expired at %s", cert.NotAfter.Format(time.RFC3339)))
+	case bootstrapIdentity:
+		return KubeletCertVerdictBootstrapIdentity, append(reasons, fmt.Sprintf("the kubelet authenticates with its bootstrap kubeconfig %s", info.KubeConfigFile))
+	case cert == nil:
+		return KubeletCertVerdictUnknown, append(reasons, "the kubelet client certificate isn't found")
+	case rotate && now.After(cert.NotBefore.Add(time.Duration(float64(cert.NotAfter.Sub(cert.NotBefore))*kubeletCertRotationDeadline))):
+		return KubeletCertVerdictRotationStalled, append(reasons, fmt.Sprintf("the client certificate wasn't rotated by 90%% of its lifetime, it expires at %s", cert.NotAfter.Format(time.RFC3339)))
+	case !rotate && cert.NotAfter.Sub(now) < window:
+		return KubeletCertVerdictExpiring, append(reasons, fmt.Sprintf("the client certificate expires at %s and rotation is disabled", cert.NotAfter.Format(time.RFC3339)))
+	case !rotate:
+		return KubeletCertVerdictRotationDisabled, append(reasons, "client certificate rotation is disabled")
+	}
+	return KubeletCertVerdictHealthy, reasons
+}
+
+// SenseKubeletCertRotation returns the client certificate of the kubelet, its rotation settings and bootstrap
+// kubeconfig, and whether the certificate is healthy
+func SenseKubeletCertRotation(ctx context.Context) (*KubeletCertRotationInfo, error) {
+	kubeletProcess, err := LocateKubeletProcess(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to LocateKubeletProcess: %w", err)
+	}
+
+	ret := &KubeletCertRotationInfo{BootstrapKubeConfigFile: bootstrapKubeconfigPath}
+	if p, ok := kubeletProcess.GetArg(kubeletBootstrapKubeConfigArg); ok && p != "" {
+		ret.BootstrapKubeConfigFile = p
+	}
+	if _, err := statHostFile(ctx, ret.BootstrapKubeConfigFile); err == nil {
+		ret.BootstrapKubeConfigExists = true
+	}
+
+	configPath := kubeletConfigDefaultPath
+	if p, ok := kubeletProcess.GetArg(kubeletConfigArgName); ok {
+		configPath = p
+	}
+	configContent, err := ReadFileOnHostFileSystem(ctx, configPath)
+	if err != nil {
+		logger(ctx).Debug("SenseKubeletCertRotation failed to read the kubelet config", zap.String("path", configPath), zap.Error(err))
+	}
+	ret.RotateCertificates = parseKubeletSettings(ctx, kubeletProcess, configContent).RotateCertificates
+
+	ret.KubeConfigFile, _ = resolveKubeletKubeConfig(ctx, kubeletProcess)
+	if ret.KubeConfigFile != "" {
+		kubeconfig, err := makeKubeconfigInfo(ctx, "kubelet", ret.KubeConfigFile)
+		if err != nil {
+			logger(ctx).Debug("SenseKubeletCertRotation failed to analyze the kubelet kubeconfig", zap.String("path", ret.KubeConfigFile), zap.Error(err))
+		} else if len(kubeconfig.Users) > 0 {
+			ret.ClientCertificate = kubeconfig.Users[0].ClientCertificate
+		}
+	}
+
+	now := time.Now()
+	if ret.ClientCertificate != nil {
+		ret.ExpiresInSeconds = int64(ret.ClientCertificate.NotAfter.Sub(now).Seconds())
+	}
+	ret.Verdict, ret.Reasons = kubeletCertRotationVerdict(ret, now, time.Duration(atomic.LoadInt64(&certExpiryWindow)))
+	return ret, nil
+}
@@ -0,0 +1,40 @@
+package sensor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKubeletCertRotationVerdict(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	window := 30 * 24 * time.Hour
+	enabled, disabled := true, false
+	cert := func(issued, expires time.Duration) *CertificateInfo {
+		return &CertificateInfo{Path: "/var/lib/kubelet/pki/kubelet-client-current.pem", NotBefore: now.Add(issued), NotAfter: now.Add(expires)}
+	}
+
+	for _, tc := range []struct {
+		name    string
+		info    KubeletCertRotationInfo
+		verdict string
+		reasons int
+	}{
+		{"healthy", KubeletCertRotationInfo{ClientCertificate: cert(-24*time.Hour, 300*24*time.Hour), RotateCertificates: &enabled}, KubeletCertVerdictHealthy, 0},
+		{"expired", KubeletCertRotationInfo{ClientCertificate: cert(-365*24*time.Hour, -time.Hour), RotateCertificates: &enabled}, KubeletCertVerdictExpired, 1},
+		{"stalled", KubeletCertRotationInfo{ClientCertificate: cert(-360*24*time.Hour, 5*24*time.Hour), RotateCertificates: &enabled}, KubeletCertVerdictRotationStalled, 1},
+		{"expiring", KubeletCertRotationInfo{ClientCertificate: cert(-360*24*time.Hour, 5*24*time.Hour)}, KubeletCertVerdictExpiring, 1},
+		{"rotation disabled", KubeletCertRotationInfo{ClientCertificate: cert(-24*time.Hour, 300*24*time.Hour), RotateCertificates: &disabled}, KubeletCertVerdictRotationDisabled, 1},
+		{"bootstrap identity", KubeletCertRotationInfo{KubeConfigFile: "/etc/kubernetes/bootstrap-kubelet.conf", BootstrapKubeConfigFile: "/etc/kubernetes/bootstrap-kubelet.conf", BootstrapKubeConfigExists: true}, KubeletCertVerdictBootstrapIdentity, 1},
+		{"stale bootstrap", KubeletCertRotationInfo{KubeConfigFile: "/etc/kubernetes/kubelet.conf", BootstrapKubeConfigFile: "/etc/kubernetes/bootstrap-kubelet.conf", BootstrapKubeConfigExists: true,
+			ClientCertificate: cert(-24*time.Hour, 300*24*time.Hour), RotateCertificates: &enabled}, KubeletCertVerdictHealthy, 1},
+		{"unknown", KubeletCertRotationInfo{KubeConfigFile: "/etc/kubernetes/kubelet.conf"}, KubeletCertVerdictUnknown, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			verdict, reasons := kubeletCertRotationVerdict(&tc.info, now, window)
+			assert.Equal(t, tc.verdict, verdict)
+			assert.Len(t, reasons, tc.reasons)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+// The kubelet executes the image credential provider plugins of its bin directory to fetch the registry credentials
+// of the images it pulls. The plugins run with the privileges of the kubelet, so whoever can replace one runs as root.
+
+const (
+	kubeletImageCredentialProviderBinDirArg = "--image-credential-provider-bin-dir"
+)
+
+// KubeletCredentialProviders holds the image credential provider config of the kubelet, and the plugins it executes
+type KubeletCredentialProviders struct {
+	// The CredentialProviderConfig file of the --image-credential-provider-config flag
+	ConfigFile *FileInfo `json:"configFile,omitempty"`
+
+	// The directory of the --image-credential-provider-bin-dir flag
+	BinDir string `json:"binDir,omitempty"`
+
+	// The users and groups other than root who can write the bin directory, i.e. add or replace plugins
+	BinDirWriters []string `json:"binDirWriters"`
+
+	// The providers of the config
+	Providers []KubeletCredentialProvider `json:"providers"`
+
+	// The executables of the bin directory
+	Binaries []KubeletCredentialProviderBinary `json:"binaries"`
+
+	// The parse error of the config, empty if it was parsed
+	Error string `json:"error,omitempty"`
+}
+
+// KubeletCredentialProvider is a provider of the CredentialProviderConfig
+type KubeletCredentialProvider struct {
+	Name        string   `json:"name"`
+	APIVersion  string   `json:"apiVersion,omitempty"`
+	MatchImages []string `json:"matchImages"`
+
+	// Whether the bin directory has no executable of the provider name
+	BinaryMissing bool `json:"binaryMissing"`
+}
+
+// KubeletCredentialProviderBinary is an executable of the bin directory, and who can replace it
+type KubeletCredentialProviderBinary struct {
+	File *FileInfo `json:"file"`
+
+	// The users and groups other than root who can write the executable
+	Writers []string `json:"writers"`
+}
+
+// parseCredentialProviders parses the providers of a kubelet CredentialProviderConfig
+func parseCredentialProviders(content []byte) ([]KubeletCredentialProvider, error) {
+	config := struct {
+		Providers []struct {
+			Name        string   `json:"name"`
+			APIVersion  string   `json:"apiVersion"`
+			MatchImages []string `json:"matchImages"`
+		} `json:"providers"`
+	}{}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse credential provider config: %w", err)
+	}
+
+	ret := []KubeletCredentialProvider{}
+	for _, provider := range config.Providers {
+		matchImages := provider.MatchImages
+		if matchImages == nil {
+			matchImages = []string{}
+		}
+		ret = append(ret, KubeletCredentialProvider{Name: provider.Name, APIVersion: provider.APIVersion, MatchImages: matchImages})
+	}
+	return ret, nil
+}
+
+// senseCredentialProviderBinaries returns the regular files of the bin directory, hashed, and who can write them
+func senseCredentialProviderBinaries(ctx context.Context, binDir string) []KubeletCredentialProviderBinary {
+	ret := []KubeletCredentialProviderBinary{}
+	entries, err := readHostDir(ctx, binDir)
+	if err != nil {
+		logger(ctx).Debug("failed to read the credential provider bin dir", zap.String("path", binDir), zap.Error(err))
+		recordCollectionError(ctx, "readdir", binDir, err)
+		return ret
+	}
+	for _, entry := range entries {
+		filePath := path.Join(binDir, entry.Name())
+		info, err := statHostFile(ctx, filePath)
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		file := makeHostFileInfoVerbose(ctx, filePath, false, zap.String("in", "senseCredentialProviderBinaries"))
+		if file == nil {
+			continue
+		}
+		ret = append(ret, KubeletCredentialProviderBinary{File: file, Writers: nonRootWriters(file, false)})
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].File.Path < ret[j].File.Path })
+	return ret
+}
+
+// senseKubeletCredentialProviders returns the credential provider config and bin directory of the kubelet, nil if
+// neither flag is set
+func senseKubeletCredentialProviders(ctx context.Context, kubeletProcess *ProcessDetails) *KubeletCredentialProviders {
+	configPath, _ := kubeletProcess.GetArg(kubeletImageCredentialProviderArg)
+	binDir, _ := kubeletProcess.GetArg(kubeletImageCredentialProviderBinDirArg)
+	if configPath == "" && binDir == "" {
+		return nil
+	}
+
+	ret := &KubeletCredentialProviders{
+		BinDir:        binDir,
+		BinDirWriters: []string{},
+		Providers:     []KubeletCredentialProvider{},
+		Binaries:      []KubeletCredentialProviderBinary{},
+	}
+	if configPath != "" {
+		ret.ConfigFile = makeHostFileInfoVerbose(ctx, configPath, true, zap.String("in", "senseKubeletCredentialProviders"))
+		if ret.ConfigFile != nil && ret.ConfigFile.Content != nil {
+			if providers, err := parseCredentialProviders(ret.ConfigFile.Content); err != nil {
+				ret.Error = err.Error()
+			} else {
+				ret.Providers = providers
+			}
+		}
+	}
+	if binDir == "" {
+		return ret
+	}
+
+	if info, err := statHostFile(ctx, binDir); err == nil {
+		if dir, err := makeHostFileStatInfo(ctx, binDir); err == nil {
+			ret.BinDirWriters = nonRootWriters(dir, info.Mode()&fs.ModeSticky != 0)
+		}
+	}
+	ret.Binaries = senseCredentialProviderBinaries(ctx, binDir)
+	for i := range ret.Providers {
+		ret.Providers[i].BinaryMissing = true
+		for _, binary := range ret.Binaries {
+			if path.Base(binary.File.Path) == ret.Providers[i].Name {
+				ret.Providers[i].BinaryMissing = false
+			}
+		}
+	}
+	return ret
+}
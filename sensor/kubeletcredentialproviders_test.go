@@ -0,0 +1,55 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenseKubeletCredentialProviders(t *testing.T) {
+	// the files are hashed for the file integrity monitoring only
+	SetFileHashing(true)
+	defer SetFileHashing(false)
+
+	hfs := NewHostFS(t.TempDir())
+
+	ctx := WithHostFS(context.Background(), hfs)
+	writeFSFile(t, hfs, "/etc/kubernetes/credential-providers.yaml", []byte(`apiVersion: kubelet.config.k8s.io/v1
+kind: CredentialProviderConfig
+providers:
+- name: ecr-credential-provider
+  apiVersion: credentialprovider.kubelet.k8s.io/v1
+  matchImages:
+  - "*.dkr.ecr.*.amazonaws.com"
+- name: acr-credential-provider
+  apiVersion: credentialprovider.kubelet.k8s.io/v1
+`))
+	writeFSFile(t, hfs, "/usr/libexec/kubernetes/credential-providers/ecr-credential-provider", []byte("#!/bin/sh\n"))
+	require.NoError(t, os.Chmod(hfs.Path("/usr/libexec/kubernetes/credential-providers/ecr-credential-provider"), 0o777))
+
+	kubelet := &ProcessDetails{CmdLine: []string{"/usr/bin/kubelet",
+		"--image-credential-provider-config=/etc/kubernetes/credential-providers.yaml",
+		"--image-credential-provider-bin-dir=/usr/libexec/kubernetes/credential-providers"}}
+	info := senseKubeletCredentialProviders(ctx, kubelet)
+	require.NotNil(t, info)
+	require.NotNil(t, info.ConfigFile)
+	assert.Empty(t, info.Error)
+	assert.Equal(t, "/usr/libexec/kubernetes/credential-providers", info.BinDir)
+	assert.Empty(t, info.BinDirWriters)
+
+	require.Len(t, info.Providers, 2)
+	assert.Equal(t, "ecr-credential-provider", info.Providers[0].Name)
+	assert.Equal(t, []string{"*.dkr.ecr.*.amazonaws.com"}, info.Providers[0].MatchImages)
+	assert.False(t, info.Providers[0].BinaryMissing)
+	assert.Equal(t, []string{}, info.Providers[1].MatchImages)
+	assert.True(t, info.Providers[1].BinaryMissing)
+
+	require.Len(t, info.Binaries, 1)
+	assert.NotEmpty(t, info.Binaries[0].File.SHA256)
+	assert.Equal(t, []string{"everyone"}, info.Binaries[0].Writers)
+
+	assert.Nil(t, senseKubeletCredentialProviders(ctx, &ProcessDetails{CmdLine: []string{"/usr/bin/kubelet"}}))
+}
@@ -0,0 +1,346 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+// The kubelet garbage collects the unused images when the image file system usage exceeds the image GC high
+// threshold, down to the low threshold, and evicts pods when a file system falls below a hard eviction threshold.
+// A node whose disks fill up before either kicks in, or on which they can't free enough, goes NotReady.
+
+// Disk exhaustion risks, by order of precedence
+const (
+	// A file system is below a hard eviction threshold, or the image GC can't bring the image store below its
+	// high threshold
+	DiskExhaustionRiskHigh = "high"
+
+	// The image GC or the disk evictions are disabled, the pods are evicted before the image GC runs, or a file
+	// system is close to its hard eviction threshold
+	DiskExhaustionRiskMedium = "medium"
+
+	DiskExhaustionRiskLow = "low"
+)
+
+const (
+	kubeletImageGCHighThresholdArg              = "--image-gc-high-threshold"
+	kubeletImageGCLowThresholdArg               = "--image-gc-low-threshold"
+	kubeletMinimumImageTTLDurationArg           = "--minimum-image-ttl-duration"
+	kubeletMaximumDeadContainersArg             = "--maximum-dead-containers"
+	kubeletMaximumDeadContainersPerContainerArg = "--maximum-dead-containers-per-container"
+	kubeletMinimumContainerTTLDurationArg       = "--minimum-container-ttl-duration"
+	kubeletEvictionHardArg                      = "--eviction-hard"
+	kubeletEvictionSoftArg                      = "--eviction-soft"
+	kubeletEvictionSoftGracePeriodArg           = "--eviction-soft-grace-period"
+	kubeletEvictionMinimumReclaimArg            = "--eviction-minimum-reclaim"
+	kubeletImageGCHighThresholdDefault          = 85
+	evictionSignalNodeFSAvailable               = "nodefs.available"
+	evictionSignalImageFSAvailable              = "imagefs.available"
+	diskExhaustionMarginPercent                 = 10
+)
+
+var (
+	// The hard eviction thresholds of the kubelet on Linux if neither --eviction-hard nor evictionHard is set
+	kubeletEvictionHardDefault = map[string]string{
+		"memory.available":             "100Mi",
+		evictionSignalNodeFSAvailable:  "10%",
+		"nodefs.inodesFree":            "5%",
+		evictionSignalImageFSAvailable: "15%",
+	}
+
+	// The multipliers of the quantity suffixes
+	quantitySuffixes = map[string]float64{
+		"Ki": 1 << 10, "Mi": 1 << 20, "Gi": 1 << 30, "Ti": 1 << 40, "Pi": 1 << 50, "Ei": 1 << 60,
+		"k": 1e3, "M": 1e6, "G": 1e9, "T": 1e12, "P": 1e15, "E": 1e18,
+	}
+)
+
+// KubeletDiskGCInfo holds the image and container garbage collection and the eviction settings of the kubelet, the
+// disk usage of its root dir and of the image stores, and the disk exhaustion risk of the node
+type KubeletDiskGCInfo struct {
+	Settings *KubeletDiskGCSettings `json:"settings"`
+
+	// The hard eviction thresholds in effect, the defaults if none is set
+	EffectiveEvictionHard map[string]string `json:"effectiveEvictionHard"`
+
+	// The usage of the file system of the kubelet root dir (nodefs), and of the image stores (imagefs)
+	NodeFS  *DiskUsage        `json:"nodeFS,omitempty"`
+	ImageFS []ImageStoreUsage `json:"imageFS"`
+
+	// One of DiskExhaustionRisk*
+	DiskExhaustionRisk string   `json:"diskExhaustionRisk"`
+	Reasons            []string `json:"reasons"`
+}
+
+// ImageStoreUsage is the usage of the file system of the image store of a container runtime
+type ImageStoreUsage struct {
+	// One of "containerd", "docker" or "crio"
+	Runtime string    `json:"runtime"`
+	Usage   DiskUsage `json:"usage"`
+}
+
+// KubeletDiskGCSettings are the garbage collection and eviction settings of the kubelet, from its flags, which
+// override its config file. A setting which is set in neither is nil, and has its default value: 85, 80, 2m, -1
+// (unlimited), 1 and 0. The dead container settings are flags only.
+type KubeletDiskGCSettings struct {
+	// The image file system usage percentages which start and stop the image GC, 100 disables it
+	ImageGCHighThresholdPercent *int `json:"imageGCHighThresholdPercent,omitempty"`
+	ImageGCLowThresholdPercent  *int `json:"imageGCLowThresholdPercent,omitempty"`
+
+	// The minimal age of an unused image before it's collected
+	ImageMinimumGCAge *string `json:"imageMinimumGCAge,omitempty"`
+
+	// The dead containers kept on the node and per container, and their minimal age before they're collected
+	MaximumDeadContainers             *int    `json:"maximumDeadContainers,omitempty"`
+	MaximumDeadContainersPerContainer *int    `json:"maximumDeadContainersPerContainer,omitempty"`
+	MinimumContainerTTLDuration       *string `json:"minimumContainerTTLDuration,omitempty"`
+
+	// The eviction thresholds by signal, e.g. "nodefs.available": "10%"
+	EvictionHard            map[string]string `json:"evictionHard,omitempty"`
+	EvictionSoft            map[string]string `json:"evictionSoft,omitempty"`
+	EvictionSoftGracePeriod map[string]string `json:"evictionSoftGracePeriod,omitempty"`
+	EvictionMinimumReclaim  map[string]string `json:"evictionMinimumReclaim,omitempty"`
+}
+
+// kubeletConfigDiskGCSettings are the garbage collection and eviction settings of the KubeletConfiguration file
+type kubeletConfigDiskGCSettings struct {
+	ImageGCHighThresholdPercent *int              `json:"imageGCHighThresholdPercent"`
+	ImageGCLowThresholdPercent  *int              `json:"imageGCLowThresholdPercent"`
+	ImageMinimumGCAge           *string           `json:"imageMinimumGCAge"`
+	EvictionHard                map[string]string `json:"evictionHard"`
+	EvictionSoft                map[string]string `json:"evictionSoft"`
+	EvictionSoftGracePeriod     map[string]string `json:"evictionSoftGracePeriod"`
+	EvictionMinimumReclaim      map[string]string `json:"evictionMinimumReclaim"`
+}
+
+// parseEvictionFlag parses an eviction flag of signals and values, e.g. "nodefs.available<10%,memory.available<1Gi"
+// or "nodefs.available=1m30s"
+func parseEvictionFlag(val string) map[string]string {
+	ret := map[string]string{}
+	for _, item := range splitList(val) {
+		if i := strings.IndexAny(item, "<="); i > 0 {
+			ret[item[:i]] = item[i+1:]
+		}
+	}
+	return ret
+}
+
+// parseKubeletDiskGCSettings returns the garbage collection and eviction settings of the kubelet flags, or else of
+// its config file content (if any)
+func parseKubeletDiskGCSettings(ctx context.Context, p *ProcessDetails, configContent []byte) *KubeletDiskGCSettings {
+	ret := &KubeletDiskGCSettings{}
+	if configContent != nil {
+		config := kubeletConfigDiskGCSettings{}
+		if err := yaml.Unmarshal(configContent, &config); err != nil {
+			logger(ctx).Warn("failed to parse kubelet config", zap.Error(err))
+		} else {
+			ret.ImageGCHighThresholdPercent = config.ImageGCHighThresholdPercent
+			ret.ImageGCLowThresholdPercent = config.ImageGCLowThresholdPercent
+			ret.ImageMinimumGCAge = config.ImageMinimumGCAge
+			ret.EvictionHard = config.EvictionHard
+			ret.EvictionSoft = config.EvictionSoft
+			ret.EvictionSoftGracePeriod = config.EvictionSoftGracePeriod
+			ret.EvictionMinimumReclaim = config.EvictionMinimumReclaim
+		}
+	}
+
+	for _, arg := range []struct {
+		name  string
+		value **int
+	}{
+		{kubeletImageGCHighThresholdArg, &ret.ImageGCHighThresholdPercent},
+		{kubeletImageGCLowThresholdArg, &ret.ImageGCLowThresholdPercent},
+		{kubeletMaximumDeadContainersArg, &ret.MaximumDeadContainers},
+		{kubeletMaximumDeadContainersPerContainerArg, &ret.MaximumDeadContainersPerContainer},
+	} {
+		val, ok := p.GetArg(arg.name)
+		if !ok {
+			continue
+		}
+		n, err := strconv.Atoi(val)
+		if err != nil {
+			logger(ctx).Warn("invalid flag value", zap.String("flag", arg.name), zap.String("value", val))
+			continue
+		}
+		*arg.value = &n
+	}
+	if val, ok := p.GetArg(kubeletMinimumImageTTLDurationArg); ok {
+		ret.ImageMinimumGCAge = &val
+	}
+	if val, ok := p.GetArg(kubeletMinimumContainerTTLDurationArg); ok {
+		ret.MinimumContainerTTLDuration = &val
+	}
+	for _, arg := range []struct {
+		name  string
+		value *map[string]string
+	}{
+		{kubeletEvictionHardArg, &ret.EvictionHard},
+		{kubeletEvictionSoftArg, &ret.EvictionSoft},
+		{kubeletEvictionSoftGracePeriodArg, &ret.EvictionSoftGracePeriod},
+		{kubeletEvictionMinimumReclaimArg, &ret.EvictionMinimumReclaim},
+	} {
+		if val, ok := p.GetArg(arg.name); ok {
+			*arg.value = parseEvictionFlag(val)
+		}
+	}
+	return ret
+}
+
+// parseQuantity parses a resource quantity into bytes, e.g. "100Mi" or "1G"
+func parseQuantity(val string) (float64, error) {
+	for suffix, multiplier := range quantitySuffixes {
+		if strings.HasSuffix(val, suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(val, suffix), 64)
+			return n * multiplier, err
+		}
+	}
+	return strconv.ParseFloat(val, 64)
+}
+
+// evictionThresholdReached returns whether the available bytes of a file system are below an available eviction
+// threshold, a percentage or a quantity
+func evictionThresholdReached(usage *DiskUsage, threshold string) (bool, error) {
+	if strings.HasSuffix(threshold, "%") {
+		pct, err := strconv.ParseFloat(strings.TrimSuffix(threshold, "%"), 64)
+		if err != nil {
+			return false, err
+		}
+		return float64(usage.AvailableBytes) < float64(usage.TotalBytes)*pct/100, nil
+	}
+	bytes, err := parseQuantity(threshold)
+	if err != nil {
+		return false, err
+	}
+	return float64(usage.AvailableBytes) < bytes, nil
+}
+
+// capacityUsedPercent returns the used percentage of a file system as the kubelet computes it, of its capacity
+func capacityUsedPercent(usage *DiskUsage) float64 {
+	if usage.TotalBytes == 0 {
+		return 0
+	}
+	return percent(usage.TotalBytes-usage.AvailableBytes, usage.TotalBytes)
+}
+
+// diskExhaustionRisk returns the disk exhaustion risk of the node, and its reasons
+func diskExhaustionRisk(ctx context.Context, info *KubeletDiskGCInfo) (string, []string) {
+	high, medium := []string{}, []string{}
+	gcHigh := kubeletImageGCHighThresholdDefault
+	if info.Settings.ImageGCHighThresholdPercent != nil {
+		gcHigh = *info.Settings.ImageGCHighThresholdPercent
+	}
+
+	type filesystem struct {
+		name   string
+		signal string
+		usage  *DiskUsage
+	}
+	filesystems := []filesystem{}
+	if info.NodeFS != nil {
+		filesystems = append(filesystems, filesystem{"the kubelet root dir " + info.NodeFS.Path, evictionSignalNodeFSAvailable, info.NodeFS})
+	}
+	for i := range info.ImageFS {
+		name := fmt.Sprintf("the %s image store %s", info.ImageFS[i].Runtime, info.ImageFS[i].Usage.Path)
+		filesystems = append(filesystems, filesystem{name, evictionSignalImageFSAvailable, &info.ImageFS[i].Usage})
+	}
+	for _, filesystem := range filesystems {
+		threshold, ok := info.EffectiveEvictionHard[filesystem.signal]
+		if !ok {
+			continue
+		}
+		reached, err := evictionThresholdReached(filesystem.usage, threshold)
+		if err != nil {
+			logger(ctx).Debug("invalid eviction threshold", zap.String("signal", filesystem.signal), zap.String("threshold", threshold), zap.Error(err))
+			continue
+		}
+		if reached {
+			high = append(high, fmt.Sprintf("%s is below the hard eviction threshold %s<%s", filesystem.name, filesystem.signal, threshold))
+			continue
+		}
+		if pct, err := strconv.ParseFloat(strings.TrimSuffix(threshold, "%"), 64); err == nil && strings.HasSuffix(threshold, "%") &&
+			capacityUsedPercent(filesystem.usage) >= 100-pct-diskExhaustionMarginPercent {
+			medium = append(medium, fmt.Sprintf("%s is %.1f%% used, close to the hard eviction threshold %s<%s", filesystem.name, capacityUsedPercent(filesystem.usage), filesystem.signal, threshold))
+		}
+	}
+	for _, store := range info.ImageFS {
+		if gcHigh < 100 && capacityUsedPercent(&store.Usage) > float64(gcHigh) {
+			high = append(high, fmt.Sprintf("the %s image store %s is %.1f%% used, above the image GC high threshold %d%%", store.Runtime, store.Usage.Path, capacityUsedPercent(&store.Usage), gcHigh))
+		}
+	}
+
+	if gcHigh >= 100 {
+		medium = append(medium, "the image GC is disabled (high threshold 100%)")
+	}
+	for _, signal := range []string{evictionSignalNodeFSAvailable, evictionSignalImageFSAvailable} {
+		threshold, ok := info.EffectiveEvictionHard[signal]
+		if !ok || threshold == "0%" || threshold == "0" {
+			medium = append(medium, fmt.Sprintf("the hard eviction on %s is disabled", signal))
+		}
+	}
+	if threshold, ok := info.EffectiveEvictionHard[evictionSignalImageFSAvailable]; ok && strings.HasSuffix(threshold, "%") && gcHigh < 100 {
+		if pct, err := strconv.ParseFloat(strings.TrimSuffix(threshold, "%"), 64); err == nil && pct > 0 && float64(gcHigh) > 100-pct {
+			medium = append(medium, fmt.Sprintf("the pods are evicted (%s<%s) before the image GC runs (high threshold %d%%)", evictionSignalImageFSAvailable, threshold, gcHigh))
+		}
+	}
+
+	switch {
+	case len(high) > 0:
+		return DiskExhaustionRiskHigh, append(high, medium...)
+	case len(medium) > 0:
+		return DiskExhaustionRiskMedium, medium
+	}
+	return DiskExhaustionRiskLow, []string{}
+}
+
+// SenseKubeletDiskGC returns the garbage collection and eviction settings of the kubelet, the disk usage of its root
+// dir and of the image stores, and the disk exhaustion risk of the node
+func SenseKubeletDiskGC(ctx context.Context) (*KubeletDiskGCInfo, error) {
+	kubeletProcess, err := LocateKubeletProcess(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to LocateKubeletProcess: %w", err)
+	}
+
+	configPath := kubeletConfigDefaultPath
+	if p, ok := kubeletProcess.GetArg(kubeletConfigArgName); ok {
+		configPath = p
+	}
+	configContent, err := ReadFileOnHostFileSystem(ctx, configPath)
+	if err != nil {
+		logger(ctx).Debug("SenseKubeletDiskGC failed to read the kubelet config", zap.String("path", configPath), zap.Error(err))
+		configContent = nil
+	}
+
+	ret := &KubeletDiskGCInfo{Settings: parseKubeletDiskGCSettings(ctx, kubeletProcess, configContent), ImageFS: []ImageStoreUsage{}}
+	ret.EffectiveEvictionHard = ret.Settings.EvictionHard
+	if ret.EffectiveEvictionHard == nil {
+		ret.EffectiveEvictionHard = kubeletEvictionHardDefault
+	}
+
+	rootDir := getKubeletRootDir(ctx)
+	if usage, err := getDiskUsage(ctx, rootDir); err != nil {
+		logger(ctx).Debug("SenseKubeletDiskGC failed to get disk usage", zap.String("path", rootDir), zap.Error(err))
+	} else {
+		ret.NodeFS = usage
+	}
+	for _, store := range imageStores {
+		root := getImageStoreRoot(ctx, store.defaultRoot, store.configPath, store.parseRoot, store.process, store.rootArg)
+		usage, err := getDiskUsage(ctx, root)
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				logger(ctx).Debug("SenseKubeletDiskGC failed to get disk usage", zap.String("path", root), zap.Error(err))
+			}
+			continue
+		}
+		ret.ImageFS = append(ret.ImageFS, ImageStoreUsage{Runtime: store.runtime, Usage: *usage})
+	}
+
+	ret.DiskExhaustionRisk, ret.Reasons = diskExhaustionRisk(ctx, ret)
+	return ret, nil
+}
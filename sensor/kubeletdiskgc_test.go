@@ -0,0 +1,61 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKubeletDiskGCSettings(t *testing.T) {
+	proc := &ProcessDetails{CmdLine: []string{"/usr/bin/kubelet", "--image-gc-high-threshold=90", "--maximum-dead-containers-per-container=3", "--eviction-hard=nodefs.available<5%,imagefs.available<2Gi"}}
+	settings := parseKubeletDiskGCSettings(context.Background(), proc, []byte(`kind: KubeletConfiguration
+imageGCHighThresholdPercent: 70
+imageGCLowThresholdPercent: 60
+evictionHard:
+  memory.available: 200Mi
+evictionSoftGracePeriod:
+  nodefs.available: 1m30s
+`))
+	require.NotNil(t, settings.ImageGCHighThresholdPercent)
+	assert.Equal(t, 90, *settings.ImageGCHighThresholdPercent)
+	require.NotNil(t, settings.ImageGCLowThresholdPercent)
+	assert.Equal(t, 60, *settings.ImageGCLowThresholdPercent)
+	require.NotNil(t, settings.MaximumDeadContainersPerContainer)
+	assert.Equal(t, 3, *settings.MaximumDeadContainersPerContainer)
+	assert.Nil(t, settings.MaximumDeadContainers)
+	assert.Equal(t, map[string]string{"nodefs.available": "5%", "imagefs.available": "2Gi"}, settings.EvictionHard)
+	assert.Equal(t, map[string]string{"nodefs.available": "1m30s"}, settings.EvictionSoftGracePeriod)
+}
+
+func TestDiskExhaustionRisk(t *testing.T) {
+	gib := uint64(1 << 30)
+	usage := func(path string, total, available uint64) DiskUsage {
+		return DiskUsage{Path: path, TotalBytes: total * gib, AvailableBytes: available * gib}
+	}
+	disabled, low := 100, 90
+
+	for _, tc := range []struct {
+		name    string
+		info    KubeletDiskGCInfo
+		risk    string
+		reasons int
+	}{
+		{"low", KubeletDiskGCInfo{Settings: &KubeletDiskGCSettings{}, EffectiveEvictionHard: kubeletEvictionHardDefault,
+			ImageFS: []ImageStoreUsage{{Runtime: "containerd", Usage: usage("/var/lib/containerd", 100, 60)}}}, DiskExhaustionRiskLow, 0},
+		{"below eviction threshold", KubeletDiskGCInfo{Settings: &KubeletDiskGCSettings{}, EffectiveEvictionHard: kubeletEvictionHardDefault,
+			NodeFS: func() *DiskUsage { u := usage("/var/lib/kubelet", 100, 5); return &u }()}, DiskExhaustionRiskHigh, 1},
+		{"above image GC threshold", KubeletDiskGCInfo{Settings: &KubeletDiskGCSettings{}, EffectiveEvictionHard: map[string]string{"nodefs.available": "1Gi", "imagefs.available": "1Gi"},
+			ImageFS: []ImageStoreUsage{{Runtime: "containerd", Usage: usage("/var/lib/containerd", 100, 10)}}}, DiskExhaustionRiskHigh, 1},
+		{"image GC disabled", KubeletDiskGCInfo{Settings: &KubeletDiskGCSettings{ImageGCHighThresholdPercent: &disabled}, EffectiveEvictionHard: kubeletEvictionHardDefault}, DiskExhaustionRiskMedium, 1},
+		{"evictions disabled", KubeletDiskGCInfo{Settings: &KubeletDiskGCSettings{}, EffectiveEvictionHard: map[string]string{"memory.available": "100Mi", "imagefs.available": "0%"}}, DiskExhaustionRiskMedium, 2},
+		{"eviction before image GC", KubeletDiskGCInfo{Settings: &KubeletDiskGCSettings{ImageGCHighThresholdPercent: &low}, EffectiveEvictionHard: kubeletEvictionHardDefault}, DiskExhaustionRiskMedium, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			risk, reasons := diskExhaustionRisk(context.Background(), &tc.info)
+			assert.Equal(t, tc.risk, risk)
+			assert.Len(t, reasons, tc.reasons, reasons)
+		})
+	}
+}
@@ -1,7 +1,10 @@
 package sensor
 
 import (
+	"context"
 	"fmt"
+	"sort"
+	"strings"
 
 	"go.uber.org/zap"
 	"sigs.k8s.io/yaml"
@@ -16,6 +19,26 @@ const (
 	// Default paths
 	kubeletConfigDefaultPath     = "/var/lib/kubelet/config.yaml"
 	kubeletKubeConfigDefaultPath = "/etc/kubernetes/kubelet.conf"
+
+	systemdEnvironment     = "Environment"
+	systemdEnvironmentFile = "EnvironmentFile"
+)
+
+// Sources of the kubelet kubeconfig path
+const (
+	KubeConfigSourceFlag    = "flag"
+	KubeConfigSourceSystemd = "systemd"
+	KubeConfigSourceDefault = "default"
+)
+
+var (
+	// Default kubelet kubeconfig paths of the distributions, in order (kubeadm, then GKE, EKS and AKS, then Windows
+	// nodes, i.e. C:\k\config)
+	kubeletKubeConfigDefaultPaths = []string{
+		kubeletKubeConfigDefaultPath,
+		"/var/lib/kubelet/kubeconfig",
+		"/k/config",
+	}
 )
 
 // KubeletInfo holds information about kubelet
@@ -30,33 +53,42 @@ type KubeletInfo struct {
 	// Information about the kubeconfig file of kubelet
 	KubeConfigFile *FileInfo `json:"kubeConfigFile,omitempty"`
 
+	// Where the kubeconfig file path was found, one of KubeConfigSource*
+	KubeConfigSource string `json:"kubeConfigSource,omitempty"`
+
 	// Information about the client ca file of kubelet (if exist)
 	ClientCAFile *FileInfo `json:"clientCAFile,omitempty"`
 
+	// The authorization and API access settings, from the flags and the config file
+	Settings *KubeletSettings `json:"settings,omitempty"`
+
+	// The image credential provider config and plugins, nil if the kubelet doesn't use credential providers
+	ImageCredentialProviders *KubeletCredentialProviders `json:"imageCredentialProviders,omitempty"`
+
 	// Raw cmd line of kubelet process
 	CmdLine string `json:"cmdLine"`
 }
 
-func LocateKubeletProcess() (*ProcessDetails, error) {
-	return LocateProcessByExecSuffix(kubeletProcessSuffix)
+func LocateKubeletProcess(ctx context.Context) (*ProcessDetails, error) {
+	return LocateProcessByExecSuffix(ctx, kubeletProcessSuffix)
 }
 
-func ReadKubeletConfig(kubeletConfArgs string) ([]byte, error) {
-	conte, err := ReadFileOnHostFileSystem(kubeletConfArgs)
-	zap.L().Debug("raw content", zap.ByteString("cont", conte))
+func ReadKubeletConfig(ctx context.Context, kubeletConfArgs string) ([]byte, error) {
+	conte, err := ReadFileOnHostFileSystem(ctx, kubeletConfArgs)
+	logger(ctx).Debug("raw content", zap.ByteString("cont", conte))
 	return conte, err
 }
 
-func makeKubeletServiceFilesInfo(pid int) []FileInfo {
-	files, err := getKubeletServiceFiles(pid)
+func makeKubeletServiceFilesInfo(ctx context.Context, pid int) []FileInfo {
+	files, err := getKubeletServiceFiles(ctx, pid)
 	if err != nil {
-		zap.L().Warn("failed to getKubeletServiceFiles", zap.Error(err))
+		logger(ctx).Warn("failed to getKubeletServiceFiles", zap.Error(err))
 		return nil
 	}
 
 	serviceFiles := []FileInfo{}
 	for _, file := range files {
-		info := makeHostFileInfoVerbose(file, false, zap.String("in", "makeProcessInfoVerbose"))
+		info := makeHostFileInfoVerbose(ctx, file, false, zap.String("in", "makeProcessInfoVerbose"))
 		if info != nil {
 			serviceFiles = append(serviceFiles, *info)
 		}
@@ -65,21 +97,22 @@ func makeKubeletServiceFilesInfo(pid int) []FileInfo {
 	if len(serviceFiles) == 0 {
 		return nil
 	}
+	sort.Slice(serviceFiles, func(i, j int) bool { return serviceFiles[i].Path < serviceFiles[j].Path })
 
 	return serviceFiles
 }
 
 // SenseKubeletInfo return varius information about the kubelet service
-func SenseKubeletInfo() (*KubeletInfo, error) {
+func SenseKubeletInfo(ctx context.Context) (*KubeletInfo, error) {
 	ret := KubeletInfo{}
 
-	kubeletProcess, err := LocateKubeletProcess()
+	kubeletProcess, err := LocateKubeletProcess(ctx)
 	if err != nil {
 		return &ret, fmt.Errorf("failed to LocateKubeletProcess: %w", err)
 	}
 
 	// Serivce files
-	ret.ServiceFiles = makeKubeletServiceFilesInfo(int(kubeletProcess.PID))
+	ret.ServiceFiles = makeKubeletServiceFilesInfo(ctx, int(kubeletProcess.PID))
 
 	// Kubelet config
 	configPath := kubeletConfigDefaultPath
@@ -87,59 +120,177 @@ func SenseKubeletInfo() (*KubeletInfo, error) {
 	if ok {
 		configPath = p
 	}
-	configInfo, err := makeHostFileInfo(configPath, true)
+	configInfo, err := makeHostFileInfo(ctx, configPath, true)
 	if err == nil {
 		ret.ConfigFile = configInfo
 	} else {
-		zap.L().Debug("SenseKubeletInfo failed to MakeHostFileInfo for kubelet config",
+		logger(ctx).Debug("SenseKubeletInfo failed to MakeHostFileInfo for kubelet config",
 			zap.String("path", configPath),
 			zap.Error(err),
 		)
 	}
 
 	// Kubelet kubeconfig
-	kubeConfigPath := kubeletConfigDefaultPath
-	p, ok = kubeletProcess.GetArg(kubeConfigArgName)
-	if ok {
-		kubeConfigPath = p
-	}
-	kubeConfigInfo, err := makeHostFileInfo(kubeConfigPath, false)
-	if err == nil {
-		ret.KubeConfigFile = kubeConfigInfo
-	} else {
-		zap.L().Debug("SenseKubeletInfo failed to MakeHostFileInfo for kubelet kubeconfig",
-			zap.String("path", kubeConfigPath),
-			zap.Error(err),
-		)
+	kubeConfigPath, kubeConfigSource := resolveKubeletKubeConfig(ctx, kubeletProcess)
+	if kubeConfigPath != "" {
+		kubeConfigInfo, err := makeHostFileInfo(ctx, kubeConfigPath, false)
+		if err == nil {
+			ret.KubeConfigFile = kubeConfigInfo
+			ret.KubeConfigSource = kubeConfigSource
+		} else {
+			logger(ctx).Debug("SenseKubeletInfo failed to MakeHostFileInfo for kubelet kubeconfig",
+				zap.String("path", kubeConfigPath),
+				zap.Error(err),
+			)
+		}
 	}
 
 	// Kubelet client ca certificate
 	caFilePath, ok := kubeletProcess.GetArg(kubeletClientCAArgName)
 	if !ok && configInfo != nil && configInfo.Content != nil {
-		zap.L().Error("extracting kubelet client ca certificate from config")
+		logger(ctx).Error("extracting kubelet client ca certificate from config")
 		extracted, err := kubeletExtractCAFileFromConf(configInfo.Content)
 		if err == nil {
 			caFilePath = extracted
 		}
 	}
 	if caFilePath != "" {
-		caInfo, err := makeHostFileInfo(caFilePath, false)
+		caInfo, err := makeHostFileInfo(ctx, caFilePath, false)
 		if err == nil {
 			ret.ClientCAFile = caInfo
 		} else {
-			zap.L().Debug("SenseKubeletInfo failed to MakeHostFileInfo for client ca file",
+			logger(ctx).Debug("SenseKubeletInfo failed to MakeHostFileInfo for client ca file",
 				zap.String("path", caFilePath),
 				zap.Error(err),
 			)
 		}
 	}
 
+	var configContent []byte
+	if ret.ConfigFile != nil {
+		configContent = ret.ConfigFile.Content
+	}
+	ret.Settings = parseKubeletSettings(ctx, kubeletProcess, configContent)
+	ret.ImageCredentialProviders = senseKubeletCredentialProviders(ctx, kubeletProcess)
+
 	// Cmd line
 	ret.CmdLine = kubeletProcess.RawCmd()
 
 	return &ret, nil
 }
 
+// getKubeletKubeConfigPath returns the kubeconfig path of the running kubelet, or of a distribution default
+func getKubeletKubeConfigPath(ctx context.Context) string {
+	kubeletProcess, err := LocateKubeletProcess(ctx)
+	if err != nil {
+		logger(ctx).Debug("failed to locate kubelet process", zap.Error(err))
+	}
+	kubeConfigPath, _ := resolveKubeletKubeConfig(ctx, kubeletProcess)
+	return kubeConfigPath
+}
+
+// resolveKubeletKubeConfig returns the kubeconfig path of the kubelet and its source. The path is the first
+// kubeconfig of the --kubeconfig flag, the --kubeconfig flag of the environment of the kubelet systemd drop-ins,
+// or the distribution defaults. It returns an empty path if none is found.
+func resolveKubeletKubeConfig(ctx context.Context, kubeletProcess *ProcessDetails) (string, string) {
+	candidates := []struct {
+		path   string
+		source string
+	}{}
+	if kubeletProcess != nil {
+		if p, ok := kubeletProcess.GetArg(kubeConfigArgName); ok && p != "" {
+			candidates = append(candidates, struct{ path, source string }{p, KubeConfigSourceFlag})
+		}
+		if p := getKubeletSystemdArg(ctx, int(kubeletProcess.PID), kubeConfigArgName); p != "" {
+			candidates = append(candidates, struct{ path, source string }{p, KubeConfigSourceSystemd})
+		}
+	}
+	for _, p := range kubeletKubeConfigDefaultPaths {
+		candidates = append(candidates, struct{ path, source string }{p, KubeConfigSourceDefault})
+	}
+
+	for _, candidate := range candidates {
+		if err := validateKubeconfig(ctx, candidate.path); err != nil {
+			logger(ctx).Debug("kubelet kubeconfig candidate skipped",
+				zap.String("path", candidate.path),
+				zap.String("source", candidate.source),
+				zap.Error(err))
+			continue
+		}
+		return candidate.path, candidate.source
+	}
+	return "", ""
+}
+
+// getKubeletSystemdArg returns the value of a kubelet flag set in the environment of the kubelet systemd drop-ins,
+// e.g. Environment="KUBELET_KUBECONFIG_ARGS=--kubeconfig=/etc/kubernetes/kubelet.conf", or in their environment files
+func getKubeletSystemdArg(ctx context.Context, kubeletPid int, argName string) string {
+	serviceFiles, err := getKubeletServiceFiles(ctx, kubeletPid)
+	if err != nil {
+		logger(ctx).Debug("failed to get kubelet service files", zap.Error(err))
+		return ""
+	}
+
+	args := &ProcessDetails{}
+	for _, serviceFile := range serviceFiles {
+		content, err := ReadFileOnHostFileSystem(ctx, serviceFile)
+		if err != nil {
+			continue
+		}
+		for _, directive := range parseUnitFile(content)[systemdServiceSection] {
+			switch directive.name {
+			case systemdEnvironment:
+				args.CmdLine = append(args.CmdLine, environmentArgs(directive.value)...)
+			case systemdEnvironmentFile:
+				// a "-" prefix ignores missing files
+				envContent, err := ReadFileOnHostFileSystem(ctx, strings.TrimPrefix(directive.value, "-"))
+				if err != nil {
+					continue
+				}
+				for _, line := range strings.Split(string(envContent), "\n") {
+					if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+						args.CmdLine = append(args.CmdLine, environmentArgs(line)...)
+					}
+				}
+			}
+		}
+	}
+	value, _ := args.GetArg(argName)
+	return value
+}
+
+// environmentArgs returns the whitespace separated args of the values of environment assignments,
+// e.g. "--kubeconfig=/etc/kubernetes/kubelet.conf" of `"KUBELET_KUBECONFIG_ARGS=--kubeconfig=/etc/kubernetes/kubelet.conf"`
+func environmentArgs(assignments string) []string {
+	args := []string{}
+	for _, field := range strings.Fields(strings.ReplaceAll(assignments, `"`, "")) {
+		if _, value, ok := strings.Cut(field, "="); ok && !strings.HasPrefix(field, "-") {
+			field = value
+		}
+		args = append(args, field)
+	}
+	return args
+}
+
+// validateKubeconfig returns an error if the host file isn't a kubeconfig with a cluster
+func validateKubeconfig(ctx context.Context, kubeconfigPath string) error {
+	content, err := ReadFileOnHostFileSystem(ctx, kubeconfigPath)
+	if err != nil {
+		return err
+	}
+	config := struct {
+		Kind     string        `json:"kind"`
+		Clusters []interface{} `json:"clusters"`
+	}{}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return fmt.Errorf("failed to parse kubeconfig: %w", err)
+	}
+	if (config.Kind != "" && config.Kind != "Config") || len(config.Clusters) == 0 {
+		return fmt.Errorf("not a kubeconfig")
+	}
+	return nil
+}
+
 // kubeletExtractCAFileFromConf extract the client ca file path from kubelet config
 func kubeletExtractCAFileFromConf(content []byte) (string, error) {
 
@@ -169,8 +320,8 @@ func kubeletExtractCAFileFromConf(content []byte) (string, error) {
 
 // Deprecated: use SenseKubeletInfo for more information.
 // Return the content of kubelet config file
-func SenseKubeletConfigurations() ([]byte, error) {
-	kubeletProcess, err := LocateKubeletProcess()
+func SenseKubeletConfigurations(ctx context.Context) ([]byte, error) {
+	kubeletProcess, err := LocateKubeletProcess(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to LocateKubeletProcess: %w", err)
 	}
@@ -179,6 +330,6 @@ func SenseKubeletConfigurations() ([]byte, error) {
 		return nil, fmt.Errorf("in SenseKubeletConfigurations failed to find kubelet config File location")
 	}
 
-	zap.L().Debug("config loaction", zap.String("kubeletConfFileLocation", kubeletConfFileLocation))
-	return ReadKubeletConfig(kubeletConfFileLocation)
+	logger(ctx).Debug("config loaction", zap.String("kubeletConfFileLocation", kubeletConfFileLocation))
+	return ReadKubeletConfig(ctx, kubeletConfFileLocation)
 }
@@ -1,8 +1,11 @@
 package sensor
 
 import (
+	"context"
 	"os"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestLocateKubelet(t *testing.T) {
@@ -60,3 +63,52 @@ func Test_kubeletExtractCAFileFromConf(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveKubeletKubeConfig(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	kubeconfig := []byte("apiVersion: v1\nkind: Config\nclusters:\n- name: default\n  cluster:\n    server: https://10.0.0.1:6443\n")
+	writeFSFile(t, hfs, "/var/lib/kubelet/config.yaml", []byte("kind: KubeletConfiguration\n"))
+	writeFSFile(t, hfs, "/etc/kubernetes/custom.conf", kubeconfig)
+	writeFSFile(t, hfs, "/var/lib/kubelet/kubeconfig", kubeconfig)
+
+	// no kubeconfig is found yet
+	path, source := resolveKubeletKubeConfig(ctx, &ProcessDetails{CmdLine: []string{"/usr/bin/kubelet"}, PID: -1})
+	assert.Equal(t, "/var/lib/kubelet/kubeconfig", path)
+	assert.Equal(t, KubeConfigSourceDefault, source)
+
+	// the flag isn't a kubeconfig, the drop-in environment file sets another
+	writeFSFile(t, hfs, "/etc/systemd/system/kubelet.service.d/10-kubeadm.conf", []byte(`[Service]
+Environment="KUBELET_CONFIG_ARGS=--config=/var/lib/kubelet/config.yaml"
+EnvironmentFile=-/var/lib/kubelet/kubeadm-flags.env
+EnvironmentFile=-/etc/default/kubelet
+ExecStart=
+ExecStart=/usr/bin/kubelet $KUBELET_CONFIG_ARGS $KUBELET_KUBEADM_ARGS
+`))
+	writeFSFile(t, hfs, "/var/lib/kubelet/kubeadm-flags.env", []byte(`KUBELET_KUBEADM_ARGS="--container-runtime-endpoint=unix:///run/containerd/containerd.sock --kubeconfig /etc/kubernetes/custom.conf"`+"\n"))
+	path, source = resolveKubeletKubeConfig(ctx, &ProcessDetails{
+		CmdLine: []string{"/usr/bin/kubelet", "--kubeconfig=/var/lib/kubelet/config.yaml"},
+		PID:     -1,
+	})
+	assert.Equal(t, "/etc/kubernetes/custom.conf", path)
+	assert.Equal(t, KubeConfigSourceSystemd, source)
+
+	// the flag wins
+	path, source = resolveKubeletKubeConfig(ctx, &ProcessDetails{
+		CmdLine: []string{"/usr/bin/kubelet", "--kubeconfig=/var/lib/kubelet/kubeconfig"},
+		PID:     -1,
+	})
+	assert.Equal(t, "/var/lib/kubelet/kubeconfig", path)
+	assert.Equal(t, KubeConfigSourceFlag, source)
+
+	path, source = resolveKubeletKubeConfig(ctx, nil)
+	assert.Equal(t, "/var/lib/kubelet/kubeconfig", path)
+	assert.Equal(t, KubeConfigSourceDefault, source)
+}
+
+func TestEnvironmentArgs(t *testing.T) {
+	assert.Equal(t, []string{"--bootstrap-kubeconfig=/etc/kubernetes/bootstrap-kubelet.conf", "--kubeconfig=/etc/kubernetes/kubelet.conf"},
+		environmentArgs(`"KUBELET_KUBECONFIG_ARGS=--bootstrap-kubeconfig=/etc/kubernetes/bootstrap-kubelet.conf --kubeconfig=/etc/kubernetes/kubelet.conf"`))
+	assert.Equal(t, []string{"--v=2", "--node-ip", "10.0.0.2"}, environmentArgs(`KUBELET_EXTRA_ARGS="--v=2 --node-ip 10.0.0.2"`))
+}
@@ -0,0 +1,120 @@
+package sensor
+
+import (
+	"context"
+	"strconv"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	kubeletAuthorizationModeArg         = "--authorization-mode"
+	kubeletWebhookCacheAuthorizedTTLArg = "--authorization-webhook-cache-authorized-ttl"
+	kubeletWebhookCacheUnauthorizedArg  = "--authorization-webhook-cache-unauthorized-ttl"
+	kubeletEventQPSArg                  = "--event-qps"
+	kubeletMakeIPTablesUtilChainsArg    = "--make-iptables-util-chains"
+	kubeletSeccompDefaultArg            = "--seccomp-default"
+	kubeletRotateCertificatesArg        = "--rotate-certificates"
+	kubeletRotateServerCertificatesArg  = "--rotate-server-certificates"
+	kubeletEnableDebuggingHandlersArg   = "--enable-debugging-handlers"
+)
+
+// KubeletSettings are the authorization and API access settings of the kubelet (CIS 4.2), from its flags, which
+// override its config file. A setting which is set in neither is nil, and has its default value: Webhook (AlwaysAllow
+// without a config file), 5m, 30s, 50, true, false, false, false and true.
+type KubeletSettings struct {
+	// AlwaysAllow or Webhook
+	AuthorizationMode *string `json:"authorizationMode,omitempty"`
+
+	// How long the webhook authorizer caches the authorized and the unauthorized responses
+	WebhookCacheAuthorizedTTL   *string `json:"webhookCacheAuthorizedTTL,omitempty"`
+	WebhookCacheUnauthorizedTTL *string `json:"webhookCacheUnauthorizedTTL,omitempty"`
+
+	// The maximal event creations per second, 0 for unlimited
+	EventRecordQPS *int `json:"eventRecordQPS,omitempty"`
+
+	MakeIPTablesUtilChains *bool `json:"makeIPTablesUtilChains,omitempty"`
+
+	// Whether the containers without a seccomp profile run with the RuntimeDefault profile rather than unconfined
+	SeccompDefault *bool `json:"seccompDefault,omitempty"`
+
+	// Whether the kubelet rotates its client certificate, and requests its serving certificate from the API server
+	RotateCertificates *bool `json:"rotateCertificates,omitempty"`
+	ServerTLSBootstrap *bool `json:"serverTLSBootstrap,omitempty"`
+
+	// Whether the kubelet API serves the exec, attach, port forwarding, logs and profiling handlers
+	EnableDebuggingHandlers *bool `json:"enableDebuggingHandlers,omitempty"`
+}
+
+// kubeletConfigSettings are the settings of the KubeletConfiguration file
+type kubeletConfigSettings struct {
+	Authorization struct {
+		Mode    *string `json:"mode"`
+		Webhook struct {
+			CacheAuthorizedTTL   *string `json:"cacheAuthorizedTTL"`
+			CacheUnauthorizedTTL *string `json:"cacheUnauthorizedTTL"`
+		} `json:"webhook"`
+	} `json:"authorization"`
+	EventRecordQPS          *int  `json:"eventRecordQPS"`
+	MakeIPTablesUtilChains  *bool `json:"makeIPTablesUtilChains"`
+	SeccompDefault          *bool `json:"seccompDefault"`
+	RotateCertificates      *bool `json:"rotateCertificates"`
+	ServerTLSBootstrap      *bool `json:"serverTLSBootstrap"`
+	EnableDebuggingHandlers *bool `json:"enableDebuggingHandlers"`
+}
+
+// parseKubeletSettings returns the settings of the kubelet flags, or else of its config file content (if any)
+func parseKubeletSettings(ctx context.Context, p *ProcessDetails, configContent []byte) *KubeletSettings {
+	ret := &KubeletSettings{}
+	if configContent != nil {
+		config := kubeletConfigSettings{}
+		if err := yaml.Unmarshal(configContent, &config); err != nil {
+			logger(ctx).Warn("failed to parse kubelet config", zap.Error(err))
+		} else {
+			ret.AuthorizationMode = config.Authorization.Mode
+			ret.WebhookCacheAuthorizedTTL = config.Authorization.Webhook.CacheAuthorizedTTL
+			ret.WebhookCacheUnauthorizedTTL = config.Authorization.Webhook.CacheUnauthorizedTTL
+			ret.EventRecordQPS = config.EventRecordQPS
+			ret.MakeIPTablesUtilChains = config.MakeIPTablesUtilChains
+			ret.SeccompDefault = config.SeccompDefault
+			ret.RotateCertificates = config.RotateCertificates
+			ret.ServerTLSBootstrap = config.ServerTLSBootstrap
+			ret.EnableDebuggingHandlers = config.EnableDebuggingHandlers
+		}
+	}
+
+	if val, ok := p.GetArg(kubeletAuthorizationModeArg); ok {
+		ret.AuthorizationMode = &val
+	}
+	if val, ok := p.GetArg(kubeletWebhookCacheAuthorizedTTLArg); ok {
+		ret.WebhookCacheAuthorizedTTL = &val
+	}
+	if val, ok := p.GetArg(kubeletWebhookCacheUnauthorizedArg); ok {
+		ret.WebhookCacheUnauthorizedTTL = &val
+	}
+	if val, ok := p.GetArg(kubeletEventQPSArg); ok {
+		qps, err := strconv.Atoi(val)
+		if err != nil {
+			logger(ctx).Warn("invalid flag value", zap.String("flag", kubeletEventQPSArg), zap.String("value", val))
+		} else {
+			ret.EventRecordQPS = &qps
+		}
+	}
+	if makeChains := getBoolArg(ctx, p, kubeletMakeIPTablesUtilChainsArg); makeChains != nil {
+		ret.MakeIPTablesUtilChains = makeChains
+	}
+	if seccompDefault := getBoolArg(ctx, p, kubeletSeccompDefaultArg); seccompDefault != nil {
+		ret.SeccompDefault = seccompDefault
+	}
+	if rotate := getBoolArg(ctx, p, kubeletRotateCertificatesArg); rotate != nil {
+		ret.RotateCertificates = rotate
+	}
+	if rotate := getBoolArg(ctx, p, kubeletRotateServerCertificatesArg); rotate != nil {
+		ret.ServerTLSBootstrap = rotate
+	}
+	if debugging := getBoolArg(ctx, p, kubeletEnableDebuggingHandlersArg); debugging != nil {
+		ret.EnableDebuggingHandlers = debugging
+	}
+	return ret
+}
@@ -0,0 +1,43 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKubeletSettings(t *testing.T) {
+	config := []byte(`apiVersion: kubelet.config.k8s.io/v1beta1
+kind: KubeletConfiguration
+authorization:
+  mode: Webhook
+  webhook:
+    cacheAuthorizedTTL: 5m0s
+    cacheUnauthorizedTTL: 30s
+eventRecordQPS: 0
+seccompDefault: true
+rotateCertificates: true
+`)
+	settings := parseKubeletSettings(context.Background(), &ProcessDetails{CmdLine: []string{"/usr/bin/kubelet", "--config=/var/lib/kubelet/config.yaml"}}, config)
+	assert.Equal(t, "Webhook", *settings.AuthorizationMode)
+	assert.Equal(t, "5m0s", *settings.WebhookCacheAuthorizedTTL)
+	assert.Equal(t, "30s", *settings.WebhookCacheUnauthorizedTTL)
+	assert.Equal(t, 0, *settings.EventRecordQPS)
+	assert.Nil(t, settings.MakeIPTablesUtilChains)
+	assert.True(t, *settings.SeccompDefault)
+	assert.True(t, *settings.RotateCertificates)
+	assert.Nil(t, settings.ServerTLSBootstrap)
+
+	// the flags override the config file
+	settings = parseKubeletSettings(context.Background(), &ProcessDetails{CmdLine: []string{"/usr/bin/kubelet",
+		"--authorization-mode=AlwaysAllow", "--event-qps", "5", "--make-iptables-util-chains=false", "--authorization-webhook-cache-authorized-ttl=1m", "--seccomp-default=false", "--rotate-server-certificates"}}, config)
+	assert.Equal(t, "AlwaysAllow", *settings.AuthorizationMode)
+	assert.Equal(t, "1m", *settings.WebhookCacheAuthorizedTTL)
+	assert.Equal(t, 5, *settings.EventRecordQPS)
+	assert.False(t, *settings.MakeIPTablesUtilChains)
+	assert.False(t, *settings.SeccompDefault)
+	assert.True(t, *settings.ServerTLSBootstrap)
+
+	assert.Equal(t, &KubeletSettings{}, parseKubeletSettings(context.Background(), &ProcessDetails{CmdLine: []string{"/usr/bin/kubelet"}}, nil))
+}
@@ -0,0 +1,264 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"path"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"gopkg.in/yaml.v3"
+)
+
+// kube-proxy raises the conntrack table size of the kernel (nf_conntrack_max) on start, and the ipvs mode needs the
+// ip_vs modules of its scheduler. A table smaller than kube-proxy expects, or a missing module, drops service traffic
+// without any error in the cluster.
+
+const (
+	kubeProxyConfigArg = "--config"
+
+	kubeProxyModeIPVS = "ipvs"
+
+	// The defaults of kube-proxy
+	kubeProxyDefaultConntrackMaxPerCore = 32768
+	kubeProxyDefaultConntrackMin        = 131072
+	kubeProxyDefaultIPVSScheduler       = "rr"
+
+	conntrackMaxPath     = "/proc/sys/net/netfilter/nf_conntrack_max"
+	conntrackBucketsPath = "/proc/sys/net/netfilter/nf_conntrack_buckets"
+	procModulesPath      = "/proc/modules"
+)
+
+// KubeProxyConntrack holds the conntrack settings of kube-proxy and of the kernel
+type KubeProxyConntrack struct {
+	// The conntrack table size per CPU core and its minimum, 0 per core leaves the kernel setting as is
+	MaxPerCore int `json:"maxPerCore"`
+	Min        int `json:"min"`
+
+	TCPEstablishedTimeout string `json:"tcpEstablishedTimeout,omitempty"`
+	TCPCloseWaitTimeout   string `json:"tcpCloseWaitTimeout,omitempty"`
+
+	// The table size kube-proxy sets, 0 if it doesn't set it
+	ExpectedMax int `json:"expectedMax"`
+
+	// The table size and hash buckets of the kernel, 0 if they can't be read
+	KernelMax     int `json:"kernelMax"`
+	KernelBuckets int `json:"kernelBuckets"`
+}
+
+// KubeProxyIPVS holds the ipvs settings of kube-proxy
+type KubeProxyIPVS struct {
+	Scheduler     string `json:"scheduler"`
+	SyncPeriod    string `json:"syncPeriod,omitempty"`
+	MinSyncPeriod string `json:"minSyncPeriod,omitempty"`
+	StrictARP     bool   `json:"strictARP"`
+}
+
+// KernelModule is the availability of a kernel module
+type KernelModule struct {
+	Name string `json:"name"`
+
+	Loaded  bool `json:"loaded"`
+	BuiltIn bool `json:"builtIn"`
+
+	// Whether the module exists in the modules of the running kernel
+	Available bool `json:"available"`
+}
+
+// kubeProxyConfigFile is the KubeProxyConfiguration file, of kubeproxy.config.k8s.io/v1alpha1
+type kubeProxyConfigFile struct {
+	Mode      string `yaml:"mode"`
+	Conntrack struct {
+		MaxPerCore            *int   `yaml:"maxPerCore"`
+		Min                   *int   `yaml:"min"`
+		TCPEstablishedTimeout string `yaml:"tcpEstablishedTimeout"`
+		TCPCloseWaitTimeout   string `yaml:"tcpCloseWaitTimeout"`
+	} `yaml:"conntrack"`
+	IPVS struct {
+		Scheduler     string `yaml:"scheduler"`
+		SyncPeriod    string `yaml:"syncPeriod"`
+		MinSyncPeriod string `yaml:"minSyncPeriod"`
+		StrictARP     bool   `yaml:"strictARP"`
+	} `yaml:"ipvs"`
+}
+
+// parseKubeProxyConfig parses the mode, conntrack and ipvs settings of a KubeProxyConfiguration file
+func parseKubeProxyConfig(content []byte) (string, KubeProxyConntrack, KubeProxyIPVS, error) {
+	file := kubeProxyConfigFile{}
+	if err := yaml.Unmarshal(content, &file); err != nil {
+		return "", KubeProxyConntrack{}, KubeProxyIPVS{}, err
+	}
+	conntrack := KubeProxyConntrack{
+		MaxPerCore:            kubeProxyDefaultConntrackMaxPerCore,
+		Min:                   kubeProxyDefaultConntrackMin,
+		TCPEstablishedTimeout: file.Conntrack.TCPEstablishedTimeout,
+		TCPCloseWaitTimeout:   file.Conntrack.TCPCloseWaitTimeout,
+	}
+	if file.Conntrack.MaxPerCore != nil {
+		conntrack.MaxPerCore = *file.Conntrack.MaxPerCore
+	}
+	if file.Conntrack.Min != nil {
+		conntrack.Min = *file.Conntrack.Min
+	}
+	ipvs := KubeProxyIPVS{
+		Scheduler:     file.IPVS.Scheduler,
+		SyncPeriod:    file.IPVS.SyncPeriod,
+		MinSyncPeriod: file.IPVS.MinSyncPeriod,
+		StrictARP:     file.IPVS.StrictARP,
+	}
+	return file.Mode, conntrack, ipvs, nil
+}
+
+// parseKubeProxyFlags returns the mode, conntrack and ipvs settings of the kube-proxy flags
+func parseKubeProxyFlags(ctx context.Context, proc *ProcessDetails) (string, KubeProxyConntrack, KubeProxyIPVS) {
+	conntrack := KubeProxyConntrack{MaxPerCore: kubeProxyDefaultConntrackMaxPerCore, Min: kubeProxyDefaultConntrackMin}
+	if value, ok := proc.GetArg("--conntrack-max-per-core"); ok {
+		if n, err := strconv.Atoi(value); err == nil {
+			conntrack.MaxPerCore = n
+		}
+	}
+	if value, ok := proc.GetArg("--conntrack-min"); ok {
+		if n, err := strconv.Atoi(value); err == nil {
+			conntrack.Min = n
+		}
+	}
+	conntrack.TCPEstablishedTimeout, _ = proc.GetArg("--conntrack-tcp-timeout-established")
+	conntrack.TCPCloseWaitTimeout, _ = proc.GetArg("--conntrack-tcp-timeout-close-wait")
+
+	ipvs := KubeProxyIPVS{}
+	if strictARP := getBoolArg(ctx, proc, "--ipvs-strict-arp"); strictARP != nil {
+		ipvs.StrictARP = *strictARP
+	}
+	ipvs.Scheduler, _ = proc.GetArg("--ipvs-scheduler")
+	ipvs.SyncPeriod, _ = proc.GetArg("--ipvs-sync-period")
+	ipvs.MinSyncPeriod, _ = proc.GetArg("--ipvs-min-sync-period")
+
+	mode, _ := proc.GetArg("--proxy-mode")
+	return mode, conntrack, ipvs
+}
+
+// readKubeProxyMode returns the proxy mode of kube-proxy, from its config file or else its flags
+func readKubeProxyMode(ctx context.Context, proc *ProcessDetails) string {
+	if configPath, ok := proc.GetArg(kubeProxyConfigArg); ok && configPath != "" {
+		if fi, err := makeContaineredFileInfo(ctx, configPath, true, proc); err == nil {
+			if mode, _, _, err := parseKubeProxyConfig(fi.Content); err == nil {
+				return mode
+			}
+		}
+	}
+	mode, _, _ := parseKubeProxyFlags(ctx, proc)
+	return mode
+}
+
+// expectedConntrackMax returns the conntrack table size kube-proxy sets on a node of `cpus` cores
+func expectedConntrackMax(conntrack KubeProxyConntrack, cpus int) int {
+	if conntrack.MaxPerCore <= 0 {
+		return 0
+	}
+	if size := conntrack.MaxPerCore * cpus; size > conntrack.Min {
+		return size
+	}
+	return conntrack.Min
+}
+
+// readProcSysInt reads an integer kernel parameter, 0 if it can't be read
+func readProcSysInt(ctx context.Context, filePath string) int {
+	content, err := ReadFileOnHostFileSystem(ctx, filePath)
+	if err != nil {
+		logger(ctx).Debug("failed to read kernel parameter", zap.String("path", filePath), zap.Error(err))
+		return 0
+	}
+	n, _ := strconv.Atoi(strings.TrimSpace(string(content)))
+	return n
+}
+
+// kernelModuleNames returns the module names of a modules file (/proc/modules, modules.builtin or modules.dep),
+// whose lines start with the module name or the module path
+func kernelModuleNames(content []byte) map[string]bool {
+	ret := map[string]bool{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		name := path.Base(strings.TrimSuffix(fields[0], ":"))
+		if i := strings.Index(name, ".ko"); i >= 0 {
+			name = name[:i]
+		}
+		ret[strings.ReplaceAll(name, "-", "_")] = true
+	}
+	return ret
+}
+
+// senseKernelModules returns the availability of the kernel modules `names` in the running kernel, nil if none of
+// the modules files can be read
+func senseKernelModules(ctx context.Context, names []string) []KernelModule {
+	readAny := false
+	read := func(filePath string) map[string]bool {
+		content, err := ReadFileOnHostFileSystem(ctx, filePath)
+		if err != nil {
+			logger(ctx).Debug("failed to read kernel modules", zap.String("path", filePath), zap.Error(err))
+			return map[string]bool{}
+		}
+		readAny = true
+		return kernelModuleNames(content)
+	}
+
+	loaded := read(procModulesPath)
+	builtIn, available := map[string]bool{}, map[string]bool{}
+	if release, err := ReadFileOnHostFileSystem(ctx, kernelReleasePath); err == nil {
+		modulesDir := path.Join("/lib/modules", strings.TrimSpace(string(release)))
+		builtIn, available = read(path.Join(modulesDir, "modules.builtin")), read(path.Join(modulesDir, "modules.dep"))
+	}
+	if !readAny {
+		return nil
+	}
+
+	ret := make([]KernelModule, 0, len(names))
+	for _, name := range names {
+		module := KernelModule{Name: name, Loaded: loaded[name], BuiltIn: builtIn[name]}
+		module.Available = module.Loaded || module.BuiltIn || available[name]
+		ret = append(ret, module)
+	}
+	return ret
+}
+
+// senseKubeProxyNetworking sets the mode, conntrack and ipvs settings of kube-proxy, from its config file or else its
+// flags, and the conntrack parameters and modules of the kernel
+func senseKubeProxyNetworking(ctx context.Context, proc *ProcessDetails, info *KubeProxyInfo) {
+	mode, conntrack, ipvs := parseKubeProxyFlags(ctx, proc)
+	if configPath, ok := proc.GetArg(kubeProxyConfigArg); ok && configPath != "" {
+		fi, err := makeContaineredFileInfo(ctx, configPath, true, proc)
+		if err != nil {
+			logger(ctx).Warn("failed to read kube-proxy config", zap.String("path", configPath), zap.Error(err))
+			recordCollectionError(ctx, "read", configPath, err)
+		} else {
+			info.ConfigFile = fi
+			// the flags are ignored when kube-proxy has a config file
+			if configMode, configConntrack, configIPVS, err := parseKubeProxyConfig(fi.Content); err != nil {
+				logger(ctx).Warn("failed to parse kube-proxy config", zap.String("path", configPath), zap.Error(err))
+			} else {
+				mode, conntrack, ipvs = configMode, configConntrack, configIPVS
+			}
+		}
+	}
+
+	conntrack.ExpectedMax = expectedConntrackMax(conntrack, runtime.NumCPU())
+	conntrack.KernelMax = readProcSysInt(ctx, conntrackMaxPath)
+	conntrack.KernelBuckets = readProcSysInt(ctx, conntrackBucketsPath)
+	info.Mode, info.Conntrack = mode, &conntrack
+
+	modules := []string{"nf_conntrack"}
+	if mode == kubeProxyModeIPVS {
+		if ipvs.Scheduler == "" {
+			ipvs.Scheduler = kubeProxyDefaultIPVSScheduler
+		}
+		info.IPVS = &ipvs
+		modules = append(modules, "ip_vs", "ip_vs_"+ipvs.Scheduler)
+	}
+	info.KernelModules = senseKernelModules(ctx, modules)
+}
@@ -0,0 +1,60 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseKubeProxyConfig(t *testing.T) {
+	mode, conntrack, ipvs, err := parseKubeProxyConfig([]byte(`apiVersion: kubeproxy.config.k8s.io/v1alpha1
+kind: KubeProxyConfiguration
+mode: ipvs
+conntrack:
+  maxPerCore: 0
+  tcpEstablishedTimeout: 24h0m0s
+ipvs:
+  scheduler: wrr
+  syncPeriod: 30s
+  strictARP: true
+`))
+	require.NoError(t, err)
+	assert.Equal(t, "ipvs", mode)
+	assert.Equal(t, KubeProxyConntrack{MaxPerCore: 0, Min: 131072, TCPEstablishedTimeout: "24h0m0s"}, conntrack)
+	assert.Equal(t, KubeProxyIPVS{Scheduler: "wrr", SyncPeriod: "30s", StrictARP: true}, ipvs)
+}
+
+func TestParseKubeProxyFlags(t *testing.T) {
+	mode, conntrack, ipvs := parseKubeProxyFlags(context.Background(), &ProcessDetails{CmdLine: []string{"kube-proxy",
+		"--proxy-mode=ipvs", "--conntrack-max-per-core", "65536", "--ipvs-strict-arp", "--ipvs-min-sync-period=5s"}})
+	assert.Equal(t, "ipvs", mode)
+	assert.Equal(t, KubeProxyConntrack{MaxPerCore: 65536, Min: 131072}, conntrack)
+	assert.Equal(t, KubeProxyIPVS{MinSyncPeriod: "5s", StrictARP: true}, ipvs)
+}
+
+func TestExpectedConntrackMax(t *testing.T) {
+	assert.Equal(t, 131072, expectedConntrackMax(KubeProxyConntrack{MaxPerCore: 32768, Min: 131072}, 2))
+	assert.Equal(t, 262144, expectedConntrackMax(KubeProxyConntrack{MaxPerCore: 32768, Min: 131072}, 8))
+	assert.Equal(t, 0, expectedConntrackMax(KubeProxyConntrack{MaxPerCore: 0, Min: 131072}, 8))
+}
+
+func TestSenseKernelModules(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+	assert.Nil(t, senseKernelModules(ctx, []string{"ip_vs"}))
+
+	writeFSFile(t, hfs, kernelReleasePath, []byte("5.15.0-91-generic\n"))
+	writeFSFile(t, hfs, procModulesPath, []byte("nf_conntrack 172032 6 xt_conntrack,ip_vs, Live 0x0000000000000000\nip_vs 176128 6 ip_vs_rr, Live 0x0000000000000000\n"))
+	writeFSFile(t, hfs, "/lib/modules/5.15.0-91-generic/modules.builtin", []byte("kernel/net/ipv4/tcp_cubic.ko\n"))
+	writeFSFile(t, hfs, "/lib/modules/5.15.0-91-generic/modules.dep", []byte(
+		"kernel/net/netfilter/ipvs/ip_vs.ko.zst: kernel/net/netfilter/nf_conntrack.ko.zst\nkernel/net/netfilter/ipvs/ip_vs_wrr.ko.zst: kernel/net/netfilter/ipvs/ip_vs.ko.zst\n"))
+
+	assert.Equal(t, []KernelModule{
+		{Name: "ip_vs", Loaded: true, Available: true},
+		{Name: "ip_vs_wrr", Available: true},
+		{Name: "ip_vs_sh"},
+		{Name: "tcp_cubic", BuiltIn: true, Available: true},
+	}, senseKernelModules(ctx, []string{"ip_vs", "ip_vs_wrr", "ip_vs_sh", "tcp_cubic"}))
+}
@@ -1,6 +1,7 @@
 package sensor
 
 import (
+	"context"
 	"fmt"
 
 	"go.uber.org/zap"
@@ -15,16 +16,30 @@ type KubeProxyInfo struct {
 	// Information about the kubeconfig file of kube-proxy
 	KubeConfigFile *FileInfo `json:"kubeConfigFile,omitempty"`
 
+	// The KubeProxyConfiguration file (--config), nil if kube-proxy is configured by its flags
+	ConfigFile *FileInfo `json:"configFile,omitempty"`
+
+	// The proxy mode, e.g. iptables or ipvs, empty for the default of the platform
+	Mode string `json:"mode,omitempty"`
+
+	Conntrack *KubeProxyConntrack `json:"conntrack,omitempty"`
+
+	// The ipvs settings, nil if the mode isn't ipvs
+	IPVS *KubeProxyIPVS `json:"ipvs,omitempty"`
+
+	// The kernel modules of the conntrack and of the ipvs mode and scheduler
+	KernelModules []KernelModule `json:"kernelModules,omitempty"`
+
 	// Raw cmd line of kubelet process
 	CmdLine string `json:"cmdLine"`
 }
 
 // SenseKubeProxyInfo return `KubeProxyInfo`
-func SenseKubeProxyInfo() (*KubeProxyInfo, error) {
+func SenseKubeProxyInfo(ctx context.Context) (*KubeProxyInfo, error) {
 	ret := KubeProxyInfo{}
 
 	// Get process
-	proc, err := LocateProcessByExecSuffix(kubeProxyExe)
+	proc, err := LocateProcessByExecSuffix(ctx, kubeProxyExe)
 	if err != nil {
 		return &ret, fmt.Errorf("failed to locate kube-proxy process: %w", err)
 	}
@@ -32,16 +47,18 @@ func SenseKubeProxyInfo() (*KubeProxyInfo, error) {
 	// kubeconfig
 	kubeConfigPath, ok := proc.GetArg(kubeConfigArgName)
 	if ok {
-		kubeConfigInfo, err := makeContaineredFileInfo(kubeConfigPath, false, proc)
+		kubeConfigInfo, err := makeContaineredFileInfo(ctx, kubeConfigPath, false, proc)
 		ret.KubeConfigFile = kubeConfigInfo
 		if err != nil {
-			zap.L().Debug("SenseKubeProxyInfo failed to MakeFileInfo for kube-proxy kubeconfig",
+			logger(ctx).Debug("SenseKubeProxyInfo failed to MakeFileInfo for kube-proxy kubeconfig",
 				zap.String("path", kubeConfigPath),
 				zap.Error(err),
 			)
 		}
 	}
 
+	senseKubeProxyNetworking(ctx, proc, &ret)
+
 	// cmd line
 	ret.CmdLine = proc.RawCmd()
 
@@ -0,0 +1,139 @@
+package sensor
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// The warnings and errors of the sensors are deduplicated: an entry with the same message and fields as a logged
+// entry (e.g. the same unreadable path on every poll) is suppressed within the repeat interval. The first entry
+// after the interval is logged with the number of the suppressed repeats.
+
+const (
+	defaultLogRepeatInterval = 6 * time.Hour
+
+	// Maximal number of the tracked entries, the expired entries are dropped when it's reached
+	maxRepeatedLogs = 10000
+)
+
+var (
+	// Interval of logging repeated entries, 0 disables the deduplication. Accessed atomically since it's reloadable.
+	logRepeatInterval = int64(defaultLogRepeatInterval)
+
+	repeatedLogs = &logDeduplicator{entries: map[string]*repeatedLog{}, suppressed: map[zapcore.Level]int64{}}
+)
+
+// SetLogRepeatInterval sets the interval of logging repeated sensor warnings and errors, 0 disables the deduplication
+func SetLogRepeatInterval(interval time.Duration) {
+	atomic.StoreInt64(&logRepeatInterval, int64(interval))
+}
+
+// SuppressedLogs returns the number of the suppressed repeated log entries, by level
+func SuppressedLogs() map[string]int64 {
+	return repeatedLogs.suppressedCounts()
+}
+
+// repeatedLog is a logged entry, and its repeats since
+type repeatedLog struct {
+	logged     time.Time
+	suppressed int
+}
+
+type logDeduplicator struct {
+	lock       sync.Mutex
+	entries    map[string]*repeatedLog
+	suppressed map[zapcore.Level]int64
+}
+
+// observe returns true if the entry of `key` should be logged, and the number of its repeats suppressed since it
+// was logged
+func (d *logDeduplicator) observe(key string, level zapcore.Level, now time.Time, interval time.Duration) (bool, int) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	entry, ok := d.entries[key]
+	if ok && now.Sub(entry.logged) < interval {
+		entry.suppressed++
+		d.suppressed[level]++
+		return false, 0
+	}
+	if !ok {
+		if len(d.entries) >= maxRepeatedLogs {
+			d.expire(now, interval)
+		}
+		if len(d.entries) >= maxRepeatedLogs {
+			// too many distinct entries to track, log them all
+			return true, 0
+		}
+		entry = &repeatedLog{}
+		d.entries[key] = entry
+	}
+	suppressed := entry.suppressed
+	entry.logged, entry.suppressed = now, 0
+	return true, suppressed
+}
+
+func (d *logDeduplicator) expire(now time.Time, interval time.Duration) {
+	for key, entry := range d.entries {
+		if now.Sub(entry.logged) >= interval {
+			delete(d.entries, key)
+		}
+	}
+}
+
+func (d *logDeduplicator) suppressedCounts() map[string]int64 {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	ret := map[string]int64{}
+	for level, count := range d.suppressed {
+		ret[level.String()] = count
+	}
+	return ret
+}
+
+// dedupCore is a core suppressing the repeated warnings and errors
+type dedupCore struct {
+	zapcore.Core
+}
+
+// dedupLogs is a logger option suppressing the repeated warnings and errors
+var dedupLogs = zap.WrapCore(func(core zapcore.Core) zapcore.Core { return dedupCore{core} })
+
+func (c dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return dedupCore{c.Core.With(fields)}
+}
+
+func (c dedupCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c dedupCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	interval := time.Duration(atomic.LoadInt64(&logRepeatInterval))
+	if entry.Level < zapcore.WarnLevel || interval <= 0 {
+		return c.Core.Write(entry, fields)
+	}
+
+	// the context fields (e.g. the request ID) aren't part of the key, so the repeats of other requests are suppressed
+	encoder := zapcore.NewMapObjectEncoder()
+	for i := range fields {
+		fields[i].AddTo(encoder)
+	}
+	key := fmt.Sprintf("%s|%s|%v", entry.Level, entry.Message, encoder.Fields)
+
+	logged, suppressed := repeatedLogs.observe(key, entry.Level, entry.Time, interval)
+	if !logged {
+		return nil
+	}
+	if suppressed > 0 {
+		fields = append(fields, zap.Int("suppressedRepeats", suppressed))
+	}
+	return c.Core.Write(entry, fields)
+}
@@ -0,0 +1,43 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestLogDeduplication(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+	defer SetLogRepeatInterval(time.Duration(logRepeatInterval))
+	SetLogRepeatInterval(time.Hour)
+	defer func(d *logDeduplicator) { repeatedLogs = d }(repeatedLogs)
+	repeatedLogs = &logDeduplicator{entries: map[string]*repeatedLog{}, suppressed: map[zapcore.Level]int64{}}
+
+	for i := 0; i < 3; i++ {
+		logger(context.Background()).Warn("failed to read file", zap.String("path", "/etc/missing.conf"), zap.Error(errors.New("ENOENT")))
+		logger(context.Background()).Warn("failed to read file", zap.String("path", "/etc/other.conf"))
+		logger(context.Background()).Debug("reading file", zap.String("path", "/etc/missing.conf"))
+	}
+	assert.Equal(t, 5, logs.Len())
+	assert.Equal(t, map[string]int64{"warn": 4}, SuppressedLogs())
+
+	// logged again after the interval, with the number of the suppressed repeats
+	for _, entry := range repeatedLogs.entries {
+		entry.logged = entry.logged.Add(-time.Hour)
+	}
+	logger(context.Background()).Warn("failed to read file", zap.String("path", "/etc/missing.conf"), zap.Error(errors.New("ENOENT")))
+	entries := logs.All()
+	assert.Equal(t, int64(2), entries[len(entries)-1].ContextMap()["suppressedRepeats"])
+
+	// disabled
+	SetLogRepeatInterval(0)
+	logger(context.Background()).Warn("failed to read file", zap.String("path", "/etc/other.conf"))
+	assert.Equal(t, 7, logs.Len())
+}
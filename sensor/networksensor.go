@@ -1,8 +1,13 @@
+//go:build linux
+
 package sensor
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"os"
+	"sort"
 
 	"github.com/weaveworks/procspy"
 	"go.uber.org/zap"
@@ -10,6 +15,7 @@ import (
 
 const (
 	tcpListeningState = 10
+	udpListeningState = 7
 )
 
 var (
@@ -22,6 +28,12 @@ type OpenPortsStatus struct {
 	TcpPorts  []procspy.Connection `json:"tcpPorts"`
 	UdpPorts  []procspy.Connection `json:"udpPorts"`
 	ICMPPorts []procspy.Connection `json:"icmpPorts"`
+
+	// The iptables backends of the node, kube-proxy and the CNI, and whether they're compatible
+	IPTablesBackend *IPTablesBackendInfo `json:"iptablesBackend"`
+
+	// The DNS servers running on the node, node-local-dns and CoreDNS
+	DNS *NodeDNSInfo `json:"dns"`
 }
 
 func getOpenedPorts(pathsList []string) ([]procspy.Connection, error) {
@@ -36,32 +48,77 @@ func getOpenedPorts(pathsList []string) ([]procspy.Connection, error) {
 			res = append(res, *c)
 		}
 	}
+	// the kernel lists the sockets by hash bucket
+	sort.Slice(res, func(i, j int) bool {
+		if cmp := bytes.Compare(res[i].LocalAddress, res[j].LocalAddress); cmp != 0 {
+			return cmp < 0
+		}
+		return res[i].LocalPort < res[j].LocalPort
+	})
 	return res, nil
 }
 
-func SenseOpenPorts() (*OpenPortsStatus, error) {
+func SenseOpenPorts(ctx context.Context) (*OpenPortsStatus, error) {
 	// TODO: take process name. walks on ProcNetTCPPaths for each process in the system
 	res := OpenPortsStatus{TcpPorts: make([]procspy.Connection, 0)}
 	// tcp
 	ports, err := getOpenedPorts(ProcNetTCPPaths)
 	if err != nil {
-		zap.L().Error("In SenseOpenPorts", zap.Strings("paths", ProcNetTCPPaths), zap.Error(err))
+		logger(ctx).Error("In SenseOpenPorts", zap.Strings("paths", ProcNetTCPPaths), zap.Error(err))
 	} else {
 		res.TcpPorts = ports
 	}
 	// udp
 	ports, err = getOpenedPorts(ProcNetUDPPaths)
 	if err != nil {
-		zap.L().Error("In SenseOpenPorts", zap.Strings("paths", ProcNetUDPPaths), zap.Error(err))
+		logger(ctx).Error("In SenseOpenPorts", zap.Strings("paths", ProcNetUDPPaths), zap.Error(err))
 	} else {
 		res.UdpPorts = ports
 	}
 	// icmp
 	ports, err = getOpenedPorts(ProcNetICMPPaths)
 	if err != nil {
-		zap.L().Error("In SenseOpenPorts", zap.Strings("paths", ProcNetICMPPaths), zap.Error(err))
+		logger(ctx).Error("In SenseOpenPorts", zap.Strings("paths", ProcNetICMPPaths), zap.Error(err))
 	} else {
 		res.ICMPPorts = ports
 	}
+	res.IPTablesBackend = SenseIPTablesBackend(ctx)
+	res.DNS = senseNodeDNS(ctx)
 	return &res, nil
 }
+
+// senseNodeDNS returns the DNS servers running on the node, with the sockets they listen on in their network
+// namespace
+func senseNodeDNS(ctx context.Context) *NodeDNSInfo {
+	ret := &NodeDNSInfo{Servers: []DNSServer{}}
+	for _, dnsServer := range dnsServers {
+		proc, err := LocateProcessByExecSuffix(ctx, dnsServer.exe)
+		if err != nil {
+			continue
+		}
+		server := makeDNSServer(ctx, proc, dnsServer.kind, dnsServer.corefile)
+		for _, protocol := range []struct {
+			name  string
+			files []string
+			state uint
+		}{
+			{"tcp", []string{"tcp", "tcp6"}, tcpListeningState},
+			{"udp", []string{"udp", "udp6"}, udpListeningState},
+		} {
+			for _, file := range protocol.files {
+				netPath := fmt.Sprintf("/proc/%d/net/%s", proc.PID, file)
+				content, err := ReadFileOnHostFileSystem(ctx, netPath)
+				if err != nil {
+					logger(ctx).Debug("senseNodeDNS failed to read the sockets", zap.String("path", netPath), zap.Error(err))
+					continue
+				}
+				netCons := procspy.NewProcNet(content, protocol.state)
+				for c := netCons.Next(); c != nil; c = netCons.Next() {
+					addDNSListener(&server, protocol.name, c.LocalAddress, int(c.LocalPort))
+				}
+			}
+		}
+		ret.Servers = append(ret.Servers, server)
+	}
+	return ret
+}
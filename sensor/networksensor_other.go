@@ -0,0 +1,13 @@
+//go:build !linux
+
+package sensor
+
+import "context"
+
+// OpenPortsStatus holds the listening sockets of the node, only supported on Linux
+type OpenPortsStatus struct{}
+
+// SenseOpenPorts returns an error, open ports are read from /proc
+func SenseOpenPorts(ctx context.Context) (*OpenPortsStatus, error) {
+	return nil, errNotSupported
+}
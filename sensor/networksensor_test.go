@@ -1,9 +1,12 @@
+//go:build linux
+
 package sensor
 
+import "context"
 import "testing"
 
 func TestSenseOpenPorts(t *testing.T) {
-	_, err := SenseOpenPorts()
+	_, err := SenseOpenPorts(context.Background())
 	if err != nil {
 		t.Errorf("%v", err)
 	}
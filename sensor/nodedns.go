@@ -0,0 +1,263 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// node-local-dns (the node-cache binary) runs in the host network and serves the pods of the node on a link-local
+// address and the cluster DNS service IP, of a dummy interface. Listening on the node addresses (e.g. without a bind
+// directive) exposes the cluster DNS off the node.
+
+// DNS servers
+const (
+	DNSServerNodeLocal = "node-local-dns"
+	DNSServerCoreDNS   = "coredns"
+)
+
+// DNS exposures, from the narrowest to the widest
+const (
+	// Only the node itself can reach the listener, e.g. on a loopback address
+	DNSExposureNode = "node"
+
+	// The pods can reach the listener, e.g. on a link-local address or in a pod network
+	DNSExposurePods = "pods"
+
+	// The listener is on an address of the node (or on all of them), reachable off the node
+	DNSExposureOffNode = "offNode"
+)
+
+const (
+	dnsDefaultPort = 53
+
+	nodeLocalDNSLocalIPArg = "-localip"
+	corednsConfArg         = "-conf"
+)
+
+var (
+	// The DNS servers, by their executable suffix, and their default Corefile
+	dnsServers = []struct {
+		exe      string
+		kind     string
+		corefile string
+	}{
+		{"/node-cache", DNSServerNodeLocal, "/etc/Corefile"},
+		{"/coredns", DNSServerCoreDNS, "/etc/coredns/Corefile"},
+	}
+
+	dnsExposureRanks = map[string]int{DNSExposureNode: 1, DNSExposurePods: 2, DNSExposureOffNode: 3}
+)
+
+// NodeDNSInfo holds the DNS servers running on the node
+type NodeDNSInfo struct {
+	Servers []DNSServer `json:"servers"`
+}
+
+// DNSServer is a DNS server running on the node, node-local-dns or CoreDNS, and its listeners
+type DNSServer struct {
+	// One of DNSServer*
+	Kind string `json:"kind"`
+
+	PID int32 `json:"pid"`
+
+	// The Corefile of the server
+	ConfigFile *FileInfo `json:"configFile,omitempty"`
+
+	// The ports of the server blocks of the Corefile
+	Ports []int `json:"ports"`
+
+	// The addresses of the bind directives and of the -localip flag of node-local-dns, empty if it listens on all
+	// the addresses
+	BindAddresses []string `json:"bindAddresses"`
+
+	// Whether the server runs in the host network namespace
+	HostNetwork bool `json:"hostNetwork"`
+
+	// The sockets listening on the ports of the server, in its network namespace
+	Listeners []DNSListener `json:"listeners"`
+
+	// The widest exposure of the listeners, one of DNSExposure*, empty if it has no listeners
+	Exposure string `json:"exposure,omitempty"`
+}
+
+// DNSListener is a listening socket of a DNS server
+type DNSListener struct {
+	// "tcp" or "udp"
+	Protocol string `json:"protocol"`
+	Address  string `json:"address"`
+	Port     int    `json:"port"`
+
+	// One of DNSExposure*
+	Exposure string `json:"exposure"`
+}
+
+// parseCorefile returns the ports of the server blocks of a Corefile, and the addresses of their bind directives
+func parseCorefile(content []byte) ([]int, []string) {
+	ports := []int{}
+	binds := []string{}
+	depth := 0
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(strings.NewReplacer("{", " { ", "}", " } ").Replace(line))
+		if len(fields) == 0 {
+			continue
+		}
+		if depth == 0 && fields[0] != "{" && fields[0] != "}" {
+			// the keys of a server block, e.g. "cluster.local:53 in-addr.arpa:53 {"
+			for _, key := range fields {
+				if key == "{" {
+					break
+				}
+				port := corefileKeyPort(key)
+				if !containsInt(ports, port) {
+					ports = append(ports, port)
+				}
+			}
+		} else if depth == 1 && fields[0] == "bind" {
+			for _, address := range fields[1:] {
+				if address != "{" && address != "}" && !containsString(binds, address) {
+					binds = append(binds, address)
+				}
+			}
+		}
+		for _, field := range fields {
+			switch field {
+			case "{":
+				depth++
+			case "}":
+				depth--
+			}
+		}
+	}
+	sort.Ints(ports)
+	return ports, binds
+}
+
+// corefileKeyPort returns the port of a server block key, e.g. "dns://.:5353"
+func corefileKeyPort(key string) int {
+	if i := strings.Index(key, "://"); i >= 0 {
+		key = key[i+len("://"):]
+	}
+	if i := strings.LastIndex(key, ":"); i >= 0 {
+		if port, err := strconv.Atoi(key[i+1:]); err == nil {
+			return port
+		}
+	}
+	return dnsDefaultPort
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// dnsAddressExposure returns the exposure of a DNS listener address. It returns an empty exposure for the loopback
+// addresses of a pod network namespace.
+func dnsAddressExposure(address net.IP, hostNetwork bool, podAddresses []string) string {
+	switch {
+	case address.IsLoopback():
+		if !hostNetwork {
+			return ""
+		}
+		return DNSExposureNode
+	case !hostNetwork, address.IsLinkLocalUnicast(), containsString(podAddresses, address.String()):
+		return DNSExposurePods
+	}
+	return DNSExposureOffNode
+}
+
+// widerDNSExposure returns the wider of two exposures
+func widerDNSExposure(a, b string) string {
+	if dnsExposureRanks[b] > dnsExposureRanks[a] {
+		return b
+	}
+	return a
+}
+
+// processHostNetwork returns whether a process runs in the host network namespace
+func processHostNetwork(ctx context.Context, pid int32) (bool, error) {
+	hostNS, err := hostFS(ctx).Readlink("/proc/1/ns/net")
+	if err != nil {
+		return false, err
+	}
+	processNS, err := hostFS(ctx).Readlink(fmt.Sprintf("/proc/%d/ns/net", pid))
+	if err != nil {
+		return false, err
+	}
+	return hostNS == processNS, nil
+}
+
+// makeDNSServer returns a DNS server process with its Corefile settings, without its listeners
+func makeDNSServer(ctx context.Context, proc *ProcessDetails, kind, defaultCorefile string) DNSServer {
+	server := DNSServer{Kind: kind, PID: proc.PID, Ports: []int{dnsDefaultPort}, BindAddresses: []string{}, Listeners: []DNSListener{}}
+
+	corefile, ok := proc.GetArg(corednsConfArg)
+	if !ok || corefile == "" {
+		corefile = defaultCorefile
+	}
+	if !path.IsAbs(corefile) {
+		// coredns resolves the Corefile from its working directory, which is usually the root of the container
+		corefile = "/" + corefile
+	}
+	configFile, err := makeContaineredFileInfo(ctx, corefile, true, proc)
+	if err != nil {
+		logger(ctx).Debug("failed to read Corefile", zap.String("kind", kind), zap.String("path", corefile), zap.Error(err))
+	} else {
+		server.ConfigFile = configFile
+		if ports, binds := parseCorefile(configFile.Content); len(ports) > 0 {
+			server.Ports, server.BindAddresses = ports, binds
+		}
+	}
+
+	if kind == DNSServerNodeLocal {
+		localIPs, _ := proc.GetArg(nodeLocalDNSLocalIPArg)
+		for _, address := range strings.Split(localIPs, ",") {
+			if address = strings.TrimSpace(address); address != "" && !containsString(server.BindAddresses, address) {
+				server.BindAddresses = append(server.BindAddresses, address)
+			}
+		}
+	}
+
+	if server.HostNetwork, err = processHostNetwork(ctx, proc.PID); err != nil {
+		logger(ctx).Debug("failed to read the network namespace", zap.Int32("pid", proc.PID), zap.Error(err))
+	}
+	return server
+}
+
+// addDNSListener adds a listening socket to a DNS server, if it's on one of its ports and bind addresses
+func addDNSListener(server *DNSServer, protocol string, address net.IP, port int) {
+	if !containsInt(server.Ports, port) {
+		return
+	}
+	if len(server.BindAddresses) > 0 && !address.IsUnspecified() && !containsString(server.BindAddresses, address.String()) {
+		return
+	}
+	podAddresses := []string{}
+	if server.Kind == DNSServerNodeLocal {
+		// the addresses of its dummy interface
+		podAddresses = server.BindAddresses
+	}
+	exposure := dnsAddressExposure(address, server.HostNetwork, podAddresses)
+	if exposure == "" {
+		return
+	}
+	server.Listeners = append(server.Listeners, DNSListener{Protocol: protocol, Address: address.String(), Port: port, Exposure: exposure})
+	server.Exposure = widerDNSExposure(server.Exposure, exposure)
+}
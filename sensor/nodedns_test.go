@@ -0,0 +1,66 @@
+package sensor
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCorefile(t *testing.T) {
+	ports, binds := parseCorefile([]byte(`cluster.local:53 {
+    errors
+    cache {
+            success 9984 30
+            denial 9984 5
+    }
+    reload
+    loop
+    bind 169.254.20.10 10.96.0.10
+    forward . 10.96.0.11 {
+            force_tcp
+    }
+    health 169.254.20.10:8080
+    }
+in-addr.arpa:53 ip6.arpa:53 {
+    bind 169.254.20.10 10.96.0.10 # the dummy interface
+    forward . __PILLAR__CLUSTER__DNS__
+}
+.:53 {
+    bind 169.254.20.10 10.96.0.10
+}
+dns://metrics.local:9253 { prometheus }
+`))
+	assert.Equal(t, []int{53, 9253}, ports)
+	assert.Equal(t, []string{"169.254.20.10", "10.96.0.10"}, binds)
+
+	ports, binds = parseCorefile([]byte(".:5353 {\n  forward . /etc/resolv.conf\n}\n"))
+	assert.Equal(t, []int{5353}, ports)
+	assert.Empty(t, binds)
+}
+
+func TestAddDNSListener(t *testing.T) {
+	server := DNSServer{Kind: DNSServerNodeLocal, Ports: []int{53}, BindAddresses: []string{"169.254.20.10", "10.96.0.10"}, HostNetwork: true}
+	addDNSListener(&server, "udp", net.ParseIP("169.254.20.10"), 53)
+	addDNSListener(&server, "udp", net.ParseIP("10.96.0.10"), 53)
+	addDNSListener(&server, "udp", net.ParseIP("127.0.0.53"), 53)
+	addDNSListener(&server, "tcp", net.ParseIP("169.254.20.10"), 8080)
+	assert.Equal(t, []DNSListener{
+		{Protocol: "udp", Address: "169.254.20.10", Port: 53, Exposure: DNSExposurePods},
+		{Protocol: "udp", Address: "10.96.0.10", Port: 53, Exposure: DNSExposurePods},
+	}, server.Listeners)
+	assert.Equal(t, DNSExposurePods, server.Exposure)
+
+	// without a bind directive
+	server = DNSServer{Kind: DNSServerNodeLocal, Ports: []int{53}, HostNetwork: true}
+	addDNSListener(&server, "udp", net.ParseIP("127.0.0.53"), 53)
+	addDNSListener(&server, "tcp", net.IPv4zero, 53)
+	assert.Equal(t, DNSExposureOffNode, server.Exposure)
+	assert.Equal(t, DNSExposureNode, server.Listeners[0].Exposure)
+
+	// in a pod network namespace
+	server = DNSServer{Kind: DNSServerCoreDNS, Ports: []int{53}}
+	addDNSListener(&server, "udp", net.IPv6loopback, 53)
+	addDNSListener(&server, "udp", net.IPv6unspecified, 53)
+	assert.Equal(t, []DNSListener{{Protocol: "udp", Address: "::", Port: 53, Exposure: DNSExposurePods}}, server.Listeners)
+}
@@ -0,0 +1,149 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Node roles
+const (
+	// The API server runs on the node
+	NodeRoleControlPlane = "control-plane"
+
+	// etcd runs on the node without the API server, e.g. a member of an external etcd cluster
+	NodeRoleEtcd = "etcd"
+
+	// Only the kubelet runs on the node
+	NodeRoleWorker = "worker"
+
+	// Neither the kubelet, nor the control plane, nor etcd run on the node
+	NodeRoleUnknown = "unknown"
+)
+
+// Node name sources
+const (
+	// The --hostname-override flag of the kubelet
+	NodeNameSourceFlag = "flag"
+
+	// The common name of the client certificate of the kubelet kubeconfig, i.e. "system:node:<node name>"
+	NodeNameSourceCertificate = "certificate"
+
+	// The lowercase hostname, the kubelet default
+	NodeNameSourceHostname = "hostname"
+)
+
+const (
+	kubeletHostnameOverrideArg = "--hostname-override"
+	nodeCommonNamePrefix       = "CN=system:node:"
+)
+
+var (
+	// The components detected by the node identity sensor, by executable suffix
+	nodeComponents = []struct {
+		name string
+		exe  string
+	}{
+		{"kube-apiserver", apiServerExe},
+		{"kube-controller-manager", controllerManagerExe},
+		{"kube-scheduler", schedulerExe},
+		{"etcd", etcdExe},
+		{"kubelet", kubeletProcessSuffix},
+	}
+)
+
+// NodeIdentity holds the names and the role of the node
+type NodeIdentity struct {
+	Hostname string `json:"hostname"`
+
+	// The name the kubelet registers the node with, empty if the kubelet doesn't run
+	NodeName string `json:"nodeName,omitempty"`
+
+	// One of NodeNameSource*
+	NodeNameSource string `json:"nodeNameSource,omitempty"`
+
+	// One of NodeRole*
+	Role string `json:"role"`
+
+	// The running components, e.g. "kubelet" or "kube-apiserver"
+	Components []string `json:"components"`
+}
+
+// SenseNodeIdentity returns the hostname, the node name registered by the kubelet, and the role of the node
+// inferred from the running components
+func SenseNodeIdentity(ctx context.Context) (*NodeIdentity, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	ret := &NodeIdentity{Hostname: hostname, Components: []string{}}
+
+	var kubeletProcess *ProcessDetails
+	for _, component := range nodeComponents {
+		proc, err := LocateProcessByExecSuffix(ctx, component.exe)
+		if err != nil {
+			continue
+		}
+		ret.Components = append(ret.Components, component.name)
+		if component.exe == kubeletProcessSuffix {
+			kubeletProcess = proc
+		}
+	}
+	ret.Role = inferNodeRole(ret.Components)
+
+	if kubeletProcess != nil {
+		ret.NodeName, ret.NodeNameSource = getNodeName(ctx, kubeletProcess, hostname)
+	}
+	return ret, nil
+}
+
+// inferNodeRole returns the role of the node by its running components
+func inferNodeRole(components []string) string {
+	switch {
+	case containsString(components, "kube-apiserver"):
+		return NodeRoleControlPlane
+	case containsString(components, "etcd"):
+		return NodeRoleEtcd
+	case containsString(components, "kubelet"):
+		return NodeRoleWorker
+	}
+	return NodeRoleUnknown
+}
+
+// getNodeName returns the node name of the kubelet and its source
+func getNodeName(ctx context.Context, kubeletProcess *ProcessDetails, hostname string) (string, string) {
+	if name, ok := kubeletProcess.GetArg(kubeletHostnameOverrideArg); ok && name != "" {
+		return strings.ToLower(strings.TrimSpace(name)), NodeNameSourceFlag
+	}
+
+	if kubeconfigPath := getKubeletKubeConfigPath(ctx); kubeconfigPath != "" {
+		info, err := makeKubeconfigInfo(ctx, "kubelet", kubeconfigPath)
+		if err != nil {
+			logger(ctx).Debug("failed to read the kubelet kubeconfig", zap.String("path", kubeconfigPath), zap.Error(err))
+		} else {
+			for _, user := range info.Users {
+				if user.ClientCertificate == nil {
+					continue
+				}
+				if name := nodeNameFromSubject(user.ClientCertificate.Subject); name != "" {
+					return name, NodeNameSourceCertificate
+				}
+			}
+		}
+	}
+
+	return strings.ToLower(hostname), NodeNameSourceHostname
+}
+
+// nodeNameFromSubject returns the node name of a node certificate subject, e.g. "node-1" of
+// "CN=system:node:node-1,O=system:nodes", or empty if it isn't a node certificate
+func nodeNameFromSubject(subject string) string {
+	for _, attribute := range strings.Split(subject, ",") {
+		if strings.HasPrefix(attribute, nodeCommonNamePrefix) {
+			return strings.TrimPrefix(attribute, nodeCommonNamePrefix)
+		}
+	}
+	return ""
+}
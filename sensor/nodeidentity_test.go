@@ -0,0 +1,34 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInferNodeRole(t *testing.T) {
+	assert.Equal(t, NodeRoleControlPlane, inferNodeRole([]string{"kube-apiserver", "etcd", "kubelet"}))
+	assert.Equal(t, NodeRoleEtcd, inferNodeRole([]string{"etcd", "kubelet"}))
+	assert.Equal(t, NodeRoleWorker, inferNodeRole([]string{"kubelet"}))
+	assert.Equal(t, NodeRoleUnknown, inferNodeRole([]string{}))
+}
+
+func TestNodeNameFromSubject(t *testing.T) {
+	assert.Equal(t, "ip-10-0-1-7.ec2.internal", nodeNameFromSubject("CN=system:node:ip-10-0-1-7.ec2.internal,O=system:nodes"))
+	assert.Equal(t, "node-1", nodeNameFromSubject("O=system:nodes,CN=system:node:node-1"))
+	assert.Empty(t, nodeNameFromSubject("CN=kube-apiserver-kubelet-client,O=system:masters"))
+}
+
+func TestGetNodeName(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	name, source := getNodeName(ctx, &ProcessDetails{PID: -1, CmdLine: []string{"kubelet", "--hostname-override=Node-1"}}, "host")
+	assert.Equal(t, "node-1", name)
+	assert.Equal(t, NodeNameSourceFlag, source)
+
+	name, source = getNodeName(ctx, &ProcessDetails{PID: -1, CmdLine: []string{"kubelet"}}, "Host-1")
+	assert.Equal(t, "host-1", name)
+	assert.Equal(t, NodeNameSourceHostname, source)
+}
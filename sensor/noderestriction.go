@@ -0,0 +1,182 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+// A kubelet is restricted to its own node and pods when it authenticates as system:node:<node name> in the
+// system:nodes group, and the API server authorizes it with the Node authorizer and admits its writes with the
+// NodeRestriction admission plugin. Otherwise a compromised node can modify the other nodes and read every secret.
+
+const (
+	apiAuthorizationModeArg          = "--authorization-mode"
+	apiAuthorizationConfigArg        = "--authorization-config"
+	apiEnableAdmissionPluginsArg     = "--enable-admission-plugins"
+	apiDisableAdmissionPluginsArg    = "--disable-admission-plugins"
+	nodeAuthorizerMode               = "Node"
+	nodeRestrictionAdmissionPlugin   = "NodeRestriction"
+	nodesGroup                       = "system:nodes"
+	apiAuthorizationModeDefaultValue = "AlwaysAllow"
+)
+
+// NodeRestrictionInfo holds the evidence of the node identity restriction: the authorizers and admission plugins of
+// the API server, and the client certificate identity of the kubelet
+type NodeRestrictionInfo struct {
+	// The API server settings, nil if the API server doesn't run on the node
+	APIServer *NodeRestrictionAPIServer `json:"apiServer,omitempty"`
+
+	// The kubelet identity, nil if the kubelet doesn't run on the node
+	Kubelet *KubeletIdentity `json:"kubelet,omitempty"`
+}
+
+// NodeRestrictionAPIServer holds the authorization and admission settings of the API server
+type NodeRestrictionAPIServer struct {
+	// The authorizers, in order, of --authorization-mode or of the types of the --authorization-config authorizers
+	AuthorizationModes []string `json:"authorizationModes"`
+
+	// The structured authorization config, if any
+	AuthorizationConfigFile *FileInfo `json:"authorizationConfigFile,omitempty"`
+
+	// The admission plugins of --enable-admission-plugins and --disable-admission-plugins
+	EnabledAdmissionPlugins  []string `json:"enabledAdmissionPlugins"`
+	DisabledAdmissionPlugins []string `json:"disabledAdmissionPlugins"`
+
+	// Whether the Node authorizer and the NodeRestriction admission plugin are enabled
+	NodeAuthorizer  bool `json:"nodeAuthorizer"`
+	NodeRestriction bool `json:"nodeRestriction"`
+}
+
+// KubeletIdentity is the client certificate identity of the kubelet
+type KubeletIdentity struct {
+	KubeConfigFile    string           `json:"kubeConfigFile,omitempty"`
+	ClientCertificate *CertificateInfo `json:"clientCertificate,omitempty"`
+
+	// The user name and groups of the certificate, its common name and organizations
+	Username string   `json:"username,omitempty"`
+	Groups   []string `json:"groups"`
+
+	// Whether the certificate is a node identity, i.e. system:node:<node name> in the system:nodes group
+	NodeIdentity bool `json:"nodeIdentity"`
+
+	// The node name of the identity, and the name the kubelet registers the node with, which should match
+	NodeName           string `json:"nodeName,omitempty"`
+	RegisteredNodeName string `json:"registeredNodeName,omitempty"`
+}
+
+// splitList splits a comma separated flag value
+func splitList(val string) []string {
+	ret := []string{}
+	for _, item := range strings.Split(val, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			ret = append(ret, item)
+		}
+	}
+	return ret
+}
+
+// parseAuthorizationConfigModes returns the types of the authorizers of a structured AuthorizationConfiguration
+func parseAuthorizationConfigModes(content []byte) ([]string, error) {
+	config := struct {
+		Authorizers []struct {
+			Type string `json:"type"`
+		} `json:"authorizers"`
+	}{}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse authorization config: %w", err)
+	}
+	ret := []string{}
+	for _, authorizer := range config.Authorizers {
+		ret = append(ret, authorizer.Type)
+	}
+	return ret, nil
+}
+
+// makeNodeRestrictionAPIServer returns the authorization and admission settings of the API server process
+func makeNodeRestrictionAPIServer(ctx context.Context, p *ProcessDetails) *NodeRestrictionAPIServer {
+	ret := &NodeRestrictionAPIServer{AuthorizationModes: []string{apiAuthorizationModeDefaultValue}}
+	if val, ok := p.GetArg(apiAuthorizationModeArg); ok {
+		ret.AuthorizationModes = splitList(val)
+	}
+	if configPath, ok := p.GetArg(apiAuthorizationConfigArg); ok && configPath != "" {
+		file, err := makeContaineredFileInfo(ctx, configPath, true, p)
+		if err != nil {
+			logger(ctx).Debug("failed to read the API server authorization config", zap.String("path", configPath), zap.Error(err))
+		} else {
+			if modes, err := parseAuthorizationConfigModes(file.Content); err != nil {
+				logger(ctx).Debug("failed to parse the API server authorization config", zap.String("path", configPath), zap.Error(err))
+			} else {
+				ret.AuthorizationModes = modes
+			}
+			file.Content = nil
+			ret.AuthorizationConfigFile = file
+		}
+	}
+	enabled, _ := p.GetArg(apiEnableAdmissionPluginsArg)
+	disabled, _ := p.GetArg(apiDisableAdmissionPluginsArg)
+	ret.EnabledAdmissionPlugins, ret.DisabledAdmissionPlugins = splitList(enabled), splitList(disabled)
+
+	ret.NodeAuthorizer = containsString(ret.AuthorizationModes, nodeAuthorizerMode)
+	// NodeRestriction isn't enabled by default
+	ret.NodeRestriction = containsString(ret.EnabledAdmissionPlugins, nodeRestrictionAdmissionPlugin) &&
+		!containsString(ret.DisabledAdmissionPlugins, nodeRestrictionAdmissionPlugin)
+	return ret
+}
+
+// subjectNames returns the common name and the organizations of a certificate subject
+func subjectNames(subject string) (string, []string) {
+	commonName, organizations := "", []string{}
+	for _, rdn := range strings.FieldsFunc(subject, func(r rune) bool { return r == ',' || r == '+' }) {
+		switch {
+		case strings.HasPrefix(rdn, "CN="):
+			commonName = strings.TrimPrefix(rdn, "CN=")
+		case strings.HasPrefix(rdn, "O="):
+			organizations = append(organizations, strings.TrimPrefix(rdn, "O="))
+		}
+	}
+	return commonName, organizations
+}
+
+// makeKubeletIdentity returns the client certificate identity of the kubelet process
+func makeKubeletIdentity(ctx context.Context, p *ProcessDetails) *KubeletIdentity {
+	ret := &KubeletIdentity{Groups: []string{}}
+	if hostname, err := os.Hostname(); err == nil {
+		ret.RegisteredNodeName, _ = getNodeName(ctx, p, hostname)
+	}
+
+	ret.KubeConfigFile, _ = resolveKubeletKubeConfig(ctx, p)
+	if ret.KubeConfigFile == "" {
+		return ret
+	}
+	kubeconfig, err := makeKubeconfigInfo(ctx, "kubelet", ret.KubeConfigFile)
+	if err != nil {
+		logger(ctx).Debug("failed to analyze the kubelet kubeconfig", zap.String("path", ret.KubeConfigFile), zap.Error(err))
+		return ret
+	}
+	if len(kubeconfig.Users) == 0 || kubeconfig.Users[0].ClientCertificate == nil {
+		return ret
+	}
+	ret.ClientCertificate = kubeconfig.Users[0].ClientCertificate
+	ret.Username, ret.Groups = subjectNames(ret.ClientCertificate.Subject)
+	ret.NodeName = nodeNameFromSubject(ret.ClientCertificate.Subject)
+	ret.NodeIdentity = ret.NodeName != "" && containsString(ret.Groups, nodesGroup)
+	return ret
+}
+
+// SenseNodeRestriction returns the authorizers and admission plugins of the API server, and the client certificate
+// identity of the kubelet, of those running on the node
+func SenseNodeRestriction(ctx context.Context) (*NodeRestrictionInfo, error) {
+	ret := &NodeRestrictionInfo{}
+	if proc, err := LocateProcessByExecSuffix(ctx, apiServerExe); err == nil {
+		ret.APIServer = makeNodeRestrictionAPIServer(ctx, proc)
+	}
+	if proc, err := LocateKubeletProcess(ctx); err == nil {
+		ret.Kubelet = makeKubeletIdentity(ctx, proc)
+	}
+	return ret, nil
+}
@@ -0,0 +1,55 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSubjectNames(t *testing.T) {
+	commonName, organizations := subjectNames("CN=system:node:node-1,O=system:nodes")
+	assert.Equal(t, "system:node:node-1", commonName)
+	assert.Equal(t, []string{"system:nodes"}, organizations)
+
+	commonName, organizations = subjectNames("CN=kubernetes-admin,O=system:masters+O=admins")
+	assert.Equal(t, "kubernetes-admin", commonName)
+	assert.Equal(t, []string{"system:masters", "admins"}, organizations)
+}
+
+func TestSenseNodeRestriction(t *testing.T) {
+	origPlatform := hostPlatform
+	defer func() { hostPlatform = origPlatform }()
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+	hostPlatform = fixturePlatform{root: hfs.Root()}
+	writeFSFile(t, hfs, "/proc/43/cmdline", []byte("/usr/local/bin/kube-apiserver\x00--authorization-mode=Node,RBAC\x00--enable-admission-plugins=NodeRestriction,PodSecurity\x00--disable-admission-plugins=NodeRestriction\x00"))
+
+	info, err := SenseNodeRestriction(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, info.Kubelet)
+	require.NotNil(t, info.APIServer)
+	assert.Equal(t, []string{"Node", "RBAC"}, info.APIServer.AuthorizationModes)
+	assert.True(t, info.APIServer.NodeAuthorizer)
+	assert.Equal(t, []string{"NodeRestriction", "PodSecurity"}, info.APIServer.EnabledAdmissionPlugins)
+	// disabling wins
+	assert.False(t, info.APIServer.NodeRestriction)
+
+	writeFSFile(t, hfs, "/proc/43/cmdline", []byte("/usr/local/bin/kube-apiserver\x00--authorization-config=/etc/kubernetes/authz.yaml\x00"))
+	writeFSFile(t, hfs, "/etc/kubernetes/authz.yaml", []byte(`apiVersion: apiserver.config.k8s.io/v1
+kind: AuthorizationConfiguration
+authorizers:
+- type: Webhook
+  name: webhook
+- type: RBAC
+  name: rbac
+`))
+	info, err = SenseNodeRestriction(ctx)
+	require.NoError(t, err)
+	require.NotNil(t, info.APIServer)
+	assert.Equal(t, []string{"Webhook", "RBAC"}, info.APIServer.AuthorizationModes)
+	require.NotNil(t, info.APIServer.AuthorizationConfigFile)
+	assert.False(t, info.APIServer.NodeAuthorizer)
+	assert.False(t, info.APIServer.NodeRestriction)
+}
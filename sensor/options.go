@@ -0,0 +1,87 @@
+package sensor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrProbesDisabled is returned by the sensors which need to probe, when probing is disabled
+	ErrProbesDisabled = errors.New("probes are disabled")
+)
+
+// SenseOptions are the options of a sensing request
+type SenseOptions struct {
+	// Add the contents of the files to the results
+	Content bool
+
+	// Let the sensors probe the network, e.g. query the time servers or the etcd API
+	Probes bool
+}
+
+type senseOptionsKey struct{}
+
+// WithSenseOptions returns a context of a sensing request with `opts`
+func WithSenseOptions(ctx context.Context, opts SenseOptions) context.Context {
+	return context.WithValue(ctx, senseOptionsKey{}, opts)
+}
+
+// GetSenseOptions returns the options of a sensing request, all enabled by default
+func GetSenseOptions(ctx context.Context) SenseOptions {
+	if opts, ok := ctx.Value(senseOptionsKey{}).(SenseOptions); ok {
+		return opts
+	}
+	return SenseOptions{Content: true, Probes: true}
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context of the request `id`. The logs of the sensors run with it are stamped with the ID.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the ID of the request of the context, empty if there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// Logger returns the logger of the context, stamped with its request ID if set
+func Logger(ctx context.Context) *zap.Logger {
+	if id := RequestID(ctx); id != "" {
+		return zap.L().With(zap.String("requestID", id))
+	}
+	return zap.L()
+}
+
+// StripContent removes the file contents of a JSON encoded result, i.e. the content fields of its `FileInfo` objects
+func StripContent(result json.RawMessage) (json.RawMessage, error) {
+	decoder := json.NewDecoder(bytes.NewReader(result))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	stripContentValue(value)
+	return json.Marshal(value)
+}
+
+func stripContentValue(value interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		delete(v, "content")
+		delete(v, "contentOmitted")
+		for _, item := range v {
+			stripContentValue(item)
+		}
+	case []interface{}:
+		for _, item := range v {
+			stripContentValue(item)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package sensor
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSenseOptions(t *testing.T) {
+	assert.Equal(t, SenseOptions{Content: true, Probes: true}, GetSenseOptions(context.Background()))
+	ctx := WithSenseOptions(context.Background(), SenseOptions{Content: false, Probes: true})
+	assert.Equal(t, SenseOptions{Content: false, Probes: true}, GetSenseOptions(ctx))
+}
+
+func TestStripContent(t *testing.T) {
+	stripped, err := StripContent(json.RawMessage(
+		`{"files": [{"path": "/a", "content": "YQ==", "contentOmitted": true, "size": 12345678901234567}], "content": "b"}`))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"files": [{"path": "/a", "size": 12345678901234567}]}`, string(stripped))
+}
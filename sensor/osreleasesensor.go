@@ -1,6 +1,7 @@
 package sensor
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
@@ -16,26 +17,29 @@ const (
 	seLinuxConfigFileName    = "/etc/selinux/semanage.conf"
 )
 
-func SenseOsRelease() ([]byte, error) {
-	osFileName, err := getOsReleaseFile()
+func SenseOsRelease(ctx context.Context) ([]byte, error) {
+	osFileName, err := getOsReleaseFile(ctx)
 	if err == nil {
-		return ReadFileOnHostFileSystem(path.Join(etcDirName, osFileName))
+		return ReadFileOnHostFileSystem(ctx, path.Join(etcDirName, osFileName))
 	}
-	return []byte{}, fmt.Errorf("failed to find os-release file: %v", err)
+	return []byte{}, fmt.Errorf("failed to find os-release file: %w", err)
 }
 
-func getOsReleaseFile() (string, error) {
-	hostEtcDir := hostPath(etcDirName)
+func getOsReleaseFile(ctx context.Context) (string, error) {
+	hostEtcDir, err := resolveHostPath(ctx, etcDirName)
+	if err != nil {
+		return "", fmt.Errorf("failed to open etc dir: %w", err)
+	}
 	etcDir, err := os.Open(hostEtcDir)
 	if err != nil {
-		return "", fmt.Errorf("failed to open etc dir: %v", err)
+		return "", fmt.Errorf("failed to open etc dir: %w", err)
 	}
 	defer etcDir.Close()
 	var etcSons []string
 	for etcSons, err = etcDir.Readdirnames(100); err == nil; etcSons, err = etcDir.Readdirnames(100) {
 		for idx := range etcSons {
 			if strings.HasSuffix(etcSons[idx], osReleaseFileSuffix) {
-				zap.L().Debug("os release file found", zap.String("filename", etcSons[idx]))
+				logger(ctx).Debug("os release file found", zap.String("filename", etcSons[idx]))
 				return etcSons[idx], nil
 			}
 		}
@@ -43,44 +47,36 @@ func getOsReleaseFile() (string, error) {
 	return "", err
 }
 
-func SenseKernelVersion() ([]byte, error) {
-	return ReadFileOnHostFileSystem(path.Join(procDirName, "version"))
+func SenseKernelVersion(ctx context.Context) ([]byte, error) {
+	return ReadFileOnHostFileSystem(ctx, path.Join(procDirName, "version"))
 }
 
-func getAppArmorStatus() string {
+func getAppArmorStatus(ctx context.Context) string {
 	statusStr := "unloaded"
-	hostAppArmorProfilesFileName := hostPath(appArmorProfilesFileName)
-	profFile, err := os.Open(hostAppArmorProfilesFileName)
+	content, err := ReadFileOnHostFileSystem(ctx, appArmorProfilesFileName)
 	if err == nil {
-		defer profFile.Close()
 		statusStr = "stopped"
-		content, err := ReadFileOnHostFileSystem(appArmorProfilesFileName)
-		if err == nil && len(content) > 0 {
+		if len(content) > 0 {
 			statusStr = string(content)
 		}
 	}
 	return statusStr
 }
 
-func getSELinuxStatus() string {
+func getSELinuxStatus(ctx context.Context) string {
 	statusStr := "not found"
-	hostAppArmorProfilesFileName := hostPath(seLinuxConfigFileName)
-	conFile, err := os.Open(hostAppArmorProfilesFileName)
-	if err == nil {
-		defer conFile.Close()
-		content, err := ReadFileOnHostFileSystem(appArmorProfilesFileName)
-		if err == nil && len(content) > 0 {
-			statusStr = string(content)
-		}
+	content, err := ReadFileOnHostFileSystem(ctx, seLinuxConfigFileName)
+	if err == nil && len(content) > 0 {
+		statusStr = string(content)
 	}
 	return statusStr
 }
 
-func SenseLinuxSecurityHardening() (*LinuxSecurityHardeningStatus, error) {
+func SenseLinuxSecurityHardening(ctx context.Context) (*LinuxSecurityHardeningStatus, error) {
 	res := LinuxSecurityHardeningStatus{}
 
-	res.AppArmor = getAppArmorStatus()
-	res.SeLinux = getSELinuxStatus()
+	res.AppArmor = getAppArmorStatus(ctx)
+	res.SeLinux = getSELinuxStatus(ctx)
 
 	return &res, nil
 }
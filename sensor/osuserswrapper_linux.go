@@ -0,0 +1,25 @@
+package sensor
+
+import (
+	"io"
+	"os/user"
+
+	_ "net"
+	_ "unsafe"
+)
+
+// os/users package handles extracting information from users files (/etc/passwd, /etc/group) but limited to current user root only.
+// Module utilizes unexported (private) functions (using go:linkname), expanding their use for custom root path.
+// NOTE: code requires environment variable CGO_ENABLED = 0
+
+//go:linkname readColonFile os/user.readColonFile
+func readColonFile(r io.Reader, fn lineFunc, readCols int) (v any, err error)
+
+//go:linkname findUserId os/user.findUserId
+func findUserId(uid string, r io.Reader) (*user.User, error)
+
+//go:linkname findGroupId os/user.findGroupId
+func findGroupId(id string, r io.Reader) (*user.Group, error)
+
+//goLlinkname lineFunc os/user lineFunc
+type lineFunc func(line []byte) (v any, err error)
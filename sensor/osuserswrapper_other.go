@@ -0,0 +1,18 @@
+//go:build !linux
+
+package sensor
+
+import (
+	"io"
+	"os/user"
+)
+
+// The users files are read with the unexported functions of os/user, which only the Linux build has
+
+func findUserId(uid string, r io.Reader) (*user.User, error) {
+	return nil, errNotSupported
+}
+
+func findGroupId(id string, r io.Reader) (*user.Group, error) {
+	return nil, errNotSupported
+}
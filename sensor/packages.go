@@ -0,0 +1,368 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Package managers
+const (
+	PackageManagerDpkg = "dpkg"
+	PackageManagerRPM  = "rpm"
+	PackageManagerAPK  = "apk"
+)
+
+const (
+	dpkgStatusPath   = "/var/lib/dpkg/status"
+	apkInstalledPath = "/lib/apk/db/installed"
+	rpmBDBPath       = "/var/lib/rpm/Packages"
+)
+
+var (
+	// RPM databases of newer distributions, which aren't supported
+	rpmUnsupportedDBPaths = []string{
+		"/var/lib/rpm/rpmdb.sqlite",
+		"/usr/lib/sysimage/rpm/rpmdb.sqlite",
+		"/var/lib/rpm/Packages.db",
+		"/usr/lib/sysimage/rpm/Packages.db",
+	}
+
+	// Package databases, in detection order
+	packageDatabases = []struct {
+		manager string
+		path    string
+		parse   func(ctx context.Context, content []byte) ([]Package, error)
+	}{
+		{PackageManagerDpkg, dpkgStatusPath, parseDpkgStatus},
+		{PackageManagerRPM, rpmBDBPath, parseRPMBerkeleyDB},
+		{PackageManagerAPK, apkInstalledPath, parseAPKInstalled},
+	}
+)
+
+// PackageInventory holds the installed OS packages of the node
+type PackageInventory struct {
+	// One of PackageManager*, empty if no package database was found
+	Manager string `json:"manager"`
+
+	// The path of the package database
+	Database string `json:"database,omitempty"`
+
+	// The total number of packages, and the offset of the listed packages when paginated
+	Total  int `json:"total"`
+	Offset int `json:"offset,omitempty"`
+
+	// The installed packages, sorted by name
+	Packages []Package `json:"packages"`
+}
+
+// Package is an installed OS package
+type Package struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Arch    string `json:"arch,omitempty"`
+
+	// The source package name (dpkg), source RPM (rpm) or origin (apk), used by vulnerability databases
+	Source string `json:"source,omitempty"`
+}
+
+// SensePackages returns the installed packages, read from the dpkg, rpm or apk database of the host
+func SensePackages(ctx context.Context) (*PackageInventory, error) {
+	for _, db := range packageDatabases {
+		advanceProgress(ctx, db.path, 0, 0)
+		content, err := ReadFileOnHostFileSystem(ctx, db.path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s database: %w", db.manager, err)
+		}
+
+		packages, err := db.parse(ctx, content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s database: %w", db.manager, err)
+		}
+		sortPackages(packages)
+		return &PackageInventory{Manager: db.manager, Database: db.path, Total: len(packages), Packages: packages}, nil
+	}
+
+	for _, dbPath := range rpmUnsupportedDBPaths {
+		if _, err := statHostFile(ctx, dbPath); err == nil {
+			return nil, fmt.Errorf("unsupported rpm database format: %s", dbPath)
+		}
+	}
+	return &PackageInventory{Packages: []Package{}}, nil
+}
+
+// Page returns the inventory with the packages in [offset, offset+limit). A non-positive limit means no limit.
+func (inventory *PackageInventory) Page(offset, limit int) *PackageInventory {
+	ret := *inventory
+	if offset > len(ret.Packages) {
+		offset = len(ret.Packages)
+	}
+	ret.Offset = offset
+	ret.Packages = ret.Packages[offset:]
+	if limit > 0 && limit < len(ret.Packages) {
+		ret.Packages = ret.Packages[:limit]
+	}
+	return &ret
+}
+
+func sortPackages(packages []Package) {
+	sort.Slice(packages, func(i, j int) bool {
+		if packages[i].Name != packages[j].Name {
+			return packages[i].Name < packages[j].Name
+		}
+		return packages[i].Arch < packages[j].Arch
+	})
+}
+
+// parseDpkgStatus parses the installed packages of a dpkg status file
+func parseDpkgStatus(ctx context.Context, content []byte) ([]Package, error) {
+	ret := []Package{}
+	records := parseControlRecords(content)
+	advanceProgress(ctx, "", 0, len(records))
+	for _, record := range records {
+		advanceProgress(ctx, "", 1, 0)
+		// e.g. "install ok installed", removed packages may keep their config files
+		if !strings.HasSuffix(record["Status"], " installed") || record["Package"] == "" {
+			continue
+		}
+		pkg := Package{Name: record["Package"], Version: record["Version"], Arch: record["Architecture"]}
+		// "Source: name (version)" if the source version differs
+		if source := strings.Fields(record["Source"]); len(source) > 0 {
+			pkg.Source = source[0]
+		}
+		ret = append(ret, pkg)
+	}
+	return ret, nil
+}
+
+// parseAPKInstalled parses the installed packages of an apk database
+func parseAPKInstalled(ctx context.Context, content []byte) ([]Package, error) {
+	ret := []Package{}
+	records := parseAPKRecords(content)
+	advanceProgress(ctx, "", 0, len(records))
+	for _, record := range records {
+		advanceProgress(ctx, "", 1, 0)
+		if record["P"] == "" {
+			continue
+		}
+		ret = append(ret, Package{Name: record["P"], Version: record["V"], Arch: record["A"], Source: record["o"]})
+	}
+	return ret, nil
+}
+
+// parseControlRecords parses the records of a Debian control file, separated by empty lines.
+// Continuation lines are ignored, since only single line fields are used.
+func parseControlRecords(content []byte) []map[string]string {
+	ret := []map[string]string{}
+	record := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			if len(record) > 0 {
+				ret = append(ret, record)
+				record = map[string]string{}
+			}
+			continue
+		}
+		if line[0] == ' ' || line[0] == '\t' {
+			continue
+		}
+		if name, value, ok := strings.Cut(line, ":"); ok {
+			record[name] = strings.TrimSpace(value)
+		}
+	}
+	if len(record) > 0 {
+		ret = append(ret, record)
+	}
+	return ret
+}
+
+// parseAPKRecords parses the records of an apk database, whose lines are "<letter>:<value>"
+func parseAPKRecords(content []byte) []map[string]string {
+	ret := []map[string]string{}
+	record := map[string]string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line == "" {
+			if len(record) > 0 {
+				ret = append(ret, record)
+				record = map[string]string{}
+			}
+			continue
+		}
+		if len(line) >= 2 && line[1] == ':' {
+			// the first occurrence wins, e.g. the package name before its file names
+			if _, ok := record[line[:1]]; !ok {
+				record[line[:1]] = line[2:]
+			}
+		}
+	}
+	if len(record) > 0 {
+		ret = append(ret, record)
+	}
+	return ret
+}
+
+// Berkeley DB hash database layout, see db_page.h
+const (
+	bdbHashMagic      = 0x061561
+	bdbPageHeaderSize = 26
+	bdbPageHashOld    = 2
+	bdbPageOverflow   = 7
+	bdbPageHash       = 13
+	bdbItemOffPage    = 3
+)
+
+// RPM header tags
+const (
+	rpmTagName      = 1000
+	rpmTagVersion   = 1001
+	rpmTagRelease   = 1002
+	rpmTagEpoch     = 1003
+	rpmTagArch      = 1022
+	rpmTagSourceRPM = 1044
+
+	rpmTypeInt32  = 4
+	rpmTypeString = 6
+
+	rpmHeaderEntrySize = 16
+)
+
+// parseRPMBerkeleyDB parses the packages of an rpm Berkeley DB (hash) database.
+// The package headers are stored as off-page items, other items are skipped.
+func parseRPMBerkeleyDB(ctx context.Context, content []byte) ([]Package, error) {
+	if len(content) < 36 {
+		return nil, errors.New("invalid Berkeley DB file")
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if order.Uint32(content[12:16]) != bdbHashMagic {
+		order = binary.BigEndian
+		if order.Uint32(content[12:16]) != bdbHashMagic {
+			return nil, errors.New("not a Berkeley DB hash file")
+		}
+	}
+	pageSize := int(order.Uint32(content[20:24]))
+	if pageSize < bdbPageHeaderSize || len(content)%pageSize != 0 {
+		return nil, fmt.Errorf("invalid Berkeley DB page size %d", pageSize)
+	}
+	page := func(pgno uint32) []byte {
+		start := int(pgno) * pageSize
+		if pgno == 0 || start+pageSize > len(content) {
+			return nil
+		}
+		return content[start : start+pageSize]
+	}
+
+	ret := []Package{}
+	advanceProgress(ctx, "", 0, len(content)/pageSize-1)
+	for pgno := uint32(1); int(pgno)*pageSize < len(content); pgno++ {
+		advanceProgress(ctx, "", 1, 0)
+		p := page(pgno)
+		if p[25] != bdbPageHash && p[25] != bdbPageHashOld {
+			continue
+		}
+		entries := int(order.Uint16(p[20:22]))
+		// the items are key/data pairs, the data items are at odd indices
+		for i := 1; i < entries; i += 2 {
+			indexOffset := bdbPageHeaderSize + 2*i
+			if indexOffset+2 > len(p) {
+				break
+			}
+			itemOffset := int(order.Uint16(p[indexOffset:]))
+			if itemOffset+12 > len(p) || p[itemOffset] != bdbItemOffPage {
+				continue
+			}
+			header, err := readBDBOverflow(page, order, order.Uint32(p[itemOffset+4:]), int(order.Uint32(p[itemOffset+8:])))
+			if err != nil {
+				return nil, err
+			}
+			pkg, err := parseRPMHeader(header)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, *pkg)
+		}
+	}
+	return ret, nil
+}
+
+// readBDBOverflow reads an off-page item of `length` bytes from its chain of overflow pages
+func readBDBOverflow(page func(pgno uint32) []byte, order binary.ByteOrder, pgno uint32, length int) ([]byte, error) {
+	ret := make([]byte, 0, length)
+	for pgno != 0 && len(ret) < length {
+		p := page(pgno)
+		if p == nil || p[25] != bdbPageOverflow {
+			return nil, fmt.Errorf("invalid overflow page %d", pgno)
+		}
+		// every page adds data, so a cycle of pages ends when the length is reached
+		used := int(order.Uint16(p[22:24]))
+		if used == 0 || bdbPageHeaderSize+used > len(p) {
+			return nil, fmt.Errorf("invalid overflow page %d", pgno)
+		}
+		ret = append(ret, p[bdbPageHeaderSize:bdbPageHeaderSize+used]...)
+		pgno = order.Uint32(p[16:20])
+	}
+	if len(ret) < length {
+		return nil, errors.New("truncated overflow item")
+	}
+	return ret[:length], nil
+}
+
+// parseRPMHeader parses the package fields of an rpm header blob (without the header magic)
+func parseRPMHeader(blob []byte) (*Package, error) {
+	if len(blob) < 8 {
+		return nil, errors.New("invalid rpm header")
+	}
+	indexCount := int(binary.BigEndian.Uint32(blob[0:4]))
+	dataLength := int(binary.BigEndian.Uint32(blob[4:8]))
+	dataStart := 8 + indexCount*rpmHeaderEntrySize
+	if indexCount < 0 || dataLength < 0 || dataStart+dataLength > len(blob) || dataStart < 8 {
+		return nil, errors.New("invalid rpm header size")
+	}
+	data := blob[dataStart : dataStart+dataLength]
+
+	strs := map[uint32]string{}
+	epoch := ""
+	for i := 0; i < indexCount; i++ {
+		entry := blob[8+i*rpmHeaderEntrySize:]
+		tag := binary.BigEndian.Uint32(entry[0:4])
+		dataType := binary.BigEndian.Uint32(entry[4:8])
+		offset := int(binary.BigEndian.Uint32(entry[8:12]))
+		if offset < 0 || offset >= len(data) {
+			continue
+		}
+		switch {
+		case dataType == rpmTypeString:
+			if end := bytes.IndexByte(data[offset:], 0); end >= 0 {
+				strs[tag] = string(data[offset : offset+end])
+			}
+		case tag == rpmTagEpoch && dataType == rpmTypeInt32 && offset+4 <= len(data):
+			epoch = strconv.FormatUint(uint64(binary.BigEndian.Uint32(data[offset:])), 10)
+		}
+	}
+
+	version := strs[rpmTagVersion]
+	if release := strs[rpmTagRelease]; release != "" {
+		version += "-" + release
+	}
+	if epoch != "" && epoch != "0" {
+		version = epoch + ":" + version
+	}
+	return &Package{
+		Name:    strs[rpmTagName],
+		Version: version,
+		Arch:    strs[rpmTagArch],
+		Source:  strs[rpmTagSourceRPM],
+	}, nil
+}
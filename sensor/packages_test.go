@@ -0,0 +1,201 @@
+package sensor
+
+import (
+	"context"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFSFile(t *testing.T, hfs HostFS, filePath string, content []byte) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(hfs.Path(filePath)), 0o755))
+	require.NoError(t, os.WriteFile(hfs.Path(filePath), content, 0o644))
+}
+
+func TestSensePackagesDpkg(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	writeFSFile(t, hfs, dpkgStatusPath, []byte(`Package: openssl
+Status: install ok installed
+Architecture: amd64
+Version: 1.1.1n-0+deb11u3
+Description: Secure Sockets Layer toolkit
+ This package contains the openssl binary.
+
+Package: libc6
+Status: install ok installed
+Architecture: amd64
+Source: glibc (2.31-13+deb11u4)
+Version: 2.31-13+deb11u5
+
+Package: removed
+Status: deinstall ok config-files
+Version: 1.0
+`))
+
+	inventory, err := SensePackages(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, &PackageInventory{
+		Manager:  PackageManagerDpkg,
+		Database: dpkgStatusPath,
+		Total:    2,
+		Packages: []Package{
+			{Name: "libc6", Version: "2.31-13+deb11u5", Arch: "amd64", Source: "glibc"},
+			{Name: "openssl", Version: "1.1.1n-0+deb11u3", Arch: "amd64"},
+		},
+	}, inventory)
+
+	page := inventory.Page(1, 10)
+	assert.Equal(t, 1, page.Offset)
+	assert.Equal(t, 2, page.Total)
+	assert.Equal(t, []Package{{Name: "openssl", Version: "1.1.1n-0+deb11u3", Arch: "amd64"}}, page.Packages)
+	assert.Empty(t, inventory.Page(5, 0).Packages)
+	assert.Len(t, inventory.Page(0, 1).Packages, 1)
+}
+
+func TestSensePackagesAPK(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	writeFSFile(t, hfs, apkInstalledPath, []byte(`C:Q1abc=
+P:musl
+V:1.2.3-r4
+A:x86_64
+o:musl
+F:lib
+R:libc.musl-x86_64.so.1
+
+P:busybox
+V:1.35.0-r29
+A:x86_64
+o:busybox
+`))
+
+	inventory, err := SensePackages(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, PackageManagerAPK, inventory.Manager)
+	assert.Equal(t, []Package{
+		{Name: "busybox", Version: "1.35.0-r29", Arch: "x86_64", Source: "busybox"},
+		{Name: "musl", Version: "1.2.3-r4", Arch: "x86_64", Source: "musl"},
+	}, inventory.Packages)
+}
+
+// appendUint32 appends a big endian uint32 (binary.BigEndian.AppendUint32 requires Go 1.19)
+func appendUint32(b []byte, v uint32) []byte {
+	return append(b, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+// makeRPMHeader encodes an rpm header blob with string tags, and an int32 epoch if not 0
+func makeRPMHeader(strs map[uint32]string, epoch uint32) []byte {
+	index, data := []byte{}, []byte{}
+	for _, tag := range []uint32{rpmTagName, rpmTagVersion, rpmTagRelease, rpmTagArch, rpmTagSourceRPM} {
+		if s, ok := strs[tag]; ok {
+			index = appendUint32(index, tag)
+			index = appendUint32(index, rpmTypeString)
+			index = appendUint32(index, uint32(len(data)))
+			index = appendUint32(index, 1)
+			data = append(append(data, s...), 0)
+		}
+	}
+	if epoch != 0 {
+		for len(data)%4 != 0 {
+			data = append(data, 0)
+		}
+		index = appendUint32(index, rpmTagEpoch)
+		index = appendUint32(index, rpmTypeInt32)
+		index = appendUint32(index, uint32(len(data)))
+		index = appendUint32(index, 1)
+		data = appendUint32(data, epoch)
+	}
+	blob := appendUint32(nil, uint32(len(index)/rpmHeaderEntrySize))
+	blob = appendUint32(blob, uint32(len(data)))
+	return append(append(blob, index...), data...)
+}
+
+// makeRPMBerkeleyDB encodes a Berkeley DB hash file with the headers as off-page items
+func makeRPMBerkeleyDB(pageSize int, headers ...[]byte) []byte {
+	le := binary.LittleEndian
+	meta := make([]byte, pageSize)
+	le.PutUint32(meta[12:], bdbHashMagic)
+	le.PutUint32(meta[20:], uint32(pageSize))
+	pages := [][]byte{meta, make([]byte, pageSize)}
+
+	hashPage := pages[1]
+	hashPage[25] = bdbPageHash
+	le.PutUint16(hashPage[20:], uint16(2*len(headers)))
+	itemOffset := pageSize
+	for i, header := range headers {
+		// the key item
+		itemOffset -= 5
+		hashPage[itemOffset] = 1
+		le.PutUint32(hashPage[itemOffset+1:], uint32(i+1))
+		le.PutUint16(hashPage[bdbPageHeaderSize+4*i:], uint16(itemOffset))
+
+		// the off-page data item, split across overflow pages
+		itemOffset -= 12
+		hashPage[itemOffset] = bdbItemOffPage
+		le.PutUint32(hashPage[itemOffset+4:], uint32(len(pages)))
+		le.PutUint32(hashPage[itemOffset+8:], uint32(len(header)))
+		le.PutUint16(hashPage[bdbPageHeaderSize+4*i+2:], uint16(itemOffset))
+		for len(header) > 0 {
+			overflow := make([]byte, pageSize)
+			overflow[25] = bdbPageOverflow
+			n := copy(overflow[bdbPageHeaderSize:], header)
+			le.PutUint16(overflow[22:], uint16(n))
+			header = header[n:]
+			if len(header) > 0 {
+				le.PutUint32(overflow[16:], uint32(len(pages)+1))
+			}
+			pages = append(pages, overflow)
+		}
+	}
+
+	ret := []byte{}
+	for _, page := range pages {
+		ret = append(ret, page...)
+	}
+	return ret
+}
+
+func TestSensePackagesRPM(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	writeFSFile(t, hfs, rpmBDBPath, makeRPMBerkeleyDB(128,
+		makeRPMHeader(map[uint32]string{
+			rpmTagName:      "openssl-libs",
+			rpmTagVersion:   "1.1.1k",
+			rpmTagRelease:   "7.el8_6",
+			rpmTagArch:      "x86_64",
+			rpmTagSourceRPM: "openssl-1.1.1k-7.el8_6.src.rpm",
+		}, 1),
+		makeRPMHeader(map[uint32]string{rpmTagName: "bash", rpmTagVersion: "4.4.20", rpmTagRelease: "4.el8_6", rpmTagArch: "x86_64"}, 0),
+	))
+
+	inventory, err := SensePackages(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, PackageManagerRPM, inventory.Manager)
+	assert.Equal(t, []Package{
+		{Name: "bash", Version: "4.4.20-4.el8_6", Arch: "x86_64"},
+		{Name: "openssl-libs", Version: "1:1.1.1k-7.el8_6", Arch: "x86_64", Source: "openssl-1.1.1k-7.el8_6.src.rpm"},
+	}, inventory.Packages)
+}
+
+func TestSensePackagesUnsupportedRPM(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	inventory, err := SensePackages(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "", inventory.Manager)
+	assert.Empty(t, inventory.Packages)
+
+	writeFSFile(t, hfs, "/var/lib/rpm/rpmdb.sqlite", []byte("SQLite format 3"))
+	_, err = SensePackages(ctx)
+	assert.ErrorContains(t, err, "unsupported rpm database format")
+}
@@ -0,0 +1,29 @@
+package sensor
+
+import "context"
+
+// Platform is the access of the sensors to the facilities of the operating system the sensor runs on. It is
+// implemented for Linux and Windows, and stubbed on the other operating systems (e.g. darwin, for development and
+// embedding), whose methods fail with `errNotSupported`.
+type Platform interface {
+	// Name returns the operating system, e.g. "linux"
+	Name() string
+
+	// LocateProcess returns the first process whose executable name ends with `execSuffix`
+	LocateProcess(ctx context.Context, execSuffix string) (*ProcessDetails, error)
+
+	// ProcessRoot returns the root directory of a process, the host root if processes share it
+	ProcessRoot(pid int32) string
+
+	// ReadXattr returns an extended attribute of a file, e.g. "security.selinux", without following symlinks
+	ReadXattr(filePath, name string) ([]byte, error)
+
+	// Mounts returns the mounts of the host mount namespace
+	Mounts(ctx context.Context) ([]MountInfo, error)
+}
+
+var (
+	// The platform of the sensor.
+	// Defined as var for testing purposes only
+	hostPlatform Platform = newPlatform()
+)
@@ -0,0 +1,59 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"golang.org/x/sys/unix"
+)
+
+// linuxPlatform reads the processes from /proc, and the mounts from the mountinfo of the host init process
+type linuxPlatform struct{}
+
+func newPlatform() Platform {
+	return linuxPlatform{}
+}
+
+// Name implements Platform
+func (linuxPlatform) Name() string { return "linux" }
+
+// LocateProcess implements Platform
+func (linuxPlatform) LocateProcess(ctx context.Context, execSuffix string) (*ProcessDetails, error) {
+	return locateProcfsProcess(ctx, procDirName, execSuffix)
+}
+
+// ProcessRoot implements Platform, the root is read through /proc, so it is the root of the process mount namespace
+func (linuxPlatform) ProcessRoot(pid int32) string {
+	return fmt.Sprintf("/proc/%d/root", pid)
+}
+
+// ReadXattr implements Platform
+func (linuxPlatform) ReadXattr(filePath, name string) ([]byte, error) {
+	size, err := unix.Lgetxattr(filePath, name, nil)
+	for {
+		if err != nil {
+			return nil, err
+		}
+		value := make([]byte, size)
+		size, err = unix.Lgetxattr(filePath, name, value)
+		// the value grew between the calls
+		if errors.Is(err, unix.ERANGE) {
+			size, err = unix.Lgetxattr(filePath, name, nil)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return value[:size], nil
+	}
+}
+
+// Mounts implements Platform
+func (linuxPlatform) Mounts(ctx context.Context) ([]MountInfo, error) {
+	content, err := ReadFileOnHostFileSystem(ctx, hostMountInfoPath)
+	if err != nil {
+		return nil, err
+	}
+	return parseMountInfo(content)
+}
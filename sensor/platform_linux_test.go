@@ -0,0 +1,51 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/sys/unix"
+)
+
+func TestLinuxPlatformReadXattr(t *testing.T) {
+	filePath := path.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(filePath, []byte{}, 0644))
+	if err := unix.Lsetxattr(filePath, "user.test", []byte("value"), 0); err != nil {
+		if errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EPERM) {
+			t.Skip("extended attributes aren't supported by the file system")
+		}
+		require.NoError(t, err)
+	}
+
+	value, err := linuxPlatform{}.ReadXattr(filePath, "user.test")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value"), value)
+
+	_, err = linuxPlatform{}.ReadXattr(filePath, "user.missing")
+	assert.ErrorIs(t, err, unix.ENODATA)
+}
+
+func TestLinuxPlatformProcessRoot(t *testing.T) {
+	assert.Equal(t, "/proc/42/root", linuxPlatform{}.ProcessRoot(42))
+}
+
+func TestMakeFileInfoFIFO(t *testing.T) {
+	SetFileHashing(true)
+	defer SetFileHashing(false)
+	fifoPath := path.Join(t.TempDir(), "fifo")
+	require.NoError(t, unix.Mkfifo(fifoPath, 0600))
+
+	// a FIFO without a writer would block opening it
+	info, err := makeFileInfo(context.Background(), fifoPath, true, true)
+	require.NoError(t, err)
+	assert.Empty(t, info.SHA256)
+	assert.Nil(t, info.Content)
+
+	_, _, err = readFileContent(fifoPath)
+	assert.ErrorIs(t, err, errNotRegularFile)
+}
@@ -0,0 +1,39 @@
+//go:build !linux && !windows
+
+package sensor
+
+import (
+	"context"
+	"runtime"
+)
+
+// stubPlatform lets the sensor build and run on operating systems it doesn't sense, its sensors fail as not
+// supported
+type stubPlatform struct{}
+
+func newPlatform() Platform {
+	return stubPlatform{}
+}
+
+// Name implements Platform
+func (stubPlatform) Name() string { return runtime.GOOS }
+
+// LocateProcess implements Platform
+func (stubPlatform) LocateProcess(ctx context.Context, execSuffix string) (*ProcessDetails, error) {
+	return nil, errNotSupported
+}
+
+// ProcessRoot implements Platform
+func (stubPlatform) ProcessRoot(pid int32) string {
+	return hostFileSystemDefaultLocation
+}
+
+// ReadXattr implements Platform
+func (stubPlatform) ReadXattr(filePath, name string) ([]byte, error) {
+	return nil, errNotSupported
+}
+
+// Mounts implements Platform
+func (stubPlatform) Mounts(ctx context.Context) ([]MountInfo, error) {
+	return nil, errNotSupported
+}
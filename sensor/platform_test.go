@@ -0,0 +1,49 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePlatform struct {
+	processes map[string]*ProcessDetails
+}
+
+func (f fakePlatform) Name() string { return "fake" }
+
+func (f fakePlatform) LocateProcess(ctx context.Context, execSuffix string) (*ProcessDetails, error) {
+	if p, ok := f.processes[execSuffix]; ok {
+		return p, nil
+	}
+	return nil, errNotSupported
+}
+
+func (f fakePlatform) ProcessRoot(pid int32) string {
+	return "/fake"
+}
+
+func (f fakePlatform) ReadXattr(filePath, name string) ([]byte, error) {
+	return nil, errNotSupported
+}
+
+func (f fakePlatform) Mounts(ctx context.Context) ([]MountInfo, error) {
+	return nil, errNotSupported
+}
+
+func TestHostPlatform(t *testing.T) {
+	defer func(orig Platform) { hostPlatform = orig }(hostPlatform)
+	hostPlatform = fakePlatform{processes: map[string]*ProcessDetails{
+		kubeletProcessSuffix: {PID: 7, CmdLine: []string{"kubelet", "--config=/etc/kubelet.yaml"}},
+	}}
+
+	p, err := LocateKubeletProcess(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int32(7), p.PID)
+	assert.Equal(t, "/fake/etc/kubelet.yaml", p.ContaineredPath("/etc/kubelet.yaml"))
+
+	_, err = LocateProcessByExecSuffix(context.Background(), kubeProxyExe)
+	assert.ErrorIs(t, err, errNotSupported)
+}
@@ -0,0 +1,34 @@
+package sensor
+
+import "context"
+
+// windowsPlatform locates the processes with process snapshots and the service manager. Windows processes share
+// the host file system, and it has neither extended attributes nor mount namespaces.
+type windowsPlatform struct{}
+
+func newPlatform() Platform {
+	return windowsPlatform{}
+}
+
+// Name implements Platform
+func (windowsPlatform) Name() string { return "windows" }
+
+// LocateProcess implements Platform
+func (windowsPlatform) LocateProcess(ctx context.Context, execSuffix string) (*ProcessDetails, error) {
+	return locateServiceProcess(ctx, execSuffix)
+}
+
+// ProcessRoot implements Platform
+func (windowsPlatform) ProcessRoot(pid int32) string {
+	return hostFileSystemDefaultLocation
+}
+
+// ReadXattr implements Platform
+func (windowsPlatform) ReadXattr(filePath, name string) ([]byte, error) {
+	return nil, errNotSupported
+}
+
+// Mounts implements Platform
+func (windowsPlatform) Mounts(ctx context.Context) ([]MountInfo, error) {
+	return nil, errNotSupported
+}
@@ -0,0 +1,215 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// The kubelet keeps a directory per pod under <root-dir>/pods/<pod UID>, with a directory per volume plugin and
+// volume (e.g. volumes/kubernetes.io~secret/<volume>). The hostPath volumes aren't kept there, they're read from the
+// bind mounts of the OCI runtime bundles of the pod containers. Only the metadata of the files is read.
+
+const (
+	// The plugin directory of the secret volumes, and the directory the kubelet atomically swaps their files in
+	secretVolumePlugin  = "kubernetes.io~secret"
+	atomicWriterDataDir = "..data"
+)
+
+// PodVolumesInfo holds the volumes of the pods of the kubelet pods directory
+type PodVolumesInfo struct {
+	// The pods directory of the kubelet, under its --root-dir
+	PodsDir string `json:"podsDir"`
+
+	// Whether the containers of the node were read from their OCI runtime bundles, otherwise the hostPath volumes and
+	// the orphaned pods aren't reported
+	ContainersKnown bool `json:"containersKnown"`
+
+	Pods []PodVolumes `json:"pods"`
+}
+
+// PodVolumes holds the volumes of a pod directory
+type PodVolumes struct {
+	UID string `json:"uid"`
+
+	// The pod of the running containers, empty if none runs
+	PodName      string `json:"podName,omitempty"`
+	PodNamespace string `json:"podNamespace,omitempty"`
+
+	// Whether no container of the pod runs but its directory still holds volumes, e.g. of a deleted pod whose
+	// volumes the kubelet failed to clean up
+	Orphaned bool `json:"orphaned"`
+
+	Volumes []PodVolume `json:"volumes"`
+
+	HostPaths []PodHostPath `json:"hostPaths"`
+}
+
+// PodVolume is a volume directory of a pod
+type PodVolume struct {
+	// The volume plugin, e.g. "kubernetes.io/secret" or "kubernetes.io/csi"
+	Plugin string `json:"plugin"`
+	Name   string `json:"name"`
+
+	File *FileInfo `json:"file"`
+
+	// The files of a secret volume which any user on the node can read, through directories any user can traverse
+	WorldReadable []string `json:"worldReadable,omitempty"`
+}
+
+// PodHostPath is a host path a container of the pod mounts
+type PodHostPath struct {
+	Container   string `json:"container"`
+	Source      string `json:"source"`
+	Destination string `json:"destination"`
+	ReadOnly    bool   `json:"readOnly"`
+
+	// Whether the host path enables escaping the container if mounted writable, see the escape surface
+	Sensitive bool `json:"sensitive"`
+}
+
+// othersCanTraverse returns true if any user on the node can traverse a host directory and its parents
+func othersCanTraverse(ctx context.Context, dir string) bool {
+	for current := path.Clean(dir); ; current = path.Dir(current) {
+		info, err := statHostFile(ctx, current)
+		if err != nil || info.Mode().Perm()&0o001 == 0 {
+			return false
+		}
+		if current == "/" || current == "." {
+			return true
+		}
+	}
+}
+
+// worldReadableSecretFiles returns the files of a secret volume which any user can read, following the kubelet
+// atomic writer links
+func worldReadableSecretFiles(ctx context.Context, volumeDir string) []string {
+	dataDir := path.Join(volumeDir, atomicWriterDataDir)
+	if _, err := statHostFile(ctx, dataDir); err != nil {
+		dataDir = volumeDir
+	}
+	if !othersCanTraverse(ctx, dataDir) {
+		return nil
+	}
+	entries, err := readHostDir(ctx, dataDir)
+	if err != nil {
+		logger(ctx).Debug("failed to read secret volume", zap.String("path", dataDir), zap.Error(err))
+		return nil
+	}
+	ret := []string{}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), "..") {
+			continue
+		}
+		filePath := path.Join(dataDir, entry.Name())
+		if info, err := statHostFile(ctx, filePath); err == nil && info.Mode().IsRegular() && info.Mode().Perm()&0o004 != 0 {
+			ret = append(ret, filePath)
+		}
+	}
+	sort.Strings(ret)
+	return ret
+}
+
+// readPodVolumes returns the volume directories of a pod directory
+func readPodVolumes(ctx context.Context, podDir string) []PodVolume {
+	ret := []PodVolume{}
+	volumesDir := path.Join(podDir, "volumes")
+	plugins, err := readHostDir(ctx, volumesDir)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger(ctx).Debug("failed to read pod volumes", zap.String("path", volumesDir), zap.Error(err))
+		}
+		return ret
+	}
+	for _, plugin := range plugins {
+		pluginDir := path.Join(volumesDir, plugin.Name())
+		volumes, err := readHostDir(ctx, pluginDir)
+		if err != nil {
+			logger(ctx).Debug("failed to read pod volumes", zap.String("path", pluginDir), zap.Error(err))
+			continue
+		}
+		for _, volume := range volumes {
+			volumeDir := path.Join(pluginDir, volume.Name())
+			file, err := makeHostFileStatInfo(ctx, volumeDir)
+			if err != nil {
+				logger(ctx).Debug("failed to stat pod volume", zap.String("path", volumeDir), zap.Error(err))
+				continue
+			}
+			podVolume := PodVolume{Plugin: strings.ReplaceAll(plugin.Name(), "~", "/"), Name: volume.Name(), File: file}
+			if plugin.Name() == secretVolumePlugin {
+				podVolume.WorldReadable = worldReadableSecretFiles(ctx, volumeDir)
+			}
+			ret = append(ret, podVolume)
+		}
+	}
+	return ret
+}
+
+// containerHostPaths returns the host paths the bind mounts of a container mount, other than the files and volumes
+// the kubelet and the runtime manage for the container
+func containerHostPaths(container *ociContainer) []PodHostPath {
+	ret := []PodHostPath{}
+	for _, mount := range container.Spec.Mounts {
+		if mount.Type != "bind" && !containsString(mount.Options, "bind") && !containsString(mount.Options, "rbind") {
+			continue
+		}
+		managed := false
+		for _, prefix := range managedHostPathPrefixes {
+			managed = managed || strings.HasPrefix(path.Clean(mount.Source), prefix)
+		}
+		if managed {
+			continue
+		}
+		ret = append(ret, PodHostPath{
+			Container:   container.Container,
+			Source:      mount.Source,
+			Destination: mount.Destination,
+			ReadOnly:    containsString(mount.Options, "ro"),
+			Sensitive:   isSensitiveHostPath(mount.Source),
+		})
+	}
+	return ret
+}
+
+// SensePodVolumes returns the volumes of the pods of the kubelet pods directory, the host paths their containers
+// mount, and the pod directories which are orphaned
+func SensePodVolumes(ctx context.Context) (*PodVolumesInfo, error) {
+	ret := &PodVolumesInfo{PodsDir: path.Join(getKubeletRootDir(ctx), "pods"), Pods: []PodVolumes{}}
+	entries, err := readHostDir(ctx, ret.PodsDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return ret, nil
+		}
+		return nil, err
+	}
+
+	containers, err := readOCIContainers(ctx)
+	if err != nil {
+		logger(ctx).Debug("SensePodVolumes failed to read the containers", zap.Error(err))
+	}
+	ret.ContainersKnown = len(containers) > 0
+	podContainers := map[string][]*ociContainer{}
+	for i := range containers {
+		podContainers[containers[i].PodUID] = append(podContainers[containers[i].PodUID], &containers[i])
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		pod := PodVolumes{UID: entry.Name(), Volumes: readPodVolumes(ctx, path.Join(ret.PodsDir, entry.Name())), HostPaths: []PodHostPath{}}
+		for _, container := range podContainers[pod.UID] {
+			pod.PodName, pod.PodNamespace = container.PodName, container.PodNamespace
+			pod.HostPaths = append(pod.HostPaths, containerHostPaths(container)...)
+		}
+		pod.Orphaned = ret.ContainersKnown && len(podContainers[pod.UID]) == 0 && len(pod.Volumes) > 0
+		ret.Pods = append(ret.Pods, pod)
+	}
+	sort.Slice(ret.Pods, func(i, j int) bool { return ret.Pods[i].UID < ret.Pods[j].UID })
+	return ret, nil
+}
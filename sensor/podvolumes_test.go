@@ -0,0 +1,60 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSensePodVolumes(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+	require.NoError(t, os.Chmod(hfs.Root(), 0o755))
+
+	secretDir := "/var/lib/kubelet/pods/0b6c3f7e/volumes/kubernetes.io~secret/db-credentials"
+	writeFSFile(t, hfs, secretDir+"/..2024_01_01_00_00_00.000000000/password", []byte("secret"))
+	require.NoError(t, os.Symlink("..2024_01_01_00_00_00.000000000", hfs.Path(secretDir+"/..data")))
+	writeFSFile(t, hfs, "/var/lib/kubelet/pods/0b6c3f7e/volumes/kubernetes.io~empty-dir/cache/.keep", []byte{})
+	writeFSFile(t, hfs, "/var/lib/kubelet/pods/5d2e9a41/volumes/kubernetes.io~configmap/config/app.yaml", []byte{})
+	writeFSFile(t, hfs, "/run/containerd/io.containerd.runtime.v2.task/k8s.io/abc123/config.json", []byte(`{
+  "mounts": [
+    {"destination": "/host/var/log", "type": "bind", "source": "/var/log", "options": ["rbind", "ro"]},
+    {"destination": "/etc/hosts", "type": "bind", "source": "/var/lib/kubelet/pods/0b6c3f7e/etc-hosts", "options": ["rbind"]},
+    {"destination": "/proc", "type": "proc", "source": "proc"}
+  ],
+  "annotations": {
+    "io.kubernetes.cri.container-type": "container",
+    "io.kubernetes.cri.sandbox-uid": "0b6c3f7e",
+    "io.kubernetes.cri.sandbox-name": "db",
+    "io.kubernetes.cri.sandbox-namespace": "default",
+    "io.kubernetes.cri.container-name": "postgres"
+  }
+}`))
+
+	info, err := SensePodVolumes(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/kubelet/pods", info.PodsDir)
+	assert.True(t, info.ContainersKnown)
+	require.Len(t, info.Pods, 2)
+
+	pod := info.Pods[0]
+	assert.Equal(t, "0b6c3f7e", pod.UID)
+	assert.Equal(t, "db", pod.PodName)
+	assert.False(t, pod.Orphaned)
+	require.Len(t, pod.Volumes, 2)
+	assert.Equal(t, "kubernetes.io/empty-dir", pod.Volumes[0].Plugin)
+	assert.Equal(t, "kubernetes.io/secret", pod.Volumes[1].Plugin)
+	assert.Equal(t, "db-credentials", pod.Volumes[1].Name)
+	assert.Equal(t, []string{secretDir + "/..data/password"}, pod.Volumes[1].WorldReadable)
+	assert.Equal(t, []PodHostPath{{Container: "postgres", Source: "/var/log", Destination: "/host/var/log", ReadOnly: true, Sensitive: false}}, pod.HostPaths)
+
+	assert.Equal(t, "5d2e9a41", info.Pods[1].UID)
+	assert.True(t, info.Pods[1].Orphaned)
+
+	// the secret files aren't reachable through a private pods directory
+	require.NoError(t, os.Chmod(hfs.Path("/var/lib/kubelet/pods"), 0o750))
+	assert.Empty(t, worldReadableSecretFiles(ctx, secretDir))
+}
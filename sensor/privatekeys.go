@@ -0,0 +1,161 @@
+package sensor
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Private key kinds
+const (
+	PrivateKeyPEM              = "pem"
+	PrivateKeyCloudCredentials = "cloudCredentials"
+)
+
+// Private key permission issues
+const (
+	KeyGroupReadable = "groupReadable"
+	KeyWorldReadable = "worldReadable"
+	KeyNonRootOwner  = "nonRootOwner"
+)
+
+const (
+	groupReadablePerm = 0o040
+	worldReadablePerm = 0o004
+
+	// Size of the file head searched for a PEM private key block
+	privateKeyHeadSize = 64 * 1024
+)
+
+var (
+	// Directories searched (recursively) for private keys
+	privateKeyDirs = []string{
+		pkiDir,
+		"/var/lib/kubelet/pki",
+		"/etc/etcd",
+		"/etc/ssl/etcd",
+	}
+
+	// Files holding cloud provider credentials
+	cloudCredentialsFiles = []string{
+		"/etc/kubernetes/azure.json",
+		"/etc/kubernetes/cloud-config",
+		"/etc/kubernetes/cloud.conf",
+		"/root/.aws/credentials",
+		"/root/.config/gcloud/application_default_credentials.json",
+	}
+
+	// Command line arguments of private key files, by process
+	privateKeyArgs = map[string][]string{
+		kubeletProcessSuffix: {"--tls-private-key-file"},
+		etcdExe:              {"--key-file", "--peer-key-file"},
+	}
+)
+
+// PrivateKeyInfo holds the permissions of a private key file
+type PrivateKeyInfo struct {
+	// Information about the key file (without its content)
+	File *FileInfo `json:"file"`
+
+	// One of PrivateKey*
+	Kind string `json:"kind"`
+
+	// The permission issues of the file, one of Key*
+	Issues []string `json:"issues,omitempty"`
+}
+
+// SensePrivateKeys returns a consolidated list of the private keys on the node, sorted by path:
+// the PKI directories of Kubernetes, the kubelet and etcd, the key files of the kubelet and etcd processes,
+// and the cloud provider credentials.
+func SensePrivateKeys(ctx context.Context) ([]PrivateKeyInfo, error) {
+	keys := map[string]string{}
+
+	for _, dir := range privateKeyDirs {
+		err := fs.WalkDir(hostFS(ctx), dir, func(filePath string, d fs.DirEntry, err error) error {
+			if err != nil {
+				logger(ctx).Debug("SensePrivateKeys failed to walk", zap.String("path", filePath), zap.Error(err))
+				recordCollectionError(ctx, "walk", filePath, err)
+				return nil
+			}
+			if d.Type().IsRegular() && isPrivateKeyFile(ctx, filePath) {
+				keys[filePath] = PrivateKeyPEM
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for exe, args := range privateKeyArgs {
+		proc, err := LocateProcessByExecSuffix(ctx, exe)
+		if err != nil {
+			continue
+		}
+		for _, arg := range args {
+			if keyPath, ok := proc.GetArg(arg); ok && keyPath != "" {
+				keys[keyPath] = PrivateKeyPEM
+			}
+		}
+	}
+
+	for _, credentialsPath := range cloudCredentialsFiles {
+		keys[credentialsPath] = PrivateKeyCloudCredentials
+	}
+
+	ret := []PrivateKeyInfo{}
+	for keyPath, kind := range keys {
+		fileInfo, err := makeHostFileInfo(ctx, keyPath, false)
+		if err != nil {
+			logger(ctx).Debug("SensePrivateKeys failed to MakeHostFileInfo", zap.String("path", keyPath), zap.Error(err))
+			recordCollectionError(ctx, "stat", keyPath, err)
+			continue
+		}
+		ret = append(ret, PrivateKeyInfo{File: fileInfo, Kind: kind, Issues: privateKeyIssues(fileInfo)})
+	}
+
+	sort.Slice(ret, func(i, j int) bool { return ret[i].File.Path < ret[j].File.Path })
+	return ret, nil
+}
+
+// isPrivateKeyFile returns true for .key files and files with a PEM private key block
+func isPrivateKeyFile(ctx context.Context, filePath string) bool {
+	if strings.HasSuffix(filePath, ".key") {
+		return true
+	}
+
+	f, err := hostFS(ctx).Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	head, err := io.ReadAll(io.LimitReader(f, privateKeyHeadSize))
+	if err != nil {
+		return false
+	}
+	return bytes.Contains(head, []byte("PRIVATE KEY-----"))
+}
+
+// privateKeyIssues returns the permission issues of a private key file
+func privateKeyIssues(fileInfo *FileInfo) []string {
+	issues := []string{}
+	if fileInfo.Permissions&groupReadablePerm != 0 {
+		issues = append(issues, KeyGroupReadable)
+	}
+	if fileInfo.Permissions&worldReadablePerm != 0 {
+		issues = append(issues, KeyWorldReadable)
+	}
+	if fileInfo.Ownership != nil && fileInfo.Ownership.Err == "" && fileInfo.Ownership.UID != 0 {
+		issues = append(issues, KeyNonRootOwner)
+	}
+	if len(issues) == 0 {
+		return nil
+	}
+	return issues
+}
@@ -0,0 +1,103 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// The status of the sensor process itself, not of the host
+	selfStatusPath = "/proc/self/status"
+
+	// The privilege of running as root
+	PrivilegeRoot = "root"
+)
+
+var (
+	// The capabilities the sensor needs to read the whole host, by bit
+	requiredCapabilities = []struct {
+		bit  uint
+		name string
+	}{
+		{1, "CAP_DAC_OVERRIDE"},
+		{2, "CAP_DAC_READ_SEARCH"},
+		{19, "CAP_SYS_PTRACE"},
+	}
+
+	missingPrivileges     []string
+	missingPrivilegesOnce sync.Once
+)
+
+// Degradation marks a partial sensor result, which the missing privileges of the sensor kept from being collected
+// entirely
+type Degradation struct {
+	// The missing privileges of the sensor, "root" or capabilities, e.g. "CAP_DAC_READ_SEARCH"
+	MissingPrivileges []string `json:"missingPrivileges"`
+
+	// The paths which weren't collected since the permission was denied
+	Blocked []string `json:"blocked"`
+}
+
+// MissingPrivileges returns the privileges the sensor lacks to read the whole host, empty if it is fully privileged
+func MissingPrivileges(ctx context.Context) []string {
+	missingPrivilegesOnce.Do(func() {
+		content, err := os.ReadFile(selfStatusPath)
+		if err != nil {
+			logger(ctx).Warn("failed to read the sensor capabilities", zap.Error(err))
+		}
+		missingPrivileges = getMissingPrivileges(os.Geteuid(), content)
+	})
+	return missingPrivileges
+}
+
+// getMissingPrivileges returns the missing privileges by the effective user id and the effective capabilities of
+// the process status
+func getMissingPrivileges(euid int, status []byte) []string {
+	ret := []string{}
+	if euid != 0 {
+		ret = append(ret, PrivilegeRoot)
+	}
+
+	var effective uint64
+	scanner := bufio.NewScanner(bytes.NewReader(status))
+	for scanner.Scan() {
+		if name, value, ok := strings.Cut(scanner.Text(), ":"); ok && name == "CapEff" {
+			effective, _ = strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+		}
+	}
+	for _, capability := range requiredCapabilities {
+		if effective&(1<<capability.bit) == 0 {
+			ret = append(ret, capability.name)
+		}
+	}
+	return ret
+}
+
+// Degraded returns the degradation of a sensor result by its collection errors, nil if no permission was denied or
+// the sensor is fully privileged
+func Degraded(ctx context.Context, collectionErrors []CollectionError) *Degradation {
+	return makeDegradation(MissingPrivileges(ctx), collectionErrors)
+}
+
+func makeDegradation(missing []string, collectionErrors []CollectionError) *Degradation {
+	if len(missing) == 0 {
+		return nil
+	}
+	blocked := []string{}
+	for _, collectionError := range collectionErrors {
+		if collectionError.Errno == "EACCES" || collectionError.Errno == "EPERM" {
+			blocked = append(blocked, collectionError.Path)
+		}
+	}
+	if len(blocked) == 0 {
+		return nil
+	}
+	return &Degradation{MissingPrivileges: missing, Blocked: blocked}
+}
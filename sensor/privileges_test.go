@@ -0,0 +1,29 @@
+package sensor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetMissingPrivileges(t *testing.T) {
+	// the default capabilities of a container runtime, without CAP_DAC_READ_SEARCH and CAP_SYS_PTRACE
+	status := []byte("Name:\thost-sensor\nCapInh:\t0000000000000000\nCapEff:\t00000000a80425fb\n")
+	assert.Equal(t, []string{"CAP_DAC_READ_SEARCH", "CAP_SYS_PTRACE"}, getMissingPrivileges(0, status))
+	assert.Equal(t, []string{PrivilegeRoot, "CAP_DAC_OVERRIDE", "CAP_DAC_READ_SEARCH", "CAP_SYS_PTRACE"},
+		getMissingPrivileges(1000, []byte("CapEff:\t0000000000000000\n")))
+	assert.Empty(t, getMissingPrivileges(0, []byte("CapEff:\t000001ffffffffff\n")))
+}
+
+func TestMakeDegradation(t *testing.T) {
+	collectionErrors := []CollectionError{
+		{Path: "/etc/kubernetes/admin.conf", Op: "open", Errno: "EACCES"},
+		{Path: "/etc/kubernetes/scheduler.conf", Op: "stat", Errno: "ENOENT"},
+	}
+	assert.Equal(t, &Degradation{MissingPrivileges: []string{PrivilegeRoot}, Blocked: []string{"/etc/kubernetes/admin.conf"}},
+		makeDegradation([]string{PrivilegeRoot}, collectionErrors))
+
+	// fully privileged, or nothing blocked
+	assert.Nil(t, makeDegradation([]string{}, collectionErrors))
+	assert.Nil(t, makeDegradation([]string{PrivilegeRoot}, collectionErrors[1:]))
+}
@@ -1,15 +1,9 @@
 package sensor
 
 import (
-	"bytes"
-	"fmt"
-	"io"
-	"os"
+	"context"
 	"path"
-	"strconv"
 	"strings"
-
-	"go.uber.org/zap"
 )
 
 type ProcessDetails struct {
@@ -18,53 +12,13 @@ type ProcessDetails struct {
 }
 
 // LocateProcessByExecSuffix locates process with executable name ends with `processSuffix`.
-// The first entry at `/proc` that matches the suffix is returned, other process are ignored.
+// The first process that matches the suffix is returned, other process are ignored.
 // It returns a `ProcessDetails` object.
-func LocateProcessByExecSuffix(processSuffix string) (*ProcessDetails, error) {
-	// TODO: consider taking the exec name from /proc/[pid]/exe instead of /proc/[pid]/cmdline
-	procDir, err := os.Open(procDirName)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open processes dir: %v", err)
-	}
-	defer procDir.Close()
-	var pidDirs []string
-	for pidDirs, err = procDir.Readdirnames(100); err == nil; pidDirs, err = procDir.Readdirnames(100) {
-		for pidIdx := range pidDirs {
-			// since processes are about to die in the middle of the loop, we will ignore next errors
-			pid, err := strconv.ParseInt(pidDirs[pidIdx], 10, 0)
-			if err != nil {
-				continue
-			}
-			specificProcessCMD := path.Join(procDirName, pidDirs[pidIdx], "cmdline")
-			cmdLine, err := os.ReadFile(specificProcessCMD)
-			if err != nil {
-				continue
-			}
-			cmdLineSplitted := bytes.Split(cmdLine, []byte{00})
-
-			processNameFromCMD := cmdLineSplitted[0]
-			if len(processNameFromCMD) == 0 {
-				continue
-			}
-			// solve open shift kubelet not start with full path
-			if processNameFromCMD[0] != '/' && processNameFromCMD[0] != '[' {
-				processNameFromCMD = append([]byte{'/'}, processNameFromCMD...)
-			}
-			if bytes.HasSuffix(processNameFromCMD, []byte(processSuffix)) {
-				zap.L().Debug("process found", zap.String("processSuffix", processSuffix),
-					zap.Int64("pid", pid))
-				res := &ProcessDetails{PID: int32(pid), CmdLine: make([]string, 0, len(cmdLineSplitted))}
-				for splitIdx := range cmdLineSplitted {
-					res.CmdLine = append(res.CmdLine, string(cmdLineSplitted[splitIdx]))
-				}
-				return res, nil
-			}
-		}
-	}
-	if err != io.EOF {
-		return nil, fmt.Errorf("failed to read processes dir names: %v", err)
-	}
-	return nil, fmt.Errorf("no process with given suffix found")
+func LocateProcessByExecSuffix(ctx context.Context, processSuffix string) (*ProcessDetails, error) {
+	step := startStep(ctx, "process scan", "process.suffix", processSuffix)
+	proc, err := hostPlatform.LocateProcess(ctx, processSuffix)
+	step.finish(err)
+	return proc, err
 }
 
 // GetArg returns argument value from the process cmdline, and an ok.
@@ -112,7 +66,7 @@ func (p ProcessDetails) RawCmd() string {
 // RootDir returns the root directory of a process.
 // This is useful when dealing with processes that are running inside a container
 func (p ProcessDetails) RootDir() string {
-	return fmt.Sprintf("/proc/%d/root", p.PID)
+	return hostPlatform.ProcessRoot(p.PID)
 }
 
 // ContaineredPath returns path for the file that the process see.
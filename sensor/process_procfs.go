@@ -0,0 +1,62 @@
+package sensor
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// locateProcfsProcess locates the first entry of the proc file system at `procRoot` whose executable name ends with
+// `processSuffix`
+func locateProcfsProcess(ctx context.Context, procRoot, processSuffix string) (*ProcessDetails, error) {
+	// TODO: consider taking the exec name from /proc/[pid]/exe instead of /proc/[pid]/cmdline
+	procDir, err := os.Open(procRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open processes dir: %w", err)
+	}
+	defer procDir.Close()
+	var pidDirs []string
+	for pidDirs, err = procDir.Readdirnames(100); err == nil; pidDirs, err = procDir.Readdirnames(100) {
+		for pidIdx := range pidDirs {
+			// since processes are about to die in the middle of the loop, we will ignore next errors
+			pid, err := strconv.ParseInt(pidDirs[pidIdx], 10, 0)
+			if err != nil {
+				continue
+			}
+			specificProcessCMD := path.Join(procRoot, pidDirs[pidIdx], "cmdline")
+			cmdLine, err := os.ReadFile(specificProcessCMD)
+			if err != nil {
+				continue
+			}
+			cmdLineSplitted := bytes.Split(cmdLine, []byte{00})
+
+			processNameFromCMD := cmdLineSplitted[0]
+			if len(processNameFromCMD) == 0 {
+				continue
+			}
+			// solve open shift kubelet not start with full path
+			if processNameFromCMD[0] != '/' && processNameFromCMD[0] != '[' {
+				processNameFromCMD = append([]byte{'/'}, processNameFromCMD...)
+			}
+			if bytes.HasSuffix(processNameFromCMD, []byte(processSuffix)) {
+				logger(ctx).Debug("process found", zap.String("processSuffix", processSuffix),
+					zap.Int64("pid", pid))
+				res := &ProcessDetails{PID: int32(pid), CmdLine: make([]string, 0, len(cmdLineSplitted))}
+				for splitIdx := range cmdLineSplitted {
+					res.CmdLine = append(res.CmdLine, string(cmdLineSplitted[splitIdx]))
+				}
+				return res, nil
+			}
+		}
+	}
+	if err != io.EOF {
+		return nil, fmt.Errorf("failed to read processes dir names: %v", err)
+	}
+	return nil, fmt.Errorf("no process with given suffix found")
+}
@@ -0,0 +1,112 @@
+package sensor
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"unsafe"
+
+	"go.uber.org/zap"
+	"golang.org/x/sys/windows"
+	winregistry "golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+// Windows nodes run the kubelet and kube-proxy as services named after their executables, registered either with
+// their command line, or wrapped by nssm, which keeps the command line in the registry.
+
+const (
+	nssmExe           = "nssm.exe"
+	nssmParametersKey = `SYSTEM\CurrentControlSet\Services\%s\Parameters`
+)
+
+// locateServiceProcess locates the first process whose executable name ends with `processSuffix`, e.g.
+// "kubelet.exe" of "/kubelet". The command line is read from the service of the process, it is the executable
+// alone if there is none.
+func locateServiceProcess(ctx context.Context, processSuffix string) (*ProcessDetails, error) {
+	snapshot, err := windows.CreateToolhelp32Snapshot(windows.TH32CS_SNAPPROCESS, 0)
+	if err != nil {
+		return nil, fmt.Errorf("failed to snapshot processes: %w", err)
+	}
+	defer windows.CloseHandle(snapshot)
+
+	entry := windows.ProcessEntry32{Size: uint32(unsafe.Sizeof(windows.ProcessEntry32{}))}
+	for err = windows.Process32First(snapshot, &entry); err == nil; err = windows.Process32Next(snapshot, &entry) {
+		exe := windows.UTF16ToString(entry.ExeFile[:])
+		name := strings.TrimSuffix(strings.ToLower(exe), ".exe")
+		if !strings.HasSuffix("/"+name, processSuffix) {
+			continue
+		}
+		logger(ctx).Debug("process found", zap.String("processSuffix", processSuffix),
+			zap.Uint32("pid", entry.ProcessID))
+
+		cmdLine, err := getServiceCommandLine(name, entry.ProcessID)
+		if err != nil {
+			logger(ctx).Debug("failed to get the service command line", zap.String("service", name), zap.Error(err))
+			cmdLine = []string{exe}
+		}
+		return &ProcessDetails{PID: int32(entry.ProcessID), CmdLine: cmdLine}, nil
+	}
+	if err != windows.ERROR_NO_MORE_FILES {
+		return nil, fmt.Errorf("failed to read processes: %w", err)
+	}
+	return nil, fmt.Errorf("no process with given suffix found")
+}
+
+// getServiceCommandLine returns the command line of the service `name`, if it runs as the process `pid`
+func getServiceCommandLine(name string, pid uint32) ([]string, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	service, err := m.OpenService(name)
+	if err != nil {
+		return nil, err
+	}
+	defer service.Close()
+
+	status, err := service.Query()
+	if err != nil {
+		return nil, err
+	}
+	if status.ProcessId != pid {
+		return nil, fmt.Errorf("service runs as process %d", status.ProcessId)
+	}
+	config, err := service.Config()
+	if err != nil {
+		return nil, err
+	}
+	cmdLine, err := windows.DecomposeCommandLine(config.BinaryPathName)
+	if err != nil || len(cmdLine) == 0 {
+		return nil, fmt.Errorf("failed to parse the service command line: %v", err)
+	}
+	if !strings.EqualFold(path.Base(toSlashHostPath(cmdLine[0])), nssmExe) {
+		return cmdLine, nil
+	}
+	return getNssmCommandLine(name)
+}
+
+// getNssmCommandLine returns the command line of the application of an nssm service
+func getNssmCommandLine(name string) ([]string, error) {
+	key, err := winregistry.OpenKey(winregistry.LOCAL_MACHINE, fmt.Sprintf(nssmParametersKey, name), winregistry.QUERY_VALUE)
+	if err != nil {
+		return nil, err
+	}
+	defer key.Close()
+
+	application, _, err := key.GetStringValue("Application")
+	if err != nil {
+		return nil, err
+	}
+	parameters, _, err := key.GetStringValue("AppParameters")
+	if err != nil && err != winregistry.ErrNotExist {
+		return nil, err
+	}
+	args, err := windows.DecomposeCommandLine(parameters)
+	if err != nil {
+		return nil, err
+	}
+	return append([]string{application}, args...), nil
+}
@@ -0,0 +1,84 @@
+package sensor
+
+import (
+	"context"
+	"time"
+)
+
+// The long running sensors (e.g. the directory walks and the package inventory) report their progress, so a slow
+// sensor can be told from a hung one.
+
+// minimal interval between the reported progresses of a sensor run
+const progressReportInterval = 500 * time.Millisecond
+
+// Progress is the progress of a running sensor
+type Progress struct {
+	Sensor string `json:"sensor"`
+
+	// Number of the processed items (e.g. files or packages), and of the expected items, 0 if unknown
+	Processed int `json:"processed"`
+	Total     int `json:"total,omitempty"`
+
+	// The path the sensor is processing
+	CurrentPath string `json:"currentPath,omitempty"`
+
+	// Estimated time left, when the total is known
+	ETA       time.Duration `json:"-"`
+	ETAMillis int64         `json:"etaMillis,omitempty"`
+
+	// Time of the progress
+	Updated time.Time `json:"updated"`
+}
+
+type progressKey struct{}
+
+// WithProgress returns a context whose sensors report their progress to `report`, at most every 500ms.
+// `report` is called by the running sensor, it must not block.
+func WithProgress(ctx context.Context, report func(Progress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, report)
+}
+
+func progressReporter(ctx context.Context) func(Progress) {
+	report, _ := ctx.Value(progressKey{}).(func(Progress))
+	return report
+}
+
+// sensorProgress is the progress of a sensor run
+type sensorProgress struct {
+	report   func(Progress)
+	current  Progress
+	start    time.Time
+	reported time.Time
+}
+
+// advanceProgress counts `items` more processed items of the sensor run of `ctx`, and `total` more expected items
+// (0 if unknown). `path` (if set) is the path it's processing.
+func advanceProgress(ctx context.Context, path string, items, total int) {
+	run := runFromContext(ctx)
+	if run == nil || run.progress == nil {
+		return
+	}
+	run.lock.Lock()
+	progress := run.progress
+	progress.current.Processed += items
+	progress.current.Total += total
+	if path != "" {
+		progress.current.CurrentPath = path
+	}
+	now := time.Now()
+	if now.Sub(progress.reported) < progressReportInterval {
+		run.lock.Unlock()
+		return
+	}
+	progress.reported = now
+	snapshot := progress.current
+	run.lock.Unlock()
+
+	snapshot.Updated = now.UTC()
+	if snapshot.Total > 0 && snapshot.Processed > 0 && snapshot.Processed < snapshot.Total {
+		elapsed := now.Sub(progress.start)
+		snapshot.ETA = time.Duration(float64(elapsed) * float64(snapshot.Total-snapshot.Processed) / float64(snapshot.Processed))
+		snapshot.ETAMillis = snapshot.ETA.Milliseconds()
+	}
+	progress.report(snapshot)
+}
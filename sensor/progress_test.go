@@ -0,0 +1,36 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress(t *testing.T) {
+	advanceProgress(context.Background(), "/var/lib/dpkg/status", 1, 0)
+
+	reported := []Progress{}
+	ctx := WithProgress(context.Background(), func(progress Progress) { reported = append(reported, progress) })
+	Instrument(ctx, "packages", func(ctx context.Context) {
+		advanceProgress(ctx, "/var/lib/dpkg/status", 0, 4)
+		// throttled
+		advanceProgress(ctx, "", 1, 0)
+		run := runFromContext(ctx)
+		run.progress.reported = run.progress.reported.Add(-progressReportInterval)
+		advanceProgress(ctx, "", 1, 0)
+	})
+	if assert.Len(t, reported, 2) {
+		assert.Equal(t, "packages", reported[0].Sensor)
+		assert.Equal(t, 0, reported[0].Processed)
+		assert.Equal(t, 4, reported[0].Total)
+		assert.Zero(t, reported[0].ETA)
+		assert.Equal(t, 2, reported[1].Processed)
+		assert.Equal(t, "/var/lib/dpkg/status", reported[1].CurrentPath)
+		assert.NotZero(t, reported[1].Updated)
+	}
+
+	// not reported without a reporter
+	Instrument(context.Background(), "packages", func(ctx context.Context) { advanceProgress(ctx, "", 1, 0) })
+	assert.Len(t, reported, 2)
+}
@@ -0,0 +1,140 @@
+package sensor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// Sensor senses a single aspect of the node
+type Sensor interface {
+	// Name of the sensor, unique among the registered sensors
+	Name() string
+
+	// Sense returns the JSON encoded result of the sensor
+	Sense(ctx context.Context) (json.RawMessage, error)
+}
+
+var (
+	registry     []Sensor
+	registryLock sync.RWMutex
+)
+
+// The built-in sensors, named after their HTTP endpoints
+func init() {
+	Register(NewSensor("osRelease", func(ctx context.Context) (interface{}, error) {
+		content, err := SenseOsRelease(ctx)
+		return string(content), err
+	}))
+	Register(NewSensor("kernelVersion", func(ctx context.Context) (interface{}, error) {
+		content, err := SenseKernelVersion(ctx)
+		return string(content), err
+	}))
+	Register(NewSensor("linuxSecurityHardening", func(ctx context.Context) (interface{}, error) { return SenseLinuxSecurityHardening(ctx) }))
+	Register(NewSensor("openedPorts", func(ctx context.Context) (interface{}, error) { return SenseOpenPorts(ctx) }))
+	Register(NewSensor("LinuxKernelVariables", func(ctx context.Context) (interface{}, error) { return SenseKernelVariables(ctx) }))
+	Register(NewSensor("kubeletInfo", func(ctx context.Context) (interface{}, error) { return SenseKubeletInfo(ctx) }))
+	Register(NewSensor("kubeProxyInfo", func(ctx context.Context) (interface{}, error) { return SenseKubeProxyInfo(ctx) }))
+	Register(NewSensor("controlPlaneInfo", func(ctx context.Context) (interface{}, error) { return SenseControlPlaneInfo(ctx) }))
+	Register(NewSensor("escapeSurface", func(ctx context.Context) (interface{}, error) { return SenseEscapeSurface(ctx) }))
+	Register(NewSensor("kubeconfigs", func(ctx context.Context) (interface{}, error) { return SenseKubeconfigs(ctx) }))
+	Register(NewSensor("privateKeys", func(ctx context.Context) (interface{}, error) { return SensePrivateKeys(ctx) }))
+	Register(NewSensor("tokens", func(ctx context.Context) (interface{}, error) { return SenseTokens(ctx) }))
+	Register(NewSensor("registryCredentials", func(ctx context.Context) (interface{}, error) { return SenseRegistryCredentials(ctx) }))
+	Register(NewSensor("certificates", func(ctx context.Context) (interface{}, error) { return SenseCertificates(ctx) }))
+	Register(NewSensor("tlsConfigs", func(ctx context.Context) (interface{}, error) { return SenseTLSConfigs(ctx) }))
+	Register(NewSensor("etcdEncryption", func(ctx context.Context) (interface{}, error) { return SenseEtcdEncryption(ctx) }))
+	Register(NewSensor("kubeadmArtifacts", func(ctx context.Context) (interface{}, error) { return SenseKubeadmArtifacts(ctx) }))
+	Register(NewSensor("systemdUnits", func(ctx context.Context) (interface{}, error) { return SenseSystemdUnits(ctx) }))
+	Register(NewSensor("timeSync", func(ctx context.Context) (interface{}, error) { return SenseTimeSync(ctx) }))
+	Register(NewSensor("packages", func(ctx context.Context) (interface{}, error) { return SensePackages(ctx) }))
+	Register(NewSensor("kernelConfig", func(ctx context.Context) (interface{}, error) { return SenseKernelConfig(ctx) }))
+	Register(NewSensor("resourcePressure", func(ctx context.Context) (interface{}, error) { return SenseResourcePressure(ctx) }))
+	Register(NewSensor("devices", func(ctx context.Context) (interface{}, error) { return SenseDevices(ctx) }))
+	Register(NewSensor("containerLogs", func(ctx context.Context) (interface{}, error) { return SenseContainerLogs(ctx) }))
+	Register(NewSensor("imageStores", func(ctx context.Context) (interface{}, error) { return SenseImageStores(ctx) }))
+	Register(NewSensor("staticPods", func(ctx context.Context) (interface{}, error) { return SenseStaticPods(ctx) }))
+	Register(NewSensor("credentialProtection", func(ctx context.Context) (interface{}, error) { return SenseCredentialProtection(ctx) }))
+	Register(NewSensor("nodeIdentity", func(ctx context.Context) (interface{}, error) { return SenseNodeIdentity(ctx) }))
+	Register(NewSensor("windowsSecurityHardening", func(ctx context.Context) (interface{}, error) { return SenseWindowsSecurityHardening(ctx) }))
+	Register(NewSensor("konnectivity", func(ctx context.Context) (interface{}, error) { return SenseKonnectivity(ctx) }))
+	Register(NewSensor("containerdServer", func(ctx context.Context) (interface{}, error) { return SenseContainerdServer(ctx) }))
+	Register(NewSensor("hostInfo", func(ctx context.Context) (interface{}, error) { return SenseHostInfo(ctx) }))
+	Register(NewSensor("runtimeSockets", func(ctx context.Context) (interface{}, error) { return SenseRuntimeSockets(ctx) }))
+	Register(NewSensor("adminTools", func(ctx context.Context) (interface{}, error) { return SenseAdminTools(ctx) }))
+	Register(NewSensor("kernelPatching", func(ctx context.Context) (interface{}, error) { return SenseKernelPatching(ctx) }))
+	Register(NewSensor("accelerators", func(ctx context.Context) (interface{}, error) { return SenseAccelerators(ctx) }))
+	Register(NewSensor("admissionBypass", func(ctx context.Context) (interface{}, error) { return SenseAdmissionBypass(ctx) }))
+	Register(NewSensor("podVolumes", func(ctx context.Context) (interface{}, error) { return SensePodVolumes(ctx) }))
+	Register(NewSensor("seccompProfiles", func(ctx context.Context) (interface{}, error) { return SenseSeccompProfiles(ctx) }))
+	Register(NewSensor("kubeletCertRotation", func(ctx context.Context) (interface{}, error) { return SenseKubeletCertRotation(ctx) }))
+	Register(NewSensor("riskyServices", func(ctx context.Context) (interface{}, error) { return SenseRiskyServices(ctx) }))
+	Register(NewSensor("nodeRestriction", func(ctx context.Context) (interface{}, error) { return SenseNodeRestriction(ctx) }))
+	Register(NewSensor("kubeletDiskGC", func(ctx context.Context) (interface{}, error) { return SenseKubeletDiskGC(ctx) }))
+	Register(NewSensor("containerLogRotation", func(ctx context.Context) (interface{}, error) { return SenseContainerLogRotation(ctx) }))
+}
+
+// Register adds a sensor to the registry. It fails if a sensor with the same name is registered.
+func Register(s Sensor) error {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	for _, registered := range registry {
+		if registered.Name() == s.Name() {
+			return fmt.Errorf("sensor %s is already registered", s.Name())
+		}
+	}
+	registry = append(registry, s)
+	return nil
+}
+
+// Lookup returns the registered sensor by name, nil if not found
+func Lookup(name string) Sensor {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	for _, s := range registry {
+		if s.Name() == name {
+			return s
+		}
+	}
+	return nil
+}
+
+// Registered returns the registered sensors, in registration order
+func Registered() []Sensor {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	return append([]Sensor{}, registry...)
+}
+
+// funcSensor is a sensor implemented by a function returning a JSON serializable result
+type funcSensor struct {
+	name  string
+	sense func(ctx context.Context) (interface{}, error)
+}
+
+// NewSensor creates a sensor from a function returning a JSON serializable result
+func NewSensor(name string, sense func(ctx context.Context) (interface{}, error)) Sensor {
+	return &funcSensor{name: name, sense: sense}
+}
+
+// Name implements Sensor
+func (s *funcSensor) Name() string { return s.name }
+
+// Sense implements Sensor, without the file contents if the content option of the context is off.
+// A panic of the sensor is returned as an `ErrSensorPanic` error.
+func (s *funcSensor) Sense(ctx context.Context) (json.RawMessage, error) {
+	var result interface{}
+	var err error
+	if panicErr := RecoverPanic(ctx, s.name, func() { result, err = s.sense(ctx) }); panicErr != nil {
+		return nil, panicErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	encoded, err := json.Marshal(result)
+	if err != nil || GetSenseOptions(ctx).Content {
+		return encoded, err
+	}
+	return StripContent(encoded)
+}
@@ -0,0 +1,303 @@
+package sensor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+// Registry credentials file kinds
+const (
+	RegistryCredentialsDockerConfig            = "dockerConfig"
+	RegistryCredentialsContainerdConfig        = "containerdConfig"
+	RegistryCredentialsContainerdHosts         = "containerdHosts"
+	RegistryCredentialsImageCredentialProvider = "imageCredentialProvider"
+)
+
+// Registry credential types
+const (
+	RegistryAuth               = "auth"
+	RegistryIdentityToken      = "identityToken"
+	RegistryHeader             = "header"
+	RegistryCredHelper         = "credHelper"
+	RegistryCredsStore         = "credsStore"
+	RegistryCredentialProvider = "credentialProvider"
+)
+
+const (
+	containerdConfigPath               = "/etc/containerd/config.toml"
+	containerdHostsPattern             = "/etc/containerd/certs.d/*/hosts.toml"
+	kubeletImageCredentialProviderArg  = "--image-credential-provider-config"
+	containerdHostsDefaultRegistryName = "_default"
+
+	// Registry of credentials granting access to all registries
+	allRegistries = "*"
+)
+
+var (
+	// Docker config files (config.json and the legacy .dockercfg), used by docker, the kubelet and CRI-O
+	dockerConfigPatterns = []string{
+		"/root/.docker/config.json",
+		"/root/.dockercfg",
+		"/home/*/.docker/config.json",
+		"/.docker/config.json",
+		"/var/lib/kubelet/config.json",
+		"/var/lib/kubelet/.dockercfg",
+		"/etc/containers/auth.json",
+		"/run/containers/0/auth.json",
+	}
+)
+
+// RegistryCredentialsFile holds the registries a credentials file grants access to
+type RegistryCredentialsFile struct {
+	// Information about the file (without its content)
+	File *FileInfo `json:"file"`
+
+	// One of RegistryCredentials*
+	Kind string `json:"kind"`
+
+	// True if the file holds the credentials themselves, rather than referring to helpers
+	InlineSecrets bool `json:"inlineSecrets"`
+
+	Registries []RegistryCredential `json:"registries"`
+}
+
+// RegistryCredential is the access granted to a registry, without the credentials
+type RegistryCredential struct {
+	// The registry host, or an image pattern for credential providers. "*" for all registries.
+	Registry string `json:"registry"`
+
+	// One of Registry*
+	Type string `json:"type"`
+
+	// The credential helper or provider name (if relevant)
+	Helper string `json:"helper,omitempty"`
+}
+
+// SenseRegistryCredentials returns the container registry credential files on the node
+func SenseRegistryCredentials(ctx context.Context) ([]RegistryCredentialsFile, error) {
+	ret := []RegistryCredentialsFile{}
+	add := func(filePath, kind string, parse func(content []byte) ([]RegistryCredential, error)) {
+		file, err := makeRegistryCredentialsFile(ctx, filePath, kind, parse)
+		if err != nil {
+			logger(ctx).Debug("SenseRegistryCredentials failed to read credentials file",
+				zap.String("path", filePath),
+				zap.Error(err))
+			recordCollectionError(ctx, "read", filePath, err)
+			return
+		}
+		if len(file.Registries) > 0 {
+			ret = append(ret, *file)
+		}
+	}
+
+	for _, pattern := range dockerConfigPatterns {
+		for _, filePath := range globHostPaths(ctx, pattern) {
+			add(filePath, RegistryCredentialsDockerConfig, parseDockerConfigCredentials)
+		}
+	}
+
+	add(containerdConfigPath, RegistryCredentialsContainerdConfig, parseContainerdConfigCredentials)
+	for _, filePath := range globHostPaths(ctx, containerdHostsPattern) {
+		registry := path.Base(path.Dir(filePath))
+		add(filePath, RegistryCredentialsContainerdHosts, func(content []byte) ([]RegistryCredential, error) {
+			return parseContainerdHostsCredentials(content, registry)
+		})
+	}
+
+	if kubeletProcess, err := LocateKubeletProcess(ctx); err == nil {
+		if configPath, ok := kubeletProcess.GetArg(kubeletImageCredentialProviderArg); ok && configPath != "" {
+			add(configPath, RegistryCredentialsImageCredentialProvider, parseImageCredentialProviderConfig)
+		}
+	}
+
+	for i := range ret {
+		ret[i].InlineSecrets = hasInlineSecrets(ret[i].Registries)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].File.Path < ret[j].File.Path })
+	return ret, nil
+}
+
+// globHostPaths returns the host paths matching the pattern
+func globHostPaths(ctx context.Context, pattern string) []string {
+	ret, err := fs.Glob(hostFS(ctx), pattern)
+	if err != nil {
+		return nil
+	}
+	return ret
+}
+
+func makeRegistryCredentialsFile(ctx context.Context, filePath, kind string, parse func(content []byte) ([]RegistryCredential, error)) (*RegistryCredentialsFile, error) {
+	content, err := ReadFileOnHostFileSystem(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	registries, err := parse(content)
+	if err != nil {
+		return nil, err
+	}
+	fileInfo, err := makeHostFileInfo(ctx, filePath, false)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(registries, func(i, j int) bool {
+		if registries[i].Registry != registries[j].Registry {
+			return registries[i].Registry < registries[j].Registry
+		}
+		return registries[i].Type < registries[j].Type
+	})
+	return &RegistryCredentialsFile{File: fileInfo, Kind: kind, Registries: registries}, nil
+}
+
+func hasInlineSecrets(registries []RegistryCredential) bool {
+	for _, registry := range registries {
+		switch registry.Type {
+		case RegistryAuth, RegistryIdentityToken, RegistryHeader:
+			return true
+		}
+	}
+	return false
+}
+
+// parseDockerConfigCredentials parses a docker config.json, or a legacy .dockercfg (which is the "auths" map itself)
+func parseDockerConfigCredentials(content []byte) ([]RegistryCredential, error) {
+	type authConfig struct {
+		Auth          string `json:"auth"`
+		Username      string `json:"username"`
+		Password      string `json:"password"`
+		IdentityToken string `json:"identitytoken"`
+	}
+	config := struct {
+		Auths       map[string]authConfig `json:"auths"`
+		CredHelpers map[string]string     `json:"credHelpers"`
+		CredsStore  string                `json:"credsStore"`
+	}{}
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse docker config: %w", err)
+	}
+	if config.Auths == nil && config.CredHelpers == nil && config.CredsStore == "" {
+		legacyAuths := map[string]authConfig{}
+		if err := json.Unmarshal(content, &legacyAuths); err == nil {
+			config.Auths = legacyAuths
+		}
+	}
+
+	ret := []RegistryCredential{}
+	for registry, auth := range config.Auths {
+		switch {
+		case auth.IdentityToken != "":
+			ret = append(ret, RegistryCredential{Registry: registry, Type: RegistryIdentityToken})
+		case auth.Auth != "" || auth.Password != "":
+			ret = append(ret, RegistryCredential{Registry: registry, Type: RegistryAuth})
+		}
+	}
+	for registry, helper := range config.CredHelpers {
+		ret = append(ret, RegistryCredential{Registry: registry, Type: RegistryCredHelper, Helper: helper})
+	}
+	if config.CredsStore != "" {
+		ret = append(ret, RegistryCredential{Registry: allRegistries, Type: RegistryCredsStore, Helper: config.CredsStore})
+	}
+	return ret, nil
+}
+
+// parseContainerdConfigCredentials parses the registry auth sections of the containerd CRI plugin config
+func parseContainerdConfigCredentials(content []byte) ([]RegistryCredential, error) {
+	config := struct {
+		Plugins map[string]struct {
+			Registry struct {
+				Configs map[string]struct {
+					Auth *struct {
+						Username      string `toml:"username"`
+						Password      string `toml:"password"`
+						Auth          string `toml:"auth"`
+						IdentityToken string `toml:"identitytoken"`
+					} `toml:"auth"`
+				} `toml:"configs"`
+			} `toml:"registry"`
+		} `toml:"plugins"`
+	}{}
+	if _, err := toml.Decode(string(content), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse containerd config: %w", err)
+	}
+
+	ret := []RegistryCredential{}
+	for registry, registryConfig := range config.Plugins[containerdConfigSection].Registry.Configs {
+		auth := registryConfig.Auth
+		switch {
+		case auth == nil:
+		case auth.IdentityToken != "":
+			ret = append(ret, RegistryCredential{Registry: registry, Type: RegistryIdentityToken})
+		case auth.Auth != "" || auth.Password != "":
+			ret = append(ret, RegistryCredential{Registry: registry, Type: RegistryAuth})
+		}
+	}
+	return ret, nil
+}
+
+// parseContainerdHostsCredentials parses the authorization headers of a containerd hosts.toml of `registry`
+func parseContainerdHostsCredentials(content []byte, registry string) ([]RegistryCredential, error) {
+	type hostConfig struct {
+		Header map[string]interface{} `toml:"header"`
+	}
+	config := struct {
+		Server string `toml:"server"`
+		hostConfig
+		Host map[string]hostConfig `toml:"host"`
+	}{}
+	if _, err := toml.Decode(string(content), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse containerd hosts config: %w", err)
+	}
+	if registry == containerdHostsDefaultRegistryName {
+		registry = allRegistries
+	}
+
+	hasAuthorization := func(header map[string]interface{}) bool {
+		for name := range header {
+			if strings.EqualFold(name, "Authorization") {
+				return true
+			}
+		}
+		return false
+	}
+
+	ret := []RegistryCredential{}
+	if hasAuthorization(config.Header) {
+		ret = append(ret, RegistryCredential{Registry: registry, Type: RegistryHeader})
+	}
+	for host, hc := range config.Host {
+		if hasAuthorization(hc.Header) {
+			ret = append(ret, RegistryCredential{Registry: host, Type: RegistryHeader})
+		}
+	}
+	return ret, nil
+}
+
+// parseImageCredentialProviderConfig parses a kubelet CredentialProviderConfig
+func parseImageCredentialProviderConfig(content []byte) ([]RegistryCredential, error) {
+	config := struct {
+		Providers []struct {
+			Name        string   `json:"name"`
+			MatchImages []string `json:"matchImages"`
+		} `json:"providers"`
+	}{}
+	if err := yaml.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse credential provider config: %w", err)
+	}
+
+	ret := []RegistryCredential{}
+	for _, provider := range config.Providers {
+		for _, image := range provider.MatchImages {
+			ret = append(ret, RegistryCredential{Registry: image, Type: RegistryCredentialProvider, Helper: provider.Name})
+		}
+	}
+	return ret, nil
+}
@@ -0,0 +1,63 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenseRegistryCredentials(t *testing.T) {
+	hfs := NewHostFS("testdata/registrycredentials")
+	ctx := WithHostFS(context.Background(), hfs)
+
+	files, err := SenseRegistryCredentials(ctx)
+	require.NoError(t, err)
+
+	type fileCredentials struct {
+		kind          string
+		inlineSecrets bool
+		registries    []RegistryCredential
+	}
+	got := map[string]fileCredentials{}
+	for _, file := range files {
+		assert.Nil(t, file.File.Content)
+		got[file.File.Path] = fileCredentials{file.Kind, file.InlineSecrets, file.Registries}
+	}
+
+	assert.Equal(t, map[string]fileCredentials{
+		"/root/.docker/config.json": {RegistryCredentialsDockerConfig, true, []RegistryCredential{
+			{Registry: "123456789012.dkr.ecr.us-east-1.amazonaws.com", Type: RegistryCredHelper, Helper: "ecr-login"},
+			{Registry: "ghcr.io", Type: RegistryIdentityToken},
+			{Registry: "registry.example.com", Type: RegistryAuth},
+		}},
+		"/var/lib/kubelet/.dockercfg": {RegistryCredentialsDockerConfig, true, []RegistryCredential{
+			{Registry: "registry.example.com", Type: RegistryAuth},
+		}},
+		"/etc/containerd/config.toml": {RegistryCredentialsContainerdConfig, true, []RegistryCredential{
+			{Registry: "registry.example.com", Type: RegistryAuth},
+		}},
+		"/etc/containerd/certs.d/registry.example.com/hosts.toml": {RegistryCredentialsContainerdHosts, true, []RegistryCredential{
+			{Registry: "https://mirror.example.com", Type: RegistryHeader},
+		}},
+	}, got)
+}
+
+func Test_parseImageCredentialProviderConfig(t *testing.T) {
+	registries, err := parseImageCredentialProviderConfig([]byte(`
+apiVersion: kubelet.config.k8s.io/v1
+kind: CredentialProviderConfig
+providers:
+- name: ecr-credential-provider
+  matchImages:
+  - "*.dkr.ecr.*.amazonaws.com"
+  defaultCacheDuration: "12h"
+  apiVersion: credentialprovider.kubelet.k8s.io/v1
+`))
+	require.NoError(t, err)
+	assert.Equal(t, []RegistryCredential{
+		{Registry: "*.dkr.ecr.*.amazonaws.com", Type: RegistryCredentialProvider, Helper: "ecr-credential-provider"},
+	}, registries)
+	assert.False(t, hasInlineSecrets(registries))
+}
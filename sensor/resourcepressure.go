@@ -0,0 +1,151 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	procMeminfoPath        = "/proc/meminfo"
+	procMemoryPressurePath = "/proc/pressure/memory"
+
+	kubeletRootDirArg     = "--root-dir"
+	kubeletRootDirDefault = "/var/lib/kubelet"
+)
+
+var (
+	// Paths whose file systems are checked, in addition to the etcd data dir and the kubelet root dir
+	resourcePressurePaths = []string{
+		"/",
+		"/var/log",
+		"/var/lib/containerd",
+		"/var/lib/docker",
+		"/var/lib/containers",
+	}
+)
+
+// ResourcePressure is a snapshot of the disk and memory usage of the node
+type ResourcePressure struct {
+	// The usage of the file systems of the critical paths
+	Disks []DiskUsage `json:"disks"`
+
+	Memory *MemoryUsage `json:"memory,omitempty"`
+}
+
+// DiskUsage is the usage of the file system of a path
+type DiskUsage struct {
+	Path string `json:"path"`
+
+	TotalBytes     uint64  `json:"totalBytes"`
+	AvailableBytes uint64  `json:"availableBytes"`
+	UsedPercent    float64 `json:"usedPercent"`
+
+	// Zero for file systems without a fixed number of inodes (e.g. btrfs)
+	TotalInodes       uint64  `json:"totalInodes"`
+	FreeInodes        uint64  `json:"freeInodes"`
+	InodesUsedPercent float64 `json:"inodesUsedPercent"`
+}
+
+// MemoryUsage is the memory usage of the node
+type MemoryUsage struct {
+	TotalBytes     uint64  `json:"totalBytes"`
+	AvailableBytes uint64  `json:"availableBytes"`
+	UsedPercent    float64 `json:"usedPercent"`
+
+	// The percentage of time some tasks were stalled on memory in the last 60 seconds (PSI), if supported
+	PressureSome60 *float64 `json:"pressureSome60,omitempty"`
+}
+
+// SenseResourcePressure returns the disk usage of the critical paths (the etcd data dir, the kubelet root dir,
+// the container runtimes and the logs), and the memory usage
+func SenseResourcePressure(ctx context.Context) (*ResourcePressure, error) {
+	paths := []string{}
+	if dataDir, err := getEtcdDataDir(ctx); err == nil {
+		paths = append(paths, dataDir)
+	}
+	paths = append(append(paths, getKubeletRootDir(ctx)), resourcePressurePaths...)
+
+	ret := &ResourcePressure{Disks: []DiskUsage{}}
+	for _, p := range paths {
+		usage, err := getDiskUsage(ctx, p)
+		if err != nil {
+			logger(ctx).Debug("failed to get disk usage", zap.String("path", p), zap.Error(err))
+			recordCollectionError(ctx, "statfs", p, err)
+			continue
+		}
+		ret.Disks = append(ret.Disks, *usage)
+	}
+
+	memory, err := getMemoryUsage(ctx)
+	if err != nil {
+		logger(ctx).Debug("failed to get memory usage", zap.Error(err))
+	}
+	ret.Memory = memory
+	return ret, nil
+}
+
+// getMemoryUsage returns the memory usage from /proc/meminfo, and the memory pressure if supported
+func getMemoryUsage(ctx context.Context) (*MemoryUsage, error) {
+	content, err := ReadFileOnHostFileSystem(ctx, procMeminfoPath)
+	if err != nil {
+		return nil, err
+	}
+	meminfo := map[string]uint64{}
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		// e.g. "MemAvailable:    1234567 kB"
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		if value, err := strconv.ParseUint(fields[1], 10, 64); err == nil {
+			meminfo[strings.TrimSuffix(fields[0], ":")] = value * 1024
+		}
+	}
+	total, ok := meminfo["MemTotal"]
+	if !ok {
+		return nil, fmt.Errorf("no MemTotal in %s", procMeminfoPath)
+	}
+
+	usage := &MemoryUsage{TotalBytes: total, AvailableBytes: meminfo["MemAvailable"]}
+	usage.UsedPercent = percent(total-usage.AvailableBytes, total)
+
+	// e.g. "some avg10=0.00 avg60=0.00 avg300=0.00 total=0"
+	if pressure, err := ReadFileOnHostFileSystem(ctx, procMemoryPressurePath); err == nil {
+		for _, line := range strings.Split(string(pressure), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) < 3 || fields[0] != "some" || !strings.HasPrefix(fields[2], "avg60=") {
+				continue
+			}
+			if value, err := strconv.ParseFloat(strings.TrimPrefix(fields[2], "avg60="), 64); err == nil {
+				usage.PressureSome60 = &value
+			}
+		}
+	}
+	return usage, nil
+}
+
+// percent returns part/total as a percentage rounded to one decimal, 0 if total is 0
+func percent(part, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*1000) / 10
+}
+
+// getKubeletRootDir returns the --root-dir of the running kubelet, or its default
+func getKubeletRootDir(ctx context.Context) string {
+	if proc, err := LocateKubeletProcess(ctx); err == nil {
+		if rootDir, ok := proc.GetArg(kubeletRootDirArg); ok && rootDir != "" {
+			return rootDir
+		}
+	}
+	return kubeletRootDirDefault
+}
@@ -0,0 +1,42 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenseResourcePressure(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	require.NoError(t, os.MkdirAll(hfs.Path("/var/log"), 0o755))
+	writeFSFile(t, hfs, procMeminfoPath, []byte("MemTotal:        8000000 kB\nMemFree:          500000 kB\nMemAvailable:    2000000 kB\n"))
+	writeFSFile(t, hfs, procMemoryPressurePath, []byte("some avg10=1.50 avg60=12.25 avg300=3.00 total=123\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n"))
+
+	pressure, err := SenseResourcePressure(ctx)
+	require.NoError(t, err)
+
+	paths := []string{}
+	for _, disk := range pressure.Disks {
+		paths = append(paths, disk.Path)
+		assert.NotZero(t, disk.TotalBytes)
+		assert.LessOrEqual(t, disk.UsedPercent, 100.0)
+	}
+	assert.Equal(t, []string{"/", "/var/log"}, paths)
+
+	require.NotNil(t, pressure.Memory)
+	assert.Equal(t, uint64(8000000*1024), pressure.Memory.TotalBytes)
+	assert.Equal(t, uint64(2000000*1024), pressure.Memory.AvailableBytes)
+	assert.Equal(t, 75.0, pressure.Memory.UsedPercent)
+	require.NotNil(t, pressure.Memory.PressureSome60)
+	assert.Equal(t, 12.25, *pressure.Memory.PressureSome60)
+}
+
+func TestPercent(t *testing.T) {
+	assert.Equal(t, 33.3, percent(1, 3))
+	assert.Equal(t, 0.0, percent(1, 0))
+}
@@ -0,0 +1,35 @@
+//go:build !windows
+
+package sensor
+
+import (
+	"context"
+	"io/fs"
+	"syscall"
+)
+
+// getDiskUsage returns the usage of the file system of a host path, computed like df
+func getDiskUsage(ctx context.Context, p string) (*DiskUsage, error) {
+	fullPath, err := resolveHostPath(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	stat := syscall.Statfs_t{}
+	if err := syscall.Statfs(fullPath, &stat); err != nil {
+		return nil, &fs.PathError{Op: "statfs", Path: p, Err: err}
+	}
+
+	blockSize := uint64(stat.Bsize)
+	usage := &DiskUsage{
+		Path:           p,
+		TotalBytes:     stat.Blocks * blockSize,
+		AvailableBytes: stat.Bavail * blockSize,
+		TotalInodes:    stat.Files,
+		FreeInodes:     stat.Ffree,
+	}
+	// the blocks reserved for root aren't available, nor used
+	used := stat.Blocks - stat.Bfree
+	usage.UsedPercent = percent(used, used+stat.Bavail)
+	usage.InodesUsedPercent = percent(stat.Files-stat.Ffree, stat.Files)
+	return usage, nil
+}
@@ -0,0 +1,11 @@
+package sensor
+
+import (
+	"context"
+	"io/fs"
+)
+
+// getDiskUsage returns the usage of the file system of a host path, not supported on Windows
+func getDiskUsage(ctx context.Context, p string) (*DiskUsage, error) {
+	return nil, &fs.PathError{Op: "statfs", Path: p, Err: errNotSupported}
+}
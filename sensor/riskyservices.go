@@ -0,0 +1,249 @@
+package sensor
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+// Risky service kinds, the services which are commonly abused to access a node
+const (
+	// A telnet server, which authenticates in plaintext
+	RiskyServiceTelnet = "telnet"
+
+	// A plain FTP server, which authenticates in plaintext
+	RiskyServiceFTP = "ftp"
+
+	// A docker daemon serving its API over TCP without TLS client authentication, i.e. root to anyone who reaches it
+	RiskyServiceDockerTCP = "dockerTCP"
+
+	// A kubelet serving its debugging handlers (e.g. exec and run) to every client, with the AlwaysAllow authorization
+	RiskyServiceKubeletDebugging = "kubeletDebugging"
+)
+
+const (
+	telnetPort         = 23
+	ftpPort            = 21
+	dockerPlainPort    = 2375
+	dockerdExe         = "/dockerd"
+	kubeletPortArg     = "--port"
+	kubeletPortDefault = 10250
+)
+
+var (
+	// The executables of the services, by kind. The inetd services listen through inetd, and are found by their port.
+	riskyServiceExes = []struct {
+		kind string
+		exe  string
+	}{
+		{RiskyServiceTelnet, "telnetd"},
+		{RiskyServiceFTP, "ftpd"},
+	}
+)
+
+// RiskyServicesInfo holds the risky services running on the node
+type RiskyServicesInfo struct {
+	Services []RiskyService `json:"services"`
+}
+
+// RiskyService is a service running on the node which is commonly abused to access it
+type RiskyService struct {
+	// One of RiskyService*
+	Kind string `json:"kind"`
+
+	// The command line of the process, empty if it was only found by a listening port
+	CmdLine string `json:"cmdLine,omitempty"`
+
+	// The TCP ports the service listens on, other than on the loopback addresses
+	Ports []int `json:"ports"`
+
+	Reason string `json:"reason"`
+}
+
+// dockerTCPHosts returns the TCP hosts of the docker daemon flags and config, and whether it verifies the TLS clients
+func dockerTCPHosts(ctx context.Context, p *ProcessDetails, configContent []byte) ([]string, bool) {
+	hosts := []string{}
+	tlsVerify := false
+	if configContent != nil {
+		config := struct {
+			Hosts     []string `json:"hosts"`
+			TLSVerify bool     `json:"tlsverify"`
+		}{}
+		if err := json.Unmarshal(configContent, &config); err != nil {
+			logger(ctx).Debug("failed to parse docker daemon config", zap.Error(err))
+		} else {
+			hosts, tlsVerify = append(hosts, config.Hosts...), config.TLSVerify
+		}
+	}
+	for i, arg := range p.CmdLine {
+		switch {
+		case (arg == "-H" || arg == "--host") && i+1 < len(p.CmdLine):
+			hosts = append(hosts, p.CmdLine[i+1])
+		case strings.HasPrefix(arg, "-H="), strings.HasPrefix(arg, "--host="):
+			hosts = append(hosts, arg[strings.Index(arg, "=")+1:])
+		case strings.HasPrefix(arg, "-H") && len(arg) > 2 && arg[2] != '=':
+			hosts = append(hosts, arg[2:])
+		}
+	}
+	if verify := getBoolArg(ctx, p, "--tlsverify"); verify != nil {
+		tlsVerify = *verify
+	}
+
+	ret := []string{}
+	for _, host := range hosts {
+		if strings.HasPrefix(host, "tcp://") {
+			ret = append(ret, host)
+		}
+	}
+	return ret, tlsVerify
+}
+
+// hostPort returns the port of a tcp:// host, the plain docker port if it has none
+func hostPort(host string) int {
+	u, err := url.Parse(host)
+	if err != nil || u.Port() == "" {
+		return dockerPlainPort
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		return dockerPlainPort
+	}
+	return port
+}
+
+// senseDockerTCP returns the docker daemon if it serves its API over TCP without TLS client authentication
+func senseDockerTCP(ctx context.Context, listening map[int]bool) *RiskyService {
+	proc, err := LocateProcessByExecSuffix(ctx, dockerdExe)
+	if err != nil {
+		if listening[dockerPlainPort] {
+			return &RiskyService{Kind: RiskyServiceDockerTCP, Ports: []int{dockerPlainPort}, Reason: fmt.Sprintf("a service listens on the plain docker API port %d", dockerPlainPort)}
+		}
+		return nil
+	}
+	content, err := ReadFileOnHostFileSystem(ctx, dockerDaemonConfigPath)
+	if err != nil {
+		content = nil
+	}
+	hosts, tlsVerify := dockerTCPHosts(ctx, proc, content)
+	if len(hosts) == 0 || tlsVerify {
+		return nil
+	}
+	ports := []int{}
+	for _, host := range hosts {
+		if port := hostPort(host); listening[port] && !containsInt(ports, port) {
+			ports = append(ports, port)
+		}
+	}
+	sort.Ints(ports)
+	return &RiskyService{
+		Kind:    RiskyServiceDockerTCP,
+		CmdLine: proc.RawCmd(),
+		Ports:   ports,
+		Reason:  fmt.Sprintf("the docker daemon serves its API on %s without TLS client verification", strings.Join(hosts, ", ")),
+	}
+}
+
+// senseKubeletDebugging returns the kubelet if it serves its debugging handlers with the AlwaysAllow authorization
+func senseKubeletDebugging(ctx context.Context, listening map[int]bool) *RiskyService {
+	proc, err := LocateKubeletProcess(ctx)
+	if err != nil {
+		return nil
+	}
+	configPath := kubeletConfigDefaultPath
+	if p, ok := proc.GetArg(kubeletConfigArgName); ok {
+		configPath = p
+	}
+	configContent, err := ReadFileOnHostFileSystem(ctx, configPath)
+	if err != nil {
+		configContent = nil
+	}
+	settings := parseKubeletSettings(ctx, proc, configContent)
+	if settings.EnableDebuggingHandlers != nil && !*settings.EnableDebuggingHandlers {
+		return nil
+	}
+	// without a config file, the flag defaults to AlwaysAllow
+	mode := settings.AuthorizationMode
+	if !((mode == nil && configContent == nil) || (mode != nil && *mode == "AlwaysAllow")) {
+		return nil
+	}
+
+	port := kubeletPortDefault
+	config := struct {
+		Port int `json:"port"`
+	}{}
+	if configContent != nil && yaml.Unmarshal(configContent, &config) == nil && config.Port != 0 {
+		port = config.Port
+	}
+	if val, ok := proc.GetArg(kubeletPortArg); ok {
+		if flagPort, err := strconv.Atoi(val); err == nil {
+			port = flagPort
+		}
+	}
+	if !listening[port] {
+		return nil
+	}
+	return &RiskyService{
+		Kind:    RiskyServiceKubeletDebugging,
+		CmdLine: proc.RawCmd(),
+		Ports:   []int{port},
+		Reason:  fmt.Sprintf("the kubelet serves its debugging handlers on port %d to every client (authorization mode AlwaysAllow)", port),
+	}
+}
+
+// senseRiskyServices returns the risky services running on the node, given its non-loopback listening TCP ports
+func senseRiskyServices(ctx context.Context, listening map[int]bool) *RiskyServicesInfo {
+	ret := &RiskyServicesInfo{Services: []RiskyService{}}
+	found := map[string]bool{}
+	for _, service := range riskyServiceExes {
+		if found[service.kind] {
+			continue
+		}
+		if proc, err := LocateProcessByExecSuffix(ctx, service.exe); err == nil {
+			found[service.kind] = true
+			ret.Services = append(ret.Services, RiskyService{Kind: service.kind, CmdLine: proc.RawCmd(), Ports: []int{}, Reason: fmt.Sprintf("a %s server runs on the node", service.kind)})
+		}
+	}
+	for _, service := range []struct {
+		kind string
+		port int
+	}{{RiskyServiceTelnet, telnetPort}, {RiskyServiceFTP, ftpPort}} {
+		if !listening[service.port] {
+			continue
+		}
+		if !found[service.kind] {
+			found[service.kind] = true
+			ret.Services = append(ret.Services, RiskyService{Kind: service.kind, Ports: []int{}, Reason: fmt.Sprintf("a service listens on the %s port %d", service.kind, service.port)})
+		}
+		for i := range ret.Services {
+			if ret.Services[i].Kind == service.kind {
+				ret.Services[i].Ports = append(ret.Services[i].Ports, service.port)
+			}
+		}
+	}
+
+	if service := senseDockerTCP(ctx, listening); service != nil {
+		ret.Services = append(ret.Services, *service)
+	}
+	if service := senseKubeletDebugging(ctx, listening); service != nil {
+		ret.Services = append(ret.Services, *service)
+	}
+	return ret
+}
+
+// SenseRiskyServices returns the services running on the node which are commonly abused to access it: telnet and
+// plain FTP servers, docker daemons serving their API over TCP without TLS client authentication, and kubelets
+// serving their debugging handlers to every client
+func SenseRiskyServices(ctx context.Context) (*RiskyServicesInfo, error) {
+	listening, err := listeningTCPPorts(ctx)
+	if err != nil {
+		logger(ctx).Debug("SenseRiskyServices failed to read the listening ports", zap.Error(err))
+	}
+	return senseRiskyServices(ctx, listening), nil
+}
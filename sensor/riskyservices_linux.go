@@ -0,0 +1,24 @@
+package sensor
+
+import (
+	"context"
+	"github.com/weaveworks/procspy"
+)
+
+// listeningTCPPorts returns the TCP ports the node listens on, other than on the loopback addresses
+func listeningTCPPorts(ctx context.Context) (map[int]bool, error) {
+	ret := map[int]bool{}
+	for _, netPath := range ProcNetTCPPaths {
+		content, err := ReadFileOnHostFileSystem(ctx, netPath)
+		if err != nil {
+			return ret, err
+		}
+		netCons := procspy.NewProcNet(content, tcpListeningState)
+		for c := netCons.Next(); c != nil; c = netCons.Next() {
+			if !c.LocalAddress.IsLoopback() {
+				ret[int(c.LocalPort)] = true
+			}
+		}
+	}
+	return ret, nil
+}
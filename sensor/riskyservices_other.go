@@ -0,0 +1,10 @@
+//go:build !linux
+
+package sensor
+
+import "context"
+
+// listeningTCPPorts returns an error, the listening ports are read from /proc
+func listeningTCPPorts(ctx context.Context) (map[int]bool, error) {
+	return map[int]bool{}, errNotSupported
+}
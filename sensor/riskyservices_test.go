@@ -0,0 +1,49 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDockerTCPHosts(t *testing.T) {
+	p := &ProcessDetails{CmdLine: []string{"/usr/bin/dockerd", "-H", "fd://", "-Htcp://0.0.0.0:2376", "--host=unix:///var/run/docker.sock"}}
+	hosts, tlsVerify := dockerTCPHosts(context.Background(), p, []byte(`{"hosts": ["tcp://10.0.0.5"], "tlsverify": true}`))
+	assert.Equal(t, []string{"tcp://10.0.0.5", "tcp://0.0.0.0:2376"}, hosts)
+	assert.True(t, tlsVerify)
+	assert.Equal(t, 2375, hostPort(hosts[0]))
+	assert.Equal(t, 2376, hostPort(hosts[1]))
+
+	p.CmdLine = append(p.CmdLine, "--tlsverify=false")
+	_, tlsVerify = dockerTCPHosts(context.Background(), p, nil)
+	assert.False(t, tlsVerify)
+}
+
+func TestSenseRiskyServices(t *testing.T) {
+	origPlatform := hostPlatform
+	defer func() { hostPlatform = origPlatform }()
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+	hostPlatform = fixturePlatform{root: hfs.Root()}
+	writeFSFile(t, hfs, "/proc/40/cmdline", []byte("/usr/sbin/in.telnetd\x00"))
+	writeFSFile(t, hfs, "/proc/41/cmdline", []byte("/usr/bin/dockerd\x00-H\x00tcp://0.0.0.0:2375\x00"))
+	writeFSFile(t, hfs, "/proc/42/cmdline", []byte("/usr/bin/kubelet\x00--authorization-mode=AlwaysAllow\x00"))
+
+	info := senseRiskyServices(ctx, map[int]bool{21: true, 2375: true, 10250: true})
+	require.Len(t, info.Services, 4)
+	assert.Equal(t, RiskyServiceTelnet, info.Services[0].Kind)
+	assert.Contains(t, info.Services[0].CmdLine, "/usr/sbin/in.telnetd")
+	assert.Empty(t, info.Services[0].Ports)
+	assert.Equal(t, RiskyService{Kind: RiskyServiceFTP, Ports: []int{21}, Reason: "a service listens on the ftp port 21"}, info.Services[1])
+	assert.Equal(t, RiskyServiceDockerTCP, info.Services[2].Kind)
+	assert.Equal(t, []int{2375}, info.Services[2].Ports)
+	assert.Equal(t, RiskyServiceKubeletDebugging, info.Services[3].Kind)
+
+	// the kubelet port and the docker API port aren't reachable from off the node
+	info = senseRiskyServices(ctx, map[int]bool{})
+	require.Len(t, info.Services, 2)
+	assert.Equal(t, RiskyServiceDockerTCP, info.Services[1].Kind)
+	assert.Empty(t, info.Services[1].Ports)
+}
@@ -0,0 +1,162 @@
+package sensor
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// Connecting to a container runtime socket lets a user run a privileged container, so every user who can write the
+// socket, e.g. through the docker group, is equivalent to root.
+
+var (
+	// The container runtime sockets, /var/run is usually a link to /run
+	runtimeSockets = []struct {
+		runtime string
+		path    string
+	}{
+		{dockerRuntimeName, "/run/docker.sock"},
+		{dockerRuntimeName, "/var/run/docker.sock"},
+		{containerdContainerRuntimeName, containerdDefaultSocket},
+		{containerdContainerRuntimeName, "/run/k3s/containerd/containerd.sock"},
+		{crioContainerRuntimeName, "/run/crio/crio.sock"},
+		{crioContainerRuntimeName, "/var/run/crio/crio.sock"},
+		{"cri-dockerd", "/run/cri-dockerd.sock"},
+		{"podman", "/run/podman/podman.sock"},
+	}
+)
+
+// RuntimeSocket is a container runtime socket, and the users who can connect to it
+type RuntimeSocket struct {
+	// e.g. "docker", "containerd" or "crio"
+	Runtime string `json:"runtime"`
+
+	File *FileInfo `json:"file"`
+
+	// The group which can connect to the socket (e.g. "docker"), empty if the group can't or it's root
+	Group string `json:"group,omitempty"`
+
+	// The users other than root who can connect to the socket: its owner, and the members of its group
+	Users []string `json:"users"`
+
+	// Whether any user on the node can connect to the socket
+	WorldAccessible bool `json:"worldAccessible"`
+}
+
+// hostAccounts are the users and groups of the host
+type hostAccounts struct {
+	// user names by their primary GID
+	primaryUsers map[int64][]string
+
+	// the supplementary members of the groups, by GID
+	members map[int64][]string
+}
+
+// parseHostAccounts parses the primary groups of /etc/passwd and the members of /etc/group
+func parseHostAccounts(passwd, group []byte) hostAccounts {
+	accounts := hostAccounts{primaryUsers: map[int64][]string{}, members: map[int64][]string{}}
+	for _, line := range strings.Split(string(passwd), "\n") {
+		// name:password:uid:gid:gecos:home:shell
+		fields := strings.Split(line, ":")
+		if len(fields) < 4 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if gid, err := strconv.ParseInt(fields[3], 10, 64); err == nil {
+			accounts.primaryUsers[gid] = append(accounts.primaryUsers[gid], fields[0])
+		}
+	}
+	for _, line := range strings.Split(string(group), "\n") {
+		// name:password:gid:member,member
+		fields := strings.Split(line, ":")
+		if len(fields) < 4 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		gid, err := strconv.ParseInt(fields[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		for _, member := range strings.Split(strings.TrimSpace(fields[3]), ",") {
+			if member != "" {
+				accounts.members[gid] = append(accounts.members[gid], member)
+			}
+		}
+	}
+	return accounts
+}
+
+// groupUsers returns the users of a group, the users whose primary group it is and its supplementary members
+func (a hostAccounts) groupUsers(gid int64) []string {
+	return append(append([]string{}, a.primaryUsers[gid]...), a.members[gid]...)
+}
+
+// socketAccess fills the group and the users who can connect to a socket, i.e. write it
+func socketAccess(socket *RuntimeSocket, accounts hostAccounts) {
+	users := map[string]bool{}
+	ownership := socket.File.Ownership
+	if ownership != nil && ownership.Err == "" {
+		if ownership.UID != 0 && socket.File.Permissions&0o200 != 0 {
+			owner := ownership.Username
+			if owner == "" {
+				owner = strconv.FormatInt(ownership.UID, 10)
+			}
+			users[owner] = true
+		}
+		if ownership.GID != 0 && socket.File.Permissions&0o020 != 0 {
+			socket.Group = ownership.Groupname
+			if socket.Group == "" {
+				socket.Group = strconv.FormatInt(ownership.GID, 10)
+			}
+			for _, user := range accounts.groupUsers(ownership.GID) {
+				users[user] = true
+			}
+		}
+	}
+	socket.WorldAccessible = socket.File.Permissions&0o002 != 0
+
+	socket.Users = []string{}
+	for user := range users {
+		if user != "" && user != "root" {
+			socket.Users = append(socket.Users, user)
+		}
+	}
+	sort.Strings(socket.Users)
+}
+
+// SenseRuntimeSockets returns the container runtime sockets which exist on the node, with the users and the group
+// who can connect to them
+func SenseRuntimeSockets(ctx context.Context) ([]RuntimeSocket, error) {
+	passwd, err := ReadFileOnHostFileSystem(ctx, userFile)
+	if err != nil {
+		logger(ctx).Debug("SenseRuntimeSockets failed to read the users", zap.Error(err))
+	}
+	group, err := ReadFileOnHostFileSystem(ctx, groupFile)
+	if err != nil {
+		logger(ctx).Debug("SenseRuntimeSockets failed to read the groups", zap.Error(err))
+	}
+	accounts := parseHostAccounts(passwd, group)
+
+	ret := []RuntimeSocket{}
+	seen := map[string]bool{}
+	for _, runtimeSocket := range runtimeSockets {
+		// skip the links to a reported socket, e.g. /var/run/docker.sock
+		resolved, err := resolveHostPath(ctx, runtimeSocket.path)
+		if err != nil || seen[resolved] {
+			continue
+		}
+		file, err := makeHostFileStatInfo(ctx, runtimeSocket.path)
+		if err != nil {
+			logger(ctx).Debug("failed to stat container runtime socket", zap.String("path", runtimeSocket.path), zap.Error(err))
+			recordCollectionError(ctx, "stat", runtimeSocket.path, err)
+			continue
+		}
+		seen[resolved] = true
+
+		socket := RuntimeSocket{Runtime: runtimeSocket.runtime, File: file}
+		socketAccess(&socket, accounts)
+		ret = append(ret, socket)
+	}
+	return ret, nil
+}
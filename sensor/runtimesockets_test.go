@@ -0,0 +1,53 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSocketAccess(t *testing.T) {
+	accounts := parseHostAccounts([]byte(`root:x:0:0:root:/root:/bin/bash
+# comment
+ci:x:1001:999::/home/ci:/bin/bash
+alice:x:1000:1000::/home/alice:/bin/bash
+`), []byte(`root:x:0:
+docker:x:999:alice,root
+alice:x:1000:
+`))
+	assert.Equal(t, []string{"ci", "alice", "root"}, accounts.groupUsers(999))
+
+	socket := RuntimeSocket{Runtime: "docker", File: &FileInfo{
+		Path:        "/run/docker.sock",
+		Permissions: 0o660,
+		Ownership:   &FileOwnership{UID: 0, GID: 999, Username: "root", Groupname: "docker"},
+	}}
+	socketAccess(&socket, accounts)
+	assert.Equal(t, "docker", socket.Group)
+	assert.Equal(t, []string{"alice", "ci"}, socket.Users)
+	assert.False(t, socket.WorldAccessible)
+
+	socket = RuntimeSocket{Runtime: "containerd", File: &FileInfo{
+		Path:        "/run/containerd/containerd.sock",
+		Permissions: 0o666,
+		Ownership:   &FileOwnership{UID: 0, GID: 0, Username: "root", Groupname: "root"},
+	}}
+	socketAccess(&socket, accounts)
+	assert.Empty(t, socket.Group)
+	assert.Empty(t, socket.Users)
+	assert.True(t, socket.WorldAccessible)
+}
+
+func TestSenseRuntimeSockets(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	writeFSFile(t, hfs, "/run/crio/crio.sock", nil)
+	sockets, err := SenseRuntimeSockets(ctx)
+	require.NoError(t, err)
+	require.Len(t, sockets, 1)
+	assert.Equal(t, "crio", sockets[0].Runtime)
+	assert.Equal(t, "/run/crio/crio.sock", sockets[0].File.Path)
+}
@@ -0,0 +1,111 @@
+package sensor
+
+import (
+	"context"
+	"encoding/json"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// The Localhost seccomp profiles of the pods are read from the seccomp directory under the kubelet root dir, e.g.
+// localhost/profiles/audit.json is <root-dir>/seccomp/profiles/audit.json.
+
+var (
+	// The seccomp actions which let a syscall through
+	permissiveSeccompActions = []string{"SCMP_ACT_ALLOW", "SCMP_ACT_LOG"}
+)
+
+// SeccompProfilesInfo holds the seccomp profiles of the kubelet seccomp directory
+type SeccompProfilesInfo struct {
+	// The seccomp directory, under the kubelet --root-dir
+	Dir string `json:"dir"`
+
+	Profiles []SeccompProfile `json:"profiles"`
+}
+
+// SeccompProfile is a Localhost seccomp profile
+type SeccompProfile struct {
+	// The profile file, hashed
+	File *FileInfo `json:"file"`
+
+	// The name of the profile in the pod specs, i.e. its path relative to the seccomp directory
+	Name string `json:"name"`
+
+	DefaultAction string `json:"defaultAction,omitempty"`
+
+	// Whether the default action lets the syscalls through, i.e. the profile only blocks the listed syscalls
+	Permissive bool `json:"permissive"`
+
+	// The number of syscalls the rules let through
+	PermissiveSyscalls int `json:"permissiveSyscalls"`
+
+	// The parse error of the profile, empty if it was parsed
+	Error string `json:"error,omitempty"`
+}
+
+// parseSeccompProfile parses the default action and the syscalls the rules let through of a seccomp profile
+func parseSeccompProfile(content []byte, profile *SeccompProfile) error {
+	spec := struct {
+		DefaultAction string `json:"defaultAction"`
+		Syscalls      []struct {
+			// the name field is of the legacy docker profiles
+			Name   string   `json:"name"`
+			Names  []string `json:"names"`
+			Action string   `json:"action"`
+		} `json:"syscalls"`
+	}{}
+	if err := json.Unmarshal(content, &spec); err != nil {
+		return err
+	}
+
+	profile.DefaultAction = spec.DefaultAction
+	profile.Permissive = containsString(permissiveSeccompActions, spec.DefaultAction)
+	syscalls := map[string]bool{}
+	for _, rule := range spec.Syscalls {
+		if !containsString(permissiveSeccompActions, rule.Action) {
+			continue
+		}
+		for _, name := range append([]string{rule.Name}, rule.Names...) {
+			if name != "" {
+				syscalls[name] = true
+			}
+		}
+	}
+	profile.PermissiveSyscalls = len(syscalls)
+	return nil
+}
+
+// SenseSeccompProfiles returns the Localhost seccomp profiles of the kubelet seccomp directory
+func SenseSeccompProfiles(ctx context.Context) (*SeccompProfilesInfo, error) {
+	ret := &SeccompProfilesInfo{Dir: path.Join(getKubeletRootDir(ctx), "seccomp"), Profiles: []SeccompProfile{}}
+
+	fs.WalkDir(hostFS(ctx), ret.Dir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			logger(ctx).Debug("failed to walk", zap.String("path", filePath), zap.Error(err))
+			recordCollectionError(ctx, "walk", filePath, err)
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		file := makeHostFileInfoVerbose(ctx, filePath, false, zap.String("in", "SenseSeccompProfiles"))
+		if file == nil {
+			return nil
+		}
+		profile := SeccompProfile{File: file, Name: strings.TrimPrefix(filePath, ret.Dir+"/")}
+		if content, err := ReadFileOnHostFileSystem(ctx, filePath); err != nil {
+			profile.Error = err.Error()
+		} else if err := parseSeccompProfile(content, &profile); err != nil {
+			profile.Error = err.Error()
+		}
+		ret.Profiles = append(ret.Profiles, profile)
+		return nil
+	})
+
+	sort.Slice(ret.Profiles, func(i, j int) bool { return ret.Profiles[i].Name < ret.Profiles[j].Name })
+	return ret, nil
+}
@@ -0,0 +1,46 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenseSeccompProfiles(t *testing.T) {
+	// the files are hashed for the file integrity monitoring only
+	SetFileHashing(true)
+	defer SetFileHashing(false)
+
+	hfs := NewHostFS(t.TempDir())
+
+	ctx := WithHostFS(context.Background(), hfs)
+	writeFSFile(t, hfs, "/var/lib/kubelet/seccomp/profiles/audit.json", []byte(`{"defaultAction": "SCMP_ACT_LOG"}`))
+	writeFSFile(t, hfs, "/var/lib/kubelet/seccomp/profiles/fine-grained.json", []byte(`{
+  "defaultAction": "SCMP_ACT_ERRNO",
+  "architectures": ["SCMP_ARCH_X86_64"],
+  "syscalls": [
+    {"names": ["accept4", "epoll_wait", "read"], "action": "SCMP_ACT_ALLOW"},
+    {"names": ["read", "ptrace"], "action": "SCMP_ACT_LOG"},
+    {"name": "mount", "action": "SCMP_ACT_ERRNO"}
+  ]
+}`))
+	writeFSFile(t, hfs, "/var/lib/kubelet/seccomp/broken.json", []byte("{"))
+
+	info, err := SenseSeccompProfiles(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "/var/lib/kubelet/seccomp", info.Dir)
+	require.Len(t, info.Profiles, 3)
+
+	assert.Equal(t, "broken.json", info.Profiles[0].Name)
+	assert.NotEmpty(t, info.Profiles[0].Error)
+
+	assert.Equal(t, "profiles/audit.json", info.Profiles[1].Name)
+	assert.True(t, info.Profiles[1].Permissive)
+	assert.NotEmpty(t, info.Profiles[1].File.SHA256)
+
+	assert.Equal(t, "SCMP_ACT_ERRNO", info.Profiles[2].DefaultAction)
+	assert.False(t, info.Profiles[2].Permissive)
+	assert.Equal(t, 4, info.Profiles[2].PermissiveSyscalls)
+}
@@ -26,9 +26,12 @@ var (
 	ErrServicePathNotFound = errors.New("cannot locate service file path")
 )
 
-func newSystemDbusConnection() (*dbus.Conn, error) {
-	systemBusPath := "unix:path=" + hostPath("/run/dbus/system_bus_socket")
-	d, err := dbus.Dial(systemBusPath)
+func newSystemDbusConnection(ctx context.Context) (*dbus.Conn, error) {
+	socketPath, err := resolveHostPath(ctx, "/run/dbus/system_bus_socket")
+	if err != nil {
+		return nil, err
+	}
+	d, err := dbus.Dial("unix:path=" + socketPath)
 	if err != nil {
 		return d, err
 	}
@@ -41,12 +44,12 @@ func newSystemDbusConnection() (*dbus.Conn, error) {
 }
 
 // getKubeletServiceFiles all the service files associated with the kubelet service.
-func getKubeletServiceFiles(kubeletPid int) ([]string, error) {
+func getKubeletServiceFiles(ctx context.Context, kubeletPid int) ([]string, error) {
 
 	// First try to get the service files from systemd daemon
-	configDir, err := getServiceFilesByPIDSystemd(kubeletPid)
+	configDir, err := getServiceFilesByPIDSystemd(ctx, kubeletPid)
 	if err != nil {
-		zap.L().Debug("failed to get service files by PID from systemd", zap.Error(err))
+		logger(ctx).Debug("failed to get service files by PID from systemd", zap.Error(err))
 	}
 
 	// Fallback to the default location
@@ -55,7 +58,7 @@ func getKubeletServiceFiles(kubeletPid int) ([]string, error) {
 		configDir = kubeletSystemdServiceConfigDir
 	}
 
-	files, err := os.ReadDir(hostPath(configDir))
+	files, err := readHostDir(ctx, configDir)
 	if err != nil {
 		return nil, err
 	}
@@ -69,14 +72,8 @@ func getKubeletServiceFiles(kubeletPid int) ([]string, error) {
 }
 
 // getServiceFilesByPIDSystemd returns the serivce config directory for a given process id.
-func getServiceFilesByPIDSystemd(pid int) (string, error) {
-	conn, err := systemd_debus.NewConnection(newSystemDbusConnection)
-	if err != nil {
-		return "", err
-	}
-	defer conn.Close()
-
-	unitName, err := conn.GetUnitNameByPID(context.Background(), uint32(pid))
+func getServiceFilesByPIDSystemd(ctx context.Context, pid int) (string, error) {
+	unitName, err := getUnitNameByPID(ctx, pid)
 	if err != nil {
 		return "", err
 	}
@@ -91,7 +88,7 @@ func getServiceFilesByPIDSystemd(pid int) (string, error) {
 
 	// Find the service override files path (if any)
 	unitDirName := unitName + ".d"
-	configDir := getExistsPath(hostFileSystemDefaultLocation,
+	configDir := getExistsPath(hostFS(ctx).Root(),
 		path.Join(systemdPkgDir, unitDirName),
 		path.Join(systemdAdminDir, unitDirName),
 	)
@@ -99,6 +96,17 @@ func getServiceFilesByPIDSystemd(pid int) (string, error) {
 	return configDir, nil
 }
 
+// getUnitNameByPID returns the name of the systemd unit of a process id.
+func getUnitNameByPID(ctx context.Context, pid int) (string, error) {
+	conn, err := systemd_debus.NewConnection(func() (*dbus.Conn, error) { return newSystemDbusConnection(ctx) })
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	return conn.GetUnitNameByPID(context.Background(), uint32(pid))
+}
+
 // getExistsPath return the first exists path from a list of `paths`, prefixing it with `rootDir`.
 func getExistsPath(rootDir string, paths ...string) string {
 	for _, p := range paths {
@@ -0,0 +1,243 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	kubeletPodManifestPathArg = "--pod-manifest-path"
+	kubeletManifestURLArg     = "--manifest-url"
+	staticPodDefaultDir       = "/etc/kubernetes/manifests"
+)
+
+var (
+	// The manifests of the control plane static pods of kubeadm
+	controlPlaneManifests = []string{
+		path.Base(apiServerSpecsPath),
+		path.Base(controllerManagerSpecsPath),
+		path.Base(schedulerSpecsPath),
+		path.Base(etcdConfigPath),
+	}
+)
+
+// StaticPod holds the privileges of a static pod, which bypasses admission control
+type StaticPod struct {
+	File *FileInfo `json:"file"`
+
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+
+	// True for the control plane manifests of kubeadm, e.g. kube-apiserver.yaml
+	ControlPlane bool `json:"controlPlane,omitempty"`
+
+	HostNetwork bool `json:"hostNetwork,omitempty"`
+	HostPID     bool `json:"hostPID,omitempty"`
+	HostIPC     bool `json:"hostIPC,omitempty"`
+
+	HostPathVolumes []StaticPodHostPath `json:"hostPathVolumes"`
+
+	Containers []StaticPodContainer `json:"containers"`
+}
+
+// StaticPodHostPath is a hostPath volume of a static pod
+type StaticPodHostPath struct {
+	Name string `json:"name"`
+	Path string `json:"path"`
+
+	// True if every mount of the volume is read only
+	ReadOnly bool `json:"readOnly"`
+
+	// True if the path enables escaping to the host if writable, e.g. /etc or /var/run
+	Sensitive bool `json:"sensitive"`
+}
+
+// StaticPodContainer holds the privileges of a container of a static pod
+type StaticPodContainer struct {
+	Name  string `json:"name"`
+	Image string `json:"image"`
+
+	// True for init containers
+	Init bool `json:"init,omitempty"`
+
+	Privileged bool `json:"privileged,omitempty"`
+
+	// The added capabilities
+	Capabilities []string `json:"capabilities,omitempty"`
+}
+
+// podManifest is the subset of a pod manifest used by the static pods sensor
+type podManifest struct {
+	Kind     string `json:"kind"`
+	Metadata struct {
+		Name      string `json:"name"`
+		Namespace string `json:"namespace"`
+	} `json:"metadata"`
+	Spec struct {
+		HostNetwork    bool                   `json:"hostNetwork"`
+		HostPID        bool                   `json:"hostPID"`
+		HostIPC        bool                   `json:"hostIPC"`
+		InitContainers []podManifestContainer `json:"initContainers"`
+		Containers     []podManifestContainer `json:"containers"`
+		Volumes        []struct {
+			Name     string `json:"name"`
+			HostPath *struct {
+				Path string `json:"path"`
+			} `json:"hostPath"`
+		} `json:"volumes"`
+	} `json:"spec"`
+}
+
+type podManifestContainer struct {
+	Name            string `json:"name"`
+	Image           string `json:"image"`
+	SecurityContext *struct {
+		Privileged   *bool `json:"privileged"`
+		Capabilities *struct {
+			Add []string `json:"add"`
+		} `json:"capabilities"`
+	} `json:"securityContext"`
+	VolumeMounts []struct {
+		Name     string `json:"name"`
+		ReadOnly bool   `json:"readOnly"`
+	} `json:"volumeMounts"`
+}
+
+// SenseStaticPods returns the privileged containers, host namespaces and hostPath volumes of all the static pods
+// of the kubelet static pod path
+func SenseStaticPods(ctx context.Context) ([]StaticPod, error) {
+	manifestPath := getStaticPodPath(ctx)
+	manifests := []string{manifestPath}
+	entries, err := readHostDir(ctx, manifestPath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return []StaticPod{}, nil
+	case err == nil:
+		manifests = []string{}
+		for _, entry := range entries {
+			// the kubelet ignores hidden files
+			if !entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+				manifests = append(manifests, path.Join(manifestPath, entry.Name()))
+			}
+		}
+	}
+
+	ret := []StaticPod{}
+	for _, manifest := range manifests {
+		pod, err := readStaticPod(ctx, manifest)
+		if err != nil {
+			logger(ctx).Debug("failed to read static pod manifest", zap.String("path", manifest), zap.Error(err))
+			continue
+		}
+		if pod != nil {
+			ret = append(ret, *pod)
+		}
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].File.Path < ret[j].File.Path })
+	return ret, nil
+}
+
+// getStaticPodPath returns the static pod path of the kubelet flags or config, a directory or a single manifest
+func getStaticPodPath(ctx context.Context) string {
+	manifestPath, _ := getStaticPodSources(ctx)
+	return manifestPath
+}
+
+// getStaticPodSources returns the static pod path and URL of the kubelet flags or config, the URL is empty if the
+// kubelet doesn't fetch static pods over HTTP
+func getStaticPodSources(ctx context.Context) (string, string) {
+	kubeletProcess, err := LocateKubeletProcess(ctx)
+	if err != nil {
+		return staticPodDefaultDir, ""
+	}
+
+	configPath := kubeletConfigDefaultPath
+	if p, ok := kubeletProcess.GetArg(kubeletConfigArgName); ok {
+		configPath = p
+	}
+	conf := struct {
+		StaticPodPath string `json:"staticPodPath"`
+		StaticPodURL  string `json:"staticPodURL"`
+	}{}
+	if content, err := ReadKubeletConfig(ctx, configPath); err == nil {
+		if err := yaml.Unmarshal(content, &conf); err != nil {
+			logger(ctx).Debug("failed to parse kubelet config", zap.String("path", configPath), zap.Error(err))
+		}
+	}
+	if manifestPath, ok := kubeletProcess.GetArg(kubeletPodManifestPathArg); ok && manifestPath != "" {
+		conf.StaticPodPath = manifestPath
+	}
+	if manifestURL, ok := kubeletProcess.GetArg(kubeletManifestURLArg); ok && manifestURL != "" {
+		conf.StaticPodURL = manifestURL
+	}
+	if conf.StaticPodPath == "" {
+		conf.StaticPodPath = staticPodDefaultDir
+	}
+	return conf.StaticPodPath, conf.StaticPodURL
+}
+
+// readStaticPod returns the static pod of a manifest, or nil if the manifest isn't a pod
+func readStaticPod(ctx context.Context, manifestPath string) (*StaticPod, error) {
+	file, err := makeHostFileInfo(ctx, manifestPath, true)
+	if err != nil {
+		return nil, err
+	}
+	manifest := podManifest{}
+	if err := yaml.Unmarshal(file.Content, &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Kind != "Pod" {
+		return nil, nil
+	}
+	file.Content = nil
+
+	pod := &StaticPod{
+		File:            file,
+		Name:            manifest.Metadata.Name,
+		Namespace:       manifest.Metadata.Namespace,
+		ControlPlane:    containsString(controlPlaneManifests, path.Base(manifestPath)),
+		HostNetwork:     manifest.Spec.HostNetwork,
+		HostPID:         manifest.Spec.HostPID,
+		HostIPC:         manifest.Spec.HostIPC,
+		HostPathVolumes: []StaticPodHostPath{},
+		Containers:      []StaticPodContainer{},
+	}
+
+	containers := append(append([]podManifestContainer{}, manifest.Spec.InitContainers...), manifest.Spec.Containers...)
+	for i, container := range containers {
+		podContainer := StaticPodContainer{Name: container.Name, Image: container.Image, Init: i < len(manifest.Spec.InitContainers)}
+		if securityContext := container.SecurityContext; securityContext != nil {
+			podContainer.Privileged = securityContext.Privileged != nil && *securityContext.Privileged
+			if securityContext.Capabilities != nil {
+				podContainer.Capabilities = securityContext.Capabilities.Add
+			}
+		}
+		pod.Containers = append(pod.Containers, podContainer)
+	}
+
+	for _, volume := range manifest.Spec.Volumes {
+		if volume.HostPath == nil {
+			continue
+		}
+		readOnly := true
+		for _, container := range containers {
+			for _, mount := range container.VolumeMounts {
+				readOnly = readOnly && (mount.Name != volume.Name || mount.ReadOnly)
+			}
+		}
+		pod.HostPathVolumes = append(pod.HostPathVolumes, StaticPodHostPath{
+			Name:      volume.Name,
+			Path:      volume.HostPath.Path,
+			ReadOnly:  readOnly,
+			Sensitive: isSensitiveHostPath(volume.HostPath.Path),
+		})
+	}
+	return pod, nil
+}
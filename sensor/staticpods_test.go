@@ -0,0 +1,78 @@
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenseStaticPods(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	writeFSFile(t, hfs, "/etc/kubernetes/manifests/etcd.yaml", []byte(`apiVersion: v1
+kind: Pod
+metadata:
+  name: etcd
+  namespace: kube-system
+spec:
+  hostNetwork: true
+  containers:
+  - name: etcd
+    image: registry.k8s.io/etcd:3.5.6-0
+    volumeMounts:
+    - name: etcd-data
+      mountPath: /var/lib/etcd
+    - name: etcd-certs
+      mountPath: /etc/kubernetes/pki/etcd
+      readOnly: true
+  volumes:
+  - name: etcd-data
+    hostPath:
+      path: /var/lib/etcd
+  - name: etcd-certs
+    hostPath:
+      path: /etc/kubernetes/pki/etcd
+`))
+	writeFSFile(t, hfs, "/etc/kubernetes/manifests/node-agent.json", []byte(`{
+  "kind": "Pod",
+  "metadata": {"name": "node-agent"},
+  "spec": {
+    "hostPID": true,
+    "initContainers": [{"name": "setup", "image": "busybox", "volumeMounts": [{"name": "host-etc", "mountPath": "/host/etc"}]}],
+    "containers": [{"name": "agent", "image": "agent:1.0", "securityContext": {"privileged": true, "capabilities": {"add": ["SYS_ADMIN"]}}}],
+    "volumes": [{"name": "host-etc", "hostPath": {"path": "/etc"}}, {"name": "scratch", "emptyDir": {}}]
+  }
+}`))
+	writeFSFile(t, hfs, "/etc/kubernetes/manifests/.kube-apiserver.yaml.swp", []byte("kind: Pod"))
+	writeFSFile(t, hfs, "/etc/kubernetes/manifests/config.yaml", []byte("kind: ConfigMap"))
+
+	pods, err := SenseStaticPods(ctx)
+	require.NoError(t, err)
+	require.Len(t, pods, 2)
+
+	etcd := pods[0]
+	assert.Equal(t, "/etc/kubernetes/manifests/etcd.yaml", etcd.File.Path)
+	assert.Nil(t, etcd.File.Content)
+	assert.Equal(t, "etcd", etcd.Name)
+	assert.Equal(t, "kube-system", etcd.Namespace)
+	assert.True(t, etcd.ControlPlane)
+	assert.True(t, etcd.HostNetwork)
+	assert.Equal(t, []StaticPodHostPath{
+		{Name: "etcd-data", Path: "/var/lib/etcd", ReadOnly: false, Sensitive: false},
+		{Name: "etcd-certs", Path: "/etc/kubernetes/pki/etcd", ReadOnly: true, Sensitive: true},
+	}, etcd.HostPathVolumes)
+	assert.Equal(t, []StaticPodContainer{{Name: "etcd", Image: "registry.k8s.io/etcd:3.5.6-0"}}, etcd.Containers)
+
+	agent := pods[1]
+	assert.Equal(t, "node-agent", agent.Name)
+	assert.False(t, agent.ControlPlane)
+	assert.True(t, agent.HostPID)
+	assert.Equal(t, []StaticPodHostPath{{Name: "host-etc", Path: "/etc", ReadOnly: false, Sensitive: true}}, agent.HostPathVolumes)
+	assert.Equal(t, []StaticPodContainer{
+		{Name: "setup", Image: "busybox", Init: true},
+		{Name: "agent", Image: "agent:1.0", Privileged: true, Capabilities: []string{"SYS_ADMIN"}},
+	}, agent.Containers)
+}
@@ -0,0 +1,292 @@
+package sensor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	systemd_debus "github.com/coreos/go-systemd/v22/dbus"
+	"github.com/godbus/dbus/v5"
+	"go.uber.org/zap"
+)
+
+// Unit file states
+const (
+	UnitFileEnabled  = "enabled"
+	UnitFileDisabled = "disabled"
+	UnitFileStatic   = "static"
+	UnitFileMasked   = "masked"
+)
+
+// Unit active states (if systemd isn't reachable)
+const (
+	UnitActive   = "active"
+	UnitInactive = "inactive"
+)
+
+const (
+	systemdRuntimeDir = "/run/systemd/system/"
+	systemdLibDir     = "/lib/systemd/system/"
+
+	systemdServiceSection = "Service"
+	systemdInstallSection = "Install"
+)
+
+var (
+	// The units of the Kubernetes node services
+	kubernetesUnits = []string{
+		"kubelet.service",
+		"containerd.service",
+		"docker.service",
+		"crio.service",
+		"etcd.service",
+		"kube-proxy.service",
+	}
+
+	// Unit directories, by priority
+	systemdUnitDirs = []string{
+		systemdAdminDir,
+		systemdRuntimeDir,
+		systemdLibDir,
+		systemdPkgDir,
+	}
+
+	// The reported sandboxing directives of the [Service] section
+	systemdHardeningDirectives = []string{
+		"User",
+		"NoNewPrivileges",
+		"ProtectSystem",
+		"ProtectHome",
+		"PrivateTmp",
+		"PrivateDevices",
+		"ProtectKernelTunables",
+		"ProtectKernelModules",
+		"ProtectControlGroups",
+		"RestrictSUIDSGID",
+		"CapabilityBoundingSet",
+		"ReadOnlyPaths",
+	}
+)
+
+// SystemdUnit holds the state and hardening of a systemd unit
+type SystemdUnit struct {
+	Name string `json:"name"`
+
+	// The active state, e.g. "active" or "failed"
+	ActiveState string `json:"activeState,omitempty"`
+
+	// One of UnitFile*
+	UnitFileState string `json:"unitFileState"`
+
+	// The unit file and its drop-in files, in the order they're applied
+	UnitFile    string   `json:"unitFile,omitempty"`
+	DropInFiles []string `json:"dropInFiles,omitempty"`
+
+	// The hardening directives set in the [Service] section, by name
+	Hardening map[string]string `json:"hardening"`
+}
+
+// SenseSystemdUnits returns the systemd units of the Kubernetes node services which exist on the node.
+// The active states are read from systemd, or from the processes cgroups if systemd isn't reachable.
+func SenseSystemdUnits(ctx context.Context) ([]SystemdUnit, error) {
+	activeStates, err := getSystemdActiveStates(ctx, kubernetesUnits)
+	if err != nil {
+		logger(ctx).Debug("failed to get unit states from systemd, falling back to cgroups", zap.Error(err))
+		activeStates = getCgroupActiveStates(ctx, kubernetesUnits)
+	}
+
+	ret := []SystemdUnit{}
+	for _, name := range kubernetesUnits {
+		unit := readSystemdUnit(ctx, name)
+		if unit.UnitFile == "" && unit.UnitFileState != UnitFileMasked {
+			continue
+		}
+		unit.ActiveState = activeStates[name]
+		ret = append(ret, unit)
+	}
+	return ret, nil
+}
+
+// readSystemdUnit reads the unit file and drop-ins of a unit from the unit directories
+func readSystemdUnit(ctx context.Context, name string) SystemdUnit {
+	unit := SystemdUnit{Name: name, UnitFileState: UnitFileDisabled, Hardening: map[string]string{}}
+
+	// units linked to /dev/null in the admin dir are masked
+	if target, err := hostFS(ctx).Readlink(path.Join(systemdAdminDir, name)); err == nil && target == os.DevNull {
+		unit.UnitFileState = UnitFileMasked
+		return unit
+	}
+	for _, dir := range systemdUnitDirs {
+		if _, err := statHostFile(ctx, path.Join(dir, name)); err == nil {
+			unit.UnitFile = path.Join(dir, name)
+			break
+		}
+	}
+	if unit.UnitFile == "" {
+		return unit
+	}
+
+	// drop-ins are applied by file name, the higher priority directory wins for the same name
+	dropIns := map[string]string{}
+	for i := len(systemdUnitDirs) - 1; i >= 0; i-- {
+		for _, filePath := range globHostPaths(ctx, path.Join(systemdUnitDirs[i], name+".d", "*.conf")) {
+			dropIns[path.Base(filePath)] = filePath
+		}
+	}
+	for _, filePath := range dropIns {
+		unit.DropInFiles = append(unit.DropInFiles, filePath)
+	}
+	sort.Slice(unit.DropInFiles, func(i, j int) bool {
+		return path.Base(unit.DropInFiles[i]) < path.Base(unit.DropInFiles[j])
+	})
+
+	hasInstall := false
+	for _, filePath := range append([]string{unit.UnitFile}, unit.DropInFiles...) {
+		content, err := ReadFileOnHostFileSystem(ctx, filePath)
+		if err != nil {
+			logger(ctx).Debug("failed to read unit file", zap.String("path", filePath), zap.Error(err))
+			continue
+		}
+		for section, directives := range parseUnitFile(content) {
+			switch section {
+			case systemdInstallSection:
+				hasInstall = true
+			case systemdServiceSection:
+				for _, directive := range directives {
+					if !containsString(systemdHardeningDirectives, directive.name) {
+						continue
+					}
+					// an empty assignment resets the directive
+					if directive.value == "" {
+						delete(unit.Hardening, directive.name)
+					} else {
+						unit.Hardening[directive.name] = directive.value
+					}
+				}
+			}
+		}
+	}
+
+	switch {
+	case isUnitWanted(ctx, name):
+		unit.UnitFileState = UnitFileEnabled
+	case !hasInstall:
+		unit.UnitFileState = UnitFileStatic
+	}
+	return unit
+}
+
+// isUnitWanted returns true if the unit is enabled, i.e. linked in a .wants or .requires directory
+func isUnitWanted(ctx context.Context, name string) bool {
+	for _, pattern := range []string{"*.wants", "*.requires"} {
+		for _, dir := range []string{systemdAdminDir, systemdRuntimeDir} {
+			if len(globHostPaths(ctx, path.Join(dir, pattern, name))) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+type unitDirective struct {
+	name  string
+	value string
+}
+
+// parseUnitFile returns the directives of a unit file by section, in order
+func parseUnitFile(content []byte) map[string][]unitDirective {
+	ret := map[string][]unitDirective{}
+	section := ""
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		// continuation lines
+		for strings.HasSuffix(line, "\\") && scanner.Scan() {
+			line = strings.TrimSuffix(line, "\\") + " " + strings.TrimSpace(scanner.Text())
+		}
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			section = line[1 : len(line)-1]
+			if _, ok := ret[section]; !ok {
+				ret[section] = []unitDirective{}
+			}
+		default:
+			if name, value, ok := strings.Cut(line, "="); ok {
+				ret[section] = append(ret[section], unitDirective{strings.TrimSpace(name), strings.TrimSpace(value)})
+			}
+		}
+	}
+	return ret
+}
+
+// getUnitEnvironmentFiles returns the EnvironmentFiles of the [Service] section of unit files, in order
+func getUnitEnvironmentFiles(ctx context.Context, unitFiles []string) []string {
+	ret := []string{}
+	for _, unitFile := range unitFiles {
+		content, err := ReadFileOnHostFileSystem(ctx, unitFile)
+		if err != nil {
+			continue
+		}
+		for _, directive := range parseUnitFile(content)[systemdServiceSection] {
+			// a "-" prefix ignores missing files
+			if directive.name == systemdEnvironmentFile && directive.value != "" {
+				ret = append(ret, strings.TrimPrefix(directive.value, "-"))
+			}
+		}
+	}
+	return ret
+}
+
+// getSystemdActiveStates returns the active states of the loaded units, by name
+func getSystemdActiveStates(ctx context.Context, names []string) (map[string]string, error) {
+	conn, err := systemd_debus.NewConnection(func() (*dbus.Conn, error) { return newSystemDbusConnection(ctx) })
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	statuses, err := conn.ListUnitsByNamesContext(ctx, names)
+	if err != nil {
+		return nil, err
+	}
+	ret := map[string]string{}
+	for _, status := range statuses {
+		if status.LoadState != "not-found" {
+			ret[status.Name] = status.ActiveState
+		}
+	}
+	return ret, nil
+}
+
+// getCgroupActiveStates returns the units which have a process in their cgroup as active, the others as inactive
+func getCgroupActiveStates(ctx context.Context, names []string) map[string]string {
+	ret := map[string]string{}
+	for _, name := range names {
+		ret[name] = UnitInactive
+	}
+
+	cgroupFiles, err := fs.Glob(hostFS(ctx), path.Join(procDirName, "*", "cgroup"))
+	if err != nil {
+		return ret
+	}
+	for _, cgroupFile := range cgroupFiles {
+		content, err := hostFS(ctx).ReadFile(cgroupFile)
+		if err != nil {
+			logger(ctx).Debug("failed to read cgroup", zap.String("path", cgroupFile), zap.Error(err))
+			recordCollectionError(ctx, "read", cgroupFile, err)
+			continue
+		}
+		for _, name := range names {
+			if bytes.Contains(content, []byte("/"+name+"\n")) || bytes.Contains(content, []byte("/"+name+"/")) {
+				ret[name] = UnitActive
+			}
+		}
+	}
+	return ret
+}
@@ -0,0 +1,80 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenseSystemdUnits(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	for filePath, content := range map[string]string{
+		"/lib/systemd/system/kubelet.service": `[Unit]
+Description=kubelet
+
+[Service]
+ExecStart=/usr/bin/kubelet
+ProtectSystem=full
+NoNewPrivileges=yes
+
+[Install]
+WantedBy=multi-user.target
+`,
+		"/etc/systemd/system/kubelet.service.d/10-kubeadm.conf": `[Service]
+ExecStart=
+ExecStart=/usr/bin/kubelet \
+  --config=/var/lib/kubelet/config.yaml
+ProtectSystem=
+`,
+		"/lib/systemd/system/kubelet.service.d/20-hardening.conf": "[Service]\nPrivateTmp=true\n",
+		"/lib/systemd/system/containerd.service":                  "[Service]\nExecStart=/usr/bin/containerd\n\n[Install]\nWantedBy=multi-user.target\n",
+		"/usr/lib/systemd/system/etcd.service":                    "[Service]\nUser=etcd\n",
+		"/proc/1/cgroup":                                          "0::/init.scope\n",
+		"/proc/100/cgroup":                                        "0::/system.slice/kubelet.service\n",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(hfs.Path(filePath)), 0o755))
+		require.NoError(t, os.WriteFile(hfs.Path(filePath), []byte(content), 0o644))
+	}
+	require.NoError(t, os.MkdirAll(hfs.Path("/etc/systemd/system/multi-user.target.wants"), 0o755))
+	require.NoError(t, os.Symlink("/lib/systemd/system/kubelet.service", hfs.Path("/etc/systemd/system/multi-user.target.wants/kubelet.service")))
+	require.NoError(t, os.Symlink(os.DevNull, hfs.Path("/etc/systemd/system/docker.service")))
+
+	units, err := SenseSystemdUnits(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []SystemdUnit{
+		{
+			Name:          "kubelet.service",
+			ActiveState:   UnitActive,
+			UnitFileState: UnitFileEnabled,
+			UnitFile:      "/lib/systemd/system/kubelet.service",
+			DropInFiles:   []string{"/etc/systemd/system/kubelet.service.d/10-kubeadm.conf", "/lib/systemd/system/kubelet.service.d/20-hardening.conf"},
+			Hardening:     map[string]string{"NoNewPrivileges": "yes", "PrivateTmp": "true"},
+		},
+		{
+			Name:          "containerd.service",
+			ActiveState:   UnitInactive,
+			UnitFileState: UnitFileDisabled,
+			UnitFile:      "/lib/systemd/system/containerd.service",
+			Hardening:     map[string]string{},
+		},
+		{
+			Name:          "docker.service",
+			ActiveState:   UnitInactive,
+			UnitFileState: UnitFileMasked,
+			Hardening:     map[string]string{},
+		},
+		{
+			Name:          "etcd.service",
+			ActiveState:   UnitInactive,
+			UnitFileState: UnitFileStatic,
+			UnitFile:      "/usr/lib/systemd/system/etcd.service",
+			Hardening:     map[string]string{"User": "etcd"},
+		},
+	}, units)
+}
@@ -0,0 +1,233 @@
+package sensor
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Time synchronization daemons
+const (
+	TimeSyncChrony    = "chronyd"
+	TimeSyncNTP       = "ntpd"
+	TimeSyncTimesyncd = "systemd-timesyncd"
+)
+
+const (
+	ntpPacketSize = 48
+	// Seconds between the NTP epoch (1900) and the Unix epoch
+	ntpEpochOffset = 2208988800
+	// Max number of servers queried for the clock offset
+	ntpMaxQueriedServers = 3
+	ntpQueryTimeout      = 2 * time.Second
+
+	timesyncdSection = "Time"
+)
+
+var (
+	// Config files of the daemons, the first existing main config is used
+	timeSyncConfigs = []struct {
+		daemon   string
+		configs  []string
+		includes []string
+	}{
+		{TimeSyncChrony, []string{"/etc/chrony.conf", "/etc/chrony/chrony.conf"}, []string{"/etc/chrony/conf.d/*.conf", "/etc/chrony/sources.d/*.sources"}},
+		{TimeSyncNTP, []string{"/etc/ntp.conf", "/etc/ntpsec/ntp.conf"}, nil},
+		{TimeSyncTimesyncd, []string{"/etc/systemd/timesyncd.conf"}, []string{"/etc/systemd/timesyncd.conf.d/*.conf"}},
+	}
+
+	// Overridden by tests
+	ntpPort = 123
+)
+
+// TimeSyncInfo holds the time synchronization status of the node
+type TimeSyncInfo struct {
+	// The installed or running time synchronization daemons
+	Daemons []TimeSyncDaemon `json:"daemons"`
+
+	// True if the kernel clock is synchronized (by any daemon)
+	Synchronized bool `json:"synchronized"`
+
+	// The max and the estimated error of the kernel clock, as maintained by the synchronization daemon
+	MaxErrorMicroseconds       int64 `json:"maxErrorMicroseconds"`
+	EstimatedErrorMicroseconds int64 `json:"estimatedErrorMicroseconds"`
+
+	// The clock offset measured against a configured server, if any responded
+	ClockOffset *ClockOffset `json:"clockOffset,omitempty"`
+}
+
+// TimeSyncDaemon is a time synchronization daemon and its configured servers
+type TimeSyncDaemon struct {
+	// One of TimeSync*
+	Name string `json:"name"`
+
+	Running bool `json:"running"`
+
+	ConfigFiles []string `json:"configFiles,omitempty"`
+
+	// The configured servers and pools
+	Servers []string `json:"servers,omitempty"`
+}
+
+// ClockOffset is the offset of the local clock from a time server (positive if the local clock is behind)
+type ClockOffset struct {
+	Server       string `json:"server"`
+	OffsetMillis int64  `json:"offsetMillis"`
+}
+
+// SenseTimeSync returns the time synchronization daemons, the kernel clock status, and the clock offset
+// measured against the configured servers.
+func SenseTimeSync(ctx context.Context) (*TimeSyncInfo, error) {
+	ret := &TimeSyncInfo{Daemons: []TimeSyncDaemon{}}
+
+	servers := []string{}
+	for _, timeSync := range timeSyncConfigs {
+		daemon := readTimeSyncDaemon(ctx, timeSync.daemon, timeSync.configs, timeSync.includes)
+		if _, err := LocateProcessByExecSuffix(ctx, "/"+timeSync.daemon); err == nil {
+			daemon.Running = true
+		}
+		if daemon.Running || len(daemon.ConfigFiles) > 0 {
+			ret.Daemons = append(ret.Daemons, daemon)
+		}
+		if daemon.Running {
+			servers = append(servers, daemon.Servers...)
+		}
+	}
+
+	if err := readKernelClock(ret); err != nil {
+		return nil, fmt.Errorf("failed to read kernel clock status: %w", err)
+	}
+
+	if !GetSenseOptions(ctx).Probes {
+		return ret, nil
+	}
+	for i, server := range servers {
+		if i == ntpMaxQueriedServers {
+			break
+		}
+		offset, err := queryClockOffset(ctx, server)
+		if err != nil {
+			logger(ctx).Debug("failed to query time server", zap.String("server", server), zap.Error(err))
+			continue
+		}
+		ret.ClockOffset = &ClockOffset{Server: server, OffsetMillis: offset.Milliseconds()}
+		break
+	}
+	return ret, nil
+}
+
+// readTimeSyncDaemon reads the configured servers of a daemon from its config files
+func readTimeSyncDaemon(ctx context.Context, name string, configs, includes []string) TimeSyncDaemon {
+	daemon := TimeSyncDaemon{Name: name}
+	for _, config := range configs {
+		if _, err := ReadFileOnHostFileSystem(ctx, config); err == nil {
+			daemon.ConfigFiles = append(daemon.ConfigFiles, config)
+			break
+		}
+	}
+	for _, pattern := range includes {
+		daemon.ConfigFiles = append(daemon.ConfigFiles, globHostPaths(ctx, pattern)...)
+	}
+
+	for _, config := range daemon.ConfigFiles {
+		content, err := ReadFileOnHostFileSystem(ctx, config)
+		if err != nil {
+			logger(ctx).Debug("failed to read time sync config", zap.String("path", config), zap.Error(err))
+			continue
+		}
+		if name == TimeSyncTimesyncd {
+			daemon.Servers = append(daemon.Servers, parseTimesyncdServers(content)...)
+		} else {
+			daemon.Servers = append(daemon.Servers, parseNTPServers(content)...)
+		}
+	}
+	return daemon
+}
+
+// parseNTPServers returns the servers of the server, pool and peer directives of chrony and ntpd configs
+func parseNTPServers(content []byte) []string {
+	ret := []string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "server", "pool", "peer":
+			ret = append(ret, fields[1])
+		}
+	}
+	return ret
+}
+
+// parseTimesyncdServers returns the NTP servers of a timesyncd config, and the fallback servers
+func parseTimesyncdServers(content []byte) []string {
+	ret := []string{}
+	for _, name := range []string{"NTP", "FallbackNTP"} {
+		for _, directive := range parseUnitFile(content)[timesyncdSection] {
+			if directive.name == name {
+				ret = append(ret, strings.Fields(directive.value)...)
+			}
+		}
+	}
+	return ret
+}
+
+// queryClockOffset returns the offset of the local clock from an NTP server, with an SNTP query
+func queryClockOffset(ctx context.Context, server string) (offset time.Duration, err error) {
+	step := startStep(ctx, "network probe", "net.peer.name", server, "net.transport", "udp")
+	defer func() { step.finish(err) }()
+
+	ctx, cancel := context.WithTimeout(ctx, ntpQueryTimeout)
+	defer cancel()
+
+	dialer := net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "udp", net.JoinHostPort(server, strconv.Itoa(ntpPort)))
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	// LI 0, version 3, mode 3 (client)
+	request := make([]byte, ntpPacketSize)
+	request[0] = 0x1b
+	sent := time.Now()
+	if _, err := conn.Write(request); err != nil {
+		return 0, err
+	}
+	response := make([]byte, ntpPacketSize)
+	if _, err := conn.Read(response); err != nil {
+		return 0, err
+	}
+	received := time.Now()
+
+	if mode := response[0] & 7; mode != 4 {
+		return 0, fmt.Errorf("unexpected NTP mode %d", mode)
+	}
+	serverReceived := ntpTime(response[32:40])
+	serverSent := ntpTime(response[40:48])
+	if serverSent.IsZero() {
+		return 0, fmt.Errorf("empty NTP response")
+	}
+	return (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2, nil
+}
+
+// ntpTime decodes an NTP timestamp, returns the zero time for an empty timestamp
+func ntpTime(b []byte) time.Time {
+	seconds := binary.BigEndian.Uint32(b[:4])
+	fraction := binary.BigEndian.Uint32(b[4:])
+	if seconds == 0 && fraction == 0 {
+		return time.Time{}
+	}
+	nanos := (int64(fraction) * 1e9) >> 32
+	return time.Unix(int64(seconds)-ntpEpochOffset, nanos)
+}
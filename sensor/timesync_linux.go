@@ -0,0 +1,21 @@
+package sensor
+
+import "syscall"
+
+// Kernel clock status bit of an unsynchronized clock
+const kernelStatusUnsync = 0x0040
+
+// Overridden by tests
+var adjtimex = syscall.Adjtimex
+
+// readKernelClock fills the synchronization status and the error estimates of the kernel clock
+func readKernelClock(ret *TimeSyncInfo) error {
+	timex := &syscall.Timex{}
+	if _, err := adjtimex(timex); err != nil {
+		return err
+	}
+	ret.Synchronized = timex.Status&kernelStatusUnsync == 0
+	ret.MaxErrorMicroseconds = int64(timex.Maxerror)
+	ret.EstimatedErrorMicroseconds = int64(timex.Esterror)
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package sensor
+
+// readKernelClock fills the synchronization status and the error estimates of the kernel clock
+func readKernelClock(ret *TimeSyncInfo) error {
+	return errNotSupported
+}
@@ -0,0 +1,89 @@
+//go:build linux
+
+package sensor
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenseTimeSync(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+	defer func(orig func(*syscall.Timex) (int, error)) { adjtimex = orig }(adjtimex)
+	adjtimex = func(timex *syscall.Timex) (int, error) {
+		timex.Status = kernelStatusUnsync
+		timex.Maxerror = 16000000
+		return 5, nil
+	}
+
+	for filePath, content := range map[string]string{
+		"/etc/chrony/chrony.conf":             "# servers\npool 2.debian.pool.ntp.org iburst\nserver 169.254.169.123 prefer\ndriftfile /var/lib/chrony/chrony.drift\n",
+		"/etc/chrony/sources.d/local.sources": "server ntp.example.com\n",
+		"/etc/systemd/timesyncd.conf":         "[Time]\n#NTP=\nNTP=time1.example.com time2.example.com\nFallbackNTP=ntp.ubuntu.com\n",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(hfs.Path(filePath)), 0o755))
+		require.NoError(t, os.WriteFile(hfs.Path(filePath), []byte(content), 0o644))
+	}
+
+	info, err := SenseTimeSync(ctx)
+	require.NoError(t, err)
+	// the daemons running on the machine of the test are ignored
+	daemons := []TimeSyncDaemon{}
+	for _, daemon := range info.Daemons {
+		if len(daemon.ConfigFiles) > 0 {
+			daemon.Running = false
+			daemons = append(daemons, daemon)
+		}
+	}
+	assert.Equal(t, []TimeSyncDaemon{
+		{
+			Name:        TimeSyncChrony,
+			ConfigFiles: []string{"/etc/chrony/chrony.conf", "/etc/chrony/sources.d/local.sources"},
+			Servers:     []string{"2.debian.pool.ntp.org", "169.254.169.123", "ntp.example.com"},
+		},
+		{
+			Name:        TimeSyncTimesyncd,
+			ConfigFiles: []string{"/etc/systemd/timesyncd.conf"},
+			Servers:     []string{"time1.example.com", "time2.example.com", "ntp.ubuntu.com"},
+		},
+	}, daemons)
+	assert.False(t, info.Synchronized)
+	assert.Equal(t, int64(16000000), info.MaxErrorMicroseconds)
+}
+
+func TestQueryClockOffset(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	defer func(orig int) { ntpPort = orig }(ntpPort)
+	ntpPort = conn.LocalAddr().(*net.UDPAddr).Port
+
+	// a server whose clock is 10 seconds ahead
+	go func() {
+		request := make([]byte, ntpPacketSize)
+		_, addr, err := conn.ReadFrom(request)
+		if err != nil {
+			return
+		}
+		response := make([]byte, ntpPacketSize)
+		response[0] = 0x1c
+		serverTime := uint32(time.Now().Add(10*time.Second).Unix() + ntpEpochOffset)
+		binary.BigEndian.PutUint32(response[32:], serverTime)
+		binary.BigEndian.PutUint32(response[40:], serverTime)
+		conn.WriteTo(response, addr)
+	}()
+
+	offset, err := queryClockOffset(context.Background(), "127.0.0.1")
+	require.NoError(t, err)
+	assert.InDelta(t, 10*time.Second, offset, float64(2*time.Second))
+}
@@ -0,0 +1,83 @@
+package sensor
+
+import (
+	"context"
+	"strings"
+
+	"go.uber.org/zap"
+	"sigs.k8s.io/yaml"
+)
+
+// TLSConfig holds the TLS settings of a component's server
+type TLSConfig struct {
+	// The component, e.g. "kubelet"
+	Component string `json:"component"`
+
+	// The configured cipher suites (IANA names), empty for the defaults
+	CipherSuites []string `json:"cipherSuites,omitempty"`
+
+	// The configured minimal TLS version (e.g. "VersionTLS12"), empty for the default
+	MinVersion string `json:"minVersion,omitempty"`
+}
+
+var (
+	// Command line arguments of the TLS settings, by process
+	tlsConfigArgs = []struct {
+		component       string
+		exe             string
+		cipherSuitesArg string
+		minVersionArg   string
+	}{
+		{"kubelet", kubeletProcessSuffix, "--tls-cipher-suites", "--tls-min-version"},
+		{"apiserver", apiServerExe, "--tls-cipher-suites", "--tls-min-version"},
+		{"etcd", etcdExe, "--cipher-suites", "--tls-min-version"},
+	}
+)
+
+// SenseTLSConfigs returns the TLS settings of the kubelet, the API server and etcd (of the running components).
+// The kubelet command line flags take precedence over its config file.
+func SenseTLSConfigs(ctx context.Context) ([]TLSConfig, error) {
+	ret := []TLSConfig{}
+	for _, component := range tlsConfigArgs {
+		proc, err := LocateProcessByExecSuffix(ctx, component.exe)
+		if err != nil {
+			continue
+		}
+
+		conf := TLSConfig{Component: component.component}
+		if component.exe == kubeletProcessSuffix {
+			conf.CipherSuites, conf.MinVersion = readKubeletTLSConfig(ctx, proc)
+		}
+		if cipherSuites, ok := proc.GetArg(component.cipherSuitesArg); ok && cipherSuites != "" {
+			conf.CipherSuites = strings.Split(cipherSuites, ",")
+		}
+		if minVersion, ok := proc.GetArg(component.minVersionArg); ok && minVersion != "" {
+			conf.MinVersion = minVersion
+		}
+		ret = append(ret, conf)
+	}
+	return ret, nil
+}
+
+// readKubeletTLSConfig returns the TLS settings of the kubelet config file
+func readKubeletTLSConfig(ctx context.Context, kubeletProcess *ProcessDetails) ([]string, string) {
+	configPath := kubeletConfigDefaultPath
+	if p, ok := kubeletProcess.GetArg(kubeletConfigArgName); ok {
+		configPath = p
+	}
+	content, err := ReadKubeletConfig(ctx, configPath)
+	if err != nil {
+		logger(ctx).Debug("failed to read kubelet config", zap.String("path", configPath), zap.Error(err))
+		return nil, ""
+	}
+
+	conf := struct {
+		TLSCipherSuites []string `json:"tlsCipherSuites"`
+		TLSMinVersion   string   `json:"tlsMinVersion"`
+	}{}
+	if err := yaml.Unmarshal(content, &conf); err != nil {
+		logger(ctx).Debug("failed to parse kubelet config", zap.String("path", configPath), zap.Error(err))
+		return nil, ""
+	}
+	return conf.TLSCipherSuites, conf.TLSMinVersion
+}
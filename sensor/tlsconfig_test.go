@@ -0,0 +1,35 @@
+package sensor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_readKubeletTLSConfig(t *testing.T) {
+	hfs := NewHostFS(t.TempDir())
+	ctx := WithHostFS(context.Background(), hfs)
+
+	configPath := "/etc/kubelet/config.yaml"
+	require.NoError(t, os.MkdirAll(filepath.Dir(hfs.Path(configPath)), 0o755))
+	require.NoError(t, os.WriteFile(hfs.Path(configPath), []byte(`
+kind: KubeletConfiguration
+tlsCipherSuites:
+- TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256
+- TLS_RSA_WITH_3DES_EDE_CBC_SHA
+tlsMinVersion: VersionTLS11
+`), 0o644))
+
+	cipherSuites, minVersion := readKubeletTLSConfig(ctx, &ProcessDetails{CmdLine: []string{"/usr/bin/kubelet", "--config", configPath}})
+	assert.Equal(t, []string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256", "TLS_RSA_WITH_3DES_EDE_CBC_SHA"}, cipherSuites)
+	assert.Equal(t, "VersionTLS11", minVersion)
+
+	// missing config file
+	cipherSuites, minVersion = readKubeletTLSConfig(ctx, &ProcessDetails{CmdLine: []string{"/usr/bin/kubelet"}})
+	assert.Empty(t, cipherSuites)
+	assert.Empty(t, minVersion)
+}
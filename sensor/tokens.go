@@ -0,0 +1,234 @@
+package sensor
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Token types
+const (
+	TokenServiceAccount = "serviceAccount"
+	TokenBootstrap      = "bootstrap"
+)
+
+// Token locations
+const (
+	TokenLocationPodVolume           = "podVolume"
+	TokenLocationBootstrapKubeconfig = "bootstrapKubeconfig"
+	TokenLocationTmp                 = "tmp"
+)
+
+const (
+	bootstrapKubeconfigPath = "/etc/kubernetes/bootstrap-kubelet.conf"
+	tmpDir                  = "/tmp"
+
+	// Max depth of the tmp dir scan
+	tmpScanMaxDepth = 3
+
+	// Size of the file head searched for tokens
+	tokenHeadSize = 64 * 1024
+)
+
+var (
+	// Glob patterns of the service account tokens mounted to the pods by the kubelet
+	podTokenPatterns = []string{
+		"/var/lib/kubelet/pods/*/volumes/kubernetes.io~projected/*/token",
+		"/var/lib/kubelet/pods/*/volumes/kubernetes.io~serviceaccount/*/token",
+		"/var/lib/kubelet/pods/*/volumes/kubernetes.io~secret/*/token",
+	}
+
+	jwtRegexp            = regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`)
+	bootstrapTokenRegexp = regexp.MustCompile(`\b([a-z0-9]{6})\.[a-z0-9]{16}\b`)
+)
+
+// TokenInfo holds the claims of a token found on the node, without the token value
+type TokenInfo struct {
+	// Information about the file holding the token (without its content)
+	File *FileInfo `json:"file"`
+
+	// One of Token*
+	Type string `json:"type"`
+
+	// One of TokenLocation*
+	Location string `json:"location"`
+
+	// UID of the pod whose volume holds the token (for pod volume tokens)
+	PodUID string `json:"podUID,omitempty"`
+
+	// Service account token claims
+	Issuer         string     `json:"issuer,omitempty"`
+	Subject        string     `json:"subject,omitempty"`
+	Audiences      []string   `json:"audiences,omitempty"`
+	IssuedAt       *time.Time `json:"issuedAt,omitempty"`
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty"`
+	Namespace      string     `json:"namespace,omitempty"`
+	ServiceAccount string     `json:"serviceAccount,omitempty"`
+
+	// The public part of a bootstrap token
+	TokenID string `json:"tokenID,omitempty"`
+}
+
+// jwtClaims holds the claims of bound and legacy service account tokens
+type jwtClaims struct {
+	Issuer     string          `json:"iss"`
+	Subject    string          `json:"sub"`
+	Audience   json.RawMessage `json:"aud"`
+	IssuedAt   *int64          `json:"iat"`
+	Expiration *int64          `json:"exp"`
+	Kubernetes *struct {
+		Namespace      string `json:"namespace"`
+		ServiceAccount struct {
+			Name string `json:"name"`
+		} `json:"serviceaccount"`
+	} `json:"kubernetes.io"`
+	LegacyNamespace      string `json:"kubernetes.io/serviceaccount/namespace"`
+	LegacyServiceAccount string `json:"kubernetes.io/serviceaccount/service-account.name"`
+}
+
+// SenseTokens returns the service account and bootstrap tokens found in the pod volumes,
+// the kubelet bootstrap kubeconfig and the tmp dir of the node.
+func SenseTokens(ctx context.Context) ([]TokenInfo, error) {
+	ret := []TokenInfo{}
+
+	for _, pattern := range podTokenPatterns {
+		tokenPaths, err := fs.Glob(hostFS(ctx), pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, filePath := range tokenPaths {
+			tokens := findTokens(ctx, filePath, TokenLocationPodVolume)
+			podUID := strings.SplitN(strings.TrimPrefix(filePath, "/var/lib/kubelet/pods/"), "/", 2)[0]
+			for i := range tokens {
+				tokens[i].PodUID = podUID
+			}
+			ret = append(ret, tokens...)
+		}
+	}
+
+	ret = append(ret, findTokens(ctx, bootstrapKubeconfigPath, TokenLocationBootstrapKubeconfig)...)
+
+	err := fs.WalkDir(hostFS(ctx), tmpDir, func(filePath string, d fs.DirEntry, err error) error {
+		if err != nil {
+			logger(ctx).Debug("SenseTokens failed to walk", zap.String("path", filePath), zap.Error(err))
+			recordCollectionError(ctx, "walk", filePath, err)
+			return nil
+		}
+		if d.IsDir() && filePath != tmpDir && strings.Count(strings.TrimPrefix(filePath, tmpDir), "/") >= tmpScanMaxDepth {
+			return fs.SkipDir
+		}
+		if d.Type().IsRegular() {
+			ret = append(ret, findTokens(ctx, filePath, TokenLocationTmp)...)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(ret, func(i, j int) bool { return ret[i].File.Path < ret[j].File.Path })
+	return ret, nil
+}
+
+// findTokens returns the tokens in the head of the host file at `filePath`
+func findTokens(ctx context.Context, filePath, location string) []TokenInfo {
+	f, err := hostFS(ctx).Open(filePath)
+	if err != nil {
+		logger(ctx).Debug("failed to open token file", zap.String("path", filePath), zap.Error(err))
+		recordCollectionError(ctx, "open", filePath, err)
+		return nil
+	}
+	defer f.Close()
+
+	head, err := io.ReadAll(io.LimitReader(f, tokenHeadSize))
+	if err != nil {
+		logger(ctx).Debug("failed to read token file", zap.String("path", filePath), zap.Error(err))
+		return nil
+	}
+
+	ret := []TokenInfo{}
+	for _, jwt := range jwtRegexp.FindAll(head, -1) {
+		token, err := parseServiceAccountToken(string(jwt))
+		if err != nil {
+			logger(ctx).Debug("failed to parse token", zap.String("path", filePath), zap.Error(err))
+			continue
+		}
+		ret = append(ret, *token)
+	}
+	// the JWTs may contain strings matching bootstrap tokens
+	head = jwtRegexp.ReplaceAll(head, nil)
+	for _, match := range bootstrapTokenRegexp.FindAllSubmatch(head, -1) {
+		ret = append(ret, TokenInfo{Type: TokenBootstrap, TokenID: string(match[1])})
+	}
+	if len(ret) == 0 {
+		return nil
+	}
+
+	fileInfo, err := makeHostFileInfo(ctx, filePath, false)
+	if err != nil {
+		logger(ctx).Debug("failed to MakeHostFileInfo for token file", zap.String("path", filePath), zap.Error(err))
+		return nil
+	}
+	for i := range ret {
+		ret[i].File = fileInfo
+		ret[i].Location = location
+	}
+	return ret
+}
+
+// parseServiceAccountToken returns the claims of a service account JWT, without verifying its signature
+func parseServiceAccountToken(jwt string) (*TokenInfo, error) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return nil, errors.New("malformed JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, err
+	}
+	claims := jwtClaims{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, err
+	}
+
+	ret := &TokenInfo{
+		Type:           TokenServiceAccount,
+		Issuer:         claims.Issuer,
+		Subject:        claims.Subject,
+		Namespace:      claims.LegacyNamespace,
+		ServiceAccount: claims.LegacyServiceAccount,
+	}
+	if claims.Kubernetes != nil {
+		ret.Namespace = claims.Kubernetes.Namespace
+		ret.ServiceAccount = claims.Kubernetes.ServiceAccount.Name
+	}
+
+	// the audience is either a string or a list of strings
+	if len(claims.Audience) > 0 {
+		audience := ""
+		if err := json.Unmarshal(claims.Audience, &audience); err == nil {
+			ret.Audiences = []string{audience}
+		} else if err := json.Unmarshal(claims.Audience, &ret.Audiences); err != nil {
+			return nil, err
+		}
+	}
+
+	if claims.IssuedAt != nil {
+		issuedAt := time.Unix(*claims.IssuedAt, 0).UTC()
+		ret.IssuedAt = &issuedAt
+	}
+	if claims.Expiration != nil {
+		expiresAt := time.Unix(*claims.Expiration, 0).UTC()
+		ret.ExpiresAt = &expiresAt
+	}
+	return ret, nil
+}
@@ -0,0 +1,44 @@
+package sensor
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSenseTokens(t *testing.T) {
+	hfs := NewHostFS("testdata/tokens")
+	ctx := WithHostFS(context.Background(), hfs)
+
+	tokens, err := SenseTokens(ctx)
+	require.NoError(t, err)
+	require.Len(t, tokens, 3)
+
+	bootstrap := tokens[0]
+	assert.Equal(t, "/etc/kubernetes/bootstrap-kubelet.conf", bootstrap.File.Path)
+	assert.Equal(t, TokenBootstrap, bootstrap.Type)
+	assert.Equal(t, TokenLocationBootstrapKubeconfig, bootstrap.Location)
+	assert.Equal(t, "abcdef", bootstrap.TokenID)
+
+	legacy := tokens[1]
+	assert.Equal(t, "/tmp/debug/curl.sh", legacy.File.Path)
+	assert.Equal(t, TokenServiceAccount, legacy.Type)
+	assert.Equal(t, TokenLocationTmp, legacy.Location)
+	assert.Equal(t, "default", legacy.Namespace)
+	assert.Equal(t, "deployer", legacy.ServiceAccount)
+	assert.Nil(t, legacy.ExpiresAt)
+
+	bound := tokens[2]
+	assert.True(t, strings.HasPrefix(bound.File.Path, "/var/lib/kubelet/pods/0b6c3f7e/"))
+	assert.Equal(t, TokenLocationPodVolume, bound.Location)
+	assert.Equal(t, "0b6c3f7e", bound.PodUID)
+	assert.Equal(t, "kube-system", bound.Namespace)
+	assert.Equal(t, "coredns", bound.ServiceAccount)
+	assert.Equal(t, []string{"https://kubernetes.default.svc.cluster.local"}, bound.Audiences)
+	require.NotNil(t, bound.ExpiresAt)
+	assert.Equal(t, time.Date(2022, 9, 1, 10, 0, 0, 0, time.UTC), *bound.ExpiresAt)
+}
@@ -0,0 +1,152 @@
+package sensor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/armosec/host-sensor/tracing"
+	"go.uber.org/zap"
+)
+
+// SenseMetrics measures a sensor run
+type SenseMetrics struct {
+	Duration       time.Duration `json:"-"`
+	DurationMillis int64         `json:"durationMillis"`
+
+	// The sub-collections of the run (process scans, directory walks, file reads and network probes), keyed by name
+	Steps map[string]*StepMetrics `json:"steps,omitempty"`
+
+	// Number of the items (mostly files) the sensor failed to collect
+	FailedFiles int `json:"failedFiles"`
+}
+
+// StepMetrics measures the sub-collections of a kind in a sensor run
+type StepMetrics struct {
+	Count          int           `json:"count"`
+	Failed         int           `json:"failed,omitempty"`
+	Duration       time.Duration `json:"-"`
+	DurationMillis int64         `json:"durationMillis"`
+}
+
+// sensorRun is the instrumentation of a sensor run, carried in the context of the sensor
+type sensorRun struct {
+	span    *tracing.Span
+	metrics *SenseMetrics
+
+	// Logger of the sensor, stamped with the request ID
+	logger *zap.Logger
+
+	// The progress of the sensor, nil if not reported
+	progress *sensorProgress
+
+	// The items the sensor failed to collect
+	collectionErrors []CollectionError
+
+	// Guards the metrics, the progress and the collection errors, the sensors may collect concurrently
+	lock sync.Mutex
+}
+
+type sensorRunKey struct{}
+
+// runFromContext returns the sensor run of `ctx`, nil if the context isn't of an instrumented sensor
+func runFromContext(ctx context.Context) *sensorRun {
+	run, _ := ctx.Value(sensorRunKey{}).(*sensorRun)
+	return run
+}
+
+// Instrument runs `sense` as the sensor `name`, and returns its metrics and the items it failed to collect.
+// It's traced in a span, a child of the span of `ctx`, and its sub-collections are traced as the children of the
+// sensor span. `sense` must collect with the context it's called with, which carries the run.
+func Instrument(ctx context.Context, name string, sense func(ctx context.Context)) (*SenseMetrics, []CollectionError) {
+	run := &sensorRun{
+		span:             tracing.StartChild(tracing.SpanFromContext(ctx), "sensor "+name, tracing.KindInternal),
+		metrics:          &SenseMetrics{Steps: map[string]*StepMetrics{}},
+		logger:           Logger(ctx),
+		collectionErrors: []CollectionError{},
+	}
+	run.span.SetAttribute("sensor.name", name)
+	if report := progressReporter(ctx); report != nil {
+		run.progress = &sensorProgress{report: report, current: Progress{Sensor: name}, start: time.Now()}
+	}
+
+	start := time.Now()
+	sense(context.WithValue(tracing.ContextWithSpan(ctx, run.span), sensorRunKey{}, run))
+
+	run.lock.Lock()
+	run.metrics.Duration = time.Since(start)
+	run.metrics.DurationMillis = run.metrics.Duration.Milliseconds()
+	for _, step := range run.metrics.Steps {
+		step.DurationMillis = step.Duration.Milliseconds()
+	}
+	collectionErrors := run.collectionErrors
+	run.lock.Unlock()
+
+	run.span.SetAttribute("sensor.failed_files", run.metrics.FailedFiles)
+	run.span.Finish()
+	return run.metrics, collectionErrors
+}
+
+// logger returns the logger of the sensor run of `ctx`, stamped with its request ID.
+// Its repeated warnings and errors are suppressed.
+func logger(ctx context.Context) *zap.Logger {
+	if run := runFromContext(ctx); run != nil {
+		return run.logger.WithOptions(dedupLogs)
+	}
+	return Logger(ctx).WithOptions(dedupLogs)
+}
+
+// step is a sub-collection of a sensor run
+type step struct {
+	run   *sensorRun
+	name  string
+	start time.Time
+	span  *tracing.Span
+}
+
+// startStep starts a sub-collection of the sensor run of `ctx`, nil if the context isn't of an instrumented sensor
+func startStep(ctx context.Context, name string, attributes ...interface{}) *step {
+	run := runFromContext(ctx)
+	if run == nil {
+		return nil
+	}
+
+	s := &step{run: run, name: name, start: time.Now(), span: tracing.StartChild(run.span, name, tracing.KindInternal)}
+	if s.span == nil {
+		return s
+	}
+	for i := 0; i+1 < len(attributes); i += 2 {
+		s.span.SetAttribute(attributes[i].(string), attributes[i+1])
+	}
+	return s
+}
+
+// setAttribute sets an attribute of the span of the step
+func (s *step) setAttribute(key string, value interface{}) {
+	if s != nil {
+		s.span.SetAttribute(key, value)
+	}
+}
+
+// finish ends the step, failed if `err` is set
+func (s *step) finish(err error) {
+	if s == nil {
+		return
+	}
+	duration := time.Since(s.start)
+	s.span.RecordError(err)
+	s.span.Finish()
+
+	s.run.lock.Lock()
+	defer s.run.lock.Unlock()
+	metrics := s.run.metrics.Steps[s.name]
+	if metrics == nil {
+		metrics = &StepMetrics{}
+		s.run.metrics.Steps[s.name] = metrics
+	}
+	metrics.Count++
+	metrics.Duration += duration
+	if err != nil {
+		metrics.Failed++
+	}
+}
@@ -0,0 +1,67 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/armosec/host-sensor/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestInstrument(t *testing.T) {
+	assert.Nil(t, startStep(context.Background(), "file read"))
+
+	metrics, collectionErrors := Instrument(context.Background(), "osRelease", func(ctx context.Context) {
+		startStep(ctx, "file read").finish(nil)
+		startStep(ctx, "file read").finish(errors.New("permission denied"))
+		recordCollectionError(ctx, "read", "/etc/os-release", errors.New("permission denied"))
+	})
+	assert.Equal(t, 1, metrics.FailedFiles)
+	assert.Len(t, collectionErrors, 1)
+	if assert.Contains(t, metrics.Steps, "file read") {
+		assert.Equal(t, 2, metrics.Steps["file read"].Count)
+		assert.Equal(t, 1, metrics.Steps["file read"].Failed)
+	}
+	assert.Nil(t, startStep(context.Background(), "file read"))
+}
+
+func TestInstrumentTracing(t *testing.T) {
+	tracer := tracing.NewTracer(tracing.Options{Endpoint: "http://127.0.0.1:0"})
+	tracing.SetTracer(tracer)
+	defer func() {
+		tracing.SetTracer(nil)
+		tracer.Shutdown(context.Background())
+	}()
+
+	ctx, scan := tracing.Start(context.Background(), "scan", tracing.KindInternal)
+	var sensorSpan, fileSpan *tracing.Span
+	Instrument(ctx, "osRelease", func(ctx context.Context) {
+		step := startStep(ctx, "file read", "file.path", "/etc/os-release")
+		sensorSpan, fileSpan = step.run.span, step.span
+		step.finish(nil)
+	})
+	if assert.NotNil(t, sensorSpan) && assert.NotNil(t, fileSpan) {
+		assert.Equal(t, scan.TraceID, sensorSpan.TraceID)
+		assert.Equal(t, scan.SpanID, sensorSpan.ParentID)
+		assert.Equal(t, sensorSpan.SpanID, fileSpan.ParentID)
+	}
+}
+
+func TestInstrumentLogger(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	Instrument(WithRequestID(context.Background(), "req-1"), "osRelease", func(ctx context.Context) {
+		logger(ctx).Info("sensing")
+	})
+	logger(context.Background()).Info("idle")
+
+	entries := logs.All()
+	if assert.Len(t, entries, 2) {
+		assert.Equal(t, "req-1", entries[0].ContextMap()["requestID"])
+		assert.NotContains(t, entries[1].ContextMap(), "requestID")
+	}
+}
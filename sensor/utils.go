@@ -1,11 +1,18 @@
 package sensor
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"syscall"
 
 	"go.uber.org/zap"
@@ -15,18 +22,186 @@ import (
 const (
 	kubeConfigArgName = "--kubeconfig"
 	maxRecursionDepth = 10
+
+	// Max number of symlinks followed to resolve a path, as Linux
+	maxSymlinks = 40
 )
 
 var (
-	ErrNotUnixFS = errors.New("operation not supported by the file system")
+	ErrNotUnixFS       = errors.New("operation not supported by the file system")
+	ErrPathOutsideRoot = errors.New("path leads outside the root directory")
+
+	errNotSupported   = errors.New("not supported on this platform")
+	errNotRegularFile = errors.New("not a regular file")
+
+	// Maximal size in bytes of a file content to read, 0 means unlimited.
+	// Accessed atomically since it's reloadable.
+	maxContentSize int64
+
+	// Whether the files of the `FileInfo` objects are hashed, 1 if enabled. Accessed atomically.
+	fileHashing int32
 )
 
-func ReadFileOnHostFileSystem(fileName string) ([]byte, error) {
-	return os.ReadFile(hostPath(fileName))
+// SetMaxContentSize limits the size of the file contents added to `FileInfo` objects. 0 means unlimited.
+func SetMaxContentSize(size int64) {
+	atomic.StoreInt64(&maxContentSize, size)
+}
+
+// SetFileHashing enables hashing the files of the `FileInfo` objects, which only the file integrity monitoring uses
+func SetFileHashing(enabled bool) {
+	var val int32
+	if enabled {
+		val = 1
+	}
+	atomic.StoreInt32(&fileHashing, val)
+}
+
+// openRegularFile opens a regular file for reading. The path is lstat-ed before opening, since opening devices and
+// FIFOs may block or have side effects, and other file types (including symlinks, which the callers resolve within
+// the host root) fail with `errNotRegularFile`.
+func openRegularFile(filePath string) (*os.File, error) {
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, &fs.PathError{Op: "open", Path: filePath, Err: errNotRegularFile}
+	}
+	// non blocking, in case the file was replaced with a FIFO since
+	f, err := os.OpenFile(filePath, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	if opened, err := f.Stat(); err != nil || !os.SameFile(info, opened) {
+		f.Close()
+		return nil, &fs.PathError{Op: "open", Path: filePath, Err: errNotRegularFile}
+	}
+	return f, nil
+}
+
+// readFileContent reads the content of a regular file, up to the max content size.
+// It returns `false` if the file is larger than the max content size.
+func readFileContent(filePath string) ([]byte, bool, error) {
+	f, err := openRegularFile(filePath)
+	if err != nil {
+		return nil, false, err
+	}
+	defer f.Close()
+
+	limit := atomic.LoadInt64(&maxContentSize)
+	if limit <= 0 {
+		content, err := io.ReadAll(f)
+		return content, err == nil, err
+	}
+
+	// Read one more byte to know if the limit was exceeded. Not using the file size, since it's 0 for /proc files
+	content, err := io.ReadAll(io.LimitReader(f, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(content)) > limit {
+		return nil, false, nil
+	}
+	return content, true, nil
+}
+
+func ReadFileOnHostFileSystem(ctx context.Context, fileName string) ([]byte, error) {
+	step := startStep(ctx, "file read", "file.path", fileName)
+	content, err := hostFS(ctx).ReadFile(fileName)
+	step.setAttribute("file.size", len(content))
+	step.finish(err)
+	return content, err
+}
+
+// statHostFile returns the file info of a host file, following its symlinks in the host file system
+func statHostFile(ctx context.Context, filePath string) (fs.FileInfo, error) {
+	return hostFS(ctx).Stat(filePath)
+}
+
+// readHostDir returns the entries of a host directory, following its symlinks in the host file system
+func readHostDir(ctx context.Context, dir string) ([]fs.DirEntry, error) {
+	return hostFS(ctx).ReadDir(dir)
+}
+
+// resolveHostPath returns the path in the sensor file system of a host path, resolving its symlinks in the host file
+// system. See `resolveRootPath`.
+func resolveHostPath(ctx context.Context, filePath string) (string, error) {
+	return hostFS(ctx).Resolve(filePath)
+}
+
+// resolveRootPath returns `filePath` joined to `rootDir`, with its symlinks resolved as if `rootDir` was the root
+// directory, i.e. absolute symlinks are relative to `rootDir`. It fails with `ErrPathOutsideRoot` if a ".."
+// component (of the path or of a symlink target) leads outside `rootDir`. The components which don't exist are
+// joined as is.
+func resolveRootPath(rootDir, filePath string) (string, error) {
+	resolved := "/"
+	remaining := toSlashHostPath(filePath)
+	links := 0
+	exists := true
+	for remaining != "" {
+		var part string
+		part, remaining, _ = strings.Cut(remaining, "/")
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if resolved == "/" {
+				return "", &fs.PathError{Op: "resolve", Path: filePath, Err: ErrPathOutsideRoot}
+			}
+			resolved = path.Dir(resolved)
+			continue
+		}
+
+		next := path.Join(resolved, part)
+		if !exists {
+			resolved = next
+			continue
+		}
+		info, err := os.Lstat(path.Join(rootDir, next))
+		if err != nil {
+			if !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, syscall.ENOTDIR) {
+				return "", err
+			}
+			exists = false
+			resolved = next
+			continue
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return "", &fs.PathError{Op: "resolve", Path: filePath, Err: syscall.ELOOP}
+		}
+		target, err := os.Readlink(path.Join(rootDir, next))
+		if err != nil {
+			return "", err
+		}
+		if path.IsAbs(target) {
+			resolved = "/"
+		}
+		remaining = target + "/" + remaining
+	}
+	return path.Join(rootDir, resolved), nil
 }
 
-func hostPath(filePath string) string {
-	return path.Join(hostFileSystemDefaultLocation, filePath)
+// CheckHostRoot verifies that the host file system is mounted and accessible.
+// It returns `ErrHostRootMissing` or `ErrPermissionDenied` otherwise.
+func CheckHostRoot() error {
+	info, err := os.Stat(hostFileSystemDefaultLocation)
+	if err != nil {
+		if errors.Is(err, fs.ErrPermission) {
+			return newSenseError(ErrPermissionDenied, "CheckHostRoot", err)
+		}
+		return newSenseError(ErrHostRootMissing, "CheckHostRoot", err)
+	}
+	if !info.IsDir() {
+		return newSenseError(ErrHostRootMissing, "CheckHostRoot",
+			fmt.Errorf("%s is not a directory", hostFileSystemDefaultLocation))
+	}
+	return nil
 }
 
 // GetFilePermissions returns file permissions as int.
@@ -52,14 +227,11 @@ func GetFileUNIXOwnership(filePath string) (int64, int64, error) {
 		return -1, -1, err
 	}
 
-	asUnix, ok := info.Sys().(*syscall.Stat_t)
+	user, group, ok := fileOwnership(info)
 	if !ok {
 		return -1, -1, ErrNotUnixFS
 	}
 
-	user := int64(asUnix.Uid)
-	group := int64(asUnix.Gid)
-
 	return user, group, nil
 }
 
@@ -72,10 +244,23 @@ func IsPathExists(filename string) bool {
 // MakeFileInfo returns a `FileInfo` object for given path
 // If `readContent` is set to `true`, it adds the file content
 // On access error, it returns the error as is
-func MakeFileInfo(filePath string, readContent bool) (*FileInfo, error) {
+func MakeFileInfo(ctx context.Context, filePath string, readContent bool) (*FileInfo, error) {
+	return makeFileInfo(ctx, filePath, readContent, true)
+}
+
+// makeFileInfo is `MakeFileInfo`, which hashes the file only if `hash` is set to `true` and file hashing is enabled.
+// Only regular files are opened, to hash and read them.
+func makeFileInfo(ctx context.Context, filePath string, readContent, hash bool) (*FileInfo, error) {
 	ret := FileInfo{Path: filePath}
 
-	zap.L().Debug("making file info", zap.String("path", filePath))
+	logger(ctx).Debug("making file info", zap.String("path", filePath))
+
+	// lstat before opening, since opening devices and FIFOs may block or have side effects
+	info, err := os.Lstat(filePath)
+	if err != nil {
+		return nil, err
+	}
+	regular := info.Mode().IsRegular()
 
 	// Permissions
 	perms, err := GetFilePermissions(filePath)
@@ -91,45 +276,92 @@ func MakeFileInfo(filePath string, readContent bool) (*FileInfo, error) {
 		ret.Ownership.Err = err.Error()
 	}
 
+	// Attributes
+	if ret.Attributes, err = fileAttributes(filePath); err != nil {
+		logger(ctx).Debug("failed to get file attributes", zap.String("path", filePath), zap.Error(err))
+	}
+
+	// Hash, only for the file integrity monitoring
+	if hash && regular && atomic.LoadInt32(&fileHashing) == 1 {
+		if ret.SHA256, err = hashFile(filePath); err != nil {
+			logger(ctx).Debug("failed to hash file", zap.String("path", filePath), zap.Error(err))
+		}
+	}
+
 	// Content
-	if readContent {
-		content, err := os.ReadFile(filePath)
+	if readContent && regular {
+		content, ok, err := readFileContent(filePath)
 		if err != nil {
 			return nil, err
 		}
 		ret.Content = content
+		ret.ContentOmitted = !ok
 	}
 
 	return &ret, nil
 }
 
+// hashFile returns the hex encoded SHA256 hash of a regular file, or an empty string for other file types
+func hashFile(filePath string) (string, error) {
+	f, err := openRegularFile(filePath)
+	if errors.Is(err, errNotRegularFile) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// HostFileInfo returns a `FileInfo` object (without content) for a path in the host file system
+func HostFileInfo(ctx context.Context, filePath string) (*FileInfo, error) {
+	return makeHostFileInfo(ctx, filePath, false)
+}
+
 // MakeContaineredFileInfo is a wrapper of `MakeChangedRootFileInfo` for container files
-func makeContaineredFileInfo(filePath string, readContent bool, p *ProcessDetails) (*FileInfo, error) {
-	return makeChangedRootFileInfo(filePath, readContent, p.RootDir())
+func makeContaineredFileInfo(ctx context.Context, filePath string, readContent bool, p *ProcessDetails) (*FileInfo, error) {
+	return makeChangedRootFileInfo(ctx, filePath, readContent, true, p.RootDir())
 }
 
 // MakeHostFileInfo is a wrapper of `MakeChangedRootFileInfo` for host files
-func makeHostFileInfo(filePath string, readContent bool) (*FileInfo, error) {
-	return makeChangedRootFileInfo(filePath, readContent, hostFileSystemDefaultLocation)
+func makeHostFileInfo(ctx context.Context, filePath string, readContent bool) (*FileInfo, error) {
+	return hostFS(ctx).FileInfo(ctx, filePath, readContent)
+}
+
+// makeHostFileStatInfo is a wrapper of `makeHostFileInfo` which doesn't hash the file, for large files (e.g. logs)
+func makeHostFileStatInfo(ctx context.Context, filePath string) (*FileInfo, error) {
+	return makeChangedRootFileInfo(ctx, filePath, false, false, hostFS(ctx).Root())
 }
 
 // MakeHostFileInfo is a wrapper of `MakeFileInfo` for rootDir/filePath
-func makeChangedRootFileInfo(filePath string, readContent bool, rootDir string) (*FileInfo, error) {
-	fullPath := path.Join(rootDir, filePath)
-	obj, err := MakeFileInfo(fullPath, readContent)
+func makeChangedRootFileInfo(ctx context.Context, filePath string, readContent, hash bool, rootDir string) (*FileInfo, error) {
+	fullPath, err := resolveRootPath(rootDir, filePath)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := makeFileInfo(ctx, fullPath, readContent, hash)
 
 	if err != nil {
 		return obj, err
 	}
 
 	obj.Path = filePath
+	if obj.Ownership.Err != "" {
+		return obj, nil
+	}
 
 	// Username
 	username, err := getUserName(obj.Ownership.UID, rootDir)
 	obj.Ownership.Username = username
 
 	if err != nil {
-		zap.L().Error("MakeHostFileInfo", zap.Error(err))
+		logger(ctx).Error("MakeHostFileInfo", zap.Error(err))
 	}
 
 	// Groupname
@@ -137,15 +369,20 @@ func makeChangedRootFileInfo(filePath string, readContent bool, rootDir string)
 	obj.Ownership.Groupname = groupname
 
 	if err != nil {
-		zap.L().Error("MakeHostFileInfo", zap.Error(err))
+		logger(ctx).Error("MakeHostFileInfo", zap.Error(err))
 	}
 
 	return obj, nil
 }
 
 // makeHostFileInfoVerbose is wrapper of `MakeHostFileInfo` with error logging
-func makeHostFileInfoVerbose(path string, readContent bool, failMsgs ...zap.Field) *FileInfo {
-	fileInfo, err := makeHostFileInfo(path, readContent)
+func makeHostFileInfoVerbose(ctx context.Context, path string, readContent bool, failMsgs ...zap.Field) *FileInfo {
+	return makeFileInfoVerbose(ctx, hostFS(ctx), path, readContent, failMsgs...)
+}
+
+// makeFileInfoVerbose is `makeHostFileInfoVerbose` of the host file system `hfs`
+func makeFileInfoVerbose(ctx context.Context, hfs HostFS, path string, readContent bool, failMsgs ...zap.Field) *FileInfo {
+	fileInfo, err := hfs.FileInfo(ctx, path, readContent)
 	if err != nil {
 		logArgs := append([]zapcore.Field{
 			zap.String("path", path),
@@ -153,30 +390,50 @@ func makeHostFileInfoVerbose(path string, readContent bool, failMsgs ...zap.Fiel
 		},
 			failMsgs...,
 		)
-		zap.L().Error("failed to MakeHostFileInfo", logArgs...)
+		logger(ctx).Error("failed to MakeHostFileInfo", logArgs...)
+		recordCollectionError(ctx, "stat", path, err)
 	}
 	return fileInfo
 }
 
+// sortFileInfos sorts file infos by path, for deterministic results
+func sortFileInfos(fileInfos []*FileInfo) {
+	sort.Slice(fileInfos, func(i, j int) bool { return fileInfos[i].Path < fileInfos[j].Path })
+}
+
 // makeHostDirFilesInfo iterate over a directory and make a list of
 // file infos for all the files inside it. If `recursive` is set to true,
 // the file infos will be added recursively until `maxRecursionDepth` is reached
-func makeHostDirFilesInfo(dir string, recursive bool, fileInfos *([]*FileInfo), recursionLevel int) ([]*FileInfo, error) {
-	dirInfo, err := os.Open(hostPath(dir))
+func makeHostDirFilesInfo(ctx context.Context, dir string, recursive bool, fileInfos *([]*FileInfo), recursionLevel int) ([]*FileInfo, error) {
+	step := startStep(ctx, "dir walk", "dir.path", dir, "dir.recursive", recursive)
+	ret, err := makeDirFilesInfo(ctx, hostFS(ctx), dir, recursive, fileInfos, recursionLevel)
+	step.setAttribute("dir.files", len(ret))
+	step.finish(err)
+	return ret, err
+}
+
+// makeDirFilesInfo is `makeHostDirFilesInfo` of the host file system `hfs`
+func makeDirFilesInfo(ctx context.Context, hfs HostFS, dir string, recursive bool, fileInfos *([]*FileInfo), recursionLevel int) ([]*FileInfo, error) {
+	dirInfo, err := hfs.Open(dir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open dir at %s: %w", dir, err)
 	}
 	defer dirInfo.Close()
+	dirFile, ok := dirInfo.(*os.File)
+	if !ok {
+		return nil, fmt.Errorf("failed to open dir at %s: not a file", dir)
+	}
 
 	if fileInfos == nil {
 		fileInfos = &([]*FileInfo{})
 	}
 
 	var fileNames []string
-	for fileNames, err = dirInfo.Readdirnames(100); err == nil; fileNames, err = dirInfo.Readdirnames(100) {
+	for fileNames, err = dirFile.Readdirnames(100); err == nil; fileNames, err = dirFile.Readdirnames(100) {
 		for i := range fileNames {
 			filePath := path.Join(dir, fileNames[i])
-			fileInfo := makeHostFileInfoVerbose(filePath,
+			advanceProgress(ctx, filePath, 1, 0)
+			fileInfo := makeFileInfoVerbose(ctx, hfs, filePath,
 				false,
 				zap.String("in", "makeHostDirFilesInfo"),
 				zap.String("dir", dir),
@@ -191,25 +448,29 @@ func makeHostDirFilesInfo(dir string, recursive bool, fileInfos *([]*FileInfo),
 			}
 
 			// Check if is directory
-			stats, err := os.Stat(hostPath(filePath))
+			stats, err := hfs.Stat(filePath)
 			if err != nil {
-				zap.L().Error("failed to get file stats",
+				logger(ctx).Error("failed to get file stats",
 					zap.String("in", "makeHostDirFilesInfo"),
 					zap.String("path", filePath))
 				continue
 			}
 			if stats.IsDir() {
 				if recursionLevel+1 == maxRecursionDepth {
-					zap.L().Error("max recusrion depth exceeded",
+					logger(ctx).Error("max recusrion depth exceeded",
 						zap.String("in", "makeHostDirFilesInfo"),
 						zap.String("path", filePath))
 					continue
 				}
-				makeHostDirFilesInfo(filePath, recursive, fileInfos, recursionLevel+1)
+				makeDirFilesInfo(ctx, hfs, filePath, recursive, fileInfos, recursionLevel+1)
 			}
 		}
 	}
 
+	if recursionLevel == 0 {
+		sortFileInfos(*fileInfos)
+	}
+
 	if errors.Is(err, io.EOF) {
 		err = nil
 	}
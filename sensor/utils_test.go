@@ -1,26 +1,120 @@
 package sensor
 
 import (
+	"context"
+	"os"
+	"path"
+	"syscall"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zaptest/observer"
 )
 
 func Test_makeHostDirFilesInfo(t *testing.T) {
-	hostFileSystemDefaultLocation = "."
-	fileInfos, err := makeHostDirFilesInfo("testdata/testmakehostfiles", true, nil, 0)
+	hfs := NewHostFS("testdata/testmakehostfiles")
+	fileInfos, err := makeDirFilesInfo(context.Background(), hfs, "/", true, nil, 0)
 	assert.NoError(t, err)
 	assert.Len(t, fileInfos, 5)
+	paths := []string{}
+	for _, fileInfo := range fileInfos {
+		paths = append(paths, fileInfo.Path)
+	}
+	assert.IsIncreasing(t, paths)
 
 	// Test maxRecursionDepth
 	observedZapCore, observedLogs := observer.New(zap.InfoLevel)
 	observedLogger := zap.New(observedZapCore)
 	zap.ReplaceGlobals(observedLogger)
 
-	fileInfos, err = makeHostDirFilesInfo("testdata/testmakehostfiles", true, nil, maxRecursionDepth-1)
+	fileInfos, err = makeDirFilesInfo(context.Background(), hfs, "/", true, nil, maxRecursionDepth-1)
 	assert.NoError(t, err)
 	assert.Len(t, fileInfos, 4)
 	assert.Len(t, observedLogs.FilterMessage("max recusrion depth exceeded").All(), 1)
 }
+
+func Test_readFileContent(t *testing.T) {
+	defer SetMaxContentSize(0)
+
+	content, ok, err := readFileContent("testdata/clientCAKubeletConf.yaml")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NotEmpty(t, content)
+
+	SetMaxContentSize(int64(len(content)))
+	limited, ok, err := readFileContent("testdata/clientCAKubeletConf.yaml")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, content, limited)
+
+	SetMaxContentSize(int64(len(content) - 1))
+	limited, ok, err = readFileContent("testdata/clientCAKubeletConf.yaml")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Nil(t, limited)
+}
+
+func Test_hashFile(t *testing.T) {
+	hash, err := hashFile("testdata/testmakehostfiles/file1.yaml")
+	assert.NoError(t, err)
+	// SHA256 of empty content
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", hash)
+
+	hash, err = hashFile("testdata")
+	assert.NoError(t, err)
+	assert.Empty(t, hash)
+}
+
+func TestMakeFileInfoHashing(t *testing.T) {
+	info, err := makeFileInfo(context.Background(), "testdata/testmakehostfiles/file1.yaml", false, true)
+	require.NoError(t, err)
+	assert.Empty(t, info.SHA256)
+
+	SetFileHashing(true)
+	defer SetFileHashing(false)
+	info, err = makeFileInfo(context.Background(), "testdata/testmakehostfiles/file1.yaml", false, true)
+	require.NoError(t, err)
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", info.SHA256)
+	info, err = makeFileInfo(context.Background(), "testdata/testmakehostfiles/file1.yaml", false, false)
+	require.NoError(t, err)
+	assert.Empty(t, info.SHA256)
+}
+
+func TestResolveRootPath(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(path.Join(root, "etc/kubernetes"), 0755))
+	require.NoError(t, os.WriteFile(path.Join(root, "etc/kubernetes/kubelet.conf"), []byte("kind: Config\n"), 0600))
+	require.NoError(t, os.Symlink("/etc/kubernetes/kubelet.conf", path.Join(root, "etc/kubelet.conf")))
+	require.NoError(t, os.Symlink("kubernetes", path.Join(root, "etc/k8s")))
+	require.NoError(t, os.Symlink("../../../../outside", path.Join(root, "etc/escape")))
+	require.NoError(t, os.Symlink("/loop2", path.Join(root, "loop1")))
+	require.NoError(t, os.Symlink("/loop1", path.Join(root, "loop2")))
+
+	tests := []struct {
+		filePath string
+		expected string
+		err      error
+	}{
+		{"/etc/kubernetes/kubelet.conf", "/etc/kubernetes/kubelet.conf", nil},
+		// absolute symlinks are relative to the root
+		{"/etc/kubelet.conf", "/etc/kubernetes/kubelet.conf", nil},
+		{"/etc/k8s/kubelet.conf", "/etc/kubernetes/kubelet.conf", nil},
+		{"/etc/k8s/../kubelet.conf", "/etc/kubernetes/kubelet.conf", nil},
+		{"/etc/missing/../kubelet.conf", "/etc/kubelet.conf", nil},
+		{"/etc/escape", "", ErrPathOutsideRoot},
+		{"/../etc/kubelet.conf", "", ErrPathOutsideRoot},
+		{"/loop1", "", syscall.ELOOP},
+	}
+	for _, test := range tests {
+		resolved, err := resolveRootPath(root, test.filePath)
+		if test.err != nil {
+			assert.ErrorIs(t, err, test.err, test.filePath)
+			continue
+		}
+		if assert.NoError(t, err, test.filePath) {
+			assert.Equal(t, path.Join(root, test.expected), resolved, test.filePath)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !windows
+
+package sensor
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// fileOwnership returns the user id and group id of a file, false if the file system has no UNIX ownership
+func fileOwnership(info fs.FileInfo) (int64, int64, bool) {
+	asUnix, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return -1, -1, false
+	}
+	return int64(asUnix.Uid), int64(asUnix.Gid), true
+}
+
+// toSlashHostPath returns the host path as is, host paths are slash separated
+func toSlashHostPath(filePath string) string {
+	return filePath
+}
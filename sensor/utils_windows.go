@@ -0,0 +1,17 @@
+package sensor
+
+import (
+	"io/fs"
+	"path/filepath"
+)
+
+// fileOwnership returns false, Windows files have no UNIX ownership
+func fileOwnership(info fs.FileInfo) (int64, int64, bool) {
+	return -1, -1, false
+}
+
+// toSlashHostPath returns the slash separated host path of a Windows path, without its volume name,
+// e.g. "/k/config" of `C:\k\config`
+func toSlashHostPath(filePath string) string {
+	return filepath.ToSlash(filePath[len(filepath.VolumeName(filePath)):])
+}
@@ -0,0 +1,17 @@
+package sensor
+
+const (
+	// The containerd config of Windows nodes, i.e. C:\Program Files\containerd\config.toml
+	containerdWindowsConfigPath = "/Program Files/containerd/config.toml"
+
+	firewallActionAllow = "allow"
+	firewallActionBlock = "block"
+)
+
+// firewallAction returns the name of a DefaultInboundAction or DefaultOutboundAction registry value
+func firewallAction(value uint64) string {
+	if value == 0 {
+		return firewallActionAllow
+	}
+	return firewallActionBlock
+}
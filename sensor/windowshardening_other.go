@@ -0,0 +1,10 @@
+//go:build !windows
+
+package sensor
+
+import "context"
+
+// SenseWindowsSecurityHardening returns an error, the sensor is only supported on Windows nodes
+func SenseWindowsSecurityHardening(ctx context.Context) (*WindowsSecurityHardeningStatus, error) {
+	return nil, errNotSupported
+}
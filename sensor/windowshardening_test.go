@@ -0,0 +1,20 @@
+//go:build !windows
+
+package sensor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFirewallAction(t *testing.T) {
+	assert.Equal(t, firewallActionAllow, firewallAction(0))
+	assert.Equal(t, firewallActionBlock, firewallAction(1))
+}
+
+func TestSenseWindowsSecurityHardeningNotSupported(t *testing.T) {
+	_, err := SenseWindowsSecurityHardening(context.Background())
+	assert.ErrorIs(t, err, errNotSupported)
+}
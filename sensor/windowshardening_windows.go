@@ -0,0 +1,126 @@
+package sensor
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+	winregistry "golang.org/x/sys/windows/registry"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const (
+	defenderService           = "WinDefend"
+	defenderPolicyKey         = `SOFTWARE\Policies\Microsoft\Windows Defender`
+	defenderRealTimeKey       = `SOFTWARE\Microsoft\Windows Defender\Real-Time Protection`
+	defenderRealTimePolicyKey = `SOFTWARE\Policies\Microsoft\Windows Defender\Real-Time Protection`
+
+	defenderDisabledValue    = "DisableAntiSpyware"
+	defenderRealTimeDisabled = "DisableRealtimeMonitoring"
+
+	firewallPolicyKey      = `SYSTEM\CurrentControlSet\Services\SharedAccess\Parameters\FirewallPolicy\`
+	firewallGroupPolicyKey = `SOFTWARE\Policies\Microsoft\WindowsFirewall\`
+	firewallEnableValue    = "EnableFirewall"
+	firewallInboundValue   = "DefaultInboundAction"
+	firewallOutboundValue  = "DefaultOutboundAction"
+)
+
+var (
+	// The firewall profiles, and their local and group policy keys
+	firewallProfiles = []struct {
+		name      string
+		localKey  string
+		policyKey string
+	}{
+		{name: "domain", localKey: "DomainProfile", policyKey: "DomainProfile"},
+		{name: "private", localKey: "StandardProfile", policyKey: "PrivateProfile"},
+		{name: "public", localKey: "PublicProfile", policyKey: "PublicProfile"},
+	}
+
+	serviceStates = map[svc.State]string{
+		svc.Stopped:         "stopped",
+		svc.StartPending:    "start pending",
+		svc.StopPending:     "stop pending",
+		svc.Running:         "running",
+		svc.ContinuePending: "continue pending",
+		svc.PausePending:    "pause pending",
+		svc.Paused:          "paused",
+	}
+)
+
+// SenseWindowsSecurityHardening returns the status of Microsoft Defender, the Windows Firewall profiles and the
+// containerd config
+func SenseWindowsSecurityHardening(ctx context.Context) (*WindowsSecurityHardeningStatus, error) {
+	ret := WindowsSecurityHardeningStatus{FirewallProfiles: []FirewallProfile{}}
+
+	defender, err := getDefenderStatus(ctx)
+	if err != nil {
+		logger(ctx).Debug("failed to get the Defender status", zap.Error(err))
+	}
+	ret.Defender = defender
+
+	for _, profile := range firewallProfiles {
+		ret.FirewallProfiles = append(ret.FirewallProfiles, FirewallProfile{
+			Name:                  profile.name,
+			Enabled:               getFirewallValue(ctx, profile.localKey, profile.policyKey, firewallEnableValue, 1) != 0,
+			DefaultInboundAction:  firewallAction(getFirewallValue(ctx, profile.localKey, profile.policyKey, firewallInboundValue, 1)),
+			DefaultOutboundAction: firewallAction(getFirewallValue(ctx, profile.localKey, profile.policyKey, firewallOutboundValue, 0)),
+		})
+	}
+
+	ret.ContainerdConfig = makeHostFileInfoVerbose(ctx, containerdWindowsConfigPath, true,
+		zap.String("in", "SenseWindowsSecurityHardening"))
+
+	return &ret, nil
+}
+
+// getDefenderStatus returns the status of Defender, nil if its service isn't installed
+func getDefenderStatus(ctx context.Context) (*DefenderStatus, error) {
+	m, err := mgr.Connect()
+	if err != nil {
+		return nil, err
+	}
+	defer m.Disconnect()
+	service, err := m.OpenService(defenderService)
+	if err != nil {
+		return nil, err
+	}
+	defer service.Close()
+	status, err := service.Query()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := &DefenderStatus{ServiceState: serviceStates[status.State]}
+	ret.DisabledByPolicy = getRegistryInteger(ctx, defenderPolicyKey, defenderDisabledValue, 0) != 0
+	realTimeDisabled := getRegistryInteger(ctx, defenderRealTimeKey, defenderRealTimeDisabled, 0)
+	realTimeDisabled = getRegistryInteger(ctx, defenderRealTimePolicyKey, defenderRealTimeDisabled, realTimeDisabled)
+	ret.RealTimeProtection = !ret.DisabledByPolicy && status.State == svc.Running && realTimeDisabled == 0
+	return ret, nil
+}
+
+// getFirewallValue returns a value of a firewall profile, the group policy value if set, else the local one
+func getFirewallValue(ctx context.Context, localKey, policyKey, name string, defaultValue uint64) uint64 {
+	value := getRegistryInteger(ctx, firewallPolicyKey+localKey, name, defaultValue)
+	return getRegistryInteger(ctx, firewallGroupPolicyKey+policyKey, name, value)
+}
+
+// getRegistryInteger returns an integer value of a HKEY_LOCAL_MACHINE key, `defaultValue` if not set
+func getRegistryInteger(ctx context.Context, keyPath, name string, defaultValue uint64) uint64 {
+	key, err := winregistry.OpenKey(winregistry.LOCAL_MACHINE, keyPath, winregistry.QUERY_VALUE)
+	if err != nil {
+		return defaultValue
+	}
+	defer key.Close()
+	value, _, err := key.GetIntegerValue(name)
+	if err != nil {
+		if !errors.Is(err, winregistry.ErrNotExist) {
+			logger(ctx).Debug("failed to read registry value",
+				zap.String("key", strings.Join([]string{keyPath, name}, `\`)), zap.Error(err))
+		}
+		return defaultValue
+	}
+	return value
+}
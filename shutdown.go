@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/armosec/host-sensor/push"
+	"go.uber.org/zap"
+)
+
+// This file contains the graceful shutdown, which drains the in-flight requests and scans.
+
+// sensingCtx is the context of the requests, jobs and scans, cancelled when the shutdown grace period ends
+var sensingCtx, cancelSensing = context.WithCancel(context.Background())
+
+// pushRunner runs the pusher, and the heartbeats, in the background
+type pushRunner struct {
+	pusher *push.Pusher
+	cancel context.CancelFunc
+	done   sync.WaitGroup
+}
+
+// startPusher runs the pusher, and sends heartbeats every `heartbeatInterval` (none if 0)
+func startPusher(pusher *push.Pusher, heartbeatInterval time.Duration) *pushRunner {
+	ctx, cancel := context.WithCancel(context.Background())
+	runner := &pushRunner{pusher: pusher, cancel: cancel}
+	runner.done.Add(1)
+	go func() {
+		defer runner.done.Done()
+		pusher.Run(ctx)
+	}()
+	if heartbeatInterval > 0 {
+		runner.done.Add(1)
+		go func() {
+			defer runner.done.Done()
+			sendHeartbeats(ctx, pusher, heartbeatInterval)
+		}()
+	}
+	return runner
+}
+
+// stop stops the pusher and the heartbeats, and sends the unsent reports until `ctx` is done. The reports left are
+// spooled.
+func (r *pushRunner) stop(ctx context.Context) {
+	r.cancel()
+	r.done.Wait()
+	r.pusher.Flush(ctx)
+}
+
+// gracefulShutdown stops serving new requests and scheduling scans, waits for the in-flight requests and scan,
+// flushes the pending push reports and spans. The sensing still running when the grace period ends is cancelled.
+func gracefulShutdown(grace time.Duration, server *http.Server, pushes *pushRunner) {
+	ctx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	go func() {
+		<-ctx.Done()
+		if ctx.Err() == context.DeadlineExceeded {
+			cancelSensing()
+		}
+	}()
+
+	// streams never complete by themselves
+	if eventsStream != nil {
+		eventsStream.close()
+	}
+	if err := server.Shutdown(ctx); err != nil {
+		zap.L().Error("HTTP shutdown error", zap.Error(err))
+	}
+
+	if scheduler != nil {
+		scheduler.stop()
+		select {
+		case <-scheduler.done:
+		case <-ctx.Done():
+			zap.L().Warn("the running scan didn't complete within the shutdown grace period")
+		}
+	}
+
+	if pushes != nil {
+		pushes.stop(ctx)
+	}
+
+	if tracer != nil {
+		if err := tracer.Shutdown(ctx); err != nil {
+			zap.L().Warn("failed to export the pending spans", zap.Error(err))
+		}
+	}
+}
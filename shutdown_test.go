@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/armosec/host-sensor/push"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGracefulShutdown(t *testing.T) {
+	defer func() { scheduler, eventsStream = nil, nil }()
+
+	var received int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		atomic.AddInt32(&received, 1)
+	}))
+	defer collector.Close()
+	pusher, err := push.New(push.Options{URL: collector.URL})
+	require.NoError(t, err)
+	pushes := startPusher(pusher, 0)
+
+	scheduler = newScanScheduler(time.Hour, time.Hour)
+	go scheduler.run(context.Background())
+	eventsStream = newEventStream()
+
+	server := &http.Server{Addr: "127.0.0.1:0"}
+	require.NoError(t, pusher.Push("report"))
+
+	start := time.Now()
+	gracefulShutdown(5*time.Second, server, pushes)
+	assert.Less(t, time.Since(start), 5*time.Second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&received))
+	_, err = eventsStream.subscribe()
+	assert.Error(t, err)
+}
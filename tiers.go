@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/armosec/host-sensor/sensor"
+	"go.uber.org/zap"
+)
+
+// This file contains the tiers of the API. The endpoints themselves are the evidence tier, serving the full results.
+// The summary tier serves their read-only responses under /summary/, without the file contents, so the verdicts,
+// owners and permissions can be consumed without access to private keys and credentials. Since the tiers have
+// different paths, they are authorized separately by the token review RBAC and the client allowlist.
+
+const summaryTierPrefix = "/summary"
+
+var (
+	errNotSummarizable = &sensor.SenseError{
+		Massage: "endpoint is not available in the summary tier",
+		Kind:    "NotSummarizable",
+		Code:    http.StatusNotFound,
+	}
+
+	// summaryExcludedPaths are the endpoints which aren't served in the summary tier: raw files and command lines,
+	// streams and archives, which can't be summarized
+	summaryExcludedPaths = map[string]bool{
+		"/kubeletConfigurations": true,
+		"/kubeletCommandLine":    true,
+		"/events":                true,
+		"/metrics":               true,
+		"/debug/bundle":          true,
+	}
+)
+
+// summaryHandler serves the GET requests of /summary/<endpoint>, with the response of the endpoint without its file
+// contents
+func summaryHandler(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		writeSenseError(rw, &sensor.SenseError{Massage: "method not allowed", Code: http.StatusMethodNotAllowed}, "summary")
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, summaryTierPrefix)
+	if summaryExcludedPaths[path] || strings.HasPrefix(path, summaryTierPrefix+"/") {
+		writeSenseError(rw, errNotSummarizable, "summary")
+		return
+	}
+
+	evidence := r.Clone(r.Context())
+	evidence.URL.Path, evidence.URL.RawPath = path, ""
+	w := &summaryWriter{ResponseWriter: rw, path: path}
+	http.DefaultServeMux.ServeHTTP(w, evidence)
+	w.flush()
+}
+
+// summaryWriter buffers the response of an endpoint, and writes the summary of a successful result
+type summaryWriter struct {
+	http.ResponseWriter
+	path   string
+	status int
+	body   bytes.Buffer
+}
+
+func (w *summaryWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *summaryWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// flush writes the buffered response, without the file contents if it succeeded
+func (w *summaryWriter) flush() {
+	status, content := w.status, w.body.Bytes()
+	if status == 0 {
+		status = http.StatusOK
+	}
+	if status == http.StatusOK {
+		summary, err := sensor.StripContent(content)
+		if err != nil {
+			// not a JSON result, which may hold anything
+			zap.L().Debug("summary of a non JSON response", zap.String("path", w.path), zap.Error(err))
+			writeSenseError(w.ResponseWriter, errNotSummarizable, "summary")
+			return
+		}
+		w.Header().Del("Content-Length")
+		w.Header().Set("Content-Type", "application/json")
+		content = append(summary, '\n')
+	}
+
+	w.ResponseWriter.WriteHeader(status)
+	if _, err := w.ResponseWriter.Write(content); err != nil {
+		zap.L().Error(fmt.Sprintf("In summary of %s handler failed to write", w.path), zap.Error(err))
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var registerSummaryTestHandlers sync.Once
+
+func TestSummaryHandler(t *testing.T) {
+	registerSummaryTestHandlers.Do(func() {
+		http.HandleFunc("/summaryTestKeys", func(rw http.ResponseWriter, r *http.Request) {
+			GenericSensorHandler(rw, r, []map[string]interface{}{
+				{"path": "/etc/kubernetes/pki/ca.key", "permissions": 384, "content": "c2VjcmV0"},
+			}, nil, "summaryTestKeys")
+		})
+		http.HandleFunc("/summaryTestRaw", func(rw http.ResponseWriter, r *http.Request) {
+			_, _ = rw.Write([]byte("secret: value"))
+		})
+	})
+
+	// the file contents are removed, the permissions are kept
+	rw := httptest.NewRecorder()
+	summaryHandler(rw, httptest.NewRequest(http.MethodGet, "/summary/summaryTestKeys", nil))
+	require.Equal(t, http.StatusOK, rw.Code)
+	files := []map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &files))
+	require.Len(t, files, 1)
+	assert.Equal(t, "/etc/kubernetes/pki/ca.key", files[0]["path"])
+	assert.EqualValues(t, 384, files[0]["permissions"])
+	assert.NotContains(t, files[0], "content")
+
+	// a response which isn't JSON isn't served
+	rw = httptest.NewRecorder()
+	summaryHandler(rw, httptest.NewRequest(http.MethodGet, "/summary/summaryTestRaw", nil))
+	assert.Equal(t, http.StatusNotFound, rw.Code)
+	assert.NotContains(t, rw.Body.String(), "secret")
+
+	for _, path := range []string{"/summary/kubeletConfigurations", "/summary/events", "/summary/summary/summaryTestKeys"} {
+		rw = httptest.NewRecorder()
+		summaryHandler(rw, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusNotFound, rw.Code, path)
+	}
+
+	// the summary tier is read-only
+	rw = httptest.NewRecorder()
+	summaryHandler(rw, httptest.NewRequest(http.MethodPost, "/summary/summaryTestKeys", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rw.Code)
+}
+
+func TestSummaryTierAllowlist(t *testing.T) {
+	rules := []ClientAllowRule{{Paths: []string{allowlistSensitivePaths}, Identities: []string{"aggregator"}}}
+
+	// the summary of a sensitive endpoint isn't sensitive
+	assert.False(t, isClientAllowed(rules, "/privateKeys", []string{"monitoring"}))
+	assert.True(t, isClientAllowed(rules, "/summary/privateKeys", []string{"monitoring"}))
+	assert.False(t, isAuditedPath("/summary/privateKeys"))
+	assert.False(t, isSensitivePath("/summary/privateKeys"))
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/armosec/host-sensor/tracing"
+	"github.com/codegangsta/negroni"
+)
+
+// tracer exports the spans of the requests, scans and sensors, nil if tracing is disabled
+var tracer *tracing.Tracer
+
+// newTracer returns the tracer of the configuration, nil if tracing is disabled
+func newTracer(conf *Config) *tracing.Tracer {
+	if conf.Tracing.Endpoint == "" {
+		return nil
+	}
+	resource := map[string]string{"service.name": conf.Tracing.ServiceName, "service.version": buildVersion}
+	if conf.Identity.NodeName != "" {
+		resource["k8s.node.name"] = conf.Identity.NodeName
+	}
+	if conf.Identity.PodName != "" {
+		resource["k8s.pod.name"] = conf.Identity.PodName
+	}
+	if conf.Identity.PodNamespace != "" {
+		resource["k8s.namespace.name"] = conf.Identity.PodNamespace
+	}
+	return tracing.NewTracer(tracing.Options{
+		Endpoint: conf.Tracing.Endpoint,
+		Headers:  conf.Tracing.Headers,
+		Resource: resource,
+	})
+}
+
+// traceRequest traces every request in a server span, continuing the trace of the `traceparent` header if set
+func traceRequest(rw http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	ctx, span := tracing.Start(tracing.Extract(r), r.Method+" "+r.URL.Path, tracing.KindServer)
+	if span == nil {
+		next(rw, r)
+		return
+	}
+	defer span.Finish()
+	span.SetAttribute("http.method", r.Method)
+	span.SetAttribute("http.target", r.URL.Path)
+
+	next(rw, r.WithContext(ctx))
+
+	if nrw, ok := rw.(negroni.ResponseWriter); ok {
+		span.SetAttribute("http.status_code", nrw.Status())
+		if nrw.Status() >= http.StatusInternalServerError {
+			span.RecordError(fmt.Errorf("responded %d", nrw.Status()))
+		}
+	}
+}
@@ -0,0 +1,251 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	queueSize     = 2048
+	batchSize     = 512
+	flushInterval = 5 * time.Second
+
+	statusCodeError = 2
+)
+
+// Options of a Tracer
+type Options struct {
+	// URL of the OTLP/HTTP traces endpoint, e.g. http://collector:4318/v1/traces
+	Endpoint string
+
+	// Headers added to the export requests, e.g. authorization
+	Headers map[string]string
+
+	// Attributes of the resource of the spans, e.g. service.name and k8s.node.name
+	Resource map[string]string
+
+	HTTPClient *http.Client
+}
+
+// Tracer exports the finished spans in batches. Spans are dropped if the queue is full.
+type Tracer struct {
+	options Options
+	queue   chan *Span
+
+	lock    sync.Mutex
+	stopped bool
+	done    chan struct{}
+}
+
+// NewTracer returns a tracer exporting to `options.Endpoint`, call `Shutdown` to flush it
+func NewTracer(options Options) *Tracer {
+	if options.HTTPClient == nil {
+		options.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	t := &Tracer{options: options, queue: make(chan *Span, queueSize), done: make(chan struct{})}
+	go t.run()
+	return t
+}
+
+func (t *Tracer) enqueue(span *Span) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.stopped {
+		return
+	}
+	select {
+	case t.queue <- span:
+	default:
+		zap.L().Debug("Trace queue is full, dropping span", zap.String("span", span.Name))
+	}
+}
+
+func (t *Tracer) run() {
+	defer close(t.done)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := []*Span{}
+	for {
+		select {
+		case span, ok := <-t.queue:
+			if !ok {
+				t.export(batch)
+				return
+			}
+			batch = append(batch, span)
+			if len(batch) < batchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		t.export(batch)
+		batch = batch[:0]
+	}
+}
+
+// Shutdown exports the queued spans, until `ctx` is done. Spans finished afterwards are dropped.
+func (t *Tracer) Shutdown(ctx context.Context) error {
+	t.lock.Lock()
+	if !t.stopped {
+		t.stopped = true
+		close(t.queue)
+	}
+	t.lock.Unlock()
+
+	select {
+	case <-t.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (t *Tracer) export(batch []*Span) {
+	if len(batch) == 0 {
+		return
+	}
+	body, err := json.Marshal(t.encode(batch))
+	if err != nil {
+		zap.L().Error("Failed to encode spans", zap.Error(err))
+		return
+	}
+	if err := t.send(body); err != nil {
+		zap.L().Warn("Failed to export spans", zap.Int("spans", len(batch)), zap.Error(err))
+	}
+}
+
+func (t *Tracer) send(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, t.options.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range t.options.Headers {
+		req.Header.Set(name, value)
+	}
+
+	res, err := t.options.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("collector responded %s", res.Status)
+	}
+	return nil
+}
+
+// OTLP JSON encoding, see https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpResource struct {
+	Attributes []otlpAttribute `json:"attributes"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpScope struct {
+	Name string `json:"name"`
+}
+
+type otlpSpan struct {
+	TraceID           string          `json:"traceId"`
+	SpanID            string          `json:"spanId"`
+	ParentSpanID      string          `json:"parentSpanId,omitempty"`
+	Name              string          `json:"name"`
+	Kind              int             `json:"kind"`
+	StartTimeUnixNano string          `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string          `json:"endTimeUnixNano"`
+	Attributes        []otlpAttribute `json:"attributes,omitempty"`
+	Status            *otlpStatus     `json:"status,omitempty"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpAttribute struct {
+	Key   string                 `json:"key"`
+	Value map[string]interface{} `json:"value"`
+}
+
+func (t *Tracer) encode(batch []*Span) otlpTraces {
+	resource := otlpResource{Attributes: []otlpAttribute{}}
+	for key, value := range t.options.Resource {
+		resource.Attributes = append(resource.Attributes, newAttribute(key, value))
+	}
+
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, span := range batch {
+		spans = append(spans, encodeSpan(span))
+	}
+	return otlpTraces{ResourceSpans: []otlpResourceSpans{{
+		Resource:   resource,
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "github.com/armosec/host-sensor"}, Spans: spans}},
+	}}}
+}
+
+func encodeSpan(span *Span) otlpSpan {
+	span.lock.Lock()
+	defer span.lock.Unlock()
+
+	encoded := otlpSpan{
+		TraceID:           hex.EncodeToString(span.TraceID[:]),
+		SpanID:            hex.EncodeToString(span.SpanID[:]),
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+	}
+	if span.ParentID != [8]byte{} {
+		encoded.ParentSpanID = hex.EncodeToString(span.ParentID[:])
+	}
+	for key, value := range span.attributes {
+		encoded.Attributes = append(encoded.Attributes, newAttribute(key, value))
+	}
+	if span.err != nil {
+		encoded.Status = &otlpStatus{Code: statusCodeError, Message: span.err.Error()}
+	}
+	return encoded
+}
+
+func newAttribute(key string, value interface{}) otlpAttribute {
+	switch v := value.(type) {
+	case string:
+		return otlpAttribute{Key: key, Value: map[string]interface{}{"stringValue": v}}
+	case bool:
+		return otlpAttribute{Key: key, Value: map[string]interface{}{"boolValue": v}}
+	case int:
+		return otlpAttribute{Key: key, Value: map[string]interface{}{"intValue": strconv.Itoa(v)}}
+	case int64:
+		return otlpAttribute{Key: key, Value: map[string]interface{}{"intValue": strconv.FormatInt(v, 10)}}
+	case float64:
+		return otlpAttribute{Key: key, Value: map[string]interface{}{"doubleValue": v}}
+	default:
+		return otlpAttribute{Key: key, Value: map[string]interface{}{"stringValue": fmt.Sprint(v)}}
+	}
+}
@@ -0,0 +1,189 @@
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// This package records OpenTelemetry spans and exports them with OTLP over HTTP (JSON encoded).
+// Spans are no-ops until a tracer is set with `SetTracer`.
+
+// Span kinds, as in OTLP
+const (
+	KindInternal = 1
+	KindServer   = 2
+	KindClient   = 3
+)
+
+// TraceparentHeader propagates the trace context (W3C Trace Context)
+const TraceparentHeader = "traceparent"
+
+// sampledFlag is the trace flag of the sampled traces
+const sampledFlag = 0x01
+
+// Span is a timed operation of a trace. A nil span is a no-op.
+type Span struct {
+	TraceID  [16]byte
+	SpanID   [8]byte
+	ParentID [8]byte
+	Name     string
+	Kind     int
+	Start    time.Time
+	End      time.Time
+
+	// Sampled spans are exported, the spans of an unsampled trace only propagate it
+	Sampled bool
+
+	lock       sync.Mutex
+	attributes map[string]interface{}
+	err        error
+	ended      bool
+	tracer     *Tracer
+}
+
+var (
+	globalTracer     *Tracer
+	globalTracerLock sync.RWMutex
+)
+
+// SetTracer sets the tracer of the started spans, nil disables tracing
+func SetTracer(t *Tracer) {
+	globalTracerLock.Lock()
+	defer globalTracerLock.Unlock()
+	globalTracer = t
+}
+
+func getTracer() *Tracer {
+	globalTracerLock.RLock()
+	defer globalTracerLock.RUnlock()
+	return globalTracer
+}
+
+// Enabled returns true if a tracer is set
+func Enabled() bool {
+	return getTracer() != nil
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpan returns a context holding the span, the parent of the spans started with it
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanContextKey{}, span)
+}
+
+// SpanFromContext returns the span of the context, nil if there is none
+func SpanFromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanContextKey{}).(*Span)
+	return span
+}
+
+// Start starts a span, the child of the span of `ctx` (if any). It returns nil if tracing is disabled.
+func Start(ctx context.Context, name string, kind int) (context.Context, *Span) {
+	span := StartChild(SpanFromContext(ctx), name, kind)
+	return ContextWithSpan(ctx, span), span
+}
+
+// StartChild starts a child span of `parent`, or a root span if `parent` is nil. A child is sampled if its parent
+// is, a root span is sampled. It returns nil if tracing is disabled.
+func StartChild(parent *Span, name string, kind int) *Span {
+	tracer := getTracer()
+	if tracer == nil {
+		return nil
+	}
+	span := &Span{Name: name, Kind: kind, Start: time.Now(), tracer: tracer}
+	if parent != nil {
+		span.TraceID, span.ParentID, span.Sampled = parent.TraceID, parent.SpanID, parent.Sampled
+	} else {
+		rand.Read(span.TraceID[:])
+		span.Sampled = true
+	}
+	rand.Read(span.SpanID[:])
+	return span
+}
+
+// SetAttribute sets an attribute of the span, the value is a string, bool, int, int64 or float64
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.attributes == nil {
+		s.attributes = map[string]interface{}{}
+	}
+	s.attributes[key] = value
+}
+
+// RecordError marks the span as failed, a nil error is ignored
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.err = err
+}
+
+// Finish ends the span and queues it for export if sampled. Only the first call has effect.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.lock.Lock()
+	if s.ended {
+		s.lock.Unlock()
+		return
+	}
+	s.ended = true
+	s.End = time.Now()
+	s.lock.Unlock()
+	if s.Sampled {
+		s.tracer.enqueue(s)
+	}
+}
+
+// Traceparent returns the W3C traceparent header value of the span
+func (s *Span) Traceparent() string {
+	flags := 0
+	if s.Sampled {
+		flags |= sampledFlag
+	}
+	return fmt.Sprintf("00-%s-%s-%02x", hex.EncodeToString(s.TraceID[:]), hex.EncodeToString(s.SpanID[:]), flags)
+}
+
+// Extract returns a context holding the remote parent span of the request's traceparent header, if valid. The
+// sampled flag of the header is kept, so the spans of an unsampled trace aren't exported.
+func Extract(r *http.Request) context.Context {
+	parts := strings.Split(r.Header.Get(TraceparentHeader), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return r.Context()
+	}
+	// only version 00 has exactly 4 parts, later versions may add some
+	if parts[0] == "00" && len(parts) != 4 {
+		return r.Context()
+	}
+	var flags [1]byte
+	if _, err := hex.Decode(flags[:], []byte(parts[3])); err != nil {
+		return r.Context()
+	}
+	remote := &Span{Sampled: flags[0]&sampledFlag != 0}
+	if _, err := hex.Decode(remote.TraceID[:], []byte(parts[1])); err != nil {
+		return r.Context()
+	}
+	if _, err := hex.Decode(remote.SpanID[:], []byte(parts[2])); err != nil {
+		return r.Context()
+	}
+	if remote.TraceID == [16]byte{} || remote.SpanID == [8]byte{} {
+		return r.Context()
+	}
+	return ContextWithSpan(r.Context(), remote)
+}
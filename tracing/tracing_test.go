@@ -0,0 +1,121 @@
+package tracing
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisabled(t *testing.T) {
+	SetTracer(nil)
+	ctx, span := Start(context.Background(), "noop", KindInternal)
+	assert.Nil(t, span)
+	assert.Nil(t, SpanFromContext(ctx))
+
+	// a nil span is a no-op
+	span.SetAttribute("key", "value")
+	span.RecordError(errors.New("failed"))
+	span.Finish()
+}
+
+func TestExtract(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	remote := SpanFromContext(Extract(r))
+	require.NotNil(t, remote)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(remote.TraceID[:]))
+	assert.Equal(t, "00f067aa0ba902b7", hex.EncodeToString(remote.SpanID[:]))
+	assert.Equal(t, r.Header.Get(TraceparentHeader), remote.Traceparent())
+
+	assert.True(t, remote.Sampled)
+
+	// the sampled flag is kept
+	r.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	remote = SpanFromContext(Extract(r))
+	require.NotNil(t, remote)
+	assert.False(t, remote.Sampled)
+	assert.Equal(t, r.Header.Get(TraceparentHeader), remote.Traceparent())
+
+	for _, invalid := range []string{"", "00-xyz-00f067aa0ba902b7-01", "00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-zz", "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"} {
+		r.Header.Set(TraceparentHeader, invalid)
+		assert.Nil(t, SpanFromContext(Extract(r)), invalid)
+	}
+}
+
+func TestExport(t *testing.T) {
+	var lock sync.Mutex
+	received := []otlpSpan{}
+	var resource otlpResource
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/traces", r.URL.Path)
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		traces := otlpTraces{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&traces))
+		lock.Lock()
+		defer lock.Unlock()
+		for _, resourceSpans := range traces.ResourceSpans {
+			resource = resourceSpans.Resource
+			for _, scopeSpans := range resourceSpans.ScopeSpans {
+				received = append(received, scopeSpans.Spans...)
+			}
+		}
+	}))
+	defer collector.Close()
+
+	tracer := NewTracer(Options{
+		Endpoint: collector.URL + "/v1/traces",
+		Headers:  map[string]string{"Authorization": "Bearer token"},
+		Resource: map[string]string{"service.name": "host-sensor"},
+	})
+	SetTracer(tracer)
+	defer SetTracer(nil)
+
+	ctx, root := Start(context.Background(), "scan", KindInternal)
+	_, child := Start(ctx, "sensor kubeletInfo", KindInternal)
+	child.SetAttribute("file.size", 42)
+	child.RecordError(errors.New("permission denied"))
+	child.Finish()
+	root.Finish()
+	root.Finish()
+
+	// the spans of an unsampled trace aren't exported
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set(TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	_, unsampled := Start(Extract(r), "unsampled", KindServer)
+	assert.False(t, unsampled.Sampled)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(unsampled.TraceID[:]))
+	unsampled.Finish()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, tracer.Shutdown(shutdownCtx))
+
+	lock.Lock()
+	defer lock.Unlock()
+	require.Len(t, received, 2)
+	assert.Equal(t, []otlpAttribute{newAttribute("service.name", "host-sensor")}, resource.Attributes)
+
+	exportedChild, exportedRoot := received[0], received[1]
+	assert.Equal(t, "sensor kubeletInfo", exportedChild.Name)
+	assert.Equal(t, exportedRoot.TraceID, exportedChild.TraceID)
+	assert.Equal(t, exportedRoot.SpanID, exportedChild.ParentSpanID)
+	assert.Empty(t, exportedRoot.ParentSpanID)
+	assert.Equal(t, []otlpAttribute{{Key: "file.size", Value: map[string]interface{}{"intValue": "42"}}}, exportedChild.Attributes)
+	assert.Equal(t, &otlpStatus{Code: statusCodeError, Message: "permission denied"}, exportedChild.Status)
+	assert.Nil(t, exportedRoot.Status)
+
+	// spans finished after the shutdown are dropped
+	_, late := Start(context.Background(), "late", KindInternal)
+	late.Finish()
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/armosec/host-sensor/tracing"
+	"github.com/codegangsta/negroni"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTraceRequest(t *testing.T) {
+	assert.Nil(t, newTracer(defaultConfig()))
+
+	conf := defaultConfig()
+	conf.Tracing.Endpoint = "http://127.0.0.1:0/v1/traces"
+	tracer := newTracer(conf)
+	tracing.SetTracer(tracer)
+	defer func() {
+		tracing.SetTracer(nil)
+		tracer.Shutdown(context.Background())
+	}()
+
+	var span *tracing.Span
+	r := httptest.NewRequest(http.MethodGet, "/kubeletInfo", nil)
+	r.Header.Set(tracing.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	traceRequest(negroni.NewResponseWriter(httptest.NewRecorder()), r, func(rw http.ResponseWriter, r *http.Request) {
+		span = tracing.SpanFromContext(r.Context())
+	})
+
+	if assert.NotNil(t, span) {
+		assert.Equal(t, "GET /kubeletInfo", span.Name)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", hex.EncodeToString(span.TraceID[:]))
+		assert.Equal(t, "00f067aa0ba902b7", hex.EncodeToString(span.ParentID[:]))
+	}
+}